@@ -48,8 +48,11 @@ func Run() {
 	// Setup HTTP router
 	router := SetupRouter()
 
+	// Start background jobs
+	stopBackgroundJobs := StartBackgroundJobs()
+
 	// Setup graceful shutdown
-	SetupGracefulShutdown(nil)
+	SetupGracefulShutdown(stopBackgroundJobs)
 
 	// Start the server
 	StartServer(router)
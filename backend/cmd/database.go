@@ -17,6 +17,32 @@ func InitializeDatabase() {
 	// Initialize gormigrate with migrations
 	m := gormigrate.New(db, gormigrate.DefaultOptions, []*gormigrate.Migration{
 		migrations.InitialSchema,
+		migrations.EventRecurrence,
+		migrations.CalendarSubscription,
+		migrations.FeedTokens,
+		migrations.EventTitleOverride,
+		migrations.CalendarRedactionMode,
+		migrations.CalendarFreeBusy,
+		migrations.BookingPages,
+		migrations.CalendarSyncSchedule,
+		migrations.CalendarWatchChannel,
+		migrations.CalDAVAccounts,
+		migrations.AppPasswords,
+		migrations.EventWrites,
+		migrations.BackfillJobs,
+		migrations.SyncRuns,
+		migrations.EventAttendees,
+		migrations.CalendarBackgroundColor,
+		migrations.EventStatus,
+		migrations.EventConference,
+		migrations.EventSearch,
+		migrations.EventSourceIndex,
+		migrations.EventWriteConflicts,
+		migrations.AccountScopes,
+		migrations.DAVObjects,
+		migrations.EventWriteVersions,
+		migrations.CalendarSyncLeaseTokens,
+		migrations.EventSourceURLIndex,
 	})
 
 	// Run migrations
@@ -24,5 +50,10 @@ func InitializeDatabase() {
 		log.Fatal("Failed to migrate database: " + err.Error())
 	}
 
+	// The search index of SQLite depends on how the server is built, so it is checked at every startup
+	if err := migrations.EnsureEventSearchIndex(db); err != nil {
+		log.Fatal("Failed to create the event search index: " + err.Error())
+	}
+
 	log.Println("Database connected and migrated successfully")
 }
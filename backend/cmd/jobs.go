@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"context"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/NathanWasTaken/timely/backend/internal/config"
+	"github.com/NathanWasTaken/timely/backend/internal/repository"
+	"github.com/NathanWasTaken/timely/backend/internal/service"
+)
+
+// StartBackgroundJobs starts the background workers and returns a function that stops them
+func StartBackgroundJobs() func() {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	// Initialize dependencies
+	dbConfig := config.NewDatabaseConfig()
+	userRepo := repository.NewUserRepository(dbConfig.GetDB())
+	calendarRepo := repository.NewCalendarRepository(dbConfig.GetDB())
+	calendarService := service.NewCalendarService(userRepo, calendarRepo, config.NewOAuthConfig())
+
+	// Refresh subscribed ICS feeds
+	go calendarService.RunICSSubscriptionRefresher(ctx, getICSRefreshInterval())
+
+	// Sync Google calendars
+	go calendarService.RunSyncScheduler(ctx, service.SyncSchedulerConfig{
+		Interval: getSyncInterval(),
+		Workers:  getSyncWorkers(),
+	})
+
+	// Keep Google push notification channels open
+	go calendarService.RunWatchChannelRenewer(ctx)
+
+	// Fetch historical events of Google calendars
+	go calendarService.RunBackfillWorker(ctx)
+
+	log.Println("Background jobs started")
+
+	return cancel
+}
+
+// getICSRefreshInterval reads the ICS subscription refresh interval from ICS_REFRESH_INTERVAL
+func getICSRefreshInterval() time.Duration {
+	value := os.Getenv("ICS_REFRESH_INTERVAL")
+	if value == "" {
+		return service.DefaultICSRefreshInterval
+	}
+
+	interval, err := time.ParseDuration(value)
+	if err != nil || interval <= 0 {
+		log.Printf("Invalid ICS_REFRESH_INTERVAL %q, using default of %s", value, service.DefaultICSRefreshInterval)
+		return service.DefaultICSRefreshInterval
+	}
+
+	return interval
+}
+
+// getSyncInterval reads the default Google calendar sync interval from SYNC_INTERVAL
+func getSyncInterval() time.Duration {
+	value := os.Getenv("SYNC_INTERVAL")
+	if value == "" {
+		return service.DefaultSyncInterval
+	}
+
+	interval, err := time.ParseDuration(value)
+	if err != nil || interval <= 0 {
+		log.Printf("Invalid SYNC_INTERVAL %q, using default of %s", value, service.DefaultSyncInterval)
+		return service.DefaultSyncInterval
+	}
+
+	return interval
+}
+
+// getSyncWorkers reads the number of calendars synced at the same time from SYNC_WORKERS
+func getSyncWorkers() int {
+	value := os.Getenv("SYNC_WORKERS")
+	if value == "" {
+		return service.DefaultSyncWorkers
+	}
+
+	workers, err := strconv.Atoi(value)
+	if err != nil || workers <= 0 {
+		log.Printf("Invalid SYNC_WORKERS %q, using default of %d", value, service.DefaultSyncWorkers)
+		return service.DefaultSyncWorkers
+	}
+
+	return workers
+}
@@ -40,8 +40,12 @@ func SetupRouter() *chi.Mux {
 		router.AuthRouter(r)
 		router.CalendarRouter(r)
 		router.UserRouter(r)
+		router.FeedRouter(r)
 	})
 
+	// CalDAV server, outside of /api so clients find it through /.well-known/caldav
+	router.DAVRouter(r)
+
 	return r
 }
 
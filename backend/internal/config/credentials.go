@@ -0,0 +1,24 @@
+package config
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/NathanWasTaken/timely/backend/pkg/encrypt"
+)
+
+// NewCredentialsCipher creates the cipher that encrypts stored credentials of remote calendar
+// accounts, such as CalDAV passwords, keyed by CREDENTIALS_SECRET
+func NewCredentialsCipher() (*encrypt.Cipher, error) {
+	secret := os.Getenv("CREDENTIALS_SECRET")
+	if secret == "" {
+		return nil, fmt.Errorf("CREDENTIALS_SECRET environment variable is required")
+	}
+
+	// Ensure secret is at least 32 bytes for security
+	if len(secret) < 32 {
+		return nil, fmt.Errorf("CREDENTIALS_SECRET must be at least 32 characters long")
+	}
+
+	return encrypt.NewCipher(secret)
+}
@@ -7,8 +7,15 @@ import (
 	"golang.org/x/oauth2/google"
 )
 
+// GoogleCalendarEventsScope is the Google scope that allows editing events, needed to write changes
+// back to calendars with write-back enabled
+const GoogleCalendarEventsScope = "https://www.googleapis.com/auth/calendar.events"
+
 type OAuthConfig struct {
 	Google *oauth2.Config
+	// GoogleWebhookURL is the public URL Google sends calendar push notifications to. Push
+	// notifications are disabled when it is empty.
+	GoogleWebhookURL string
 }
 
 func NewOAuthConfig() *OAuthConfig {
@@ -21,9 +28,11 @@ func NewOAuthConfig() *OAuthConfig {
 				"https://www.googleapis.com/auth/userinfo.email",
 				"https://www.googleapis.com/auth/userinfo.profile",
 				"https://www.googleapis.com/auth/calendar.readonly",
+				GoogleCalendarEventsScope,
 			},
 			Endpoint: google.Endpoint,
 		},
+		GoogleWebhookURL: getEnv("GOOGLE_WEBHOOK_URL", ""),
 	}
 }
 
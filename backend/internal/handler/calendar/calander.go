@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"go.uber.org/zap"
@@ -27,13 +28,17 @@ func NewCalendarHandler(calendarService *service.CalendarService) *CalendarHandl
 
 // GetCalendarEvents retrieves all events for user's calendars within a specified time range
 // @Summary Get Calendar Events
-// @Description Retrieves all events for user's calendars within a specified time range (max 6 months)
+// @Description Retrieves all events for user's calendars within a specified time range (max 6 months), nested in their calendars. Events are served from storage, which the background sync keeps up to date. Larger ranges can be listed with format=flat, which returns a model.CalendarEventPageResponse: a flat list of events ordered by start in pages linked by cursors, or with format=ndjson (or Accept: application/x-ndjson), which streams one event per line without attendees.
 // @Tags Calendar
 // @Produce json
+// @Produce application/x-ndjson
 // @Security BearerAuth
 // @Param start_timestamp query string true "Start timestamp in Unix format"
 // @Param end_timestamp query string true "End timestamp in Unix format"
-// @Param force_sync query bool false "Force sync from Google API regardless of cache"
+// @Param force_sync query bool false "Sync from Google API before responding instead of waiting for the background sync"
+// @Param format query string false "Listing format: nested (default), flat or ndjson"
+// @Param cursor query string false "Cursor of the page of the flat listing, from next_cursor of the previous page"
+// @Param limit query int false "Maximum number of events of a page of the flat listing (default 250, max 1000)"
 // @Success 200 {object} model.CalendarEventsResponse "Events retrieved successfully"
 // @Failure 400 {object} model.ErrorResponse "Bad Request - Invalid query parameters or time range"
 // @Failure 401 {object} model.ErrorResponse "Unauthorized - Authentication required"
@@ -84,6 +89,23 @@ func (h *CalendarHandler) GetCalendarEvents(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
+	format := r.URL.Query().Get("format")
+	if format == "" && strings.Contains(r.Header.Get("Accept"), ndjsonContentType) {
+		format = "ndjson"
+	}
+	switch format {
+	case "", "nested":
+	case "flat":
+		h.getCalendarEventsPage(w, r, user.ID, startTime, endTime, forceSync)
+		return
+	case "ndjson":
+		h.streamCalendarEvents(w, user.ID, startTime, endTime, forceSync)
+		return
+	default:
+		sendErrorResponse(w, "Format must be nested, flat or ndjson", "invalid_format", http.StatusBadRequest)
+		return
+	}
+
 	h.logger.Info("Fetching calendar events for user",
 		zap.Uint64("user_id", user.ID),
 		zap.Time("start_time", startTime),
@@ -136,7 +158,7 @@ func (h *CalendarHandler) GetCalendarEvents(w http.ResponseWriter, r *http.Reque
 
 // GetImportedCalendars retrieves all imported calendars for the authenticated user
 // @Summary Get Imported Calendars
-// @Description Retrieves all imported calendars (Google and ICS) for the authenticated user
+// @Description Retrieves all imported calendars (Google and ICS) for the authenticated user. Calendars synced with a remote source include their sync health: healthy, degraded or needs_reauthentication
 // @Tags Calendar
 // @Accept json
 // @Produce json
@@ -196,7 +218,7 @@ func (h *CalendarHandler) GetImportedCalendars(w http.ResponseWriter, r *http.Re
 // @Param id path string true "Calendar ID"
 // @Param request body model.CalendarUpdateRequest true "Calendar update request"
 // @Success 200 {object} model.CalendarUpdateResponse "Calendar updated successfully"
-// @Failure 400 {object} model.ErrorResponse "Bad Request - Invalid request body or calendar ID"
+// @Failure 400 {object} model.ErrorResponse "Bad Request - Invalid request body or calendar ID, or write-back enabled without permission to edit Google Calendar events"
 // @Failure 401 {object} model.ErrorResponse "Unauthorized - Authentication required"
 // @Failure 404 {object} model.ErrorResponse "Not Found - Calendar not found or access denied"
 // @Failure 500 {object} model.ErrorResponse "Internal server error"
@@ -240,6 +262,10 @@ func (h *CalendarHandler) UpdateCalendar(w http.ResponseWriter, r *http.Request)
 			sendErrorResponse(w, "Calendar not found or access denied", "calendar_not_found", http.StatusNotFound)
 		case err.Error() == "failed to find calendar: record not found":
 			sendErrorResponse(w, "Calendar not found", "calendar_not_found", http.StatusNotFound)
+		case strings.HasPrefix(err.Error(), "invalid calendar: write-back needs permission"):
+			sendErrorResponse(w, err.Error(), "google_consent_required", http.StatusBadRequest)
+		case strings.HasPrefix(err.Error(), "invalid calendar"):
+			sendErrorResponse(w, err.Error(), "invalid_calendar", http.StatusBadRequest)
 		default:
 			sendErrorResponse(w, "Failed to update calendar", "calendar_update_error", http.StatusInternalServerError)
 		}
@@ -339,6 +365,76 @@ func (h *CalendarHandler) DeleteCalendar(w http.ResponseWriter, r *http.Request)
 		zap.String("calendar_id", calendarID))
 }
 
+// GetCalendarSyncRuns retrieves the sync history of a calendar
+// @Summary Get Calendar Sync History
+// @Description Retrieves the most recent syncs of a calendar with their mode, changes and errors, and the sync health derived from them
+// @Tags Calendar
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Calendar ID"
+// @Param limit query int false "Maximum number of syncs returned (default 50, max 200)"
+// @Success 200 {object} model.SyncRunListResponse "Sync history retrieved successfully"
+// @Failure 400 {object} model.ErrorResponse "Bad Request - Invalid limit"
+// @Failure 401 {object} model.ErrorResponse "Unauthorized - Authentication required"
+// @Failure 404 {object} model.ErrorResponse "Not Found - Calendar not found or access denied"
+// @Failure 500 {object} model.ErrorResponse "Internal server error"
+// @Router /api/calendars/{id}/syncs [get]
+func (h *CalendarHandler) GetCalendarSyncRuns(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by JWT middleware)
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		h.logger.Error("User not found in context")
+		sendErrorResponse(w, "Authentication required", "authentication_required", http.StatusUnauthorized)
+		return
+	}
+
+	calendarID := r.PathValue("id")
+
+	limit := service.DefaultSyncRunLimit
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 || parsed > service.MaxSyncRunLimit {
+			sendErrorResponse(w, "Limit must be between 1 and 200", "invalid_limit", http.StatusBadRequest)
+			return
+		}
+		limit = parsed
+	}
+
+	runs, health, err := h.calendarService.GetCalendarSyncRuns(user.ID, calendarID, limit)
+	if err != nil {
+		h.logger.Error("Failed to get calendar sync runs", zap.Error(err), zap.Uint64("user_id", user.ID))
+
+		// Handle specific error cases
+		switch {
+		case err.Error() == "calendar not found or access denied":
+			sendErrorResponse(w, "Calendar not found or access denied", "calendar_not_found", http.StatusNotFound)
+		case err.Error() == "failed to find calendar: record not found":
+			sendErrorResponse(w, "Calendar not found", "calendar_not_found", http.StatusNotFound)
+		default:
+			sendErrorResponse(w, "Failed to retrieve sync history", "sync_history_fetch_error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	// Create success response
+	response := model.SyncRunListResponse{
+		Success: true,
+		Message: "Sync history retrieved successfully",
+		Health:  health,
+		Runs:    runs,
+	}
+
+	// Send response
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		h.logger.Error("Failed to encode response", zap.Error(err))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+}
+
 // sendErrorResponse sends a standardized error response
 func sendErrorResponse(w http.ResponseWriter, message, errorType string, statusCode int) {
 	w.Header().Set("Content-Type", "application/json")
@@ -0,0 +1,280 @@
+package calendar
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/NathanWasTaken/timely/backend/internal/middleware"
+	"github.com/NathanWasTaken/timely/backend/internal/model"
+)
+
+// ConnectCalDAVAccount connects a CalDAV account
+// @Summary Connect CalDAV Account
+// @Description Connects a CalDAV server such as Nextcloud, Fastmail or iCloud. The calendar home is discovered from the server URL, falling back to /.well-known/caldav. The password, ideally an app password, is stored encrypted. Returns the calendars of the account that can be imported
+// @Tags Calendar
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body model.CalDAVAccountCreateRequest true "CalDAV account connect request"
+// @Success 201 {object} model.CalDAVAccountResponse "CalDAV account connected successfully"
+// @Failure 400 {object} model.ErrorResponse "Bad Request - Invalid request body or server URL"
+// @Failure 401 {object} model.ErrorResponse "Unauthorized - Authentication required"
+// @Failure 422 {object} model.ErrorResponse "Unprocessable Entity - CalDAV server rejected the credentials"
+// @Failure 502 {object} model.ErrorResponse "Bad Gateway - CalDAV server could not be reached or has no calendars"
+// @Failure 503 {object} model.ErrorResponse "Service Unavailable - CalDAV accounts are disabled"
+// @Failure 500 {object} model.ErrorResponse "Internal server error"
+// @Router /api/calendars/caldav/accounts [post]
+func (h *CalendarHandler) ConnectCalDAVAccount(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by JWT middleware)
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		h.logger.Error("User not found in context")
+		sendErrorResponse(w, "Authentication required", "authentication_required", http.StatusUnauthorized)
+		return
+	}
+
+	// Parse request body
+	var req model.CalDAVAccountCreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Error("Failed to decode request body", zap.Error(err))
+		sendErrorResponse(w, "Invalid request body", "invalid_request", http.StatusBadRequest)
+		return
+	}
+
+	account, calendars, err := h.calendarService.ConnectCalDAVAccount(user.ID, &req)
+	if err != nil {
+		h.logger.Error("Failed to connect CalDAV account", zap.Error(err), zap.Uint64("user_id", user.ID))
+
+		switch {
+		case strings.HasPrefix(err.Error(), "invalid server URL"):
+			sendErrorResponse(w, err.Error(), "invalid_server_url", http.StatusBadRequest)
+		case strings.HasPrefix(err.Error(), "failed to discover CalDAV calendars"):
+			sendErrorResponse(w, "Failed to discover calendars on the CalDAV server", "caldav_discovery_error", http.StatusBadGateway)
+		default:
+			h.sendCalDAVError(w, err, "Failed to connect CalDAV account", "caldav_connect_error")
+		}
+		return
+	}
+
+	response := model.CalDAVAccountResponse{
+		Success:   true,
+		Message:   "CalDAV account connected successfully",
+		Account:   account,
+		Calendars: calendars,
+	}
+
+	h.sendCalDAVResponse(w, http.StatusCreated, response)
+
+	h.logger.Info("Successfully connected CalDAV account",
+		zap.Uint64("user_id", user.ID),
+		zap.Uint64("account_id", account.ID))
+}
+
+// GetCalDAVAccounts lists the connected CalDAV accounts
+// @Summary Get CalDAV Accounts
+// @Description Retrieves the CalDAV accounts the authenticated user connected
+// @Tags Calendar
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} model.CalDAVAccountListResponse "CalDAV accounts retrieved successfully"
+// @Failure 401 {object} model.ErrorResponse "Unauthorized - Authentication required"
+// @Failure 500 {object} model.ErrorResponse "Internal server error"
+// @Router /api/calendars/caldav/accounts [get]
+func (h *CalendarHandler) GetCalDAVAccounts(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by JWT middleware)
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		h.logger.Error("User not found in context")
+		sendErrorResponse(w, "Authentication required", "authentication_required", http.StatusUnauthorized)
+		return
+	}
+
+	accounts, err := h.calendarService.GetCalDAVAccounts(user.ID)
+	if err != nil {
+		h.logger.Error("Failed to get CalDAV accounts", zap.Error(err), zap.Uint64("user_id", user.ID))
+		sendErrorResponse(w, "Failed to retrieve CalDAV accounts", "caldav_fetch_error", http.StatusInternalServerError)
+		return
+	}
+
+	response := model.CalDAVAccountListResponse{
+		Success:  true,
+		Message:  "CalDAV accounts retrieved successfully",
+		Accounts: accounts,
+	}
+
+	h.sendCalDAVResponse(w, http.StatusOK, response)
+}
+
+// GetCalDAVCalendars lists the calendars of a CalDAV account
+// @Summary Get CalDAV Calendars
+// @Description Lists the calendars of a connected CalDAV account that can be imported
+// @Tags Calendar
+// @Produce json
+// @Security BearerAuth
+// @Param accountId path string true "CalDAV account ID"
+// @Success 200 {object} model.CalDAVCalendarListResponse "Calendars retrieved successfully"
+// @Failure 401 {object} model.ErrorResponse "Unauthorized - Authentication required"
+// @Failure 404 {object} model.ErrorResponse "Not Found - CalDAV account not found"
+// @Failure 422 {object} model.ErrorResponse "Unprocessable Entity - CalDAV server rejected the credentials"
+// @Failure 502 {object} model.ErrorResponse "Bad Gateway - CalDAV server could not be reached"
+// @Failure 503 {object} model.ErrorResponse "Service Unavailable - CalDAV accounts are disabled"
+// @Failure 500 {object} model.ErrorResponse "Internal server error"
+// @Router /api/calendars/caldav/accounts/{accountId}/calendars [get]
+func (h *CalendarHandler) GetCalDAVCalendars(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by JWT middleware)
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		h.logger.Error("User not found in context")
+		sendErrorResponse(w, "Authentication required", "authentication_required", http.StatusUnauthorized)
+		return
+	}
+
+	calendars, err := h.calendarService.GetCalDAVCalendars(user.ID, r.PathValue("accountId"))
+	if err != nil {
+		h.logger.Error("Failed to get CalDAV calendars", zap.Error(err), zap.Uint64("user_id", user.ID))
+		h.sendCalDAVError(w, err, "Failed to retrieve calendars", "calendar_fetch_error")
+		return
+	}
+
+	response := model.CalDAVCalendarListResponse{
+		Success:   true,
+		Message:   "Calendars retrieved successfully",
+		Calendars: calendars,
+	}
+
+	h.sendCalDAVResponse(w, http.StatusOK, response)
+}
+
+// DeleteCalDAVAccount disconnects a CalDAV account
+// @Summary Delete CalDAV Account
+// @Description Disconnects a CalDAV account and deletes the calendars imported from it
+// @Tags Calendar
+// @Produce json
+// @Security BearerAuth
+// @Param accountId path string true "CalDAV account ID"
+// @Success 200 {object} model.CalendarDeleteResponse "CalDAV account deleted successfully"
+// @Failure 401 {object} model.ErrorResponse "Unauthorized - Authentication required"
+// @Failure 404 {object} model.ErrorResponse "Not Found - CalDAV account not found"
+// @Failure 500 {object} model.ErrorResponse "Internal server error"
+// @Router /api/calendars/caldav/accounts/{accountId} [delete]
+func (h *CalendarHandler) DeleteCalDAVAccount(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by JWT middleware)
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		h.logger.Error("User not found in context")
+		sendErrorResponse(w, "Authentication required", "authentication_required", http.StatusUnauthorized)
+		return
+	}
+
+	if err := h.calendarService.DeleteCalDAVAccount(user.ID, r.PathValue("accountId")); err != nil {
+		h.logger.Error("Failed to delete CalDAV account", zap.Error(err), zap.Uint64("user_id", user.ID))
+		h.sendCalDAVError(w, err, "Failed to delete CalDAV account", "caldav_delete_error")
+		return
+	}
+
+	response := model.CalendarDeleteResponse{
+		Success: true,
+		Message: "CalDAV account deleted successfully",
+	}
+
+	h.sendCalDAVResponse(w, http.StatusOK, response)
+}
+
+// ImportCalDAVCalendar imports a calendar of a CalDAV account
+// @Summary Import CalDAV Calendar
+// @Description Imports a calendar of a connected CalDAV account and fetches its events. The calendar is kept up to date by the background sync, incrementally where the server supports sync-collection
+// @Tags Calendar
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body model.CalDAVCalendarImportRequest true "CalDAV calendar import request"
+// @Success 201 {object} ImportCalendarResponse "Calendar imported successfully"
+// @Failure 400 {object} model.ErrorResponse "Bad Request - Invalid request body"
+// @Failure 401 {object} model.ErrorResponse "Unauthorized - Authentication required"
+// @Failure 404 {object} model.ErrorResponse "Not Found - CalDAV account or calendar not found"
+// @Failure 409 {object} model.ErrorResponse "Conflict - Calendar already imported"
+// @Failure 422 {object} model.ErrorResponse "Unprocessable Entity - CalDAV server rejected the credentials"
+// @Failure 502 {object} model.ErrorResponse "Bad Gateway - CalDAV server could not be reached"
+// @Failure 503 {object} model.ErrorResponse "Service Unavailable - CalDAV accounts are disabled"
+// @Failure 500 {object} model.ErrorResponse "Internal server error"
+// @Router /api/calendars/caldav [post]
+func (h *CalendarHandler) ImportCalDAVCalendar(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by JWT middleware)
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		h.logger.Error("User not found in context")
+		sendErrorResponse(w, "Authentication required", "authentication_required", http.StatusUnauthorized)
+		return
+	}
+
+	// Parse request body
+	var req model.CalDAVCalendarImportRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Error("Failed to decode request body", zap.Error(err))
+		sendErrorResponse(w, "Invalid request body", "invalid_request", http.StatusBadRequest)
+		return
+	}
+
+	if req.AccountID == "" || req.CalendarURL == "" {
+		sendErrorResponse(w, "Account ID and calendar URL are required", "invalid_request", http.StatusBadRequest)
+		return
+	}
+
+	calendar, err := h.calendarService.ImportCalDAVCalendar(user.ID, &req)
+	if err != nil {
+		h.logger.Error("Failed to import CalDAV calendar", zap.Error(err), zap.Uint64("user_id", user.ID))
+
+		switch {
+		case err.Error() == "calendar not found in list":
+			sendErrorResponse(w, "Calendar not found in list", "calendar_not_found", http.StatusNotFound)
+		case err.Error() == "calendar already imported":
+			sendErrorResponse(w, "Calendar already imported", "calendar_already_imported", http.StatusConflict)
+		default:
+			h.sendCalDAVError(w, err, "Failed to import calendar", "calendar_import_error")
+		}
+		return
+	}
+
+	response := ImportCalendarResponse{
+		Success:  true,
+		Message:  "Calendar imported successfully",
+		Calendar: calendar,
+	}
+
+	h.sendCalDAVResponse(w, http.StatusCreated, response)
+
+	h.logger.Info("Successfully imported CalDAV calendar",
+		zap.Uint64("user_id", user.ID),
+		zap.Uint64("calendar_id", calendar.ID))
+}
+
+// sendCalDAVError maps the errors shared by the CalDAV endpoints, anything else is an internal error
+func (h *CalendarHandler) sendCalDAVError(w http.ResponseWriter, err error, message, errorType string) {
+	switch {
+	case err.Error() == "CalDAV account not found":
+		sendErrorResponse(w, "CalDAV account not found", "caldav_account_not_found", http.StatusNotFound)
+	case err.Error() == "invalid CalDAV credentials":
+		sendErrorResponse(w, "CalDAV server rejected the credentials", "caldav_invalid_credentials", http.StatusUnprocessableEntity)
+	case strings.HasPrefix(err.Error(), "CalDAV accounts are disabled"):
+		sendErrorResponse(w, "CalDAV accounts are disabled on this server", "caldav_disabled", http.StatusServiceUnavailable)
+	case strings.HasPrefix(err.Error(), "failed to fetch calendars from CalDAV server"):
+		sendErrorResponse(w, "Failed to fetch calendars from the CalDAV server", "caldav_fetch_error", http.StatusBadGateway)
+	default:
+		sendErrorResponse(w, message, errorType, http.StatusInternalServerError)
+	}
+}
+
+// sendCalDAVResponse sends a successful CalDAV response
+func (h *CalendarHandler) sendCalDAVResponse(w http.ResponseWriter, statusCode int, response interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		h.logger.Error("Failed to encode response", zap.Error(err))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+}
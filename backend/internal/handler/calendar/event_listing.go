@@ -0,0 +1,136 @@
+package calendar
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/NathanWasTaken/timely/backend/internal/model"
+	"github.com/NathanWasTaken/timely/backend/internal/service"
+)
+
+// ndjsonContentType is the content type of responses streaming one JSON value per line
+const ndjsonContentType = "application/x-ndjson"
+
+// getCalendarEventsPage sends a page of the flat listing of the user's events
+func (h *CalendarHandler) getCalendarEventsPage(w http.ResponseWriter, r *http.Request, userID uint64, startTime, endTime time.Time, forceSync bool) {
+	cursor := r.URL.Query().Get("cursor")
+
+	limit := service.DefaultEventPageLimit
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 || parsed > service.MaxEventPageLimit {
+			sendErrorResponse(w, "Limit must be between 1 and 1000", "invalid_limit", http.StatusBadRequest)
+			return
+		}
+		limit = parsed
+	}
+
+	// Only sync for the first page, later pages continue the listing that page started
+	if forceSync && cursor == "" {
+		h.syncBeforeListing(userID)
+	}
+
+	events, nextCursor, err := h.calendarService.GetUserCalendarEventsPage(userID, startTime, endTime, cursor, limit)
+	if err != nil {
+		h.logger.Error("Failed to get page of calendar events", zap.Error(err), zap.Uint64("user_id", userID))
+
+		// Handle specific error cases
+		switch {
+		case strings.HasPrefix(err.Error(), "invalid cursor"):
+			sendErrorResponse(w, "Invalid cursor", "invalid_cursor", http.StatusBadRequest)
+		case strings.HasPrefix(err.Error(), "invalid time range"):
+			sendErrorResponse(w, "Start time must be before end time", "invalid_time_range", http.StatusBadRequest)
+		default:
+			sendErrorResponse(w, "Failed to retrieve calendar events", "calendar_events_fetch_error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	// Create success response
+	response := model.CalendarEventPageResponse{
+		Success:    true,
+		Message:    "Calendar events retrieved successfully",
+		Events:     events,
+		NextCursor: nextCursor,
+	}
+
+	// Send response
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		h.logger.Error("Failed to encode response", zap.Error(err))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+}
+
+// streamCalendarEvents streams the user's events as newline-delimited JSON, flushing each batch as
+// it is read. A failure after the first event can't change the status anymore, so it is reported
+// by a last line holding an error response.
+func (h *CalendarHandler) streamCalendarEvents(w http.ResponseWriter, userID uint64, startTime, endTime time.Time, forceSync bool) {
+	if forceSync {
+		h.syncBeforeListing(userID)
+	}
+
+	flusher, _ := w.(http.Flusher)
+	encoder := json.NewEncoder(w)
+	started := false
+
+	err := h.calendarService.StreamUserCalendarEvents(userID, startTime, endTime, func(events []*model.CalendarEvent) error {
+		if !started {
+			w.Header().Set("Content-Type", ndjsonContentType)
+			w.WriteHeader(http.StatusOK)
+			started = true
+		}
+		for _, event := range events {
+			if err := encoder.Encode(event); err != nil {
+				return err
+			}
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		return nil
+	})
+	if err != nil {
+		h.logger.Error("Failed to stream calendar events", zap.Error(err), zap.Uint64("user_id", userID))
+
+		if started {
+			encoder.Encode(model.ErrorResponse{
+				Success: false,
+				Message: "Failed to retrieve calendar events",
+				Error:   "calendar_events_fetch_error",
+			})
+			return
+		}
+
+		// Handle specific error cases
+		switch {
+		case strings.HasPrefix(err.Error(), "invalid time range"):
+			sendErrorResponse(w, "Start time must be before end time", "invalid_time_range", http.StatusBadRequest)
+		default:
+			sendErrorResponse(w, "Failed to retrieve calendar events", "calendar_events_fetch_error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	// No events at all still make an empty stream
+	if !started {
+		w.Header().Set("Content-Type", ndjsonContentType)
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// syncBeforeListing syncs the user's calendars before their events are listed
+func (h *CalendarHandler) syncBeforeListing(userID uint64) {
+	if _, err := h.calendarService.SyncUserCalendarsNow(userID); err != nil {
+		// Continue with cached data even if sync fails
+		h.logger.Error("Failed to sync calendars", zap.Error(err), zap.Uint64("user_id", userID))
+	}
+}
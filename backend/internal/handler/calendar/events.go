@@ -0,0 +1,336 @@
+package calendar
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/NathanWasTaken/timely/backend/internal/middleware"
+	"github.com/NathanWasTaken/timely/backend/internal/model"
+)
+
+// CreateCalendar creates a calendar owned by Timely
+// @Summary Create Calendar
+// @Description Creates a calendar whose events are created, updated and deleted through the API instead of being imported. Sync never modifies its events.
+// @Tags Calendar
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body model.CalendarCreateRequest true "Calendar create request"
+// @Success 201 {object} model.CalendarCreateResponse "Calendar created successfully"
+// @Failure 400 {object} model.ErrorResponse "Bad Request - Invalid request body"
+// @Failure 401 {object} model.ErrorResponse "Unauthorized - Authentication required"
+// @Failure 500 {object} model.ErrorResponse "Internal server error"
+// @Router /api/calendars [post]
+func (h *CalendarHandler) CreateCalendar(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by JWT middleware)
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		h.logger.Error("User not found in context")
+		sendErrorResponse(w, "Authentication required", "authentication_required", http.StatusUnauthorized)
+		return
+	}
+
+	// Parse request body
+	var createRequest model.CalendarCreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&createRequest); err != nil {
+		h.logger.Error("Failed to decode request body", zap.Error(err))
+		sendErrorResponse(w, "Invalid request body", "invalid_request", http.StatusBadRequest)
+		return
+	}
+
+	calendar, err := h.calendarService.CreateCalendar(user.ID, &createRequest)
+	if err != nil {
+		h.logger.Error("Failed to create calendar", zap.Error(err), zap.Uint64("user_id", user.ID))
+
+		// Handle specific error cases
+		switch {
+		case strings.HasPrefix(err.Error(), "invalid calendar"):
+			sendErrorResponse(w, err.Error(), "invalid_calendar", http.StatusBadRequest)
+		default:
+			sendErrorResponse(w, "Failed to create calendar", "calendar_create_error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	// Create success response
+	response := model.CalendarCreateResponse{
+		Success:  true,
+		Message:  "Calendar created successfully",
+		Calendar: calendar,
+	}
+
+	// Send response
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		h.logger.Error("Failed to encode response", zap.Error(err))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	h.logger.Info("Successfully created calendar",
+		zap.Uint64("user_id", user.ID),
+		zap.Uint64("calendar_id", calendar.ID))
+}
+
+// CreateEvent creates an event in a Timely calendar
+// @Summary Create Event
+// @Description Creates an event in a calendar owned by Timely, or in a Google calendar with write-back enabled, where it is pushed to Google by the next sync. Start and end are RFC 3339 date-times, or dates (YYYY-MM-DD) for all-day events whose end date is exclusive.
+// @Tags Calendar
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Calendar ID"
+// @Param request body model.CalendarEventRequest true "Event create request"
+// @Success 201 {object} model.CalendarEventResponse "Event created successfully"
+// @Failure 400 {object} model.ErrorResponse "Bad Request - Invalid request body, not a Timely calendar or write-back disabled"
+// @Failure 401 {object} model.ErrorResponse "Unauthorized - Authentication required"
+// @Failure 404 {object} model.ErrorResponse "Not Found - Calendar not found or access denied"
+// @Failure 500 {object} model.ErrorResponse "Internal server error"
+// @Router /api/calendars/{id}/events [post]
+func (h *CalendarHandler) CreateEvent(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by JWT middleware)
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		h.logger.Error("User not found in context")
+		sendErrorResponse(w, "Authentication required", "authentication_required", http.StatusUnauthorized)
+		return
+	}
+
+	// Parse request body
+	var eventRequest model.CalendarEventRequest
+	if err := json.NewDecoder(r.Body).Decode(&eventRequest); err != nil {
+		h.logger.Error("Failed to decode request body", zap.Error(err))
+		sendErrorResponse(w, "Invalid request body", "invalid_request", http.StatusBadRequest)
+		return
+	}
+
+	event, err := h.calendarService.CreateEvent(user.ID, r.PathValue("id"), &eventRequest)
+	if err != nil {
+		h.logger.Error("Failed to create event", zap.Error(err), zap.Uint64("user_id", user.ID))
+		h.sendEventErrorResponse(w, err, "Failed to create event", "event_create_error")
+		return
+	}
+
+	h.sendEventResponse(w, http.StatusCreated, "Event created successfully", event)
+}
+
+// UpdateEvent replaces an event of a Timely calendar
+// @Summary Update Event
+// @Description Replaces the fields of an event in a calendar owned by Timely, or in a Google calendar with write-back enabled
+// @Tags Calendar
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Calendar ID"
+// @Param eventId path string true "Event ID"
+// @Param request body model.CalendarEventRequest true "Event update request"
+// @Success 200 {object} model.CalendarEventResponse "Event updated successfully"
+// @Failure 400 {object} model.ErrorResponse "Bad Request - Invalid request body, not a Timely calendar or write-back disabled"
+// @Failure 401 {object} model.ErrorResponse "Unauthorized - Authentication required"
+// @Failure 404 {object} model.ErrorResponse "Not Found - Calendar or event not found"
+// @Failure 500 {object} model.ErrorResponse "Internal server error"
+// @Router /api/calendars/{id}/events/{eventId} [put]
+func (h *CalendarHandler) UpdateEvent(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by JWT middleware)
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		h.logger.Error("User not found in context")
+		sendErrorResponse(w, "Authentication required", "authentication_required", http.StatusUnauthorized)
+		return
+	}
+
+	// Parse request body
+	var eventRequest model.CalendarEventRequest
+	if err := json.NewDecoder(r.Body).Decode(&eventRequest); err != nil {
+		h.logger.Error("Failed to decode request body", zap.Error(err))
+		sendErrorResponse(w, "Invalid request body", "invalid_request", http.StatusBadRequest)
+		return
+	}
+
+	event, err := h.calendarService.UpdateEvent(user.ID, r.PathValue("id"), r.PathValue("eventId"), &eventRequest)
+	if err != nil {
+		h.logger.Error("Failed to update event", zap.Error(err), zap.Uint64("user_id", user.ID))
+		h.sendEventErrorResponse(w, err, "Failed to update event", "event_update_error")
+		return
+	}
+
+	h.sendEventResponse(w, http.StatusOK, "Event updated successfully", event)
+}
+
+// PatchEvent changes how an event is shared
+// @Summary Update Event Sharing
+// @Description Changes the visibility of an event in any of the user's calendars and sets a title override that is shown instead of the calendar's event redaction. An empty title override removes it. Both settings are kept when the event is later updated by sync.
+// @Tags Calendar
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Calendar ID"
+// @Param eventId path string true "Event ID"
+// @Param request body model.CalendarEventPatchRequest true "Event patch request"
+// @Success 200 {object} model.CalendarEventResponse "Event updated successfully"
+// @Failure 400 {object} model.ErrorResponse "Bad Request - Invalid request body"
+// @Failure 401 {object} model.ErrorResponse "Unauthorized - Authentication required"
+// @Failure 404 {object} model.ErrorResponse "Not Found - Calendar or event not found"
+// @Failure 500 {object} model.ErrorResponse "Internal server error"
+// @Router /api/calendars/{id}/events/{eventId} [patch]
+func (h *CalendarHandler) PatchEvent(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by JWT middleware)
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		h.logger.Error("User not found in context")
+		sendErrorResponse(w, "Authentication required", "authentication_required", http.StatusUnauthorized)
+		return
+	}
+
+	// Parse request body
+	var patchRequest model.CalendarEventPatchRequest
+	if err := json.NewDecoder(r.Body).Decode(&patchRequest); err != nil {
+		h.logger.Error("Failed to decode request body", zap.Error(err))
+		sendErrorResponse(w, "Invalid request body", "invalid_request", http.StatusBadRequest)
+		return
+	}
+
+	event, err := h.calendarService.PatchEvent(user.ID, r.PathValue("id"), r.PathValue("eventId"), &patchRequest)
+	if err != nil {
+		h.logger.Error("Failed to patch event", zap.Error(err), zap.Uint64("user_id", user.ID))
+		h.sendEventErrorResponse(w, err, "Failed to update event", "event_update_error")
+		return
+	}
+
+	h.sendEventResponse(w, http.StatusOK, "Event updated successfully", event)
+}
+
+// ResolveEventConflict resolves a conflict between a change made in Timely and one made on Google
+// @Summary Resolve Event Write Conflict
+// @Description Resolves the conflict of a change made in Timely to an event of a write-back calendar with a change made to the same fields on Google. Conflicting changes are not pushed until resolved. Keeping "timely" pushes Timely's version over the change made on Google, keeping "google" drops the change made in Timely. The event is omitted from the response if it is deleted.
+// @Tags Calendar
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Calendar ID"
+// @Param eventId path string true "Event ID"
+// @Param request body model.EventConflictResolveRequest true "Conflict resolution request"
+// @Success 200 {object} model.CalendarEventResponse "Conflict resolved successfully"
+// @Failure 400 {object} model.ErrorResponse "Bad Request - Invalid resolution or no conflicting change"
+// @Failure 401 {object} model.ErrorResponse "Unauthorized - Authentication required"
+// @Failure 404 {object} model.ErrorResponse "Not Found - Calendar or event not found"
+// @Failure 409 {object} model.ErrorResponse "Conflict - Calendar is being synced"
+// @Failure 500 {object} model.ErrorResponse "Internal server error"
+// @Router /api/calendars/{id}/events/{eventId}/resolve [post]
+func (h *CalendarHandler) ResolveEventConflict(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by JWT middleware)
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		h.logger.Error("User not found in context")
+		sendErrorResponse(w, "Authentication required", "authentication_required", http.StatusUnauthorized)
+		return
+	}
+
+	// Parse request body
+	var resolveRequest model.EventConflictResolveRequest
+	if err := json.NewDecoder(r.Body).Decode(&resolveRequest); err != nil {
+		h.logger.Error("Failed to decode request body", zap.Error(err))
+		sendErrorResponse(w, "Invalid request body", "invalid_request", http.StatusBadRequest)
+		return
+	}
+
+	event, err := h.calendarService.ResolveEventWriteConflict(user.ID, r.PathValue("id"), r.PathValue("eventId"), &resolveRequest)
+	if err != nil {
+		h.logger.Error("Failed to resolve event conflict", zap.Error(err), zap.Uint64("user_id", user.ID))
+		h.sendEventErrorResponse(w, err, "Failed to resolve event conflict", "event_conflict_error")
+		return
+	}
+
+	h.sendEventResponse(w, http.StatusOK, "Conflict resolved successfully", event)
+}
+
+// DeleteEvent deletes an event of a Timely calendar
+// @Summary Delete Event
+// @Description Deletes an event from a calendar owned by Timely, or from a Google calendar with write-back enabled
+// @Tags Calendar
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Calendar ID"
+// @Param eventId path string true "Event ID"
+// @Success 200 {object} model.CalendarEventDeleteResponse "Event deleted successfully"
+// @Failure 400 {object} model.ErrorResponse "Bad Request - Not a Timely calendar or write-back disabled"
+// @Failure 401 {object} model.ErrorResponse "Unauthorized - Authentication required"
+// @Failure 404 {object} model.ErrorResponse "Not Found - Calendar or event not found"
+// @Failure 500 {object} model.ErrorResponse "Internal server error"
+// @Router /api/calendars/{id}/events/{eventId} [delete]
+func (h *CalendarHandler) DeleteEvent(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by JWT middleware)
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		h.logger.Error("User not found in context")
+		sendErrorResponse(w, "Authentication required", "authentication_required", http.StatusUnauthorized)
+		return
+	}
+
+	if err := h.calendarService.DeleteEvent(user.ID, r.PathValue("id"), r.PathValue("eventId")); err != nil {
+		h.logger.Error("Failed to delete event", zap.Error(err), zap.Uint64("user_id", user.ID))
+		h.sendEventErrorResponse(w, err, "Failed to delete event", "event_delete_error")
+		return
+	}
+
+	// Create success response
+	response := model.CalendarEventDeleteResponse{
+		Success: true,
+		Message: "Event deleted successfully",
+	}
+
+	// Send response
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		h.logger.Error("Failed to encode response", zap.Error(err))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+}
+
+// sendEventResponse sends a single event
+func (h *CalendarHandler) sendEventResponse(w http.ResponseWriter, statusCode int, message string, event *model.CalendarEvent) {
+	response := model.CalendarEventResponse{
+		Success: true,
+		Message: message,
+		Event:   event,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		h.logger.Error("Failed to encode response", zap.Error(err))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+}
+
+// sendEventErrorResponse maps event service errors to error responses
+func (h *CalendarHandler) sendEventErrorResponse(w http.ResponseWriter, err error, message, errorType string) {
+	switch {
+	case err.Error() == "calendar not found or access denied":
+		sendErrorResponse(w, "Calendar not found or access denied", "calendar_not_found", http.StatusNotFound)
+	case err.Error() == "failed to find calendar: record not found":
+		sendErrorResponse(w, "Calendar not found", "calendar_not_found", http.StatusNotFound)
+	case err.Error() == "event not found", err.Error() == "failed to find event: record not found":
+		sendErrorResponse(w, "Event not found", "event_not_found", http.StatusNotFound)
+	case err.Error() == "calendar is not a Timely calendar":
+		sendErrorResponse(w, "Events can only be edited in Timely calendars", "invalid_calendar_source", http.StatusBadRequest)
+	case err.Error() == "calendar write-back is not enabled":
+		sendErrorResponse(w, "Enable write-back to edit the events of this Google calendar", "write_back_disabled", http.StatusBadRequest)
+	case err.Error() == "calendar is being synced":
+		sendErrorResponse(w, "Calendar is being synced, try again shortly", "calendar_busy", http.StatusConflict)
+	case strings.HasPrefix(err.Error(), "invalid event"):
+		sendErrorResponse(w, err.Error(), "invalid_event", http.StatusBadRequest)
+	default:
+		sendErrorResponse(w, message, errorType, http.StatusInternalServerError)
+	}
+}
@@ -174,27 +174,19 @@ type SyncHistoricalDataRequest struct {
 	EndDate    string `json:"end_date" validate:"required"`   // Format: 2006-01-02
 }
 
-// SyncHistoricalDataResponse represents the response for syncing historical data
-type SyncHistoricalDataResponse struct {
-	Success       bool   `json:"success"`
-	Message       string `json:"message"`
-	EventsAdded   int    `json:"events_added"`
-	EventsUpdated int    `json:"events_updated"`
-	EventsDeleted int    `json:"events_deleted"`
-}
-
-// SyncHistoricalData syncs historical data for a specific Google calendar within a date range
+// SyncHistoricalData starts a backfill job fetching the events of a Google calendar within a date range
 // @Summary Sync Historical Data
-// @Description Syncs historical events for a specific Google calendar within a specified date range (max 2 years)
+// @Description Queues a job fetching the events of a Google calendar within a date range (max 2 years) in chunks. Poll the returned job for its progress
 // @Tags Calendar
 // @Accept json
 // @Produce json
 // @Security BearerAuth
 // @Param request body SyncHistoricalDataRequest true "Historical sync request"
-// @Success 200 {object} SyncHistoricalDataResponse "Historical data synced successfully"
+// @Success 202 {object} model.BackfillJobResponse "Historical sync started"
 // @Failure 400 {object} model.ErrorResponse "Bad Request - Invalid request body or date range"
 // @Failure 401 {object} model.ErrorResponse "Unauthorized - Authentication required"
 // @Failure 404 {object} model.ErrorResponse "Not Found - Calendar not found"
+// @Failure 409 {object} model.ErrorResponse "Conflict - A historical sync of the calendar is already running"
 // @Failure 500 {object} model.ErrorResponse "Internal server error"
 // @Router /api/calendars/google/sync/historical [post]
 func (h *CalendarHandler) SyncHistoricalData(w http.ResponseWriter, r *http.Request) {
@@ -251,43 +243,69 @@ func (h *CalendarHandler) SyncHistoricalData(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	h.logger.Info("Syncing historical data for calendar",
+	h.logger.Info("Starting historical sync for calendar",
 		zap.Uint64("user_id", user.ID),
 		zap.String("calendar_id", req.CalendarID),
 		zap.String("start_date", req.StartDate),
 		zap.String("end_date", req.EndDate))
 
-	// Force sync the calendar with historical data (this will use the service's existing sync logic)
-	err = h.calendarService.SyncCalendarEventsWithForce(user.ID, req.CalendarID, true)
+	job, err := h.calendarService.CreateBackfillJob(user.ID, req.CalendarID, startDate, endDate)
 	if err != nil {
-		h.logger.Error("Failed to sync historical data", zap.Error(err), zap.Uint64("user_id", user.ID))
+		h.logger.Error("Failed to start historical sync", zap.Error(err), zap.Uint64("user_id", user.ID))
 
 		// Handle specific error cases
 		switch {
-		case err.Error() == "failed to get Google account: record not found":
-			sendErrorResponse(w, "Google account not connected", "google_token_not_found", http.StatusNotFound)
-		case err.Error() == "Google account not properly configured with OAuth tokens":
-			sendErrorResponse(w, "Google account not properly configured", "google_account_not_configured", http.StatusNotFound)
 		case err.Error() == "failed to find local calendar: record not found":
 			sendErrorResponse(w, "Calendar not found or not imported", "calendar_not_found", http.StatusNotFound)
+		case err.Error() == "calendar is not a Google calendar":
+			sendErrorResponse(w, "Calendar is not a Google calendar", "invalid_calendar_source", http.StatusBadRequest)
+		case err.Error() == "backfill already running":
+			sendErrorResponse(w, "A historical sync of this calendar is already running", "backfill_already_running", http.StatusConflict)
 		default:
-			sendErrorResponse(w, "Failed to sync historical data", "historical_sync_error", http.StatusInternalServerError)
+			sendErrorResponse(w, "Failed to start historical sync", "historical_sync_error", http.StatusInternalServerError)
 		}
 		return
 	}
 
-	// Create success response
-	// Note: The current sync implementation doesn't return detailed counts,
-	// so we'll return a generic success message
-	response := SyncHistoricalDataResponse{
-		Success:       true,
-		Message:       "Historical data sync completed successfully",
-		EventsAdded:   -1, // -1 indicates count not available
-		EventsUpdated: -1,
-		EventsDeleted: -1,
+	h.sendBackfillJobResponse(w, http.StatusAccepted, "Historical sync started", job)
+
+	h.logger.Info("Successfully started historical sync",
+		zap.Uint64("user_id", user.ID),
+		zap.Uint64("job_id", job.ID))
+}
+
+// GetBackfillJobs lists the historical sync jobs of the user
+// @Summary Get Historical Sync Jobs
+// @Description Lists the historical sync jobs of the authenticated user with their progress, the most recent first
+// @Tags Calendar
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} model.BackfillJobListResponse "Backfill jobs retrieved successfully"
+// @Failure 401 {object} model.ErrorResponse "Unauthorized - Authentication required"
+// @Failure 500 {object} model.ErrorResponse "Internal server error"
+// @Router /api/calendars/google/sync/historical [get]
+func (h *CalendarHandler) GetBackfillJobs(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by JWT middleware)
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		h.logger.Error("User not found in context")
+		sendErrorResponse(w, "Authentication required", "authentication_required", http.StatusUnauthorized)
+		return
+	}
+
+	jobs, err := h.calendarService.GetBackfillJobs(user.ID)
+	if err != nil {
+		h.logger.Error("Failed to get backfill jobs", zap.Error(err), zap.Uint64("user_id", user.ID))
+		sendErrorResponse(w, "Failed to retrieve historical sync jobs", "backfill_fetch_error", http.StatusInternalServerError)
+		return
+	}
+
+	response := model.BackfillJobListResponse{
+		Success: true,
+		Message: "Backfill jobs retrieved successfully",
+		Jobs:    jobs,
 	}
 
-	// Send response
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 
@@ -296,8 +314,101 @@ func (h *CalendarHandler) SyncHistoricalData(w http.ResponseWriter, r *http.Requ
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
+}
+
+// GetBackfillJob returns the progress of a historical sync job
+// @Summary Get Historical Sync Job
+// @Description Returns a historical sync job with its progress: chunks done, pages fetched, events stored and errors
+// @Tags Calendar
+// @Produce json
+// @Security BearerAuth
+// @Param jobId path string true "Backfill job ID"
+// @Success 200 {object} model.BackfillJobResponse "Backfill job retrieved successfully"
+// @Failure 401 {object} model.ErrorResponse "Unauthorized - Authentication required"
+// @Failure 404 {object} model.ErrorResponse "Not Found - Backfill job not found"
+// @Failure 500 {object} model.ErrorResponse "Internal server error"
+// @Router /api/calendars/google/sync/historical/{jobId} [get]
+func (h *CalendarHandler) GetBackfillJob(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by JWT middleware)
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		h.logger.Error("User not found in context")
+		sendErrorResponse(w, "Authentication required", "authentication_required", http.StatusUnauthorized)
+		return
+	}
+
+	job, err := h.calendarService.GetBackfillJob(user.ID, r.PathValue("jobId"))
+	if err != nil {
+		h.logger.Error("Failed to get backfill job", zap.Error(err), zap.Uint64("user_id", user.ID))
+		h.sendBackfillJobError(w, err, "Failed to retrieve historical sync job", "backfill_fetch_error")
+		return
+	}
+
+	h.sendBackfillJobResponse(w, http.StatusOK, "Backfill job retrieved successfully", job)
+}
+
+// CancelBackfillJob cancels a historical sync job
+// @Summary Cancel Historical Sync Job
+// @Description Cancels a historical sync job that has not finished. Events already stored are kept
+// @Tags Calendar
+// @Produce json
+// @Security BearerAuth
+// @Param jobId path string true "Backfill job ID"
+// @Success 200 {object} model.BackfillJobResponse "Historical sync cancelled"
+// @Failure 401 {object} model.ErrorResponse "Unauthorized - Authentication required"
+// @Failure 404 {object} model.ErrorResponse "Not Found - Backfill job not found"
+// @Failure 409 {object} model.ErrorResponse "Conflict - Backfill job already finished"
+// @Failure 500 {object} model.ErrorResponse "Internal server error"
+// @Router /api/calendars/google/sync/historical/{jobId}/cancel [post]
+func (h *CalendarHandler) CancelBackfillJob(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by JWT middleware)
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		h.logger.Error("User not found in context")
+		sendErrorResponse(w, "Authentication required", "authentication_required", http.StatusUnauthorized)
+		return
+	}
 
-	h.logger.Info("Successfully synced historical data",
+	job, err := h.calendarService.CancelBackfillJob(user.ID, r.PathValue("jobId"))
+	if err != nil {
+		h.logger.Error("Failed to cancel backfill job", zap.Error(err), zap.Uint64("user_id", user.ID))
+		h.sendBackfillJobError(w, err, "Failed to cancel historical sync job", "backfill_cancel_error")
+		return
+	}
+
+	h.sendBackfillJobResponse(w, http.StatusOK, "Historical sync cancelled", job)
+
+	h.logger.Info("Cancelled historical sync",
 		zap.Uint64("user_id", user.ID),
-		zap.String("calendar_id", req.CalendarID))
+		zap.Uint64("job_id", job.ID))
+}
+
+// sendBackfillJobError maps backfill job service errors to error responses
+func (h *CalendarHandler) sendBackfillJobError(w http.ResponseWriter, err error, message, errorType string) {
+	switch {
+	case err.Error() == "backfill job not found":
+		sendErrorResponse(w, "Backfill job not found", "backfill_job_not_found", http.StatusNotFound)
+	case err.Error() == "backfill job already finished":
+		sendErrorResponse(w, "Backfill job already finished", "backfill_job_finished", http.StatusConflict)
+	default:
+		sendErrorResponse(w, message, errorType, http.StatusInternalServerError)
+	}
+}
+
+// sendBackfillJobResponse sends a single backfill job
+func (h *CalendarHandler) sendBackfillJobResponse(w http.ResponseWriter, statusCode int, message string, job *model.BackfillJob) {
+	response := model.BackfillJobResponse{
+		Success: true,
+		Message: message,
+		Job:     job,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		h.logger.Error("Failed to encode response", zap.Error(err))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
 }
@@ -12,35 +12,41 @@ import (
 
 	"github.com/NathanWasTaken/timely/backend/internal/middleware"
 	"github.com/NathanWasTaken/timely/backend/internal/model"
+	"github.com/NathanWasTaken/timely/backend/internal/service"
 )
 
 // ImportICSRequest represents the request body for importing an ICS file
 type ImportICSRequest struct {
+	CalendarID   string `json:"calendar_id,omitempty"` // Existing ICS calendar to re-import into
 	CalendarName string `json:"calendar_name,omitempty"`
 	ICSData      string `json:"ics_data" validate:"required"`
 }
 
 // ImportICSResponse represents the response for importing an ICS file
 type ImportICSResponse struct {
-	Success     bool            `json:"success"`
-	Message     string          `json:"message"`
-	Calendar    *model.Calendar `json:"calendar"`
-	EventsCount int             `json:"events_count"`
+	Success     bool                `json:"success"`
+	Message     string              `json:"message"`
+	Calendar    *model.Calendar     `json:"calendar"`
+	EventsCount int                 `json:"events_count"`
+	Changes     *service.SyncResult `json:"changes,omitempty"` // Only set when re-importing into an existing calendar
 }
 
-// ImportICS imports an ICS file and creates a calendar with events
+// ImportICS imports an ICS file and creates a calendar with events, or re-imports it into an existing calendar
 // @Summary Import ICS File
-// @Description Imports an ICS file via JSON body or file upload. Calendar name is extracted from ICS properties (X-WR-CALNAME) or falls back to "Untitled Calendar"
+// @Description Imports an ICS file via JSON body or file upload. Calendar name is extracted from ICS properties (X-WR-CALNAME) or falls back to "Untitled Calendar". If calendar_id is given, the file is merged into that existing ICS calendar instead: events are upserted by UID and RECURRENCE-ID, events missing from the file are removed, and the calendar keeps its settings
 // @Tags Calendar
 // @Accept json,multipart/form-data
 // @Produce json
 // @Security BearerAuth
 // @Param request body ImportICSRequest false "Import ICS request (JSON) - calendar_name is optional"
+// @Param calendar_id formData string false "Existing ICS calendar to re-import into (optional)"
 // @Param calendar_name formData string false "Calendar name override (optional - will use ICS properties if not provided)"
 // @Param ics_file formData file true "ICS file to upload (required for file upload)"
+// @Success 200 {object} ImportICSResponse "ICS file re-imported into an existing calendar successfully"
 // @Success 201 {object} ImportICSResponse "ICS file imported successfully"
-// @Failure 400 {object} model.ErrorResponse "Bad Request - Invalid request body or ICS data"
+// @Failure 400 {object} model.ErrorResponse "Bad Request - Invalid request body, ICS data or target calendar"
 // @Failure 401 {object} model.ErrorResponse "Unauthorized - Authentication required"
+// @Failure 404 {object} model.ErrorResponse "Not Found - Target calendar not found or access denied"
 // @Failure 500 {object} model.ErrorResponse "Internal server error"
 // @Router /api/calendars/ics [post]
 func (h *CalendarHandler) ImportICS(w http.ResponseWriter, r *http.Request) {
@@ -52,14 +58,14 @@ func (h *CalendarHandler) ImportICS(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var providedCalendarName, icsData string
+	var req *ImportICSRequest
 	var err error
 
 	// Check Content-Type to determine if it's JSON or multipart form
 	contentType := r.Header.Get("Content-Type")
 	if strings.HasPrefix(contentType, "multipart/form-data") {
 		// Handle file upload
-		providedCalendarName, icsData, err = h.handleFileUpload(r)
+		req, err = h.handleFileUpload(r)
 		if err != nil {
 			h.logger.Error("Failed to handle file upload", zap.Error(err))
 			sendErrorResponse(w, err.Error(), "file_upload_error", http.StatusBadRequest)
@@ -67,7 +73,7 @@ func (h *CalendarHandler) ImportICS(w http.ResponseWriter, r *http.Request) {
 		}
 	} else {
 		// Handle JSON request
-		providedCalendarName, icsData, err = h.handleJSONRequest(r)
+		req, err = h.handleJSONRequest(r)
 		if err != nil {
 			h.logger.Error("Failed to handle JSON request", zap.Error(err))
 			sendErrorResponse(w, err.Error(), "json_request_error", http.StatusBadRequest)
@@ -76,23 +82,28 @@ func (h *CalendarHandler) ImportICS(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Parse ICS data first to extract calendar name
-	calendar, events, err := h.parseICSData(icsData)
+	calendar, events, err := h.parseICSData(req.ICSData)
 	if err != nil {
 		h.logger.Error("Failed to parse ICS data", zap.Error(err))
 		sendErrorResponse(w, "Failed to parse ICS data: "+err.Error(), "invalid_ics_data", http.StatusBadRequest)
 		return
 	}
 
+	if req.CalendarID != "" {
+		h.reimportICS(w, user.ID, req, calendar, events)
+		return
+	}
+
 	// Determine final calendar name: provided name takes priority, then ICS properties, then fallback
-	calendarName := providedCalendarName
+	calendarName := req.CalendarName
 	if calendarName == "" {
-		calendarName = h.extractCalendarName(calendar)
+		calendarName = service.ExtractICSCalendarName(calendar)
 	}
 
 	h.logger.Info("Importing ICS file for user",
 		zap.Uint64("user_id", user.ID),
 		zap.String("calendar_name", calendarName),
-		zap.String("provided_name", providedCalendarName))
+		zap.String("provided_name", req.CalendarName))
 
 	// Create calendar and import events
 	createdCalendar, eventsCount, err := h.calendarService.ImportICSCalendar(user.ID, calendarName, calendar, events)
@@ -126,36 +137,92 @@ func (h *CalendarHandler) ImportICS(w http.ResponseWriter, r *http.Request) {
 		zap.Int("events_count", eventsCount))
 }
 
+// reimportICS merges parsed ICS events into the existing calendar targeted by the import request
+func (h *CalendarHandler) reimportICS(w http.ResponseWriter, userID uint64, req *ImportICSRequest, icsCalendar *ics.Calendar, events []*ics.VEvent) {
+	h.logger.Info("Re-importing ICS file for user",
+		zap.Uint64("user_id", userID),
+		zap.String("calendar_id", req.CalendarID))
+
+	calendar, eventsCount, changes, err := h.calendarService.ReimportICSCalendar(userID, req.CalendarID, req.CalendarName, icsCalendar, events)
+	if err != nil {
+		h.logger.Error("Failed to re-import ICS calendar", zap.Error(err), zap.Uint64("user_id", userID))
+
+		// Handle specific error cases
+		switch {
+		case err.Error() == "calendar not found or access denied":
+			sendErrorResponse(w, "Calendar not found or access denied", "calendar_not_found", http.StatusNotFound)
+		case err.Error() == "failed to find calendar: record not found":
+			sendErrorResponse(w, "Calendar not found", "calendar_not_found", http.StatusNotFound)
+		case err.Error() == "calendar is not an ICS calendar":
+			sendErrorResponse(w, "Only ICS calendars can be re-imported", "invalid_import_target", http.StatusBadRequest)
+		case err.Error() == "calendar is subscribed to a remote feed":
+			sendErrorResponse(w, "Subscribed calendars are refreshed from their feed and cannot be re-imported", "invalid_import_target", http.StatusBadRequest)
+		default:
+			sendErrorResponse(w, "Failed to re-import ICS calendar", "calendar_import_error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	// Create success response
+	response := ImportICSResponse{
+		Success:     true,
+		Message:     "ICS file re-imported successfully",
+		Calendar:    calendar,
+		EventsCount: eventsCount,
+		Changes:     changes,
+	}
+
+	// Send response
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		h.logger.Error("Failed to encode response", zap.Error(err))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	h.logger.Info("Successfully re-imported ICS file",
+		zap.Uint64("user_id", userID),
+		zap.String("calendar_id", req.CalendarID),
+		zap.Int("added_events", changes.Added),
+		zap.Int("updated_events", changes.Updated),
+		zap.Int("removed_events", changes.Removed))
+}
+
 // handleJSONRequest handles JSON request body for ICS import
-func (h *CalendarHandler) handleJSONRequest(r *http.Request) (string, string, error) {
+func (h *CalendarHandler) handleJSONRequest(r *http.Request) (*ImportICSRequest, error) {
 	var req ImportICSRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		return "", "", fmt.Errorf("invalid request body: %w", err)
+		return nil, fmt.Errorf("invalid request body: %w", err)
 	}
 
 	if req.ICSData == "" {
-		return "", "", fmt.Errorf("ICS data is required")
+		return nil, fmt.Errorf("ICS data is required")
 	}
 
 	// Calendar name is now optional - can be extracted from ICS
-	return req.CalendarName, req.ICSData, nil
+	return &req, nil
 }
 
 // handleFileUpload handles multipart form file upload for ICS import
-func (h *CalendarHandler) handleFileUpload(r *http.Request) (string, string, error) {
+func (h *CalendarHandler) handleFileUpload(r *http.Request) (*ImportICSRequest, error) {
 	// Parse multipart form
 	err := r.ParseMultipartForm(10 << 20) // 10 MB max
 	if err != nil {
-		return "", "", fmt.Errorf("failed to parse form data: %w", err)
+		return nil, fmt.Errorf("failed to parse form data: %w", err)
 	}
 
-	// Get calendar name (now optional)
-	calendarName := r.FormValue("calendar_name")
+	// Get calendar name (now optional) and target calendar (optional)
+	req := &ImportICSRequest{
+		CalendarID:   r.FormValue("calendar_id"),
+		CalendarName: r.FormValue("calendar_name"),
+	}
 
 	// Get uploaded file
 	file, header, err := r.FormFile("ics_file")
 	if err != nil {
-		return "", "", fmt.Errorf("ICS file is required: %w", err)
+		return nil, fmt.Errorf("ICS file is required: %w", err)
 	}
 	defer file.Close()
 
@@ -165,10 +232,11 @@ func (h *CalendarHandler) handleFileUpload(r *http.Request) (string, string, err
 	// Read file content
 	icsData, err := io.ReadAll(file)
 	if err != nil {
-		return "", "", fmt.Errorf("failed to read ICS file: %w", err)
+		return nil, fmt.Errorf("failed to read ICS file: %w", err)
 	}
+	req.ICSData = string(icsData)
 
-	return calendarName, string(icsData), nil
+	return req, nil
 }
 
 // parseICSData parses ICS data and extracts calendar and event information
@@ -191,38 +259,181 @@ func (h *CalendarHandler) parseICSData(icsData string) (*ics.Calendar, []*ics.VE
 	return cal, events, nil
 }
 
-// extractCalendarName extracts calendar name from ICS properties
-func (h *CalendarHandler) extractCalendarName(cal *ics.Calendar) string {
-	// Try to get X-WR-CALNAME property (common non-standard property for calendar name)
-	for _, prop := range cal.CalendarProperties {
-		if prop.IANAToken == "X-WR-CALNAME" && prop.Value != "" {
-			return prop.Value
-		}
+// SubscribeICSRequest represents the request body for subscribing to a remote ICS feed
+type SubscribeICSRequest struct {
+	URL          string `json:"url" validate:"required" example:"webcal://example.com/calendar.ics"`
+	CalendarName string `json:"calendar_name,omitempty"`
+}
+
+// RefreshCalendarResponse represents the response for refreshing a subscribed ICS calendar
+type RefreshCalendarResponse struct {
+	Success  bool                `json:"success"`
+	Message  string              `json:"message"`
+	Calendar *model.Calendar     `json:"calendar"`
+	Changes  *service.SyncResult `json:"changes"`
+}
+
+// SubscribeICS subscribes to a remote ICS feed and creates a calendar that is refreshed periodically
+// @Summary Subscribe to ICS Feed
+// @Description Subscribes to a remote ICS feed by webcal, http or https URL. The feed is fetched immediately and then re-fetched on a schedule. Calendar name is extracted from ICS properties (X-WR-CALNAME) unless provided
+// @Tags Calendar
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body SubscribeICSRequest true "Subscribe ICS request"
+// @Success 201 {object} ImportICSResponse "ICS feed subscribed successfully"
+// @Failure 400 {object} model.ErrorResponse "Bad Request - Invalid request body or subscription URL"
+// @Failure 401 {object} model.ErrorResponse "Unauthorized - Authentication required"
+// @Failure 502 {object} model.ErrorResponse "Bad Gateway - The ICS feed could not be fetched or parsed"
+// @Failure 500 {object} model.ErrorResponse "Internal server error"
+// @Router /api/calendars/ics/subscribe [post]
+func (h *CalendarHandler) SubscribeICS(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by JWT middleware)
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		h.logger.Error("User not found in context")
+		sendErrorResponse(w, "Authentication required", "authentication_required", http.StatusUnauthorized)
+		return
 	}
 
-	// Try to get other calendar-level properties
-	for _, prop := range cal.CalendarProperties {
-		// Try NAME property
-		if prop.IANAToken == "NAME" && prop.Value != "" {
-			return prop.Value
-		}
-		// Try SUMMARY property
-		if prop.IANAToken == "SUMMARY" && prop.Value != "" {
-			return prop.Value
+	var req SubscribeICSRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Error("Failed to decode request body", zap.Error(err))
+		sendErrorResponse(w, "Invalid request body", "invalid_request", http.StatusBadRequest)
+		return
+	}
+
+	if req.URL == "" {
+		sendErrorResponse(w, "Subscription URL is required", "missing_subscription_url", http.StatusBadRequest)
+		return
+	}
+
+	h.logger.Info("Subscribing to ICS feed for user",
+		zap.Uint64("user_id", user.ID),
+		zap.String("provided_name", req.CalendarName))
+
+	createdCalendar, eventsCount, err := h.calendarService.SubscribeICSCalendar(user.ID, req.URL, req.CalendarName)
+	if err != nil {
+		h.logger.Error("Failed to subscribe to ICS feed", zap.Error(err), zap.Uint64("user_id", user.ID))
+
+		// Handle specific error cases
+		switch {
+		case strings.HasPrefix(err.Error(), "invalid subscription URL"):
+			sendErrorResponse(w, err.Error(), "invalid_subscription_url", http.StatusBadRequest)
+		case strings.HasPrefix(err.Error(), "failed to fetch ICS feed"):
+			sendErrorResponse(w, "Failed to fetch ICS feed: "+strings.TrimPrefix(err.Error(), "failed to fetch ICS feed: "), "ics_fetch_error", http.StatusBadGateway)
+		default:
+			sendErrorResponse(w, "Failed to subscribe to ICS feed", "calendar_import_error", http.StatusInternalServerError)
 		}
+		return
 	}
 
-	// Try to extract from PRODID as last resort (clean it up)
-	for _, prop := range cal.CalendarProperties {
-		if prop.IANAToken == "PRODID" && prop.Value != "" {
-			prodId := strings.TrimSpace(prop.Value)
-			// Clean up common PRODID patterns
-			if !strings.HasPrefix(prodId, "-//") && !strings.Contains(prodId, "//") {
-				return prodId
-			}
+	// Create success response
+	response := ImportICSResponse{
+		Success:     true,
+		Message:     "ICS feed subscribed successfully",
+		Calendar:    createdCalendar,
+		EventsCount: eventsCount,
+	}
+
+	// Send response
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		h.logger.Error("Failed to encode response", zap.Error(err))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	h.logger.Info("Successfully subscribed to ICS feed",
+		zap.Uint64("user_id", user.ID),
+		zap.Uint64("calendar_id", createdCalendar.ID),
+		zap.Int("events_count", eventsCount))
+}
+
+// RefreshCalendar re-fetches the remote feed of a subscribed ICS calendar immediately
+// @Summary Refresh Subscribed Calendar
+// @Description Re-fetches the remote feed of a subscribed ICS calendar and merges the changes by event UID. The outcome of the fetch is recorded on the calendar
+// @Tags Calendar
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Calendar ID"
+// @Success 200 {object} RefreshCalendarResponse "Calendar refreshed successfully"
+// @Failure 400 {object} model.ErrorResponse "Bad Request - Calendar is not an ICS subscription"
+// @Failure 401 {object} model.ErrorResponse "Unauthorized - Authentication required"
+// @Failure 404 {object} model.ErrorResponse "Not Found - Calendar not found or access denied"
+// @Failure 409 {object} model.ErrorResponse "Conflict - Calendar is being refreshed"
+// @Failure 502 {object} model.ErrorResponse "Bad Gateway - The ICS feed could not be fetched or parsed"
+// @Failure 500 {object} model.ErrorResponse "Internal server error"
+// @Router /api/calendars/{id}/refresh [post]
+func (h *CalendarHandler) RefreshCalendar(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by JWT middleware)
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		h.logger.Error("User not found in context")
+		sendErrorResponse(w, "Authentication required", "authentication_required", http.StatusUnauthorized)
+		return
+	}
+
+	// Get calendar ID from URL path
+	calendarID := r.PathValue("id")
+	if calendarID == "" {
+		sendErrorResponse(w, "Calendar ID is required", "missing_calendar_id", http.StatusBadRequest)
+		return
+	}
+
+	h.logger.Info("Refreshing calendar for user",
+		zap.Uint64("user_id", user.ID),
+		zap.String("calendar_id", calendarID))
+
+	calendar, changes, err := h.calendarService.RefreshICSSubscription(user.ID, calendarID)
+	if err != nil {
+		h.logger.Error("Failed to refresh calendar", zap.Error(err), zap.Uint64("user_id", user.ID))
+
+		// Handle specific error cases
+		switch {
+		case err.Error() == "calendar not found or access denied":
+			sendErrorResponse(w, "Calendar not found or access denied", "calendar_not_found", http.StatusNotFound)
+		case err.Error() == "failed to find calendar: record not found":
+			sendErrorResponse(w, "Calendar not found", "calendar_not_found", http.StatusNotFound)
+		case err.Error() == "calendar is not an ICS subscription":
+			sendErrorResponse(w, "Calendar is not an ICS subscription", "not_a_subscription", http.StatusBadRequest)
+		case err.Error() == "calendar is being synced":
+			sendErrorResponse(w, "Calendar is being refreshed, try again shortly", "calendar_busy", http.StatusConflict)
+		case strings.HasPrefix(err.Error(), "failed to fetch ICS feed"):
+			sendErrorResponse(w, "Failed to fetch ICS feed: "+strings.TrimPrefix(err.Error(), "failed to fetch ICS feed: "), "ics_fetch_error", http.StatusBadGateway)
+		default:
+			sendErrorResponse(w, "Failed to refresh calendar", "calendar_refresh_error", http.StatusInternalServerError)
 		}
+		return
+	}
+
+	// Create success response
+	message := "Calendar refreshed successfully"
+	if changes.NotModified {
+		message = "Calendar is already up to date"
+	}
+
+	response := RefreshCalendarResponse{
+		Success:  true,
+		Message:  message,
+		Calendar: calendar,
+		Changes:  changes,
+	}
+
+	// Send response
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		h.logger.Error("Failed to encode response", zap.Error(err))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
 	}
 
-	// Default fallback
-	return "Untitled Calendar"
+	h.logger.Info("Successfully refreshed calendar",
+		zap.Uint64("user_id", user.ID),
+		zap.String("calendar_id", calendarID),
+		zap.Bool("not_modified", changes.NotModified))
 }
@@ -0,0 +1,134 @@
+package calendar
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/NathanWasTaken/timely/backend/internal/middleware"
+	"github.com/NathanWasTaken/timely/backend/internal/model"
+	"github.com/NathanWasTaken/timely/backend/internal/service"
+)
+
+// SearchEvents searches the events of the user's calendars
+// @Summary Search Events
+// @Description Finds the events of the user's calendars whose title, location or description contain every word of the query, as a word or the start of one. Results are ordered by start and paginated with limit and offset.
+// @Tags Calendar
+// @Produce json
+// @Security BearerAuth
+// @Param q query string true "Search query"
+// @Param start_timestamp query string false "Only events ending after this Unix timestamp"
+// @Param end_timestamp query string false "Only events starting before this Unix timestamp"
+// @Param calendar_id query []string false "Only events of these calendars, repeated or comma-separated" collectionFormat(multi)
+// @Param limit query int false "Maximum number of events returned (default 50, max 200)"
+// @Param offset query int false "Number of matching events skipped"
+// @Success 200 {object} model.EventSearchResponse "Events searched successfully"
+// @Failure 400 {object} model.ErrorResponse "Bad Request - Invalid query parameters"
+// @Failure 401 {object} model.ErrorResponse "Unauthorized - Authentication required"
+// @Failure 404 {object} model.ErrorResponse "Not Found - Calendar not found or access denied"
+// @Failure 500 {object} model.ErrorResponse "Internal server error"
+// @Router /api/events/search [get]
+func (h *CalendarHandler) SearchEvents(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by JWT middleware)
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		h.logger.Error("User not found in context")
+		sendErrorResponse(w, "Authentication required", "authentication_required", http.StatusUnauthorized)
+		return
+	}
+
+	params := r.URL.Query()
+	query := strings.TrimSpace(params.Get("q"))
+	if query == "" {
+		sendErrorResponse(w, "Search query is required", "missing_query", http.StatusBadRequest)
+		return
+	}
+
+	var startTime, endTime time.Time
+	if value := params.Get("start_timestamp"); value != "" {
+		timestamp, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			sendErrorResponse(w, "Invalid start timestamp format", "invalid_start_timestamp", http.StatusBadRequest)
+			return
+		}
+		startTime = time.Unix(timestamp, 0)
+	}
+	if value := params.Get("end_timestamp"); value != "" {
+		timestamp, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			sendErrorResponse(w, "Invalid end timestamp format", "invalid_end_timestamp", http.StatusBadRequest)
+			return
+		}
+		endTime = time.Unix(timestamp, 0)
+	}
+
+	var calendarIDs []string
+	for _, value := range params["calendar_id"] {
+		for _, id := range strings.Split(value, ",") {
+			if id = strings.TrimSpace(id); id != "" {
+				calendarIDs = append(calendarIDs, id)
+			}
+		}
+	}
+
+	limit := service.DefaultEventSearchLimit
+	if value := params.Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 || parsed > service.MaxEventSearchLimit {
+			sendErrorResponse(w, "Limit must be between 1 and 200", "invalid_limit", http.StatusBadRequest)
+			return
+		}
+		limit = parsed
+	}
+
+	offset := 0
+	if value := params.Get("offset"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 {
+			sendErrorResponse(w, "Offset must be a non-negative number", "invalid_offset", http.StatusBadRequest)
+			return
+		}
+		offset = parsed
+	}
+
+	events, more, err := h.calendarService.SearchEvents(user.ID, query, calendarIDs, startTime, endTime, limit, offset)
+	if err != nil {
+		h.logger.Error("Failed to search events", zap.Error(err), zap.Uint64("user_id", user.ID))
+
+		// Handle specific error cases
+		switch {
+		case err.Error() == "calendar not found or access denied":
+			sendErrorResponse(w, "Calendar not found or access denied", "calendar_not_found", http.StatusNotFound)
+		case strings.HasPrefix(err.Error(), "invalid search"):
+			sendErrorResponse(w, err.Error(), "invalid_search", http.StatusBadRequest)
+		default:
+			sendErrorResponse(w, "Failed to search events", "event_search_error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	// Create success response
+	response := model.EventSearchResponse{
+		Success: true,
+		Message: "Events searched successfully",
+		Events:  events,
+	}
+	if more {
+		nextOffset := offset + limit
+		response.NextOffset = &nextOffset
+	}
+
+	// Send response
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		h.logger.Error("Failed to encode response", zap.Error(err))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+}
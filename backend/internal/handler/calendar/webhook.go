@@ -0,0 +1,48 @@
+package calendar
+
+import (
+	"net/http"
+
+	"go.uber.org/zap"
+
+	"github.com/NathanWasTaken/timely/backend/internal/service"
+)
+
+// GoogleWebhook receives Google Calendar push notifications
+// @Summary Google Calendar Push Notification
+// @Description Receives a push notification for a watched Google calendar and syncs the calendar in the background. Notifications are authenticated by the channel token Timely registered with Google.
+// @Tags Calendar
+// @Param X-Goog-Channel-ID header string true "Channel ID"
+// @Param X-Goog-Channel-Token header string true "Channel token"
+// @Param X-Goog-Resource-ID header string true "Watched resource ID"
+// @Param X-Goog-Resource-State header string true "sync, exists or not_exists"
+// @Success 204 "Notification accepted"
+// @Failure 403 {object} model.ErrorResponse "Forbidden - Invalid channel token"
+// @Failure 404 {object} model.ErrorResponse "Not Found - Unknown channel"
+// @Router /api/webhooks/google [post]
+func (h *CalendarHandler) GoogleWebhook(w http.ResponseWriter, r *http.Request) {
+	notification := &service.GoogleNotification{
+		ChannelID:     r.Header.Get("X-Goog-Channel-ID"),
+		ChannelToken:  r.Header.Get("X-Goog-Channel-Token"),
+		ResourceID:    r.Header.Get("X-Goog-Resource-ID"),
+		ResourceState: r.Header.Get("X-Goog-Resource-State"),
+	}
+
+	if err := h.calendarService.HandleGoogleNotification(notification); err != nil {
+		h.logger.Warn("Rejected Google push notification",
+			zap.Error(err),
+			zap.String("channel_id", notification.ChannelID))
+
+		switch err.Error() {
+		case "watch channel not found":
+			sendErrorResponse(w, "Watch channel not found", "watch_channel_not_found", http.StatusNotFound)
+		case "invalid watch channel token":
+			sendErrorResponse(w, "Invalid watch channel token", "invalid_watch_channel_token", http.StatusForbidden)
+		default:
+			sendErrorResponse(w, "Failed to handle notification", "notification_error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,486 @@
+package dav
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/NathanWasTaken/timely/backend/internal/model"
+	"github.com/NathanWasTaken/timely/backend/internal/service"
+	"github.com/NathanWasTaken/timely/backend/pkg/caldav"
+)
+
+const (
+	// Prefix is the path the CalDAV server is mounted at
+	Prefix = "/dav"
+	// maxResourceSize caps the size of a calendar object written by a client
+	maxResourceSize = 1 << 20
+)
+
+// userContextKey is the key used to store the authenticated user in the request context
+type userContextKey struct{}
+
+// Property names served by the CalDAV server
+var (
+	propResourceType          = xml.Name{Space: caldav.NamespaceDAV, Local: "resourcetype"}
+	propDisplayName           = xml.Name{Space: caldav.NamespaceDAV, Local: "displayname"}
+	propCurrentUserPrincipal  = xml.Name{Space: caldav.NamespaceDAV, Local: "current-user-principal"}
+	propPrincipalURL          = xml.Name{Space: caldav.NamespaceDAV, Local: "principal-URL"}
+	propCurrentUserPrivileges = xml.Name{Space: caldav.NamespaceDAV, Local: "current-user-privilege-set"}
+	propGetETag               = xml.Name{Space: caldav.NamespaceDAV, Local: "getetag"}
+	propGetContentType        = xml.Name{Space: caldav.NamespaceDAV, Local: "getcontenttype"}
+	propCalendarHomeSet       = xml.Name{Space: caldav.NamespaceCalDAV, Local: "calendar-home-set"}
+	propCalendarDescription   = xml.Name{Space: caldav.NamespaceCalDAV, Local: "calendar-description"}
+	propCalendarTimeZone      = xml.Name{Space: caldav.NamespaceCalDAV, Local: "calendar-timezone"}
+	propSupportedComponents   = xml.Name{Space: caldav.NamespaceCalDAV, Local: "supported-calendar-component-set"}
+	propCalendarData          = xml.Name{Space: caldav.NamespaceCalDAV, Local: "calendar-data"}
+	propCTag                  = xml.Name{Space: caldav.NamespaceCalendarServer, Local: "getctag"}
+	propCalendarColor         = xml.Name{Space: caldav.NamespaceApple, Local: "calendar-color"}
+
+	elementCollection = xml.Name{Space: caldav.NamespaceDAV, Local: "collection"}
+	elementPrincipal  = xml.Name{Space: caldav.NamespaceDAV, Local: "principal"}
+	elementCalendar   = xml.Name{Space: caldav.NamespaceCalDAV, Local: "calendar"}
+
+	preconditionValidCalendarData = xml.Name{Space: caldav.NamespaceCalDAV, Local: "valid-calendar-data"}
+	preconditionNeedPrivileges    = xml.Name{Space: caldav.NamespaceDAV, Local: "need-privileges"}
+	preconditionSupportedReport   = xml.Name{Space: caldav.NamespaceDAV, Local: "supported-report"}
+)
+
+// DAVHandler serves the calendars of a user to CalDAV clients (RFC 4791). Clients sign in with
+// the username and an app password. Timely calendars are writable, imported calendars read-only.
+type DAVHandler struct {
+	calendarService    *service.CalendarService
+	appPasswordService *service.AppPasswordService
+	logger             *zap.Logger
+}
+
+func NewDAVHandler(calendarService *service.CalendarService, appPasswordService *service.AppPasswordService) *DAVHandler {
+	return &DAVHandler{
+		calendarService:    calendarService,
+		appPasswordService: appPasswordService,
+		logger:             zap.L(),
+	}
+}
+
+// Authenticate is a middleware that signs clients in with basic authentication
+func (h *DAVHandler) Authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		username, password, ok := r.BasicAuth()
+		if !ok {
+			requestAuthentication(w)
+			return
+		}
+
+		user, err := h.appPasswordService.AuthenticateAppPassword(username, password)
+		if err != nil {
+			h.logger.Debug("CalDAV authentication failed", zap.String("username", username))
+			requestAuthentication(w)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), userContextKey{}, user)))
+	})
+}
+
+// WellKnown redirects clients looking up the CalDAV server (RFC 6764) to it
+func (h *DAVHandler) WellKnown(w http.ResponseWriter, r *http.Request) {
+	http.Redirect(w, r, Prefix+"/", http.StatusMovedPermanently)
+}
+
+// Options advertises the CalDAV features of the server
+func (h *DAVHandler) Options(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("DAV", "1, 3, calendar-access")
+	w.Header().Set("Allow", "OPTIONS, GET, HEAD, PUT, DELETE, PROPFIND, REPORT")
+	w.WriteHeader(http.StatusOK)
+}
+
+// PropFindRoot points clients at the principal of the signed in user
+func (h *DAVHandler) PropFindRoot(w http.ResponseWriter, r *http.Request) {
+	user := userFromContext(r.Context())
+	propFind, ok := h.parsePropFind(w, r)
+	if !ok {
+		return
+	}
+
+	props := h.principalProps(user)
+	props[propResourceType] = caldav.Elements(elementCollection)
+
+	var multiStatus caldav.MultiStatus
+	multiStatus.AddProps(Prefix+"/", props, requestedProps(propFind))
+	multiStatus.WriteTo(w)
+}
+
+// PropFindPrincipal serves the principal of the signed in user
+func (h *DAVHandler) PropFindPrincipal(w http.ResponseWriter, r *http.Request) {
+	user, ok := h.pathUser(w, r)
+	if !ok {
+		return
+	}
+	propFind, ok := h.parsePropFind(w, r)
+	if !ok {
+		return
+	}
+
+	var multiStatus caldav.MultiStatus
+	multiStatus.AddProps(principalPath(user), h.principalProps(user), requestedProps(propFind))
+	multiStatus.WriteTo(w)
+}
+
+// PropFindHome serves the calendar home of the signed in user, listing every calendar at depth 1
+func (h *DAVHandler) PropFindHome(w http.ResponseWriter, r *http.Request) {
+	user, ok := h.pathUser(w, r)
+	if !ok {
+		return
+	}
+	propFind, ok := h.parsePropFind(w, r)
+	if !ok {
+		return
+	}
+
+	var multiStatus caldav.MultiStatus
+	multiStatus.AddProps(homePath(user), caldav.Properties{
+		propResourceType:         caldav.Elements(elementCollection),
+		propDisplayName:          caldav.Text(user.DisplayName),
+		propCurrentUserPrincipal: caldav.Href(principalPath(user)),
+	}, requestedProps(propFind))
+
+	if r.Header.Get("Depth") != "0" {
+		calendars, err := h.calendarService.GetDAVCalendars(user.ID)
+		if err != nil {
+			h.logger.Error("Failed to get calendars", zap.Error(err), zap.Uint64("user_id", user.ID))
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		for _, calendar := range calendars {
+			resources, err := h.calendarService.GetDAVResources(calendar)
+			if err != nil {
+				h.logger.Error("Failed to get calendar resources", zap.Error(err), zap.Uint64("calendar_id", calendar.ID))
+				http.Error(w, "Internal server error", http.StatusInternalServerError)
+				return
+			}
+			multiStatus.AddProps(calendarPath(user, calendar), calendarProps(user, calendar, resources), requestedProps(propFind))
+		}
+	}
+
+	multiStatus.WriteTo(w)
+}
+
+// PropFindCalendar serves a calendar, listing its resources at depth 1
+func (h *DAVHandler) PropFindCalendar(w http.ResponseWriter, r *http.Request) {
+	user, calendar, ok := h.pathCalendar(w, r)
+	if !ok {
+		return
+	}
+	propFind, ok := h.parsePropFind(w, r)
+	if !ok {
+		return
+	}
+
+	resources, err := h.calendarService.GetDAVResources(calendar)
+	if err != nil {
+		h.logger.Error("Failed to get calendar resources", zap.Error(err), zap.Uint64("calendar_id", calendar.ID))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	var multiStatus caldav.MultiStatus
+	multiStatus.AddProps(calendarPath(user, calendar), calendarProps(user, calendar, resources), requestedProps(propFind))
+
+	if r.Header.Get("Depth") != "0" {
+		for _, resource := range resources {
+			// Calendar data is only served by REPORTs, listing it for every resource would be too large
+			props := resourceProps(resource)
+			multiStatus.AddProps(resourcePath(user, calendar, resource.Name), props, requestedProps(propFind))
+		}
+	}
+
+	multiStatus.WriteTo(w)
+}
+
+// Report answers calendar-query and calendar-multiget REPORTs on a calendar
+func (h *DAVHandler) Report(w http.ResponseWriter, r *http.Request) {
+	user, calendar, ok := h.pathCalendar(w, r)
+	if !ok {
+		return
+	}
+
+	report, err := caldav.ParseReport(io.LimitReader(r.Body, maxResourceSize))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if report.Type != caldav.ReportCalendarQuery && report.Type != caldav.ReportCalendarMultiGet {
+		caldav.WriteError(w, http.StatusForbidden, preconditionSupportedReport)
+		return
+	}
+
+	resources, err := h.calendarService.GetDAVResources(calendar)
+	if err != nil {
+		h.logger.Error("Failed to get calendar resources", zap.Error(err), zap.Uint64("calendar_id", calendar.ID))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	var multiStatus caldav.MultiStatus
+	addResource := func(resource *service.DAVResource) {
+		props := resourceProps(resource)
+		props[propCalendarData] = caldav.Text(service.RenderDAVResource(calendar, resource))
+		multiStatus.AddProps(resourcePath(user, calendar, resource.Name), props, report.Props)
+	}
+
+	if report.Type == caldav.ReportCalendarQuery {
+		for _, resource := range resources {
+			if resource.Overlaps(report.Start, report.End) {
+				addResource(resource)
+			}
+		}
+		multiStatus.WriteTo(w)
+		return
+	}
+
+	byName := make(map[string]*service.DAVResource, len(resources))
+	for _, resource := range resources {
+		byName[resource.Name] = resource
+	}
+	for _, href := range report.Hrefs {
+		if resource, ok := byName[path.Base(href)]; ok && path.Dir(href) == strings.TrimSuffix(calendarPath(user, calendar), "/") {
+			addResource(resource)
+			continue
+		}
+		multiStatus.AddStatus(href, http.StatusNotFound)
+	}
+	multiStatus.WriteTo(w)
+}
+
+// GetResource serves the iCalendar data of a resource
+func (h *DAVHandler) GetResource(w http.ResponseWriter, r *http.Request) {
+	_, calendar, ok := h.pathCalendar(w, r)
+	if !ok {
+		return
+	}
+
+	resource, err := h.calendarService.GetDAVResource(calendar, r.PathValue("resource"))
+	if err != nil {
+		h.sendError(w, err)
+		return
+	}
+
+	w.Header().Set("ETag", resource.ETag)
+	if r.Header.Get("If-None-Match") == resource.ETag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	data := service.RenderDAVResource(calendar, resource)
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	w.WriteHeader(http.StatusOK)
+	if r.Method != http.MethodHead {
+		io.WriteString(w, data)
+	}
+}
+
+// PutResource creates or replaces a resource of a Timely calendar
+func (h *DAVHandler) PutResource(w http.ResponseWriter, r *http.Request) {
+	user, ok := h.pathUser(w, r)
+	if !ok {
+		return
+	}
+
+	data, err := io.ReadAll(io.LimitReader(r.Body, maxResourceSize+1))
+	if err != nil {
+		http.Error(w, "Failed to read request body", http.StatusBadRequest)
+		return
+	}
+	if len(data) > maxResourceSize {
+		http.Error(w, "Calendar object too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	resource, created, err := h.calendarService.PutDAVResource(user.ID, r.PathValue("calendarId"), r.PathValue("resource"),
+		data, r.Header.Get("If-Match"), r.Header.Get("If-None-Match"))
+	if err != nil {
+		h.sendError(w, err)
+		return
+	}
+
+	// An ETag tells the client the resource is stored exactly as it was sent (RFC 4791 section
+	// 5.3.4), the calendar object is served back as it was written
+	w.Header().Set("ETag", resource.ETag)
+	if created {
+		w.WriteHeader(http.StatusCreated)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// DeleteResource deletes a resource of a Timely calendar
+func (h *DAVHandler) DeleteResource(w http.ResponseWriter, r *http.Request) {
+	user, ok := h.pathUser(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.calendarService.DeleteDAVResource(user.ID, r.PathValue("calendarId"), r.PathValue("resource"), r.Header.Get("If-Match")); err != nil {
+		h.sendError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// principalProps returns the properties of the principal of a user
+func (h *DAVHandler) principalProps(user *model.User) caldav.Properties {
+	return caldav.Properties{
+		propResourceType:         caldav.Elements(elementPrincipal),
+		propDisplayName:          caldav.Text(user.DisplayName),
+		propCurrentUserPrincipal: caldav.Href(principalPath(user)),
+		propPrincipalURL:         caldav.Href(principalPath(user)),
+		propCalendarHomeSet:      caldav.Href(homePath(user)),
+	}
+}
+
+// calendarProps returns the properties of a calendar collection
+func calendarProps(user *model.User, calendar *model.Calendar, resources []*service.DAVResource) caldav.Properties {
+	props := caldav.Properties{
+		propResourceType:          caldav.Elements(elementCollection, elementCalendar),
+		propDisplayName:           caldav.Text(calendar.Summary),
+		propCurrentUserPrincipal:  caldav.Href(principalPath(user)),
+		propCurrentUserPrivileges: privileges(service.IsDAVCalendarWritable(calendar)),
+		propSupportedComponents:   `<c:comp name="VEVENT"/>`,
+		propCTag:                  caldav.Text(service.DAVCalendarCTag(calendar, resources)),
+	}
+	if calendar.Description != nil && *calendar.Description != "" {
+		props[propCalendarDescription] = caldav.Text(*calendar.Description)
+	}
+	if color := service.CalendarColor(calendar); color != "" {
+		props[propCalendarColor] = caldav.Text(color)
+	}
+	if calendar.TimeZone != "" {
+		props[propCalendarTimeZone] = caldav.Text("BEGIN:VCALENDAR\r\nVERSION:2.0\r\nBEGIN:VTIMEZONE\r\nTZID:" +
+			calendar.TimeZone + "\r\nEND:VTIMEZONE\r\nEND:VCALENDAR\r\n")
+	}
+	return props
+}
+
+// resourceProps returns the properties of a calendar object resource, without its calendar data
+func resourceProps(resource *service.DAVResource) caldav.Properties {
+	return caldav.Properties{
+		propResourceType:   "",
+		propGetETag:        caldav.Text(resource.ETag),
+		propGetContentType: caldav.Text("text/calendar; charset=utf-8; component=VEVENT"),
+	}
+}
+
+// privileges returns the current-user-privilege-set of a calendar
+func privileges(writable bool) string {
+	names := []string{"read", "read-current-user-privilege-set"}
+	if writable {
+		names = append(names, "write", "write-content", "bind", "unbind")
+	}
+
+	var set strings.Builder
+	for _, name := range names {
+		set.WriteString(`<d:privilege><d:` + name + `/></d:privilege>`)
+	}
+	return set.String()
+}
+
+// parsePropFind parses the body of a PROPFIND request, answering malformed bodies with 400
+func (h *DAVHandler) parsePropFind(w http.ResponseWriter, r *http.Request) (*caldav.PropFind, bool) {
+	propFind, err := caldav.ParsePropFind(io.LimitReader(r.Body, maxResourceSize))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+	return propFind, true
+}
+
+// pathUser returns the signed in user if the path belongs to them. Paths of other users are
+// answered with 404 so they do not reveal which usernames exist.
+func (h *DAVHandler) pathUser(w http.ResponseWriter, r *http.Request) (*model.User, bool) {
+	user := userFromContext(r.Context())
+	if r.PathValue("username") != user.Username {
+		http.NotFound(w, r)
+		return nil, false
+	}
+	return user, true
+}
+
+// pathCalendar returns the signed in user and the calendar of the path
+func (h *DAVHandler) pathCalendar(w http.ResponseWriter, r *http.Request) (*model.User, *model.Calendar, bool) {
+	user, ok := h.pathUser(w, r)
+	if !ok {
+		return nil, nil, false
+	}
+
+	calendar, err := h.calendarService.GetDAVCalendar(user.ID, r.PathValue("calendarId"))
+	if err != nil {
+		h.sendError(w, err)
+		return nil, nil, false
+	}
+	return user, calendar, true
+}
+
+// sendError maps service errors to CalDAV error responses
+func (h *DAVHandler) sendError(w http.ResponseWriter, err error) {
+	switch {
+	case err.Error() == "calendar not found or access denied",
+		err.Error() == "failed to find calendar: record not found",
+		err.Error() == "resource not found":
+		http.Error(w, "Not found", http.StatusNotFound)
+	case err.Error() == "calendar is read-only":
+		caldav.WriteError(w, http.StatusForbidden, preconditionNeedPrivileges)
+	case err.Error() == "precondition failed":
+		http.Error(w, "Precondition failed", http.StatusPreconditionFailed)
+	case strings.HasPrefix(err.Error(), "invalid calendar data"):
+		caldav.WriteError(w, http.StatusForbidden, preconditionValidCalendarData)
+	default:
+		h.logger.Error("CalDAV request failed", zap.Error(err))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+// requestedProps returns the properties asked for, nil for every property
+func requestedProps(propFind *caldav.PropFind) []xml.Name {
+	if propFind.AllProp {
+		return nil
+	}
+	return propFind.Props
+}
+
+// requestAuthentication answers a request without valid credentials
+func requestAuthentication(w http.ResponseWriter) {
+	w.Header().Set("WWW-Authenticate", `Basic realm="Timely", charset="UTF-8"`)
+	http.Error(w, "Unauthorized", http.StatusUnauthorized)
+}
+
+func userFromContext(ctx context.Context) *model.User {
+	user, _ := ctx.Value(userContextKey{}).(*model.User)
+	return user
+}
+
+func principalPath(user *model.User) string {
+	return Prefix + "/principals/" + user.Username + "/"
+}
+
+func homePath(user *model.User) string {
+	return Prefix + "/calendars/" + user.Username + "/"
+}
+
+func calendarPath(user *model.User, calendar *model.Calendar) string {
+	return fmt.Sprintf("%s%d/", homePath(user), calendar.ID)
+}
+
+func resourcePath(user *model.User, calendar *model.Calendar, name string) string {
+	return calendarPath(user, calendar) + name
+}
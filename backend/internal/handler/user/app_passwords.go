@@ -0,0 +1,192 @@
+package user
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/NathanWasTaken/timely/backend/internal/middleware"
+	"github.com/NathanWasTaken/timely/backend/internal/model"
+	"github.com/NathanWasTaken/timely/backend/internal/service"
+)
+
+type AppPasswordHandler struct {
+	appPasswordService *service.AppPasswordService
+	userService        *service.UserService
+	logger             *zap.Logger
+}
+
+func NewAppPasswordHandler(appPasswordService *service.AppPasswordService, userService *service.UserService) *AppPasswordHandler {
+	return &AppPasswordHandler{
+		appPasswordService: appPasswordService,
+		userService:        userService,
+		logger:             zap.L(),
+	}
+}
+
+// ListAppPasswords lists the app passwords of the authenticated user
+// @Summary List App Passwords
+// @Description Lists the passwords CalDAV clients sign in to the built-in CalDAV server with. The passwords themselves are never returned again after creation
+// @Tags User
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} model.AppPasswordsResponse "App passwords retrieved successfully"
+// @Failure 401 {object} model.ErrorResponse "Unauthorized - Authentication required"
+// @Failure 500 {object} model.ErrorResponse "Internal server error"
+// @Router /api/users/me/app-passwords [get]
+func (h *AppPasswordHandler) ListAppPasswords(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by JWT middleware)
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		h.logger.Error("User not found in context")
+		sendErrorResponse(w, "Authentication required", "authentication_required", http.StatusUnauthorized)
+		return
+	}
+
+	appPasswords, err := h.appPasswordService.ListAppPasswords(user.ID)
+	if err != nil {
+		h.logger.Error("Failed to get app passwords", zap.Error(err), zap.Uint64("user_id", user.ID))
+		sendErrorResponse(w, "Failed to retrieve app passwords", "app_password_fetch_error", http.StatusInternalServerError)
+		return
+	}
+
+	// Create success response
+	response := model.AppPasswordsResponse{
+		Success:      true,
+		Message:      "App passwords retrieved successfully",
+		AppPasswords: appPasswords,
+	}
+
+	// Send response
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		h.logger.Error("Failed to encode response", zap.Error(err))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+}
+
+// CreateAppPassword creates an app password for the authenticated user
+// @Summary Create App Password
+// @Description Creates a password for signing in to the built-in CalDAV server at /dav/ from clients such as Apple Calendar, DAVx5 or Thunderbird. The password is only returned in this response
+// @Tags User
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body model.AppPasswordCreateRequest false "App password create request"
+// @Success 201 {object} model.AppPasswordResponse "App password created successfully"
+// @Failure 400 {object} model.ErrorResponse "Bad Request - Invalid request body"
+// @Failure 401 {object} model.ErrorResponse "Unauthorized - Authentication required"
+// @Failure 500 {object} model.ErrorResponse "Internal server error"
+// @Router /api/users/me/app-passwords [post]
+func (h *AppPasswordHandler) CreateAppPassword(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by JWT middleware)
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		h.logger.Error("User not found in context")
+		sendErrorResponse(w, "Authentication required", "authentication_required", http.StatusUnauthorized)
+		return
+	}
+
+	// Parse request body (optional)
+	var req model.AppPasswordCreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		h.logger.Error("Failed to decode request body", zap.Error(err))
+		sendErrorResponse(w, "Invalid request body", "invalid_request", http.StatusBadRequest)
+		return
+	}
+
+	// Clients sign in with the username, so it is returned along with the password
+	profile, err := h.userService.GetUserByID(user.ID)
+	if err != nil {
+		h.logger.Error("Failed to get user", zap.Error(err), zap.Uint64("user_id", user.ID))
+		sendErrorResponse(w, "Failed to create app password", "app_password_create_error", http.StatusInternalServerError)
+		return
+	}
+
+	appPassword, password, err := h.appPasswordService.CreateAppPassword(user.ID, strings.TrimSpace(req.Name))
+	if err != nil {
+		h.logger.Error("Failed to create app password", zap.Error(err), zap.Uint64("user_id", user.ID))
+		sendErrorResponse(w, "Failed to create app password", "app_password_create_error", http.StatusInternalServerError)
+		return
+	}
+
+	// Create success response
+	response := model.AppPasswordResponse{
+		Success:     true,
+		Message:     "App password created successfully",
+		AppPassword: appPassword,
+		Username:    profile.Username,
+		Password:    password,
+		DAVPath:     "/dav/",
+	}
+
+	// Secrets must not be cached by intermediaries
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		h.logger.Error("Failed to encode response", zap.Error(err))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+}
+
+// RevokeAppPassword revokes one of the authenticated user's app passwords
+// @Summary Revoke App Password
+// @Description Deletes an app password so clients signed in with it lose access
+// @Tags User
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "App password ID"
+// @Success 200 {object} model.AppPasswordDeleteResponse "App password revoked successfully"
+// @Failure 401 {object} model.ErrorResponse "Unauthorized - Authentication required"
+// @Failure 404 {object} model.ErrorResponse "Not Found - App password not found or access denied"
+// @Failure 500 {object} model.ErrorResponse "Internal server error"
+// @Router /api/users/me/app-passwords/{id} [delete]
+func (h *AppPasswordHandler) RevokeAppPassword(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by JWT middleware)
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		h.logger.Error("User not found in context")
+		sendErrorResponse(w, "Authentication required", "authentication_required", http.StatusUnauthorized)
+		return
+	}
+
+	if err := h.appPasswordService.RevokeAppPassword(user.ID, r.PathValue("id")); err != nil {
+		h.logger.Error("Failed to revoke app password", zap.Error(err), zap.Uint64("user_id", user.ID))
+
+		// Handle specific error cases
+		switch {
+		case err.Error() == "app password not found or access denied",
+			err.Error() == "failed to find app password: record not found":
+			sendErrorResponse(w, "App password not found", "app_password_not_found", http.StatusNotFound)
+		default:
+			sendErrorResponse(w, "Failed to revoke app password", "app_password_delete_error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	// Create success response
+	response := model.AppPasswordDeleteResponse{
+		Success: true,
+		Message: "App password revoked successfully",
+	}
+
+	// Send response
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		h.logger.Error("Failed to encode response", zap.Error(err))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+}
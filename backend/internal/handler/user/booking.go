@@ -0,0 +1,339 @@
+package user
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/NathanWasTaken/timely/backend/internal/middleware"
+	"github.com/NathanWasTaken/timely/backend/internal/model"
+	"github.com/NathanWasTaken/timely/backend/internal/service"
+)
+
+type BookingHandler struct {
+	bookingService *service.BookingService
+	userService    *service.UserService
+	logger         *zap.Logger
+}
+
+func NewBookingHandler(bookingService *service.BookingService, userService *service.UserService) *BookingHandler {
+	return &BookingHandler{
+		bookingService: bookingService,
+		userService:    userService,
+		logger:         zap.L(),
+	}
+}
+
+// ListBookingTypes lists the booking types of the authenticated user
+// @Summary List Booking Types
+// @Description Lists the booking types offered on the authenticated user's booking pages
+// @Tags User
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} model.BookingTypesResponse "Booking types retrieved successfully"
+// @Failure 401 {object} model.ErrorResponse "Unauthorized - Authentication required"
+// @Failure 500 {object} model.ErrorResponse "Internal server error"
+// @Router /api/users/me/booking-types [get]
+func (h *BookingHandler) ListBookingTypes(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by JWT middleware)
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		h.logger.Error("User not found in context")
+		sendErrorResponse(w, "Authentication required", "authentication_required", http.StatusUnauthorized)
+		return
+	}
+
+	bookingTypes, err := h.bookingService.ListBookingTypes(user.ID)
+	if err != nil {
+		h.logger.Error("Failed to get booking types", zap.Error(err), zap.Uint64("user_id", user.ID))
+		sendErrorResponse(w, "Failed to retrieve booking types", "booking_type_fetch_error", http.StatusInternalServerError)
+		return
+	}
+
+	h.sendJSON(w, http.StatusOK, model.BookingTypesResponse{
+		Success:      true,
+		Message:      "Booking types retrieved successfully",
+		BookingTypes: bookingTypes,
+	})
+}
+
+// CreateBookingType creates a booking type for the authenticated user
+// @Summary Create Booking Type
+// @Description Creates a booking type visitors can book on the user's booking page. Bookings are created as events in the given Timely calendar, and events of it and of the conflict calendars block slots
+// @Tags User
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body model.BookingTypeRequest true "Booking type create request"
+// @Success 201 {object} model.BookingTypeResponse "Booking type created successfully"
+// @Failure 400 {object} model.ErrorResponse "Bad Request - Invalid request body or booking type"
+// @Failure 401 {object} model.ErrorResponse "Unauthorized - Authentication required"
+// @Failure 500 {object} model.ErrorResponse "Internal server error"
+// @Router /api/users/me/booking-types [post]
+func (h *BookingHandler) CreateBookingType(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by JWT middleware)
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		h.logger.Error("User not found in context")
+		sendErrorResponse(w, "Authentication required", "authentication_required", http.StatusUnauthorized)
+		return
+	}
+
+	var req model.BookingTypeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Error("Failed to decode request body", zap.Error(err))
+		sendErrorResponse(w, "Invalid request body", "invalid_request", http.StatusBadRequest)
+		return
+	}
+
+	bookingType, err := h.bookingService.CreateBookingType(user.ID, &req)
+	if err != nil {
+		h.sendBookingTypeError(w, err, "Failed to create booking type", "booking_type_create_error")
+		return
+	}
+
+	h.sendJSON(w, http.StatusCreated, model.BookingTypeResponse{
+		Success:     true,
+		Message:     "Booking type created successfully",
+		BookingType: bookingType,
+	})
+}
+
+// UpdateBookingType replaces one of the authenticated user's booking types
+// @Summary Update Booking Type
+// @Description Replaces a booking type. Bookings already made are not changed
+// @Tags User
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Booking type ID"
+// @Param request body model.BookingTypeRequest true "Booking type update request"
+// @Success 200 {object} model.BookingTypeResponse "Booking type updated successfully"
+// @Failure 400 {object} model.ErrorResponse "Bad Request - Invalid request body or booking type"
+// @Failure 401 {object} model.ErrorResponse "Unauthorized - Authentication required"
+// @Failure 404 {object} model.ErrorResponse "Not Found - Booking type not found or access denied"
+// @Failure 500 {object} model.ErrorResponse "Internal server error"
+// @Router /api/users/me/booking-types/{id} [put]
+func (h *BookingHandler) UpdateBookingType(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by JWT middleware)
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		h.logger.Error("User not found in context")
+		sendErrorResponse(w, "Authentication required", "authentication_required", http.StatusUnauthorized)
+		return
+	}
+
+	var req model.BookingTypeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Error("Failed to decode request body", zap.Error(err))
+		sendErrorResponse(w, "Invalid request body", "invalid_request", http.StatusBadRequest)
+		return
+	}
+
+	bookingType, err := h.bookingService.UpdateBookingType(user.ID, r.PathValue("id"), &req)
+	if err != nil {
+		h.sendBookingTypeError(w, err, "Failed to update booking type", "booking_type_update_error")
+		return
+	}
+
+	h.sendJSON(w, http.StatusOK, model.BookingTypeResponse{
+		Success:     true,
+		Message:     "Booking type updated successfully",
+		BookingType: bookingType,
+	})
+}
+
+// DeleteBookingType deletes one of the authenticated user's booking types
+// @Summary Delete Booking Type
+// @Description Deletes a booking type so it can no longer be booked. Events of bookings already made are kept
+// @Tags User
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Booking type ID"
+// @Success 200 {object} model.BookingTypeDeleteResponse "Booking type deleted successfully"
+// @Failure 401 {object} model.ErrorResponse "Unauthorized - Authentication required"
+// @Failure 404 {object} model.ErrorResponse "Not Found - Booking type not found or access denied"
+// @Failure 500 {object} model.ErrorResponse "Internal server error"
+// @Router /api/users/me/booking-types/{id} [delete]
+func (h *BookingHandler) DeleteBookingType(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by JWT middleware)
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		h.logger.Error("User not found in context")
+		sendErrorResponse(w, "Authentication required", "authentication_required", http.StatusUnauthorized)
+		return
+	}
+
+	if err := h.bookingService.DeleteBookingType(user.ID, r.PathValue("id")); err != nil {
+		h.sendBookingTypeError(w, err, "Failed to delete booking type", "booking_type_delete_error")
+		return
+	}
+
+	h.sendJSON(w, http.StatusOK, model.BookingTypeDeleteResponse{
+		Success: true,
+		Message: "Booking type deleted successfully",
+	})
+}
+
+// GetBookingSlots retrieves the open slots of a user's booking page
+// @Summary Get Booking Slots
+// @Description Retrieves a booking type of a user and its open slots within a specified time range (max 6 months). Slots follow the booking type's availability in its time zone and leave out times that conflict with existing events. No authentication required.
+// @Tags User
+// @Produce json
+// @Param username path string true "Username"
+// @Param slug path string true "Booking type slug"
+// @Param start_timestamp query string true "Start timestamp in Unix format"
+// @Param end_timestamp query string true "End timestamp in Unix format"
+// @Success 200 {object} model.BookingSlotsResponse "Booking slots retrieved successfully"
+// @Failure 400 {object} model.ErrorResponse "Bad Request - Invalid parameters or time range"
+// @Failure 404 {object} model.ErrorResponse "Not Found - User or booking type not found"
+// @Failure 500 {object} model.ErrorResponse "Internal server error"
+// @Router /api/users/{username}/booking/{slug} [get]
+func (h *BookingHandler) GetBookingSlots(w http.ResponseWriter, r *http.Request) {
+	user, ok := h.findPageUser(w, r)
+	if !ok {
+		return
+	}
+
+	startTime, endTime, ok := parseTimeRange(w, r, h.logger)
+	if !ok {
+		return
+	}
+
+	page, slots, err := h.bookingService.GetBookingSlots(user.ID, r.PathValue("slug"), startTime, endTime)
+	if err != nil {
+		h.logger.Error("Failed to get booking slots", zap.Error(err), zap.Uint64("user_id", user.ID))
+
+		// Handle specific error cases
+		switch {
+		case err.Error() == "time range cannot exceed 6 months":
+			sendEventsErrorResponse(w, "Time range cannot exceed 6 months", "time_range_too_large", http.StatusBadRequest)
+		case err.Error() == "failed to find booking type: record not found":
+			sendEventsErrorResponse(w, "Booking page not found", "booking_type_not_found", http.StatusNotFound)
+		default:
+			sendEventsErrorResponse(w, "Failed to retrieve booking slots", "booking_slots_fetch_error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	h.sendJSON(w, http.StatusOK, model.BookingSlotsResponse{
+		Success:     true,
+		Message:     "Booking slots retrieved successfully",
+		BookingPage: page,
+		Slots:       slots,
+	})
+}
+
+// CreateBooking books a slot on a user's booking page
+// @Summary Create Booking
+// @Description Books one of the open slots of a booking type. The booking is added to the user's calendar and an ICS confirmation is returned, as the response body when format=ics is set or text/calendar is accepted. No authentication required.
+// @Tags User
+// @Accept json
+// @Produce json
+// @Produce text/calendar
+// @Param username path string true "Username"
+// @Param slug path string true "Booking type slug"
+// @Param format query string false "Response format (json or ics)"
+// @Param request body model.BookingRequest true "Booking request"
+// @Success 201 {object} model.BookingResponse "Booking created successfully"
+// @Failure 400 {object} model.ErrorResponse "Bad Request - Invalid request body"
+// @Failure 404 {object} model.ErrorResponse "Not Found - User or booking type not found"
+// @Failure 409 {object} model.ErrorResponse "Conflict - The slot is not available"
+// @Failure 500 {object} model.ErrorResponse "Internal server error"
+// @Router /api/users/{username}/booking/{slug} [post]
+func (h *BookingHandler) CreateBooking(w http.ResponseWriter, r *http.Request) {
+	user, ok := h.findPageUser(w, r)
+	if !ok {
+		return
+	}
+
+	var req model.BookingRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Error("Failed to decode request body", zap.Error(err))
+		sendEventsErrorResponse(w, "Invalid request body", "invalid_request", http.StatusBadRequest)
+		return
+	}
+
+	booking, confirmation, err := h.bookingService.CreateBooking(user, r.PathValue("slug"), &req)
+	if err != nil {
+		h.logger.Error("Failed to create booking", zap.Error(err), zap.Uint64("user_id", user.ID))
+
+		// Handle specific error cases
+		switch {
+		case err.Error() == "failed to find booking type: record not found":
+			sendEventsErrorResponse(w, "Booking page not found", "booking_type_not_found", http.StatusNotFound)
+		case err.Error() == "slot is not available":
+			sendEventsErrorResponse(w, "The slot is not available", "slot_not_available", http.StatusConflict)
+		case strings.HasPrefix(err.Error(), "invalid booking"):
+			sendEventsErrorResponse(w, err.Error(), "invalid_booking", http.StatusBadRequest)
+		default:
+			sendEventsErrorResponse(w, "Failed to create booking", "booking_create_error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	if wantsICS(r) {
+		w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", "booking.ics"))
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(confirmation))
+		return
+	}
+
+	h.sendJSON(w, http.StatusCreated, model.BookingResponse{
+		Success: true,
+		Message: "Booking created successfully",
+		Booking: booking,
+		ICS:     confirmation,
+	})
+}
+
+// findPageUser finds the owner of a booking page by the username in the path
+func (h *BookingHandler) findPageUser(w http.ResponseWriter, r *http.Request) (*model.User, bool) {
+	username := r.PathValue("username")
+	if username == "" {
+		h.logger.Error("Username not provided in path")
+		sendEventsErrorResponse(w, "Username is required", "missing_username", http.StatusBadRequest)
+		return nil, false
+	}
+
+	user, err := h.userService.GetUserByUsername(username)
+	if err != nil {
+		h.logger.Error("Failed to get user by username", zap.Error(err), zap.String("username", username))
+		sendEventsErrorResponse(w, "User not found", "user_not_found", http.StatusNotFound)
+		return nil, false
+	}
+
+	return user, true
+}
+
+// sendBookingTypeError maps a booking type service error to an error response
+func (h *BookingHandler) sendBookingTypeError(w http.ResponseWriter, err error, message, errorType string) {
+	h.logger.Error(message, zap.Error(err))
+
+	// Handle specific error cases
+	switch {
+	case err.Error() == "booking type not found or access denied":
+		sendErrorResponse(w, "Booking type not found or access denied", "booking_type_not_found", http.StatusNotFound)
+	case err.Error() == "failed to find booking type: record not found":
+		sendErrorResponse(w, "Booking type not found", "booking_type_not_found", http.StatusNotFound)
+	case strings.HasPrefix(err.Error(), "invalid booking type"):
+		sendErrorResponse(w, err.Error(), "invalid_booking_type", http.StatusBadRequest)
+	default:
+		sendErrorResponse(w, message, errorType, http.StatusInternalServerError)
+	}
+}
+
+// sendJSON sends a JSON response
+func (h *BookingHandler) sendJSON(w http.ResponseWriter, statusCode int, response interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		h.logger.Error("Failed to encode response", zap.Error(err))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,98 @@
+package user
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/NathanWasTaken/timely/backend/internal/service"
+)
+
+// GetPublicUserCalendarFeed publishes a user's public events as an ICS feed
+// @Summary Get Public User Calendar Feed
+// @Description Returns the public events of a user as an ICS (iCalendar) feed that can be subscribed to from Apple Calendar, Thunderbird or Google Calendar. Covers a rolling window from 30 days ago to 150 days ahead and applies the same visibility and redaction rules as the events endpoint. No authentication required.
+// @Tags User
+// @Produce text/calendar
+// @Param username path string true "Username"
+// @Success 200 {string} string "ICS feed"
+// @Success 304 {string} string "Not Modified - The feed has not changed since the ETag sent in If-None-Match"
+// @Failure 404 {object} model.ErrorResponse "Not Found - User not found"
+// @Failure 500 {object} model.ErrorResponse "Internal server error"
+// @Router /api/users/{username}/calendar.ics [get]
+func (h *UserEventsHandler) GetPublicUserCalendarFeed(w http.ResponseWriter, r *http.Request) {
+	// Get username from path parameter
+	username := r.PathValue("username")
+	if username == "" {
+		h.logger.Error("Username not provided in path")
+		sendEventsErrorResponse(w, "Username is required", "missing_username", http.StatusBadRequest)
+		return
+	}
+
+	// Get user by username
+	user, err := h.userService.GetUserByUsername(username)
+	if err != nil {
+		h.logger.Error("Failed to get user by username", zap.Error(err), zap.String("username", username))
+		sendEventsErrorResponse(w, "User not found", "user_not_found", http.StatusNotFound)
+		return
+	}
+
+	feed, err := h.calendarService.GetPublicUserCalendarICS(user)
+	if err != nil {
+		h.logger.Error("Failed to render public calendar feed", zap.Error(err), zap.Uint64("user_id", user.ID))
+
+		// Handle specific error cases
+		switch {
+		case err.Error() == "user not found":
+			sendEventsErrorResponse(w, "User not found", "user_not_found", http.StatusNotFound)
+		default:
+			sendEventsErrorResponse(w, "Failed to render calendar feed", "calendar_feed_error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	writeICSFeed(w, r, feed, user.Username+".ics", "public")
+
+	h.logger.Debug("Served public calendar feed",
+		zap.Uint64("user_id", user.ID),
+		zap.Int("feed_size", len(feed)))
+}
+
+// writeICSFeed writes an ICS feed with caching headers, answering with 304 Not Modified when the
+// client already holds the current version. cacheScope is the Cache-Control scope ("public" or "private").
+func writeICSFeed(w http.ResponseWriter, r *http.Request, feed, filename, cacheScope string) {
+	sum := sha256.Sum256([]byte(feed))
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", fmt.Sprintf("%s, max-age=%d", cacheScope, int(service.ICSFeedRefreshInterval.Seconds())))
+
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=%q", filename))
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(feed))
+}
+
+// etagMatches checks whether an If-None-Match header matches etag, using weak comparison
+func etagMatches(ifNoneMatch, etag string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+
+	return false
+}
@@ -56,38 +56,8 @@ func (h *UserEventsHandler) GetPublicUserEvents(w http.ResponseWriter, r *http.R
 		return
 	}
 
-	// Parse query parameters
-	startTimestampStr := r.URL.Query().Get("start_timestamp")
-	endTimestampStr := r.URL.Query().Get("end_timestamp")
-
-	// Validate query parameters
-	if startTimestampStr == "" || endTimestampStr == "" {
-		sendEventsErrorResponse(w, "Start timestamp and end timestamp query parameters are required", "missing_time_range", http.StatusBadRequest)
-		return
-	}
-
-	// Parse timestamps
-	startTimestamp, err := strconv.ParseInt(startTimestampStr, 10, 64)
-	if err != nil {
-		h.logger.Error("Failed to parse start timestamp", zap.Error(err), zap.String("start_timestamp", startTimestampStr))
-		sendEventsErrorResponse(w, "Invalid start timestamp format", "invalid_start_timestamp", http.StatusBadRequest)
-		return
-	}
-
-	endTimestamp, err := strconv.ParseInt(endTimestampStr, 10, 64)
-	if err != nil {
-		h.logger.Error("Failed to parse end timestamp", zap.Error(err), zap.String("end_timestamp", endTimestampStr))
-		sendEventsErrorResponse(w, "Invalid end timestamp format", "invalid_end_timestamp", http.StatusBadRequest)
-		return
-	}
-
-	// Convert timestamps to time.Time
-	startTime := time.Unix(startTimestamp, 0)
-	endTime := time.Unix(endTimestamp, 0)
-
-	// Validate time range
-	if startTime.After(endTime) {
-		sendEventsErrorResponse(w, "Start time must be before end time", "invalid_time_range", http.StatusBadRequest)
+	startTime, endTime, ok := parseTimeRange(w, r, h.logger)
+	if !ok {
 		return
 	}
 
@@ -142,6 +112,47 @@ func (h *UserEventsHandler) GetPublicUserEvents(w http.ResponseWriter, r *http.R
 		zap.Int("total_events", totalEvents))
 }
 
+// parseTimeRange parses and validates the start_timestamp and end_timestamp query parameters,
+// sending an error response if they are missing or invalid
+func parseTimeRange(w http.ResponseWriter, r *http.Request, logger *zap.Logger) (time.Time, time.Time, bool) {
+	// Parse query parameters
+	startTimestampStr := r.URL.Query().Get("start_timestamp")
+	endTimestampStr := r.URL.Query().Get("end_timestamp")
+
+	// Validate query parameters
+	if startTimestampStr == "" || endTimestampStr == "" {
+		sendEventsErrorResponse(w, "Start timestamp and end timestamp query parameters are required", "missing_time_range", http.StatusBadRequest)
+		return time.Time{}, time.Time{}, false
+	}
+
+	// Parse timestamps
+	startTimestamp, err := strconv.ParseInt(startTimestampStr, 10, 64)
+	if err != nil {
+		logger.Error("Failed to parse start timestamp", zap.Error(err), zap.String("start_timestamp", startTimestampStr))
+		sendEventsErrorResponse(w, "Invalid start timestamp format", "invalid_start_timestamp", http.StatusBadRequest)
+		return time.Time{}, time.Time{}, false
+	}
+
+	endTimestamp, err := strconv.ParseInt(endTimestampStr, 10, 64)
+	if err != nil {
+		logger.Error("Failed to parse end timestamp", zap.Error(err), zap.String("end_timestamp", endTimestampStr))
+		sendEventsErrorResponse(w, "Invalid end timestamp format", "invalid_end_timestamp", http.StatusBadRequest)
+		return time.Time{}, time.Time{}, false
+	}
+
+	// Convert timestamps to time.Time
+	startTime := time.Unix(startTimestamp, 0)
+	endTime := time.Unix(endTimestamp, 0)
+
+	// Validate time range
+	if startTime.After(endTime) {
+		sendEventsErrorResponse(w, "Start time must be before end time", "invalid_time_range", http.StatusBadRequest)
+		return time.Time{}, time.Time{}, false
+	}
+
+	return startTime, endTime, true
+}
+
 // sendEventsErrorResponse sends a standardized error response for user events
 func sendEventsErrorResponse(w http.ResponseWriter, message, errorType string, statusCode int) {
 	w.Header().Set("Content-Type", "application/json")
@@ -0,0 +1,289 @@
+package user
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/NathanWasTaken/timely/backend/internal/middleware"
+	"github.com/NathanWasTaken/timely/backend/internal/model"
+	"github.com/NathanWasTaken/timely/backend/internal/service"
+)
+
+type FeedHandler struct {
+	feedService     *service.FeedService
+	calendarService *service.CalendarService
+	logger          *zap.Logger
+}
+
+func NewFeedHandler(feedService *service.FeedService, calendarService *service.CalendarService) *FeedHandler {
+	return &FeedHandler{
+		feedService:     feedService,
+		calendarService: calendarService,
+		logger:          zap.L(),
+	}
+}
+
+// ListFeedTokens lists the private feed tokens of the authenticated user
+// @Summary List Feed Tokens
+// @Description Lists the tokens granting access to the authenticated user's private ICS feed. The secret tokens themselves are never returned again after creation
+// @Tags User
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} model.FeedTokensResponse "Feed tokens retrieved successfully"
+// @Failure 401 {object} model.ErrorResponse "Unauthorized - Authentication required"
+// @Failure 500 {object} model.ErrorResponse "Internal server error"
+// @Router /api/users/me/feeds [get]
+func (h *FeedHandler) ListFeedTokens(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by JWT middleware)
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		h.logger.Error("User not found in context")
+		sendErrorResponse(w, "Authentication required", "authentication_required", http.StatusUnauthorized)
+		return
+	}
+
+	feedTokens, err := h.feedService.ListFeedTokens(user.ID)
+	if err != nil {
+		h.logger.Error("Failed to get feed tokens", zap.Error(err), zap.Uint64("user_id", user.ID))
+		sendErrorResponse(w, "Failed to retrieve feed tokens", "feed_token_fetch_error", http.StatusInternalServerError)
+		return
+	}
+
+	// Create success response
+	response := model.FeedTokensResponse{
+		Success:    true,
+		Message:    "Feed tokens retrieved successfully",
+		FeedTokens: feedTokens,
+	}
+
+	// Send response
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		h.logger.Error("Failed to encode response", zap.Error(err))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+}
+
+// CreateFeedToken creates a private feed token for the authenticated user
+// @Summary Create Feed Token
+// @Description Creates an unguessable token for subscribing to all of the authenticated user's calendars, including private events, from clients that cannot send a JWT. The token is only returned in this response
+// @Tags User
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body model.FeedTokenCreateRequest false "Feed token create request"
+// @Success 201 {object} model.FeedTokenResponse "Feed token created successfully"
+// @Failure 400 {object} model.ErrorResponse "Bad Request - Invalid request body"
+// @Failure 401 {object} model.ErrorResponse "Unauthorized - Authentication required"
+// @Failure 500 {object} model.ErrorResponse "Internal server error"
+// @Router /api/users/me/feeds [post]
+func (h *FeedHandler) CreateFeedToken(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by JWT middleware)
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		h.logger.Error("User not found in context")
+		sendErrorResponse(w, "Authentication required", "authentication_required", http.StatusUnauthorized)
+		return
+	}
+
+	// Parse request body (optional)
+	var req model.FeedTokenCreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		h.logger.Error("Failed to decode request body", zap.Error(err))
+		sendErrorResponse(w, "Invalid request body", "invalid_request", http.StatusBadRequest)
+		return
+	}
+
+	feedToken, token, err := h.feedService.CreateFeedToken(user.ID, strings.TrimSpace(req.Name))
+	if err != nil {
+		h.logger.Error("Failed to create feed token", zap.Error(err), zap.Uint64("user_id", user.ID))
+		sendErrorResponse(w, "Failed to create feed token", "feed_token_create_error", http.StatusInternalServerError)
+		return
+	}
+
+	h.sendFeedTokenResponse(w, http.StatusCreated, "Feed token created successfully", feedToken, token)
+}
+
+// RotateFeedToken replaces the secret of one of the authenticated user's feed tokens
+// @Summary Rotate Feed Token
+// @Description Generates a new secret for a feed token. The previous feed URL stops working immediately
+// @Tags User
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Feed token ID"
+// @Success 200 {object} model.FeedTokenResponse "Feed token rotated successfully"
+// @Failure 401 {object} model.ErrorResponse "Unauthorized - Authentication required"
+// @Failure 404 {object} model.ErrorResponse "Not Found - Feed token not found or access denied"
+// @Failure 500 {object} model.ErrorResponse "Internal server error"
+// @Router /api/users/me/feeds/{id}/rotate [post]
+func (h *FeedHandler) RotateFeedToken(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by JWT middleware)
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		h.logger.Error("User not found in context")
+		sendErrorResponse(w, "Authentication required", "authentication_required", http.StatusUnauthorized)
+		return
+	}
+
+	feedToken, token, err := h.feedService.RotateFeedToken(user.ID, r.PathValue("id"))
+	if err != nil {
+		h.logger.Error("Failed to rotate feed token", zap.Error(err), zap.Uint64("user_id", user.ID))
+
+		// Handle specific error cases
+		switch {
+		case err.Error() == "feed token not found or access denied",
+			err.Error() == "failed to find feed token: record not found":
+			sendErrorResponse(w, "Feed token not found", "feed_token_not_found", http.StatusNotFound)
+		default:
+			sendErrorResponse(w, "Failed to rotate feed token", "feed_token_rotate_error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	h.sendFeedTokenResponse(w, http.StatusOK, "Feed token rotated successfully", feedToken, token)
+}
+
+// RevokeFeedToken revokes one of the authenticated user's feed tokens
+// @Summary Revoke Feed Token
+// @Description Deletes a feed token so its feed URL stops working
+// @Tags User
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Feed token ID"
+// @Success 200 {object} model.FeedTokenDeleteResponse "Feed token revoked successfully"
+// @Failure 401 {object} model.ErrorResponse "Unauthorized - Authentication required"
+// @Failure 404 {object} model.ErrorResponse "Not Found - Feed token not found or access denied"
+// @Failure 500 {object} model.ErrorResponse "Internal server error"
+// @Router /api/users/me/feeds/{id} [delete]
+func (h *FeedHandler) RevokeFeedToken(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by JWT middleware)
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		h.logger.Error("User not found in context")
+		sendErrorResponse(w, "Authentication required", "authentication_required", http.StatusUnauthorized)
+		return
+	}
+
+	if err := h.feedService.RevokeFeedToken(user.ID, r.PathValue("id")); err != nil {
+		h.logger.Error("Failed to revoke feed token", zap.Error(err), zap.Uint64("user_id", user.ID))
+
+		// Handle specific error cases
+		switch {
+		case err.Error() == "feed token not found or access denied",
+			err.Error() == "failed to find feed token: record not found":
+			sendErrorResponse(w, "Feed token not found", "feed_token_not_found", http.StatusNotFound)
+		default:
+			sendErrorResponse(w, "Failed to revoke feed token", "feed_token_delete_error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	// Create success response
+	response := model.FeedTokenDeleteResponse{
+		Success: true,
+		Message: "Feed token revoked successfully",
+	}
+
+	// Send response
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		h.logger.Error("Failed to encode response", zap.Error(err))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+}
+
+// GetPrivateCalendarFeed serves the private ICS feed identified by a secret feed token
+// @Summary Get Private Calendar Feed
+// @Description Returns every calendar of the token's owner, including private events and without redaction, as an ICS (iCalendar) feed. Covers a rolling window from 30 days ago to 150 days ahead. The token in the URL is the only credential.
+// @Tags User
+// @Produce text/calendar
+// @Param token path string true "Secret feed token"
+// @Param calendars query string false "Comma separated calendar IDs to include (defaults to all calendars)"
+// @Success 200 {string} string "ICS feed"
+// @Success 304 {string} string "Not Modified - The feed has not changed since the ETag sent in If-None-Match"
+// @Failure 400 {object} model.ErrorResponse "Bad Request - Invalid calendar filter"
+// @Failure 404 {object} model.ErrorResponse "Not Found - Unknown or revoked feed token"
+// @Failure 500 {object} model.ErrorResponse "Internal server error"
+// @Router /api/feeds/{token}.ics [get]
+func (h *FeedHandler) GetPrivateCalendarFeed(w http.ResponseWriter, r *http.Request) {
+	user, err := h.feedService.AuthenticateFeedToken(r.PathValue("token"))
+	if err != nil {
+		// Unknown and revoked tokens are indistinguishable from a missing feed
+		sendErrorResponse(w, "Feed not found", "feed_not_found", http.StatusNotFound)
+		return
+	}
+
+	calendarIDs, err := parseCalendarFilter(r.URL.Query().Get("calendars"))
+	if err != nil {
+		sendErrorResponse(w, "Invalid calendars filter", "invalid_calendar_filter", http.StatusBadRequest)
+		return
+	}
+
+	feed, err := h.calendarService.GetUserCalendarICS(user, calendarIDs)
+	if err != nil {
+		h.logger.Error("Failed to render private calendar feed", zap.Error(err), zap.Uint64("user_id", user.ID))
+
+		// Handle specific error cases
+		switch {
+		case err.Error() == "calendar not found or access denied":
+			sendErrorResponse(w, "Calendar not found or access denied", "calendar_not_found", http.StatusNotFound)
+		default:
+			sendErrorResponse(w, "Failed to render calendar feed", "calendar_feed_error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	writeICSFeed(w, r, feed, user.Username+".ics", "private")
+}
+
+// sendFeedTokenResponse sends a feed token together with its secret and feed path
+func (h *FeedHandler) sendFeedTokenResponse(w http.ResponseWriter, statusCode int, message string, feedToken *model.FeedToken, token string) {
+	response := model.FeedTokenResponse{
+		Success:   true,
+		Message:   message,
+		FeedToken: feedToken,
+		Token:     token,
+		FeedPath:  "/api/feeds/" + token + ".ics",
+	}
+
+	// Secrets must not be cached by intermediaries
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		h.logger.Error("Failed to encode response", zap.Error(err))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+}
+
+// parseCalendarFilter parses a comma separated list of calendar IDs
+func parseCalendarFilter(value string) ([]uint64, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	var calendarIDs []uint64
+	for _, part := range strings.Split(value, ",") {
+		id, err := strconv.ParseUint(strings.TrimSpace(part), 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		calendarIDs = append(calendarIDs, id)
+	}
+
+	return calendarIDs, nil
+}
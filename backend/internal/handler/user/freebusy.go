@@ -0,0 +1,111 @@
+package user
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/NathanWasTaken/timely/backend/internal/model"
+)
+
+// GetPublicUserFreeBusy retrieves the busy times of a specific user
+// @Summary Get Public User Free/Busy
+// @Description Retrieves the merged busy intervals of a user within a specified time range (max 6 months), across all calendars the user shares free/busy information of. Private events are included but no event details are returned. Responds with an iCalendar VFREEBUSY when format=ics is set or text/calendar is accepted. No authentication required.
+// @Tags User
+// @Produce json
+// @Produce text/calendar
+// @Param username path string true "Username"
+// @Param start_timestamp query string true "Start timestamp in Unix format"
+// @Param end_timestamp query string true "End timestamp in Unix format"
+// @Param format query string false "Response format (json or ics)"
+// @Success 200 {object} model.FreeBusyResponse "Free/busy retrieved successfully"
+// @Failure 400 {object} model.ErrorResponse "Bad Request - Invalid parameters or time range"
+// @Failure 404 {object} model.ErrorResponse "Not Found - User not found"
+// @Failure 500 {object} model.ErrorResponse "Internal server error"
+// @Router /api/users/{username}/freebusy [get]
+func (h *UserEventsHandler) GetPublicUserFreeBusy(w http.ResponseWriter, r *http.Request) {
+	// Get username from path parameter
+	username := r.PathValue("username")
+	if username == "" {
+		h.logger.Error("Username not provided in path")
+		sendEventsErrorResponse(w, "Username is required", "missing_username", http.StatusBadRequest)
+		return
+	}
+
+	// Get user by username
+	user, err := h.userService.GetUserByUsername(username)
+	if err != nil {
+		h.logger.Error("Failed to get user by username", zap.Error(err), zap.String("username", username))
+		sendEventsErrorResponse(w, "User not found", "user_not_found", http.StatusNotFound)
+		return
+	}
+
+	startTime, endTime, ok := parseTimeRange(w, r, h.logger)
+	if !ok {
+		return
+	}
+
+	if wantsICS(r) {
+		freeBusy, err := h.calendarService.GetUserFreeBusyICS(user, startTime, endTime)
+		if err != nil {
+			h.sendFreeBusyError(w, err, user.ID)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=%q", user.Username+"-freebusy.ics"))
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(freeBusy))
+		return
+	}
+
+	busy, err := h.calendarService.GetUserFreeBusy(user.ID, startTime, endTime)
+	if err != nil {
+		h.sendFreeBusyError(w, err, user.ID)
+		return
+	}
+
+	// Create success response
+	response := model.FreeBusyResponse{
+		Success: true,
+		Message: "Free/busy retrieved successfully",
+		Start:   startTime.UTC(),
+		End:     endTime.UTC(),
+		Busy:    busy,
+	}
+
+	// Send response
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		h.logger.Error("Failed to encode response", zap.Error(err))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+}
+
+// sendFreeBusyError maps a free/busy service error to an error response
+func (h *UserEventsHandler) sendFreeBusyError(w http.ResponseWriter, err error, userID uint64) {
+	h.logger.Error("Failed to get free/busy", zap.Error(err), zap.Uint64("user_id", userID))
+
+	// Handle specific error cases
+	switch {
+	case err.Error() == "time range cannot exceed 6 months":
+		sendEventsErrorResponse(w, "Time range cannot exceed 6 months", "time_range_too_large", http.StatusBadRequest)
+	default:
+		sendEventsErrorResponse(w, "Failed to retrieve free/busy", "freebusy_fetch_error", http.StatusInternalServerError)
+	}
+}
+
+// wantsICS checks whether a request asks for an iCalendar response, through the format query
+// parameter or the Accept header
+func wantsICS(r *http.Request) bool {
+	if format := r.URL.Query().Get("format"); format != "" {
+		return strings.EqualFold(format, "ics")
+	}
+	return strings.Contains(r.Header.Get("Accept"), "text/calendar")
+}
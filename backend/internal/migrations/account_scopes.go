@@ -0,0 +1,19 @@
+package migrations
+
+import (
+	"github.com/NathanWasTaken/timely/backend/internal/model"
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+// AccountScopes adds the scopes granted to the OAuth tokens of accounts, unknown for existing accounts
+// until their owners sign in again
+var AccountScopes = &gormigrate.Migration{
+	ID: "202510160022",
+	Migrate: func(tx *gorm.DB) error {
+		return tx.AutoMigrate(&model.Account{})
+	},
+	Rollback: func(tx *gorm.DB) error {
+		return tx.Migrator().DropColumn(&model.Account{}, "scopes")
+	},
+}
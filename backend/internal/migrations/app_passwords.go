@@ -0,0 +1,18 @@
+package migrations
+
+import (
+	"github.com/NathanWasTaken/timely/backend/internal/model"
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+// AppPasswords creates the table holding the app passwords of the built-in CalDAV server
+var AppPasswords = &gormigrate.Migration{
+	ID: "202510160011",
+	Migrate: func(tx *gorm.DB) error {
+		return tx.AutoMigrate(&model.AppPassword{})
+	},
+	Rollback: func(tx *gorm.DB) error {
+		return tx.Migrator().DropTable(&model.AppPassword{})
+	},
+}
@@ -0,0 +1,18 @@
+package migrations
+
+import (
+	"github.com/NathanWasTaken/timely/backend/internal/model"
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+// BackfillJobs adds the jobs fetching the historical events of Google calendars
+var BackfillJobs = &gormigrate.Migration{
+	ID: "202510160013",
+	Migrate: func(tx *gorm.DB) error {
+		return tx.AutoMigrate(&model.BackfillJob{})
+	},
+	Rollback: func(tx *gorm.DB) error {
+		return tx.Migrator().DropTable(&model.BackfillJob{})
+	},
+}
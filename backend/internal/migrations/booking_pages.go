@@ -0,0 +1,18 @@
+package migrations
+
+import (
+	"github.com/NathanWasTaken/timely/backend/internal/model"
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+// BookingPages adds booking types and the bookings made on them
+var BookingPages = &gormigrate.Migration{
+	ID: "202510160007",
+	Migrate: func(tx *gorm.DB) error {
+		return tx.AutoMigrate(&model.BookingType{}, &model.Booking{})
+	},
+	Rollback: func(tx *gorm.DB) error {
+		return tx.Migrator().DropTable(&model.Booking{}, &model.BookingType{})
+	},
+}
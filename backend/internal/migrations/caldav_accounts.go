@@ -0,0 +1,27 @@
+package migrations
+
+import (
+	"github.com/NathanWasTaken/timely/backend/internal/model"
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+// CalDAVAccounts adds CalDAV accounts, the account of CalDAV calendars and the remote resource
+// of their events
+var CalDAVAccounts = &gormigrate.Migration{
+	ID: "202510160010",
+	Migrate: func(tx *gorm.DB) error {
+		return tx.AutoMigrate(&model.CalDAVAccount{}, &model.Calendar{}, &model.CalendarEvent{})
+	},
+	Rollback: func(tx *gorm.DB) error {
+		for _, column := range []string{"source_url", "source_etag"} {
+			if err := tx.Migrator().DropColumn(&model.CalendarEvent{}, column); err != nil {
+				return err
+			}
+		}
+		if err := tx.Migrator().DropColumn(&model.Calendar{}, "caldav_account_id"); err != nil {
+			return err
+		}
+		return tx.Migrator().DropTable(&model.CalDAVAccount{})
+	},
+}
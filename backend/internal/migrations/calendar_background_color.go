@@ -0,0 +1,19 @@
+package migrations
+
+import (
+	"github.com/NathanWasTaken/timely/backend/internal/model"
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+// CalendarBackgroundColor adds the color calendars have at their source, which the colors of their
+// events fall back to
+var CalendarBackgroundColor = &gormigrate.Migration{
+	ID: "202510160016",
+	Migrate: func(tx *gorm.DB) error {
+		return tx.AutoMigrate(&model.Calendar{})
+	},
+	Rollback: func(tx *gorm.DB) error {
+		return tx.Migrator().DropColumn(&model.Calendar{}, "background_color")
+	},
+}
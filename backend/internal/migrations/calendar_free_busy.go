@@ -0,0 +1,18 @@
+package migrations
+
+import (
+	"github.com/NathanWasTaken/timely/backend/internal/model"
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+// CalendarFreeBusy adds the opt-in flag for sharing a calendar's busy times on the free/busy endpoint
+var CalendarFreeBusy = &gormigrate.Migration{
+	ID: "202510160006",
+	Migrate: func(tx *gorm.DB) error {
+		return tx.AutoMigrate(&model.Calendar{})
+	},
+	Rollback: func(tx *gorm.DB) error {
+		return tx.Migrator().DropColumn(&model.Calendar{}, "free_busy")
+	},
+}
@@ -0,0 +1,18 @@
+package migrations
+
+import (
+	"github.com/NathanWasTaken/timely/backend/internal/model"
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+// CalendarRedactionMode adds the redaction mode of calendars, defaulting existing ones to full redaction
+var CalendarRedactionMode = &gormigrate.Migration{
+	ID: "202510160005",
+	Migrate: func(tx *gorm.DB) error {
+		return tx.AutoMigrate(&model.Calendar{})
+	},
+	Rollback: func(tx *gorm.DB) error {
+		return tx.Migrator().DropColumn(&model.Calendar{}, "redaction_mode")
+	},
+}
@@ -0,0 +1,29 @@
+package migrations
+
+import (
+	"github.com/NathanWasTaken/timely/backend/internal/model"
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+// CalendarSubscription adds the columns used to track remote ICS feed subscriptions
+var CalendarSubscription = &gormigrate.Migration{
+	ID: "202510160002",
+	Migrate: func(tx *gorm.DB) error {
+		return tx.AutoMigrate(&model.Calendar{})
+	},
+	Rollback: func(tx *gorm.DB) error {
+		for _, column := range []string{
+			"subscription_url",
+			"subscription_etag",
+			"subscription_last_modified",
+			"last_fetched_at",
+			"last_fetch_error",
+		} {
+			if err := tx.Migrator().DropColumn(&model.Calendar{}, column); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+}
@@ -0,0 +1,18 @@
+package migrations
+
+import (
+	"github.com/NathanWasTaken/timely/backend/internal/model"
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+// CalendarSyncLeaseTokens adds the token identifying the sync holding the sync lease of a calendar
+var CalendarSyncLeaseTokens = &gormigrate.Migration{
+	ID: "202510160025",
+	Migrate: func(tx *gorm.DB) error {
+		return tx.AutoMigrate(&model.Calendar{})
+	},
+	Rollback: func(tx *gorm.DB) error {
+		return tx.Migrator().DropColumn(&model.Calendar{}, "sync_lease_token")
+	},
+}
@@ -0,0 +1,23 @@
+package migrations
+
+import (
+	"github.com/NathanWasTaken/timely/backend/internal/model"
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+// CalendarSyncSchedule adds the background sync interval, schedule and lease of calendars
+var CalendarSyncSchedule = &gormigrate.Migration{
+	ID: "202510160008",
+	Migrate: func(tx *gorm.DB) error {
+		return tx.AutoMigrate(&model.Calendar{})
+	},
+	Rollback: func(tx *gorm.DB) error {
+		for _, column := range []string{"sync_interval_minutes", "next_sync_at", "sync_locked_until"} {
+			if err := tx.Migrator().DropColumn(&model.Calendar{}, column); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+}
@@ -0,0 +1,23 @@
+package migrations
+
+import (
+	"github.com/NathanWasTaken/timely/backend/internal/model"
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+// CalendarWatchChannel adds the Google push notification channel of calendars
+var CalendarWatchChannel = &gormigrate.Migration{
+	ID: "202510160009",
+	Migrate: func(tx *gorm.DB) error {
+		return tx.AutoMigrate(&model.Calendar{})
+	},
+	Rollback: func(tx *gorm.DB) error {
+		for _, column := range []string{"watch_channel_id", "watch_resource_id", "watch_token_hash", "watch_expires_at"} {
+			if err := tx.Migrator().DropColumn(&model.Calendar{}, column); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+}
@@ -0,0 +1,18 @@
+package migrations
+
+import (
+	"github.com/NathanWasTaken/timely/backend/internal/model"
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+// DAVObjects creates the table holding the calendar objects written by CalDAV clients
+var DAVObjects = &gormigrate.Migration{
+	ID: "202510160023",
+	Migrate: func(tx *gorm.DB) error {
+		return tx.AutoMigrate(&model.DAVObject{})
+	},
+	Rollback: func(tx *gorm.DB) error {
+		return tx.Migrator().DropTable(&model.DAVObject{})
+	},
+}
@@ -0,0 +1,25 @@
+package migrations
+
+import (
+	"github.com/NathanWasTaken/timely/backend/internal/model"
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+// EventAttendees adds the attendees of events, the owner's response to them and the calendar
+// option hiding declined events
+var EventAttendees = &gormigrate.Migration{
+	ID: "202510160015",
+	Migrate: func(tx *gorm.DB) error {
+		return tx.AutoMigrate(&model.EventAttendee{}, &model.CalendarEvent{}, &model.Calendar{})
+	},
+	Rollback: func(tx *gorm.DB) error {
+		if err := tx.Migrator().DropColumn(&model.Calendar{}, "hide_declined"); err != nil {
+			return err
+		}
+		if err := tx.Migrator().DropColumn(&model.CalendarEvent{}, "response_status"); err != nil {
+			return err
+		}
+		return tx.Migrator().DropTable(&model.EventAttendee{})
+	},
+}
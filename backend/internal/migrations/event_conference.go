@@ -0,0 +1,23 @@
+package migrations
+
+import (
+	"github.com/NathanWasTaken/timely/backend/internal/model"
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+// EventConference adds the conference link of events
+var EventConference = &gormigrate.Migration{
+	ID: "202510160018",
+	Migrate: func(tx *gorm.DB) error {
+		return tx.AutoMigrate(&model.CalendarEvent{})
+	},
+	Rollback: func(tx *gorm.DB) error {
+		for _, column := range []string{"conference_url", "conference_provider"} {
+			if err := tx.Migrator().DropColumn(&model.CalendarEvent{}, column); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+}
@@ -0,0 +1,21 @@
+package migrations
+
+import (
+	"github.com/NathanWasTaken/timely/backend/internal/model"
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+// EventRecurrence adds the recurrence columns used by expanded ICS occurrences
+var EventRecurrence = &gormigrate.Migration{
+	ID: "202510160001",
+	Migrate: func(tx *gorm.DB) error {
+		return tx.AutoMigrate(&model.CalendarEvent{})
+	},
+	Rollback: func(tx *gorm.DB) error {
+		if err := tx.Migrator().DropColumn(&model.CalendarEvent{}, "recurring_event_id"); err != nil {
+			return err
+		}
+		return tx.Migrator().DropColumn(&model.CalendarEvent{}, "original_start_time")
+	},
+}
@@ -0,0 +1,114 @@
+package migrations
+
+import (
+	"log"
+	"maps"
+	"slices"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+// EventSearch adds the full-text index of the titles, locations and descriptions of events: a
+// tsvector GIN index on Postgres and a FULLTEXT index on MySQL. The FTS5 table of SQLite depends on
+// how the server is built, so EnsureEventSearchIndex maintains it at every startup.
+var EventSearch = &gormigrate.Migration{
+	ID: "202510160019",
+	Migrate: func(tx *gorm.DB) error {
+		switch tx.Dialector.Name() {
+		case "postgres":
+			// The expression must match the one CalendarRepository.SearchEvents queries
+			return tx.Exec(`CREATE INDEX IF NOT EXISTS idx_calendar_events_search ON calendar_events USING GIN ` +
+				`(to_tsvector('simple', coalesce(title, '') || ' ' || coalesce(location, '') || ' ' || coalesce(description, '')))`).Error
+		case "mysql":
+			return tx.Exec("CREATE FULLTEXT INDEX idx_calendar_events_search ON calendar_events (title, location, description)").Error
+		default:
+			return EnsureEventSearchIndex(tx)
+		}
+	},
+	Rollback: func(tx *gorm.DB) error {
+		switch tx.Dialector.Name() {
+		case "postgres":
+			return tx.Exec("DROP INDEX IF EXISTS idx_calendar_events_search").Error
+		case "mysql":
+			return tx.Exec("DROP INDEX idx_calendar_events_search ON calendar_events").Error
+		default:
+			for _, statement := range []string{
+				"DROP TRIGGER IF EXISTS calendar_events_fts_insert",
+				"DROP TRIGGER IF EXISTS calendar_events_fts_delete",
+				"DROP TRIGGER IF EXISTS calendar_events_fts_update",
+				"DROP TABLE IF EXISTS calendar_events_fts",
+			} {
+				if err := tx.Exec(statement).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	},
+}
+
+// eventSearchTriggers keep the FTS5 table of SQLite up to date with calendar_events
+var eventSearchTriggers = map[string]string{
+	"calendar_events_fts_insert": `CREATE TRIGGER IF NOT EXISTS calendar_events_fts_insert AFTER INSERT ON calendar_events BEGIN
+		INSERT INTO calendar_events_fts (rowid, title, location, description) VALUES (new.id, new.title, new.location, new.description);
+	END`,
+	"calendar_events_fts_delete": `CREATE TRIGGER IF NOT EXISTS calendar_events_fts_delete AFTER DELETE ON calendar_events BEGIN
+		INSERT INTO calendar_events_fts (calendar_events_fts, rowid, title, location, description) VALUES ('delete', old.id, old.title, old.location, old.description);
+	END`,
+	"calendar_events_fts_update": `CREATE TRIGGER IF NOT EXISTS calendar_events_fts_update AFTER UPDATE ON calendar_events BEGIN
+		INSERT INTO calendar_events_fts (calendar_events_fts, rowid, title, location, description) VALUES ('delete', old.id, old.title, old.location, old.description);
+		INSERT INTO calendar_events_fts (rowid, title, location, description) VALUES (new.id, new.title, new.location, new.description);
+	END`,
+}
+
+// EnsureEventSearchIndex maintains the FTS5 table SQLite searches events with, and does nothing on
+// other databases. It runs at every startup instead of once as a migration, since FTS5 depends on
+// the build: go-sqlite3 only includes it with the sqlite_fts5 build tag. With FTS5 the table and the
+// triggers keeping it up to date are created if missing, indexing the events stored before. Without
+// it the triggers are dropped, they would fail every write of events, and searching falls back to
+// scanning the events.
+func EnsureEventSearchIndex(db *gorm.DB) error {
+	if db.Dialector.Name() != "sqlite" {
+		return nil
+	}
+
+	var fts5 bool
+	if err := db.Raw("SELECT sqlite_compileoption_used('ENABLE_FTS5')").Scan(&fts5).Error; err != nil {
+		return err
+	}
+	if !fts5 {
+		log.Println("SQLite was built without FTS5, events are searched without a full-text index")
+		for name := range eventSearchTriggers {
+			if err := db.Exec("DROP TRIGGER IF EXISTS " + name).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	var triggers int64
+	err := db.Raw("SELECT COUNT(*) FROM sqlite_master WHERE type = 'trigger' AND name IN ?", slices.Collect(maps.Keys(eventSearchTriggers))).
+		Scan(&triggers).Error
+	if err != nil {
+		return err
+	}
+	if triggers == int64(len(eventSearchTriggers)) {
+		return nil
+	}
+
+	// Events written while the triggers were missing are indexed by the rebuild
+	return db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Exec("CREATE VIRTUAL TABLE IF NOT EXISTS calendar_events_fts USING fts5" +
+			"(title, location, description, content='calendar_events', content_rowid='id')").Error
+		if err != nil {
+			return err
+		}
+		for _, statement := range eventSearchTriggers {
+			if err := tx.Exec(statement).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Exec("INSERT INTO calendar_events_fts (calendar_events_fts) VALUES ('rebuild')").Error
+	})
+}
@@ -0,0 +1,43 @@
+package migrations
+
+import (
+	"time"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+// EventSourceIndex adds a unique index on the calendar and source ID of events, so two syncs of a
+// calendar can never both store the same event. Deleted events are left out of the index, since a
+// removed event may come back with the same source ID. Duplicates stored before the index existed
+// are deleted, keeping the oldest. MySQL has no partial indexes, so its index ignores deleted
+// events through a functional key part, which needs MySQL 8.0.13 or later.
+var EventSourceIndex = &gormigrate.Migration{
+	ID: "202510160020",
+	Migrate: func(tx *gorm.DB) error {
+		// The subquery is wrapped in a derived table, as MySQL can't select from the table it updates
+		err := tx.Exec(`UPDATE calendar_events SET deleted_at = ? WHERE deleted_at IS NULL AND id NOT IN `+
+			`(SELECT id FROM (SELECT MIN(id) AS id FROM calendar_events WHERE deleted_at IS NULL GROUP BY calendar_id, source_id) AS kept)`,
+			time.Now()).Error
+		if err != nil {
+			return err
+		}
+
+		switch tx.Dialector.Name() {
+		case "mysql":
+			return tx.Exec("CREATE UNIQUE INDEX idx_calendar_events_source ON calendar_events " +
+				"(calendar_id, (SHA2(source_id, 256)), (IF(deleted_at IS NULL, 1, NULL)))").Error
+		default:
+			return tx.Exec("CREATE UNIQUE INDEX IF NOT EXISTS idx_calendar_events_source ON calendar_events " +
+				"(calendar_id, source_id) WHERE deleted_at IS NULL").Error
+		}
+	},
+	Rollback: func(tx *gorm.DB) error {
+		switch tx.Dialector.Name() {
+		case "mysql":
+			return tx.Exec("DROP INDEX idx_calendar_events_source ON calendar_events").Error
+		default:
+			return tx.Exec("DROP INDEX IF EXISTS idx_calendar_events_source").Error
+		}
+	},
+}
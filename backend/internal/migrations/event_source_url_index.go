@@ -0,0 +1,28 @@
+package migrations
+
+import (
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+// EventSourceURLIndex adds an index on the calendar and source URL of events, which CalDAV looks
+// up the events of a single resource by. MySQL can only index a prefix of text columns.
+var EventSourceURLIndex = &gormigrate.Migration{
+	ID: "202510160026",
+	Migrate: func(tx *gorm.DB) error {
+		switch tx.Dialector.Name() {
+		case "mysql":
+			return tx.Exec("CREATE INDEX idx_calendar_events_source_url ON calendar_events (calendar_id, source_url(255))").Error
+		default:
+			return tx.Exec("CREATE INDEX IF NOT EXISTS idx_calendar_events_source_url ON calendar_events (calendar_id, source_url)").Error
+		}
+	},
+	Rollback: func(tx *gorm.DB) error {
+		switch tx.Dialector.Name() {
+		case "mysql":
+			return tx.Exec("DROP INDEX idx_calendar_events_source_url ON calendar_events").Error
+		default:
+			return tx.Exec("DROP INDEX IF EXISTS idx_calendar_events_source_url").Error
+		}
+	},
+}
@@ -0,0 +1,23 @@
+package migrations
+
+import (
+	"github.com/NathanWasTaken/timely/backend/internal/model"
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+// EventStatus adds the status and transparency of events, existing events are confirmed and opaque
+var EventStatus = &gormigrate.Migration{
+	ID: "202510160017",
+	Migrate: func(tx *gorm.DB) error {
+		return tx.AutoMigrate(&model.CalendarEvent{})
+	},
+	Rollback: func(tx *gorm.DB) error {
+		for _, column := range []string{"status", "transparency"} {
+			if err := tx.Migrator().DropColumn(&model.CalendarEvent{}, column); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+}
@@ -0,0 +1,18 @@
+package migrations
+
+import (
+	"github.com/NathanWasTaken/timely/backend/internal/model"
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+// EventTitleOverride adds the per-event title override shown instead of the calendar's redaction
+var EventTitleOverride = &gormigrate.Migration{
+	ID: "202510160004",
+	Migrate: func(tx *gorm.DB) error {
+		return tx.AutoMigrate(&model.CalendarEvent{})
+	},
+	Rollback: func(tx *gorm.DB) error {
+		return tx.Migrator().DropColumn(&model.CalendarEvent{}, "title_override")
+	},
+}
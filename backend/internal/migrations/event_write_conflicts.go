@@ -0,0 +1,23 @@
+package migrations
+
+import (
+	"github.com/NathanWasTaken/timely/backend/internal/model"
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+// EventWriteConflicts adds the fields queued writes are reconciled with changes made on Google by
+var EventWriteConflicts = &gormigrate.Migration{
+	ID: "202510160021",
+	Migrate: func(tx *gorm.DB) error {
+		return tx.AutoMigrate(&model.EventWrite{})
+	},
+	Rollback: func(tx *gorm.DB) error {
+		for _, column := range []string{"base", "conflict"} {
+			if err := tx.Migrator().DropColumn(&model.EventWrite{}, column); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+}
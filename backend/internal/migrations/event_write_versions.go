@@ -0,0 +1,18 @@
+package migrations
+
+import (
+	"github.com/NathanWasTaken/timely/backend/internal/model"
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+// EventWriteVersions adds the version pushes of queued writes are finished against
+var EventWriteVersions = &gormigrate.Migration{
+	ID: "202510160024",
+	Migrate: func(tx *gorm.DB) error {
+		return tx.AutoMigrate(&model.EventWrite{})
+	},
+	Rollback: func(tx *gorm.DB) error {
+		return tx.Migrator().DropColumn(&model.EventWrite{}, "version")
+	},
+}
@@ -0,0 +1,21 @@
+package migrations
+
+import (
+	"github.com/NathanWasTaken/timely/backend/internal/model"
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+// EventWrites adds the write-back flag of calendars and the queue of changes pushed to Google
+var EventWrites = &gormigrate.Migration{
+	ID: "202510160012",
+	Migrate: func(tx *gorm.DB) error {
+		return tx.AutoMigrate(&model.Calendar{}, &model.EventWrite{})
+	},
+	Rollback: func(tx *gorm.DB) error {
+		if err := tx.Migrator().DropTable(&model.EventWrite{}); err != nil {
+			return err
+		}
+		return tx.Migrator().DropColumn(&model.Calendar{}, "write_back")
+	},
+}
@@ -0,0 +1,18 @@
+package migrations
+
+import (
+	"github.com/NathanWasTaken/timely/backend/internal/model"
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+// FeedTokens creates the table holding private ICS feed tokens
+var FeedTokens = &gormigrate.Migration{
+	ID: "202510160003",
+	Migrate: func(tx *gorm.DB) error {
+		return tx.AutoMigrate(&model.FeedToken{})
+	},
+	Rollback: func(tx *gorm.DB) error {
+		return tx.Migrator().DropTable(&model.FeedToken{})
+	},
+}
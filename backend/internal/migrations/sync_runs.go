@@ -0,0 +1,18 @@
+package migrations
+
+import (
+	"github.com/NathanWasTaken/timely/backend/internal/model"
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+// SyncRuns adds the history of calendar syncs
+var SyncRuns = &gormigrate.Migration{
+	ID: "202510160014",
+	Migrate: func(tx *gorm.DB) error {
+		return tx.AutoMigrate(&model.SyncRun{})
+	},
+	Rollback: func(tx *gorm.DB) error {
+		return tx.Migrator().DropTable(&model.SyncRun{})
+	},
+}
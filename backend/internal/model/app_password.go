@@ -0,0 +1,55 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// AppPassword represents a revocable password for CalDAV clients, which cannot sign in with Google
+// or send a JWT. Only the SHA-256 hash of the password is stored.
+// @Description App password
+type AppPassword struct {
+	ID           uint64         `json:"id,string" gorm:"primaryKey" example:"123456789"` // Unique app password identifier
+	UserID       uint64         `json:"user_id,string" gorm:"index" example:"123456789"` // Owner of the app password
+	Name         string         `json:"name" example:"iPhone"`                           // Label to tell app passwords apart
+	PasswordHash string         `json:"-" gorm:"uniqueIndex;size:64"`                    // SHA-256 hash of the password
+	PasswordHint string         `json:"password_hint" example:"x1Yz"`                    // Last characters of the password
+	LastUsedAt   *time.Time     `json:"last_used_at,omitempty"`                          // Last time a client signed in with it
+	CreatedAt    time.Time      `json:"created_at" example:"2024-01-01T00:00:00Z"`       // Creation timestamp
+	UpdatedAt    time.Time      `json:"updated_at" example:"2024-01-01T00:00:00Z"`       // Last update timestamp
+	DeletedAt    gorm.DeletedAt `json:"-" gorm:"index"`                                  // Soft delete timestamp (revoked app passwords)
+}
+
+// AppPasswordCreateRequest represents the request body for creating an app password
+// @Description App password create request
+type AppPasswordCreateRequest struct {
+	Name string `json:"name,omitempty" example:"iPhone"` // Optional label for the app password
+}
+
+// AppPasswordResponse represents the response for creating an app password.
+// The password itself is only returned once.
+// @Description App password response
+type AppPasswordResponse struct {
+	Success     bool         `json:"success" example:"true"`
+	Message     string       `json:"message" example:"App password created successfully"`
+	AppPassword *AppPassword `json:"app_password"`
+	Username    string       `json:"username" example:"alex"` // Username to sign in with
+	Password    string       `json:"password" example:"q5ZQ2vWl3b1n0tFqG0xwY2m6j8Q4aJ9kS7cX1Yz"`
+	DAVPath     string       `json:"dav_path" example:"/dav/"` // Path of the CalDAV server to add to clients
+}
+
+// AppPasswordsResponse represents the response for listing app passwords
+// @Description App passwords response
+type AppPasswordsResponse struct {
+	Success      bool           `json:"success" example:"true"`
+	Message      string         `json:"message" example:"App passwords retrieved successfully"`
+	AppPasswords []*AppPassword `json:"app_passwords"`
+}
+
+// AppPasswordDeleteResponse represents the response for revoking an app password
+// @Description App password delete response
+type AppPasswordDeleteResponse struct {
+	Success bool   `json:"success" example:"true"`
+	Message string `json:"message" example:"App password revoked successfully"`
+}
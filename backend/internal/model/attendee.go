@@ -0,0 +1,36 @@
+package model
+
+// EventAttendeeRole is the part an attendee plays in an event (RFC 5545 ROLE)
+type EventAttendeeRole string
+
+const (
+	EventAttendeeRoleChair          EventAttendeeRole = "chair"
+	EventAttendeeRoleRequired       EventAttendeeRole = "required"
+	EventAttendeeRoleOptional       EventAttendeeRole = "optional"
+	EventAttendeeRoleNonParticipant EventAttendeeRole = "non_participant"
+)
+
+// EventAttendeeStatus is the answer of an attendee to the invitation (RFC 5545 PARTSTAT)
+type EventAttendeeStatus string
+
+const (
+	EventAttendeeStatusNeedsAction EventAttendeeStatus = "needs_action"
+	EventAttendeeStatusAccepted    EventAttendeeStatus = "accepted"
+	EventAttendeeStatusDeclined    EventAttendeeStatus = "declined"
+	EventAttendeeStatusTentative   EventAttendeeStatus = "tentative"
+	EventAttendeeStatusDelegated   EventAttendeeStatus = "delegated"
+)
+
+// EventAttendee is a guest or the organizer of an event, as listed by the event's source. The
+// attendees of an event are replaced whenever sync updates the event.
+// @Description Event attendee
+type EventAttendee struct {
+	ID          uint64              `json:"-" gorm:"primaryKey"`
+	EventID     uint64              `json:"-" gorm:"not null;index"`
+	Email       string              `json:"email" example:"alex@example.com"`
+	DisplayName string              `json:"display_name,omitempty" example:"Alex"`
+	Role        EventAttendeeRole   `json:"role" example:"required"`
+	Status      EventAttendeeStatus `json:"status" example:"accepted"`
+	Organizer   bool                `json:"organizer" example:"false"` // The attendee organizes the event
+	Self        bool                `json:"self" example:"false"`      // The attendee is the owner of the calendar
+}
@@ -0,0 +1,133 @@
+package model
+
+import (
+	"time"
+)
+
+// BookingAvailabilityRule represents a weekly window in which a booking type can be booked.
+// Times are wall clock times in the booking type's time zone.
+// @Description Booking availability rule
+type BookingAvailabilityRule struct {
+	Weekday   time.Weekday `json:"weekday" example:"1"`        // Day of the week, 0 is Sunday
+	StartTime string       `json:"start_time" example:"09:00"` // Start of the window (HH:MM)
+	EndTime   string       `json:"end_time" example:"17:00"`   // End of the window (HH:MM, 24:00 for midnight)
+}
+
+// BookingType represents a kind of meeting visitors can book on a user's booking page. Booking
+// types are deleted for good so their slug can be reused.
+// @Description Booking type
+type BookingType struct {
+	ID                   uint64                    `json:"id,string" gorm:"primaryKey" example:"123456789"`                                  // Unique snowflake ID
+	UserID               uint64                    `json:"user_id,string" gorm:"uniqueIndex:idx_booking_types_user_slug"`                    // Owner of the booking page
+	Slug                 string                    `json:"slug" gorm:"uniqueIndex:idx_booking_types_user_slug;size:64" example:"intro-call"` // URL name, unique per user
+	Title                string                    `json:"title" example:"Intro call"`                                                       // Title shown to visitors
+	Description          string                    `json:"description" example:"A quick call to get to know each other"`                     // Optional description shown to visitors
+	CalendarID           uint64                    `json:"calendar_id,string" example:"123456789"`                                           // Timely calendar bookings are created in
+	ConflictCalendarIDs  []string                  `json:"conflict_calendar_ids" gorm:"serializer:json"`                                     // Further calendars whose events block slots
+	DurationMinutes      int                       `json:"duration_minutes" example:"30"`                                                    // Length of a booking
+	BufferBeforeMinutes  int                       `json:"buffer_before_minutes" example:"5"`                                                // Free time required before a booking
+	BufferAfterMinutes   int                       `json:"buffer_after_minutes" example:"5"`                                                 // Free time required after a booking
+	MinimumNoticeMinutes int                       `json:"minimum_notice_minutes" example:"120"`                                             // How far ahead a booking must be made
+	TimeZone             string                    `json:"time_zone" example:"Europe/Berlin"`                                                // Time zone of the availability rules
+	Availability         []BookingAvailabilityRule `json:"availability" gorm:"serializer:json"`                                              // Weekly windows in which slots are offered
+	CreatedAt            time.Time                 `json:"created_at"`
+	UpdatedAt            time.Time                 `json:"updated_at"`
+}
+
+// Booking represents a slot a visitor booked, and the event created for it
+// @Description Booking
+type Booking struct {
+	ID            uint64    `json:"id,string" gorm:"primaryKey" example:"123456789"`                                 // Unique snowflake ID
+	UserID        uint64    `json:"user_id,string" gorm:"index" example:"123456789"`                                 // Owner of the booking page
+	BookingTypeID uint64    `json:"booking_type_id,string" gorm:"uniqueIndex:idx_bookings_type_start"`               // Booked booking type
+	EventID       uint64    `json:"event_id,string" example:"123456789"`                                             // Event created in the booking type's calendar
+	Name          string    `json:"name" example:"Jane Doe"`                                                         // Name of the visitor
+	Email         string    `json:"email" example:"jane@example.com"`                                                // Email of the visitor
+	Notes         string    `json:"notes,omitempty" example:"Looking forward to it"`                                 // Optional message of the visitor
+	Start         time.Time `json:"start" gorm:"uniqueIndex:idx_bookings_type_start" example:"2024-01-01T10:00:00Z"` // Start of the booked slot
+	End           time.Time `json:"end" example:"2024-01-01T10:30:00Z"`                                              // End of the booked slot
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// BookingTypeRequest represents the request body for creating or replacing a booking type
+// @Description Booking type create/update request
+type BookingTypeRequest struct {
+	Slug                 string                    `json:"slug" validate:"required" example:"intro-call"`
+	Title                string                    `json:"title" validate:"required" example:"Intro call"`
+	Description          string                    `json:"description,omitempty" example:"A quick call to get to know each other"`
+	CalendarID           string                    `json:"calendar_id" validate:"required" example:"123456789"`
+	ConflictCalendarIDs  []string                  `json:"conflict_calendar_ids,omitempty"`
+	DurationMinutes      int                       `json:"duration_minutes" validate:"required" example:"30"`
+	BufferBeforeMinutes  int                       `json:"buffer_before_minutes,omitempty" example:"5"`
+	BufferAfterMinutes   int                       `json:"buffer_after_minutes,omitempty" example:"5"`
+	MinimumNoticeMinutes int                       `json:"minimum_notice_minutes,omitempty" example:"120"`
+	TimeZone             string                    `json:"time_zone,omitempty" example:"Europe/Berlin"`
+	Availability         []BookingAvailabilityRule `json:"availability" validate:"required"`
+}
+
+// BookingTypeResponse represents the response for creating, updating or retrieving a booking type
+// @Description Booking type response
+type BookingTypeResponse struct {
+	Success     bool         `json:"success" example:"true"`
+	Message     string       `json:"message" example:"Booking type created successfully"`
+	BookingType *BookingType `json:"booking_type"`
+}
+
+// BookingTypesResponse represents the response for listing booking types
+// @Description Booking types response
+type BookingTypesResponse struct {
+	Success      bool           `json:"success" example:"true"`
+	Message      string         `json:"message" example:"Booking types retrieved successfully"`
+	BookingTypes []*BookingType `json:"booking_types"`
+}
+
+// BookingTypeDeleteResponse represents the response for deleting a booking type
+// @Description Booking type delete response
+type BookingTypeDeleteResponse struct {
+	Success bool   `json:"success" example:"true"`
+	Message string `json:"message" example:"Booking type deleted successfully"`
+}
+
+// BookingPage represents the public information of a booking type
+// @Description Booking page
+type BookingPage struct {
+	Slug            string `json:"slug" example:"intro-call"`
+	Title           string `json:"title" example:"Intro call"`
+	Description     string `json:"description" example:"A quick call to get to know each other"`
+	DurationMinutes int    `json:"duration_minutes" example:"30"`
+	TimeZone        string `json:"time_zone" example:"Europe/Berlin"`
+}
+
+// BookingSlot represents a time that can be booked
+// @Description Bookable slot
+type BookingSlot struct {
+	Start time.Time `json:"start" example:"2024-01-01T10:00:00Z"`
+	End   time.Time `json:"end" example:"2024-01-01T10:30:00Z"`
+}
+
+// BookingSlotsResponse represents the response for the open slots of a booking page
+// @Description Booking slots response
+type BookingSlotsResponse struct {
+	Success     bool           `json:"success" example:"true"`
+	Message     string         `json:"message" example:"Booking slots retrieved successfully"`
+	BookingPage *BookingPage   `json:"booking_page"`
+	Slots       []*BookingSlot `json:"slots"`
+}
+
+// BookingRequest represents the request body for booking a slot
+// @Description Booking request
+type BookingRequest struct {
+	Start string `json:"start" validate:"required" example:"2024-01-01T10:00:00Z"` // RFC 3339 start of one of the open slots
+	Name  string `json:"name" validate:"required" example:"Jane Doe"`
+	Email string `json:"email" validate:"required" example:"jane@example.com"`
+	Notes string `json:"notes,omitempty" example:"Looking forward to it"`
+}
+
+// BookingResponse represents the response for booking a slot
+// @Description Booking response
+type BookingResponse struct {
+	Success bool     `json:"success" example:"true"`
+	Message string   `json:"message" example:"Booking created successfully"`
+	Booking *Booking `json:"booking"`
+	ICS     string   `json:"ics"` // ICS confirmation to add the booking to the visitor's calendar
+}
@@ -0,0 +1,77 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// CalDAVAccount represents a CalDAV server a user connected, such as Nextcloud, Fastmail or iCloud.
+// The password is stored encrypted so it can be sent to the server on every sync.
+// @Description CalDAV account
+type CalDAVAccount struct {
+	ID                uint64         `json:"id,string" gorm:"primaryKey" example:"123456789"`               // Unique account identifier
+	UserID            uint64         `json:"user_id,string" gorm:"index" example:"123456789"`               // Owner of the account
+	ServerURL         string         `json:"server_url" example:"https://cloud.example.com/remote.php/dav"` // URL the account was connected with
+	Username          string         `json:"username" example:"alex"`                                       // Username on the CalDAV server
+	EncryptedPassword string         `json:"-"`                                                             // Password or app password, encrypted with CREDENTIALS_SECRET
+	CalendarHomeURL   string         `json:"-"`                                                             // Calendar home discovered from the server URL
+	CreatedAt         time.Time      `json:"created_at" example:"2024-01-01T00:00:00Z"`
+	UpdatedAt         time.Time      `json:"updated_at" example:"2024-01-01T00:00:00Z"`
+	DeletedAt         gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+// TableName keeps the table name readable, the default naming splits CalDAV into "cal_dav"
+func (CalDAVAccount) TableName() string {
+	return "caldav_accounts"
+}
+
+// CalDAVCalendar represents a calendar of a CalDAV account that can be imported
+// @Description CalDAV calendar
+type CalDAVCalendar struct {
+	URL         string `json:"url" example:"https://cloud.example.com/remote.php/dav/calendars/alex/personal/"`
+	Name        string `json:"name" example:"Personal"`
+	Description string `json:"description,omitempty" example:"Personal calendar"`
+	Color       string `json:"color,omitempty" example:"#0082c9"`
+	TimeZone    string `json:"time_zone,omitempty" example:"Europe/Berlin"`
+}
+
+// CalDAVAccountCreateRequest represents the request body for connecting a CalDAV account
+// @Description CalDAV account connect request
+type CalDAVAccountCreateRequest struct {
+	ServerURL string `json:"server_url" validate:"required" example:"https://cloud.example.com"`
+	Username  string `json:"username" validate:"required" example:"alex"`
+	Password  string `json:"password" validate:"required" example:"app-password"`
+}
+
+// CalDAVAccountResponse represents the response for connecting a CalDAV account
+// @Description CalDAV account response
+type CalDAVAccountResponse struct {
+	Success   bool              `json:"success" example:"true"`
+	Message   string            `json:"message" example:"CalDAV account connected successfully"`
+	Account   *CalDAVAccount    `json:"account"`
+	Calendars []*CalDAVCalendar `json:"calendars"`
+}
+
+// CalDAVAccountListResponse represents the response for listing CalDAV accounts
+// @Description CalDAV account list response
+type CalDAVAccountListResponse struct {
+	Success  bool             `json:"success" example:"true"`
+	Message  string           `json:"message" example:"CalDAV accounts retrieved successfully"`
+	Accounts []*CalDAVAccount `json:"accounts"`
+}
+
+// CalDAVCalendarListResponse represents the response for listing the calendars of a CalDAV account
+// @Description CalDAV calendar list response
+type CalDAVCalendarListResponse struct {
+	Success   bool              `json:"success" example:"true"`
+	Message   string            `json:"message" example:"Calendars retrieved successfully"`
+	Calendars []*CalDAVCalendar `json:"calendars"`
+}
+
+// CalDAVCalendarImportRequest represents the request body for importing a CalDAV calendar
+// @Description CalDAV calendar import request
+type CalDAVCalendarImportRequest struct {
+	AccountID   string `json:"account_id" validate:"required" example:"123456789"`
+	CalendarURL string `json:"calendar_url" validate:"required" example:"https://cloud.example.com/remote.php/dav/calendars/alex/personal/"`
+}
@@ -14,6 +14,34 @@ const (
 	CalendarEventVisibilityInherited CalendarEventVisibility = "inherited"
 )
 
+// CalendarEventStatus is the status of an event at its source
+type CalendarEventStatus string
+
+const (
+	CalendarEventStatusConfirmed CalendarEventStatus = "confirmed"
+	CalendarEventStatusTentative CalendarEventStatus = "tentative"
+	CalendarEventStatusCancelled CalendarEventStatus = "cancelled" // Cancelled but still listed by its source
+)
+
+// CalendarEventTransparency tells whether an event blocks time in free/busy and availability
+type CalendarEventTransparency string
+
+const (
+	CalendarEventTransparencyOpaque      CalendarEventTransparency = "opaque"      // The event blocks time
+	CalendarEventTransparencyTransparent CalendarEventTransparency = "transparent" // The owner is shown as free
+)
+
+// ConferenceProvider is the video conferencing service of an event's conference link
+type ConferenceProvider string
+
+const (
+	ConferenceProviderMeet    ConferenceProvider = "meet"
+	ConferenceProviderZoom    ConferenceProvider = "zoom"
+	ConferenceProviderTeams   ConferenceProvider = "teams"
+	ConferenceProviderWebex   ConferenceProvider = "webex"
+	ConferenceProviderGeneric ConferenceProvider = "generic" // Any other service
+)
+
 type CalendarVisibility string
 
 const (
@@ -21,11 +49,22 @@ const (
 	CalendarVisibilityPrivate CalendarVisibility = "private"
 )
 
+// CalendarRedactionMode controls how much of a calendar's redacted events is shown publicly
+type CalendarRedactionMode string
+
+const (
+	CalendarRedactionModeFull  CalendarRedactionMode = "full"  // Title replaced, location, description and color removed
+	CalendarRedactionModeTitle CalendarRedactionMode = "title" // Only the title is replaced
+	CalendarRedactionModeBusy  CalendarRedactionMode = "busy"  // Only start, end and all-day are shown
+)
+
 type CalendarSource string
 
 const (
 	SourceGoogle CalendarSource = "google"
 	SourceICS    CalendarSource = "ics"
+	SourceCalDAV CalendarSource = "caldav"
+	SourceTimely CalendarSource = "timely" // Calendars owned by Timely itself, edited through the API
 )
 
 type CalendarSyncStatus string
@@ -50,31 +89,80 @@ type CalendarEvent struct {
 	Location    string                  `json:"location"`           // Optional event location
 	Description string                  `json:"description"`        // Optional description
 	Visibility  CalendarEventVisibility `json:"visibility"`         // public / private / default
-	CreatedAt   time.Time               `json:"created_at"`
-	UpdatedAt   time.Time               `json:"updated_at"`
-	DeletedAt   gorm.DeletedAt          `json:"-" gorm:"index"`
+	// Title shown instead of the event title and the calendar's redaction, set by the owner
+	TitleOverride *string `json:"title_override,omitempty"`
+	// Remote resource the event was fetched from, for sources that store every event as a resource
+	SourceURL  string `json:"-"`                           // URL of the CalDAV resource holding the event
+	SourceETag string `json:"-" gorm:"column:source_etag"` // ETag of the resource when it was fetched
+	// Recurrence information for occurrences expanded from a recurring event
+	RecurringEventID  string         `json:"recurring_event_id,omitempty" gorm:"index"` // Source ID (UID) of the recurring series
+	OriginalStartTime *time.Time     `json:"original_start_time,omitempty"`             // Start of the occurrence before any override (RECURRENCE-ID)
+	CreatedAt         time.Time      `json:"created_at"`
+	UpdatedAt         time.Time      `json:"updated_at"`
+	DeletedAt         gorm.DeletedAt `json:"-" gorm:"index"`
+	// Guests and organizer of the event, only loaded for the owner of the calendar
+	Attendees []*EventAttendee `json:"attendees,omitempty" gorm:"foreignKey:EventID"`
+	// Response of the calendar owner to the invitation, empty if the owner is not a guest
+	ResponseStatus EventAttendeeStatus `json:"response_status,omitempty"`
+	// Status and transparency of the event, cancelled and transparent events do not block time
+	Status       CalendarEventStatus       `json:"status" gorm:"default:'confirmed'"`
+	Transparency CalendarEventTransparency `json:"transparency" gorm:"default:'opaque'"`
+	// Link to join the event's video conference, only shown publicly for explicitly public events
+	ConferenceURL      string             `json:"conference_url,omitempty"`
+	ConferenceProvider ConferenceProvider `json:"conference_provider,omitempty"`
+	// Set for the owner while a change made in Timely conflicts with a change made on Google
+	WriteConflict bool `json:"write_conflict,omitempty" gorm:"-"`
 }
 
 // Calendar represents a calendar
 // @Description Calendar
 type Calendar struct {
-	ID             uint64             `json:"id,string" gorm:"primaryKey"`
-	UserID         uint64             `json:"user_id,string" gorm:"index"`
-	SourceID       *string            `json:"source_id"`
-	Source         CalendarSource     `json:"source"`
-	Summary        string             `json:"summary"`
-	TimeZone       string             `json:"time_zone"`
-	Description    *string            `json:"description,omitempty"`
-	EventRedaction *string            `json:"event_redaction,omitempty"`
-	EventColor     *string            `json:"event_color,omitempty"`
-	Visibility     CalendarVisibility `json:"visibility"`
-	SyncedAt       time.Time          `json:"synced_at"`
-	SyncStatus     CalendarSyncStatus `json:"sync_status" gorm:"default:'never_synced'"`
-	SyncToken      *string            `json:"sync_token,omitempty"`
-	LastFullSync   *time.Time         `json:"last_full_sync,omitempty"`
-	CreatedAt      time.Time          `json:"created_at"`
-	UpdatedAt      time.Time          `json:"updated_at"`
-	DeletedAt      gorm.DeletedAt     `json:"-" gorm:"index"`
+	ID             uint64                `json:"id,string" gorm:"primaryKey"`
+	UserID         uint64                `json:"user_id,string" gorm:"index"`
+	SourceID       *string               `json:"source_id"`
+	Source         CalendarSource        `json:"source"`
+	Summary        string                `json:"summary"`
+	TimeZone       string                `json:"time_zone"`
+	Description    *string               `json:"description,omitempty"`
+	EventRedaction *string               `json:"event_redaction,omitempty"`
+	RedactionMode  CalendarRedactionMode `json:"redaction_mode" gorm:"default:'full'"`
+	EventColor     *string               `json:"event_color,omitempty"`
+	Visibility     CalendarVisibility    `json:"visibility"`
+	FreeBusy       bool                  `json:"free_busy"` // Busy times of all events, including private ones, are shared publicly
+	SyncedAt       time.Time             `json:"synced_at"`
+	SyncStatus     CalendarSyncStatus    `json:"sync_status" gorm:"default:'never_synced'"`
+	SyncToken      *string               `json:"sync_token,omitempty"`
+	LastFullSync   *time.Time            `json:"last_full_sync,omitempty"`
+	// CalDAV account the calendar is synced with, SourceID holds the URL of the calendar collection
+	CalDAVAccountID *uint64 `json:"caldav_account_id,string,omitempty" gorm:"column:caldav_account_id;index"`
+	// Changes made in Timely to the events of a Google calendar are written back to Google
+	WriteBack bool `json:"write_back"`
+	// Events the owner declined are left out of the calendar's events, free/busy and feeds
+	HideDeclined bool `json:"hide_declined"`
+	// Hex color of the calendar at its source. Events without a color of their own are shown in
+	// EventColor, set by the owner, or else in this color.
+	BackgroundColor *string `json:"background_color,omitempty"`
+	// Background sync schedule of Google calendars
+	SyncIntervalMinutes int        `json:"sync_interval_minutes"`               // Minutes between syncs, 0 for the server default
+	NextSyncAt          *time.Time `json:"next_sync_at,omitempty" gorm:"index"` // When the calendar is synced next
+	SyncLockedUntil     *time.Time `json:"-"`                                   // Lease held by the sync running for the calendar
+	SyncLeaseToken      *string    `json:"-"`                                   // Identifies the sync holding the lease
+	// Google push notification channel that triggers a sync when the calendar changes
+	WatchChannelID  *string    `json:"-" gorm:"uniqueIndex"`
+	WatchResourceID *string    `json:"-"`
+	WatchTokenHash  *string    `json:"-"` // SHA-256 hash of the token Google echoes with every notification
+	WatchExpiresAt  *time.Time `json:"watch_expires_at,omitempty"`
+	// Remote feed state for ICS calendars subscribed by URL
+	SubscriptionURL          *string        `json:"subscription_url,omitempty"`
+	SubscriptionETag         *string        `json:"-" gorm:"column:subscription_etag"`
+	SubscriptionLastModified *string        `json:"-"`
+	LastFetchedAt            *time.Time     `json:"last_fetched_at,omitempty"`
+	LastFetchError           *string        `json:"last_fetch_error,omitempty"`
+	CreatedAt                time.Time      `json:"created_at"`
+	UpdatedAt                time.Time      `json:"updated_at"`
+	DeletedAt                gorm.DeletedAt `json:"-" gorm:"index"`
+	// Sync health of calendars synced with a remote source, filled in when calendars are listed
+	Health *CalendarHealth `json:"health,omitempty" gorm:"-"`
 }
 
 // GoogleCalendar represents a calendar from Google Calendar API
@@ -133,15 +221,65 @@ type GoogleCalendarEvent struct {
 	End         *GoogleCalendarEventTime    `json:"end"`
 	Visibility  string                      `json:"visibility" example:"default"`
 	Attendees   []*GoogleCalendarEventActor `json:"attendees,omitempty"`
+	// "transparent" for events shown as free, opaque events block time
+	Transparency string `json:"transparency,omitempty" example:"opaque"`
+	// Video conference of the event, hangoutLink is only set for Google Meet
+	HangoutLink    string                `json:"hangoutLink,omitempty" example:"https://meet.google.com/abc-defg-hij"`
+	ConferenceData *GoogleConferenceData `json:"conferenceData,omitempty"`
+}
+
+// GoogleConferenceData represents the conference of a Google Calendar event
+// @Description Google Calendar conference data
+type GoogleConferenceData struct {
+	ConferenceID       string                        `json:"conferenceId,omitempty" example:"abc-defg-hij"`
+	ConferenceSolution *GoogleConferenceSolution     `json:"conferenceSolution,omitempty"`
+	EntryPoints        []*GoogleConferenceEntryPoint `json:"entryPoints,omitempty"`
+}
+
+// GoogleConferenceSolution represents the service providing the conference of a Google Calendar event
+// @Description Google Calendar conference solution
+type GoogleConferenceSolution struct {
+	Key struct {
+		Type string `json:"type" example:"hangoutsMeet"`
+	} `json:"key"`
+	Name string `json:"name" example:"Google Meet"`
+}
+
+// GoogleConferenceEntryPoint represents a way to join the conference of a Google Calendar event
+// @Description Google Calendar conference entry point
+type GoogleConferenceEntryPoint struct {
+	EntryPointType string `json:"entryPointType" example:"video"` // video, phone, sip or more
+	URI            string `json:"uri" example:"https://meet.google.com/abc-defg-hij"`
+	Label          string `json:"label,omitempty" example:"meet.google.com/abc-defg-hij"`
+}
+
+// GoogleColors represents the color palettes of the Google Calendar API, which calendars and events
+// refer to by their colorId
+// @Description Google Calendar color palettes
+type GoogleColors struct {
+	Kind     string                            `json:"kind" example:"calendar#colors"`
+	Updated  string                            `json:"updated" example:"2012-02-14T00:00:00.000Z"`
+	Calendar map[string]*GoogleColorDefinition `json:"calendar"`
+	Event    map[string]*GoogleColorDefinition `json:"event"`
+}
+
+// GoogleColorDefinition represents a color of the Google Calendar API palettes
+// @Description Google Calendar color
+type GoogleColorDefinition struct {
+	Background string `json:"background" example:"#a4bdfc"`
+	Foreground string `json:"foreground" example:"#1d1d1d"`
 }
 
 // GoogleCalendarEventActor represents a creator, organizer, or attendee of an event
 // @Description Google Calendar event actor information
 type GoogleCalendarEventActor struct {
-	ID          string `json:"id" example:"user@example.com"`
-	Email       string `json:"email" example:"user@example.com"`
-	DisplayName string `json:"displayName" example:"John Doe"`
-	Self        bool   `json:"self" example:"true"`
+	ID             string `json:"id" example:"user@example.com"`
+	Email          string `json:"email" example:"user@example.com"`
+	DisplayName    string `json:"displayName" example:"John Doe"`
+	Self           bool   `json:"self" example:"true"`
+	Organizer      bool   `json:"organizer,omitempty" example:"false"`            // Attendees only
+	Optional       bool   `json:"optional,omitempty" example:"false"`             // Attendees only
+	ResponseStatus string `json:"responseStatus,omitempty" example:"needsAction"` // Attendees only
 }
 
 // GoogleCalendarEventTime represents the start or end time of an event
@@ -165,6 +303,20 @@ type GoogleCalendarEventsResponse struct {
 	Items         []*GoogleCalendarEvent `json:"items"`
 }
 
+// GoogleWatchChannel represents a push notification channel of the Google Calendar API, used both to
+// open a channel and to stop it
+// @Description Google Calendar push notification channel
+type GoogleWatchChannel struct {
+	ID          string            `json:"id" example:"123456789"`
+	Type        string            `json:"type,omitempty" example:"web_hook"`
+	Address     string            `json:"address,omitempty" example:"https://timely.example.com/api/webhooks/google"`
+	Token       string            `json:"token,omitempty"`
+	Params      map[string]string `json:"params,omitempty"`
+	ResourceID  string            `json:"resourceId,omitempty" example:"o3hgv1538sdjfh"`
+	ResourceURI string            `json:"resourceUri,omitempty"`
+	Expiration  string            `json:"expiration,omitempty" example:"1704067200000"` // Unix time in milliseconds
+}
+
 // CalendarEventsRequest represents the request for getting calendar events
 // @Description Calendar events request with time range
 type CalendarEventsRequest struct {
@@ -187,6 +339,26 @@ type CalendarEventsResponse struct {
 	Calendars []*CalendarWithEvents `json:"calendars"`
 }
 
+// EventSearchResponse represents the response for searching events
+// @Description Event search response
+type EventSearchResponse struct {
+	Success bool             `json:"success" example:"true"`
+	Message string           `json:"message" example:"Events searched successfully"`
+	Events  []*CalendarEvent `json:"events"`
+	// Offset of the next page of results, missing on the last page
+	NextOffset *int `json:"next_offset,omitempty" example:"50"`
+}
+
+// CalendarEventPageResponse represents a page of the flat listing of calendar events
+// @Description Calendar event page response
+type CalendarEventPageResponse struct {
+	Success bool             `json:"success" example:"true"`
+	Message string           `json:"message" example:"Calendar events retrieved successfully"`
+	Events  []*CalendarEvent `json:"events"`
+	// Cursor of the next page of events, missing on the last page
+	NextCursor string `json:"next_cursor,omitempty" example:"MjAyNC0wNy0wMVQwOTowMDowMFp8MTIzNDU"`
+}
+
 // ImportedCalendarsResponse represents the response for imported calendars endpoint
 // @Description Imported calendars response
 type ImportedCalendarsResponse struct {
@@ -198,12 +370,20 @@ type ImportedCalendarsResponse struct {
 // CalendarUpdateRequest represents the request body for updating a calendar
 // @Description Calendar update request
 type CalendarUpdateRequest struct {
-	Summary        *string             `json:"summary,omitempty" example:"My Updated Calendar"`
-	Description    *string             `json:"description,omitempty" example:"Updated calendar description"`
-	EventRedaction *string             `json:"event_redaction,omitempty" example:"Work"`
-	EventColor     *string             `json:"event_color,omitempty" example:"#ff5722"`
-	Visibility     *CalendarVisibility `json:"visibility,omitempty" example:"private"`
-	TimeZone       *string             `json:"time_zone,omitempty" example:"America/New_York"`
+	Summary        *string                `json:"summary,omitempty" example:"My Updated Calendar"`
+	Description    *string                `json:"description,omitempty" example:"Updated calendar description"`
+	EventRedaction *string                `json:"event_redaction,omitempty" example:"Work"`
+	RedactionMode  *CalendarRedactionMode `json:"redaction_mode,omitempty" example:"full"`
+	EventColor     *string                `json:"event_color,omitempty" example:"#ff5722"`
+	Visibility     *CalendarVisibility    `json:"visibility,omitempty" example:"private"`
+	FreeBusy       *bool                  `json:"free_busy,omitempty" example:"true"`
+	TimeZone       *string                `json:"time_zone,omitempty" example:"America/New_York"`
+	// Minutes between background syncs of a Google calendar, 0 for the server default
+	SyncIntervalMinutes *int `json:"sync_interval_minutes,omitempty" example:"30"`
+	// Write changes made in Timely back to a Google calendar, needs permission to edit Google Calendar events
+	WriteBack *bool `json:"write_back,omitempty" example:"true"`
+	// Hide the events the owner declined
+	HideDeclined *bool `json:"hide_declined,omitempty" example:"true"`
 }
 
 // CalendarUpdateResponse represents the response for updating a calendar
@@ -220,3 +400,78 @@ type CalendarDeleteResponse struct {
 	Success bool   `json:"success" example:"true"`
 	Message string `json:"message" example:"Calendar deleted successfully"`
 }
+
+// CalendarCreateRequest represents the request body for creating a Timely calendar
+// @Description Calendar create request
+type CalendarCreateRequest struct {
+	Summary     string              `json:"summary" validate:"required" example:"Team"`
+	Description *string             `json:"description,omitempty" example:"Team events"`
+	EventColor  *string             `json:"event_color,omitempty" example:"#ff5722"`
+	Visibility  *CalendarVisibility `json:"visibility,omitempty" example:"private"`
+	TimeZone    string              `json:"time_zone,omitempty" example:"America/New_York"`
+}
+
+// CalendarCreateResponse represents the response for creating a Timely calendar
+// @Description Calendar create response
+type CalendarCreateResponse struct {
+	Success  bool      `json:"success" example:"true"`
+	Message  string    `json:"message" example:"Calendar created successfully"`
+	Calendar *Calendar `json:"calendar"`
+}
+
+// CalendarEventRequest represents the request body for creating or replacing an event in a Timely calendar.
+// Start and end are RFC 3339 date-times, or dates (YYYY-MM-DD) for all-day events whose end date is exclusive.
+// @Description Calendar event create/update request
+type CalendarEventRequest struct {
+	Title       string                   `json:"title" validate:"required" example:"Planning"`
+	Start       string                   `json:"start" validate:"required" example:"2024-01-01T10:00:00Z"`
+	End         string                   `json:"end" validate:"required" example:"2024-01-01T11:00:00Z"`
+	AllDay      bool                     `json:"all_day" example:"false"`
+	Location    string                   `json:"location,omitempty" example:"Conference Room A"`
+	Description string                   `json:"description,omitempty" example:"Quarterly planning"`
+	EventColor  string                   `json:"event_color,omitempty" example:"#ff5722"`
+	Visibility  *CalendarEventVisibility `json:"visibility,omitempty" example:"inherited"`
+	// Status and transparency, confirmed and opaque if not set
+	Status       *CalendarEventStatus       `json:"status,omitempty" example:"tentative"`
+	Transparency *CalendarEventTransparency `json:"transparency,omitempty" example:"transparent"`
+}
+
+// CalendarEventResponse represents the response for creating or updating an event
+// @Description Calendar event response
+type CalendarEventResponse struct {
+	Success bool           `json:"success" example:"true"`
+	Message string         `json:"message" example:"Event created successfully"`
+	Event   *CalendarEvent `json:"event"`
+}
+
+// CalendarEventDeleteResponse represents the response for deleting an event
+// @Description Calendar event delete response
+type CalendarEventDeleteResponse struct {
+	Success bool   `json:"success" example:"true"`
+	Message string `json:"message" example:"Event deleted successfully"`
+}
+
+// CalendarEventPatchRequest represents the request body for changing how an event is shared.
+// An empty title override removes it.
+// @Description Calendar event patch request
+type CalendarEventPatchRequest struct {
+	Visibility    *CalendarEventVisibility `json:"visibility,omitempty" example:"private"`
+	TitleOverride *string                  `json:"title_override,omitempty" example:"Busy"`
+}
+
+// FreeBusyInterval represents a time range in which a user is busy
+// @Description Busy interval
+type FreeBusyInterval struct {
+	Start time.Time `json:"start" example:"2024-01-01T10:00:00Z"`
+	End   time.Time `json:"end" example:"2024-01-01T11:00:00Z"`
+}
+
+// FreeBusyResponse represents the response for the free/busy endpoint
+// @Description Free/busy response
+type FreeBusyResponse struct {
+	Success bool                `json:"success" example:"true"`
+	Message string              `json:"message" example:"Free/busy retrieved successfully"`
+	Start   time.Time           `json:"start" example:"2024-01-01T00:00:00Z"`
+	End     time.Time           `json:"end" example:"2024-01-08T00:00:00Z"`
+	Busy    []*FreeBusyInterval `json:"busy"`
+}
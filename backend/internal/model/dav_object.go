@@ -0,0 +1,18 @@
+package model
+
+import "time"
+
+// DAVObject is a calendar object written to a Timely calendar by a CalDAV client, kept as it was
+// written. Its events are expanded into occurrences like imported events, the object is served
+// back to clients so they get the recurrence rules and properties Timely doesn't store.
+type DAVObject struct {
+	ID         uint64 `json:"id,string" gorm:"primaryKey"`
+	CalendarID uint64 `json:"calendar_id,string" gorm:"not null;uniqueIndex:idx_dav_objects_calendar_name"`
+	Name       string `json:"name" gorm:"not null;size:255;uniqueIndex:idx_dav_objects_calendar_name"` // Resource name
+	Data       string `json:"-" gorm:"type:text;not null"`                                             // iCalendar data as written
+	// ETag of the resource when the object was written. Once the events change in Timely the ETag
+	// no longer matches and the object is stale.
+	ETag      string    `json:"-" gorm:"column:etag;not null"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
@@ -0,0 +1,64 @@
+package model
+
+import "time"
+
+// EventWriteOperation is the change an EventWrite pushes to the remote calendar
+type EventWriteOperation string
+
+const (
+	EventWriteCreate EventWriteOperation = "create"
+	EventWriteUpdate EventWriteOperation = "update"
+	EventWriteDelete EventWriteOperation = "delete"
+)
+
+// EventWrite is a change made in Timely to an event of a write-back calendar, queued until it has
+// been pushed to the remote calendar. There is at most one write per remote event, later changes
+// are folded into it.
+type EventWrite struct {
+	ID         uint64              `json:"id,string" gorm:"primaryKey"`
+	CalendarID uint64              `json:"calendar_id,string" gorm:"not null;uniqueIndex:idx_event_writes_calendar_source"`
+	SourceID   string              `json:"source_id" gorm:"not null;uniqueIndex:idx_event_writes_calendar_source"` // Remote event ID
+	EventID    uint64              `json:"event_id,string"`                                                        // Local event, already gone for deletes
+	Operation  EventWriteOperation `json:"operation" gorm:"not null"`
+	// ETag of the remote event a delete is conditional on, updates use the ETag stored with the event
+	ETag          string    `json:"-" gorm:"column:etag"`
+	Attempts      int       `json:"attempts"`
+	NextAttemptAt time.Time `json:"next_attempt_at" gorm:"index"`
+	LastError     *string   `json:"last_error,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+	// Fields of the event as last synced from Google before the update, changes made on Google
+	// meanwhile are merged against them. Nil for creates and deletes.
+	Base *EventWriteFields `json:"-" gorm:"serializer:json"`
+	// Set when the event changed on Google in a way that cannot be merged with the change made in
+	// Timely. Conflicting writes are not pushed until the conflict is resolved.
+	Conflict bool `json:"conflict" gorm:"not null;default:false"`
+	// Bumped whenever a change is folded into the write, a push only finishes the version it read
+	Version int `json:"-" gorm:"not null;default:0"`
+}
+
+// EventWriteFields are the fields of an event Timely writes to Google
+type EventWriteFields struct {
+	Title        string                    `json:"title"`
+	Description  string                    `json:"description"`
+	Location     string                    `json:"location"`
+	Start        time.Time                 `json:"start"`
+	End          time.Time                 `json:"end"`
+	AllDay       bool                      `json:"all_day"`
+	Status       CalendarEventStatus       `json:"status"`
+	Transparency CalendarEventTransparency `json:"transparency"`
+}
+
+// EventConflictResolution is the side whose version of an event is kept when a write conflicts
+type EventConflictResolution string
+
+const (
+	EventConflictKeepTimely EventConflictResolution = "timely"
+	EventConflictKeepGoogle EventConflictResolution = "google"
+)
+
+// EventConflictResolveRequest represents the request body for resolving a write conflict
+// @Description Event write conflict resolution request
+type EventConflictResolveRequest struct {
+	Keep EventConflictResolution `json:"keep" example:"google"`
+}
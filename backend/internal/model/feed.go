@@ -0,0 +1,54 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// FeedToken represents a revocable secret token granting read access to a user's private ICS feed.
+// Only the SHA-256 hash of the token is stored.
+// @Description Private calendar feed token
+type FeedToken struct {
+	ID         uint64         `json:"id,string" gorm:"primaryKey" example:"123456789"` // Unique token identifier
+	UserID     uint64         `json:"user_id,string" gorm:"index" example:"123456789"` // Owner of the feed
+	Name       string         `json:"name" example:"Phone"`                            // Label to tell tokens apart
+	TokenHash  string         `json:"-" gorm:"uniqueIndex;size:64"`                    // SHA-256 hash of the token
+	TokenHint  string         `json:"token_hint" example:"x1Yz"`                       // Last characters of the token
+	LastUsedAt *time.Time     `json:"last_used_at,omitempty"`                          // Last time the feed was fetched
+	CreatedAt  time.Time      `json:"created_at" example:"2024-01-01T00:00:00Z"`       // Creation timestamp
+	UpdatedAt  time.Time      `json:"updated_at" example:"2024-01-01T00:00:00Z"`       // Last update (rotation) timestamp
+	DeletedAt  gorm.DeletedAt `json:"-" gorm:"index"`                                  // Soft delete timestamp (revoked tokens)
+}
+
+// FeedTokenCreateRequest represents the request body for creating a feed token
+// @Description Feed token create request
+type FeedTokenCreateRequest struct {
+	Name string `json:"name,omitempty" example:"Phone"` // Optional label for the token
+}
+
+// FeedTokenResponse represents the response for creating or rotating a feed token.
+// The token itself is only returned once.
+// @Description Feed token response
+type FeedTokenResponse struct {
+	Success   bool       `json:"success" example:"true"`
+	Message   string     `json:"message" example:"Feed token created successfully"`
+	FeedToken *FeedToken `json:"feed_token"`
+	Token     string     `json:"token" example:"q5ZQ2vWl3b1n0tFqG0xwY2m6j8Q4aJ9kS7cX1Yz"`
+	FeedPath  string     `json:"feed_path" example:"/api/feeds/q5ZQ2vWl3b1n0tFqG0xwY2m6j8Q4aJ9kS7cX1Yz.ics"`
+}
+
+// FeedTokensResponse represents the response for listing feed tokens
+// @Description Feed tokens response
+type FeedTokensResponse struct {
+	Success    bool         `json:"success" example:"true"`
+	Message    string       `json:"message" example:"Feed tokens retrieved successfully"`
+	FeedTokens []*FeedToken `json:"feed_tokens"`
+}
+
+// FeedTokenDeleteResponse represents the response for revoking a feed token
+// @Description Feed token delete response
+type FeedTokenDeleteResponse struct {
+	Success bool   `json:"success" example:"true"`
+	Message string `json:"message" example:"Feed token revoked successfully"`
+}
@@ -0,0 +1,70 @@
+package model
+
+import "time"
+
+// SyncRunMode is how a sync run fetched the events of a calendar
+type SyncRunMode string
+
+const (
+	SyncRunModeFull        SyncRunMode = "full"        // Every event in the sync window was fetched
+	SyncRunModeIncremental SyncRunMode = "incremental" // Only the changes since the last sync were fetched
+)
+
+// SyncErrorClass groups the errors a sync run can fail with by what it takes to fix them
+type SyncErrorClass string
+
+const (
+	SyncErrorClassAuth        SyncErrorClass = "auth"         // Credentials were rejected or revoked, the user has to reconnect the account
+	SyncErrorClassNotFound    SyncErrorClass = "not_found"    // The remote calendar is gone or no longer shared with the user
+	SyncErrorClassRateLimited SyncErrorClass = "rate_limited" // The remote service asked to slow down
+	SyncErrorClassRemote      SyncErrorClass = "remote"       // The remote service failed or could not be reached
+	SyncErrorClassOther       SyncErrorClass = "other"
+)
+
+// SyncRun records a sync of a calendar with its remote source
+// @Description Calendar sync run
+type SyncRun struct {
+	ID           uint64          `json:"id,string" gorm:"primaryKey" example:"123456789"`
+	CalendarID   uint64          `json:"calendar_id,string" gorm:"index:idx_sync_runs_calendar_started" example:"123456789"`
+	StartedAt    time.Time       `json:"started_at" gorm:"index:idx_sync_runs_calendar_started" example:"2024-01-01T00:00:00Z"`
+	FinishedAt   time.Time       `json:"finished_at" example:"2024-01-01T00:00:02Z"`
+	Mode         SyncRunMode     `json:"mode" example:"incremental"`
+	NotModified  bool            `json:"not_modified" example:"false"` // The remote calendar was unchanged since the last fetch
+	Added        int             `json:"added" example:"2"`
+	Updated      int             `json:"updated" example:"1"`
+	Deleted      int             `json:"deleted" example:"0"`
+	ErrorClass   *SyncErrorClass `json:"error_class,omitempty" example:"auth"` // Set if the run failed
+	ErrorMessage *string         `json:"error_message,omitempty"`
+	CreatedAt    time.Time       `json:"created_at" example:"2024-01-01T00:00:02Z"`
+}
+
+// CalendarHealthStatus summarizes whether the events of a calendar are kept up to date
+type CalendarHealthStatus string
+
+const (
+	CalendarHealthHealthy               CalendarHealthStatus = "healthy"                // The last sync succeeded
+	CalendarHealthDegraded              CalendarHealthStatus = "degraded"               // The last sync failed, events may be stale
+	CalendarHealthNeedsReauthentication CalendarHealthStatus = "needs_reauthentication" // The last sync failed because the credentials were rejected
+)
+
+// CalendarHealth is the sync health of a calendar, derived from its sync runs
+// @Description Calendar sync health
+type CalendarHealth struct {
+	Status              CalendarHealthStatus `json:"status" example:"healthy"`
+	LastSyncAt          *time.Time           `json:"last_sync_at,omitempty" example:"2024-01-01T00:00:00Z"`
+	LastSuccessAt       *time.Time           `json:"last_success_at,omitempty" example:"2024-01-01T00:00:00Z"`
+	ConsecutiveFailures int                  `json:"consecutive_failures" example:"0"`
+	ErrorClass          *SyncErrorClass      `json:"error_class,omitempty"` // Of the last sync, if it failed
+	ErrorMessage        *string              `json:"error_message,omitempty"`
+	// Changes made in Timely that conflict with changes made on Google, they wait to be resolved
+	WriteConflicts int `json:"write_conflicts,omitempty" example:"0"`
+}
+
+// SyncRunListResponse represents the response for listing the sync runs of a calendar
+// @Description Sync run list response
+type SyncRunListResponse struct {
+	Success bool            `json:"success" example:"true"`
+	Message string          `json:"message" example:"Sync history retrieved successfully"`
+	Health  *CalendarHealth `json:"health"`
+	Runs    []*SyncRun      `json:"runs"`
+}
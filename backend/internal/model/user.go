@@ -31,6 +31,7 @@ type Account struct {
 	AccessToken  *string        `json:"-" gorm:"type:text"`          // NEVER expose
 	RefreshToken *string        `json:"-" gorm:"type:text"`          // NEVER expose
 	Expiry       *time.Time     `json:"expiry,omitempty"`            // Access token expiry
+	Scopes       *string        `json:"-" gorm:"type:text"`          // Space-separated scopes granted, nil if unknown
 	CreatedAt    time.Time      `json:"created_at"`
 	UpdatedAt    time.Time      `json:"updated_at"`
 	DeletedAt    gorm.DeletedAt `json:"-" gorm:"index"`
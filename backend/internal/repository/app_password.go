@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/NathanWasTaken/timely/backend/internal/model"
+)
+
+type AppPasswordRepository struct {
+	db *gorm.DB
+}
+
+func NewAppPasswordRepository(db *gorm.DB) *AppPasswordRepository {
+	return &AppPasswordRepository{
+		db: db,
+	}
+}
+
+// Create creates a new app password
+func (r *AppPasswordRepository) Create(appPassword *model.AppPassword) error {
+	return r.db.Create(appPassword).Error
+}
+
+// FindByID finds an app password by ID
+func (r *AppPasswordRepository) FindByID(id string) (*model.AppPassword, error) {
+	var appPassword model.AppPassword
+	err := r.db.Where("id = ?", id).First(&appPassword).Error
+	if err != nil {
+		return nil, err
+	}
+	return &appPassword, nil
+}
+
+// FindByUserID finds all app passwords for a user
+func (r *AppPasswordRepository) FindByUserID(userID uint64) ([]*model.AppPassword, error) {
+	var appPasswords []*model.AppPassword
+	err := r.db.Where("user_id = ?", userID).Order("created_at ASC").Find(&appPasswords).Error
+	if err != nil {
+		return nil, err
+	}
+	return appPasswords, nil
+}
+
+// FindByUserIDAndPasswordHash finds an app password of a user by the hash of its secret
+func (r *AppPasswordRepository) FindByUserIDAndPasswordHash(userID uint64, passwordHash string) (*model.AppPassword, error) {
+	var appPassword model.AppPassword
+	err := r.db.Where("user_id = ? AND password_hash = ?", userID, passwordHash).First(&appPassword).Error
+	if err != nil {
+		return nil, err
+	}
+	return &appPassword, nil
+}
+
+// UpdateLastUsedAt updates the last used timestamp for an app password without touching updated_at
+func (r *AppPasswordRepository) UpdateLastUsedAt(id uint64, lastUsedAt time.Time) error {
+	return r.db.Model(&model.AppPassword{}).
+		Where("id = ?", id).
+		UpdateColumn("last_used_at", lastUsedAt).Error
+}
+
+// Delete deletes an app password
+func (r *AppPasswordRepository) Delete(id string) error {
+	return r.db.Delete(&model.AppPassword{}, "id = ?", id).Error
+}
@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/NathanWasTaken/timely/backend/internal/model"
+)
+
+type BookingRepository struct {
+	db *gorm.DB
+}
+
+func NewBookingRepository(db *gorm.DB) *BookingRepository {
+	return &BookingRepository{
+		db: db,
+	}
+}
+
+// CreateBookingType creates a new booking type
+func (r *BookingRepository) CreateBookingType(bookingType *model.BookingType) error {
+	return r.db.Create(bookingType).Error
+}
+
+// FindBookingTypeByID finds a booking type by ID
+func (r *BookingRepository) FindBookingTypeByID(id string) (*model.BookingType, error) {
+	var bookingType model.BookingType
+	err := r.db.Where("id = ?", id).First(&bookingType).Error
+	if err != nil {
+		return nil, err
+	}
+	return &bookingType, nil
+}
+
+// FindBookingTypeByUserIDAndSlug finds a booking type by its owner and slug
+func (r *BookingRepository) FindBookingTypeByUserIDAndSlug(userID uint64, slug string) (*model.BookingType, error) {
+	var bookingType model.BookingType
+	err := r.db.Where("user_id = ? AND slug = ?", userID, slug).First(&bookingType).Error
+	if err != nil {
+		return nil, err
+	}
+	return &bookingType, nil
+}
+
+// FindBookingTypesByUserID finds all booking types for a user
+func (r *BookingRepository) FindBookingTypesByUserID(userID uint64) ([]*model.BookingType, error) {
+	var bookingTypes []*model.BookingType
+	err := r.db.Where("user_id = ?", userID).Order("created_at ASC").Find(&bookingTypes).Error
+	if err != nil {
+		return nil, err
+	}
+	return bookingTypes, nil
+}
+
+// UpdateBookingType updates a booking type
+func (r *BookingRepository) UpdateBookingType(bookingType *model.BookingType) error {
+	return r.db.Save(bookingType).Error
+}
+
+// DeleteBookingType deletes a booking type by ID. Bookings already made are kept.
+func (r *BookingRepository) DeleteBookingType(id string) error {
+	return r.db.Where("id = ?", id).Delete(&model.BookingType{}).Error
+}
+
+// CreateBooking creates a booking together with its calendar event in a single transaction. The
+// row of the booking page's owner is locked first and check is called within the transaction with a
+// calendar repository bound to it, so it can verify that the slot is still free while bookings of the
+// same user from other server processes wait. SQLite does not lock rows, its writes are serialized by
+// the database instead.
+func (r *BookingRepository) CreateBooking(booking *model.Booking, event *model.CalendarEvent, check func(calendarRepo *CalendarRepository) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var owner model.User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&owner, "id = ?", booking.UserID).Error; err != nil {
+			return err
+		}
+		if err := check(NewCalendarRepository(tx)); err != nil {
+			return err
+		}
+
+		if err := tx.Create(event).Error; err != nil {
+			return err
+		}
+		return tx.Create(booking).Error
+	})
+}
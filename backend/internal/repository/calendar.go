@@ -1,9 +1,11 @@
 package repository
 
 import (
+	"strings"
 	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 
 	"github.com/NathanWasTaken/timely/backend/internal/model"
 )
@@ -55,7 +57,8 @@ func (r *CalendarRepository) FindByUserIDAndSourceID(userID uint64, sourceID str
 
 // Update updates an existing calendar
 func (r *CalendarRepository) Update(calendar *model.Calendar) error {
-	return r.db.Save(calendar).Error
+	// The sync lease and the push notification channel are only changed through their own methods
+	return r.db.Omit("sync_locked_until", "sync_lease_token", "watch_channel_id", "watch_resource_id", "watch_token_hash", "watch_expires_at").Save(calendar).Error
 }
 
 // Delete deletes a calendar
@@ -79,12 +82,14 @@ func (r *CalendarRepository) CreateEvent(event *model.CalendarEvent) error {
 	return r.db.Create(event).Error
 }
 
-// CreateEvents creates multiple calendar events in a batch
+// CreateEvents creates multiple calendar events in a batch, along with their attendees
 func (r *CalendarRepository) CreateEvents(events []*model.CalendarEvent) error {
 	if len(events) == 0 {
 		return nil
 	}
-	return r.db.CreateInBatches(events, 100).Error
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return createEvents(tx, events)
+	})
 }
 
 // FindEventsByCalendarID finds all events for a specific calendar
@@ -109,6 +114,56 @@ func (r *CalendarRepository) FindEventsByUserID(userID uint64) ([]*model.Calenda
 	return events, nil
 }
 
+// FindEventByID finds a calendar event by ID, with its attendees
+func (r *CalendarRepository) FindEventByID(id string) (*model.CalendarEvent, error) {
+	var event model.CalendarEvent
+	err := r.db.Preload("Attendees").Where("id = ?", id).First(&event).Error
+	if err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+// UpdateEvent updates an existing calendar event, leaving its attendees as they are
+func (r *CalendarRepository) UpdateEvent(event *model.CalendarEvent) error {
+	return r.db.Omit(clause.Associations).Save(event).Error
+}
+
+// LoadEventAttendees fills in the attendees of events
+func (r *CalendarRepository) LoadEventAttendees(events []*model.CalendarEvent) error {
+	byID := make(map[uint64]*model.CalendarEvent, len(events))
+	for _, event := range events {
+		event.Attendees = nil
+		byID[event.ID] = event
+	}
+
+	ids := make([]uint64, 0, len(events))
+	for id := range byID {
+		ids = append(ids, id)
+	}
+
+	// Keep the number of query parameters well below the limits of SQLite and Postgres
+	for start := 0; start < len(ids); start += 500 {
+		var attendees []*model.EventAttendee
+		err := r.db.Where("event_id IN ?", ids[start:min(start+500, len(ids))]).
+			Order("id ASC").
+			Find(&attendees).Error
+		if err != nil {
+			return err
+		}
+		for _, attendee := range attendees {
+			event := byID[attendee.EventID]
+			event.Attendees = append(event.Attendees, attendee)
+		}
+	}
+	return nil
+}
+
+// DeleteEvent deletes a calendar event
+func (r *CalendarRepository) DeleteEvent(id string) error {
+	return r.db.Delete(&model.CalendarEvent{}, "id = ?", id).Error
+}
+
 // DeleteEventsByCalendarID deletes all events for a specific calendar
 func (r *CalendarRepository) DeleteEventsByCalendarID(calendarID uint64) error {
 	return r.db.Where("calendar_id = ?", calendarID).Delete(&model.CalendarEvent{}).Error
@@ -133,6 +188,136 @@ func (r *CalendarRepository) FindEventsByCalendarIDsAndTimeRange(calendarIDs []u
 	return events, nil
 }
 
+// FindEventsByCalendarIDsOverlappingTimeRange finds events for multiple calendars that overlap a
+// time range, including events that only partially fall inside it
+func (r *CalendarRepository) FindEventsByCalendarIDsOverlappingTimeRange(calendarIDs []uint64, startTime, endTime time.Time) ([]*model.CalendarEvent, error) {
+	var events []*model.CalendarEvent
+	err := r.db.Where("calendar_id IN ? AND start < ? AND end > ?", calendarIDs, endTime, startTime).
+		Order("start ASC").
+		Find(&events).Error
+	if err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// FindEventsPage finds a page of the events of calendars within a time range, ordered by start and
+// ID. The page starts after the event with afterStart and afterID, or at the first event if afterID
+// is 0.
+func (r *CalendarRepository) FindEventsPage(calendarIDs []uint64, startTime, endTime, afterStart time.Time, afterID uint64, limit int) ([]*model.CalendarEvent, error) {
+	query := r.db.Where("calendar_id IN ? AND start >= ? AND end <= ?", calendarIDs, startTime, endTime)
+	if afterID != 0 {
+		query = query.Where("(start > ? OR (start = ? AND id > ?))", afterStart, afterStart, afterID)
+	}
+
+	var events []*model.CalendarEvent
+	err := query.Order("start ASC, id ASC").Limit(limit).Find(&events).Error
+	if err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// StreamEvents reads the events of calendars within a time range from a database cursor, ordered
+// by start and ID, and passes them to fn in batches of up to batchSize events. The cursor holds a
+// connection until it is done, so fn must not query the database. Stops at the first error of fn.
+func (r *CalendarRepository) StreamEvents(calendarIDs []uint64, startTime, endTime time.Time, batchSize int, fn func([]*model.CalendarEvent) error) error {
+	rows, err := r.db.Model(&model.CalendarEvent{}).
+		Where("calendar_id IN ? AND start >= ? AND end <= ?", calendarIDs, startTime, endTime).
+		Order("start ASC, id ASC").
+		Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	batch := make([]*model.CalendarEvent, 0, batchSize)
+	for rows.Next() {
+		var event model.CalendarEvent
+		if err := r.db.ScanRows(rows, &event); err != nil {
+			return err
+		}
+		batch = append(batch, &event)
+
+		if len(batch) == batchSize {
+			if err := fn(batch); err != nil {
+				return err
+			}
+			batch = make([]*model.CalendarEvent, 0, batchSize)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	if len(batch) > 0 {
+		return fn(batch)
+	}
+	return nil
+}
+
+// eventSearchVector is the document of an event searched on Postgres, it must match the expression
+// of the index created by the EventSearch migration
+const eventSearchVector = "to_tsvector('simple', coalesce(title, '') || ' ' || coalesce(location, '') || ' ' || coalesce(description, ''))"
+
+// SearchEvents finds the events of calendars whose title, location or description contain every
+// search term as a word or the start of one, using the full-text index of the database. Terms must
+// only consist of letters and digits. Events end after startTime and start before endTime unless
+// those are zero, and are ordered by start.
+func (r *CalendarRepository) SearchEvents(calendarIDs []uint64, terms []string, startTime, endTime time.Time, limit, offset int) ([]*model.CalendarEvent, error) {
+	query := r.db.Where("calendar_id IN ?", calendarIDs)
+	if !startTime.IsZero() {
+		query = query.Where("end > ?", startTime)
+	}
+	if !endTime.IsZero() {
+		query = query.Where("start < ?", endTime)
+	}
+
+	switch r.db.Dialector.Name() {
+	case "postgres":
+		prefixes := make([]string, len(terms))
+		for i, term := range terms {
+			prefixes[i] = term + ":*"
+		}
+		query = query.Where(eventSearchVector+" @@ to_tsquery('simple', ?)", strings.Join(prefixes, " & "))
+	case "mysql":
+		prefixes := make([]string, len(terms))
+		for i, term := range terms {
+			prefixes[i] = "+" + term + "*"
+		}
+		query = query.Where("MATCH (title, location, description) AGAINST (? IN BOOLEAN MODE)", strings.Join(prefixes, " "))
+	default:
+		if r.hasEventSearchIndex() {
+			prefixes := make([]string, len(terms))
+			for i, term := range terms {
+				prefixes[i] = `"` + term + `"*`
+			}
+			query = query.Where("id IN (SELECT rowid FROM calendar_events_fts WHERE calendar_events_fts MATCH ?)", strings.Join(prefixes, " "))
+			break
+		}
+		// SQLite without FTS5 has no index, the terms are matched anywhere in the text
+		for _, term := range terms {
+			pattern := "%" + term + "%"
+			query = query.Where("(title LIKE ? OR location LIKE ? OR description LIKE ?)", pattern, pattern, pattern)
+		}
+	}
+
+	var events []*model.CalendarEvent
+	err := query.Order("start ASC, id ASC").Limit(limit).Offset(offset).Find(&events).Error
+	if err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// hasEventSearchIndex checks whether SQLite keeps the FTS5 table of events up to date, which only
+// builds with FTS5 do
+func (r *CalendarRepository) hasEventSearchIndex() bool {
+	var triggers int64
+	r.db.Raw("SELECT COUNT(*) FROM sqlite_master WHERE type = 'trigger' AND name = ?", "calendar_events_fts_insert").Scan(&triggers)
+	return triggers > 0
+}
+
 // FindBySourceID finds a calendar by its source ID
 func (r *CalendarRepository) FindBySourceID(sourceID string) (*model.Calendar, error) {
 	var calendar model.Calendar
@@ -156,7 +341,7 @@ func (r *CalendarRepository) UpdateEvents(events []*model.CalendarEvent) error {
 	}
 
 	for _, event := range events {
-		if err := tx.Save(event).Error; err != nil {
+		if err := updateEvent(tx, event); err != nil {
 			tx.Rollback()
 			return err
 		}
@@ -165,6 +350,81 @@ func (r *CalendarRepository) UpdateEvents(events []*model.CalendarEvent) error {
 	return tx.Commit().Error
 }
 
+// ReplaceEvents deletes, updates and creates calendar events in a single transaction
+func (r *CalendarRepository) ReplaceEvents(deleteIDs []uint64, updated, created []*model.CalendarEvent) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return replaceEvents(tx, deleteIDs, updated, created)
+	})
+}
+
+// ReplaceDAVResource replaces the events of a resource written by a CalDAV client like
+// ReplaceEvents. store is called within the same transaction with a calendar repository bound to
+// it once the events are saved, so the calendar object of the resource is stored or deleted
+// together with its events.
+func (r *CalendarRepository) ReplaceDAVResource(deleteIDs []uint64, updated, created []*model.CalendarEvent, store func(calendarRepo *CalendarRepository) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := replaceEvents(tx, deleteIDs, updated, created); err != nil {
+			return err
+		}
+		return store(NewCalendarRepository(tx))
+	})
+}
+
+// replaceEvents deletes, updates and creates calendar events in tx
+func replaceEvents(tx *gorm.DB, deleteIDs []uint64, updated, created []*model.CalendarEvent) error {
+	if len(deleteIDs) > 0 {
+		if err := tx.Where("id IN ?", deleteIDs).Delete(&model.CalendarEvent{}).Error; err != nil {
+			return err
+		}
+	}
+	for _, event := range updated {
+		if err := updateEvent(tx, event); err != nil {
+			return err
+		}
+	}
+	if len(created) > 0 {
+		return createEvents(tx, created)
+	}
+	return nil
+}
+
+// createEvents creates events and their attendees in tx
+func createEvents(tx *gorm.DB, events []*model.CalendarEvent) error {
+	if err := tx.Omit(clause.Associations).CreateInBatches(events, 100).Error; err != nil {
+		return err
+	}
+
+	var attendees []*model.EventAttendee
+	for _, event := range events {
+		for _, attendee := range event.Attendees {
+			attendee.EventID = event.ID
+			attendees = append(attendees, attendee)
+		}
+	}
+	if len(attendees) == 0 {
+		return nil
+	}
+	return tx.CreateInBatches(attendees, 100).Error
+}
+
+// updateEvent saves an event fetched from its source in tx, replacing its attendees
+func updateEvent(tx *gorm.DB, event *model.CalendarEvent) error {
+	if err := tx.Omit(clause.Associations).Save(event).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("event_id = ?", event.ID).Delete(&model.EventAttendee{}).Error; err != nil {
+		return err
+	}
+
+	for _, attendee := range event.Attendees {
+		attendee.EventID = event.ID
+	}
+	if len(event.Attendees) == 0 {
+		return nil
+	}
+	return tx.Create(event.Attendees).Error
+}
+
 // UpdateSyncedAt updates the synced_at timestamp for a calendar
 func (r *CalendarRepository) UpdateSyncedAt(calendarID uint64, syncedAt time.Time) error {
 	return r.db.Model(&model.Calendar{}).
@@ -227,3 +487,522 @@ func (r *CalendarRepository) FindEventsBySourceIDs(sourceIDs []string) ([]*model
 	}
 	return events, nil
 }
+
+// FindEventByCalendarIDAndSourceID finds an event by its source ID within a specific calendar
+func (r *CalendarRepository) FindEventByCalendarIDAndSourceID(calendarID uint64, sourceID string) (*model.CalendarEvent, error) {
+	var event model.CalendarEvent
+	err := r.db.Where("calendar_id = ? AND source_id = ?", calendarID, sourceID).First(&event).Error
+	if err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+// DeleteEventsByCalendarIDAndSourceID deletes an event by its source ID within a specific calendar
+func (r *CalendarRepository) DeleteEventsByCalendarIDAndSourceID(calendarID uint64, sourceID string) error {
+	return r.db.Where("calendar_id = ? AND source_id = ?", calendarID, sourceID).Delete(&model.CalendarEvent{}).Error
+}
+
+// FindICSSubscriptionsFetchedBefore finds subscribed ICS calendars that have not been fetched since the given time
+func (r *CalendarRepository) FindICSSubscriptionsFetchedBefore(before time.Time) ([]*model.Calendar, error) {
+	var calendars []*model.Calendar
+	err := r.db.Where("source = ? AND subscription_url IS NOT NULL", model.SourceICS).
+		Where("last_fetched_at IS NULL OR last_fetched_at < ?", before).
+		Find(&calendars).Error
+	if err != nil {
+		return nil, err
+	}
+	return calendars, nil
+}
+
+// FindCalendarsDueForSync finds calendars of the given sources whose next background sync is due and
+// that are not being synced, the longest overdue first
+func (r *CalendarRepository) FindCalendarsDueForSync(sources []model.CalendarSource, now time.Time, limit int) ([]*model.Calendar, error) {
+	var calendars []*model.Calendar
+	err := r.db.Where("source IN ? AND source_id IS NOT NULL", sources).
+		Where("next_sync_at IS NULL OR next_sync_at <= ?", now).
+		Where("sync_locked_until IS NULL OR sync_locked_until < ?", now).
+		Order("next_sync_at ASC").
+		Limit(limit).
+		Find(&calendars).Error
+	if err != nil {
+		return nil, err
+	}
+	return calendars, nil
+}
+
+// ClaimSyncLease atomically claims the sync lease of a calendar until the given time for the sync
+// identified by token. It returns false if the lease is still held by another sync.
+func (r *CalendarRepository) ClaimSyncLease(calendarID uint64, token string, now, until time.Time) (bool, error) {
+	return r.claimSyncLease(r.db.Where("id = ?", calendarID), now, syncLeaseUpdates(token, until))
+}
+
+// ClaimDueSyncLease claims the sync lease of a calendar like ClaimSyncLease, but only while its
+// next background sync is due, so a calendar synced in the meantime is not synced again. The next
+// sync is cleared until the lease is released, so a sync scheduled while this one runs is kept.
+func (r *CalendarRepository) ClaimDueSyncLease(calendarID uint64, token string, now, until time.Time) (bool, error) {
+	updates := syncLeaseUpdates(token, until)
+	updates["next_sync_at"] = nil
+	return r.claimSyncLease(r.db.Where("id = ?", calendarID).Where("next_sync_at IS NULL OR next_sync_at <= ?", now), now, updates)
+}
+
+func (r *CalendarRepository) claimSyncLease(query *gorm.DB, now time.Time, updates map[string]interface{}) (bool, error) {
+	result := query.Model(&model.Calendar{}).
+		Where("sync_locked_until IS NULL OR sync_locked_until < ?", now).
+		Updates(updates)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func syncLeaseUpdates(token string, until time.Time) map[string]interface{} {
+	return map[string]interface{}{
+		"sync_locked_until": until,
+		"sync_lease_token":  token,
+	}
+}
+
+// RenewSyncLease extends the sync lease of a calendar held by the sync identified by token. It
+// returns false if the lease expired and was claimed by another sync.
+func (r *CalendarRepository) RenewSyncLease(calendarID uint64, token string, until time.Time) (bool, error) {
+	result := r.db.Model(&model.Calendar{}).
+		Where("id = ? AND sync_lease_token = ?", calendarID, token).
+		Update("sync_locked_until", until)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// ReleaseSyncLease releases the sync lease of a calendar held by the sync identified by token and,
+// if nextSyncAt is not nil, schedules its next background sync. An earlier sync scheduled while the
+// lease was held is kept. A lease claimed by another sync after it expired is left alone.
+func (r *CalendarRepository) ReleaseSyncLease(calendarID uint64, token string, nextSyncAt *time.Time) error {
+	updates := map[string]interface{}{
+		"sync_locked_until": nil,
+		"sync_lease_token":  nil,
+	}
+	if nextSyncAt != nil {
+		updates["next_sync_at"] = gorm.Expr("CASE WHEN next_sync_at < ? THEN next_sync_at ELSE ? END", *nextSyncAt, *nextSyncAt)
+	}
+
+	return r.db.Model(&model.Calendar{}).
+		Where("id = ? AND sync_lease_token = ?", calendarID, token).
+		Updates(updates).Error
+}
+
+// FindByWatchChannelID finds a calendar by the ID of its Google push notification channel
+func (r *CalendarRepository) FindByWatchChannelID(channelID string) (*model.Calendar, error) {
+	var calendar model.Calendar
+	err := r.db.Where("watch_channel_id = ?", channelID).First(&calendar).Error
+	if err != nil {
+		return nil, err
+	}
+	return &calendar, nil
+}
+
+// FindGoogleCalendarsWatchExpiringBefore finds Google calendars whose push notification channel
+// expires before the given time, or that have none
+func (r *CalendarRepository) FindGoogleCalendarsWatchExpiringBefore(before time.Time) ([]*model.Calendar, error) {
+	var calendars []*model.Calendar
+	err := r.db.Where("source = ? AND source_id IS NOT NULL", model.SourceGoogle).
+		Where("watch_expires_at IS NULL OR watch_expires_at < ?", before).
+		Find(&calendars).Error
+	if err != nil {
+		return nil, err
+	}
+	return calendars, nil
+}
+
+// UpdateWatchChannel stores the push notification channel of a calendar, nil values clear it
+func (r *CalendarRepository) UpdateWatchChannel(calendarID uint64, channelID, resourceID, tokenHash *string, expiresAt *time.Time) error {
+	return r.db.Model(&model.Calendar{}).
+		Where("id = ?", calendarID).
+		Updates(map[string]interface{}{
+			"watch_channel_id":  channelID,
+			"watch_resource_id": resourceID,
+			"watch_token_hash":  tokenHash,
+			"watch_expires_at":  expiresAt,
+		}).Error
+}
+
+// UpdateFetchResult records the outcome of fetching a subscribed calendar's remote feed
+func (r *CalendarRepository) UpdateFetchResult(calendarID uint64, fetchedAt time.Time, fetchError *string) error {
+	return r.db.Model(&model.Calendar{}).
+		Where("id = ?", calendarID).
+		Updates(map[string]interface{}{
+			"last_fetched_at":  fetchedAt,
+			"last_fetch_error": fetchError,
+		}).Error
+}
+
+// UpdateSubscriptionValidators updates the HTTP cache validators used for conditional feed requests
+func (r *CalendarRepository) UpdateSubscriptionValidators(calendarID uint64, etag, lastModified *string) error {
+	return r.db.Model(&model.Calendar{}).
+		Where("id = ?", calendarID).
+		Updates(map[string]interface{}{
+			"subscription_etag":          etag,
+			"subscription_last_modified": lastModified,
+		}).Error
+}
+
+// CountEventsByCalendarID counts the events of a specific calendar
+func (r *CalendarRepository) CountEventsByCalendarID(calendarID uint64) (int64, error) {
+	var count int64
+	err := r.db.Model(&model.CalendarEvent{}).Where("calendar_id = ?", calendarID).Count(&count).Error
+	return count, err
+}
+
+// FindByCalDAVAccountID finds the calendars synced with a CalDAV account
+func (r *CalendarRepository) FindByCalDAVAccountID(accountID uint64) ([]*model.Calendar, error) {
+	var calendars []*model.Calendar
+	err := r.db.Where("caldav_account_id = ?", accountID).Find(&calendars).Error
+	if err != nil {
+		return nil, err
+	}
+	return calendars, nil
+}
+
+// CreateCalDAVAccount creates a new CalDAV account
+func (r *CalendarRepository) CreateCalDAVAccount(account *model.CalDAVAccount) error {
+	return r.db.Create(account).Error
+}
+
+// FindCalDAVAccountByID finds a CalDAV account by ID
+func (r *CalendarRepository) FindCalDAVAccountByID(id uint64) (*model.CalDAVAccount, error) {
+	var account model.CalDAVAccount
+	err := r.db.Where("id = ?", id).First(&account).Error
+	if err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
+
+// FindCalDAVAccountsByUserID finds all CalDAV accounts of a user
+func (r *CalendarRepository) FindCalDAVAccountsByUserID(userID uint64) ([]*model.CalDAVAccount, error) {
+	var accounts []*model.CalDAVAccount
+	err := r.db.Where("user_id = ?", userID).Order("created_at ASC").Find(&accounts).Error
+	if err != nil {
+		return nil, err
+	}
+	return accounts, nil
+}
+
+// DeleteCalDAVAccount deletes a CalDAV account
+func (r *CalendarRepository) DeleteCalDAVAccount(id uint64) error {
+	return r.db.Delete(&model.CalDAVAccount{}, "id = ?", id).Error
+}
+
+// UpdateEventSourceETag updates the remote ETag of an event, without touching its other fields
+func (r *CalendarRepository) UpdateEventSourceETag(eventID uint64, etag string) error {
+	return r.db.Model(&model.CalendarEvent{}).
+		Where("id = ?", eventID).
+		UpdateColumn("source_etag", etag).Error
+}
+
+// ScheduleSync moves the next background sync of a calendar forward to at
+func (r *CalendarRepository) ScheduleSync(calendarID uint64, at time.Time) error {
+	return r.db.Model(&model.Calendar{}).
+		Where("id = ? AND (next_sync_at IS NULL OR next_sync_at > ?)", calendarID, at).
+		UpdateColumn("next_sync_at", at).Error
+}
+
+// FindEventWrite finds the queued write of a remote event
+func (r *CalendarRepository) FindEventWrite(calendarID uint64, sourceID string) (*model.EventWrite, error) {
+	var write model.EventWrite
+	err := r.db.Where("calendar_id = ? AND source_id = ?", calendarID, sourceID).First(&write).Error
+	if err != nil {
+		return nil, err
+	}
+	return &write, nil
+}
+
+// FindEventWritesByCalendarID finds the queued writes of a calendar, oldest first
+func (r *CalendarRepository) FindEventWritesByCalendarID(calendarID uint64) ([]*model.EventWrite, error) {
+	var writes []*model.EventWrite
+	err := r.db.Where("calendar_id = ?", calendarID).Order("created_at ASC, id ASC").Find(&writes).Error
+	if err != nil {
+		return nil, err
+	}
+	return writes, nil
+}
+
+// FindDueEventWrites finds the queued writes of a calendar due for an attempt, oldest first. Writes
+// waiting for a conflict to be resolved are never due.
+func (r *CalendarRepository) FindDueEventWrites(calendarID uint64, now time.Time) ([]*model.EventWrite, error) {
+	var writes []*model.EventWrite
+	err := r.db.Where("calendar_id = ? AND next_attempt_at <= ? AND conflict = ?", calendarID, now, false).
+		Order("created_at ASC, id ASC").
+		Find(&writes).Error
+	if err != nil {
+		return nil, err
+	}
+	return writes, nil
+}
+
+// FindEventWriteByEventID finds the queued write of a local event, which may already be deleted
+func (r *CalendarRepository) FindEventWriteByEventID(calendarID, eventID uint64) (*model.EventWrite, error) {
+	var write model.EventWrite
+	err := r.db.Where("calendar_id = ? AND event_id = ?", calendarID, eventID).First(&write).Error
+	if err != nil {
+		return nil, err
+	}
+	return &write, nil
+}
+
+// FindConflictingEventWrites finds the queued writes of calendars waiting for a conflict to be resolved
+func (r *CalendarRepository) FindConflictingEventWrites(calendarIDs []uint64) ([]*model.EventWrite, error) {
+	var writes []*model.EventWrite
+	err := r.db.Where("calendar_id IN ? AND conflict = ?", calendarIDs, true).Find(&writes).Error
+	if err != nil {
+		return nil, err
+	}
+	return writes, nil
+}
+
+// CountConflictingEventWrites counts the queued writes of a calendar waiting for a conflict to be resolved
+func (r *CalendarRepository) CountConflictingEventWrites(calendarID uint64) (int64, error) {
+	var count int64
+	err := r.db.Model(&model.EventWrite{}).Where("calendar_id = ? AND conflict = ?", calendarID, true).Count(&count).Error
+	return count, err
+}
+
+// SaveEventWrite creates or updates a queued write
+func (r *CalendarRepository) SaveEventWrite(write *model.EventWrite) error {
+	return r.db.Save(write).Error
+}
+
+// UpdateEventWrite updates a queued write unless a change was folded into it since it was read.
+// Returns whether the write was updated.
+func (r *CalendarRepository) UpdateEventWrite(write *model.EventWrite) (bool, error) {
+	result := r.db.Model(write).Where("version = ?", write.Version).Select("*").Omit("id", "created_at").Updates(write)
+	return result.RowsAffected > 0, result.Error
+}
+
+// DeleteEventWrite removes a write from the queue unless a change was folded into it since it was read
+func (r *CalendarRepository) DeleteEventWrite(write *model.EventWrite) error {
+	return r.db.Delete(&model.EventWrite{}, "id = ? AND version = ?", write.ID, write.Version).Error
+}
+
+// DeleteEventWritesByCalendarID removes every queued write of a calendar
+func (r *CalendarRepository) DeleteEventWritesByCalendarID(calendarID uint64) error {
+	return r.db.Delete(&model.EventWrite{}, "calendar_id = ?", calendarID).Error
+}
+
+// CreateBackfillJob creates a new backfill job
+func (r *CalendarRepository) CreateBackfillJob(job *model.BackfillJob) error {
+	return r.db.Create(job).Error
+}
+
+// FindBackfillJobByID finds a backfill job by ID
+func (r *CalendarRepository) FindBackfillJobByID(id string) (*model.BackfillJob, error) {
+	var job model.BackfillJob
+	err := r.db.Where("id = ?", id).First(&job).Error
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// FindBackfillJobsByUserID finds the backfill jobs of a user, the most recent first
+func (r *CalendarRepository) FindBackfillJobsByUserID(userID uint64) ([]*model.BackfillJob, error) {
+	var jobs []*model.BackfillJob
+	err := r.db.Where("user_id = ?", userID).Order("created_at DESC, id DESC").Find(&jobs).Error
+	if err != nil {
+		return nil, err
+	}
+	return jobs, nil
+}
+
+// ExistsActiveBackfillJob checks if a calendar has a backfill job that is pending or running
+func (r *CalendarRepository) ExistsActiveBackfillJob(calendarID uint64) (bool, error) {
+	var count int64
+	err := r.db.Model(&model.BackfillJob{}).
+		Where("calendar_id = ? AND status IN ?", calendarID, activeBackfillJobStatuses).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// FindClaimableBackfillJobs finds active backfill jobs no worker holds the lease of, the oldest first
+func (r *CalendarRepository) FindClaimableBackfillJobs(now time.Time, limit int) ([]*model.BackfillJob, error) {
+	var jobs []*model.BackfillJob
+	err := r.db.Where("status IN ?", activeBackfillJobStatuses).
+		Where("locked_until IS NULL OR locked_until < ?", now).
+		Order("created_at ASC, id ASC").
+		Limit(limit).
+		Find(&jobs).Error
+	if err != nil {
+		return nil, err
+	}
+	return jobs, nil
+}
+
+// ClaimBackfillJob atomically claims the lease of an active backfill job until the given time and
+// marks it running. It returns false if another worker holds the lease or the job is no longer active.
+func (r *CalendarRepository) ClaimBackfillJob(id uint64, now, until time.Time) (bool, error) {
+	result := r.db.Model(&model.BackfillJob{}).
+		Where("id = ? AND status IN ?", id, activeBackfillJobStatuses).
+		Where("locked_until IS NULL OR locked_until < ?", now).
+		Updates(map[string]interface{}{
+			"status":       model.BackfillJobStatusRunning,
+			"locked_until": until,
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// UpdateBackfillJobProgress stores the progress of a running backfill job and extends its lease. It
+// returns false if the job is no longer running, because it was cancelled.
+func (r *CalendarRepository) UpdateBackfillJobProgress(job *model.BackfillJob, until time.Time) (bool, error) {
+	result := r.db.Model(&model.BackfillJob{}).
+		Where("id = ? AND status = ?", job.ID, model.BackfillJobStatusRunning).
+		Updates(map[string]interface{}{
+			"cursor":        job.Cursor,
+			"page_token":    job.PageToken,
+			"chunks_done":   job.ChunksDone,
+			"pages_fetched": job.PagesFetched,
+			"events_stored": job.EventsStored,
+			"errors":        job.Errors,
+			"last_error":    job.LastError,
+			"started_at":    job.StartedAt,
+			"locked_until":  until,
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// FinishBackfillJob records the final status of a running backfill job and releases its lease. A
+// job cancelled in the meantime stays cancelled.
+func (r *CalendarRepository) FinishBackfillJob(job *model.BackfillJob) error {
+	return r.db.Model(&model.BackfillJob{}).
+		Where("id = ? AND status = ?", job.ID, model.BackfillJobStatusRunning).
+		Updates(map[string]interface{}{
+			"status":        job.Status,
+			"cursor":        job.Cursor,
+			"page_token":    job.PageToken,
+			"chunks_done":   job.ChunksDone,
+			"pages_fetched": job.PagesFetched,
+			"events_stored": job.EventsStored,
+			"errors":        job.Errors,
+			"last_error":    job.LastError,
+			"finished_at":   job.FinishedAt,
+			"locked_until":  nil,
+		}).Error
+}
+
+// CancelBackfillJob cancels a backfill job unless it already finished. It returns false if the job
+// was no longer active.
+func (r *CalendarRepository) CancelBackfillJob(id uint64, finishedAt time.Time) (bool, error) {
+	result := r.db.Model(&model.BackfillJob{}).
+		Where("id = ? AND status IN ?", id, activeBackfillJobStatuses).
+		Updates(map[string]interface{}{
+			"status":       model.BackfillJobStatusCancelled,
+			"finished_at":  finishedAt,
+			"locked_until": nil,
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// DeleteBackfillJobsByCalendarID deletes every backfill job of a calendar
+func (r *CalendarRepository) DeleteBackfillJobsByCalendarID(calendarID uint64) error {
+	return r.db.Delete(&model.BackfillJob{}, "calendar_id = ?", calendarID).Error
+}
+
+// CreateSyncRun records a sync run
+func (r *CalendarRepository) CreateSyncRun(run *model.SyncRun) error {
+	return r.db.Create(run).Error
+}
+
+// FindSyncRunsByCalendarID finds the most recent sync runs of a calendar, the most recent first
+func (r *CalendarRepository) FindSyncRunsByCalendarID(calendarID uint64, limit int) ([]*model.SyncRun, error) {
+	var runs []*model.SyncRun
+	err := r.db.Where("calendar_id = ?", calendarID).
+		Order("started_at DESC, id DESC").
+		Limit(limit).
+		Find(&runs).Error
+	if err != nil {
+		return nil, err
+	}
+	return runs, nil
+}
+
+// FindLastSuccessfulSyncRun finds the most recent sync run of a calendar that did not fail
+func (r *CalendarRepository) FindLastSuccessfulSyncRun(calendarID uint64) (*model.SyncRun, error) {
+	var run model.SyncRun
+	err := r.db.Where("calendar_id = ? AND error_class IS NULL", calendarID).
+		Order("started_at DESC, id DESC").
+		First(&run).Error
+	if err != nil {
+		return nil, err
+	}
+	return &run, nil
+}
+
+// CountFailedSyncRunsSince counts the failed sync runs of a calendar started after the given time
+func (r *CalendarRepository) CountFailedSyncRunsSince(calendarID uint64, since time.Time) (int64, error) {
+	var count int64
+	err := r.db.Model(&model.SyncRun{}).
+		Where("calendar_id = ? AND error_class IS NOT NULL AND started_at > ?", calendarID, since).
+		Count(&count).Error
+	return count, err
+}
+
+// DeleteSyncRunsBefore deletes the sync runs of a calendar started before the given time
+func (r *CalendarRepository) DeleteSyncRunsBefore(calendarID uint64, before time.Time) error {
+	return r.db.Delete(&model.SyncRun{}, "calendar_id = ? AND started_at < ?", calendarID, before).Error
+}
+
+// DeleteSyncRunsByCalendarID deletes every sync run of a calendar
+func (r *CalendarRepository) DeleteSyncRunsByCalendarID(calendarID uint64) error {
+	return r.db.Delete(&model.SyncRun{}, "calendar_id = ?", calendarID).Error
+}
+
+// activeBackfillJobStatuses are the statuses of backfill jobs that still have work to do
+var activeBackfillJobStatuses = []model.BackfillJobStatus{model.BackfillJobStatusPending, model.BackfillJobStatusRunning}
+
+// FindEventsByCalendarIDAndSourceURL finds the events of a calendar stored under the same remote
+// resource
+func (r *CalendarRepository) FindEventsByCalendarIDAndSourceURL(calendarID uint64, sourceURL string) ([]*model.CalendarEvent, error) {
+	var events []*model.CalendarEvent
+	err := r.db.Where("calendar_id = ? AND source_url = ?", calendarID, sourceURL).Find(&events).Error
+	return events, err
+}
+
+// FindDAVObject finds the calendar object a CalDAV client wrote to a resource
+func (r *CalendarRepository) FindDAVObject(calendarID uint64, name string) (*model.DAVObject, error) {
+	var object model.DAVObject
+	err := r.db.Where("calendar_id = ? AND name = ?", calendarID, name).First(&object).Error
+	if err != nil {
+		return nil, err
+	}
+	return &object, nil
+}
+
+// FindDAVObjectsByCalendarID finds the calendar objects CalDAV clients wrote to a calendar
+func (r *CalendarRepository) FindDAVObjectsByCalendarID(calendarID uint64) ([]*model.DAVObject, error) {
+	var objects []*model.DAVObject
+	err := r.db.Where("calendar_id = ?", calendarID).Find(&objects).Error
+	return objects, err
+}
+
+// SaveDAVObject stores a calendar object written by a CalDAV client, replacing the object
+// previously written to the same resource
+func (r *CalendarRepository) SaveDAVObject(object *model.DAVObject) error {
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "calendar_id"}, {Name: "name"}},
+		DoUpdates: clause.AssignmentColumns([]string{"data", "etag", "updated_at"}),
+	}).Create(object).Error
+}
+
+// DeleteDAVObject removes the calendar object stored for a resource
+func (r *CalendarRepository) DeleteDAVObject(calendarID uint64, name string) error {
+	return r.db.Delete(&model.DAVObject{}, "calendar_id = ? AND name = ?", calendarID, name).Error
+}
@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/NathanWasTaken/timely/backend/internal/model"
+)
+
+type FeedTokenRepository struct {
+	db *gorm.DB
+}
+
+func NewFeedTokenRepository(db *gorm.DB) *FeedTokenRepository {
+	return &FeedTokenRepository{
+		db: db,
+	}
+}
+
+// Create creates a new feed token
+func (r *FeedTokenRepository) Create(feedToken *model.FeedToken) error {
+	return r.db.Create(feedToken).Error
+}
+
+// FindByID finds a feed token by ID
+func (r *FeedTokenRepository) FindByID(id string) (*model.FeedToken, error) {
+	var feedToken model.FeedToken
+	err := r.db.Where("id = ?", id).First(&feedToken).Error
+	if err != nil {
+		return nil, err
+	}
+	return &feedToken, nil
+}
+
+// FindByUserID finds all feed tokens for a user
+func (r *FeedTokenRepository) FindByUserID(userID uint64) ([]*model.FeedToken, error) {
+	var feedTokens []*model.FeedToken
+	err := r.db.Where("user_id = ?", userID).Order("created_at ASC").Find(&feedTokens).Error
+	if err != nil {
+		return nil, err
+	}
+	return feedTokens, nil
+}
+
+// FindByTokenHash finds a feed token by the hash of its secret
+func (r *FeedTokenRepository) FindByTokenHash(tokenHash string) (*model.FeedToken, error) {
+	var feedToken model.FeedToken
+	err := r.db.Where("token_hash = ?", tokenHash).First(&feedToken).Error
+	if err != nil {
+		return nil, err
+	}
+	return &feedToken, nil
+}
+
+// Update updates an existing feed token
+func (r *FeedTokenRepository) Update(feedToken *model.FeedToken) error {
+	return r.db.Save(feedToken).Error
+}
+
+// UpdateLastUsedAt updates the last used timestamp for a feed token without touching updated_at
+func (r *FeedTokenRepository) UpdateLastUsedAt(id uint64, lastUsedAt time.Time) error {
+	return r.db.Model(&model.FeedToken{}).
+		Where("id = ?", id).
+		UpdateColumn("last_used_at", lastUsedAt).Error
+}
+
+// Delete deletes a feed token
+func (r *FeedTokenRepository) Delete(id string) error {
+	return r.db.Delete(&model.FeedToken{}, "id = ?", id).Error
+}
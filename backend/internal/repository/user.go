@@ -127,14 +127,20 @@ func (r *UserRepository) FindGoogleAccountByUserID(userID uint64) (*model.Accoun
 }
 
 // UpdateGoogleAccountTokens updates the OAuth tokens for a Google account
-func (r *UserRepository) UpdateGoogleAccountTokens(userID uint64, accessToken, refreshToken string, expiry *time.Time) error {
+func (r *UserRepository) UpdateGoogleAccountTokens(userID uint64, accessToken, refreshToken string, expiry *time.Time, scopes string) error {
+	updates := map[string]interface{}{
+		"access_token":  accessToken,
+		"refresh_token": refreshToken,
+		"expiry":        expiry,
+	}
+	// Token responses that don't list the scopes leave the ones granted before as they are
+	if scopes != "" {
+		updates["scopes"] = scopes
+	}
+
 	return r.db.Model(&model.Account{}).
 		Where("user_id = ? AND provider = ?", userID, "google").
-		Updates(map[string]interface{}{
-			"access_token":  accessToken,
-			"refresh_token": refreshToken,
-			"expiry":        expiry,
-		}).Error
+		Updates(updates).Error
 }
 
 // FindUsersWithGoogleAccounts finds all users who have Google accounts
@@ -26,6 +26,9 @@ func CalendarRouter(r chi.Router) {
 	// Initialize handlers
 	calendarHandler := calendar.NewCalendarHandler(calendarService)
 
+	// Google push notifications, authenticated by the channel token
+	r.Post("/webhooks/google", calendarHandler.GoogleWebhook)
+
 	// Calendar routes with JWT middleware
 	r.Route("/calendars", func(r chi.Router) {
 		// Apply JWT middleware to all calendar routes
@@ -33,23 +36,56 @@ func CalendarRouter(r chi.Router) {
 
 		// Get all imported calendars endpoint
 		r.Get("/", calendarHandler.GetImportedCalendars)
+		r.Post("/", calendarHandler.CreateCalendar)
 
 		// Individual calendar operations (update/delete)
 		r.Patch("/{id}", calendarHandler.UpdateCalendar)
 		r.Delete("/{id}", calendarHandler.DeleteCalendar)
+		r.Post("/{id}/refresh", calendarHandler.RefreshCalendar)
+		r.Get("/{id}/syncs", calendarHandler.GetCalendarSyncRuns)
+
+		// Event operations, creating, replacing and deleting is limited to Timely calendars
+		r.Post("/{id}/events", calendarHandler.CreateEvent)
+		r.Put("/{id}/events/{eventId}", calendarHandler.UpdateEvent)
+		r.Patch("/{id}/events/{eventId}", calendarHandler.PatchEvent)
+		r.Delete("/{id}/events/{eventId}", calendarHandler.DeleteEvent)
+		r.Post("/{id}/events/{eventId}/resolve", calendarHandler.ResolveEventConflict)
 
 		// Google Calendar endpoints
 		r.Route("/google", func(r chi.Router) {
 			r.Get("/", calendarHandler.GetCalendars)
 			r.Post("/", calendarHandler.ImportCalendar)
+
+			// Historical sync, run as backfill jobs in the background
+			r.Post("/sync/historical", calendarHandler.SyncHistoricalData)
+			r.Get("/sync/historical", calendarHandler.GetBackfillJobs)
+			r.Get("/sync/historical/{jobId}", calendarHandler.GetBackfillJob)
+			r.Post("/sync/historical/{jobId}/cancel", calendarHandler.CancelBackfillJob)
 		})
 
 		// ICS Calendar endpoints
 		r.Route("/ics", func(r chi.Router) {
 			r.Post("/", calendarHandler.ImportICS)
+			r.Post("/subscribe", calendarHandler.SubscribeICS)
+		})
+
+		// CalDAV endpoints, calendars are imported from connected accounts
+		r.Route("/caldav", func(r chi.Router) {
+			r.Post("/", calendarHandler.ImportCalDAVCalendar)
+			r.Get("/accounts", calendarHandler.GetCalDAVAccounts)
+			r.Post("/accounts", calendarHandler.ConnectCalDAVAccount)
+			r.Delete("/accounts/{accountId}", calendarHandler.DeleteCalDAVAccount)
+			r.Get("/accounts/{accountId}/calendars", calendarHandler.GetCalDAVCalendars)
 		})
 
 		// Calendar events endpoint
 		r.Get("/events", calendarHandler.GetCalendarEvents)
 	})
+
+	// Event routes across all of the user's calendars
+	r.Route("/events", func(r chi.Router) {
+		r.Use(middleware.JWTMiddleware(zap.L()))
+
+		r.Get("/search", calendarHandler.SearchEvents)
+	})
 }
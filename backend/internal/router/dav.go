@@ -0,0 +1,56 @@
+package router
+
+import (
+	"github.com/go-chi/chi/v5"
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
+
+	"github.com/NathanWasTaken/timely/backend/internal/config"
+	"github.com/NathanWasTaken/timely/backend/internal/handler/dav"
+	"github.com/NathanWasTaken/timely/backend/internal/repository"
+	"github.com/NathanWasTaken/timely/backend/internal/service"
+)
+
+func DAVRouter(r chi.Router) {
+	// WebDAV methods are not known to chi by default
+	chi.RegisterMethod("PROPFIND")
+	chi.RegisterMethod("REPORT")
+
+	// Initialize database dependencies
+	dbConfig := config.NewDatabaseConfig()
+	userRepo := repository.NewUserRepository(dbConfig.GetDB())
+	calendarRepo := repository.NewCalendarRepository(dbConfig.GetDB())
+	appPasswordRepo := repository.NewAppPasswordRepository(dbConfig.GetDB())
+
+	// Initialize OAuth dependencies
+	oauthConfig := config.NewOAuthConfig()
+
+	// Initialize services
+	calendarService := service.NewCalendarService(userRepo, calendarRepo, oauthConfig)
+	appPasswordService := service.NewAppPasswordService(appPasswordRepo, userRepo)
+
+	// Initialize handlers
+	davHandler := dav.NewDAVHandler(calendarService, appPasswordService)
+
+	// Service discovery for CalDAV clients (RFC 6764)
+	r.Get("/.well-known/caldav", davHandler.WellKnown)
+	r.MethodFunc("PROPFIND", "/.well-known/caldav", davHandler.WellKnown)
+
+	// CalDAV server, authenticated by app passwords
+	r.Route(dav.Prefix, func(r chi.Router) {
+		r.Use(chimiddleware.StripSlashes)
+		r.Options("/*", davHandler.Options)
+
+		r.Group(func(r chi.Router) {
+			r.Use(davHandler.Authenticate)
+			r.MethodFunc("PROPFIND", "/", davHandler.PropFindRoot)
+			r.MethodFunc("PROPFIND", "/principals/{username}", davHandler.PropFindPrincipal)
+			r.MethodFunc("PROPFIND", "/calendars/{username}", davHandler.PropFindHome)
+			r.MethodFunc("PROPFIND", "/calendars/{username}/{calendarId}", davHandler.PropFindCalendar)
+			r.MethodFunc("REPORT", "/calendars/{username}/{calendarId}", davHandler.Report)
+			r.Get("/calendars/{username}/{calendarId}/{resource}", davHandler.GetResource)
+			r.Head("/calendars/{username}/{calendarId}/{resource}", davHandler.GetResource)
+			r.Put("/calendars/{username}/{calendarId}/{resource}", davHandler.PutResource)
+			r.Delete("/calendars/{username}/{calendarId}/{resource}", davHandler.DeleteResource)
+		})
+	})
+}
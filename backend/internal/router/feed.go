@@ -0,0 +1,33 @@
+package router
+
+import (
+	"github.com/go-chi/chi/v5"
+
+	"github.com/NathanWasTaken/timely/backend/internal/config"
+	"github.com/NathanWasTaken/timely/backend/internal/handler/user"
+	"github.com/NathanWasTaken/timely/backend/internal/repository"
+	"github.com/NathanWasTaken/timely/backend/internal/service"
+)
+
+func FeedRouter(r chi.Router) {
+	// Initialize database dependencies
+	dbConfig := config.NewDatabaseConfig()
+	userRepo := repository.NewUserRepository(dbConfig.GetDB())
+	calendarRepo := repository.NewCalendarRepository(dbConfig.GetDB())
+	feedTokenRepo := repository.NewFeedTokenRepository(dbConfig.GetDB())
+
+	// Initialize OAuth dependencies
+	oauthConfig := config.NewOAuthConfig()
+
+	// Initialize services
+	calendarService := service.NewCalendarService(userRepo, calendarRepo, oauthConfig)
+	feedService := service.NewFeedService(feedTokenRepo, userRepo)
+
+	// Initialize handlers
+	feedHandler := user.NewFeedHandler(feedService, calendarService)
+
+	// Private ICS feeds, authenticated by the secret token in the URL
+	r.Route("/feeds", func(r chi.Router) {
+		r.Get("/{token}.ics", feedHandler.GetPrivateCalendarFeed)
+	})
+}
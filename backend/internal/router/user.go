@@ -16,6 +16,9 @@ func UserRouter(r chi.Router) {
 	dbConfig := config.NewDatabaseConfig()
 	userRepo := repository.NewUserRepository(dbConfig.GetDB())
 	calendarRepo := repository.NewCalendarRepository(dbConfig.GetDB())
+	feedTokenRepo := repository.NewFeedTokenRepository(dbConfig.GetDB())
+	bookingRepo := repository.NewBookingRepository(dbConfig.GetDB())
+	appPasswordRepo := repository.NewAppPasswordRepository(dbConfig.GetDB())
 
 	// Initialize OAuth dependencies
 	oauthConfig := config.NewOAuthConfig()
@@ -23,10 +26,16 @@ func UserRouter(r chi.Router) {
 	// Initialize services
 	userService := service.NewUserService(userRepo)
 	calendarService := service.NewCalendarService(userRepo, calendarRepo, oauthConfig)
+	feedService := service.NewFeedService(feedTokenRepo, userRepo)
+	bookingService := service.NewBookingService(bookingRepo, calendarRepo)
+	appPasswordService := service.NewAppPasswordService(appPasswordRepo, userRepo)
 
 	// Initialize handlers
 	userHandler := user.NewUserHandler(userService)
 	userEventsHandler := user.NewUserEventsHandler(calendarService, userService)
+	feedHandler := user.NewFeedHandler(feedService, calendarService)
+	bookingHandler := user.NewBookingHandler(bookingService, userService)
+	appPasswordHandler := user.NewAppPasswordHandler(appPasswordService, userService)
 
 	// User routes
 	r.Route("/users", func(r chi.Router) {
@@ -35,10 +44,31 @@ func UserRouter(r chi.Router) {
 			r.Use(middleware.JWTMiddleware(zap.L()))
 			r.Get("/me", userHandler.GetProfile)
 			r.Patch("/me", userHandler.UpdateProfile)
+
+			// Private ICS feed tokens
+			r.Get("/me/feeds", feedHandler.ListFeedTokens)
+			r.Post("/me/feeds", feedHandler.CreateFeedToken)
+			r.Post("/me/feeds/{id}/rotate", feedHandler.RotateFeedToken)
+			r.Delete("/me/feeds/{id}", feedHandler.RevokeFeedToken)
+
+			// App passwords for CalDAV clients
+			r.Get("/me/app-passwords", appPasswordHandler.ListAppPasswords)
+			r.Post("/me/app-passwords", appPasswordHandler.CreateAppPassword)
+			r.Delete("/me/app-passwords/{id}", appPasswordHandler.RevokeAppPassword)
+
+			// Booking types offered on the user's booking pages
+			r.Get("/me/booking-types", bookingHandler.ListBookingTypes)
+			r.Post("/me/booking-types", bookingHandler.CreateBookingType)
+			r.Put("/me/booking-types/{id}", bookingHandler.UpdateBookingType)
+			r.Delete("/me/booking-types/{id}", bookingHandler.DeleteBookingType)
 		})
 
 		// Public endpoints (no authentication required)
 		r.Get("/{username}", userHandler.GetPublicProfile)
 		r.Get("/{username}/events", userEventsHandler.GetPublicUserEvents)
+		r.Get("/{username}/freebusy", userEventsHandler.GetPublicUserFreeBusy)
+		r.Get("/{username}/calendar.ics", userEventsHandler.GetPublicUserCalendarFeed)
+		r.Get("/{username}/booking/{slug}", bookingHandler.GetBookingSlots)
+		r.Post("/{username}/booking/{slug}", bookingHandler.CreateBooking)
 	})
 }
@@ -0,0 +1,123 @@
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/NathanWasTaken/timely/backend/internal/model"
+	"github.com/NathanWasTaken/timely/backend/internal/repository"
+	"github.com/NathanWasTaken/timely/backend/pkg/utils"
+)
+
+const (
+	// defaultAppPasswordName is used when an app password is created without a name
+	defaultAppPasswordName = "CalDAV client"
+	// appPasswordHintLength is the number of trailing password characters kept to identify a password
+	appPasswordHintLength = 4
+)
+
+// AppPasswordService manages the passwords CalDAV clients sign in to the built-in CalDAV server with
+type AppPasswordService struct {
+	appPasswordRepo *repository.AppPasswordRepository
+	userRepo        *repository.UserRepository
+	logger          *zap.Logger
+}
+
+func NewAppPasswordService(appPasswordRepo *repository.AppPasswordRepository, userRepo *repository.UserRepository) *AppPasswordService {
+	return &AppPasswordService{
+		appPasswordRepo: appPasswordRepo,
+		userRepo:        userRepo,
+		logger:          zap.L(),
+	}
+}
+
+// ListAppPasswords retrieves all app passwords for a user
+func (s *AppPasswordService) ListAppPasswords(userID uint64) ([]*model.AppPassword, error) {
+	appPasswords, err := s.appPasswordRepo.FindByUserID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get app passwords: %w", err)
+	}
+	return appPasswords, nil
+}
+
+// CreateAppPassword creates a new app password for a user and returns it along with the secret
+// password, which is not stored and cannot be retrieved again
+func (s *AppPasswordService) CreateAppPassword(userID uint64, name string) (*model.AppPassword, string, error) {
+	if name == "" {
+		name = defaultAppPasswordName
+	}
+
+	password, err := utils.GenerateSecretToken()
+	if err != nil {
+		return nil, "", err
+	}
+
+	appPassword := &model.AppPassword{
+		ID:           utils.GenerateID(),
+		UserID:       userID,
+		Name:         name,
+		PasswordHash: utils.HashToken(password),
+		PasswordHint: password[len(password)-appPasswordHintLength:],
+	}
+
+	if err := s.appPasswordRepo.Create(appPassword); err != nil {
+		return nil, "", fmt.Errorf("failed to create app password: %w", err)
+	}
+
+	s.logger.Info("Created app password",
+		zap.Uint64("user_id", userID),
+		zap.Uint64("app_password_id", appPassword.ID))
+
+	return appPassword, password, nil
+}
+
+// RevokeAppPassword deletes an app password so clients using it are signed out
+func (s *AppPasswordService) RevokeAppPassword(userID uint64, appPasswordID string) error {
+	appPassword, err := s.appPasswordRepo.FindByID(appPasswordID)
+	if err != nil {
+		return fmt.Errorf("failed to find app password: %w", err)
+	}
+
+	if appPassword.UserID != userID {
+		return fmt.Errorf("app password not found or access denied")
+	}
+
+	if err := s.appPasswordRepo.Delete(appPasswordID); err != nil {
+		return fmt.Errorf("failed to delete app password: %w", err)
+	}
+
+	s.logger.Info("Revoked app password",
+		zap.Uint64("user_id", userID),
+		zap.String("app_password_id", appPasswordID))
+
+	return nil
+}
+
+// AuthenticateAppPassword resolves the username and app password sent by a CalDAV client to the
+// user signing in
+func (s *AppPasswordService) AuthenticateAppPassword(username, password string) (*model.User, error) {
+	if username == "" || password == "" {
+		return nil, fmt.Errorf("invalid credentials")
+	}
+
+	user, err := s.userRepo.FindByUsername(username)
+	if err != nil {
+		return nil, fmt.Errorf("invalid credentials")
+	}
+
+	appPassword, err := s.appPasswordRepo.FindByUserIDAndPasswordHash(user.ID, utils.HashToken(password))
+	if err != nil {
+		return nil, fmt.Errorf("invalid credentials")
+	}
+
+	// Recording usage is best-effort and must not break the sign in
+	if err := s.appPasswordRepo.UpdateLastUsedAt(appPassword.ID, time.Now()); err != nil {
+		s.logger.Warn("Failed to update app password usage",
+			zap.Error(err),
+			zap.Uint64("app_password_id", appPassword.ID))
+	}
+
+	return user, nil
+}
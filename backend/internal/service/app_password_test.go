@@ -0,0 +1,56 @@
+package service
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/NathanWasTaken/timely/backend/internal/model"
+	"github.com/NathanWasTaken/timely/backend/internal/repository"
+	"github.com/NathanWasTaken/timely/backend/pkg/utils"
+)
+
+func TestAppPasswordLifecycle(t *testing.T) {
+	db := newTestDB(t)
+	userRepo := repository.NewUserRepository(db)
+	appPasswordService := NewAppPasswordService(repository.NewAppPasswordRepository(db), userRepo)
+
+	owner := &model.User{ID: utils.GenerateID(), Username: "owner", DisplayName: "Owner"}
+	if err := userRepo.Create(owner); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	appPassword, password, err := appPasswordService.CreateAppPassword(owner.ID, "Phone")
+	if err != nil {
+		t.Fatalf("Failed to create app password: %v", err)
+	}
+	if appPassword.PasswordHash != utils.HashToken(password) || appPassword.PasswordHint != password[len(password)-appPasswordHintLength:] {
+		t.Error("Expected only the hash and the hint of the password to be stored")
+	}
+
+	user, err := appPasswordService.AuthenticateAppPassword("owner", password)
+	if err != nil || user.ID != owner.ID {
+		t.Fatalf("Expected the app password to sign in its owner, got %v, %v", user, err)
+	}
+	if _, err := appPasswordService.AuthenticateAppPassword("someone", password); err == nil {
+		t.Error("Expected the app password to be rejected for another username")
+	}
+	if _, err := appPasswordService.AuthenticateAppPassword("owner", password+"x"); err == nil {
+		t.Error("Expected an unknown password to be rejected")
+	}
+
+	appPasswords, err := appPasswordService.ListAppPasswords(owner.ID)
+	if err != nil || len(appPasswords) != 1 || appPasswords[0].LastUsedAt == nil {
+		t.Fatalf("Expected the app password to be listed with its last use, got %+v, %v", appPasswords, err)
+	}
+
+	appPasswordID := fmt.Sprintf("%d", appPassword.ID)
+	if err := appPasswordService.RevokeAppPassword(owner.ID+1, appPasswordID); err == nil {
+		t.Error("Expected another user to be unable to revoke the app password")
+	}
+	if err := appPasswordService.RevokeAppPassword(owner.ID, appPasswordID); err != nil {
+		t.Fatalf("Failed to revoke app password: %v", err)
+	}
+	if _, err := appPasswordService.AuthenticateAppPassword("owner", password); err == nil {
+		t.Error("Expected a revoked app password to be rejected")
+	}
+}
@@ -0,0 +1,143 @@
+package service
+
+import (
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/NathanWasTaken/timely/backend/internal/model"
+	"github.com/NathanWasTaken/timely/backend/pkg/utils"
+)
+
+// markSelfAttendees marks the attendees of events that are the owner of the calendar and sets the
+// owner's response on the events. Google marks the owner itself, the attendees of other sources are
+// matched against the email addresses the owner is known by.
+func (s *CalendarService) markSelfAttendees(calendar *model.Calendar, events []*model.CalendarEvent) {
+	var emails map[string]bool
+	for _, event := range events {
+		event.ResponseStatus = ""
+		for _, attendee := range event.Attendees {
+			if !attendee.Self {
+				if emails == nil {
+					emails = s.ownerEmails(calendar)
+				}
+				attendee.Self = emails[strings.ToLower(attendee.Email)]
+			}
+			if attendee.Self && event.ResponseStatus == "" {
+				event.ResponseStatus = attendee.Status
+			}
+		}
+	}
+}
+
+// ownerEmails returns the lowercased email addresses of the owner of a calendar: those of their
+// linked accounts, of the Google calendar and of the CalDAV account it is synced with
+func (s *CalendarService) ownerEmails(calendar *model.Calendar) map[string]bool {
+	emails := make(map[string]bool)
+
+	accounts, err := s.userRepo.FindAccountsByUserID(calendar.UserID)
+	if err != nil {
+		s.logger.Warn("Failed to find accounts", zap.Error(err), zap.Uint64("user_id", calendar.UserID))
+	}
+	for _, account := range accounts {
+		if account.Email != nil {
+			emails[strings.ToLower(*account.Email)] = true
+		}
+	}
+
+	// The ID of a primary Google calendar is the email address of its account
+	if calendar.Source == model.SourceGoogle && calendar.SourceID != nil && strings.Contains(*calendar.SourceID, "@") {
+		emails[strings.ToLower(*calendar.SourceID)] = true
+	}
+
+	if calendar.CalDAVAccountID != nil {
+		account, err := s.calendarRepo.FindCalDAVAccountByID(*calendar.CalDAVAccountID)
+		if err != nil {
+			s.logger.Warn("Failed to find CalDAV account", zap.Error(err), zap.Uint64("calendar_id", calendar.ID))
+		} else if strings.Contains(account.Username, "@") {
+			emails[strings.ToLower(account.Username)] = true
+		}
+	}
+
+	return emails
+}
+
+// loadEventAttendees fills in the attendees of events shown to the owner of their calendars
+func (s *CalendarService) loadEventAttendees(events []*model.CalendarEvent) {
+	if err := s.calendarRepo.LoadEventAttendees(events); err != nil {
+		// The events are still shown, just without their attendees
+		s.logger.Warn("Failed to load event attendees", zap.Error(err), zap.Int("event_count", len(events)))
+	}
+}
+
+// hideEventResponses removes the guests of events shown publicly, along with the owner's response
+// to them
+func hideEventResponses(events []*model.CalendarEvent) {
+	for _, event := range events {
+		event.Attendees = nil
+		event.ResponseStatus = ""
+	}
+}
+
+// withoutHiddenDeclined drops the events the owner declined from calendars that hide them
+func withoutHiddenDeclined(calendars []*model.Calendar, events []*model.CalendarEvent) []*model.CalendarEvent {
+	hidden := make(map[uint64]bool)
+	for _, calendar := range calendars {
+		if calendar.HideDeclined {
+			hidden[calendar.ID] = true
+		}
+	}
+	if len(hidden) == 0 {
+		return events
+	}
+
+	visible := make([]*model.CalendarEvent, 0, len(events))
+	for _, event := range events {
+		if hidden[event.CalendarID] && event.ResponseStatus == model.EventAttendeeStatusDeclined {
+			continue
+		}
+		visible = append(visible, event)
+	}
+	return visible
+}
+
+// attendeesChanged checks whether the attendees of an event differ, regardless of their order
+func attendeesChanged(existing, incoming []*model.EventAttendee) bool {
+	if len(existing) != len(incoming) {
+		return true
+	}
+
+	byEmail := make(map[string]*model.EventAttendee, len(existing))
+	for _, attendee := range existing {
+		byEmail[strings.ToLower(attendee.Email)] = attendee
+	}
+
+	for _, attendee := range incoming {
+		old, ok := byEmail[strings.ToLower(attendee.Email)]
+		if !ok ||
+			old.DisplayName != attendee.DisplayName ||
+			old.Role != attendee.Role ||
+			old.Status != attendee.Status ||
+			old.Organizer != attendee.Organizer ||
+			old.Self != attendee.Self {
+			return true
+		}
+	}
+	return false
+}
+
+// copyEventAttendees copies attendees for another event, such as an occurrence of a recurring event
+func copyEventAttendees(attendees []*model.EventAttendee) []*model.EventAttendee {
+	if attendees == nil {
+		return nil
+	}
+
+	copied := make([]*model.EventAttendee, 0, len(attendees))
+	for _, attendee := range attendees {
+		attendeeCopy := *attendee
+		attendeeCopy.ID = utils.GenerateID()
+		attendeeCopy.EventID = 0
+		copied = append(copied, &attendeeCopy)
+	}
+	return copied
+}
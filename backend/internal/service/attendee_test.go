@@ -0,0 +1,202 @@
+package service
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/NathanWasTaken/timely/backend/internal/model"
+	"github.com/NathanWasTaken/timely/backend/pkg/utils"
+)
+
+// ownerEvents returns the events of a calendar as listed to its owner in July 2024, by source ID
+func ownerEvents(t *testing.T, calendarService *CalendarService, userID, calendarID uint64) map[string]*model.CalendarEvent {
+	t.Helper()
+
+	start := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+	calendars, err := calendarService.GetUserCalendarEvents(userID, start, start.AddDate(0, 1, 0))
+	if err != nil {
+		t.Fatalf("GetUserCalendarEvents failed: %v", err)
+	}
+
+	events := make(map[string]*model.CalendarEvent)
+	for _, calendar := range calendars {
+		if calendar.ID != calendarID {
+			continue
+		}
+		for _, event := range calendar.Events {
+			events[event.SourceID] = event
+		}
+	}
+	return events
+}
+
+func TestGoogleEventAttendees(t *testing.T) {
+	calendarService, _ := newTestCalendarService(t)
+	google, server := newGoogleStandIn(t)
+	calendar := newGoogleTestCalendar(t, calendarService, server.URL)
+	calendar.FreeBusy = true
+	calendar.Visibility = model.CalendarVisibilityPublic
+	if err := calendarService.calendarRepo.Update(calendar); err != nil {
+		t.Fatalf("Failed to update calendar: %v", err)
+	}
+
+	invite := func(status string) {
+		t.Helper()
+		google.edit("planning", "Planning")
+		google.mu.Lock()
+		event := google.events["planning"]
+		event.Organizer = &model.GoogleCalendarEventActor{Email: "alex@example.com", DisplayName: "Alex"}
+		event.Attendees = []*model.GoogleCalendarEventActor{
+			{Email: "alex@example.com", DisplayName: "Alex", Organizer: true, ResponseStatus: "accepted"},
+			{Email: "robin@example.com", Self: true, ResponseStatus: status},
+			{Email: "kim@example.com", Optional: true, ResponseStatus: "tentative"},
+		}
+		google.mu.Unlock()
+		syncGoogleTestCalendar(t, calendarService, calendar)
+	}
+
+	// Events without guests only list the calendar as the organizer, which is left out
+	google.edit("focus", "Focus time")
+	google.mu.Lock()
+	google.events["focus"].Organizer = &model.GoogleCalendarEventActor{Email: "robin@example.com", Self: true}
+	google.mu.Unlock()
+	invite("needsAction")
+
+	events := ownerEvents(t, calendarService, calendar.UserID, calendar.ID)
+	if focus := events["focus"]; focus == nil || len(focus.Attendees) != 0 || focus.ResponseStatus != "" {
+		t.Errorf("Expected an event without attendees, got %+v", focus)
+	}
+	planning := events["planning"]
+	if planning == nil || len(planning.Attendees) != 3 {
+		t.Fatalf("Expected the event with 3 attendees, got %+v", planning)
+	}
+	if planning.ResponseStatus != model.EventAttendeeStatusNeedsAction {
+		t.Errorf("Expected the owner not to have responded, got %q", planning.ResponseStatus)
+	}
+	byEmail := make(map[string]*model.EventAttendee)
+	for _, attendee := range planning.Attendees {
+		byEmail[attendee.Email] = attendee
+	}
+	if alex := byEmail["alex@example.com"]; alex == nil || !alex.Organizer || alex.DisplayName != "Alex" || alex.Status != model.EventAttendeeStatusAccepted {
+		t.Errorf("Expected Alex to be the organizer, got %+v", alex)
+	}
+	if kim := byEmail["kim@example.com"]; kim == nil || kim.Role != model.EventAttendeeRoleOptional || kim.Status != model.EventAttendeeStatusTentative {
+		t.Errorf("Expected Kim to be an optional attendee, got %+v", kim)
+	}
+
+	// Declining updates the stored response and replaces the attendees
+	invite("declined")
+	event, err := calendarService.calendarRepo.FindEventByID(fmt.Sprint(planning.ID))
+	if err != nil {
+		t.Fatalf("FindEventByID failed: %v", err)
+	}
+	if event.ResponseStatus != model.EventAttendeeStatusDeclined || len(event.Attendees) != 3 {
+		t.Errorf("Expected the declined event with its 3 attendees, got %+v", event)
+	}
+
+	// Visitors see neither the guests nor the owner's response
+	start := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+	calendars, err := calendarService.GetPublicUserCalendarEvents(calendar.UserID, start, start.AddDate(0, 1, 0))
+	if err != nil || len(calendars) != 1 || len(calendars[0].Events) != 2 {
+		t.Fatalf("Expected the public events of the calendar, got %+v, %v", calendars, err)
+	}
+	for _, event := range calendars[0].Events {
+		if event.ResponseStatus != "" || event.Attendees != nil {
+			t.Errorf("Expected %s to be shown without responses, got %q and %d attendees", event.SourceID, event.ResponseStatus, len(event.Attendees))
+		}
+	}
+
+	// Declined events are only hidden when the calendar asks for it
+	if busy, _ := calendarService.GetUserFreeBusy(calendar.UserID, start, start.AddDate(0, 0, 7)); len(busy) != 1 {
+		t.Errorf("Expected the declined event to be busy, got %d intervals", len(busy))
+	}
+
+	hideDeclined := true
+	if _, err := calendarService.UpdateCalendar(calendar.UserID, fmt.Sprint(calendar.ID), &model.CalendarUpdateRequest{HideDeclined: &hideDeclined}); err != nil {
+		t.Fatalf("UpdateCalendar failed: %v", err)
+	}
+	events = ownerEvents(t, calendarService, calendar.UserID, calendar.ID)
+	if _, ok := events["planning"]; ok || events["focus"] == nil {
+		t.Errorf("Expected only the declined event to be hidden, got %v", events)
+	}
+	if busy, _ := calendarService.GetUserFreeBusy(calendar.UserID, start, start.AddDate(0, 0, 7)); len(busy) != 1 || !busy[0].End.Equal(start.Add(9*time.Hour+30*time.Minute)) {
+		t.Errorf("Expected only the focus time to be busy, got %+v", busy)
+	}
+}
+
+func TestICSEventAttendees(t *testing.T) {
+	calendarService, calendarRepo := newTestCalendarService(t)
+
+	userID := utils.GenerateID()
+	email := "sam@example.com"
+	if err := calendarService.userRepo.CreateAccount(&model.Account{ID: utils.GenerateID(), UserID: userID, Provider: "google", ProviderID: "sam", Email: &email}); err != nil {
+		t.Fatalf("Failed to create account: %v", err)
+	}
+
+	weekly := "BEGIN:VEVENT\r\nUID:review\r\nSUMMARY:Review\r\nDTSTART:20240701T100000Z\r\nDTEND:20240701T110000Z\r\n" +
+		"RRULE:FREQ=WEEKLY;COUNT=2\r\n" +
+		"ORGANIZER;CN=Alex:mailto:alex@example.com\r\n" +
+		"ATTENDEE;CN=Alex;ROLE=CHAIR;PARTSTAT=ACCEPTED:mailto:alex@example.com\r\n" +
+		"ATTENDEE;ROLE=OPT-PARTICIPANT;PARTSTAT=TENTATIVE:MAILTO:Sam@Example.com\r\n" +
+		"ATTENDEE;ROLE=NON-PARTICIPANT:mailto:room@example.com\r\n" +
+		"END:VEVENT\r\n"
+	forwarded := "BEGIN:VEVENT\r\nUID:offsite\r\nSUMMARY:Offsite\r\nDTSTART:20240705T090000Z\r\nDTEND:20240705T170000Z\r\n" +
+		"ORGANIZER;CN=Kim:mailto:kim@example.com\r\nEND:VEVENT\r\n"
+
+	calendar, _, err := calendarService.ImportICSCalendar(userID, "Team", nil, parseTestICS(t, buildICSFeed(weekly, forwarded)))
+	if err != nil {
+		t.Fatalf("ImportICSCalendar failed: %v", err)
+	}
+
+	events, _ := calendarRepo.FindEventsByCalendarID(calendar.ID)
+	if err := calendarRepo.LoadEventAttendees(events); err != nil {
+		t.Fatalf("LoadEventAttendees failed: %v", err)
+	}
+	if len(events) != 3 {
+		t.Fatalf("Expected 2 occurrences and 1 single event, got %d events", len(events))
+	}
+
+	for _, event := range events {
+		byEmail := make(map[string]*model.EventAttendee)
+		for _, attendee := range event.Attendees {
+			byEmail[attendee.Email] = attendee
+		}
+
+		if event.RecurringEventID != "review" {
+			organizer := byEmail["kim@example.com"]
+			if len(event.Attendees) != 1 || organizer == nil || !organizer.Organizer || organizer.DisplayName != "Kim" {
+				t.Errorf("Expected the organizer to be stored on its own, got %+v", event.Attendees)
+			}
+			if event.ResponseStatus != "" {
+				t.Errorf("Expected no response of the owner, got %q", event.ResponseStatus)
+			}
+			continue
+		}
+
+		if len(event.Attendees) != 3 {
+			t.Errorf("Expected the organizer merged with its attendee, got %d attendees", len(event.Attendees))
+		}
+		if alex := byEmail["alex@example.com"]; alex == nil || !alex.Organizer || alex.Role != model.EventAttendeeRoleChair {
+			t.Errorf("Expected Alex to chair the event, got %+v", alex)
+		}
+		if sam := byEmail["Sam@Example.com"]; sam == nil || !sam.Self || sam.Role != model.EventAttendeeRoleOptional {
+			t.Errorf("Expected the owner to be matched by email, got %+v", sam)
+		}
+		if room := byEmail["room@example.com"]; room == nil || room.Role != model.EventAttendeeRoleNonParticipant || room.Status != model.EventAttendeeStatusNeedsAction {
+			t.Errorf("Expected the room to default to needs action, got %+v", room)
+		}
+		if event.ResponseStatus != model.EventAttendeeStatusTentative {
+			t.Errorf("Expected the owner's tentative response, got %q", event.ResponseStatus)
+		}
+	}
+
+	// Unchanged attendees do not update the events on re-import
+	_, _, result, err := calendarService.ReimportICSCalendar(userID, fmt.Sprint(calendar.ID), "", nil, parseTestICS(t, buildICSFeed(weekly, forwarded)))
+	if err != nil {
+		t.Fatalf("ReimportICSCalendar failed: %v", err)
+	}
+	if result.Updated != 0 {
+		t.Errorf("Expected no event to change, got %+v", result)
+	}
+}
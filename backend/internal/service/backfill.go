@@ -0,0 +1,365 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/NathanWasTaken/timely/backend/internal/model"
+	"github.com/NathanWasTaken/timely/backend/pkg/utils"
+)
+
+const (
+	// backfillChunkDays is the length of the chunks a backfill fetches one after another
+	backfillChunkDays = 30
+	// backfillCheckInterval is how often the worker looks for backfill jobs to run
+	backfillCheckInterval = 10 * time.Second
+	// backfillLeaseDuration bounds how long a job stays claimed by a worker that stopped without
+	// releasing it, the lease is extended after every page
+	backfillLeaseDuration = 5 * time.Minute
+	// backfillMaxPageAttempts bounds how often a page is fetched before the job fails
+	backfillMaxPageAttempts = 3
+)
+
+var (
+	// backfillRetryDelay is the delay before fetching a failed page again, multiplied by the
+	// number of failed attempts
+	backfillRetryDelay = 10 * time.Second
+	// backfillBusyDelay is the delay before trying again to fetch a page of a calendar that is
+	// being synced
+	backfillBusyDelay = 5 * time.Second
+)
+
+// errCalendarBusy is returned when the sync lease of a calendar cannot be claimed because it is
+// being synced, such as when fetching a backfill page or resolving a write conflict
+var errCalendarBusy = errors.New("calendar is being synced")
+
+// CreateBackfillJob queues a job fetching the events of a Google calendar between two dates, both
+// included, in the time zone of the calendar. calendarID is the Google calendar ID.
+func (s *CalendarService) CreateBackfillJob(userID uint64, calendarID string, startDate, endDate time.Time) (*model.BackfillJob, error) {
+	calendar, err := s.calendarRepo.FindByUserIDAndSourceID(userID, calendarID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find local calendar: %w", err)
+	}
+
+	if calendar.Source != model.SourceGoogle {
+		return nil, fmt.Errorf("calendar is not a Google calendar")
+	}
+
+	active, err := s.calendarRepo.ExistsActiveBackfillJob(calendar.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check backfill jobs: %w", err)
+	}
+	if active {
+		return nil, fmt.Errorf("backfill already running")
+	}
+
+	loc := calendarLocation(calendar)
+	timeMin := time.Date(startDate.Year(), startDate.Month(), startDate.Day(), 0, 0, 0, 0, loc)
+	timeMax := time.Date(endDate.Year(), endDate.Month(), endDate.Day()+1, 0, 0, 0, 0, loc)
+
+	job := &model.BackfillJob{
+		ID:          utils.GenerateID(),
+		UserID:      userID,
+		CalendarID:  calendar.ID,
+		Status:      model.BackfillJobStatusPending,
+		StartDate:   startDate.Format("2006-01-02"),
+		EndDate:     endDate.Format("2006-01-02"),
+		TimeMin:     timeMin,
+		TimeMax:     timeMax,
+		Cursor:      timeMin,
+		ChunksTotal: backfillChunkCount(timeMin, timeMax),
+	}
+
+	if err := s.calendarRepo.CreateBackfillJob(job); err != nil {
+		return nil, fmt.Errorf("failed to create backfill job: %w", err)
+	}
+
+	s.logger.Info("Queued backfill job",
+		zap.Uint64("user_id", userID),
+		zap.Uint64("calendar_id", calendar.ID),
+		zap.Uint64("job_id", job.ID),
+		zap.String("start_date", job.StartDate),
+		zap.String("end_date", job.EndDate))
+
+	return job, nil
+}
+
+// GetBackfillJobs returns the backfill jobs of a user, the most recent first
+func (s *CalendarService) GetBackfillJobs(userID uint64) ([]*model.BackfillJob, error) {
+	jobs, err := s.calendarRepo.FindBackfillJobsByUserID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get backfill jobs: %w", err)
+	}
+	return jobs, nil
+}
+
+// GetBackfillJob returns a backfill job of a user with its progress
+func (s *CalendarService) GetBackfillJob(userID uint64, jobID string) (*model.BackfillJob, error) {
+	job, err := s.calendarRepo.FindBackfillJobByID(jobID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("backfill job not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find backfill job: %w", err)
+	}
+
+	if job.UserID != userID {
+		return nil, fmt.Errorf("backfill job not found")
+	}
+
+	return job, nil
+}
+
+// CancelBackfillJob cancels a backfill job that has not finished yet. A running job stops after the
+// page it is fetching, the events stored until then are kept.
+func (s *CalendarService) CancelBackfillJob(userID uint64, jobID string) (*model.BackfillJob, error) {
+	job, err := s.GetBackfillJob(userID, jobID)
+	if err != nil {
+		return nil, err
+	}
+
+	cancelled, err := s.calendarRepo.CancelBackfillJob(job.ID, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("failed to cancel backfill job: %w", err)
+	}
+	if !cancelled {
+		return nil, fmt.Errorf("backfill job already finished")
+	}
+
+	s.logger.Info("Cancelled backfill job",
+		zap.Uint64("user_id", userID),
+		zap.Uint64("job_id", job.ID))
+
+	return s.GetBackfillJob(userID, jobID)
+}
+
+// RunBackfillWorker runs queued backfill jobs one after another until ctx is cancelled. Jobs hold a
+// lease while they run, so a job left behind by a stopped worker is picked up again.
+func (s *CalendarService) RunBackfillWorker(ctx context.Context) {
+	s.logger.Info("Starting backfill worker")
+
+	ticker := time.NewTicker(backfillCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		s.runClaimableBackfillJobs(ctx)
+
+		select {
+		case <-ctx.Done():
+			s.logger.Info("Stopping backfill worker")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// runClaimableBackfillJobs runs backfill jobs until none is left to claim
+func (s *CalendarService) runClaimableBackfillJobs(ctx context.Context) {
+	for ctx.Err() == nil {
+		jobs, err := s.calendarRepo.FindClaimableBackfillJobs(time.Now(), 1)
+		if err != nil {
+			s.logger.Error("Failed to find backfill jobs", zap.Error(err))
+			return
+		}
+		if len(jobs) == 0 {
+			return
+		}
+
+		job := jobs[0]
+		now := time.Now()
+		claimed, err := s.calendarRepo.ClaimBackfillJob(job.ID, now, now.Add(backfillLeaseDuration))
+		if err != nil {
+			s.logger.Error("Failed to claim backfill job", zap.Error(err), zap.Uint64("job_id", job.ID))
+			return
+		}
+		if !claimed {
+			continue
+		}
+
+		job.Status = model.BackfillJobStatusRunning
+		s.runBackfillJob(ctx, job)
+	}
+}
+
+// runBackfillJob fetches the remaining pages of a claimed backfill job, storing its progress after
+// every page. The job stops when it is cancelled, and fails once a page could not be fetched
+// backfillMaxPageAttempts times in a row.
+func (s *CalendarService) runBackfillJob(ctx context.Context, job *model.BackfillJob) {
+	calendar, err := s.calendarRepo.FindByID(strconv.FormatUint(job.CalendarID, 10))
+	if err != nil {
+		s.finishBackfillJob(job, fmt.Errorf("failed to find calendar: %w", err))
+		return
+	}
+
+	if job.StartedAt == nil {
+		now := time.Now()
+		job.StartedAt = &now
+	}
+
+	s.logger.Info("Running backfill job",
+		zap.Uint64("job_id", job.ID),
+		zap.Uint64("calendar_id", calendar.ID),
+		zap.Time("cursor", job.Cursor))
+
+	failures := 0
+	for job.Cursor.Before(job.TimeMax) {
+		if ctx.Err() != nil {
+			// Releasing the lease lets the job continue as soon as a worker runs again
+			s.saveBackfillProgress(job, time.Now())
+			return
+		}
+
+		err := s.fetchBackfillPage(ctx, calendar, job)
+		delay := time.Duration(0)
+		switch {
+		case errors.Is(err, errCalendarBusy):
+			delay = backfillBusyDelay
+		case err != nil:
+			failures++
+			job.Errors++
+			lastError := err.Error()
+			job.LastError = &lastError
+
+			if failures >= backfillMaxPageAttempts {
+				s.finishBackfillJob(job, err)
+				return
+			}
+
+			s.logger.Warn("Failed to fetch backfill page, retrying",
+				zap.Error(err),
+				zap.Uint64("job_id", job.ID),
+				zap.Int("attempt", failures))
+
+			// Page tokens may not outlive the failure, the chunk is fetched again from its first page
+			job.PageToken = ""
+			delay = backfillRetryDelay * time.Duration(failures)
+		default:
+			failures = 0
+		}
+
+		if !s.saveBackfillProgress(job, time.Now().Add(backfillLeaseDuration)) {
+			s.logger.Info("Backfill job was cancelled", zap.Uint64("job_id", job.ID))
+			return
+		}
+
+		if delay > 0 {
+			select {
+			case <-ctx.Done():
+			case <-time.After(delay):
+			}
+		}
+	}
+
+	s.finishBackfillJob(job, nil)
+}
+
+// fetchBackfillPage fetches the next page of a backfill job and merges its events into the calendar.
+// The sync lease is held meanwhile, so the page cannot overwrite newer versions of its events
+// stored by a sync running at the same time.
+func (s *CalendarService) fetchBackfillPage(ctx context.Context, calendar *model.Calendar, job *model.BackfillJob) error {
+	if calendar.SourceID == nil {
+		return fmt.Errorf("calendar has no Google calendar ID")
+	}
+
+	lease, err := s.claimSyncLease(calendar.ID, s.calendarRepo.ClaimSyncLease)
+	if err != nil {
+		return err
+	}
+	// The background schedule stays as it is
+	defer s.releaseSyncLease(lease, nil)
+
+	credentials, err := s.google.RefreshCredentials(ctx, calendar)
+	if err != nil {
+		return err
+	}
+
+	chunkEnd := job.Cursor.AddDate(0, 0, backfillChunkDays)
+	if chunkEnd.After(job.TimeMax) {
+		chunkEnd = job.TimeMax
+	}
+
+	response, err := s.google.fetchEventsWindowPage(ctx, credentials.AccessToken, *calendar.SourceID, job.Cursor, chunkEnd, job.PageToken)
+	if err != nil {
+		return fmt.Errorf("failed to fetch events from Google: %w", err)
+	}
+
+	// Events outside of the page are left alone, only events Google reports as deleted are removed
+	fetched := &ProviderEvents{}
+	eventColors := s.google.eventColors(ctx, credentials.AccessToken, calendar.UserID)
+	fetched.Events, fetched.Deleted = s.google.convertGoogleEvents(response.Items, calendar.ID, eventColors)
+
+	result, err := s.mergeEvents(calendar, fetched)
+	if err != nil {
+		return err
+	}
+
+	job.PagesFetched++
+	job.EventsStored += result.Added + result.Updated
+	if response.NextPageToken != "" {
+		job.PageToken = response.NextPageToken
+	} else {
+		job.PageToken = ""
+		job.Cursor = chunkEnd
+		job.ChunksDone++
+	}
+
+	return nil
+}
+
+// saveBackfillProgress stores the progress of a running backfill job and extends its lease until
+// the given time. It returns false if the job was cancelled.
+func (s *CalendarService) saveBackfillProgress(job *model.BackfillJob, until time.Time) bool {
+	running, err := s.calendarRepo.UpdateBackfillJobProgress(job, until)
+	if err != nil {
+		// The job continues, at worst it fetches some pages again after a restart
+		s.logger.Error("Failed to save backfill progress", zap.Error(err), zap.Uint64("job_id", job.ID))
+		return true
+	}
+	return running
+}
+
+// finishBackfillJob records that a backfill job completed, or failed with err
+func (s *CalendarService) finishBackfillJob(job *model.BackfillJob, err error) {
+	now := time.Now()
+	job.FinishedAt = &now
+	job.Status = model.BackfillJobStatusCompleted
+	if err != nil {
+		job.Status = model.BackfillJobStatusFailed
+		lastError := err.Error()
+		job.LastError = &lastError
+	}
+
+	if updateErr := s.calendarRepo.FinishBackfillJob(job); updateErr != nil {
+		s.logger.Error("Failed to finish backfill job", zap.Error(updateErr), zap.Uint64("job_id", job.ID))
+		return
+	}
+
+	if err != nil {
+		s.logger.Warn("Backfill job failed",
+			zap.Error(err),
+			zap.Uint64("job_id", job.ID),
+			zap.Uint64("calendar_id", job.CalendarID))
+		return
+	}
+
+	s.logger.Info("Backfill job completed",
+		zap.Uint64("job_id", job.ID),
+		zap.Uint64("calendar_id", job.CalendarID),
+		zap.Int("pages_fetched", job.PagesFetched),
+		zap.Int("events_stored", job.EventsStored))
+}
+
+// backfillChunkCount returns the number of chunks a backfill between timeMin and timeMax is fetched in
+func backfillChunkCount(timeMin, timeMax time.Time) int {
+	chunks := 0
+	for cursor := timeMin; cursor.Before(timeMax); cursor = cursor.AddDate(0, 0, backfillChunkDays) {
+		chunks++
+	}
+	return chunks
+}
@@ -0,0 +1,168 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/NathanWasTaken/timely/backend/internal/model"
+	"github.com/NathanWasTaken/timely/backend/pkg/utils"
+)
+
+// newBackfillStandIn creates a stand-in serving the events within the requested window, two per page
+func newBackfillStandIn(t *testing.T) (*googleStandIn, *httptest.Server) {
+	t.Helper()
+
+	google, server := newGoogleStandIn(t)
+	google.pageSize = 2
+	google.window = true
+	return google, server
+}
+
+// createBackfillTestJob queues a backfill of the calendar between two dates
+func createBackfillTestJob(t *testing.T, calendarService *CalendarService, calendar *model.Calendar, startDate, endDate string) *model.BackfillJob {
+	t.Helper()
+
+	start, _ := time.Parse("2006-01-02", startDate)
+	end, _ := time.Parse("2006-01-02", endDate)
+	job, err := calendarService.CreateBackfillJob(calendar.UserID, *calendar.SourceID, start, end)
+	if err != nil {
+		t.Fatalf("CreateBackfillJob failed: %v", err)
+	}
+	return job
+}
+
+func TestBackfillJob(t *testing.T) {
+	calendarService, calendarRepo := newTestCalendarService(t)
+	google, server := newBackfillStandIn(t)
+	calendar := newGoogleTestCalendar(t, calendarService, server.URL)
+
+	// Events kept up to date by sync are merged with, not duplicated
+	calendarRepo.CreateEvents([]*model.CalendarEvent{
+		{ID: utils.GenerateID(), CalendarID: calendar.ID, SourceID: "review", Title: "Review", SourceETag: `"old"`,
+			Start: time.Date(2022, 1, 20, 9, 0, 0, 0, time.UTC), End: time.Date(2022, 1, 20, 10, 0, 0, 0, time.UTC)},
+		{ID: utils.GenerateID(), CalendarID: calendar.ID, SourceID: "offsite", Title: "Offsite",
+			Start: time.Date(2022, 2, 10, 9, 0, 0, 0, time.UTC), End: time.Date(2022, 2, 10, 10, 0, 0, 0, time.UTC)},
+		{ID: utils.GenerateID(), CalendarID: calendar.ID, SourceID: "today", Title: "Today",
+			Start: time.Now(), End: time.Now().Add(time.Hour)},
+	})
+
+	google.add("kickoff", "Kickoff", "2022-01-03T09:00:00Z", "confirmed")
+	google.add("planning", "Planning", "2022-01-10T09:00:00Z", "confirmed")
+	google.add("review", "Quarterly review", "2022-01-20T09:00:00Z", "confirmed")
+	google.add("offsite", "Offsite", "2022-02-10T09:00:00Z", "cancelled")
+	google.add("retro", "Retro", "2022-03-15T09:00:00Z", "confirmed")
+	google.add("later", "Later", "2022-06-01T09:00:00Z", "confirmed")
+
+	job := createBackfillTestJob(t, calendarService, calendar, "2022-01-01", "2022-03-31")
+	if job.Status != model.BackfillJobStatusPending || job.ChunksTotal != 3 {
+		t.Fatalf("Expected a pending job of 3 chunks, got %+v", job)
+	}
+
+	// One backfill per calendar at a time
+	start, _ := time.Parse("2006-01-02", "2022-04-01")
+	if _, err := calendarService.CreateBackfillJob(calendar.UserID, *calendar.SourceID, start, start); err == nil || err.Error() != "backfill already running" {
+		t.Errorf("Expected a second backfill to be rejected, got %v", err)
+	}
+
+	calendarService.runClaimableBackfillJobs(context.Background())
+
+	job, err := calendarService.GetBackfillJob(calendar.UserID, fmt.Sprint(job.ID))
+	if err != nil {
+		t.Fatalf("GetBackfillJob failed: %v", err)
+	}
+	if job.Status != model.BackfillJobStatusCompleted || job.FinishedAt == nil {
+		t.Fatalf("Expected the job to complete, got %+v", job)
+	}
+	// January spans two pages, February and March one each
+	if job.ChunksDone != 3 || job.PagesFetched != 4 || job.EventsStored != 4 || job.Errors != 0 {
+		t.Errorf("Unexpected progress %+v", job)
+	}
+
+	titles := eventTitles(t, calendarService, calendar.ID)
+	expected := map[string]string{"kickoff": "Kickoff", "planning": "Planning", "review": "Quarterly review", "retro": "Retro", "today": "Today"}
+	if len(titles) != len(expected) {
+		t.Errorf("Expected events %v, got %v", expected, titles)
+	}
+	for sourceID, title := range expected {
+		if titles[sourceID] != title {
+			t.Errorf("Expected %s to be %q, got %q", sourceID, title, titles[sourceID])
+		}
+	}
+
+	// The sync lease is released once the job is done
+	if claimed, err := calendarRepo.ClaimSyncLease(calendar.ID, "test", time.Now(), time.Now().Add(time.Minute)); err != nil || !claimed {
+		t.Errorf("Expected the sync lease to be released, got %v, %v", claimed, err)
+	}
+
+	// Jobs are private to their user
+	if _, err := calendarService.GetBackfillJob(calendar.UserID+1, fmt.Sprint(job.ID)); err == nil || err.Error() != "backfill job not found" {
+		t.Errorf("Expected the job to be hidden from other users, got %v", err)
+	}
+	if _, err := calendarService.CancelBackfillJob(calendar.UserID, fmt.Sprint(job.ID)); err == nil || err.Error() != "backfill job already finished" {
+		t.Errorf("Expected cancelling a finished job to fail, got %v", err)
+	}
+}
+
+func TestBackfillJobRetriesFailedPages(t *testing.T) {
+	calendarService, _ := newTestCalendarService(t)
+	google, server := newBackfillStandIn(t)
+	calendar := newGoogleTestCalendar(t, calendarService, server.URL)
+
+	retryDelay := backfillRetryDelay
+	backfillRetryDelay = 0
+	t.Cleanup(func() { backfillRetryDelay = retryDelay })
+
+	google.failFirst = true
+	google.add("kickoff", "Kickoff", "2022-01-03T09:00:00Z", "confirmed")
+
+	job := createBackfillTestJob(t, calendarService, calendar, "2022-01-01", "2022-01-31")
+	calendarService.runClaimableBackfillJobs(context.Background())
+
+	job, _ = calendarService.GetBackfillJob(calendar.UserID, fmt.Sprint(job.ID))
+	if job.Status != model.BackfillJobStatusCompleted || job.Errors != 1 || job.LastError == nil || job.EventsStored != 1 {
+		t.Errorf("Expected the failed page to be retried, got %+v", job)
+	}
+	if titles := eventTitles(t, calendarService, calendar.ID); titles["kickoff"] != "Kickoff" {
+		t.Errorf("Expected the event to be stored, got %v", titles)
+	}
+}
+
+func TestBackfillJobCancel(t *testing.T) {
+	calendarService, _ := newTestCalendarService(t)
+	google, server := newBackfillStandIn(t)
+	calendar := newGoogleTestCalendar(t, calendarService, server.URL)
+
+	google.add("kickoff", "Kickoff", "2022-01-03T09:00:00Z", "confirmed")
+	google.add("retro", "Retro", "2022-03-15T09:00:00Z", "confirmed")
+
+	// A pending job is cancelled before it runs
+	job := createBackfillTestJob(t, calendarService, calendar, "2022-01-01", "2022-03-31")
+	cancelled, err := calendarService.CancelBackfillJob(calendar.UserID, fmt.Sprint(job.ID))
+	if err != nil || cancelled.Status != model.BackfillJobStatusCancelled {
+		t.Fatalf("Expected the job to be cancelled, got %+v, %v", cancelled, err)
+	}
+	calendarService.runClaimableBackfillJobs(context.Background())
+	if titles := eventTitles(t, calendarService, calendar.ID); len(titles) != 0 {
+		t.Errorf("Expected a cancelled job not to run, got %v", titles)
+	}
+
+	// A running job stops after the page it is fetching
+	job = createBackfillTestJob(t, calendarService, calendar, "2022-01-01", "2022-03-31")
+	google.onRequest = func(n int) {
+		if n == 1 {
+			calendarService.CancelBackfillJob(calendar.UserID, fmt.Sprint(job.ID))
+		}
+	}
+	calendarService.runClaimableBackfillJobs(context.Background())
+
+	job, _ = calendarService.GetBackfillJob(calendar.UserID, fmt.Sprint(job.ID))
+	if job.Status != model.BackfillJobStatusCancelled || google.requests != 1 {
+		t.Errorf("Expected the job to stop after its first page, got %+v after %d requests", job, google.requests)
+	}
+	if titles := eventTitles(t, calendarService, calendar.ID); len(titles) != 1 || titles["kickoff"] != "Kickoff" {
+		t.Errorf("Expected the events of the first page to be kept, got %v", titles)
+	}
+}
@@ -0,0 +1,484 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	ics "github.com/arran4/golang-ical"
+	"go.uber.org/zap"
+
+	"github.com/NathanWasTaken/timely/backend/internal/model"
+	"github.com/NathanWasTaken/timely/backend/internal/repository"
+	"github.com/NathanWasTaken/timely/backend/pkg/utils"
+)
+
+const (
+	// maxBookingDurationMinutes is the longest booking a booking type can offer
+	maxBookingDurationMinutes = 24 * 60
+	// maxBookingBufferMinutes limits buffers and minimum notice to a sensible range
+	maxBookingBufferMinutes = 30 * 24 * 60
+)
+
+// bookingSlugPattern matches the slugs of booking pages
+var bookingSlugPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// errSlotNotAvailable is returned when the requested slot of a booking is not open
+var errSlotNotAvailable = errors.New("slot is not available")
+
+// BookingService manages booking types and the bookings visitors make on a user's booking pages
+type BookingService struct {
+	bookingRepo  *repository.BookingRepository
+	calendarRepo *repository.CalendarRepository
+	logger       *zap.Logger
+}
+
+func NewBookingService(bookingRepo *repository.BookingRepository, calendarRepo *repository.CalendarRepository) *BookingService {
+	return &BookingService{
+		bookingRepo:  bookingRepo,
+		calendarRepo: calendarRepo,
+		logger:       zap.L(),
+	}
+}
+
+// ListBookingTypes retrieves all booking types of a user
+func (s *BookingService) ListBookingTypes(userID uint64) ([]*model.BookingType, error) {
+	bookingTypes, err := s.bookingRepo.FindBookingTypesByUserID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get booking types: %w", err)
+	}
+	return bookingTypes, nil
+}
+
+// CreateBookingType creates a booking type for a user
+func (s *BookingService) CreateBookingType(userID uint64, bookingTypeRequest *model.BookingTypeRequest) (*model.BookingType, error) {
+	bookingType := &model.BookingType{
+		ID:     utils.GenerateID(),
+		UserID: userID,
+	}
+
+	if err := s.applyBookingTypeRequest(bookingType, bookingTypeRequest); err != nil {
+		return nil, err
+	}
+
+	if err := s.bookingRepo.CreateBookingType(bookingType); err != nil {
+		return nil, fmt.Errorf("failed to create booking type: %w", err)
+	}
+
+	s.logger.Info("Created booking type",
+		zap.Uint64("user_id", userID),
+		zap.Uint64("booking_type_id", bookingType.ID),
+		zap.String("slug", bookingType.Slug))
+
+	return bookingType, nil
+}
+
+// UpdateBookingType replaces a booking type of a user
+func (s *BookingService) UpdateBookingType(userID uint64, bookingTypeID string, bookingTypeRequest *model.BookingTypeRequest) (*model.BookingType, error) {
+	bookingType, err := s.findOwnedBookingType(userID, bookingTypeID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.applyBookingTypeRequest(bookingType, bookingTypeRequest); err != nil {
+		return nil, err
+	}
+
+	if err := s.bookingRepo.UpdateBookingType(bookingType); err != nil {
+		return nil, fmt.Errorf("failed to update booking type: %w", err)
+	}
+
+	s.logger.Info("Updated booking type",
+		zap.Uint64("user_id", userID),
+		zap.Uint64("booking_type_id", bookingType.ID))
+
+	return bookingType, nil
+}
+
+// DeleteBookingType deletes a booking type of a user. Events of bookings already made are kept.
+func (s *BookingService) DeleteBookingType(userID uint64, bookingTypeID string) error {
+	bookingType, err := s.findOwnedBookingType(userID, bookingTypeID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.bookingRepo.DeleteBookingType(bookingTypeID); err != nil {
+		return fmt.Errorf("failed to delete booking type: %w", err)
+	}
+
+	s.logger.Info("Deleted booking type",
+		zap.Uint64("user_id", userID),
+		zap.Uint64("booking_type_id", bookingType.ID))
+
+	return nil
+}
+
+// GetBookingSlots returns the public booking page of a user's booking type and its open slots
+// within a time range
+func (s *BookingService) GetBookingSlots(userID uint64, slug string, startTime, endTime time.Time) (*model.BookingPage, []*model.BookingSlot, error) {
+	if err := validateEventTimeRange(startTime, endTime); err != nil {
+		return nil, nil, err
+	}
+
+	bookingType, err := s.bookingRepo.FindBookingTypeByUserIDAndSlug(userID, slug)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to find booking type: %w", err)
+	}
+
+	slots, err := s.openBookingSlots(s.calendarRepo, bookingType, startTime, endTime)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return bookingPage(bookingType), slots, nil
+}
+
+// CreateBooking books one of the open slots of a user's booking type. The event is created in the
+// booking type's calendar and an ICS confirmation for the visitor is returned along with the booking.
+func (s *BookingService) CreateBooking(user *model.User, slug string, bookingRequest *model.BookingRequest) (*model.Booking, string, error) {
+	bookingType, err := s.bookingRepo.FindBookingTypeByUserIDAndSlug(user.ID, slug)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to find booking type: %w", err)
+	}
+
+	name := strings.TrimSpace(bookingRequest.Name)
+	if name == "" {
+		return nil, "", fmt.Errorf("invalid booking: name is required")
+	}
+
+	address, err := mail.ParseAddress(strings.TrimSpace(bookingRequest.Email))
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid booking: invalid email")
+	}
+
+	start, err := time.Parse(time.RFC3339, strings.TrimSpace(bookingRequest.Start))
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid booking: start must be an RFC 3339 date-time")
+	}
+	end := start.Add(time.Duration(bookingType.DurationMinutes) * time.Minute)
+
+	// The calendar may have been deleted since the booking type was saved
+	if _, err := s.calendarRepo.FindByID(strconv.FormatUint(bookingType.CalendarID, 10)); err != nil {
+		return nil, "", fmt.Errorf("failed to find calendar: %w", err)
+	}
+
+	event := &model.CalendarEvent{
+		ID:          utils.GenerateID(),
+		CalendarID:  bookingType.CalendarID,
+		Title:       fmt.Sprintf("%s with %s", bookingType.Title, name),
+		Start:       start.UTC(),
+		End:         end.UTC(),
+		Description: bookingEventDescription(name, address.Address, bookingRequest.Notes),
+		Visibility:  model.CalendarEventVisibilityPrivate,
+		// Bookings block the slot they were made for
+		Status:       model.CalendarEventStatusConfirmed,
+		Transparency: model.CalendarEventTransparencyOpaque,
+	}
+	event.SourceID = strconv.FormatUint(event.ID, 10)
+
+	booking := &model.Booking{
+		ID:            utils.GenerateID(),
+		UserID:        user.ID,
+		BookingTypeID: bookingType.ID,
+		EventID:       event.ID,
+		Name:          name,
+		Email:         address.Address,
+		Notes:         strings.TrimSpace(bookingRequest.Notes),
+		Start:         event.Start,
+		End:           event.End,
+		CreatedAt:     time.Now(),
+	}
+
+	// The slot is checked within the transaction creating the booking, which holds the lock of the
+	// user's row until the booking is stored, so other bookings of the user wait for it
+	err = s.bookingRepo.CreateBooking(booking, event, func(calendarRepo *repository.CalendarRepository) error {
+		slots, err := s.openBookingSlots(calendarRepo, bookingType, start, end)
+		if err != nil {
+			return err
+		}
+		if len(slots) != 1 || !slots[0].Start.Equal(start) {
+			return errSlotNotAvailable
+		}
+		return nil
+	})
+	if errors.Is(err, errSlotNotAvailable) {
+		return nil, "", err
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create booking: %w", err)
+	}
+
+	s.logger.Info("Created booking",
+		zap.Uint64("user_id", user.ID),
+		zap.Uint64("booking_type_id", bookingType.ID),
+		zap.Uint64("booking_id", booking.ID),
+		zap.Time("start", booking.Start))
+
+	return booking, renderBookingICS(user, bookingType, booking), nil
+}
+
+// openBookingSlots computes the slots of a booking type within a time range that do not conflict
+// with events of its calendars, read through calendarRepo
+func (s *BookingService) openBookingSlots(calendarRepo *repository.CalendarRepository, bookingType *model.BookingType, startTime, endTime time.Time) ([]*model.BookingSlot, error) {
+	loc, err := time.LoadLocation(bookingType.TimeZone)
+	if err != nil {
+		loc = time.UTC
+	}
+
+	calendarIDs := []uint64{bookingType.CalendarID}
+	for _, id := range bookingType.ConflictCalendarIDs {
+		if calendarID, err := strconv.ParseUint(id, 10, 64); err == nil {
+			calendarIDs = append(calendarIDs, calendarID)
+		}
+	}
+
+	// Events just outside the range can still collide with the buffers of slots inside it
+	busyStart := startTime.Add(-time.Duration(bookingType.BufferBeforeMinutes+bookingType.DurationMinutes) * time.Minute)
+	busyEnd := endTime.Add(time.Duration(bookingType.BufferAfterMinutes+bookingType.DurationMinutes) * time.Minute)
+
+	events, err := calendarRepo.FindEventsByCalendarIDsOverlappingTimeRange(calendarIDs, busyStart, busyEnd)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get calendar events: %w", err)
+	}
+
+	// Declined events hidden by their calendar don't block slots, as in the free/busy of the owner
+	calendars, err := calendarRepo.FindByUserID(bookingType.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user calendars: %w", err)
+	}
+	events = withoutHiddenDeclined(calendars, events)
+
+	busy := mergeBusyIntervals(events, busyStart, busyEnd)
+	return bookingSlots(bookingType, loc, busy, startTime, endTime, time.Now()), nil
+}
+
+// bookingSlots computes the slots of a booking type that lie within a time range. Slots follow each
+// other back to back from the start of every availability window, which is evaluated in loc so slots
+// keep their wall clock time across DST changes. Slots starting before the minimum notice or whose
+// buffered time overlaps a busy interval are left out. busy must be sorted and disjoint.
+func bookingSlots(bookingType *model.BookingType, loc *time.Location, busy []*model.FreeBusyInterval, startTime, endTime, now time.Time) []*model.BookingSlot {
+	duration := time.Duration(bookingType.DurationMinutes) * time.Minute
+	bufferBefore := time.Duration(bookingType.BufferBeforeMinutes) * time.Minute
+	bufferAfter := time.Duration(bookingType.BufferAfterMinutes) * time.Minute
+	earliest := now.Add(time.Duration(bookingType.MinimumNoticeMinutes) * time.Minute)
+
+	slots := []*model.BookingSlot{}
+	if duration <= 0 {
+		return slots
+	}
+
+	seen := make(map[int64]bool)
+	first := startTime.In(loc)
+	for day := 0; ; day++ {
+		date := time.Date(first.Year(), first.Month(), first.Day()+day, 0, 0, 0, 0, loc)
+		if !date.Before(endTime) {
+			break
+		}
+
+		for _, rule := range bookingType.Availability {
+			if rule.Weekday != date.Weekday() {
+				continue
+			}
+
+			// Rules are validated when they are saved
+			from, _ := parseClockMinutes(rule.StartTime)
+			to, _ := parseClockMinutes(rule.EndTime)
+			windowStart := time.Date(date.Year(), date.Month(), date.Day(), 0, from, 0, 0, loc)
+			windowEnd := time.Date(date.Year(), date.Month(), date.Day(), 0, to, 0, 0, loc)
+
+			for slotStart := windowStart; !slotStart.Add(duration).After(windowEnd); slotStart = slotStart.Add(duration) {
+				slotEnd := slotStart.Add(duration)
+				if slotStart.Before(startTime) || slotEnd.After(endTime) || slotStart.Before(earliest) || seen[slotStart.Unix()] {
+					continue
+				}
+				if overlapsBusy(busy, slotStart.Add(-bufferBefore), slotEnd.Add(bufferAfter)) {
+					continue
+				}
+
+				seen[slotStart.Unix()] = true
+				slots = append(slots, &model.BookingSlot{Start: slotStart.UTC(), End: slotEnd.UTC()})
+			}
+		}
+	}
+
+	sort.Slice(slots, func(i, j int) bool {
+		return slots[i].Start.Before(slots[j].Start)
+	})
+
+	return slots
+}
+
+// overlapsBusy checks whether a time range overlaps any of the sorted, disjoint busy intervals
+func overlapsBusy(busy []*model.FreeBusyInterval, startTime, endTime time.Time) bool {
+	i := sort.Search(len(busy), func(i int) bool {
+		return busy[i].End.After(startTime)
+	})
+	return i < len(busy) && busy[i].Start.Before(endTime)
+}
+
+// applyBookingTypeRequest validates a booking type request and copies it onto bookingType
+func (s *BookingService) applyBookingTypeRequest(bookingType *model.BookingType, bookingTypeRequest *model.BookingTypeRequest) error {
+	slug := strings.ToLower(strings.TrimSpace(bookingTypeRequest.Slug))
+	if len(slug) > 64 || !bookingSlugPattern.MatchString(slug) {
+		return fmt.Errorf("invalid booking type: slug must consist of lowercase letters, digits and dashes")
+	}
+	if existing, err := s.bookingRepo.FindBookingTypeByUserIDAndSlug(bookingType.UserID, slug); err == nil && existing.ID != bookingType.ID {
+		return fmt.Errorf("invalid booking type: slug %q is already in use", slug)
+	}
+
+	title := strings.TrimSpace(bookingTypeRequest.Title)
+	if title == "" {
+		return fmt.Errorf("invalid booking type: title is required")
+	}
+
+	if bookingTypeRequest.DurationMinutes <= 0 || bookingTypeRequest.DurationMinutes > maxBookingDurationMinutes {
+		return fmt.Errorf("invalid booking type: duration must be between 1 and %d minutes", maxBookingDurationMinutes)
+	}
+	for _, minutes := range []int{bookingTypeRequest.BufferBeforeMinutes, bookingTypeRequest.BufferAfterMinutes, bookingTypeRequest.MinimumNoticeMinutes} {
+		if minutes < 0 || minutes > maxBookingBufferMinutes {
+			return fmt.Errorf("invalid booking type: buffers and minimum notice must be between 0 and %d minutes", maxBookingBufferMinutes)
+		}
+	}
+
+	// Bookings become events, so they can only go into a calendar Timely owns
+	calendar, err := s.calendarRepo.FindByID(bookingTypeRequest.CalendarID)
+	if err != nil || calendar.UserID != bookingType.UserID {
+		return fmt.Errorf("invalid booking type: calendar not found")
+	}
+	if calendar.Source != model.SourceTimely {
+		return fmt.Errorf("invalid booking type: bookings can only be created in Timely calendars")
+	}
+
+	var conflictCalendarIDs []string
+	for _, id := range bookingTypeRequest.ConflictCalendarIDs {
+		conflictCalendar, err := s.calendarRepo.FindByID(id)
+		if err != nil || conflictCalendar.UserID != bookingType.UserID {
+			return fmt.Errorf("invalid booking type: conflict calendar %s not found", id)
+		}
+		conflictCalendarIDs = append(conflictCalendarIDs, strconv.FormatUint(conflictCalendar.ID, 10))
+	}
+
+	timeZone := bookingTypeRequest.TimeZone
+	if timeZone == "" {
+		timeZone = calendar.TimeZone
+	}
+	if _, err := time.LoadLocation(timeZone); err != nil || timeZone == "" {
+		return fmt.Errorf("invalid booking type: unknown time zone %q", timeZone)
+	}
+
+	if len(bookingTypeRequest.Availability) == 0 {
+		return fmt.Errorf("invalid booking type: at least one availability rule is required")
+	}
+	for _, rule := range bookingTypeRequest.Availability {
+		if rule.Weekday < time.Sunday || rule.Weekday > time.Saturday {
+			return fmt.Errorf("invalid booking type: unknown weekday %d", rule.Weekday)
+		}
+		from, err := parseClockMinutes(rule.StartTime)
+		if err != nil {
+			return fmt.Errorf("invalid booking type: invalid start time %q", rule.StartTime)
+		}
+		to, err := parseClockMinutes(rule.EndTime)
+		if err != nil {
+			return fmt.Errorf("invalid booking type: invalid end time %q", rule.EndTime)
+		}
+		if to <= from {
+			return fmt.Errorf("invalid booking type: availability must end after it starts")
+		}
+	}
+
+	bookingType.Slug = slug
+	bookingType.Title = title
+	bookingType.Description = strings.TrimSpace(bookingTypeRequest.Description)
+	bookingType.CalendarID = calendar.ID
+	bookingType.ConflictCalendarIDs = conflictCalendarIDs
+	bookingType.DurationMinutes = bookingTypeRequest.DurationMinutes
+	bookingType.BufferBeforeMinutes = bookingTypeRequest.BufferBeforeMinutes
+	bookingType.BufferAfterMinutes = bookingTypeRequest.BufferAfterMinutes
+	bookingType.MinimumNoticeMinutes = bookingTypeRequest.MinimumNoticeMinutes
+	bookingType.TimeZone = timeZone
+	bookingType.Availability = bookingTypeRequest.Availability
+
+	return nil
+}
+
+// findOwnedBookingType finds a booking type and verifies the user owns it
+func (s *BookingService) findOwnedBookingType(userID uint64, bookingTypeID string) (*model.BookingType, error) {
+	bookingType, err := s.bookingRepo.FindBookingTypeByID(bookingTypeID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find booking type: %w", err)
+	}
+
+	if bookingType.UserID != userID {
+		return nil, fmt.Errorf("booking type not found or access denied")
+	}
+
+	return bookingType, nil
+}
+
+// parseClockMinutes parses a HH:MM wall clock time into minutes after midnight, accepting 24:00
+func parseClockMinutes(value string) (int, error) {
+	hours, minutes, ok := strings.Cut(value, ":")
+	if !ok || len(hours) != 2 || len(minutes) != 2 {
+		return 0, fmt.Errorf("expected HH:MM")
+	}
+
+	h, err := strconv.Atoi(hours)
+	if err != nil {
+		return 0, fmt.Errorf("expected HH:MM")
+	}
+	m, err := strconv.Atoi(minutes)
+	if err != nil {
+		return 0, fmt.Errorf("expected HH:MM")
+	}
+
+	total := h*60 + m
+	if h < 0 || m < 0 || m > 59 || total > 24*60 {
+		return 0, fmt.Errorf("expected HH:MM")
+	}
+	return total, nil
+}
+
+// bookingPage returns the public information of a booking type
+func bookingPage(bookingType *model.BookingType) *model.BookingPage {
+	return &model.BookingPage{
+		Slug:            bookingType.Slug,
+		Title:           bookingType.Title,
+		Description:     bookingType.Description,
+		DurationMinutes: bookingType.DurationMinutes,
+		TimeZone:        bookingType.TimeZone,
+	}
+}
+
+// bookingEventDescription describes who booked an event for the owner of the booking page
+func bookingEventDescription(name, email, notes string) string {
+	description := fmt.Sprintf("Booked by %s <%s>", name, email)
+	if notes = strings.TrimSpace(notes); notes != "" {
+		description += "\n\n" + notes
+	}
+	return description
+}
+
+// renderBookingICS renders the confirmation of a booking as an ICS file the visitor can add to their
+// calendar. The UID matches the one of the event in the owner's feeds.
+func renderBookingICS(user *model.User, bookingType *model.BookingType, booking *model.Booking) string {
+	cal := ics.NewCalendar()
+	cal.SetProductId("-//Timely//Timely Calendar//EN")
+	cal.SetMethod(ics.MethodPublish)
+
+	vevent := cal.AddEvent(fmt.Sprintf("%d@timely", booking.EventID))
+	vevent.SetDtStampTime(booking.CreatedAt)
+	vevent.SetSummary(fmt.Sprintf("%s with %s", bookingType.Title, icsFeedName(user)))
+	vevent.SetStartAt(booking.Start)
+	vevent.SetEndAt(booking.End)
+	vevent.SetStatus(ics.ObjectStatusConfirmed)
+	if bookingType.Description != "" {
+		vevent.SetDescription(bookingType.Description)
+	}
+
+	return cal.Serialize(ics.WithNewLine("\r\n"))
+}
@@ -0,0 +1,217 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	ics "github.com/arran4/golang-ical"
+
+	"github.com/NathanWasTaken/timely/backend/internal/model"
+	"github.com/NathanWasTaken/timely/backend/internal/repository"
+)
+
+func TestBookingSlotsFollowTimeZoneBuffersAndNotice(t *testing.T) {
+	bookingType := &model.BookingType{
+		DurationMinutes:      60,
+		BufferAfterMinutes:   15,
+		MinimumNoticeMinutes: 60,
+		Availability:         []model.BookingAvailabilityRule{{Weekday: time.Monday, StartTime: "09:00", EndTime: "12:00"}},
+	}
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatalf("Failed to load time zone: %v", err)
+	}
+
+	// Berlin switches to summer time on March 31st, 2024
+	busy := []*model.FreeBusyInterval{{
+		Start: time.Date(2024, 3, 25, 11, 10, 0, 0, time.UTC),
+		End:   time.Date(2024, 3, 25, 11, 30, 0, 0, time.UTC),
+	}}
+	now := time.Date(2024, 3, 25, 7, 30, 0, 0, time.UTC)
+	slots := bookingSlots(bookingType, berlin, busy,
+		time.Date(2024, 3, 25, 0, 0, 0, 0, time.UTC), time.Date(2024, 4, 2, 0, 0, 0, 0, time.UTC), now)
+
+	want := []string{
+		"2024-03-25T09:00:00Z", // 09:00 CET is within the minimum notice, 11:00 CET collides through the buffer
+		"2024-04-01T07:00:00Z",
+		"2024-04-01T08:00:00Z",
+		"2024-04-01T09:00:00Z",
+	}
+	var got []string
+	for _, slot := range slots {
+		got = append(got, slot.Start.Format(time.RFC3339))
+	}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("Expected slots %v, got %v", want, got)
+	}
+}
+
+func TestCreateBookingPreventsDoubleBooking(t *testing.T) {
+	db := newTestDB(t)
+	calendarRepo := repository.NewCalendarRepository(db)
+	calendarService := NewCalendarService(repository.NewUserRepository(db), calendarRepo, nil)
+	bookingRepo := repository.NewBookingRepository(db)
+	bookingService := NewBookingService(bookingRepo, calendarRepo)
+
+	// Bookings lock the row of the booking page's owner
+	user := &model.User{ID: 31, Username: "robin", DisplayName: "Robin"}
+	if err := repository.NewUserRepository(db).Create(user); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	calendar, err := calendarService.CreateCalendar(user.ID, &model.CalendarCreateRequest{Summary: "Bookings"})
+	if err != nil {
+		t.Fatalf("Failed to create calendar: %v", err)
+	}
+
+	day := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, 2)
+	bookingTypeRequest := &model.BookingTypeRequest{
+		Slug:            "intro",
+		Title:           "Intro call",
+		CalendarID:      fmt.Sprintf("%d", calendar.ID),
+		DurationMinutes: 30,
+		Availability:    []model.BookingAvailabilityRule{{Weekday: day.Weekday(), StartTime: "09:00", EndTime: "10:00"}},
+	}
+	if _, err := bookingService.CreateBookingType(user.ID, bookingTypeRequest); err != nil {
+		t.Fatalf("Failed to create booking type: %v", err)
+	}
+
+	// Bookings can only be created in Timely calendars
+	icsCalendar := calendarService.newICSCalendar(user.ID, "Imported", "")
+	if err := calendarRepo.Create(icsCalendar); err != nil {
+		t.Fatalf("Failed to create calendar: %v", err)
+	}
+	invalid := *bookingTypeRequest
+	invalid.Slug = "imported"
+	invalid.CalendarID = fmt.Sprintf("%d", icsCalendar.ID)
+	if _, err := bookingService.CreateBookingType(user.ID, &invalid); err == nil || !strings.HasPrefix(err.Error(), "invalid booking type") {
+		t.Errorf("Expected a booking type for an ICS calendar to be rejected, got %v", err)
+	}
+
+	start := day.Add(9 * time.Hour)
+	var wg sync.WaitGroup
+	results := make(chan error, 5)
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, _, err := bookingService.CreateBooking(user, "intro", &model.BookingRequest{
+				Start: start.Format(time.RFC3339),
+				Name:  fmt.Sprintf("Visitor %d", i),
+				Email: fmt.Sprintf("visitor%d@example.com", i),
+			})
+			results <- err
+		}(i)
+	}
+	wg.Wait()
+	close(results)
+
+	booked := 0
+	for err := range results {
+		switch {
+		case err == nil:
+			booked++
+		case err.Error() != "slot is not available":
+			t.Errorf("Unexpected booking error: %v", err)
+		}
+	}
+	if booked != 1 {
+		t.Fatalf("Expected exactly one booking of the slot, got %d", booked)
+	}
+
+	_, slots, err := bookingService.GetBookingSlots(user.ID, "intro", day, day.AddDate(0, 0, 1))
+	if err != nil {
+		t.Fatalf("Failed to get slots: %v", err)
+	}
+	if len(slots) != 1 || !slots[0].Start.Equal(start.Add(30*time.Minute)) {
+		t.Errorf("Expected only the 09:30 slot to be left, got %+v", slots)
+	}
+
+	booking, confirmation, err := bookingService.CreateBooking(user, "intro", &model.BookingRequest{
+		Start: slots[0].Start.Format(time.RFC3339),
+		Name:  "Jane",
+		Email: "Jane <jane@example.com>",
+	})
+	if err != nil {
+		t.Fatalf("Failed to book: %v", err)
+	}
+	if booking.Email != "jane@example.com" {
+		t.Errorf("Expected the email address to be normalized, got %q", booking.Email)
+	}
+
+	cal, err := ics.ParseCalendar(strings.NewReader(confirmation))
+	if err != nil || len(cal.Events()) != 1 {
+		t.Fatalf("Expected a confirmation with one event, got %v", err)
+	}
+	if summary := cal.Events()[0].GetProperty(ics.ComponentPropertySummary).Value; summary != "Intro call with Robin" {
+		t.Errorf("Unexpected confirmation summary %q", summary)
+	}
+
+	events, _ := calendarRepo.FindEventsByCalendarID(calendar.ID)
+	if len(events) != 2 || events[0].Visibility != model.CalendarEventVisibilityPrivate {
+		t.Errorf("Expected 2 private booking events, got %+v", events)
+	}
+
+	// Nothing is created when the slot turns out to be taken within the transaction
+	event := &model.CalendarEvent{ID: 991, SourceID: "991", CalendarID: calendar.ID, Start: start, End: start.Add(30 * time.Minute)}
+	taken := &model.Booking{ID: 992, UserID: user.ID, BookingTypeID: booking.BookingTypeID, EventID: event.ID, Start: event.Start.Add(time.Hour), End: event.End}
+	err = bookingRepo.CreateBooking(taken, event, func(calendarRepo *repository.CalendarRepository) error {
+		if events, err := calendarRepo.FindEventsByCalendarID(calendar.ID); err != nil || len(events) != 2 {
+			t.Errorf("Expected the check to see the existing bookings, got %d, %v", len(events), err)
+		}
+		return errSlotNotAvailable
+	})
+	if err != errSlotNotAvailable {
+		t.Errorf("Expected the failed check to be returned, got %v", err)
+	}
+	if events, _ := calendarRepo.FindEventsByCalendarID(calendar.ID); len(events) != 2 {
+		t.Errorf("Expected no event to be created, got %d events", len(events))
+	}
+}
+
+func TestBookingSlotsIgnoreHiddenDeclinedEvents(t *testing.T) {
+	db := newTestDB(t)
+	calendarRepo := repository.NewCalendarRepository(db)
+	calendarService := NewCalendarService(repository.NewUserRepository(db), calendarRepo, nil)
+	bookingService := NewBookingService(repository.NewBookingRepository(db), calendarRepo)
+
+	const userID = 32
+	calendar, err := calendarService.CreateCalendar(userID, &model.CalendarCreateRequest{Summary: "Bookings"})
+	if err != nil {
+		t.Fatalf("Failed to create calendar: %v", err)
+	}
+
+	day := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, 2)
+	if _, err := bookingService.CreateBookingType(userID, &model.BookingTypeRequest{
+		Slug:            "intro",
+		Title:           "Intro call",
+		CalendarID:      fmt.Sprintf("%d", calendar.ID),
+		DurationMinutes: 30,
+		Availability:    []model.BookingAvailabilityRule{{Weekday: day.Weekday(), StartTime: "09:00", EndTime: "10:00"}},
+	}); err != nil {
+		t.Fatalf("Failed to create booking type: %v", err)
+	}
+
+	start := day.Add(9 * time.Hour)
+	declined := &model.CalendarEvent{ID: 993, SourceID: "993", CalendarID: calendar.ID, Title: "Declined", Start: start, End: start.Add(30 * time.Minute),
+		ResponseStatus: model.EventAttendeeStatusDeclined}
+	if err := calendarRepo.CreateEvent(declined); err != nil {
+		t.Fatalf("Failed to create event: %v", err)
+	}
+
+	_, slots, err := bookingService.GetBookingSlots(userID, "intro", day, day.AddDate(0, 0, 1))
+	if err != nil || len(slots) != 1 {
+		t.Fatalf("Expected the declined event to block its slot while it is shown, got %+v, %v", slots, err)
+	}
+
+	hideDeclined := true
+	if _, err := calendarService.UpdateCalendar(userID, fmt.Sprintf("%d", calendar.ID), &model.CalendarUpdateRequest{HideDeclined: &hideDeclined}); err != nil {
+		t.Fatalf("Failed to update calendar: %v", err)
+	}
+	_, slots, err = bookingService.GetBookingSlots(userID, "intro", day, day.AddDate(0, 0, 1))
+	if err != nil || len(slots) != 2 || !slots[0].Start.Equal(start) {
+		t.Errorf("Expected the hidden declined event not to block its slot, got %+v, %v", slots, err)
+	}
+}
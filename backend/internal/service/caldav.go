@@ -0,0 +1,285 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/NathanWasTaken/timely/backend/internal/model"
+	"github.com/NathanWasTaken/timely/backend/pkg/caldav"
+	"github.com/NathanWasTaken/timely/backend/pkg/utils"
+)
+
+// ConnectCalDAVAccount discovers the calendar home of a CalDAV server and stores the account with
+// its password encrypted. Returns the calendars of the account that can be imported.
+func (s *CalendarService) ConnectCalDAVAccount(userID uint64, req *model.CalDAVAccountCreateRequest) (*model.CalDAVAccount, []*model.CalDAVCalendar, error) {
+	serverURL, err := normalizeCalDAVServerURL(req.ServerURL)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if req.Username == "" || req.Password == "" {
+		return nil, nil, fmt.Errorf("username and password are required")
+	}
+
+	if s.caldav.cipher == nil {
+		return nil, nil, errCalDAVDisabled
+	}
+
+	s.logger.Info("Connecting CalDAV account",
+		zap.Uint64("user_id", userID),
+		zap.String("server_url", serverURL))
+
+	ctx := context.Background()
+	credentials := &ProviderCredentials{Username: req.Username, Password: req.Password}
+	home, err := s.caldav.client(credentials).Discover(ctx, serverURL)
+	if err != nil {
+		if errors.Is(err, caldav.ErrUnauthorized) {
+			return nil, nil, fmt.Errorf("invalid CalDAV credentials")
+		}
+		if errors.Is(err, utils.ErrNonPublicAddress) {
+			return nil, nil, fmt.Errorf("invalid server URL: %w", err)
+		}
+		return nil, nil, fmt.Errorf("failed to discover CalDAV calendars: %w", err)
+	}
+	credentials.HomeURL = home
+
+	calendars, err := s.caldav.ListCalendars(ctx, credentials)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to discover CalDAV calendars: %w", err)
+	}
+
+	encryptedPassword, err := s.caldav.cipher.Encrypt(req.Password)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to encrypt CalDAV password: %w", err)
+	}
+
+	account := &model.CalDAVAccount{
+		ID:                utils.GenerateID(),
+		UserID:            userID,
+		ServerURL:         serverURL,
+		Username:          req.Username,
+		EncryptedPassword: encryptedPassword,
+		CalendarHomeURL:   home,
+	}
+	if err := s.calendarRepo.CreateCalDAVAccount(account); err != nil {
+		return nil, nil, fmt.Errorf("failed to save CalDAV account: %w", err)
+	}
+
+	s.logger.Info("Connected CalDAV account",
+		zap.Uint64("user_id", userID),
+		zap.Uint64("account_id", account.ID),
+		zap.Int("calendar_count", len(calendars)))
+
+	return account, convertRemoteCalendarsToCalDAV(calendars), nil
+}
+
+// GetCalDAVAccounts retrieves the CalDAV accounts a user connected
+func (s *CalendarService) GetCalDAVAccounts(userID uint64) ([]*model.CalDAVAccount, error) {
+	accounts, err := s.calendarRepo.FindCalDAVAccountsByUserID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get CalDAV accounts: %w", err)
+	}
+	return accounts, nil
+}
+
+// GetCalDAVCalendars lists the calendars of a CalDAV account on its server
+func (s *CalendarService) GetCalDAVCalendars(userID uint64, accountID string) ([]*model.CalDAVCalendar, error) {
+	account, err := s.findCalDAVAccount(userID, accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	calendars, err := s.listCalDAVCalendars(context.Background(), account)
+	if err != nil {
+		return nil, err
+	}
+
+	return convertRemoteCalendarsToCalDAV(calendars), nil
+}
+
+// ImportCalDAVCalendar imports a calendar of a CalDAV account and fetches its events
+func (s *CalendarService) ImportCalDAVCalendar(userID uint64, req *model.CalDAVCalendarImportRequest) (*model.Calendar, error) {
+	account, err := s.findCalDAVAccount(userID, req.AccountID)
+	if err != nil {
+		return nil, err
+	}
+
+	calendars, err := s.listCalDAVCalendars(context.Background(), account)
+	if err != nil {
+		return nil, err
+	}
+
+	var remote *RemoteCalendar
+	for _, calendar := range calendars {
+		if calendar.ID == req.CalendarURL {
+			remote = calendar
+			break
+		}
+	}
+	if remote == nil {
+		return nil, fmt.Errorf("calendar not found in list")
+	}
+
+	exists, err := s.calendarRepo.ExistsByUserIDAndSourceID(userID, remote.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check if calendar exists: %w", err)
+	}
+
+	if exists {
+		return nil, fmt.Errorf("calendar already imported")
+	}
+
+	s.logger.Info("Importing CalDAV calendar",
+		zap.Uint64("account_id", account.ID),
+		zap.String("calendar_url", remote.ID),
+		zap.String("summary", remote.Summary))
+
+	timeZone := remote.TimeZone
+	if timeZone == "" {
+		timeZone = "UTC"
+	}
+
+	calendar := &model.Calendar{
+		ID:              utils.GenerateID(),
+		UserID:          userID,
+		SourceID:        &remote.ID,
+		Source:          model.SourceCalDAV,
+		Summary:         remote.Summary,
+		TimeZone:        timeZone,
+		Description:     optionalString(remote.Description),
+		BackgroundColor: optionalColor(remote.Color),
+		Visibility:      model.CalendarVisibilityPrivate,
+		SyncedAt:        time.Now(),
+		SyncStatus:      model.CalendarSyncStatusNeverSynced,
+		CalDAVAccountID: &account.ID,
+	}
+	// The initial sync holds the sync lease, so the scheduler does not sync the calendar meanwhile
+	initialSyncLease(calendar)
+
+	if err := s.calendarRepo.Create(calendar); err != nil {
+		return nil, fmt.Errorf("failed to save calendar to database: %w", err)
+	}
+	lease := s.holdSyncLease(calendar.ID, *calendar.SyncLeaseToken)
+
+	result, err := s.SyncCalendar(calendar, true)
+	s.finishInitialSync(calendar, lease, err)
+	if err != nil {
+		s.logger.Error("Failed to fetch events from CalDAV server",
+			zap.Error(err),
+			zap.Uint64("calendar_id", calendar.ID))
+		// Don't fail the import if events can't be fetched, the scheduler retries
+	} else {
+		s.logger.Info("Successfully imported CalDAV calendar with events",
+			zap.Uint64("user_id", userID),
+			zap.Uint64("calendar_id", calendar.ID),
+			zap.Int("event_count", result.Added))
+	}
+
+	return calendar, nil
+}
+
+// DeleteCalDAVAccount deletes a CalDAV account together with the calendars imported from it
+func (s *CalendarService) DeleteCalDAVAccount(userID uint64, accountID string) error {
+	account, err := s.findCalDAVAccount(userID, accountID)
+	if err != nil {
+		return err
+	}
+
+	calendars, err := s.calendarRepo.FindByCalDAVAccountID(account.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get calendars of CalDAV account: %w", err)
+	}
+
+	for _, calendar := range calendars {
+		if err := s.DeleteCalendar(userID, strconv.FormatUint(calendar.ID, 10)); err != nil {
+			return err
+		}
+	}
+
+	if err := s.calendarRepo.DeleteCalDAVAccount(account.ID); err != nil {
+		return fmt.Errorf("failed to delete CalDAV account: %w", err)
+	}
+
+	s.logger.Info("Deleted CalDAV account",
+		zap.Uint64("user_id", userID),
+		zap.Uint64("account_id", account.ID),
+		zap.Int("deleted_calendars", len(calendars)))
+
+	return nil
+}
+
+// findCalDAVAccount finds a CalDAV account of a user by its ID
+func (s *CalendarService) findCalDAVAccount(userID uint64, accountID string) (*model.CalDAVAccount, error) {
+	id, err := strconv.ParseUint(accountID, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("CalDAV account not found")
+	}
+
+	account, err := s.calendarRepo.FindCalDAVAccountByID(id)
+	if err != nil || account.UserID != userID {
+		return nil, fmt.Errorf("CalDAV account not found")
+	}
+
+	return account, nil
+}
+
+// listCalDAVCalendars lists the calendars of an account on its server
+func (s *CalendarService) listCalDAVCalendars(ctx context.Context, account *model.CalDAVAccount) ([]*RemoteCalendar, error) {
+	credentials, err := s.caldav.accountCredentials(account)
+	if err != nil {
+		return nil, err
+	}
+
+	calendars, err := s.caldav.ListCalendars(ctx, credentials)
+	if err != nil {
+		if errors.Is(err, caldav.ErrUnauthorized) {
+			return nil, fmt.Errorf("invalid CalDAV credentials")
+		}
+		return nil, fmt.Errorf("failed to fetch calendars from CalDAV server: %w", err)
+	}
+
+	return calendars, nil
+}
+
+// convertRemoteCalendarsToCalDAV converts provider calendars to the API representation
+func convertRemoteCalendarsToCalDAV(calendars []*RemoteCalendar) []*model.CalDAVCalendar {
+	converted := make([]*model.CalDAVCalendar, 0, len(calendars))
+	for _, calendar := range calendars {
+		converted = append(converted, &model.CalDAVCalendar{
+			URL:         calendar.ID,
+			Name:        calendar.Summary,
+			Description: calendar.Description,
+			Color:       calendar.Color,
+			TimeZone:    calendar.TimeZone,
+		})
+	}
+	return converted
+}
+
+// normalizeCalDAVServerURL validates the URL a CalDAV account is connected with
+func normalizeCalDAVServerURL(rawURL string) (string, error) {
+	parsed, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return "", fmt.Errorf("invalid server URL: %w", err)
+	}
+
+	switch strings.ToLower(parsed.Scheme) {
+	case "http", "https":
+		parsed.Scheme = strings.ToLower(parsed.Scheme)
+	default:
+		return "", fmt.Errorf("invalid server URL: unsupported scheme %q", parsed.Scheme)
+	}
+
+	if parsed.Host == "" {
+		return "", fmt.Errorf("invalid server URL: missing host")
+	}
+
+	return parsed.String(), nil
+}
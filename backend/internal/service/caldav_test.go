@@ -0,0 +1,491 @@
+package service
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/NathanWasTaken/timely/backend/internal/model"
+	"github.com/NathanWasTaken/timely/backend/internal/repository"
+	"github.com/NathanWasTaken/timely/backend/pkg/utils"
+)
+
+const (
+	caldavTestPassword = "app-password"
+	caldavTestCalendar = "/calendars/alex/personal/"
+)
+
+var (
+	caldavHrefPattern      = regexp.MustCompile(`<d:href>([^<]*)</d:href>`)
+	caldavSyncTokenPattern = regexp.MustCompile(`<d:sync-token>([^<]*)</d:sync-token>`)
+)
+
+// caldavServer is an in-process CalDAV server with a single event calendar. Every change bumps
+// the version of the collection, sync tokens and the ctag are derived from it.
+type caldavServer struct {
+	mu             sync.Mutex
+	syncCollection bool              // Whether sync-collection is supported
+	objects        map[string]string // Resource name to iCalendar data
+	etags          map[string]int    // Resource name to the version it was last changed in
+	deleted        map[string]int    // Resource name to the version it was deleted in
+	version        int
+	minSyncToken   int      // Sync tokens older than this are rejected
+	reports        []string // Kinds of REPORT requests received, with the sync token or resource count
+}
+
+func newCalDAVServer(syncCollection bool) *caldavServer {
+	return &caldavServer{
+		syncCollection: syncCollection,
+		objects:        map[string]string{},
+		etags:          map[string]int{},
+		deleted:        map[string]int{},
+	}
+}
+
+// put creates or replaces a resource, empty data deletes it
+func (s *caldavServer) put(name, data string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.version++
+	if data == "" {
+		delete(s.objects, name)
+		delete(s.etags, name)
+		s.deleted[name] = s.version
+		return
+	}
+	s.objects[name] = data
+	s.etags[name] = s.version
+	delete(s.deleted, name)
+}
+
+// expireSyncTokens makes the server reject every sync token issued so far
+func (s *caldavServer) expireSyncTokens() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.minSyncToken = s.version + 1
+}
+
+func (s *caldavServer) takeReports() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	reports := s.reports
+	s.reports = nil
+	return reports
+}
+
+func (s *caldavServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if r.URL.Path == "/.well-known/caldav" {
+		http.Redirect(w, r, "/dav/", http.StatusMovedPermanently)
+		return
+	}
+
+	if username, password, ok := r.BasicAuth(); !ok || username != "alex" || password != caldavTestPassword {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	data, _ := io.ReadAll(r.Body)
+	body := string(data)
+
+	switch {
+	case r.Method == "PROPFIND" && r.URL.Path == "/dav/":
+		s.multistatus(w, `<d:response><d:href>/dav/</d:href><d:propstat><d:prop>`+
+			`<d:current-user-principal><d:href>/principals/alex/</d:href></d:current-user-principal>`+
+			`</d:prop><d:status>HTTP/1.1 200 OK</d:status></d:propstat></d:response>`)
+	case r.Method == "PROPFIND" && r.URL.Path == "/principals/alex/":
+		s.multistatus(w, `<d:response><d:href>/principals/alex/</d:href><d:propstat><d:prop>`+
+			`<c:calendar-home-set><d:href>/calendars/alex/</d:href></c:calendar-home-set>`+
+			`</d:prop><d:status>HTTP/1.1 200 OK</d:status></d:propstat></d:response>`)
+	case r.Method == "PROPFIND" && r.URL.Path == "/calendars/alex/":
+		s.multistatus(w, `<d:response><d:href>/calendars/alex/</d:href><d:propstat><d:prop>`+
+			`<d:resourcetype><d:collection/></d:resourcetype></d:prop><d:status>HTTP/1.1 200 OK</d:status></d:propstat></d:response>`+
+			`<d:response><d:href>`+caldavTestCalendar+`</d:href><d:propstat><d:prop>`+
+			`<d:resourcetype><d:collection/><c:calendar/></d:resourcetype><d:displayname>Personal</d:displayname>`+
+			`<a:calendar-color>#0082C9FF</a:calendar-color>`+
+			`<c:supported-calendar-component-set><c:comp name="VEVENT"/></c:supported-calendar-component-set>`+
+			`</d:prop><d:status>HTTP/1.1 200 OK</d:status></d:propstat></d:response>`+
+			`<d:response><d:href>/calendars/alex/tasks/</d:href><d:propstat><d:prop>`+
+			`<d:resourcetype><d:collection/><c:calendar/></d:resourcetype><d:displayname>Tasks</d:displayname>`+
+			`<c:supported-calendar-component-set><c:comp name="VTODO"/></c:supported-calendar-component-set>`+
+			`</d:prop><d:status>HTTP/1.1 200 OK</d:status></d:propstat></d:response>`)
+	case r.Method == "PROPFIND" && r.URL.Path == caldavTestCalendar && r.Header.Get("Depth") == "0":
+		props := `<cs:getctag>ctag-` + strconv.Itoa(s.version) + `</cs:getctag>`
+		if s.syncCollection {
+			props += `<d:sync-token>` + s.syncToken() + `</d:sync-token>`
+		}
+		s.multistatus(w, `<d:response><d:href>`+caldavTestCalendar+`</d:href><d:propstat><d:prop>`+props+
+			`</d:prop><d:status>HTTP/1.1 200 OK</d:status></d:propstat></d:response>`)
+	case r.Method == "PROPFIND" && r.URL.Path == caldavTestCalendar:
+		responses := `<d:response><d:href>` + caldavTestCalendar + `</d:href><d:propstat><d:prop>` +
+			`<d:resourcetype><d:collection/><c:calendar/></d:resourcetype></d:prop><d:status>HTTP/1.1 200 OK</d:status></d:propstat></d:response>`
+		for _, name := range s.names() {
+			responses += s.resource(name, false)
+		}
+		s.multistatus(w, responses)
+	case r.Method == "REPORT" && strings.Contains(body, "calendar-query"):
+		s.reports = append(s.reports, "query")
+		var responses string
+		for _, name := range s.names() {
+			responses += s.resource(name, true)
+		}
+		s.multistatus(w, responses)
+	case r.Method == "REPORT" && strings.Contains(body, "calendar-multiget"):
+		hrefs := caldavHrefPattern.FindAllStringSubmatch(body, -1)
+		s.reports = append(s.reports, fmt.Sprintf("multiget %d", len(hrefs)))
+		var responses string
+		for _, href := range hrefs {
+			responses += s.resource(strings.TrimPrefix(href[1], caldavTestCalendar), true)
+		}
+		s.multistatus(w, responses)
+	case r.Method == "REPORT" && strings.Contains(body, "sync-collection") && s.syncCollection:
+		token := caldavSyncTokenPattern.FindStringSubmatch(body)[1]
+		s.reports = append(s.reports, "sync "+token)
+		since, err := strconv.Atoi(strings.TrimPrefix(token, "https://example.com/sync/"))
+		if err != nil || since < s.minSyncToken {
+			w.Header().Set("Content-Type", "application/xml")
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, `<?xml version="1.0"?><d:error xmlns:d="DAV:"><d:valid-sync-token/></d:error>`)
+			return
+		}
+		var responses string
+		for name, version := range s.etags {
+			if version > since {
+				responses += s.resource(name, false)
+			}
+		}
+		for name, version := range s.deleted {
+			if version > since {
+				responses += `<d:response><d:href>` + caldavTestCalendar + name + `</d:href><d:status>HTTP/1.1 404 Not Found</d:status></d:response>`
+			}
+		}
+		s.multistatus(w, responses+`<d:sync-token>`+s.syncToken()+`</d:sync-token>`)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (s *caldavServer) syncToken() string {
+	return "https://example.com/sync/" + strconv.Itoa(s.version)
+}
+
+func (s *caldavServer) names() []string {
+	names := make([]string, 0, len(s.objects))
+	for name := range s.objects {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// resource renders the response for a resource, with its calendar data if withData is set
+func (s *caldavServer) resource(name string, withData bool) string {
+	data, ok := s.objects[name]
+	if !ok {
+		return `<d:response><d:href>` + caldavTestCalendar + name + `</d:href><d:status>HTTP/1.1 404 Not Found</d:status></d:response>`
+	}
+
+	props := `<d:getetag>"` + strconv.Itoa(s.etags[name]) + `"</d:getetag>`
+	if withData {
+		props += `<c:calendar-data>` + strings.ReplaceAll(data, "\n", "\r\n") + `</c:calendar-data>`
+	}
+	return `<d:response><d:href>` + caldavTestCalendar + name + `</d:href><d:propstat><d:prop>` + props +
+		`</d:prop><d:status>HTTP/1.1 200 OK</d:status></d:propstat></d:response>`
+}
+
+func (s *caldavServer) multistatus(w http.ResponseWriter, responses string) {
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.WriteHeader(http.StatusMultiStatus)
+	fmt.Fprint(w, `<?xml version="1.0" encoding="utf-8"?><d:multistatus xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav" `+
+		`xmlns:cs="http://calendarserver.org/ns/" xmlns:a="http://apple.com/ns/ical/">`+responses+`</d:multistatus>`)
+}
+
+// caldavEvent builds a calendar object with a single event
+func caldavEvent(uid, summary, start string) string {
+	return "BEGIN:VCALENDAR\nVERSION:2.0\nPRODID:-//Test//EN\nBEGIN:VEVENT\nUID:" + uid + "\nSUMMARY:" + summary +
+		"\nDTSTART:" + start + "\nDTEND:" + start[:9] + "230000Z\nEND:VEVENT\nEND:VCALENDAR\n"
+}
+
+// caldavRecurringEvent builds a calendar object with a weekly event of three occurrences, the
+// second one moved and renamed
+func caldavRecurringEvent(uid, summary string) string {
+	return "BEGIN:VCALENDAR\nVERSION:2.0\nPRODID:-//Test//EN\n" +
+		"BEGIN:VEVENT\nUID:" + uid + "\nSUMMARY:" + summary + "\nDTSTART:20240701T090000Z\nDTEND:20240701T093000Z\n" +
+		"RRULE:FREQ=WEEKLY;COUNT=3\nEND:VEVENT\n" +
+		"BEGIN:VEVENT\nUID:" + uid + "\nRECURRENCE-ID:20240708T090000Z\nSUMMARY:" + summary + " (moved)\n" +
+		"DTSTART:20240708T100000Z\nDTEND:20240708T103000Z\nEND:VEVENT\nEND:VCALENDAR\n"
+}
+
+// connectCalDAVCalendar connects an account with the test server and imports its calendar
+func connectCalDAVCalendar(t *testing.T, calendarService *CalendarService, serverURL string) *model.Calendar {
+	t.Helper()
+
+	account, calendars, err := calendarService.ConnectCalDAVAccount(7, &model.CalDAVAccountCreateRequest{
+		ServerURL: serverURL,
+		Username:  "alex",
+		Password:  caldavTestPassword,
+	})
+	if err != nil {
+		t.Fatalf("ConnectCalDAVAccount failed: %v", err)
+	}
+	if len(calendars) != 1 || calendars[0].Name != "Personal" || calendars[0].Color != "#0082C9" {
+		t.Fatalf("Expected only the event calendar to be listed, got %+v", calendars)
+	}
+
+	calendar, err := calendarService.ImportCalDAVCalendar(7, &model.CalDAVCalendarImportRequest{
+		AccountID:   strconv.FormatUint(account.ID, 10),
+		CalendarURL: calendars[0].URL,
+	})
+	if err != nil {
+		t.Fatalf("ImportCalDAVCalendar failed: %v", err)
+	}
+	return calendar
+}
+
+func newTestCalDAVService(t *testing.T) *CalendarService {
+	t.Helper()
+
+	t.Setenv("CREDENTIALS_SECRET", strings.Repeat("s", 32))
+	calendarService, _ := newTestCalendarService(t)
+	return calendarService
+}
+
+func TestCalDAVConnectAccount(t *testing.T) {
+	calendarService := newTestCalDAVService(t)
+	server := httptest.NewServer(newCalDAVServer(true))
+	defer server.Close()
+
+	_, _, err := calendarService.ConnectCalDAVAccount(7, &model.CalDAVAccountCreateRequest{
+		ServerURL: server.URL,
+		Username:  "alex",
+		Password:  "wrong",
+	})
+	if err == nil || err.Error() != "invalid CalDAV credentials" {
+		t.Errorf("Expected invalid credentials error, got %v", err)
+	}
+
+	_, _, err = calendarService.ConnectCalDAVAccount(7, &model.CalDAVAccountCreateRequest{
+		ServerURL: "ftp://example.com",
+		Username:  "alex",
+		Password:  caldavTestPassword,
+	})
+	if err == nil || !strings.HasPrefix(err.Error(), "invalid server URL") {
+		t.Errorf("Expected invalid server URL error, got %v", err)
+	}
+
+	// Discovery falls back to /.well-known/caldav when the server URL has no principal
+	account, _, err := calendarService.ConnectCalDAVAccount(7, &model.CalDAVAccountCreateRequest{
+		ServerURL: server.URL,
+		Username:  "alex",
+		Password:  caldavTestPassword,
+	})
+	if err != nil {
+		t.Fatalf("ConnectCalDAVAccount failed: %v", err)
+	}
+	if account.CalendarHomeURL != server.URL+"/calendars/alex/" {
+		t.Errorf("Unexpected calendar home %q", account.CalendarHomeURL)
+	}
+	if account.EncryptedPassword == "" || strings.Contains(account.EncryptedPassword, caldavTestPassword) {
+		t.Error("Expected the password to be stored encrypted")
+	}
+
+	// Other users cannot see the account
+	if _, err := calendarService.GetCalDAVCalendars(8, strconv.FormatUint(account.ID, 10)); err == nil || err.Error() != "CalDAV account not found" {
+		t.Errorf("Expected account not found for another user, got %v", err)
+	}
+}
+
+func TestCalDAVConnectAccountRefusesInternalAddresses(t *testing.T) {
+	// The service's own client, which doesn't reach loopback like the test services do
+	t.Setenv("CREDENTIALS_SECRET", strings.Repeat("s", 32))
+	db := newTestDB(t)
+	calendarService := NewCalendarService(repository.NewUserRepository(db), repository.NewCalendarRepository(db), nil)
+
+	requests := 0
+	caldav := newCalDAVServer(true)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		caldav.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	for _, serverURL := range []string{server.URL, "http://169.254.169.254/", "http://10.0.0.1/dav/"} {
+		_, _, err := calendarService.ConnectCalDAVAccount(7, &model.CalDAVAccountCreateRequest{
+			ServerURL: serverURL,
+			Username:  "alex",
+			Password:  caldavTestPassword,
+		})
+		if err == nil || !strings.HasPrefix(err.Error(), "invalid server URL") {
+			t.Errorf("Expected connecting to %s to be refused, got %v", serverURL, err)
+		}
+	}
+	if requests != 0 {
+		t.Errorf("Expected no request to reach the loopback server, got %d", requests)
+	}
+}
+
+func TestCalDAVSyncCollection(t *testing.T) {
+	calendarService := newTestCalDAVService(t)
+	caldav := newCalDAVServer(true)
+	caldav.put("standup.ics", caldavRecurringEvent("standup", "Standup"))
+	caldav.put("review.ics", caldavEvent("review", "Review", "20240702T140000Z"))
+	server := httptest.NewServer(caldav)
+	defer server.Close()
+
+	calendar := connectCalDAVCalendar(t, calendarService, server.URL)
+	if calendar.Source != model.SourceCalDAV || calendar.CalDAVAccountID == nil {
+		t.Fatalf("Unexpected calendar %+v", calendar)
+	}
+	if titles := eventTitles(t, calendarService, calendar.ID); len(titles) != 4 {
+		t.Errorf("Expected 3 occurrences and 1 single event, got %v", titles)
+	}
+	if reports := caldav.takeReports(); len(reports) != 1 || reports[0] != "query" {
+		t.Errorf("Expected import to fetch every event, got %v", reports)
+	}
+
+	// Only changed resources are fetched after the import
+	caldav.put("review.ics", caldavEvent("review", "Design review", "20240702T140000Z"))
+	caldav.put("standup.ics", "")
+	caldav.put("retro.ics", caldavEvent("retro", "Retro", "20240705T160000Z"))
+
+	result, err := calendarService.SyncCalendar(calendar, false)
+	if err != nil {
+		t.Fatalf("SyncCalendar failed: %v", err)
+	}
+	if result.Added != 1 || result.Updated != 1 || result.Removed != 3 {
+		t.Errorf("Expected 1 added, 1 updated and 3 removed events, got %+v", result)
+	}
+	if reports := caldav.takeReports(); len(reports) != 2 || reports[0] != "sync https://example.com/sync/2" || reports[1] != "multiget 2" {
+		t.Errorf("Expected a sync-collection and a multiget of the changed resources, got %v", reports)
+	}
+
+	titles := eventTitles(t, calendarService, calendar.ID)
+	if len(titles) != 2 || titles["review"] != "Design review" || titles["retro"] != "Retro" {
+		t.Errorf("Unexpected events after incremental sync: %v", titles)
+	}
+
+	// A sync token the server no longer accepts falls back to fetching everything
+	caldav.expireSyncTokens()
+	caldav.put("standup.ics", caldavRecurringEvent("standup", "Standup"))
+
+	result, err = calendarService.SyncCalendar(calendar, false)
+	if err != nil {
+		t.Fatalf("SyncCalendar failed: %v", err)
+	}
+	if result.Added != 3 || result.Updated != 0 || result.Removed != 0 {
+		t.Errorf("Expected the recurring event to be added again, got %+v", result)
+	}
+	if reports := caldav.takeReports(); len(reports) != 2 || reports[1] != "query" {
+		t.Errorf("Expected a rejected sync-collection and a full query, got %v", reports)
+	}
+	if calendar.SyncToken == nil || *calendar.SyncToken != "https://example.com/sync/6" {
+		t.Errorf("Expected the new sync token to be stored, got %v", calendar.SyncToken)
+	}
+}
+
+func TestCalDAVImportHoldsSyncLease(t *testing.T) {
+	calendarService := newTestCalDAVService(t)
+	caldav := newCalDAVServer(true)
+	caldav.put("review.ics", caldavEvent("review", "Review", "20240702T140000Z"))
+
+	// The scheduler must not pick the calendar up while its initial sync fetches the events
+	var dueDuringImport []*model.Calendar
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "REPORT" {
+			due, err := calendarService.calendarRepo.FindCalendarsDueForSync(calendarService.scheduledSources(), time.Now(), 10)
+			if err != nil {
+				t.Errorf("FindCalendarsDueForSync failed: %v", err)
+			}
+			dueDuringImport = append(dueDuringImport, due...)
+		}
+		caldav.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	calendar := connectCalDAVCalendar(t, calendarService, server.URL)
+	if len(dueDuringImport) != 0 {
+		t.Errorf("Expected no calendar to be due while the import syncs, got %d", len(dueDuringImport))
+	}
+
+	// Afterwards the lease is released and the next sync scheduled
+	stored, err := calendarService.calendarRepo.FindByID(strconv.FormatUint(calendar.ID, 10))
+	if err != nil {
+		t.Fatalf("Failed to find calendar: %v", err)
+	}
+	if stored.SyncLockedUntil != nil || stored.NextSyncAt == nil || !stored.NextSyncAt.After(time.Now()) {
+		t.Errorf("Expected the lease to be released and the next sync scheduled, got lease %v and next sync %v", stored.SyncLockedUntil, stored.NextSyncAt)
+	}
+
+	// Even without the lease a calendar never stores the same event twice
+	events, err := calendarService.calendarRepo.FindEventsByCalendarID(calendar.ID)
+	if err != nil || len(events) != 1 {
+		t.Fatalf("Expected 1 imported event, got %d (%v)", len(events), err)
+	}
+	duplicate := *events[0]
+	duplicate.ID = utils.GenerateID()
+	if err := calendarService.calendarRepo.CreateEvents([]*model.CalendarEvent{&duplicate}); err == nil {
+		t.Error("Expected a second event with the same source ID to be refused")
+	}
+}
+
+func TestCalDAVSyncWithoutSyncCollection(t *testing.T) {
+	calendarService := newTestCalDAVService(t)
+	caldav := newCalDAVServer(false)
+	caldav.put("standup.ics", caldavRecurringEvent("standup", "Standup"))
+	caldav.put("review.ics", caldavEvent("review", "Review", "20240702T140000Z"))
+	server := httptest.NewServer(caldav)
+	defer server.Close()
+
+	calendar := connectCalDAVCalendar(t, calendarService, server.URL)
+	if calendar.SyncToken == nil || *calendar.SyncToken != "ctag:ctag-2" {
+		t.Fatalf("Expected the ctag to be stored as sync token, got %v", calendar.SyncToken)
+	}
+	caldav.takeReports()
+
+	// An unchanged ctag skips listing the resources
+	result, err := calendarService.SyncCalendar(calendar, false)
+	if err != nil {
+		t.Fatalf("SyncCalendar failed: %v", err)
+	}
+	if result.Added != 0 || result.Updated != 0 || result.Removed != 0 {
+		t.Errorf("Expected no changes, got %+v", result)
+	}
+	if reports := caldav.takeReports(); len(reports) != 0 {
+		t.Errorf("Expected no reports for an unchanged calendar, got %v", reports)
+	}
+
+	// A changed ctag fetches the resources whose ETag changed
+	caldav.put("standup.ics", caldavRecurringEvent("standup", "Daily standup"))
+	caldav.put("review.ics", "")
+
+	result, err = calendarService.SyncCalendar(calendar, false)
+	if err != nil {
+		t.Fatalf("SyncCalendar failed: %v", err)
+	}
+	if result.Added != 0 || result.Updated != 3 || result.Removed != 1 {
+		t.Errorf("Expected 3 updated and 1 removed event, got %+v", result)
+	}
+	if reports := caldav.takeReports(); len(reports) != 1 || reports[0] != "multiget 1" {
+		t.Errorf("Expected a multiget of the changed resource, got %v", reports)
+	}
+
+	titles := eventTitles(t, calendarService, calendar.ID)
+	if len(titles) != 3 {
+		t.Errorf("Expected the 3 occurrences to remain, got %v", titles)
+	}
+	for sourceID, title := range titles {
+		if !strings.HasPrefix(title, "Daily standup") {
+			t.Errorf("Expected occurrence %s to be renamed, got %q", sourceID, title)
+		}
+	}
+}
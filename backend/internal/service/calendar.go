@@ -2,28 +2,28 @@ package service
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
-	"io"
-	"net/http"
 	"strings"
 	"time"
 
 	ics "github.com/arran4/golang-ical"
 	"go.uber.org/zap"
-	"golang.org/x/oauth2"
 
 	"github.com/NathanWasTaken/timely/backend/internal/config"
 	"github.com/NathanWasTaken/timely/backend/internal/model"
 	"github.com/NathanWasTaken/timely/backend/internal/repository"
+	"github.com/NathanWasTaken/timely/backend/pkg/ical"
 	"github.com/NathanWasTaken/timely/backend/pkg/utils"
 )
 
 type CalendarService struct {
 	userRepo         *repository.UserRepository
 	calendarRepo     *repository.CalendarRepository
-	oauthConfig      *config.OAuthConfig
 	syncTokenManager *SyncTokenManager
+	providers        map[model.CalendarSource]CalendarProvider
+	google           *googleProvider // Google only features, such as push notifications
+	ics              *icsProvider    // ICS parsing of uploaded files
+	caldav           *caldavProvider // CalDAV account discovery
 	logger           *zap.Logger
 }
 
@@ -115,13 +115,23 @@ func (stm *SyncTokenManager) UpdateSyncMetadata(calendarID uint64, syncToken str
 }
 
 func NewCalendarService(userRepo *repository.UserRepository, calendarRepo *repository.CalendarRepository, oauthConfig *config.OAuthConfig) *CalendarService {
-	return &CalendarService{
+	icsProvider := newICSProvider()
+	s := &CalendarService{
 		userRepo:         userRepo,
 		calendarRepo:     calendarRepo,
-		oauthConfig:      oauthConfig,
 		syncTokenManager: NewSyncTokenManager(calendarRepo),
+		providers:        make(map[model.CalendarSource]CalendarProvider),
+		google:           newGoogleProvider(userRepo, oauthConfig),
+		ics:              icsProvider,
+		caldav:           newCalDAVProvider(calendarRepo, icsProvider),
 		logger:           zap.L(),
 	}
+
+	s.RegisterProvider(s.google)
+	s.RegisterProvider(s.ics)
+	s.RegisterProvider(s.caldav)
+
+	return s
 }
 
 // GetUserCalendars retrieves all calendars for a user with smart sync logic
@@ -143,10 +153,13 @@ func (s *CalendarService) GetUserCalendarsWithSync(userID uint64, forceSync bool
 		return s.fetchUserCalendarsFromGoogle(userID)
 	}
 
-	// Use the reusable sync function
-	synced, err := s.SyncIfNeeded(userID, forceSync)
-	if err != nil {
-		return nil, fmt.Errorf("failed to sync calendars: %w", err)
+	// Calendars are kept up to date by the background scheduler, only sync here when asked to
+	synced := false
+	if forceSync {
+		synced, err = s.SyncUserCalendarsNow(userID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to sync calendars: %w", err)
+		}
 	}
 
 	// Get fresh local calendars after potential sync
@@ -168,26 +181,16 @@ func (s *CalendarService) GetUserCalendarsFromGoogle(userID uint64) ([]*model.Go
 	return s.fetchUserCalendarsFromGoogle(userID)
 }
 
-// fetchUserCalendarsFromGoogle fetches calendars from Google API (original logic)
+// fetchUserCalendarsFromGoogle fetches calendars from Google API
 func (s *CalendarService) fetchUserCalendarsFromGoogle(userID uint64) ([]*model.GoogleCalendar, error) {
-	// Get user's Google account
-	account, err := s.userRepo.FindGoogleAccountByUserID(userID)
+	ctx := context.Background()
+	credentials, err := s.google.userCredentials(ctx, userID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get Google account: %w", err)
-	}
-
-	// Check if account has tokens
-	if account.AccessToken == nil || account.RefreshToken == nil {
-		return nil, fmt.Errorf("google account not properly configured with OAuth tokens")
-	}
-
-	// Check if token needs refresh
-	if err := s.refreshTokenIfNeeded(account); err != nil {
-		return nil, fmt.Errorf("failed to refresh token: %w", err)
+		return nil, err
 	}
 
 	// Fetch calendars from Google API
-	calendars, err := s.fetchCalendarsFromGoogle(*account.AccessToken)
+	calendars, err := s.google.fetchCalendarList(ctx, credentials.AccessToken)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch calendars from Google: %w", err)
 	}
@@ -195,83 +198,49 @@ func (s *CalendarService) fetchUserCalendarsFromGoogle(userID uint64) ([]*model.
 	return calendars, nil
 }
 
-// SyncIfNeeded checks if calendars need syncing and performs sync if necessary
-// Returns true if sync was performed, false if cached data was used
-func (s *CalendarService) SyncIfNeeded(userID uint64, forceSync bool) (bool, error) {
-	// Get local calendars to check sync status
+// SyncUserCalendarsNow syncs the events of all of a user's remote calendars right away instead of
+// waiting for the background scheduler. Calendars the scheduler is syncing at the moment are skipped.
+// Returns true if any calendar was synced.
+func (s *CalendarService) SyncUserCalendarsNow(userID uint64) (bool, error) {
 	localCalendars, err := s.calendarRepo.FindByUserID(userID)
 	if err != nil {
 		return false, fmt.Errorf("failed to get local calendars: %w", err)
 	}
 
-	// Check if sync is needed
-	needsSync := forceSync || len(localCalendars) == 0
-
-	if !needsSync {
-		for _, calendar := range localCalendars {
-			if time.Since(calendar.SyncedAt) > 1*time.Minute {
-				needsSync = true
-				break
-			}
-		}
-	}
-
-	if !needsSync {
-		s.logger.Debug("Using cached data - no sync needed", zap.Uint64("user_id", userID))
-		return false, nil
-	}
-
-	// Perform sync
-	if forceSync {
-		s.logger.Info("Performing force sync", zap.Uint64("user_id", userID))
-	} else {
-		s.logger.Info("Performing automatic sync - cache expired", zap.Uint64("user_id", userID))
-	}
-
-	// Check if user has valid Google account before attempting sync
-	account, err := s.userRepo.FindGoogleAccountByUserID(userID)
-	if err != nil {
-		s.logger.Error("No Google account found for user",
-			zap.Error(err),
-			zap.Uint64("user_id", userID))
-		return false, nil // Return cached data without error
-	}
-
-	// Validate account has required tokens
-	if account.AccessToken == nil || account.RefreshToken == nil {
-		s.logger.Error("Google account missing OAuth tokens",
-			zap.Uint64("user_id", userID))
-		return false, nil // Return cached data without error
-	}
-
-	// Try to refresh token if needed
-	if err := s.refreshTokenIfNeeded(account); err != nil {
-		s.logger.Error("Failed to refresh token, using cached data",
-			zap.Error(err),
-			zap.Uint64("user_id", userID))
-		return false, nil // Return cached data without error
-	}
+	s.logger.Info("Performing force sync", zap.Uint64("user_id", userID))
 
 	// Sync each calendar's events
 	syncSuccessCount := 0
 	syncAttempts := 0
 
 	for _, calendar := range localCalendars {
-		if calendar.SourceID != nil {
-			shouldSyncCalendar := forceSync || time.Since(calendar.SyncedAt) > 1*time.Minute
-			if shouldSyncCalendar {
-				syncAttempts++
-				if err := s.SyncCalendarEvents(userID, *calendar.SourceID); err != nil {
-					s.logger.Error("Failed to sync calendar events",
-						zap.Error(err),
-						zap.String("calendar_source_id", *calendar.SourceID),
-						zap.Uint64("user_id", userID))
-					// Continue with other calendars - don't fail the entire operation
-				} else {
-					syncSuccessCount++
-				}
-			}
+		// ICS subscriptions are refreshed separately and Timely calendars are the source of truth
+		// for their events
+		if !s.isScheduledSource(calendar.Source) || calendar.SourceID == nil {
+			continue
+		}
+
+		lease, err := s.claimSyncLease(calendar.ID, s.calendarRepo.ClaimSyncLease)
+		if err != nil {
+			s.logger.Debug("Skipping calendar that is already being synced",
+				zap.Error(err),
+				zap.Uint64("calendar_id", calendar.ID))
+			continue
 		}
+
+		syncAttempts++
+		if _, err := s.SyncCalendar(calendar, false); err != nil {
+			s.logger.Error("Failed to sync calendar events",
+				zap.Error(err),
+				zap.String("calendar_source_id", *calendar.SourceID),
+				zap.Uint64("user_id", userID))
+			// Continue with other calendars - don't fail the entire operation
+		} else {
+			syncSuccessCount++
+		}
+
+		// The background schedule is left as it is
+		s.releaseSyncLease(lease, nil)
 	}
 
 	if syncAttempts > 0 {
@@ -304,9 +273,7 @@ func (s *CalendarService) convertLocalCalendarsToGoogle(localCalendars []*model.
 		if localCalendar.Description != nil {
 			googleCalendar.Description = *localCalendar.Description
 		}
-		if localCalendar.EventColor != nil {
-			googleCalendar.BackgroundColor = *localCalendar.EventColor
-		}
+		googleCalendar.BackgroundColor = CalendarColor(localCalendar)
 
 		googleCalendars = append(googleCalendars, googleCalendar)
 	}
@@ -314,140 +281,16 @@ func (s *CalendarService) convertLocalCalendarsToGoogle(localCalendars []*model.
 	return googleCalendars, nil
 }
 
-// refreshTokenIfNeeded checks if the token is expired and refreshes it if necessary
-func (s *CalendarService) refreshTokenIfNeeded(account *model.Account) error {
-	// Always try to refresh if token is expired or close to expiring
-	needsRefresh := false
-
-	if account.Expiry == nil {
-		s.logger.Warn("Token has no expiry time, forcing refresh", zap.Uint64("user_id", account.UserID))
-		needsRefresh = true
-	} else if time.Now().Add(5 * time.Minute).After(*account.Expiry) {
-		s.logger.Info("Token is expired or expiring soon, refreshing",
-			zap.Uint64("user_id", account.UserID),
-			zap.Time("expiry", *account.Expiry))
-		needsRefresh = true
-	}
-
-	if !needsRefresh {
-		s.logger.Debug("Token is still valid", zap.Uint64("user_id", account.UserID))
-		return nil
-	}
-
-	s.logger.Info("Refreshing Google OAuth token", zap.Uint64("user_id", account.UserID))
-
-	// Validate we have refresh token
-	if account.RefreshToken == nil || *account.RefreshToken == "" {
-		return fmt.Errorf("no refresh token available for user %d", account.UserID)
-	}
-
-	// Create oauth2.Token for refresh
-	oauthToken := &oauth2.Token{
-		RefreshToken: *account.RefreshToken,
-	}
-
-	// Set access token if we have one (even if expired)
-	if account.AccessToken != nil {
-		oauthToken.AccessToken = *account.AccessToken
-	}
-
-	// Set expiry if we have one
-	if account.Expiry != nil {
-		oauthToken.Expiry = *account.Expiry
-	}
-
-	// Refresh the token using the OAuth config
-	ctx := context.Background()
-	tokenSource := s.oauthConfig.Google.TokenSource(ctx, oauthToken)
-
-	newToken, err := tokenSource.Token()
-	if err != nil {
-		s.logger.Error("Failed to refresh OAuth token",
-			zap.Error(err),
-			zap.Uint64("user_id", account.UserID))
-		return fmt.Errorf("failed to refresh token for user %d: %w", account.UserID, err)
-	}
-
-	// Validate new token
-	if newToken.AccessToken == "" {
-		return fmt.Errorf("received empty access token for user %d", account.UserID)
-	}
-
-	// Update the token in database
-	refreshToken := newToken.RefreshToken
-	if refreshToken == "" && account.RefreshToken != nil {
-		// Keep existing refresh token if new one is empty
-		refreshToken = *account.RefreshToken
-	}
-
-	if err := s.userRepo.UpdateGoogleAccountTokens(account.UserID, newToken.AccessToken, refreshToken, &newToken.Expiry); err != nil {
-		return fmt.Errorf("failed to update token in database: %w", err)
-	}
-
-	// Update the account object with new tokens for immediate use
-	account.AccessToken = &newToken.AccessToken
-	account.RefreshToken = &refreshToken
-	account.Expiry = &newToken.Expiry
-
-	s.logger.Info("Successfully refreshed Google token",
-		zap.Uint64("user_id", account.UserID),
-		zap.Time("new_expiry", newToken.Expiry))
-
-	return nil
-}
-
-// fetchCalendarsFromGoogle calls the Google Calendar API to get user's calendars
-func (s *CalendarService) fetchCalendarsFromGoogle(accessToken string) ([]*model.GoogleCalendar, error) {
-	// Create HTTP client with OAuth2 transport
-	ctx := context.Background()
-	oauthToken := &oauth2.Token{AccessToken: accessToken}
-	client := s.oauthConfig.Google.Client(ctx, oauthToken)
-
-	// Call Google Calendar API
-	url := "https://www.googleapis.com/calendar/v3/users/me/calendarList"
-	resp, err := client.Get(url)
-	if err != nil {
-		return nil, fmt.Errorf("failed to call Google Calendar API: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("google Calendar API error: status %d, body: %s", resp.StatusCode, string(body))
-	}
-
-	// Parse response
-	var calendarList struct {
-		Items []*model.GoogleCalendar `json:"items"`
-	}
-
-	if err := json.NewDecoder(resp.Body).Decode(&calendarList); err != nil {
-		return nil, fmt.Errorf("failed to decode Google Calendar API response: %w", err)
-	}
-
-	return calendarList.Items, nil
-}
-
 // ImportCalendar imports a Google calendar to the database along with its events
 func (s *CalendarService) ImportCalendar(userID uint64, calendarID string) (*model.Calendar, error) {
-	// Get user's Google account
-	account, err := s.userRepo.FindGoogleAccountByUserID(userID)
+	ctx := context.Background()
+	credentials, err := s.google.userCredentials(ctx, userID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get Google account: %w", err)
-	}
-
-	// Check if account has tokens
-	if account.AccessToken == nil || account.RefreshToken == nil {
-		return nil, fmt.Errorf("google account not properly configured with OAuth tokens")
-	}
-
-	// Check if token needs refresh
-	if err := s.refreshTokenIfNeeded(account); err != nil {
-		return nil, fmt.Errorf("failed to refresh token: %w", err)
+		return nil, err
 	}
 
 	// Fetch specific calendar from Google API
-	googleCalendar, err := s.fetchCalendarFromGoogle(*account.AccessToken, calendarID)
+	googleCalendar, err := s.google.fetchCalendar(ctx, credentials.AccessToken, calendarID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch calendar from Google: %w", err)
 	}
@@ -474,7 +317,6 @@ func (s *CalendarService) ImportCalendar(userID uint64, calendarID string) (*mod
 		Source:       model.SourceGoogle,
 		Summary:      googleCalendar.Summary,
 		TimeZone:     googleCalendar.TimeZone,
-		EventColor:   &googleCalendar.BackgroundColor,
 		Description:  &googleCalendar.Description,
 		Visibility:   model.CalendarVisibilityPrivate,
 		SyncedAt:     time.Now(),
@@ -482,67 +324,40 @@ func (s *CalendarService) ImportCalendar(userID uint64, calendarID string) (*mod
 		SyncToken:    nil,
 		LastFullSync: nil,
 	}
+	calendar.BackgroundColor = optionalColor(googleCalendar.BackgroundColor)
+	// The initial sync holds the sync lease, so the scheduler does not sync the calendar meanwhile
+	initialSyncLease(calendar)
 
 	// Save calendar to database
 	if err := s.calendarRepo.Create(calendar); err != nil {
 		return nil, fmt.Errorf("failed to save calendar to database: %w", err)
 	}
+	lease := s.holdSyncLease(calendar.ID, *calendar.SyncLeaseToken)
 
 	// Fetch events from Google Calendar API
 	s.logger.Info("Fetching events for calendar",
 		zap.String("calendar_id", calendarID),
 		zap.Uint64("db_calendar_id", calendar.ID))
 
-	googleEvents, err := s.fetchEventsFromGoogle(*account.AccessToken, calendarID)
+	result, err := s.SyncCalendar(calendar, true)
+	s.finishInitialSync(calendar, lease, err)
 	if err != nil {
 		s.logger.Error("Failed to fetch events from Google",
 			zap.Error(err),
 			zap.String("calendar_id", calendarID))
 		// Don't fail the import if events can't be fetched, just log the error
 	} else {
-		// Convert and store events
-		var events []*model.CalendarEvent
-		for _, googleEvent := range googleEvents {
-			// Skip events without summary (cancelled events, etc.)
-			if googleEvent.Summary == "" {
-				continue
-			}
-
-			event, err := s.convertGoogleEventToCalendarEvent(googleEvent, calendar.ID)
-			if err != nil {
-				s.logger.Error("Failed to convert Google event",
-					zap.Error(err),
-					zap.String("event_id", googleEvent.ID))
-				continue
-			}
-
-			// Check if event already exists
-			exists, err := s.calendarRepo.ExistsEventBySourceID(googleEvent.ID)
-			if err != nil {
-				s.logger.Error("Failed to check if event exists",
-					zap.Error(err),
-					zap.String("event_id", googleEvent.ID))
-				continue
-			}
-
-			if !exists {
-				events = append(events, event)
-			}
-		}
+		s.logger.Info("Successfully stored events",
+			zap.Int("event_count", result.Added),
+			zap.Uint64("calendar_id", calendar.ID))
+	}
 
-		// Store events in batches
-		if len(events) > 0 {
-			if err := s.calendarRepo.CreateEvents(events); err != nil {
-				s.logger.Error("Failed to store events",
-					zap.Error(err),
-					zap.Int("event_count", len(events)))
-			} else {
-				s.logger.Info("Successfully stored events",
-					zap.Int("event_count", len(events)),
-					zap.Uint64("calendar_id", calendar.ID))
-			}
-		} else {
-			s.logger.Info("No new events to store", zap.Uint64("calendar_id", calendar.ID))
+	// Sync the calendar whenever it changes instead of waiting for the next background sync
+	if s.watchEnabled() {
+		if err := s.startWatchChannel(credentials.AccessToken, calendar); err != nil {
+			s.logger.Warn("Failed to open watch channel, the renewer will retry",
+				zap.Error(err),
+				zap.Uint64("calendar_id", calendar.ID))
 		}
 	}
 
@@ -555,297 +370,6 @@ func (s *CalendarService) ImportCalendar(userID uint64, calendarID string) (*mod
 	return calendar, nil
 }
 
-// fetchCalendarFromGoogle calls the Google Calendar API to get a specific calendar
-func (s *CalendarService) fetchCalendarFromGoogle(accessToken, calendarID string) (*model.GoogleCalendar, error) {
-	// Create HTTP client with OAuth2 transport
-	ctx := context.Background()
-	oauthToken := &oauth2.Token{AccessToken: accessToken}
-	client := s.oauthConfig.Google.Client(ctx, oauthToken)
-
-	// Call Google Calendar API list endpoint to get calendar with color info
-	url := "https://www.googleapis.com/calendar/v3/users/me/calendarList"
-	resp, err := client.Get(url)
-	if err != nil {
-		return nil, fmt.Errorf("failed to call Google Calendar API: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("google Calendar API error: status %d, body: %s", resp.StatusCode, string(body))
-	}
-
-	// Parse response
-	var calendarList struct {
-		Items []model.GoogleCalendar `json:"items"`
-	}
-	if err := json.NewDecoder(resp.Body).Decode(&calendarList); err != nil {
-		return nil, fmt.Errorf("failed to decode Google Calendar API response: %w", err)
-	}
-
-	// Find the specific calendar in the list
-	for _, calendar := range calendarList.Items {
-		if calendar.ID == calendarID {
-			return &calendar, nil
-		}
-	}
-
-	return nil, fmt.Errorf("calendar not found in list")
-}
-
-// fetchEventsFromGoogle calls the Google Calendar API to get events for a specific calendar (full sync)
-func (s *CalendarService) fetchEventsFromGoogle(accessToken, calendarID string) ([]*model.GoogleCalendarEvent, error) {
-	response, err := s.fetchEventsFromGoogleWithResponse(accessToken, calendarID, "", false)
-	if err != nil {
-		return nil, err
-	}
-	return response.Items, nil
-}
-
-// fetchEventsFromGoogleWithResponse calls the Google Calendar API and returns the full response including sync tokens
-func (s *CalendarService) fetchEventsFromGoogleWithResponse(accessToken, calendarID, syncToken string, isIncrementalSync bool) (*model.GoogleCalendarEventsResponse, error) {
-	// Create HTTP client with OAuth2 transport
-	ctx := context.Background()
-	oauthToken := &oauth2.Token{AccessToken: accessToken}
-	client := s.oauthConfig.Google.Client(ctx, oauthToken)
-
-	// Call Google Calendar API events endpoint
-	url := fmt.Sprintf("https://www.googleapis.com/calendar/v3/calendars/%s/events", calendarID)
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
-
-	// Add query parameters
-	q := req.URL.Query()
-
-	if isIncrementalSync && syncToken != "" {
-		// For incremental sync, use sync token
-		q.Add("syncToken", syncToken)
-	} else {
-		// For full sync, use time range
-		now := time.Now()
-		timeMin := now.AddDate(-1, 0, 0).Format(time.RFC3339) // 1 year of historical data
-		timeMax := now.AddDate(1, 0, 0).Format(time.RFC3339)  // 1 year forward
-		q.Add("timeMin", timeMin)
-		q.Add("timeMax", timeMax)
-		q.Add("singleEvents", "true")
-		q.Add("orderBy", "startTime")
-	}
-
-	// CRITICAL: Include deleted events in response for proper sync
-	q.Add("showDeleted", "true")
-	q.Add("maxResults", "2500") // Maximum allowed by Google
-	req.URL.RawQuery = q.Encode()
-
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to call Google Calendar API: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("google Calendar API error: status %d, body: %s", resp.StatusCode, string(body))
-	}
-
-	// Parse response
-	var eventsResponse model.GoogleCalendarEventsResponse
-	if err := json.NewDecoder(resp.Body).Decode(&eventsResponse); err != nil {
-		return nil, fmt.Errorf("failed to decode Google Calendar API response: %w", err)
-	}
-
-	return &eventsResponse, nil
-}
-
-// fetchAllEventsWithSyncToken fetches all events using sync token, handling pagination
-func (s *CalendarService) fetchAllEventsWithSyncToken(accessToken, calendarID, syncToken string) (*model.GoogleCalendarEventsResponse, error) {
-	var allEvents []*model.GoogleCalendarEvent
-	var nextSyncToken string
-	pageToken := ""
-
-	for {
-		response, err := s.fetchEventsPageWithSyncToken(accessToken, calendarID, syncToken, pageToken)
-		if err != nil {
-			return nil, err
-		}
-
-		// Accumulate events
-		allEvents = append(allEvents, response.Items...)
-
-		// Store the sync token (only present on the last page)
-		if response.NextSyncToken != "" {
-			nextSyncToken = response.NextSyncToken
-		}
-
-		// Check if there are more pages
-		if response.NextPageToken == "" {
-			break
-		}
-
-		pageToken = response.NextPageToken
-	}
-
-	// Return consolidated response
-	return &model.GoogleCalendarEventsResponse{
-		Kind:          "calendar#events",
-		Items:         allEvents,
-		NextSyncToken: nextSyncToken,
-	}, nil
-}
-
-// fetchEventsPageWithSyncToken fetches a single page of events with sync token
-func (s *CalendarService) fetchEventsPageWithSyncToken(accessToken, calendarID, syncToken, pageToken string) (*model.GoogleCalendarEventsResponse, error) {
-	// Create HTTP client with OAuth2 transport
-	ctx := context.Background()
-	oauthToken := &oauth2.Token{AccessToken: accessToken}
-	client := s.oauthConfig.Google.Client(ctx, oauthToken)
-
-	// Call Google Calendar API events endpoint
-	url := fmt.Sprintf("https://www.googleapis.com/calendar/v3/calendars/%s/events", calendarID)
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
-
-	// Add query parameters
-	q := req.URL.Query()
-
-	if syncToken != "" {
-		q.Add("syncToken", syncToken)
-	}
-
-	if pageToken != "" {
-		q.Add("pageToken", pageToken)
-	}
-
-	// CRITICAL: Include deleted events in response for proper sync
-	q.Add("showDeleted", "true")
-	q.Add("maxResults", "2500") // Maximum allowed by Google
-	req.URL.RawQuery = q.Encode()
-
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to call Google Calendar API: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("google Calendar API error: status %d, body: %s", resp.StatusCode, string(body))
-	}
-
-	// Parse response
-	var eventsResponse model.GoogleCalendarEventsResponse
-	if err := json.NewDecoder(resp.Body).Decode(&eventsResponse); err != nil {
-		return nil, fmt.Errorf("failed to decode Google Calendar API response: %w", err)
-	}
-
-	return &eventsResponse, nil
-}
-
-// isEventDeleted checks if a Google Calendar event is marked as deleted
-func (s *CalendarService) isEventDeleted(googleEvent *model.GoogleCalendarEvent) bool {
-	// Google Calendar API returns deleted events with status "cancelled"
-	// Also check for empty/minimal events which can indicate deletion
-	isDeleted := googleEvent.Status == "cancelled"
-
-	s.logger.Debug("Checking event deletion status",
-		zap.String("event_id", googleEvent.ID),
-		zap.String("status", googleEvent.Status),
-		zap.String("summary", googleEvent.Summary),
-		zap.Bool("is_deleted", isDeleted))
-
-	return isDeleted
-}
-
-// processSyncResponse processes the sync response and categorizes events for CRUD operations
-func (s *CalendarService) processSyncResponse(response *model.GoogleCalendarEventsResponse, calendarID uint64) (*SyncEventChanges, error) {
-	s.logger.Info("Processing sync response",
-		zap.Uint64("calendar_id", calendarID),
-		zap.Int("total_events_from_google", len(response.Items)),
-		zap.String("next_sync_token", func() string {
-			if len(response.NextSyncToken) > 20 {
-				return response.NextSyncToken[:20] + "..."
-			}
-			return response.NextSyncToken
-		}()))
-
-	changes := &SyncEventChanges{
-		ToCreate: []*model.CalendarEvent{},
-		ToUpdate: []*model.CalendarEvent{},
-		ToDelete: []string{},
-	}
-
-	// Get existing events for comparison
-	existingEvents, err := s.calendarRepo.FindEventsByCalendarID(calendarID)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get existing events: %w", err)
-	}
-
-	// Create a map of existing events by source ID for efficient lookup
-	existingEventMap := make(map[string]*model.CalendarEvent)
-	for _, event := range existingEvents {
-		existingEventMap[event.SourceID] = event
-	}
-
-	// Process each event from Google Calendar
-	for _, googleEvent := range response.Items {
-		if s.isEventDeleted(googleEvent) {
-			// Event is deleted - add to delete list if it exists locally
-			if existingEvent, exists := existingEventMap[googleEvent.ID]; exists {
-				changes.ToDelete = append(changes.ToDelete, existingEvent.SourceID)
-				s.logger.Info("Event marked for deletion - found locally",
-					zap.String("event_id", googleEvent.ID),
-					zap.String("event_title", googleEvent.Summary),
-					zap.String("status", googleEvent.Status),
-					zap.Uint64("calendar_id", calendarID))
-			} else {
-				s.logger.Info("Deleted event not found locally - skipping",
-					zap.String("event_id", googleEvent.ID),
-					zap.String("event_title", googleEvent.Summary),
-					zap.String("status", googleEvent.Status),
-					zap.Uint64("calendar_id", calendarID))
-			}
-			continue
-		}
-
-		// Skip events without summary (some system events)
-		if googleEvent.Summary == "" {
-			continue
-		}
-
-		// Convert Google event to our format
-		event, err := s.convertGoogleEventToCalendarEvent(googleEvent, calendarID)
-		if err != nil {
-			s.logger.Error("Failed to convert Google event",
-				zap.Error(err),
-				zap.String("event_id", googleEvent.ID))
-			continue
-		}
-
-		// Check if event exists locally
-		if existingEvent, exists := existingEventMap[googleEvent.ID]; exists {
-			// Update existing event
-			event.ID = existingEvent.ID // Preserve local ID
-			changes.ToUpdate = append(changes.ToUpdate, event)
-		} else {
-			// Create new event
-			changes.ToCreate = append(changes.ToCreate, event)
-		}
-	}
-
-	// Log sync operation summary
-	s.logger.Info("Sync response processing completed",
-		zap.Uint64("calendar_id", calendarID),
-		zap.Int("existing_events_count", len(existingEventMap)),
-		zap.Int("events_to_create", len(changes.ToCreate)),
-		zap.Int("events_to_update", len(changes.ToUpdate)),
-		zap.Int("events_to_delete", len(changes.ToDelete)))
-
-	return changes, nil
-}
-
 // SyncEventChanges represents the changes to apply during sync
 type SyncEventChanges struct {
 	ToCreate []*model.CalendarEvent
@@ -893,7 +417,7 @@ func (s *CalendarService) applySyncChanges(changes *SyncEventChanges, calendarID
 				zap.String("source_id", sourceID),
 				zap.Uint64("calendar_id", calendarID))
 
-			if err := s.calendarRepo.DeleteEventsBySourceID(sourceID); err != nil {
+			if err := s.calendarRepo.DeleteEventsByCalendarIDAndSourceID(calendarID, sourceID); err != nil {
 				deletionErrors = append(deletionErrors, fmt.Sprintf("sourceID:%s error:%v", sourceID, err))
 				s.logger.Error("Failed to delete event",
 					zap.Error(err),
@@ -924,70 +448,6 @@ func (s *CalendarService) applySyncChanges(changes *SyncEventChanges, calendarID
 	return nil
 }
 
-// convertGoogleEventToCalendarEvent converts a Google Calendar event to our CalendarEvent model
-func (s *CalendarService) convertGoogleEventToCalendarEvent(googleEvent *model.GoogleCalendarEvent, calendarID uint64) (*model.CalendarEvent, error) {
-	// Parse start time
-	var startTime time.Time
-	var allDay bool
-
-	if googleEvent.Start.DateTime != "" {
-		// Timed event
-		parsedTime, err := time.Parse(time.RFC3339, googleEvent.Start.DateTime)
-		if err != nil {
-			return nil, fmt.Errorf("failed to parse start time: %w", err)
-		}
-		startTime = parsedTime
-		allDay = false
-	} else if googleEvent.Start.Date != "" {
-		// All-day event
-		parsedDate, err := time.Parse("2006-01-02", googleEvent.Start.Date)
-		if err != nil {
-			return nil, fmt.Errorf("failed to parse start date: %w", err)
-		}
-		startTime = parsedDate
-		allDay = true
-	} else {
-		return nil, fmt.Errorf("event has no start time")
-	}
-
-	// Parse end time
-	var endTime time.Time
-	if googleEvent.End.DateTime != "" {
-		// Timed event
-		parsedTime, err := time.Parse(time.RFC3339, googleEvent.End.DateTime)
-		if err != nil {
-			return nil, fmt.Errorf("failed to parse end time: %w", err)
-		}
-		endTime = parsedTime
-	} else if googleEvent.End.Date != "" {
-		// All-day event
-		parsedDate, err := time.Parse("2006-01-02", googleEvent.End.Date)
-		if err != nil {
-			return nil, fmt.Errorf("failed to parse end date: %w", err)
-		}
-		endTime = parsedDate
-	} else {
-		return nil, fmt.Errorf("event has no end time")
-	}
-
-	// Create calendar event
-	event := &model.CalendarEvent{
-		ID:          utils.GenerateID(),
-		SourceID:    googleEvent.ID,
-		CalendarID:  calendarID,
-		Title:       googleEvent.Summary,
-		Start:       startTime,
-		End:         endTime,
-		AllDay:      allDay,
-		EventColor:  googleEvent.ColorID,
-		Location:    googleEvent.Location,
-		Description: googleEvent.Description,
-		Visibility:  model.CalendarEventVisibilityInherited,
-	}
-
-	return event, nil
-}
-
 // GetUserCalendarEvents retrieves all events for a user's calendars within a specified time range with smart sync
 func (s *CalendarService) GetUserCalendarEvents(userID uint64, startTime, endTime time.Time) ([]*model.CalendarWithEvents, error) {
 	return s.GetUserCalendarEventsWithSync(userID, startTime, endTime, false)
@@ -995,10 +455,8 @@ func (s *CalendarService) GetUserCalendarEvents(userID uint64, startTime, endTim
 
 // GetUserCalendarEventsWithSync retrieves events with optional force sync
 func (s *CalendarService) GetUserCalendarEventsWithSync(userID uint64, startTime, endTime time.Time, forceSync bool) ([]*model.CalendarWithEvents, error) {
-	// Validate time range (max 6 months)
-	sixMonths := startTime.AddDate(0, 6, 0)
-	if endTime.After(sixMonths) {
-		return nil, fmt.Errorf("time range cannot exceed 6 months")
+	if err := validateEventTimeRange(startTime, endTime); err != nil {
+		return nil, err
 	}
 
 	// Get all user's calendars
@@ -1011,11 +469,14 @@ func (s *CalendarService) GetUserCalendarEventsWithSync(userID uint64, startTime
 		return []*model.CalendarWithEvents{}, nil
 	}
 
-	// Use the reusable sync function
-	synced, err := s.SyncIfNeeded(userID, forceSync)
-	if err != nil {
-		s.logger.Error("Failed to sync calendars", zap.Error(err))
-		// Continue with cached data even if sync fails
+	// Calendars are kept up to date by the background scheduler, only sync here when asked to
+	synced := false
+	if forceSync {
+		synced, err = s.SyncUserCalendarsNow(userID)
+		if err != nil {
+			s.logger.Error("Failed to sync calendars", zap.Error(err))
+			// Continue with cached data even if sync fails
+		}
 	}
 
 	// Extract calendar IDs
@@ -1029,6 +490,9 @@ func (s *CalendarService) GetUserCalendarEventsWithSync(userID uint64, startTime
 	if err != nil {
 		return nil, fmt.Errorf("failed to get calendar events: %w", err)
 	}
+	events = withoutHiddenDeclined(calendars, events)
+	s.loadEventAttendees(events)
+	s.markWriteConflicts(events)
 
 	// Group events by calendar ID
 	eventsByCalendar := make(map[uint64][]*model.CalendarEvent)
@@ -1044,8 +508,9 @@ func (s *CalendarService) GetUserCalendarEventsWithSync(userID uint64, startTime
 			calendarEvents = []*model.CalendarEvent{}
 		}
 
-		// Apply calendar event redaction to event titles
-		s.applyEventRedaction(calendarEvents, calendar)
+		// Events without a color of their own are shown in the calendar's color. The owner sees
+		// their events unredacted, redaction only applies to what is rendered publicly
+		applyCalendarColor(calendarEvents, calendar)
 
 		calendarWithEvents := &model.CalendarWithEvents{
 			Calendar: calendar,
@@ -1079,165 +544,25 @@ func (s *CalendarService) SyncCalendarEvents(userID uint64, calendarID string) e
 	return s.SyncCalendarEventsWithForce(userID, calendarID, false)
 }
 
-// SyncCalendarEventsWithForce synchronizes events for a specific calendar with optional force sync
+// SyncCalendarEventsWithForce synchronizes events for a specific Google calendar with optional force sync
 func (s *CalendarService) SyncCalendarEventsWithForce(userID uint64, calendarID string, forceSync bool) error {
 	s.logger.Info("Starting calendar event sync",
 		zap.Uint64("user_id", userID),
 		zap.String("calendar_id", calendarID),
 		zap.Bool("force_sync", forceSync))
 
-	// Get user's Google account
-	account, err := s.userRepo.FindGoogleAccountByUserID(userID)
-	if err != nil {
-		return fmt.Errorf("failed to get Google account: %w", err)
-	}
-
-	// Check if account has tokens
-	if account.AccessToken == nil || account.RefreshToken == nil {
-		return fmt.Errorf("google account not properly configured with OAuth tokens")
-	}
-
-	// Check if token needs refresh
-	if err := s.refreshTokenIfNeeded(account); err != nil {
-		return fmt.Errorf("failed to refresh token: %w", err)
-	}
-
 	// Get local calendar
-	localCalendar, err := s.calendarRepo.FindBySourceID(calendarID)
+	localCalendar, err := s.calendarRepo.FindByUserIDAndSourceID(userID, calendarID)
 	if err != nil {
 		return fmt.Errorf("failed to find local calendar: %w", err)
 	}
 
-	// Determine sync strategy using SyncTokenManager
-	shouldPerformFullSync := s.syncTokenManager.ShouldPerformFullSync(localCalendar, forceSync)
-
-	var response *model.GoogleCalendarEventsResponse
-	var isFullSync bool
-
-	if shouldPerformFullSync {
-		// Perform full sync
-		s.logger.Info("Performing full sync with deleted events",
-			zap.Uint64("calendar_id", localCalendar.ID),
-			zap.String("google_calendar_id", calendarID),
-			zap.String("reason", "full sync required"))
-
-		response, err = s.fetchEventsFromGoogleWithResponse(*account.AccessToken, calendarID, "", false)
-		if err != nil {
-			return fmt.Errorf("failed to fetch events from Google (full sync): %w", err)
-		}
-		isFullSync = true
-
-		// Clear existing sync token since we're doing a full sync
-		if err := s.syncTokenManager.ClearSyncToken(localCalendar.ID); err != nil {
-			s.logger.Warn("Failed to clear sync token", zap.Error(err))
-		}
-
-		s.logger.Info("Full sync API request completed",
-			zap.Int("events_received", len(response.Items)),
-			zap.String("next_sync_token", func() string {
-				if len(response.NextSyncToken) > 20 {
-					return response.NextSyncToken[:20] + "..."
-				}
-				return response.NextSyncToken
-			}()))
-	} else {
-		// Perform incremental sync
-		syncToken, err := s.syncTokenManager.GetSyncToken(localCalendar.ID)
-		if err != nil {
-			s.logger.Error("Failed to get sync token, falling back to full sync", zap.Error(err))
-			// Fallback to full sync
-			response, err = s.fetchEventsFromGoogleWithResponse(*account.AccessToken, calendarID, "", false)
-			if err != nil {
-				return fmt.Errorf("failed to fetch events from Google (fallback full sync): %w", err)
-			}
-			isFullSync = true
-		} else {
-			s.logger.Info("Performing incremental sync with deleted events",
-				zap.Uint64("calendar_id", localCalendar.ID),
-				zap.String("google_calendar_id", calendarID),
-				zap.String("sync_token", syncToken[:min(len(syncToken), 20)]+"..."))
-
-			response, err = s.fetchAllEventsWithSyncToken(*account.AccessToken, calendarID, syncToken)
-			if err != nil {
-				// Check if it's a sync token invalidation error (410)
-				if s.isSyncTokenInvalidError(err) {
-					s.logger.Warn("Sync token invalid, performing full sync",
-						zap.Error(err),
-						zap.Uint64("calendar_id", localCalendar.ID))
-
-					// Clear invalid sync token and perform full sync
-					if clearErr := s.syncTokenManager.ClearSyncToken(localCalendar.ID); clearErr != nil {
-						s.logger.Warn("Failed to clear invalid sync token", zap.Error(clearErr))
-					}
-
-					response, err = s.fetchEventsFromGoogleWithResponse(*account.AccessToken, calendarID, "", false)
-					if err != nil {
-						return fmt.Errorf("failed to fetch events from Google (recovery full sync): %w", err)
-					}
-					isFullSync = true
-				} else {
-					return fmt.Errorf("failed to fetch events from Google (incremental sync): %w", err)
-				}
-			} else {
-				isFullSync = false
-				s.logger.Info("Incremental sync API request completed",
-					zap.Int("events_received", len(response.Items)),
-					zap.String("next_sync_token", func() string {
-						if len(response.NextSyncToken) > 20 {
-							return response.NextSyncToken[:20] + "..."
-						}
-						return response.NextSyncToken
-					}()))
-			}
-		}
-	}
-
-	// Process the sync response and get changes
-	changes, err := s.processSyncResponse(response, localCalendar.ID)
-	if err != nil {
-		return fmt.Errorf("failed to process sync response: %w", err)
-	}
-
-	// Apply changes to database
-	if err := s.applySyncChanges(changes, localCalendar.ID); err != nil {
-		return fmt.Errorf("failed to apply sync changes: %w", err)
-	}
-
-	// Update sync metadata
-	if response.NextSyncToken != "" {
-		if err := s.syncTokenManager.UpdateSyncMetadata(localCalendar.ID, response.NextSyncToken, isFullSync); err != nil {
-			s.logger.Error("Failed to update sync metadata", zap.Error(err))
-			// Don't fail the sync operation for metadata update failures
-		}
+	if localCalendar.Source != model.SourceGoogle {
+		return fmt.Errorf("calendar is not a Google calendar")
 	}
 
-	s.logger.Info("Successfully synced calendar events",
-		zap.Uint64("user_id", userID),
-		zap.String("calendar_id", calendarID),
-		zap.Uint64("local_calendar_id", localCalendar.ID),
-		zap.Bool("is_full_sync", isFullSync),
-		zap.Int("created_events", len(changes.ToCreate)),
-		zap.Int("updated_events", len(changes.ToUpdate)),
-		zap.Int("deleted_events", len(changes.ToDelete)))
-
-	return nil
-}
-
-// isSyncTokenInvalidError checks if an error indicates sync token invalidation (410 Gone)
-func (s *CalendarService) isSyncTokenInvalidError(err error) bool {
-	if err == nil {
-		return false
-	}
-	errStr := err.Error()
-	return strings.Contains(errStr, "status 410") || strings.Contains(errStr, "Gone")
-}
-
-// min returns the minimum of two integers
-func min(a, b int) int {
-	if a < b {
-		return a
-	}
-	return b
+	_, err = s.SyncCalendar(localCalendar, forceSync)
+	return err
 }
 
 // ImportICSCalendar creates a calendar from ICS data and imports all events
@@ -1247,48 +572,12 @@ func (s *CalendarService) ImportICSCalendar(userID uint64, calendarName string,
 		zap.String("calendar_name", calendarName),
 		zap.Int("events_count", len(icsEvents)))
 
-	// Create the calendar
-	calendar := &model.Calendar{
-		ID:           utils.GenerateID(),
-		UserID:       userID,
-		Source:       model.SourceICS,
-		Summary:      calendarName,
-		TimeZone:     "UTC", // Default timezone, could be extracted from ICS if available
-		Visibility:   model.CalendarVisibilityPrivate,
-		SyncedAt:     time.Now(),
-		SyncStatus:   model.CalendarSyncStatusFullSyncComplete, // ICS imports are considered complete
-		SyncToken:    nil,                                      // ICS calendars don't use sync tokens
-		LastFullSync: func() *time.Time { now := time.Now(); return &now }(),
-	}
-
-	// Save calendar to database
-	if err := s.calendarRepo.Create(calendar); err != nil {
-		return nil, 0, fmt.Errorf("failed to create calendar: %w", err)
-	}
-
-	// Convert and import events
-	var calendarEvents []*model.CalendarEvent
-	successCount := 0
-
-	for _, icsEvent := range icsEvents {
-		event, err := s.convertICSEventToCalendarEvent(icsEvent, calendar.ID)
-		if err != nil {
-			s.logger.Error("Failed to convert ICS event",
-				zap.Error(err),
-				zap.String("event_id", icsEvent.Id()))
-			continue
-		}
-
-		calendarEvents = append(calendarEvents, event)
-		successCount++
-	}
-
-	// Batch create events
-	if len(calendarEvents) > 0 {
-		if err := s.calendarRepo.CreateEvents(calendarEvents); err != nil {
-			s.logger.Error("Failed to create events", zap.Error(err))
-			return nil, 0, fmt.Errorf("failed to create events: %w", err)
-		}
+	// Create the calendar in the time zone declared by the file and import its events
+	calendar := s.newICSCalendar(userID, calendarName, icsCalendarTimeZone(icsCalendar))
+	calendar.BackgroundColor = icsCalendarColor(icsCalendar)
+	successCount, err := s.createICSCalendar(calendar, s.ics.timeZones(icsCalendar, calendar.TimeZone), icsEvents)
+	if err != nil {
+		return nil, 0, err
 	}
 
 	s.logger.Info("Successfully imported ICS calendar",
@@ -1301,7 +590,7 @@ func (s *CalendarService) ImportICSCalendar(userID uint64, calendarName string,
 }
 
 // convertICSEventToCalendarEvent converts an ICS event to our internal CalendarEvent format
-func (s *CalendarService) convertICSEventToCalendarEvent(icsEvent *ics.VEvent, calendarID uint64) (*model.CalendarEvent, error) {
+func (p *icsProvider) convertICSEventToCalendarEvent(icsEvent *ics.VEvent, calendarID uint64, zones *ical.TimeZones) (*model.CalendarEvent, error) {
 	// Extract basic event information
 	summary := icsEvent.GetProperty(ics.ComponentPropertySummary)
 	if summary == nil {
@@ -1314,30 +603,24 @@ func (s *CalendarService) convertICSEventToCalendarEvent(icsEvent *ics.VEvent, c
 		return nil, fmt.Errorf("event missing start time")
 	}
 
-	// Extract end time
-	dtEnd := icsEvent.GetProperty(ics.ComponentPropertyDtEnd)
-	if dtEnd == nil {
-		return nil, fmt.Errorf("event missing end time")
-	}
-
 	// Parse start time
-	startTime, err := s.parseICSDateTime(dtStart.Value, dtStart.ICalParameters)
+	startTime, err := p.parseICSDateTime(dtStart.Value, dtStart.ICalParameters, zones)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse start time: %w", err)
 	}
 
-	// Parse end time
-	endTime, err := s.parseICSDateTime(dtEnd.Value, dtEnd.ICalParameters)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse end time: %w", err)
-	}
-
 	// Determine if it's an all-day event
 	allDay := false
 	if valueParams, exists := dtStart.ICalParameters["VALUE"]; exists && len(valueParams) > 0 {
 		allDay = valueParams[0] == "DATE"
 	}
 
+	// Parse end time from DTEND, falling back to DURATION as allowed by RFC 5545
+	endTime, err := p.parseICSEndTime(icsEvent, startTime, allDay, zones)
+	if err != nil {
+		return nil, err
+	}
+
 	// Extract optional fields
 	description := ""
 	if desc := icsEvent.GetProperty(ics.ComponentPropertyDescription); desc != nil {
@@ -1349,6 +632,14 @@ func (s *CalendarService) convertICSEventToCalendarEvent(icsEvent *ics.VEvent, c
 		location = loc.Value
 	}
 
+	// RFC 7986 colors are CSS3 color names, they are stored as hex colors like any other color
+	eventColor := ""
+	if color := icsEvent.GetProperty(ics.ComponentPropertyColor); color != nil {
+		if parsed, err := ical.ParseColor(color.Value); err == nil {
+			eventColor = parsed
+		}
+	}
+
 	// Create calendar event
 	event := &model.CalendarEvent{
 		ID:          utils.GenerateID(),
@@ -1358,33 +649,47 @@ func (s *CalendarService) convertICSEventToCalendarEvent(icsEvent *ics.VEvent, c
 		Start:       startTime,
 		End:         endTime,
 		AllDay:      allDay,
+		EventColor:  eventColor,
 		Location:    location,
 		Description: description,
 		Visibility:  model.CalendarEventVisibilityInherited,
+		Attendees:   convertICSAttendees(icsEvent),
+		// Cancelled occurrences are kept, they no longer block time
+		Status:       icsEventStatus(icsEvent),
+		Transparency: icsEventTransparency(icsEvent),
 	}
+	// A location that is nothing but a link is taken as the conference link, whatever its service
+	setConferenceLink(event, append(icsConferenceLinks(icsEvent), location), location, description)
 
 	return event, nil
 }
 
-// parseICSDateTime parses ICS date/time strings
-func (s *CalendarService) parseICSDateTime(value string, params map[string][]string) (time.Time, error) {
+// parseICSDateTime parses ICS date/time strings. TZIDs are resolved through zones, which knows the
+// IANA and Windows time zone names and the VTIMEZONE definitions of the file, and floating times
+// are interpreted in the time zone of the calendar.
+func (p *icsProvider) parseICSDateTime(value string, params map[string][]string, zones *ical.TimeZones) (time.Time, error) {
 	// Check if it's a DATE value (all-day event)
 	if valueParams, exists := params["VALUE"]; exists && len(valueParams) > 0 && valueParams[0] == "DATE" {
 		// Parse date only: YYYYMMDD
 		return time.Parse("20060102", value)
 	}
 
+	// Check if it ends with 'Z' (UTC time), which takes precedence over any TZID
+	if strings.HasSuffix(value, "Z") {
+		return time.Parse("20060102T150405Z", value)
+	}
+
 	// Check for timezone information
 	if tzidParams, exists := params["TZID"]; exists && len(tzidParams) > 0 {
 		tzid := tzidParams[0]
 		// Parse with timezone: YYYYMMDDTHHMMSS with TZID
-		loc, err := time.LoadLocation(tzid)
-		if err != nil {
-			// If timezone loading fails, use UTC
-			s.logger.Warn("Failed to load timezone, using UTC",
+		loc, ok := zones.Resolve(tzid)
+		if !ok {
+			// If the timezone is unknown, treat the time as floating
+			p.logger.Warn("Unknown timezone, using calendar timezone",
 				zap.String("tzid", tzid),
-				zap.Error(err))
-			loc = time.UTC
+				zap.String("timezone", zones.Floating().String()))
+			loc = zones.Floating()
 		}
 
 		parsedTime, err := time.ParseInLocation("20060102T150405", value, loc)
@@ -1394,16 +699,12 @@ func (s *CalendarService) parseICSDateTime(value string, params map[string][]str
 		return parsedTime, nil
 	}
 
-	// Check if it ends with 'Z' (UTC time)
-	if strings.HasSuffix(value, "Z") {
-		return time.Parse("20060102T150405Z", value)
-	}
-
-	// Default: parse as local time
-	return time.Parse("20060102T150405", value)
+	// Default: parse floating time in the calendar's timezone
+	return time.ParseInLocation("20060102T150405", value, zones.Floating())
 }
 
-// GetImportedCalendars retrieves all imported calendars for a user
+// GetImportedCalendars retrieves all imported calendars for a user, with the sync health of those
+// synced with a remote source
 func (s *CalendarService) GetImportedCalendars(userID uint64) ([]*model.Calendar, error) {
 	s.logger.Info("Getting imported calendars for user", zap.Uint64("user_id", userID))
 
@@ -1417,6 +718,8 @@ func (s *CalendarService) GetImportedCalendars(userID uint64) ([]*model.Calendar
 		zap.Uint64("user_id", userID),
 		zap.Int("calendar_count", len(calendars)))
 
+	s.setCalendarHealth(calendars)
+
 	return calendars, nil
 }
 
@@ -1451,18 +754,72 @@ func (s *CalendarService) UpdateCalendar(userID uint64, calendarID string, updat
 		calendar.EventRedaction = updateRequest.EventRedaction
 		updated = true
 	}
+	if updateRequest.RedactionMode != nil {
+		if !isValidRedactionMode(*updateRequest.RedactionMode) {
+			return nil, fmt.Errorf("invalid calendar: unknown redaction mode %q", *updateRequest.RedactionMode)
+		}
+		calendar.RedactionMode = *updateRequest.RedactionMode
+		updated = true
+	}
 	if updateRequest.EventColor != nil {
-		calendar.EventColor = updateRequest.EventColor
+		color, err := normalizeColor(*updateRequest.EventColor)
+		if err != nil {
+			return nil, fmt.Errorf("invalid calendar: %w", err)
+		}
+		calendar.EventColor = &color
 		updated = true
 	}
 	if updateRequest.Visibility != nil {
 		calendar.Visibility = *updateRequest.Visibility
 		updated = true
 	}
+	if updateRequest.FreeBusy != nil {
+		calendar.FreeBusy = *updateRequest.FreeBusy
+		updated = true
+	}
 	if updateRequest.TimeZone != nil {
 		calendar.TimeZone = *updateRequest.TimeZone
 		updated = true
 	}
+	if updateRequest.HideDeclined != nil {
+		calendar.HideDeclined = *updateRequest.HideDeclined
+		updated = true
+	}
+	if updateRequest.SyncIntervalMinutes != nil {
+		interval := *updateRequest.SyncIntervalMinutes
+		if interval != 0 && (interval < minSyncIntervalMinutes || interval > maxSyncIntervalMinutes) {
+			return nil, fmt.Errorf("invalid calendar: sync interval must be between %d and %d minutes", minSyncIntervalMinutes, maxSyncIntervalMinutes)
+		}
+		calendar.SyncIntervalMinutes = interval
+
+		// Apply the new interval from the last sync on rather than after the next one
+		if calendar.NextSyncAt != nil && interval != 0 {
+			nextSyncAt := calendar.SyncedAt.Add(time.Duration(interval) * time.Minute)
+			calendar.NextSyncAt = &nextSyncAt
+		}
+		updated = true
+	}
+
+	if updateRequest.WriteBack != nil && *updateRequest.WriteBack != calendar.WriteBack {
+		if *updateRequest.WriteBack && calendar.Source != model.SourceGoogle {
+			return nil, fmt.Errorf("invalid calendar: write-back is only supported for Google calendars")
+		}
+		if *updateRequest.WriteBack {
+			if err := s.checkWriteBackScope(calendar.UserID); err != nil {
+				return nil, err
+			}
+		}
+		calendar.WriteBack = *updateRequest.WriteBack
+
+		if !calendar.WriteBack {
+			// Changes not pushed yet are dropped, and the next full sync restores the Google versions
+			if err := s.calendarRepo.DeleteEventWritesByCalendarID(calendar.ID); err != nil {
+				return nil, fmt.Errorf("failed to delete event writes: %w", err)
+			}
+			calendar.SyncToken = nil
+		}
+		updated = true
+	}
 
 	if !updated {
 		s.logger.Info("No fields to update", zap.String("calendar_id", calendarID))
@@ -1499,6 +856,33 @@ func (s *CalendarService) DeleteCalendar(userID uint64, calendarID string) error
 		return fmt.Errorf("calendar not found or access denied")
 	}
 
+	// Stop push notifications, a channel that cannot be stopped runs out on its own
+	if calendar.WatchChannelID != nil {
+		credentials, err := s.google.userCredentials(context.Background(), userID)
+		if err == nil {
+			err = s.stopWatchChannel(credentials.AccessToken, calendar)
+		}
+		if err != nil {
+			s.logger.Warn("Failed to stop watch channel",
+				zap.Error(err),
+				zap.Uint64("calendar_id", calendar.ID))
+		}
+	}
+
+	// Changes not pushed to Google yet go with the calendar
+	if err := s.calendarRepo.DeleteEventWritesByCalendarID(calendar.ID); err != nil {
+		return fmt.Errorf("failed to delete event writes: %w", err)
+	}
+
+	// A running backfill stops after the page it is fetching
+	if err := s.calendarRepo.DeleteBackfillJobsByCalendarID(calendar.ID); err != nil {
+		return fmt.Errorf("failed to delete backfill jobs: %w", err)
+	}
+
+	if err := s.calendarRepo.DeleteSyncRunsByCalendarID(calendar.ID); err != nil {
+		return fmt.Errorf("failed to delete sync runs: %w", err)
+	}
+
 	// Delete all events for this calendar first
 	if err := s.calendarRepo.DeleteEventsByCalendarID(calendar.ID); err != nil {
 		s.logger.Error("Failed to delete calendar events",
@@ -1522,10 +906,8 @@ func (s *CalendarService) DeleteCalendar(userID uint64, calendarID string) error
 
 // GetPublicUserCalendarEvents retrieves public calendar events for a user within a specified time range
 func (s *CalendarService) GetPublicUserCalendarEvents(userID uint64, startTime, endTime time.Time) ([]*model.CalendarWithEvents, error) {
-	// Validate time range (max 6 months)
-	sixMonths := startTime.AddDate(0, 6, 0)
-	if endTime.After(sixMonths) {
-		return nil, fmt.Errorf("time range cannot exceed 6 months")
+	if err := validateEventTimeRange(startTime, endTime); err != nil {
+		return nil, err
 	}
 
 	// Get all user's calendars
@@ -1559,6 +941,7 @@ func (s *CalendarService) GetPublicUserCalendarEvents(userID uint64, startTime,
 	if err != nil {
 		return nil, fmt.Errorf("failed to get calendar events: %w", err)
 	}
+	events = withoutHiddenDeclined(calendars, events)
 
 	// Group events by calendar ID and filter for public visibility
 	eventsByCalendar := make(map[uint64][]*model.CalendarEvent)
@@ -1590,7 +973,13 @@ func (s *CalendarService) GetPublicUserCalendarEvents(userID uint64, startTime,
 			isPublic = false
 		}
 
+		// Busy blocks only show when the owner is busy, events that do not block time are left out
+		if calendar.RedactionMode == model.CalendarRedactionModeBusy && !blocksTime(event) {
+			isPublic = false
+		}
+
 		if isPublic {
+			hideConferenceLink(event)
 			eventsByCalendar[event.CalendarID] = append(eventsByCalendar[event.CalendarID], event)
 		}
 	}
@@ -1606,11 +995,15 @@ func (s *CalendarService) GetPublicUserCalendarEvents(userID uint64, startTime,
 				calendarEvents = []*model.CalendarEvent{}
 			}
 
+			// Events without a color of their own are shown in the calendar's color
+			applyCalendarColor(calendarEvents, calendar)
+
 			// Apply calendar event redaction to event titles
 			s.applyEventRedaction(calendarEvents, calendar)
+			hideEventResponses(calendarEvents)
 
 			calendarWithEvents := &model.CalendarWithEvents{
-				Calendar: calendar,
+				Calendar: publicCalendar(calendar),
 				Events:   calendarEvents,
 			}
 			calendarsWithEvents = append(calendarsWithEvents, calendarWithEvents)
@@ -1633,18 +1026,87 @@ func (s *CalendarService) GetPublicUserCalendarEvents(userID uint64, startTime,
 	return calendarsWithEvents, nil
 }
 
-// applyEventRedaction applies the calendar's event redaction to event titles if redaction is set
+// validateEventTimeRange checks that a time range of an event query does not exceed 6 months
+func validateEventTimeRange(startTime, endTime time.Time) error {
+	sixMonths := startTime.AddDate(0, 6, 0)
+	if endTime.After(sixMonths) {
+		return fmt.Errorf("time range cannot exceed 6 months")
+	}
+	return nil
+}
+
+// applyEventRedaction applies the calendar's event redaction to events according to its redaction mode.
+// An event's own title override wins over the calendar's redaction text, but busy blocks never show
+// anything but the time of an event. Full redaction removes the details of every event, whether or
+// not its title is replaced.
 func (s *CalendarService) applyEventRedaction(events []*model.CalendarEvent, calendar *model.Calendar) {
-	// Only apply redaction if it's set and not empty
-	if calendar.EventRedaction == nil || *calendar.EventRedaction == "" {
+	if calendar.RedactionMode == model.CalendarRedactionModeBusy {
+		for i, event := range events {
+			events[i] = busyBlock(event)
+		}
 		return
 	}
 
-	// Apply redaction as prefix to each event title
-	redaction := *calendar.EventRedaction
+	redaction := ""
+	if calendar.EventRedaction != nil {
+		redaction = *calendar.EventRedaction
+	}
+
 	for _, event := range events {
-		event.Title = redaction
-		event.Location = ""
-		event.Description = ""
+		switch {
+		case event.TitleOverride != nil && *event.TitleOverride != "":
+			event.Title = *event.TitleOverride
+		case redaction != "":
+			event.Title = redaction
+		}
+
+		// Calendars created before redaction modes existed have no mode and are fully redacted
+		if calendar.RedactionMode != model.CalendarRedactionModeTitle {
+			event.Location = ""
+			event.Description = ""
+			event.EventColor = ""
+			event.ConferenceURL = ""
+			event.ConferenceProvider = ""
+		}
+	}
+}
+
+// busyBlock returns a copy of event with everything but its ID, calendar, start, end and all-day
+// removed. Events that don't block time are left out before, so busy blocks need no status.
+func busyBlock(event *model.CalendarEvent) *model.CalendarEvent {
+	return &model.CalendarEvent{
+		ID:         event.ID,
+		CalendarID: event.CalendarID,
+		Start:      event.Start,
+		End:        event.End,
+		AllDay:     event.AllDay,
+	}
+}
+
+// publicCalendar returns a copy of calendar with only what is shown publicly. Subscription URLs
+// can carry a token, and sync state, fetch errors and linked accounts are for the owner alone.
+func publicCalendar(calendar *model.Calendar) *model.Calendar {
+	return &model.Calendar{
+		ID:              calendar.ID,
+		UserID:          calendar.UserID,
+		Source:          calendar.Source,
+		Summary:         calendar.Summary,
+		TimeZone:        calendar.TimeZone,
+		Description:     calendar.Description,
+		EventColor:      calendar.EventColor,
+		BackgroundColor: calendar.BackgroundColor,
+		Visibility:      calendar.Visibility,
+		FreeBusy:        calendar.FreeBusy,
+		CreatedAt:       calendar.CreatedAt,
+		UpdatedAt:       calendar.UpdatedAt,
+	}
+}
+
+// isValidRedactionMode checks whether mode is one of the supported calendar redaction modes
+func isValidRedactionMode(mode model.CalendarRedactionMode) bool {
+	switch mode {
+	case model.CalendarRedactionModeFull, model.CalendarRedactionModeTitle, model.CalendarRedactionModeBusy:
+		return true
 	}
+	return false
 }
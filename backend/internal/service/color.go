@@ -0,0 +1,53 @@
+package service
+
+import (
+	"fmt"
+
+	"github.com/NathanWasTaken/timely/backend/internal/model"
+	"github.com/NathanWasTaken/timely/backend/pkg/ical"
+)
+
+// CalendarColor returns the color the events of a calendar without a color of their own are shown
+// in: the color chosen by the owner, or else the color of the calendar at its source
+func CalendarColor(calendar *model.Calendar) string {
+	if calendar.EventColor != nil && *calendar.EventColor != "" {
+		return *calendar.EventColor
+	}
+	if calendar.BackgroundColor != nil {
+		return *calendar.BackgroundColor
+	}
+	return ""
+}
+
+// applyCalendarColor gives the events without a color of their own the color of their calendar
+func applyCalendarColor(events []*model.CalendarEvent, calendar *model.Calendar) {
+	color := CalendarColor(calendar)
+	for _, event := range events {
+		if event.EventColor == "" {
+			event.EventColor = color
+		}
+	}
+}
+
+// normalizeColor validates a color set through the API, a hex color or a CSS3 color name, and
+// returns it as a "#rrggbb" hex color. An empty color stays empty.
+func normalizeColor(color string) (string, error) {
+	if color == "" {
+		return "", nil
+	}
+	normalized, err := ical.ParseColor(color)
+	if err != nil {
+		return "", fmt.Errorf("unknown color %q", color)
+	}
+	return normalized, nil
+}
+
+// optionalColor converts a color of calendar data to a "#rrggbb" hex color, nil if it is missing or
+// not a valid color
+func optionalColor(value string) *string {
+	color, err := ical.ParseColor(value)
+	if err != nil {
+		return nil
+	}
+	return &color
+}
@@ -0,0 +1,119 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	ics "github.com/arran4/golang-ical"
+
+	"github.com/NathanWasTaken/timely/backend/internal/model"
+	"github.com/NathanWasTaken/timely/backend/pkg/utils"
+)
+
+func TestGoogleEventColors(t *testing.T) {
+	calendarService, _ := newTestCalendarService(t)
+	google, server := newGoogleStandIn(t)
+	calendar := newGoogleTestCalendar(t, calendarService, server.URL)
+
+	background := "#9fe1e7"
+	calendar.BackgroundColor = &background
+	if err := calendarService.calendarRepo.Update(calendar); err != nil {
+		t.Fatalf("Failed to update calendar: %v", err)
+	}
+
+	colored := func(id, colorID string) {
+		t.Helper()
+		google.edit(id, strings.ToUpper(id[:1])+id[1:])
+		google.mu.Lock()
+		google.events[id].ColorID = colorID
+		google.mu.Unlock()
+	}
+
+	// Color IDs are resolved with the palette of the account
+	google.mu.Lock()
+	google.colors = map[string]string{"5": "#FFAD46"}
+	google.mu.Unlock()
+	colored("standup", "5")
+	colored("retro", "")
+	colored("lunch", "11")
+	syncGoogleTestCalendar(t, calendarService, calendar)
+
+	events := ownerEvents(t, calendarService, calendar.UserID, calendar.ID)
+	if got := events["standup"].EventColor; got != "#ffad46" {
+		t.Errorf("Expected the color of the account palette, got %q", got)
+	}
+	for _, id := range []string{"retro", "lunch"} {
+		if got := events[id].EventColor; got != background {
+			t.Errorf("Expected %s in the calendar's color, got %q", id, got)
+		}
+	}
+
+	// The color chosen by the owner wins over the calendar's own color, not over event colors
+	eventColor := "Tomato"
+	if _, err := calendarService.UpdateCalendar(calendar.UserID, fmt.Sprint(calendar.ID), &model.CalendarUpdateRequest{EventColor: &eventColor}); err != nil {
+		t.Fatalf("UpdateCalendar failed: %v", err)
+	}
+	events = ownerEvents(t, calendarService, calendar.UserID, calendar.ID)
+	if events["retro"].EventColor != "#ff6347" || events["standup"].EventColor != "#ffad46" {
+		t.Errorf("Expected the owner's color for events without one, got %q and %q", events["retro"].EventColor, events["standup"].EventColor)
+	}
+
+	invalid := "bright"
+	if _, err := calendarService.UpdateCalendar(calendar.UserID, fmt.Sprint(calendar.ID), &model.CalendarUpdateRequest{EventColor: &invalid}); err == nil || err.Error() != `invalid calendar: unknown color "bright"` {
+		t.Errorf("Expected an invalid color to be rejected, got %v", err)
+	}
+
+}
+
+func TestGoogleEventColorsDefaultPalette(t *testing.T) {
+	calendarService, _ := newTestCalendarService(t)
+	google, server := newGoogleStandIn(t)
+	calendar := newGoogleTestCalendar(t, calendarService, server.URL)
+
+	// The default palette is used when the palette of the account cannot be fetched
+	for id, colorID := range map[string]string{"standup": "5", "lunch": "11"} {
+		google.edit(id, id)
+		google.mu.Lock()
+		google.events[id].ColorID = colorID
+		google.mu.Unlock()
+	}
+	syncGoogleTestCalendar(t, calendarService, calendar)
+
+	events := ownerEvents(t, calendarService, calendar.UserID, calendar.ID)
+	if events["standup"].EventColor != "#fbd75b" || events["lunch"].EventColor != "#dc2127" {
+		t.Errorf("Expected the default palette, got %q and %q", events["standup"].EventColor, events["lunch"].EventColor)
+	}
+}
+
+func TestICSColors(t *testing.T) {
+	calendarService, _ := newTestCalendarService(t)
+
+	feed := strings.Replace(buildICSFeed(
+		strings.Replace(buildICSEvent("standup", "Standup", "20240701T090000Z"), "END:VEVENT", "COLOR:turquoise\r\nEND:VEVENT", 1),
+		buildICSEvent("retro", "Retro", "20240702T090000Z"),
+		strings.Replace(buildICSEvent("lunch", "Lunch", "20240703T120000Z"), "END:VEVENT", "COLOR:sparkly\r\nEND:VEVENT", 1),
+	), "X-WR-CALNAME:Team\r\n", "X-WR-CALNAME:Team\r\nX-APPLE-CALENDAR-COLOR:#0082C9FF\r\n", 1)
+
+	icsCalendar, err := ics.ParseCalendar(strings.NewReader(feed))
+	if err != nil {
+		t.Fatalf("Failed to parse ICS data: %v", err)
+	}
+
+	userID := utils.GenerateID()
+	calendar, _, err := calendarService.ImportICSCalendar(userID, "Team", icsCalendar, icsCalendar.Events())
+	if err != nil {
+		t.Fatalf("ImportICSCalendar failed: %v", err)
+	}
+	if calendar.BackgroundColor == nil || *calendar.BackgroundColor != "#0082c9" {
+		t.Errorf("Expected the Apple calendar color, got %v", calendar.BackgroundColor)
+	}
+
+	events := ownerEvents(t, calendarService, userID, calendar.ID)
+	expected := map[string]string{"standup": "#40e0d0", "retro": "#0082c9", "lunch": "#0082c9"}
+	for id, color := range expected {
+		if events[id] == nil || events[id].EventColor != color {
+			t.Errorf("Expected %s to be %s, got %+v", id, color, events[id])
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package service
+
+import (
+	"html"
+	"net/url"
+	"regexp"
+	"strings"
+
+	ics "github.com/arran4/golang-ical"
+
+	"github.com/NathanWasTaken/timely/backend/internal/model"
+)
+
+// conferenceURLPattern finds the URLs in the location and description of an event, which may be
+// plain text or HTML
+var conferenceURLPattern = regexp.MustCompile(`https?://[^\s<>"'\\]+`)
+
+// conferenceHosts maps the hosts of the conferencing services Timely recognizes, subdomains included
+var conferenceHosts = map[string]model.ConferenceProvider{
+	"meet.google.com":     model.ConferenceProviderMeet,
+	"zoom.us":             model.ConferenceProviderZoom,
+	"zoomgov.com":         model.ConferenceProviderZoom,
+	"teams.microsoft.com": model.ConferenceProviderTeams,
+	"teams.live.com":      model.ConferenceProviderTeams,
+	"webex.com":           model.ConferenceProviderWebex,
+}
+
+// conferenceProvider detects the conferencing service of a link, "generic" for any other service
+func conferenceProvider(link *url.URL) model.ConferenceProvider {
+	host := strings.ToLower(link.Hostname())
+	for {
+		if provider, ok := conferenceHosts[host]; ok {
+			return provider
+		}
+		_, parent, found := strings.Cut(host, ".")
+		if !found {
+			return model.ConferenceProviderGeneric
+		}
+		host = parent
+	}
+}
+
+// parseConferenceURL parses a conference link, only http and https links are accepted
+func parseConferenceURL(value string) (*url.URL, bool) {
+	link, err := url.Parse(strings.TrimSpace(value))
+	if err != nil || (link.Scheme != "http" && link.Scheme != "https") || link.Host == "" {
+		return nil, false
+	}
+	return link, true
+}
+
+// setConferenceLink sets the conference link of an event to the first link of a known conferencing
+// service, or of any service when the link comes from conference data of the source (explicit)
+func setConferenceLink(event *model.CalendarEvent, explicit []string, texts ...string) {
+	for _, value := range explicit {
+		if link, ok := parseConferenceURL(value); ok {
+			event.ConferenceURL = link.String()
+			event.ConferenceProvider = conferenceProvider(link)
+			return
+		}
+	}
+
+	for _, text := range texts {
+		for _, match := range conferenceURLPattern.FindAllString(text, -1) {
+			link, ok := parseConferenceURL(html.UnescapeString(strings.TrimRight(match, ".,;:!?)]}>")))
+			if !ok {
+				continue
+			}
+			if provider := conferenceProvider(link); provider != model.ConferenceProviderGeneric {
+				event.ConferenceURL = link.String()
+				event.ConferenceProvider = provider
+				return
+			}
+		}
+	}
+
+	event.ConferenceURL = ""
+	event.ConferenceProvider = ""
+}
+
+// googleConferenceLinks returns the conference links of a Google event, the video entry points of
+// its conference data first and its Meet link after them
+func googleConferenceLinks(googleEvent *model.GoogleCalendarEvent) []string {
+	var links []string
+	if googleEvent.ConferenceData != nil {
+		for _, entryPoint := range googleEvent.ConferenceData.EntryPoints {
+			if entryPoint != nil && entryPoint.EntryPointType == "video" {
+				links = append(links, entryPoint.URI)
+			}
+		}
+	}
+	if googleEvent.HangoutLink != "" {
+		links = append(links, googleEvent.HangoutLink)
+	}
+	return links
+}
+
+// icsConferenceLinks returns the conference links of an ICS event: the Google Meet link of events
+// exported by Google and the Teams link of events exported by Outlook
+func icsConferenceLinks(icsEvent *ics.VEvent) []string {
+	var links []string
+	for _, property := range []ics.ComponentProperty{"X-GOOGLE-CONFERENCE", "X-MICROSOFT-SKYPETEAMSMEETINGURL"} {
+		if prop := icsEvent.GetProperty(property); prop != nil {
+			links = append(links, prop.Value)
+		}
+	}
+	return links
+}
+
+// hideConferenceLink removes the conference link of an event shown publicly, unless the event
+// itself is public
+func hideConferenceLink(event *model.CalendarEvent) {
+	if event.Visibility != model.CalendarEventVisibilityPublic {
+		event.ConferenceURL = ""
+		event.ConferenceProvider = ""
+	}
+}
@@ -0,0 +1,163 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	ics "github.com/arran4/golang-ical"
+
+	"github.com/NathanWasTaken/timely/backend/internal/model"
+	"github.com/NathanWasTaken/timely/backend/pkg/utils"
+)
+
+func TestSetConferenceLink(t *testing.T) {
+	tests := []struct {
+		name         string
+		explicit     []string
+		texts        []string
+		wantURL      string
+		wantProvider model.ConferenceProvider
+	}{
+		{
+			name:         "explicit link of any service",
+			explicit:     []string{"", "https://meet.example.com/standup"},
+			texts:        []string{"https://zoom.us/j/123"},
+			wantURL:      "https://meet.example.com/standup",
+			wantProvider: model.ConferenceProviderGeneric,
+		},
+		{
+			name:         "zoom subdomain in a description",
+			texts:        []string{"Room 4", "Agenda at https://docs.example.com/a.\nJoin: https://us02web.zoom.us/j/85?pwd=x."},
+			wantURL:      "https://us02web.zoom.us/j/85?pwd=x",
+			wantProvider: model.ConferenceProviderZoom,
+		},
+		{
+			name:         "teams link in HTML",
+			texts:        []string{`<a href="https://teams.microsoft.com/l/meetup-join/19%3ameeting?a=1&amp;b=2">Join</a>`},
+			wantURL:      "https://teams.microsoft.com/l/meetup-join/19%3ameeting?a=1&b=2",
+			wantProvider: model.ConferenceProviderTeams,
+		},
+		{
+			name:         "webex",
+			texts:        []string{"(https://acme.webex.com/meet/robin)"},
+			wantURL:      "https://acme.webex.com/meet/robin",
+			wantProvider: model.ConferenceProviderWebex,
+		},
+		{
+			name:     "no conferencing service",
+			explicit: []string{"Room 4"},
+			texts:    []string{"Notes at https://docs.example.com/notes", "ftp://meet.google.com/abc"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event := &model.CalendarEvent{ConferenceURL: "https://old.example.com", ConferenceProvider: model.ConferenceProviderGeneric}
+			setConferenceLink(event, tt.explicit, tt.texts...)
+			if event.ConferenceURL != tt.wantURL || event.ConferenceProvider != tt.wantProvider {
+				t.Errorf("Expected %q (%s), got %q (%s)", tt.wantURL, tt.wantProvider, event.ConferenceURL, event.ConferenceProvider)
+			}
+		})
+	}
+}
+
+func TestEventConferenceLinks(t *testing.T) {
+	calendarService, _ := newTestCalendarService(t)
+	google, server := newGoogleStandIn(t)
+	calendar := newGoogleTestCalendar(t, calendarService, server.URL)
+	calendar.Visibility = model.CalendarVisibilityPublic
+	// Full redaction removes every conference link, only titles are redacted here
+	calendar.RedactionMode = model.CalendarRedactionModeTitle
+	if err := calendarService.calendarRepo.Update(calendar); err != nil {
+		t.Fatalf("Failed to update calendar: %v", err)
+	}
+
+	google.edit("standup", "Standup")
+	google.edit("sync", "Sync")
+	google.edit("lunch", "Lunch")
+	google.mu.Lock()
+	google.events["standup"].HangoutLink = "https://meet.google.com/abc-defg-hij"
+	google.events["standup"].ConferenceData = &model.GoogleConferenceData{
+		ConferenceID: "abc-defg-hij",
+		EntryPoints: []*model.GoogleConferenceEntryPoint{
+			{EntryPointType: "phone", URI: "tel:+1-555-0100"},
+			{EntryPointType: "video", URI: "https://meet.google.com/abc-defg-hij"},
+		},
+	}
+	google.events["sync"].Description = "Join Zoom Meeting\nhttps://zoom.us/j/987654321"
+	google.mu.Unlock()
+	syncGoogleTestCalendar(t, calendarService, calendar)
+
+	events := ownerEvents(t, calendarService, calendar.UserID, calendar.ID)
+	expected := map[string]model.ConferenceProvider{
+		"standup": model.ConferenceProviderMeet,
+		"sync":    model.ConferenceProviderZoom,
+		"lunch":   "",
+	}
+	for id, provider := range expected {
+		if event := events[id]; event == nil || event.ConferenceProvider != provider {
+			t.Errorf("Expected %s to have a %q conference link, got %+v", id, provider, event)
+		}
+	}
+
+	// Only explicitly public events show their conference link publicly
+	public := model.CalendarEventVisibilityPublic
+	if _, err := calendarService.PatchEvent(calendar.UserID, fmt.Sprint(calendar.ID), fmt.Sprint(events["standup"].ID), &model.CalendarEventPatchRequest{Visibility: &public}); err != nil {
+		t.Fatalf("PatchEvent failed: %v", err)
+	}
+	start := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+	calendars, err := calendarService.GetPublicUserCalendarEvents(calendar.UserID, start, start.AddDate(0, 1, 0))
+	if err != nil {
+		t.Fatalf("Failed to get public events: %v", err)
+	}
+	for _, calendarWithEvents := range calendars {
+		for _, event := range calendarWithEvents.Events {
+			want := ""
+			if event.SourceID == "standup" {
+				want = "https://meet.google.com/abc-defg-hij"
+			}
+			if event.ConferenceURL != want {
+				t.Errorf("Expected %s to show conference link %q publicly, got %q", event.SourceID, want, event.ConferenceURL)
+			}
+		}
+	}
+}
+
+func TestICSEventConferenceLinks(t *testing.T) {
+	calendarService, _ := newTestCalendarService(t)
+
+	withProperties := func(event, properties string) string {
+		return strings.Replace(event, "END:VEVENT", properties+"END:VEVENT", 1)
+	}
+	feed := buildICSFeed(
+		withProperties(buildICSEvent("standup", "Standup", "20240701T090000Z"), "X-GOOGLE-CONFERENCE:https://meet.google.com/abc-defg-hij\r\n"),
+		withProperties(buildICSEvent("review", "Review", "20240702T090000Z"), "LOCATION:https://video.example.com/review\r\n"),
+		withProperties(buildICSEvent("retro", "Retro", "20240703T090000Z"), "DESCRIPTION:Join on Webex: https://acme.webex.com/meet/retro\r\n"),
+		withProperties(buildICSEvent("lunch", "Lunch", "20240704T120000Z"), "LOCATION:Cafeteria\r\n"),
+	)
+	icsCalendar, err := ics.ParseCalendar(strings.NewReader(feed))
+	if err != nil {
+		t.Fatalf("Failed to parse ICS data: %v", err)
+	}
+
+	userID := utils.GenerateID()
+	calendar, _, err := calendarService.ImportICSCalendar(userID, "Team", icsCalendar, icsCalendar.Events())
+	if err != nil {
+		t.Fatalf("ImportICSCalendar failed: %v", err)
+	}
+
+	events := ownerEvents(t, calendarService, userID, calendar.ID)
+	expected := map[string]model.ConferenceProvider{
+		"standup": model.ConferenceProviderMeet,
+		"review":  model.ConferenceProviderGeneric,
+		"retro":   model.ConferenceProviderWebex,
+		"lunch":   "",
+	}
+	for id, provider := range expected {
+		if event := events[id]; event == nil || event.ConferenceProvider != provider {
+			t.Errorf("Expected %s to have a %q conference link, got %+v", id, provider, event)
+		}
+	}
+}
@@ -0,0 +1,422 @@
+package service
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	ics "github.com/arran4/golang-ical"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/NathanWasTaken/timely/backend/internal/model"
+	"github.com/NathanWasTaken/timely/backend/internal/repository"
+	"github.com/NathanWasTaken/timely/backend/pkg/utils"
+)
+
+// DAVResource represents a calendar object resource served by the built-in CalDAV server. Events
+// written by CalDAV clients keep the resource name they were written to, a recurring event is
+// stored as one event per occurrence under the same name. Every other event is its own resource.
+type DAVResource struct {
+	Name   string
+	ETag   string
+	Events []*model.CalendarEvent
+	// Calendar object the client wrote, empty if the resource wasn't written by a client or its
+	// events changed in Timely since
+	Data string
+}
+
+// Overlaps reports whether any event of the resource overlaps the time range
+func (r *DAVResource) Overlaps(start, end time.Time) bool {
+	for _, event := range r.Events {
+		if (end.IsZero() || event.Start.Before(end)) && (start.IsZero() || event.End.After(start)) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsDAVCalendarWritable reports whether CalDAV clients may change the events of a calendar. Only
+// Timely calendars are writable, the events of imported calendars would be overwritten by sync.
+func IsDAVCalendarWritable(calendar *model.Calendar) bool {
+	return calendar.Source == model.SourceTimely
+}
+
+// GetDAVCalendar finds a calendar of the user for the CalDAV server
+func (s *CalendarService) GetDAVCalendar(userID uint64, calendarID string) (*model.Calendar, error) {
+	calendar, err := s.calendarRepo.FindByID(calendarID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find calendar: %w", err)
+	}
+
+	if calendar.UserID != userID {
+		return nil, fmt.Errorf("calendar not found or access denied")
+	}
+
+	return calendar, nil
+}
+
+// GetDAVCalendars retrieves every calendar of the user for the CalDAV server
+func (s *CalendarService) GetDAVCalendars(userID uint64) ([]*model.Calendar, error) {
+	calendars, err := s.calendarRepo.FindByUserID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user calendars: %w", err)
+	}
+	return calendars, nil
+}
+
+// GetDAVResources retrieves the resources of a calendar, ordered by name
+func (s *CalendarService) GetDAVResources(calendar *model.Calendar) ([]*DAVResource, error) {
+	events, err := s.calendarRepo.FindEventsByCalendarID(calendar.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get calendar events: %w", err)
+	}
+
+	byName := make(map[string]*DAVResource)
+	for _, event := range events {
+		name := davResourceName(calendar, event)
+		resource, ok := byName[name]
+		if !ok {
+			resource = &DAVResource{Name: name}
+			byName[name] = resource
+		}
+		resource.Events = append(resource.Events, event)
+	}
+
+	objects := make(map[string]*model.DAVObject)
+	if IsDAVCalendarWritable(calendar) {
+		stored, err := s.calendarRepo.FindDAVObjectsByCalendarID(calendar.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get calendar objects: %w", err)
+		}
+		for _, object := range stored {
+			objects[object.Name] = object
+		}
+	}
+
+	resources := make([]*DAVResource, 0, len(byName))
+	for _, resource := range byName {
+		resource.ETag = davResourceETag(resource.Events)
+		if object, ok := objects[resource.Name]; ok && object.ETag == resource.ETag {
+			resource.Data = object.Data
+		}
+		resources = append(resources, resource)
+	}
+	sort.Slice(resources, func(i, j int) bool {
+		return resources[i].Name < resources[j].Name
+	})
+
+	return resources, nil
+}
+
+// GetDAVResource retrieves a resource of a calendar by name
+func (s *CalendarService) GetDAVResource(calendar *model.Calendar, name string) (*DAVResource, error) {
+	events, err := s.findDAVResourceEvents(calendar, name)
+	if err != nil {
+		return nil, err
+	}
+	if len(events) == 0 {
+		return nil, fmt.Errorf("resource not found")
+	}
+
+	resource := &DAVResource{Name: name, Events: events, ETag: davResourceETag(events)}
+	if IsDAVCalendarWritable(calendar) {
+		object, err := s.calendarRepo.FindDAVObject(calendar.ID, name)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("failed to get calendar object: %w", err)
+		}
+		if object != nil && object.ETag == resource.ETag {
+			resource.Data = object.Data
+		}
+	}
+
+	return resource, nil
+}
+
+// findDAVResourceEvents finds the events served as a resource, see davResourceName
+func (s *CalendarService) findDAVResourceEvents(calendar *model.Calendar, name string) ([]*model.CalendarEvent, error) {
+	if calendar.Source == model.SourceTimely {
+		events, err := s.calendarRepo.FindEventsByCalendarIDAndSourceURL(calendar.ID, name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get calendar events: %w", err)
+		}
+		if len(events) > 0 {
+			return events, nil
+		}
+	}
+
+	id, ok := strings.CutSuffix(name, ".ics")
+	if _, err := strconv.ParseUint(id, 10, 64); !ok || err != nil {
+		return nil, nil
+	}
+	event, err := s.calendarRepo.FindEventByID(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get calendar event: %w", err)
+	}
+	if event.CalendarID != calendar.ID || davResourceName(calendar, event) != name {
+		return nil, nil
+	}
+	return []*model.CalendarEvent{event}, nil
+}
+
+// PutDAVResource creates or replaces a resource of a Timely calendar with the events of a calendar
+// object written by a CalDAV client. ifMatch and ifNoneMatch are the conditional request headers,
+// empty if the client did not send them. Returns the stored resource and whether it was created.
+func (s *CalendarService) PutDAVResource(userID uint64, calendarID, name string, data []byte, ifMatch, ifNoneMatch string) (*DAVResource, bool, error) {
+	calendar, err := s.findWritableDAVCalendar(userID, calendarID)
+	if err != nil {
+		return nil, false, err
+	}
+
+	existing, err := s.GetDAVResource(calendar, name)
+	if err != nil && err.Error() != "resource not found" {
+		return nil, false, err
+	}
+
+	if ifNoneMatch == "*" && existing != nil {
+		return nil, false, fmt.Errorf("precondition failed")
+	}
+	if ifMatch != "" && (existing == nil || (ifMatch != "*" && ifMatch != existing.ETag)) {
+		return nil, false, fmt.Errorf("precondition failed")
+	}
+
+	cal, err := ics.ParseCalendar(bytes.NewReader(data))
+	if err != nil {
+		return nil, false, fmt.Errorf("invalid calendar data: %w", err)
+	}
+
+	// A calendar object resource holds a single event with its overrides (RFC 4791 section 4.1)
+	icsEvents := cal.Events()
+	if len(icsEvents) == 0 {
+		return nil, false, fmt.Errorf("invalid calendar data: no event")
+	}
+	for _, icsEvent := range icsEvents {
+		if icsEvent.Id() != icsEvents[0].Id() {
+			return nil, false, fmt.Errorf("invalid calendar data: events with different UIDs")
+		}
+	}
+
+	events := s.ics.convertICSEvents(icsEvents, calendar.ID, s.ics.timeZones(cal, calendar.TimeZone))
+	if len(events) == 0 {
+		return nil, false, fmt.Errorf("invalid calendar data: no valid event")
+	}
+	s.markSelfAttendees(calendar, events)
+
+	// Events that are still there keep their ID and the settings made in Timely
+	previous := make(map[string]*model.CalendarEvent)
+	if existing != nil {
+		for _, event := range existing.Events {
+			previous[event.SourceID] = event
+			previous[davEventUID(calendar, event)] = event
+		}
+	}
+
+	var created, updated []*model.CalendarEvent
+	kept := make(map[uint64]bool)
+	for _, event := range events {
+		event.SourceURL = name
+		if old, ok := previous[event.SourceID]; ok && !kept[old.ID] {
+			event.ID = old.ID
+			event.CreatedAt = old.CreatedAt
+			keepEventSharingSettings(old, event)
+			kept[old.ID] = true
+			updated = append(updated, event)
+			continue
+		}
+		created = append(created, event)
+	}
+
+	var removed []uint64
+	if existing != nil {
+		for _, event := range existing.Events {
+			if !kept[event.ID] {
+				removed = append(removed, event.ID)
+			}
+		}
+	}
+
+	var resource *DAVResource
+	err = s.calendarRepo.ReplaceDAVResource(removed, updated, created, func(calendarRepo *repository.CalendarRepository) error {
+		// Read the events back, so the ETag matches the one listed later
+		events, err := calendarRepo.FindEventsByCalendarIDAndSourceURL(calendar.ID, name)
+		if err != nil {
+			return err
+		}
+		resource = &DAVResource{Name: name, Events: events, ETag: davResourceETag(events), Data: string(data)}
+
+		// The events hold the occurrences of recurring events, not the rules, so the object itself
+		// is kept to be served back. Without it the resource is rendered from its events.
+		return calendarRepo.SaveDAVObject(&model.DAVObject{
+			ID:         utils.GenerateID(),
+			CalendarID: calendar.ID,
+			Name:       name,
+			Data:       resource.Data,
+			ETag:       resource.ETag,
+		})
+	})
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to save events: %w", err)
+	}
+
+	s.logger.Info("Stored CalDAV resource",
+		zap.Uint64("user_id", userID),
+		zap.Uint64("calendar_id", calendar.ID),
+		zap.String("resource", name),
+		zap.Int("event_count", len(resource.Events)))
+
+	return resource, existing == nil, nil
+}
+
+// DeleteDAVResource deletes a resource of a Timely calendar with all of its events
+func (s *CalendarService) DeleteDAVResource(userID uint64, calendarID, name, ifMatch string) error {
+	calendar, err := s.findWritableDAVCalendar(userID, calendarID)
+	if err != nil {
+		return err
+	}
+
+	existing, err := s.GetDAVResource(calendar, name)
+	if err != nil {
+		return err
+	}
+
+	if ifMatch != "" && ifMatch != "*" && ifMatch != existing.ETag {
+		return fmt.Errorf("precondition failed")
+	}
+
+	removed := make([]uint64, 0, len(existing.Events))
+	for _, event := range existing.Events {
+		removed = append(removed, event.ID)
+	}
+
+	err = s.calendarRepo.ReplaceDAVResource(removed, nil, nil, func(calendarRepo *repository.CalendarRepository) error {
+		return calendarRepo.DeleteDAVObject(calendar.ID, name)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete events: %w", err)
+	}
+
+	s.logger.Info("Deleted CalDAV resource",
+		zap.Uint64("user_id", userID),
+		zap.Uint64("calendar_id", calendar.ID),
+		zap.String("resource", name))
+
+	return nil
+}
+
+// DAVCalendarCTag derives the ctag of a calendar from its resources, it changes whenever a
+// resource is created, changed or deleted
+func DAVCalendarCTag(calendar *model.Calendar, resources []*DAVResource) string {
+	tag := fmt.Sprintf("%d", calendar.UpdatedAt.UnixNano())
+	for _, resource := range resources {
+		tag += "/" + resource.Name + resource.ETag
+	}
+	return utils.HashToken(tag)
+}
+
+// RenderDAVResource serializes a resource to iCalendar. Calendar objects written by CalDAV clients
+// are served as they were written. Once their events changed in Timely they keep their UID and
+// occurrences of a recurring event are emitted as instances with a RECURRENCE-ID. Every other
+// event gets the UID of the ICS feeds.
+func RenderDAVResource(calendar *model.Calendar, resource *DAVResource) string {
+	if resource.Data != "" {
+		return resource.Data
+	}
+
+	cal := ics.NewCalendar()
+	cal.SetProductId("-//Timely//Timely Calendar//EN")
+	cal.SetCalscale("GREGORIAN")
+
+	for _, event := range resource.Events {
+		vevent := cal.AddEvent(davEventUID(calendar, event))
+		vevent.SetDtStampTime(event.UpdatedAt)
+		vevent.SetSummary(event.Title)
+
+		if event.AllDay {
+			vevent.SetAllDayStartAt(event.Start)
+			vevent.SetAllDayEndAt(event.End)
+		} else {
+			vevent.SetStartAt(event.Start)
+			vevent.SetEndAt(event.End)
+		}
+
+		if davWrittenEvent(calendar, event) && event.OriginalStartTime != nil {
+			if event.AllDay {
+				vevent.SetProperty(ics.ComponentPropertyRecurrenceId, event.OriginalStartTime.Format("20060102"), ics.WithValue("DATE"))
+			} else {
+				vevent.SetProperty(ics.ComponentPropertyRecurrenceId, event.OriginalStartTime.UTC().Format("20060102T150405Z"))
+			}
+		}
+
+		if event.Location != "" {
+			vevent.SetLocation(event.Location)
+		}
+		if event.Description != "" {
+			vevent.SetDescription(event.Description)
+		}
+		if status := icsObjectStatus(event.Status); status != "" {
+			vevent.SetStatus(status)
+		}
+		if event.Transparency == model.CalendarEventTransparencyTransparent {
+			vevent.SetTimeTransparency(ics.TransparencyTransparent)
+		}
+	}
+
+	return cal.Serialize(ics.WithNewLine("\r\n"))
+}
+
+// findWritableDAVCalendar finds a calendar of the user whose events CalDAV clients may change
+func (s *CalendarService) findWritableDAVCalendar(userID uint64, calendarID string) (*model.Calendar, error) {
+	calendar, err := s.GetDAVCalendar(userID, calendarID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !IsDAVCalendarWritable(calendar) {
+		return nil, fmt.Errorf("calendar is read-only")
+	}
+
+	return calendar, nil
+}
+
+// davWrittenEvent reports whether an event was written by a CalDAV client, which stores the name
+// of its resource in SourceURL
+func davWrittenEvent(calendar *model.Calendar, event *model.CalendarEvent) bool {
+	return calendar.Source == model.SourceTimely && event.SourceURL != ""
+}
+
+// davResourceName returns the name of the resource an event is served as
+func davResourceName(calendar *model.Calendar, event *model.CalendarEvent) string {
+	if davWrittenEvent(calendar, event) {
+		return event.SourceURL
+	}
+	return strconv.FormatUint(event.ID, 10) + ".ics"
+}
+
+// davEventUID returns the UID an event is served with
+func davEventUID(calendar *model.Calendar, event *model.CalendarEvent) string {
+	if !davWrittenEvent(calendar, event) {
+		return fmt.Sprintf("%d@timely", event.ID)
+	}
+	if event.RecurringEventID != "" {
+		return event.RecurringEventID
+	}
+	return strings.TrimSpace(event.SourceID)
+}
+
+// davResourceETag derives the ETag of a resource from its events and when each of them was last
+// updated, so it changes whenever an event of the resource is added, changed or removed
+func davResourceETag(events []*model.CalendarEvent) string {
+	versions := make([]string, 0, len(events))
+	for _, event := range events {
+		versions = append(versions, fmt.Sprintf("%d@%d", event.ID, event.UpdatedAt.UnixNano()))
+	}
+	sort.Strings(versions)
+	return `"` + utils.HashToken(strings.Join(versions, "/")) + `"`
+}
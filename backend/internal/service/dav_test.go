@@ -0,0 +1,226 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NathanWasTaken/timely/backend/internal/model"
+	"github.com/NathanWasTaken/timely/backend/internal/repository"
+)
+
+const davTestEvent = "BEGIN:VCALENDAR\r\nVERSION:2.0\r\nPRODID:-//Test//Test//EN\r\n" +
+	"BEGIN:VEVENT\r\nUID:standup@client\r\nSUMMARY:%s\r\nDTSTART:20240701T090000Z\r\nDTEND:20240701T093000Z\r\nEND:VEVENT\r\n" +
+	"END:VCALENDAR\r\n"
+
+func TestDAVResourceLifecycle(t *testing.T) {
+	calendarService, _ := newTestCalendarService(t)
+
+	const userID = 21
+	calendar, err := calendarService.CreateCalendar(userID, &model.CalendarCreateRequest{Summary: "Work", TimeZone: "UTC"})
+	if err != nil {
+		t.Fatalf("Failed to create calendar: %v", err)
+	}
+	calendarID := fmt.Sprintf("%d", calendar.ID)
+
+	// Events created in Timely are served as their own resources
+	event, err := calendarService.CreateEvent(userID, calendarID, &model.CalendarEventRequest{
+		Title: "Planning",
+		Start: "2024-07-02T10:00:00Z",
+		End:   "2024-07-02T11:00:00Z",
+	})
+	if err != nil {
+		t.Fatalf("Failed to create event: %v", err)
+	}
+	resources, err := calendarService.GetDAVResources(calendar)
+	if err != nil || len(resources) != 1 || resources[0].Name != fmt.Sprintf("%d.ics", event.ID) {
+		t.Fatalf("Expected the event to be served as a resource, got %+v, %v", resources, err)
+	}
+	ctag := DAVCalendarCTag(calendar, resources)
+
+	// Single resources are looked up by name within their calendar
+	if single, err := calendarService.GetDAVResource(calendar, resources[0].Name); err != nil || single.ETag != resources[0].ETag {
+		t.Errorf("Expected the event to be found by its resource name, got %+v, %v", single, err)
+	}
+	other, err := calendarService.CreateCalendar(userID, &model.CalendarCreateRequest{Summary: "Home", TimeZone: "UTC"})
+	if err != nil {
+		t.Fatalf("Failed to create calendar: %v", err)
+	}
+	if _, err := calendarService.GetDAVResource(other, resources[0].Name); err == nil || err.Error() != "resource not found" {
+		t.Errorf("Expected the resource not to be found in another calendar, got %v", err)
+	}
+
+	// A client creates a resource, a second create must not overwrite it
+	resource, created, err := calendarService.PutDAVResource(userID, calendarID, "standup.ics", []byte(fmt.Sprintf(davTestEvent, "Standup")), "", "*")
+	if err != nil || !created {
+		t.Fatalf("Failed to create resource: %v", err)
+	}
+	if _, _, err := calendarService.PutDAVResource(userID, calendarID, "standup.ics", []byte(fmt.Sprintf(davTestEvent, "Standup")), "", "*"); err == nil || err.Error() != "precondition failed" {
+		t.Errorf("Expected If-None-Match to reject overwriting the resource, got %v", err)
+	}
+
+	if _, err := calendarService.GetDAVResource(calendar, fmt.Sprintf("%d.ics", resource.Events[0].ID)); err == nil || err.Error() != "resource not found" {
+		t.Errorf("Expected the written event to only be served under its resource name, got %v", err)
+	}
+
+	rendered := RenderDAVResource(calendar, resource)
+	if !strings.Contains(rendered, "UID:standup@client\r\n") || !strings.Contains(rendered, "SUMMARY:Standup\r\n") {
+		t.Errorf("Expected the resource to keep its UID, got %s", rendered)
+	}
+
+	resources, err = calendarService.GetDAVResources(calendar)
+	if err != nil || len(resources) != 2 {
+		t.Fatalf("Expected two resources, got %+v, %v", resources, err)
+	}
+	if DAVCalendarCTag(calendar, resources) == ctag {
+		t.Error("Expected the ctag to change with the new resource")
+	}
+
+	// Updates are only applied to the version the client knows
+	if _, _, err := calendarService.PutDAVResource(userID, calendarID, "standup.ics", []byte(fmt.Sprintf(davTestEvent, "Stale")), `"stale"`, ""); err == nil || err.Error() != "precondition failed" {
+		t.Errorf("Expected If-Match to reject a stale update, got %v", err)
+	}
+	time.Sleep(time.Millisecond)
+	updated, created, err := calendarService.PutDAVResource(userID, calendarID, "standup.ics", []byte(fmt.Sprintf(davTestEvent, "Daily standup")), resource.ETag, "")
+	if err != nil || created {
+		t.Fatalf("Failed to update resource: %v", err)
+	}
+	if updated.ETag == resource.ETag || updated.Events[0].ID != resource.Events[0].ID || updated.Events[0].Title != "Daily standup" {
+		t.Errorf("Expected the event to be updated in place with a new ETag, got %+v", updated.Events[0])
+	}
+	if updated.Data != fmt.Sprintf(davTestEvent, "Daily standup") {
+		t.Errorf("Expected the new calendar object to be stored, got %s", updated.Data)
+	}
+
+	if _, _, err := calendarService.PutDAVResource(userID, calendarID, "broken.ics", []byte("BEGIN:VCALENDAR\r\nEND:VCALENDAR\r\n"), "", ""); err == nil || !strings.HasPrefix(err.Error(), "invalid calendar data") {
+		t.Errorf("Expected a calendar without events to be rejected, got %v", err)
+	}
+
+	// Other users cannot reach the calendar
+	if _, _, err := calendarService.PutDAVResource(userID+1, calendarID, "standup.ics", []byte(fmt.Sprintf(davTestEvent, "Intruder")), "", ""); err == nil {
+		t.Error("Expected another user to be unable to write to the calendar")
+	}
+
+	if err := calendarService.DeleteDAVResource(userID, calendarID, "standup.ics", updated.ETag); err != nil {
+		t.Fatalf("Failed to delete resource: %v", err)
+	}
+	if _, err := calendarService.GetDAVResource(calendar, "standup.ics"); err == nil || err.Error() != "resource not found" {
+		t.Errorf("Expected the resource to be deleted, got %v", err)
+	}
+}
+
+func TestDAVRecurringResource(t *testing.T) {
+	calendarService, _ := newTestCalendarService(t)
+
+	const userID = 22
+	calendar, err := calendarService.CreateCalendar(userID, &model.CalendarCreateRequest{Summary: "Work", TimeZone: "UTC"})
+	if err != nil {
+		t.Fatalf("Failed to create calendar: %v", err)
+	}
+
+	data := "BEGIN:VCALENDAR\r\nVERSION:2.0\r\nPRODID:-//Test//Test//EN\r\n" +
+		"BEGIN:VEVENT\r\nUID:weekly@client\r\nSUMMARY:Weekly\r\nDTSTART:20240701T090000Z\r\nDTEND:20240701T100000Z\r\nRRULE:FREQ=WEEKLY;COUNT=3\r\nEND:VEVENT\r\n" +
+		"END:VCALENDAR\r\n"
+	resource, _, err := calendarService.PutDAVResource(userID, fmt.Sprintf("%d", calendar.ID), "weekly.ics", []byte(data), "", "")
+	if err != nil {
+		t.Fatalf("Failed to create resource: %v", err)
+	}
+	if len(resource.Events) != 3 {
+		t.Fatalf("Expected the occurrences to be stored in one resource, got %d events", len(resource.Events))
+	}
+
+	if rendered := RenderDAVResource(calendar, resource); rendered != data {
+		t.Errorf("Expected the calendar object to be served as it was written, got %s", rendered)
+	}
+	resources, err := calendarService.GetDAVResources(calendar)
+	if err != nil || len(resources) != 1 || resources[0].Data != data || resources[0].ETag != resource.ETag {
+		t.Fatalf("Expected the calendar object to be listed with the resource, got %+v, %v", resources, err)
+	}
+
+	if !resource.Overlaps(time.Date(2024, 7, 8, 0, 0, 0, 0, time.UTC), time.Date(2024, 7, 9, 0, 0, 0, 0, time.UTC)) {
+		t.Error("Expected the resource to overlap its second occurrence")
+	}
+	if resource.Overlaps(time.Date(2024, 8, 1, 0, 0, 0, 0, time.UTC), time.Time{}) {
+		t.Error("Expected the resource not to overlap a range after its last occurrence")
+	}
+
+	// Once an occurrence is changed in Timely the object is stale and the events are served
+	time.Sleep(time.Millisecond)
+	if _, err := calendarService.UpdateEvent(userID, fmt.Sprintf("%d", calendar.ID), fmt.Sprintf("%d", resource.Events[1].ID), &model.CalendarEventRequest{
+		Title: "Moved weekly",
+		Start: "2024-07-08T11:00:00Z",
+		End:   "2024-07-08T12:00:00Z",
+	}); err != nil {
+		t.Fatalf("Failed to update occurrence: %v", err)
+	}
+	changed, err := calendarService.GetDAVResource(calendar, "weekly.ics")
+	if err != nil || changed.Data != "" || changed.ETag == resource.ETag {
+		t.Fatalf("Expected the stale calendar object not to be served, got %+v, %v", changed, err)
+	}
+	rendered := RenderDAVResource(calendar, changed)
+	if strings.Count(rendered, "UID:weekly@client\r\n") != 3 || strings.Count(rendered, "RECURRENCE-ID:") != 3 || !strings.Contains(rendered, "SUMMARY:Moved weekly\r\n") {
+		t.Errorf("Expected the occurrences to be served as instances of the event, got %s", rendered)
+	}
+
+	if err := calendarService.DeleteDAVResource(userID, fmt.Sprintf("%d", calendar.ID), "weekly.ics", ""); err != nil {
+		t.Fatalf("Failed to delete resource: %v", err)
+	}
+	if objects, err := calendarService.calendarRepo.FindDAVObjectsByCalendarID(calendar.ID); err != nil || len(objects) != 0 {
+		t.Errorf("Expected the calendar object to be deleted with the resource, got %d, %v", len(objects), err)
+	}
+}
+
+func TestDAVResourceIsStoredWithItsObject(t *testing.T) {
+	db := newTestDB(t)
+	calendarRepo := repository.NewCalendarRepository(db)
+	calendarService := NewCalendarService(repository.NewUserRepository(db), calendarRepo, nil)
+
+	const userID = 22
+	calendar, err := calendarService.CreateCalendar(userID, &model.CalendarCreateRequest{Summary: "Work", TimeZone: "UTC"})
+	if err != nil {
+		t.Fatalf("Failed to create calendar: %v", err)
+	}
+
+	// The events of a resource are only stored together with its calendar object
+	if err := db.Migrator().DropTable(&model.DAVObject{}); err != nil {
+		t.Fatalf("Failed to drop calendar objects: %v", err)
+	}
+	if _, _, err := calendarService.PutDAVResource(userID, fmt.Sprint(calendar.ID), "standup.ics", []byte(fmt.Sprintf(davTestEvent, "Standup")), "", ""); err == nil {
+		t.Fatal("Expected storing the resource to fail")
+	}
+	if events, _ := calendarRepo.FindEventsByCalendarID(calendar.ID); len(events) != 0 {
+		t.Errorf("Expected no event to be stored without its calendar object, got %d", len(events))
+	}
+}
+
+func TestDAVResourceETag(t *testing.T) {
+	now := time.Now()
+	standup := &model.CalendarEvent{ID: 1, UpdatedAt: now}
+	review := &model.CalendarEvent{ID: 2, UpdatedAt: now}
+	retro := &model.CalendarEvent{ID: 3, UpdatedAt: now}
+
+	etag := davResourceETag([]*model.CalendarEvent{standup, review})
+	if davResourceETag([]*model.CalendarEvent{review, standup}) != etag {
+		t.Error("Expected the ETag not to depend on the order of the events")
+	}
+	// An event replaced by another one stored at the same moment
+	if davResourceETag([]*model.CalendarEvent{standup, retro}) == etag {
+		t.Error("Expected the ETag to change when an event is replaced")
+	}
+	review.UpdatedAt = now.Add(time.Microsecond)
+	if davResourceETag([]*model.CalendarEvent{standup, review}) == etag {
+		t.Error("Expected the ETag to change when an event is updated")
+	}
+}
+
+func TestDAVImportedCalendarIsReadOnly(t *testing.T) {
+	calendarService, calendarRepo := newTestCalendarService(t)
+
+	calendar := createSyncTestCalendar(t, calendarRepo, model.SourceICS, nil)
+
+	_, _, err := calendarService.PutDAVResource(calendar.UserID, fmt.Sprintf("%d", calendar.ID), "event.ics", []byte(fmt.Sprintf(davTestEvent, "Standup")), "", "")
+	if err == nil || err.Error() != "calendar is read-only" {
+		t.Errorf("Expected imported calendars to be read-only, got %v", err)
+	}
+}
@@ -0,0 +1,409 @@
+package service
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/NathanWasTaken/timely/backend/internal/model"
+	"github.com/NathanWasTaken/timely/backend/pkg/utils"
+)
+
+// CreateCalendar creates a calendar owned by Timely itself, whose events are managed through the API
+// and never touched by sync
+func (s *CalendarService) CreateCalendar(userID uint64, createRequest *model.CalendarCreateRequest) (*model.Calendar, error) {
+	summary := strings.TrimSpace(createRequest.Summary)
+	if summary == "" {
+		return nil, fmt.Errorf("invalid calendar: summary is required")
+	}
+
+	timeZone := createRequest.TimeZone
+	if timeZone == "" {
+		timeZone = "UTC"
+	}
+	if _, err := time.LoadLocation(timeZone); err != nil {
+		return nil, fmt.Errorf("invalid calendar: unknown time zone %q", timeZone)
+	}
+
+	visibility := model.CalendarVisibilityPrivate
+	if createRequest.Visibility != nil {
+		visibility = *createRequest.Visibility
+		if visibility != model.CalendarVisibilityPublic && visibility != model.CalendarVisibilityPrivate {
+			return nil, fmt.Errorf("invalid calendar: unknown visibility %q", visibility)
+		}
+	}
+
+	var eventColor *string
+	if createRequest.EventColor != nil {
+		color, err := normalizeColor(*createRequest.EventColor)
+		if err != nil {
+			return nil, fmt.Errorf("invalid calendar: %w", err)
+		}
+		eventColor = &color
+	}
+
+	now := time.Now()
+	calendar := &model.Calendar{
+		ID:            utils.GenerateID(),
+		UserID:        userID,
+		Source:        model.SourceTimely,
+		Summary:       summary,
+		TimeZone:      timeZone,
+		Description:   createRequest.Description,
+		EventColor:    eventColor,
+		RedactionMode: model.CalendarRedactionModeFull,
+		Visibility:    visibility,
+		SyncedAt:      now,
+		SyncStatus:    model.CalendarSyncStatusFullSyncComplete, // Nothing to sync, Timely is the source of truth
+		LastFullSync:  &now,
+	}
+
+	if err := s.calendarRepo.Create(calendar); err != nil {
+		return nil, fmt.Errorf("failed to create calendar: %w", err)
+	}
+
+	s.logger.Info("Created Timely calendar",
+		zap.Uint64("user_id", userID),
+		zap.Uint64("calendar_id", calendar.ID),
+		zap.String("summary", calendar.Summary))
+
+	return calendar, nil
+}
+
+// CreateEvent creates an event in one of the user's Timely calendars, or in a Google calendar with
+// write-back enabled
+func (s *CalendarService) CreateEvent(userID uint64, calendarID string, eventRequest *model.CalendarEventRequest) (*model.CalendarEvent, error) {
+	calendar, err := s.findEditableCalendar(userID, calendarID)
+	if err != nil {
+		return nil, err
+	}
+
+	event := &model.CalendarEvent{
+		ID:         utils.GenerateID(),
+		CalendarID: calendar.ID,
+	}
+	// Digits are valid Google event IDs, so events created in write-back calendars keep their source ID
+	event.SourceID = strconv.FormatUint(event.ID, 10)
+
+	if err := applyEventRequest(event, eventRequest, calendar); err != nil {
+		return nil, err
+	}
+
+	if err := s.calendarRepo.CreateEvent(event); err != nil {
+		return nil, fmt.Errorf("failed to create event: %w", err)
+	}
+
+	if calendar.WriteBack {
+		if err := s.queueEventWrite(calendar, event, model.EventWriteCreate, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	s.logger.Info("Created event",
+		zap.Uint64("user_id", userID),
+		zap.Uint64("calendar_id", calendar.ID),
+		zap.Uint64("event_id", event.ID))
+
+	return event, nil
+}
+
+// UpdateEvent replaces an event of one of the user's Timely calendars, or of a Google calendar with
+// write-back enabled
+func (s *CalendarService) UpdateEvent(userID uint64, calendarID, eventID string, eventRequest *model.CalendarEventRequest) (*model.CalendarEvent, error) {
+	calendar, err := s.findEditableCalendar(userID, calendarID)
+	if err != nil {
+		return nil, err
+	}
+
+	event, err := s.findCalendarEvent(calendar, eventID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Changes made on Google before this one is pushed are merged against the current version
+	base := eventWriteFields(event)
+	if err := applyEventRequest(event, eventRequest, calendar); err != nil {
+		return nil, err
+	}
+
+	if err := s.calendarRepo.UpdateEvent(event); err != nil {
+		return nil, fmt.Errorf("failed to update event: %w", err)
+	}
+
+	if calendar.WriteBack {
+		if err := s.queueEventWrite(calendar, event, model.EventWriteUpdate, base); err != nil {
+			return nil, err
+		}
+	}
+
+	s.logger.Info("Updated event",
+		zap.Uint64("user_id", userID),
+		zap.Uint64("calendar_id", calendar.ID),
+		zap.Uint64("event_id", event.ID))
+
+	return event, nil
+}
+
+// DeleteEvent deletes an event of one of the user's Timely calendars, or of a Google calendar with
+// write-back enabled
+func (s *CalendarService) DeleteEvent(userID uint64, calendarID, eventID string) error {
+	calendar, err := s.findEditableCalendar(userID, calendarID)
+	if err != nil {
+		return err
+	}
+
+	event, err := s.findCalendarEvent(calendar, eventID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.calendarRepo.DeleteEvent(eventID); err != nil {
+		return fmt.Errorf("failed to delete event: %w", err)
+	}
+
+	if calendar.WriteBack {
+		if err := s.queueEventWrite(calendar, event, model.EventWriteDelete, nil); err != nil {
+			return err
+		}
+	}
+
+	s.logger.Info("Deleted event",
+		zap.Uint64("user_id", userID),
+		zap.Uint64("calendar_id", calendar.ID),
+		zap.Uint64("event_id", event.ID))
+
+	return nil
+}
+
+// PatchEvent changes the visibility and title override of an event in any of the user's calendars.
+// These settings are kept when the event is later updated by sync.
+func (s *CalendarService) PatchEvent(userID uint64, calendarID, eventID string, patchRequest *model.CalendarEventPatchRequest) (*model.CalendarEvent, error) {
+	calendar, err := s.calendarRepo.FindByID(calendarID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find calendar: %w", err)
+	}
+
+	if calendar.UserID != userID {
+		return nil, fmt.Errorf("calendar not found or access denied")
+	}
+
+	event, err := s.findCalendarEvent(calendar, eventID)
+	if err != nil {
+		return nil, err
+	}
+
+	if patchRequest.Visibility != nil {
+		if !isValidEventVisibility(*patchRequest.Visibility) {
+			return nil, fmt.Errorf("invalid event: unknown visibility %q", *patchRequest.Visibility)
+		}
+		event.Visibility = *patchRequest.Visibility
+	}
+
+	if patchRequest.TitleOverride != nil {
+		if titleOverride := strings.TrimSpace(*patchRequest.TitleOverride); titleOverride != "" {
+			event.TitleOverride = &titleOverride
+		} else {
+			event.TitleOverride = nil
+		}
+	}
+
+	if err := s.calendarRepo.UpdateEvent(event); err != nil {
+		return nil, fmt.Errorf("failed to update event: %w", err)
+	}
+
+	s.logger.Info("Updated event sharing settings",
+		zap.Uint64("user_id", userID),
+		zap.Uint64("calendar_id", calendar.ID),
+		zap.Uint64("event_id", event.ID),
+		zap.String("visibility", string(event.Visibility)))
+
+	return event, nil
+}
+
+// keepEventSharingSettings copies the settings made in Timely from a stored event onto the
+// incoming version of it, so sync does not reset them
+func keepEventSharingSettings(existing, incoming *model.CalendarEvent) {
+	incoming.Visibility = existing.Visibility
+	incoming.TitleOverride = existing.TitleOverride
+}
+
+// findEditableCalendar finds a calendar, verifies the user owns it and that its events can be edited
+// in Timely, either because Timely manages them or because changes are written back to Google
+func (s *CalendarService) findEditableCalendar(userID uint64, calendarID string) (*model.Calendar, error) {
+	calendar, err := s.calendarRepo.FindByID(calendarID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find calendar: %w", err)
+	}
+
+	if calendar.UserID != userID {
+		return nil, fmt.Errorf("calendar not found or access denied")
+	}
+
+	switch {
+	case calendar.Source == model.SourceTimely:
+	case calendar.Source == model.SourceGoogle && calendar.WriteBack:
+	case calendar.Source == model.SourceGoogle:
+		return nil, fmt.Errorf("calendar write-back is not enabled")
+	default:
+		// Events of other imported calendars would be overwritten by the next sync
+		return nil, fmt.Errorf("calendar is not a Timely calendar")
+	}
+
+	return calendar, nil
+}
+
+// findCalendarEvent finds an event and verifies it belongs to calendar
+func (s *CalendarService) findCalendarEvent(calendar *model.Calendar, eventID string) (*model.CalendarEvent, error) {
+	event, err := s.calendarRepo.FindEventByID(eventID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find event: %w", err)
+	}
+
+	if event.CalendarID != calendar.ID {
+		return nil, fmt.Errorf("event not found")
+	}
+
+	return event, nil
+}
+
+// calendarLocation returns the time zone of a calendar, falling back to UTC
+func calendarLocation(calendar *model.Calendar) *time.Location {
+	loc, err := time.LoadLocation(calendar.TimeZone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// applyEventRequest validates an event request and copies it onto event of calendar. Times without an
+// offset are interpreted in the calendar's time zone, and all-day events are stored as whole UTC days
+// like imported ones.
+func applyEventRequest(event *model.CalendarEvent, eventRequest *model.CalendarEventRequest, calendar *model.Calendar) error {
+	loc := calendarLocation(calendar)
+	title := strings.TrimSpace(eventRequest.Title)
+	if title == "" {
+		return fmt.Errorf("invalid event: title is required")
+	}
+
+	start, err := parseEventRequestTime(eventRequest.Start, eventRequest.AllDay, loc)
+	if err != nil {
+		return fmt.Errorf("invalid event: invalid start: %w", err)
+	}
+
+	end, err := parseEventRequestTime(eventRequest.End, eventRequest.AllDay, loc)
+	if err != nil {
+		return fmt.Errorf("invalid event: invalid end: %w", err)
+	}
+
+	if eventRequest.AllDay && !end.After(start) {
+		return fmt.Errorf("invalid event: end date must be after start date")
+	}
+	if end.Before(start) {
+		return fmt.Errorf("invalid event: end must not be before start")
+	}
+
+	eventColor, err := normalizeColor(eventRequest.EventColor)
+	if err != nil {
+		return fmt.Errorf("invalid event: %w", err)
+	}
+
+	visibility := model.CalendarEventVisibilityInherited
+	if eventRequest.Visibility != nil {
+		visibility = *eventRequest.Visibility
+		if !isValidEventVisibility(visibility) {
+			return fmt.Errorf("invalid event: unknown visibility %q", visibility)
+		}
+	}
+
+	status := model.CalendarEventStatusConfirmed
+	if eventRequest.Status != nil {
+		status = *eventRequest.Status
+		if !isValidEventStatus(status) {
+			return fmt.Errorf("invalid event: unknown status %q", status)
+		}
+	}
+	// Google deletes cancelled events, so they are deleted instead of being cancelled
+	if status == model.CalendarEventStatusCancelled && calendar.Source == model.SourceGoogle {
+		return fmt.Errorf("invalid event: events of Google calendars cannot be cancelled")
+	}
+
+	transparency := model.CalendarEventTransparencyOpaque
+	if eventRequest.Transparency != nil {
+		transparency = *eventRequest.Transparency
+		if !isValidEventTransparency(transparency) {
+			return fmt.Errorf("invalid event: unknown transparency %q", transparency)
+		}
+	}
+
+	event.Title = title
+	event.Start = start
+	event.End = end
+	event.AllDay = eventRequest.AllDay
+	event.Location = eventRequest.Location
+	event.Description = eventRequest.Description
+	event.EventColor = eventColor
+	event.Visibility = visibility
+	event.Status = status
+	event.Transparency = transparency
+	setConferenceLink(event, []string{event.Location}, event.Location, event.Description)
+
+	return nil
+}
+
+// parseEventRequestTime parses the start or end of an event request. All-day events accept a date
+// or a date-time whose date is used.
+func parseEventRequestTime(value string, allDay bool, loc *time.Location) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, fmt.Errorf("value is required")
+	}
+
+	if allDay {
+		if date, err := time.Parse("2006-01-02", value); err == nil {
+			return date, nil
+		}
+	}
+
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		parsed, err = time.ParseInLocation("2006-01-02T15:04:05", value, loc)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("expected an RFC 3339 date-time or, for all-day events, a date")
+		}
+	}
+
+	if allDay {
+		return time.Date(parsed.Year(), parsed.Month(), parsed.Day(), 0, 0, 0, 0, time.UTC), nil
+	}
+	return parsed, nil
+}
+
+// isValidEventVisibility checks whether visibility is one of the supported event visibilities
+func isValidEventVisibility(visibility model.CalendarEventVisibility) bool {
+	switch visibility {
+	case model.CalendarEventVisibilityPublic, model.CalendarEventVisibilityPrivate, model.CalendarEventVisibilityInherited:
+		return true
+	}
+	return false
+}
+
+// isValidEventStatus checks whether status is one of the supported event statuses
+func isValidEventStatus(status model.CalendarEventStatus) bool {
+	switch status {
+	case model.CalendarEventStatusConfirmed, model.CalendarEventStatusTentative, model.CalendarEventStatusCancelled:
+		return true
+	}
+	return false
+}
+
+// isValidEventTransparency checks whether transparency is one of the supported event transparencies
+func isValidEventTransparency(transparency model.CalendarEventTransparency) bool {
+	switch transparency {
+	case model.CalendarEventTransparencyOpaque, model.CalendarEventTransparencyTransparent:
+		return true
+	}
+	return false
+}
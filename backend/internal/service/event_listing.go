@@ -0,0 +1,176 @@
+package service
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/NathanWasTaken/timely/backend/internal/model"
+)
+
+const (
+	// DefaultEventPageLimit and MaxEventPageLimit bound the number of events a page of the flat listing holds
+	DefaultEventPageLimit = 250
+	MaxEventPageLimit     = 1000
+	// eventStreamBatchSize is the number of events streamed between flushes
+	eventStreamBatchSize = 200
+)
+
+// GetUserCalendarEventsPage returns a page of the events of the user's calendars within a time range,
+// ordered by start and ID, and the cursor of the next page, which is empty on the last page. The
+// first page is returned for an empty cursor. Unlike the nested listing the range isn't limited,
+// since only one page is loaded at a time.
+func (s *CalendarService) GetUserCalendarEventsPage(userID uint64, startTime, endTime time.Time, cursor string, limit int) ([]*model.CalendarEvent, string, error) {
+	if endTime.Before(startTime) {
+		return nil, "", fmt.Errorf("invalid time range: end must not be before start")
+	}
+	if limit <= 0 {
+		limit = DefaultEventPageLimit
+	}
+	limit = min(limit, MaxEventPageLimit)
+
+	var afterStart time.Time
+	var afterID uint64
+	if cursor != "" {
+		var err error
+		afterStart, afterID, err = decodeEventCursor(cursor)
+		if err != nil {
+			return nil, "", err
+		}
+	}
+
+	calendars, err := s.calendarRepo.FindByUserID(userID)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get user calendars: %w", err)
+	}
+	if len(calendars) == 0 {
+		return []*model.CalendarEvent{}, "", nil
+	}
+
+	calendarIDs := make([]uint64, 0, len(calendars))
+	for _, calendar := range calendars {
+		calendarIDs = append(calendarIDs, calendar.ID)
+	}
+
+	// One more event than asked for tells whether there is a next page
+	events, err := s.calendarRepo.FindEventsPage(calendarIDs, startTime, endTime, afterStart, afterID, limit+1)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get calendar events: %w", err)
+	}
+
+	nextCursor := ""
+	if len(events) > limit {
+		events = events[:limit]
+		// The cursor points at the last stored event, before any are hidden or redacted
+		nextCursor = encodeEventCursor(events[len(events)-1])
+	}
+
+	events = s.presentOwnerEvents(calendars, events, true)
+
+	s.logger.Info("Retrieved page of calendar events",
+		zap.Uint64("user_id", userID),
+		zap.Int("calendar_count", len(calendars)),
+		zap.Int("event_count", len(events)),
+		zap.Bool("last_page", nextCursor == ""))
+
+	return events, nextCursor, nil
+}
+
+// StreamUserCalendarEvents passes the events of the user's calendars within a time range to fn in
+// batches, ordered by start and ID, as they are read from the database instead of loading them at
+// once. Streamed events come without their attendees and write conflicts, since those would need
+// another query while the events are read. Stops at the first error of fn.
+func (s *CalendarService) StreamUserCalendarEvents(userID uint64, startTime, endTime time.Time, fn func([]*model.CalendarEvent) error) error {
+	if endTime.Before(startTime) {
+		return fmt.Errorf("invalid time range: end must not be before start")
+	}
+
+	calendars, err := s.calendarRepo.FindByUserID(userID)
+	if err != nil {
+		return fmt.Errorf("failed to get user calendars: %w", err)
+	}
+	if len(calendars) == 0 {
+		return nil
+	}
+
+	calendarIDs := make([]uint64, 0, len(calendars))
+	for _, calendar := range calendars {
+		calendarIDs = append(calendarIDs, calendar.ID)
+	}
+
+	eventCount := 0
+	err = s.calendarRepo.StreamEvents(calendarIDs, startTime, endTime, eventStreamBatchSize, func(events []*model.CalendarEvent) error {
+		events = s.presentOwnerEvents(calendars, events, false)
+		if len(events) == 0 {
+			return nil
+		}
+		eventCount += len(events)
+		return fn(events)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to stream calendar events: %w", err)
+	}
+
+	s.logger.Info("Streamed calendar events",
+		zap.Uint64("user_id", userID),
+		zap.Int("calendar_count", len(calendars)),
+		zap.Int("event_count", eventCount))
+
+	return nil
+}
+
+// presentOwnerEvents prepares events of the user's calendars the way the owner's listing shows
+// them: without hidden declined events and in their calendar's color, but unredacted since
+// redaction only applies to what is rendered publicly. Attendees are loaded only when
+// withAttendees is set, together with the write conflicts of the events.
+func (s *CalendarService) presentOwnerEvents(calendars []*model.Calendar, events []*model.CalendarEvent, withAttendees bool) []*model.CalendarEvent {
+	calendarMap := make(map[uint64]*model.Calendar, len(calendars))
+	for _, calendar := range calendars {
+		calendarMap[calendar.ID] = calendar
+	}
+
+	events = withoutHiddenDeclined(calendars, events)
+	if withAttendees {
+		s.loadEventAttendees(events)
+		s.markWriteConflicts(events)
+	}
+
+	for _, event := range events {
+		// Events without a color of their own are shown in the calendar's color
+		applyCalendarColor([]*model.CalendarEvent{event}, calendarMap[event.CalendarID])
+	}
+	return events
+}
+
+// encodeEventCursor returns the cursor of the events after event in order of start and ID. The
+// start keeps its offset so it compares equal to the stored one.
+func encodeEventCursor(event *model.CalendarEvent) string {
+	value := event.Start.Format(time.RFC3339Nano) + "|" + strconv.FormatUint(event.ID, 10)
+	return base64.RawURLEncoding.EncodeToString([]byte(value))
+}
+
+// decodeEventCursor returns the start and ID of the event a cursor points after
+func decodeEventCursor(cursor string) (time.Time, uint64, error) {
+	value, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, 0, fmt.Errorf("invalid cursor: malformed cursor")
+	}
+
+	start, id, ok := strings.Cut(string(value), "|")
+	if !ok {
+		return time.Time{}, 0, fmt.Errorf("invalid cursor: malformed cursor")
+	}
+	startTime, err := time.Parse(time.RFC3339Nano, start)
+	if err != nil {
+		return time.Time{}, 0, fmt.Errorf("invalid cursor: malformed cursor")
+	}
+	eventID, err := strconv.ParseUint(id, 10, 64)
+	if err != nil || eventID == 0 {
+		return time.Time{}, 0, fmt.Errorf("invalid cursor: malformed cursor")
+	}
+	return startTime, eventID, nil
+}
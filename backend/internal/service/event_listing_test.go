@@ -0,0 +1,98 @@
+package service
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	ics "github.com/arran4/golang-ical"
+
+	"github.com/NathanWasTaken/timely/backend/internal/model"
+	"github.com/NathanWasTaken/timely/backend/pkg/utils"
+)
+
+func TestListCalendarEventsByCursor(t *testing.T) {
+	calendarService, _ := newTestCalendarService(t)
+
+	// Events a year apart and events starting at the same time
+	icsCalendar, err := ics.ParseCalendar(strings.NewReader(buildICSFeed(
+		buildICSEvent("kickoff", "Kickoff", "20240101T090000Z"),
+		buildICSEvent("standup", "Standup", "20240701T090000Z"),
+		buildICSEvent("review", "Review", "20240701T090000Z"),
+		buildICSEvent("retro", "Retro", "20240701T090000Z"),
+		buildICSEvent("wrapup", "Wrap-up", "20241231T090000Z"),
+	)))
+	if err != nil {
+		t.Fatalf("Failed to parse ICS data: %v", err)
+	}
+	userID := utils.GenerateID()
+	calendar, _, err := calendarService.ImportICSCalendar(userID, "Team", icsCalendar, icsCalendar.Events())
+	if err != nil {
+		t.Fatalf("ImportICSCalendar failed: %v", err)
+	}
+	// Redaction only applies publicly, the owner's listing shows the events as they are
+	redaction := "Busy"
+	calendar.EventRedaction = &redaction
+	calendar.RedactionMode = model.CalendarRedactionModeBusy
+	if err := calendarService.calendarRepo.Update(calendar); err != nil {
+		t.Fatalf("Failed to update calendar: %v", err)
+	}
+
+	// The flat listing isn't limited to 6 months
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(1, 0, 0)
+
+	var paged []*model.CalendarEvent
+	cursor, pages := "", 0
+	for {
+		events, nextCursor, err := calendarService.GetUserCalendarEventsPage(userID, start, end, cursor, 2)
+		if err != nil {
+			t.Fatalf("GetUserCalendarEventsPage failed on page %d: %v", pages, err)
+		}
+		paged = append(paged, events...)
+		pages++
+		if nextCursor == "" {
+			break
+		}
+		if pages > 5 {
+			t.Fatalf("Expected the listing to end, got cursor %q", nextCursor)
+		}
+		cursor = nextCursor
+	}
+	if pages != 3 || len(paged) != 5 {
+		t.Fatalf("Expected 5 events on 3 pages, got %d on %d", len(paged), pages)
+	}
+	for i, event := range paged {
+		if event.Title == "" || event.Title == "Busy" {
+			t.Errorf("Expected event %s not to be redacted, got %q", event.SourceID, event.Title)
+		}
+		if i > 0 {
+			previous := paged[i-1]
+			if event.Start.Before(previous.Start) || (event.Start.Equal(previous.Start) && event.ID <= previous.ID) {
+				t.Errorf("Expected events in order of start and ID, got %s after %s", event.SourceID, previous.SourceID)
+			}
+		}
+	}
+
+	// The stream sends the same events in the same order
+	var streamed []*model.CalendarEvent
+	err = calendarService.StreamUserCalendarEvents(userID, start, end, func(events []*model.CalendarEvent) error {
+		streamed = append(streamed, events...)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("StreamUserCalendarEvents failed: %v", err)
+	}
+	if len(streamed) != len(paged) {
+		t.Fatalf("Expected %d streamed events, got %d", len(paged), len(streamed))
+	}
+	for i := range streamed {
+		if streamed[i].ID != paged[i].ID || streamed[i].Title != paged[i].Title {
+			t.Errorf("Expected streamed event %d to be %s, got %+v", i, paged[i].SourceID, streamed[i])
+		}
+	}
+
+	if _, _, err := calendarService.GetUserCalendarEventsPage(userID, start, end, "not a cursor", 2); err == nil || !strings.HasPrefix(err.Error(), "invalid cursor") {
+		t.Errorf("Expected a malformed cursor to be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,355 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NathanWasTaken/timely/backend/internal/model"
+)
+
+func TestTimelyCalendarEventLifecycle(t *testing.T) {
+	calendarService, calendarRepo := newTestCalendarService(t)
+
+	const userID = 11
+	calendar, err := calendarService.CreateCalendar(userID, &model.CalendarCreateRequest{
+		Summary:  "Team",
+		TimeZone: "Europe/Berlin",
+	})
+	if err != nil {
+		t.Fatalf("Failed to create calendar: %v", err)
+	}
+	if calendar.Source != model.SourceTimely || calendar.Visibility != model.CalendarVisibilityPrivate {
+		t.Errorf("Expected a private Timely calendar, got source %q and visibility %q", calendar.Source, calendar.Visibility)
+	}
+	calendarID := fmt.Sprintf("%d", calendar.ID)
+
+	// Times without an offset are interpreted in the calendar's time zone
+	event, err := calendarService.CreateEvent(userID, calendarID, &model.CalendarEventRequest{
+		Title: " Planning ",
+		Start: "2024-07-01T10:00:00",
+		End:   "2024-07-01T11:00:00",
+	})
+	if err != nil {
+		t.Fatalf("Failed to create event: %v", err)
+	}
+	if event.Title != "Planning" || event.Visibility != model.CalendarEventVisibilityInherited {
+		t.Errorf("Unexpected event: title %q, visibility %q", event.Title, event.Visibility)
+	}
+	if got := event.Start.UTC().Format(time.RFC3339); got != "2024-07-01T08:00:00Z" {
+		t.Errorf("Expected the start to be interpreted in Europe/Berlin, got %s", got)
+	}
+	eventID := fmt.Sprintf("%d", event.ID)
+
+	// All-day events are stored as whole UTC days
+	public := model.CalendarEventVisibilityPublic
+	updated, err := calendarService.UpdateEvent(userID, calendarID, eventID, &model.CalendarEventRequest{
+		Title:      "Offsite",
+		Start:      "2024-07-02",
+		End:        "2024-07-04",
+		AllDay:     true,
+		Visibility: &public,
+	})
+	if err != nil {
+		t.Fatalf("Failed to update event: %v", err)
+	}
+	if !updated.AllDay || updated.Start.Format(time.RFC3339) != "2024-07-02T00:00:00Z" || updated.Visibility != public {
+		t.Errorf("Unexpected updated event: %+v", updated)
+	}
+
+	invalid := []*model.CalendarEventRequest{
+		{Title: "", Start: "2024-07-01T10:00:00Z", End: "2024-07-01T11:00:00Z"},
+		{Title: "Backwards", Start: "2024-07-01T11:00:00Z", End: "2024-07-01T10:00:00Z"},
+		{Title: "Empty day", Start: "2024-07-01", End: "2024-07-01", AllDay: true},
+		{Title: "Bad time", Start: "tomorrow", End: "2024-07-01T10:00:00Z"},
+		{Title: "Bad visibility", Start: "2024-07-01T10:00:00Z", End: "2024-07-01T11:00:00Z", Visibility: func() *model.CalendarEventVisibility {
+			v := model.CalendarEventVisibility("secret")
+			return &v
+		}()},
+	}
+	for _, eventRequest := range invalid {
+		if _, err := calendarService.CreateEvent(userID, calendarID, eventRequest); err == nil || !strings.HasPrefix(err.Error(), "invalid event") {
+			t.Errorf("Expected a validation error for %q, got %v", eventRequest.Title, err)
+		}
+	}
+
+	// Other users cannot edit the calendar
+	if _, err := calendarService.UpdateEvent(userID+1, calendarID, eventID, &model.CalendarEventRequest{}); err == nil {
+		t.Error("Expected updating another user's event to fail")
+	}
+
+	// Imported calendars cannot be edited
+	icsCalendar := calendarService.newICSCalendar(userID, "Imported", "")
+	if err := calendarRepo.Create(icsCalendar); err != nil {
+		t.Fatalf("Failed to create calendar: %v", err)
+	}
+	_, err = calendarService.CreateEvent(userID, fmt.Sprintf("%d", icsCalendar.ID), &model.CalendarEventRequest{
+		Title: "Nope",
+		Start: "2024-07-01T10:00:00Z",
+		End:   "2024-07-01T11:00:00Z",
+	})
+	if err == nil || err.Error() != "calendar is not a Timely calendar" {
+		t.Errorf("Expected creating an event in an ICS calendar to fail, got %v", err)
+	}
+
+	if err := calendarService.DeleteEvent(userID, calendarID, eventID); err != nil {
+		t.Fatalf("Failed to delete event: %v", err)
+	}
+	if count, _ := calendarRepo.CountEventsByCalendarID(calendar.ID); count != 0 {
+		t.Errorf("Expected no events after delete, got %d", count)
+	}
+}
+
+func TestPatchEventSurvivesGoogleSync(t *testing.T) {
+	calendarService, calendarRepo := newTestCalendarService(t)
+
+	const userID = 12
+	if err := calendarService.userRepo.Create(&model.User{ID: userID, Username: "alex", DisplayName: "Alex"}); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	redaction := "Busy"
+	sourceID := "primary"
+	calendar := &model.Calendar{
+		ID:             1,
+		UserID:         userID,
+		SourceID:       &sourceID,
+		Source:         model.SourceGoogle,
+		Summary:        "Work",
+		Visibility:     model.CalendarVisibilityPrivate,
+		EventRedaction: &redaction,
+	}
+	if err := calendarRepo.Create(calendar); err != nil {
+		t.Fatalf("Failed to create calendar: %v", err)
+	}
+
+	googleEvent := &model.GoogleCalendarEvent{
+		ID:       "abc",
+		Status:   "confirmed",
+		Location: "Room 1",
+		Start:    &model.GoogleCalendarEventTime{DateTime: "2024-07-01T10:00:00Z"},
+		End:      &model.GoogleCalendarEventTime{DateTime: "2024-07-01T11:00:00Z"},
+	}
+	sync := func(summary string) {
+		t.Helper()
+		googleEvent.Summary = summary
+		event, err := convertGoogleEventToCalendarEvent(googleEvent, calendar.ID, googleDefaultEventColors)
+		if err != nil {
+			t.Fatalf("Failed to convert event: %v", err)
+		}
+		if _, err := calendarService.mergeEvents(calendar, &ProviderEvents{Events: []*model.CalendarEvent{event}}); err != nil {
+			t.Fatalf("Failed to merge events: %v", err)
+		}
+	}
+
+	sync("1:1 with Alex")
+	events, err := calendarRepo.FindEventsByCalendarID(calendar.ID)
+	if err != nil || len(events) != 1 {
+		t.Fatalf("Expected 1 synced event, got %d (%v)", len(events), err)
+	}
+
+	public := model.CalendarEventVisibilityPublic
+	titleOverride := " Meeting "
+	_, err = calendarService.PatchEvent(userID, "1", fmt.Sprintf("%d", events[0].ID), &model.CalendarEventPatchRequest{
+		Visibility:    &public,
+		TitleOverride: &titleOverride,
+	})
+	if err != nil {
+		t.Fatalf("Failed to patch event: %v", err)
+	}
+
+	// An incremental sync changing the event keeps the settings made in Timely
+	sync("1:1 with Alex (moved)")
+
+	calendarsWithEvents, err := calendarService.GetPublicUserCalendarEvents(userID,
+		time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 7, 2, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("Failed to get public events: %v", err)
+	}
+	if len(calendarsWithEvents) != 1 || len(calendarsWithEvents[0].Events) != 1 {
+		t.Fatalf("Expected the patched event to be public, got %+v", calendarsWithEvents)
+	}
+	event := calendarsWithEvents[0].Events[0]
+	if event.Title != "Meeting" || event.Location != "" {
+		t.Errorf("Expected the title override to win over the redaction, got title %q and location %q", event.Title, event.Location)
+	}
+
+	// An empty override removes it again
+	empty := ""
+	patched, err := calendarService.PatchEvent(userID, "1", fmt.Sprintf("%d", event.ID), &model.CalendarEventPatchRequest{TitleOverride: &empty})
+	if err != nil {
+		t.Fatalf("Failed to patch event: %v", err)
+	}
+	if patched.TitleOverride != nil || patched.Visibility != public {
+		t.Errorf("Expected only the title override to be cleared, got %+v", patched)
+	}
+
+	// Other users cannot change the event
+	if _, err := calendarService.PatchEvent(userID+1, "1", fmt.Sprintf("%d", event.ID), &model.CalendarEventPatchRequest{}); err == nil {
+		t.Error("Expected patching another user's event to fail")
+	}
+}
+
+func TestPublicEventsRedactionModes(t *testing.T) {
+	calendarService, calendarRepo := newTestCalendarService(t)
+
+	const userID = 13
+	user := &model.User{ID: userID, Username: "sam", DisplayName: "Sam"}
+	if err := calendarService.userRepo.Create(user); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	calendar, err := calendarService.CreateCalendar(userID, &model.CalendarCreateRequest{Summary: "Private"})
+	if err != nil {
+		t.Fatalf("Failed to create calendar: %v", err)
+	}
+	calendarID := fmt.Sprintf("%d", calendar.ID)
+
+	start := time.Now().UTC().Truncate(time.Hour).Add(24 * time.Hour)
+	public := model.CalendarEventVisibilityPublic
+	_, err = calendarService.CreateEvent(userID, calendarID, &model.CalendarEventRequest{
+		Title:       "Doctor",
+		Start:       start.Format(time.RFC3339),
+		End:         start.Add(time.Hour).Format(time.RFC3339),
+		Location:    "Clinic",
+		Description: "Checkup",
+		EventColor:  "#ff0000",
+		Visibility:  &public,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create event: %v", err)
+	}
+
+	redaction := "Appointment"
+	publicEvent := func(mode model.CalendarRedactionMode) *model.CalendarEvent {
+		t.Helper()
+		if _, err := calendarService.UpdateCalendar(userID, calendarID, &model.CalendarUpdateRequest{
+			EventRedaction: &redaction,
+			RedactionMode:  &mode,
+		}); err != nil {
+			t.Fatalf("Failed to update calendar: %v", err)
+		}
+		calendarsWithEvents, err := calendarService.GetPublicUserCalendarEvents(userID, start.Add(-time.Hour), start.Add(2*time.Hour))
+		if err != nil {
+			t.Fatalf("Failed to get public events: %v", err)
+		}
+		if len(calendarsWithEvents) != 1 || len(calendarsWithEvents[0].Events) != 1 {
+			t.Fatalf("Expected 1 public event, got %+v", calendarsWithEvents)
+		}
+		return calendarsWithEvents[0].Events[0]
+	}
+
+	if event := publicEvent(model.CalendarRedactionModeTitle); event.Title != redaction || event.Location != "Clinic" || event.EventColor != "#ff0000" {
+		t.Errorf("Expected only the title to be redacted, got %+v", event)
+	}
+	if event := publicEvent(model.CalendarRedactionModeFull); event.Title != redaction || event.Location != "" || event.Description != "" || event.EventColor != "" {
+		t.Errorf("Expected title, location, description and color to be redacted, got %+v", event)
+	}
+
+	// Full redaction removes the details even without a redaction text
+	redaction = ""
+	if event := publicEvent(model.CalendarRedactionModeFull); event.Title != "Doctor" || event.Location != "" || event.Description != "" || event.EventColor != "" {
+		t.Errorf("Expected location, description and color to be redacted, got %+v", event)
+	}
+	redaction = "Appointment"
+
+	event := publicEvent(model.CalendarRedactionModeBusy)
+	if event.Title != "" || event.Location != "" || event.Description != "" || event.EventColor != "" || event.SourceID != "" || !event.UpdatedAt.IsZero() ||
+		event.Status != "" || event.Transparency != "" {
+		t.Errorf("Expected a busy block with only times, got %+v", event)
+	}
+	if !event.Start.Equal(start) || !event.End.Equal(start.Add(time.Hour)) {
+		t.Errorf("Expected the busy block to keep the event times, got %s to %s", event.Start, event.End)
+	}
+
+	// The published ICS feed renders the same busy block
+	feed, err := calendarService.GetPublicUserCalendarICS(user)
+	if err != nil {
+		t.Fatalf("Failed to render feed: %v", err)
+	}
+	if strings.Contains(feed, "Doctor") || strings.Contains(feed, "Clinic") || strings.Contains(feed, "SUMMARY") {
+		t.Errorf("Expected the feed to contain only busy blocks, got:\n%s", feed)
+	}
+
+	invalid := model.CalendarRedactionMode("partial")
+	if _, err := calendarService.UpdateCalendar(userID, calendarID, &model.CalendarUpdateRequest{RedactionMode: &invalid}); err == nil || !strings.HasPrefix(err.Error(), "invalid calendar") {
+		t.Errorf("Expected an unknown redaction mode to be rejected, got %v", err)
+	}
+
+	// Stored events are not changed by rendering
+	stored, _ := calendarRepo.FindEventsByCalendarID(calendar.ID)
+	if len(stored) != 1 || stored[0].Title != "Doctor" || stored[0].Location != "Clinic" {
+		t.Errorf("Expected the stored event to be unchanged, got %+v", stored)
+	}
+}
+
+func TestOwnerEventsAreNotRedacted(t *testing.T) {
+	calendarService, _ := newTestCalendarService(t)
+
+	const userID = 14
+	if err := calendarService.userRepo.Create(&model.User{ID: userID, Username: "kim", DisplayName: "Kim"}); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	calendar, err := calendarService.CreateCalendar(userID, &model.CalendarCreateRequest{Summary: "Private"})
+	if err != nil {
+		t.Fatalf("Failed to create calendar: %v", err)
+	}
+	calendarID := fmt.Sprintf("%d", calendar.ID)
+
+	start := time.Date(2024, 7, 1, 9, 0, 0, 0, time.UTC)
+	_, err = calendarService.CreateEvent(userID, calendarID, &model.CalendarEventRequest{
+		Title:       "Doctor",
+		Start:       start.Format(time.RFC3339),
+		End:         start.Add(time.Hour).Format(time.RFC3339),
+		Location:    "Clinic",
+		Description: "Join at https://zoom.us/j/123456",
+		EventColor:  "#ff0000",
+	})
+	if err != nil {
+		t.Fatalf("Failed to create event: %v", err)
+	}
+
+	redaction := "Appointment"
+	for _, mode := range []model.CalendarRedactionMode{model.CalendarRedactionModeFull, model.CalendarRedactionModeTitle, model.CalendarRedactionModeBusy} {
+		if _, err := calendarService.UpdateCalendar(userID, calendarID, &model.CalendarUpdateRequest{
+			EventRedaction: &redaction,
+			RedactionMode:  &mode,
+		}); err != nil {
+			t.Fatalf("Failed to update calendar: %v", err)
+		}
+
+		// Every listing of the owner shows the event as it is, whatever its public redaction
+		var listed []*model.CalendarEvent
+		calendarsWithEvents, err := calendarService.GetUserCalendarEventsWithSync(userID, start.Add(-time.Hour), start.Add(2*time.Hour), false)
+		if err != nil {
+			t.Fatalf("Failed to get events in %s mode: %v", mode, err)
+		}
+		for _, calendarWithEvents := range calendarsWithEvents {
+			listed = append(listed, calendarWithEvents.Events...)
+		}
+		paged, _, err := calendarService.GetUserCalendarEventsPage(userID, start.Add(-time.Hour), start.Add(2*time.Hour), "", 0)
+		if err != nil {
+			t.Fatalf("Failed to get page of events in %s mode: %v", mode, err)
+		}
+		listed = append(listed, paged...)
+		err = calendarService.StreamUserCalendarEvents(userID, start.Add(-time.Hour), start.Add(2*time.Hour), func(events []*model.CalendarEvent) error {
+			listed = append(listed, events...)
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("Failed to stream events in %s mode: %v", mode, err)
+		}
+
+		if len(listed) != 3 {
+			t.Fatalf("Expected the event in every listing in %s mode, got %d events", mode, len(listed))
+		}
+		for _, event := range listed {
+			if event.Title != "Doctor" || event.Location != "Clinic" || event.EventColor != "#ff0000" ||
+				event.ConferenceProvider != model.ConferenceProviderZoom || event.SourceID == "" {
+				t.Errorf("Expected the owner to see the event unredacted in %s mode, got %+v", mode, event)
+			}
+		}
+	}
+}
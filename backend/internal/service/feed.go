@@ -0,0 +1,158 @@
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/NathanWasTaken/timely/backend/internal/model"
+	"github.com/NathanWasTaken/timely/backend/internal/repository"
+	"github.com/NathanWasTaken/timely/backend/pkg/utils"
+)
+
+const (
+	// defaultFeedTokenName is used when a feed token is created without a name
+	defaultFeedTokenName = "Calendar feed"
+	// feedTokenHintLength is the number of trailing token characters kept to identify a token
+	feedTokenHintLength = 4
+)
+
+// FeedService manages the secret tokens that grant access to a user's private ICS feed
+type FeedService struct {
+	feedTokenRepo *repository.FeedTokenRepository
+	userRepo      *repository.UserRepository
+	logger        *zap.Logger
+}
+
+func NewFeedService(feedTokenRepo *repository.FeedTokenRepository, userRepo *repository.UserRepository) *FeedService {
+	return &FeedService{
+		feedTokenRepo: feedTokenRepo,
+		userRepo:      userRepo,
+		logger:        zap.L(),
+	}
+}
+
+// ListFeedTokens retrieves all feed tokens for a user
+func (s *FeedService) ListFeedTokens(userID uint64) ([]*model.FeedToken, error) {
+	feedTokens, err := s.feedTokenRepo.FindByUserID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get feed tokens: %w", err)
+	}
+	return feedTokens, nil
+}
+
+// CreateFeedToken creates a new feed token for a user and returns it along with the secret token,
+// which is not stored and cannot be retrieved again
+func (s *FeedService) CreateFeedToken(userID uint64, name string) (*model.FeedToken, string, error) {
+	if name == "" {
+		name = defaultFeedTokenName
+	}
+
+	token, err := utils.GenerateSecretToken()
+	if err != nil {
+		return nil, "", err
+	}
+
+	feedToken := &model.FeedToken{
+		ID:        utils.GenerateID(),
+		UserID:    userID,
+		Name:      name,
+		TokenHash: utils.HashToken(token),
+		TokenHint: token[len(token)-feedTokenHintLength:],
+	}
+
+	if err := s.feedTokenRepo.Create(feedToken); err != nil {
+		return nil, "", fmt.Errorf("failed to create feed token: %w", err)
+	}
+
+	s.logger.Info("Created feed token",
+		zap.Uint64("user_id", userID),
+		zap.Uint64("feed_token_id", feedToken.ID))
+
+	return feedToken, token, nil
+}
+
+// RotateFeedToken replaces the secret of a feed token, invalidating the previous feed URL
+func (s *FeedService) RotateFeedToken(userID uint64, feedTokenID string) (*model.FeedToken, string, error) {
+	feedToken, err := s.findOwnedFeedToken(userID, feedTokenID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	token, err := utils.GenerateSecretToken()
+	if err != nil {
+		return nil, "", err
+	}
+
+	feedToken.TokenHash = utils.HashToken(token)
+	feedToken.TokenHint = token[len(token)-feedTokenHintLength:]
+	feedToken.LastUsedAt = nil
+
+	if err := s.feedTokenRepo.Update(feedToken); err != nil {
+		return nil, "", fmt.Errorf("failed to update feed token: %w", err)
+	}
+
+	s.logger.Info("Rotated feed token",
+		zap.Uint64("user_id", userID),
+		zap.Uint64("feed_token_id", feedToken.ID))
+
+	return feedToken, token, nil
+}
+
+// RevokeFeedToken deletes a feed token so its feed URL stops working
+func (s *FeedService) RevokeFeedToken(userID uint64, feedTokenID string) error {
+	if _, err := s.findOwnedFeedToken(userID, feedTokenID); err != nil {
+		return err
+	}
+
+	if err := s.feedTokenRepo.Delete(feedTokenID); err != nil {
+		return fmt.Errorf("failed to delete feed token: %w", err)
+	}
+
+	s.logger.Info("Revoked feed token",
+		zap.Uint64("user_id", userID),
+		zap.String("feed_token_id", feedTokenID))
+
+	return nil
+}
+
+// AuthenticateFeedToken resolves a secret feed token to the user owning it
+func (s *FeedService) AuthenticateFeedToken(token string) (*model.User, error) {
+	if token == "" {
+		return nil, fmt.Errorf("invalid feed token")
+	}
+
+	feedToken, err := s.feedTokenRepo.FindByTokenHash(utils.HashToken(token))
+	if err != nil {
+		return nil, fmt.Errorf("invalid feed token")
+	}
+
+	user, err := s.userRepo.FindByID(feedToken.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid feed token")
+	}
+
+	// Recording usage is best-effort and must not break the feed
+	if err := s.feedTokenRepo.UpdateLastUsedAt(feedToken.ID, time.Now()); err != nil {
+		s.logger.Warn("Failed to update feed token usage",
+			zap.Error(err),
+			zap.Uint64("feed_token_id", feedToken.ID))
+	}
+
+	return user, nil
+}
+
+// findOwnedFeedToken finds a feed token and verifies it belongs to the user
+func (s *FeedService) findOwnedFeedToken(userID uint64, feedTokenID string) (*model.FeedToken, error) {
+	feedToken, err := s.feedTokenRepo.FindByID(feedTokenID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find feed token: %w", err)
+	}
+
+	if feedToken.UserID != userID {
+		return nil, fmt.Errorf("feed token not found or access denied")
+	}
+
+	return feedToken, nil
+}
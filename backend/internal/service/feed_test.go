@@ -0,0 +1,73 @@
+package service
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/NathanWasTaken/timely/backend/internal/model"
+	"github.com/NathanWasTaken/timely/backend/internal/repository"
+	"github.com/NathanWasTaken/timely/backend/pkg/utils"
+)
+
+func TestFeedTokenLifecycle(t *testing.T) {
+	db := newTestDB(t)
+	userRepo := repository.NewUserRepository(db)
+	feedService := NewFeedService(repository.NewFeedTokenRepository(db), userRepo)
+
+	owner := &model.User{ID: utils.GenerateID(), Username: "owner", DisplayName: "Owner"}
+	if err := userRepo.Create(owner); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	feedToken, token, err := feedService.CreateFeedToken(owner.ID, "")
+	if err != nil {
+		t.Fatalf("Failed to create feed token: %v", err)
+	}
+	if feedToken.TokenHash == token || feedToken.TokenHash != utils.HashToken(token) {
+		t.Error("Expected only the hash of the token to be stored")
+	}
+	if feedToken.Name != defaultFeedTokenName {
+		t.Errorf("Expected the default name, got %q", feedToken.Name)
+	}
+
+	user, err := feedService.AuthenticateFeedToken(token)
+	if err != nil || user.ID != owner.ID {
+		t.Fatalf("Expected the token to resolve to its owner, got %v, %v", user, err)
+	}
+	if _, err := feedService.AuthenticateFeedToken(token + "x"); err == nil {
+		t.Error("Expected an unknown token to be rejected")
+	}
+
+	feedTokenID := fmt.Sprintf("%d", feedToken.ID)
+
+	// Only the owner can rotate or revoke a token
+	if _, _, err := feedService.RotateFeedToken(owner.ID+1, feedTokenID); err == nil {
+		t.Error("Expected rotating another user's token to fail")
+	}
+
+	_, rotated, err := feedService.RotateFeedToken(owner.ID, feedTokenID)
+	if err != nil {
+		t.Fatalf("Failed to rotate feed token: %v", err)
+	}
+	if _, err := feedService.AuthenticateFeedToken(token); err == nil {
+		t.Error("Expected the previous token to stop working after rotation")
+	}
+	if _, err := feedService.AuthenticateFeedToken(rotated); err != nil {
+		t.Errorf("Expected the rotated token to work: %v", err)
+	}
+
+	if err := feedService.RevokeFeedToken(owner.ID, feedTokenID); err != nil {
+		t.Fatalf("Failed to revoke feed token: %v", err)
+	}
+	if _, err := feedService.AuthenticateFeedToken(rotated); err == nil {
+		t.Error("Expected a revoked token to be rejected")
+	}
+
+	feedTokens, err := feedService.ListFeedTokens(owner.ID)
+	if err != nil {
+		t.Fatalf("Failed to list feed tokens: %v", err)
+	}
+	if len(feedTokens) != 0 {
+		t.Errorf("Expected no feed tokens after revocation, got %d", len(feedTokens))
+	}
+}
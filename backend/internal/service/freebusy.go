@@ -0,0 +1,134 @@
+package service
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	ics "github.com/arran4/golang-ical"
+	"go.uber.org/zap"
+
+	"github.com/NathanWasTaken/timely/backend/internal/model"
+)
+
+// GetUserFreeBusy returns the busy intervals of a user within a time range. Every event of the
+// calendars the user shares free/busy information of counts, regardless of its visibility, and
+// overlapping or adjacent events are merged so no event details can be inferred.
+func (s *CalendarService) GetUserFreeBusy(userID uint64, startTime, endTime time.Time) ([]*model.FreeBusyInterval, error) {
+	if err := validateEventTimeRange(startTime, endTime); err != nil {
+		return nil, err
+	}
+
+	calendars, err := s.calendarRepo.FindByUserID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user calendars: %w", err)
+	}
+
+	var calendarIDs []uint64
+	for _, calendar := range calendars {
+		if calendar.FreeBusy {
+			calendarIDs = append(calendarIDs, calendar.ID)
+		}
+	}
+
+	if len(calendarIDs) == 0 {
+		return []*model.FreeBusyInterval{}, nil
+	}
+
+	events, err := s.calendarRepo.FindEventsByCalendarIDsOverlappingTimeRange(calendarIDs, startTime, endTime)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get calendar events: %w", err)
+	}
+	events = withoutHiddenDeclined(calendars, events)
+
+	busy := mergeBusyIntervals(events, startTime, endTime)
+
+	s.logger.Info("Successfully retrieved free/busy",
+		zap.Uint64("user_id", userID),
+		zap.Int("calendar_count", len(calendarIDs)),
+		zap.Int("busy_count", len(busy)),
+		zap.Time("start_time", startTime),
+		zap.Time("end_time", endTime))
+
+	return busy, nil
+}
+
+// GetUserFreeBusyICS renders the busy intervals of a user within a time range as a VFREEBUSY
+func (s *CalendarService) GetUserFreeBusyICS(user *model.User, startTime, endTime time.Time) (string, error) {
+	busy, err := s.GetUserFreeBusy(user.ID, startTime, endTime)
+	if err != nil {
+		return "", err
+	}
+
+	return renderFreeBusyICS(user, startTime, endTime, busy), nil
+}
+
+// mergeBusyIntervals clips the events that block time to a time range and merges those that overlap
+// or touch into sorted, disjoint busy intervals
+func mergeBusyIntervals(events []*model.CalendarEvent, startTime, endTime time.Time) []*model.FreeBusyInterval {
+	busy := []*model.FreeBusyInterval{}
+	for _, event := range events {
+		if !blocksTime(event) {
+			continue
+		}
+
+		start, end := event.Start.UTC(), event.End.UTC()
+		if start.Before(startTime) {
+			start = startTime.UTC()
+		}
+		if end.After(endTime) {
+			end = endTime.UTC()
+		}
+		if !end.After(start) {
+			continue
+		}
+		busy = append(busy, &model.FreeBusyInterval{Start: start, End: end})
+	}
+
+	sort.Slice(busy, func(i, j int) bool {
+		return busy[i].Start.Before(busy[j].Start)
+	})
+
+	merged := []*model.FreeBusyInterval{}
+	for _, interval := range busy {
+		if len(merged) > 0 {
+			last := merged[len(merged)-1]
+			if !interval.Start.After(last.End) {
+				if interval.End.After(last.End) {
+					last.End = interval.End
+				}
+				continue
+			}
+		}
+		merged = append(merged, interval)
+	}
+
+	return merged
+}
+
+// blocksTime checks whether an event makes its owner busy. Cancelled events and events marked as
+// free do not.
+func blocksTime(event *model.CalendarEvent) bool {
+	return event.Status != model.CalendarEventStatusCancelled &&
+		event.Transparency != model.CalendarEventTransparencyTransparent
+}
+
+// renderFreeBusyICS serializes busy intervals into a VCALENDAR with a single published VFREEBUSY
+func renderFreeBusyICS(user *model.User, startTime, endTime time.Time, busy []*model.FreeBusyInterval) string {
+	cal := ics.NewCalendar()
+	cal.SetProductId("-//Timely//Timely Calendar//EN")
+	cal.SetMethod(ics.MethodPublish)
+
+	vfreebusy := cal.AddBusy(fmt.Sprintf("%d-freebusy@timely", user.ID))
+	vfreebusy.SetDtStampTime(time.Now())
+	vfreebusy.SetStartAt(startTime)
+	vfreebusy.SetEndAt(endTime)
+
+	for _, interval := range busy {
+		period := interval.Start.UTC().Format("20060102T150405Z") + "/" + interval.End.UTC().Format("20060102T150405Z")
+		vfreebusy.AddProperty(ics.ComponentPropertyFreebusy, period,
+			&ics.KeyValues{Key: string(ics.ParameterFbtype), Value: []string{string(ics.FreeBusyTimeTypeBusy)}})
+	}
+
+	return cal.Serialize(ics.WithNewLine("\r\n"))
+}
@@ -0,0 +1,230 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	ics "github.com/arran4/golang-ical"
+
+	"github.com/NathanWasTaken/timely/backend/internal/model"
+)
+
+func TestGetUserFreeBusyMergesSharedCalendars(t *testing.T) {
+	calendarService, _ := newTestCalendarService(t)
+
+	const userID = 21
+	user := &model.User{ID: userID, Username: "kim"}
+
+	day := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+	at := func(hour, minute int) string {
+		return day.Add(time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute).Format(time.RFC3339)
+	}
+
+	createCalendar := func(summary string, freeBusy bool) string {
+		t.Helper()
+		calendar, err := calendarService.CreateCalendar(userID, &model.CalendarCreateRequest{Summary: summary})
+		if err != nil {
+			t.Fatalf("Failed to create calendar: %v", err)
+		}
+		calendarID := fmt.Sprintf("%d", calendar.ID)
+		if _, err := calendarService.UpdateCalendar(userID, calendarID, &model.CalendarUpdateRequest{FreeBusy: &freeBusy}); err != nil {
+			t.Fatalf("Failed to update calendar: %v", err)
+		}
+		return calendarID
+	}
+	createEvent := func(calendarID, title, start, end string) {
+		t.Helper()
+		private := model.CalendarEventVisibilityPrivate
+		if _, err := calendarService.CreateEvent(userID, calendarID, &model.CalendarEventRequest{
+			Title: title, Start: start, End: end, Visibility: &private,
+		}); err != nil {
+			t.Fatalf("Failed to create event: %v", err)
+		}
+	}
+
+	work := createCalendar("Work", true)
+	personal := createCalendar("Personal", true)
+	hidden := createCalendar("Hidden", false)
+
+	createEvent(work, "Early", at(7, 30), at(9, 30))    // Starts before the range
+	createEvent(work, "Standup", at(10, 0), at(10, 30)) // Touches the next event
+	createEvent(personal, "Dentist", at(10, 30), at(12, 0))
+	createEvent(personal, "Lunch", at(11, 0), at(11, 30)) // Inside the previous event
+	createEvent(hidden, "Secret", at(14, 0), at(15, 0))   // Calendar does not share free/busy
+
+	busy, err := calendarService.GetUserFreeBusy(userID, day.Add(8*time.Hour), day.Add(18*time.Hour))
+	if err != nil {
+		t.Fatalf("Failed to get free/busy: %v", err)
+	}
+
+	want := []string{"08:00-09:30", "10:00-12:00"}
+	var got []string
+	for _, interval := range busy {
+		got = append(got, interval.Start.Format("15:04")+"-"+interval.End.Format("15:04"))
+	}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("Expected busy intervals %v, got %v", want, got)
+	}
+
+	if _, err := calendarService.GetUserFreeBusy(userID, day, day.AddDate(0, 7, 0)); err == nil || err.Error() != "time range cannot exceed 6 months" {
+		t.Errorf("Expected the time range to be limited, got %v", err)
+	}
+
+	// The iCalendar response contains the same intervals and no event details
+	freeBusy, err := calendarService.GetUserFreeBusyICS(user, day.Add(8*time.Hour), day.Add(18*time.Hour))
+	if err != nil {
+		t.Fatalf("Failed to render free/busy: %v", err)
+	}
+	if strings.Contains(freeBusy, "Dentist") {
+		t.Errorf("Expected no event titles in the free/busy response, got:\n%s", freeBusy)
+	}
+
+	cal, err := ics.ParseCalendar(strings.NewReader(freeBusy))
+	if err != nil {
+		t.Fatalf("Failed to parse free/busy response: %v", err)
+	}
+	var periods []string
+	for _, component := range cal.Components {
+		if vfreebusy, ok := component.(*ics.VBusy); ok {
+			for _, prop := range vfreebusy.GetProperties(ics.ComponentPropertyFreebusy) {
+				periods = append(periods, prop.Value)
+			}
+		}
+	}
+	wantPeriods := []string{"20240701T080000Z/20240701T093000Z", "20240701T100000Z/20240701T120000Z"}
+	if strings.Join(periods, ",") != strings.Join(wantPeriods, ",") {
+		t.Errorf("Expected FREEBUSY periods %v, got %v", wantPeriods, periods)
+	}
+}
+
+func TestCancelledAndTransparentEventsDoNotBlockTime(t *testing.T) {
+	calendarService, _ := newTestCalendarService(t)
+
+	withProperties := func(event, properties string) string {
+		return strings.Replace(event, "END:VEVENT", properties+"END:VEVENT", 1)
+	}
+	feed := buildICSFeed(
+		buildICSEvent("standup", "Standup", "20240701T090000Z"),
+		withProperties(buildICSEvent("offsite", "Offsite", "20240701T110000Z"), "STATUS:CANCELLED\r\n"),
+		withProperties(buildICSEvent("focus", "Focus", "20240701T130000Z"), "TRANSP:TRANSPARENT\r\n"),
+		withProperties(buildICSEvent("review", "Review", "20240701T150000Z"), "STATUS:TENTATIVE\r\n"),
+	)
+	icsCalendar, err := ics.ParseCalendar(strings.NewReader(feed))
+	if err != nil {
+		t.Fatalf("Failed to parse ICS data: %v", err)
+	}
+
+	const userID = 22
+	if err := calendarService.userRepo.Create(&model.User{ID: userID, Username: "alex"}); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	calendar, _, err := calendarService.ImportICSCalendar(userID, "Team", icsCalendar, icsCalendar.Events())
+	if err != nil {
+		t.Fatalf("ImportICSCalendar failed: %v", err)
+	}
+
+	// Every event is kept with its status and transparency
+	events := ownerEvents(t, calendarService, userID, calendar.ID)
+	expected := map[string][2]string{
+		"standup": {"confirmed", "opaque"},
+		"offsite": {"cancelled", "opaque"},
+		"focus":   {"confirmed", "transparent"},
+		"review":  {"tentative", "opaque"},
+	}
+	for id, want := range expected {
+		event := events[id]
+		if event == nil || string(event.Status) != want[0] || string(event.Transparency) != want[1] {
+			t.Errorf("Expected %s to be %v, got %+v", id, want, event)
+		}
+	}
+
+	freeBusy, busyMode, public := true, model.CalendarRedactionModeBusy, model.CalendarVisibilityPublic
+	if _, err := calendarService.UpdateCalendar(userID, fmt.Sprint(calendar.ID), &model.CalendarUpdateRequest{
+		FreeBusy: &freeBusy, RedactionMode: &busyMode, Visibility: &public,
+	}); err != nil {
+		t.Fatalf("Failed to update calendar: %v", err)
+	}
+
+	day := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+	busy, err := calendarService.GetUserFreeBusy(userID, day, day.AddDate(0, 0, 1))
+	if err != nil {
+		t.Fatalf("Failed to get free/busy: %v", err)
+	}
+	var got []string
+	for _, interval := range busy {
+		got = append(got, interval.Start.Format("15:04")+"-"+interval.End.Format("15:04"))
+	}
+	if want := "09:00-10:00,15:00-16:00"; strings.Join(got, ",") != want {
+		t.Errorf("Expected busy intervals %s, got %v", want, got)
+	}
+
+	// Busy blocks are only shown for the events that block time
+	calendars, err := calendarService.GetPublicUserCalendarEvents(userID, day, day.AddDate(0, 0, 1))
+	if err != nil {
+		t.Fatalf("Failed to get public events: %v", err)
+	}
+	if len(calendars) != 1 || len(calendars[0].Events) != 2 {
+		t.Fatalf("Expected 2 busy blocks, got %+v", calendars)
+	}
+	// Tentative events block time, but busy blocks don't tell they are tentative
+	if block := calendars[0].Events[1]; block.Status != "" || block.Title != "" {
+		t.Errorf("Expected a busy block without status, got %+v", block)
+	}
+}
+
+func TestEventRequestStatusAndTransparency(t *testing.T) {
+	calendarService, _ := newTestCalendarService(t)
+
+	const userID = 23
+	calendar, err := calendarService.CreateCalendar(userID, &model.CalendarCreateRequest{Summary: "Personal"})
+	if err != nil {
+		t.Fatalf("Failed to create calendar: %v", err)
+	}
+	calendarID := fmt.Sprint(calendar.ID)
+
+	// Tomorrow, so the event is inside the window of the feed
+	start := time.Now().UTC().Truncate(time.Hour).Add(24 * time.Hour)
+	at := func(hours int) string {
+		return start.Add(time.Duration(hours) * time.Hour).Format(time.RFC3339)
+	}
+
+	event, err := calendarService.CreateEvent(userID, calendarID, &model.CalendarEventRequest{
+		Title: "Planning", Start: at(0), End: at(1),
+	})
+	if err != nil {
+		t.Fatalf("CreateEvent failed: %v", err)
+	}
+	if event.Status != model.CalendarEventStatusConfirmed || event.Transparency != model.CalendarEventTransparencyOpaque {
+		t.Errorf("Expected a confirmed, opaque event by default, got %s and %s", event.Status, event.Transparency)
+	}
+
+	cancelled, transparent := model.CalendarEventStatusCancelled, model.CalendarEventTransparencyTransparent
+	event, err = calendarService.UpdateEvent(userID, calendarID, fmt.Sprint(event.ID), &model.CalendarEventRequest{
+		Title: "Planning", Start: at(0), End: at(1),
+		Status: &cancelled, Transparency: &transparent,
+	})
+	if err != nil {
+		t.Fatalf("UpdateEvent failed: %v", err)
+	}
+	if event.Status != cancelled || event.Transparency != transparent {
+		t.Errorf("Expected a cancelled, transparent event, got %s and %s", event.Status, event.Transparency)
+	}
+
+	unknown := model.CalendarEventStatus("postponed")
+	if _, err := calendarService.CreateEvent(userID, calendarID, &model.CalendarEventRequest{
+		Title: "Retro", Start: at(2), End: at(3), Status: &unknown,
+	}); err == nil || err.Error() != `invalid event: unknown status "postponed"` {
+		t.Errorf("Expected an unknown status to be rejected, got %v", err)
+	}
+
+	// The feed of the owner carries the status and transparency of the event
+	feed, err := calendarService.GetUserCalendarICS(&model.User{ID: userID, Username: "sam"}, nil)
+	if err != nil {
+		t.Fatalf("Failed to render feed: %v", err)
+	}
+	if !strings.Contains(feed, "STATUS:CANCELLED") || !strings.Contains(feed, "TRANSP:TRANSPARENT") {
+		t.Errorf("Expected the status and transparency in the feed, got:\n%s", feed)
+	}
+}
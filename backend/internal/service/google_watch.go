@@ -0,0 +1,232 @@
+package service
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/NathanWasTaken/timely/backend/internal/model"
+	"github.com/NathanWasTaken/timely/backend/pkg/utils"
+)
+
+const (
+	// watchChannelTTL is the lifetime requested for push notification channels, Google caps it at a week
+	watchChannelTTL = 7 * 24 * time.Hour
+	// watchRenewalWindow is how long before expiry a push notification channel is replaced
+	watchRenewalWindow = 24 * time.Hour
+	// watchRenewalCheckInterval is how often the renewer looks for expiring channels
+	watchRenewalCheckInterval = time.Hour
+	// watchedSyncInterval is the shortest background sync interval of calendars with a push
+	// notification channel, which only needs to catch notifications that got lost
+	watchedSyncInterval = 6 * time.Hour
+)
+
+// Resource states sent by Google in the X-Goog-Resource-State header
+const (
+	googleResourceStateSync = "sync" // Sent once when a channel is opened
+)
+
+// GoogleNotification represents the headers of a Google Calendar push notification
+type GoogleNotification struct {
+	ChannelID     string
+	ChannelToken  string
+	ResourceID    string
+	ResourceState string
+}
+
+// watchEnabled reports whether push notifications can be received
+func (s *CalendarService) watchEnabled() bool {
+	return s.google.oauthConfig != nil && s.google.oauthConfig.GoogleWebhookURL != ""
+}
+
+// HandleGoogleNotification validates a push notification and schedules the calendar it is about
+// for a sync by the background scheduler, so Google gets its response right away
+func (s *CalendarService) HandleGoogleNotification(notification *GoogleNotification) error {
+	if notification.ChannelID == "" {
+		return fmt.Errorf("watch channel not found")
+	}
+
+	calendar, err := s.calendarRepo.FindByWatchChannelID(notification.ChannelID)
+	if err != nil {
+		return fmt.Errorf("watch channel not found")
+	}
+
+	if calendar.WatchTokenHash == nil ||
+		subtle.ConstantTimeCompare([]byte(utils.HashToken(notification.ChannelToken)), []byte(*calendar.WatchTokenHash)) != 1 {
+		return fmt.Errorf("invalid watch channel token")
+	}
+	if calendar.WatchResourceID != nil && notification.ResourceID != *calendar.WatchResourceID {
+		return fmt.Errorf("invalid watch channel token")
+	}
+
+	if notification.ResourceState == googleResourceStateSync {
+		s.logger.Debug("Watch channel opened", zap.Uint64("calendar_id", calendar.ID))
+		return nil
+	}
+
+	// A sync already running may have missed the change, the scheduler syncs the calendar again
+	// once it is done
+	if err := s.calendarRepo.ScheduleSync(calendar.ID, time.Now()); err != nil {
+		return fmt.Errorf("failed to schedule sync: %w", err)
+	}
+
+	return nil
+}
+
+// RunWatchChannelRenewer opens push notification channels for Google calendars and replaces them
+// before they expire, until ctx is cancelled
+func (s *CalendarService) RunWatchChannelRenewer(ctx context.Context) {
+	if !s.watchEnabled() {
+		s.logger.Info("GOOGLE_WEBHOOK_URL is not set, Google calendars are only synced by polling")
+		return
+	}
+
+	s.logger.Info("Starting watch channel renewer")
+
+	ticker := time.NewTicker(watchRenewalCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		s.renewExpiringWatchChannels()
+
+		select {
+		case <-ctx.Done():
+			s.logger.Info("Stopping watch channel renewer")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// renewExpiringWatchChannels replaces every channel expiring within watchRenewalWindow
+func (s *CalendarService) renewExpiringWatchChannels() {
+	calendars, err := s.calendarRepo.FindGoogleCalendarsWatchExpiringBefore(time.Now().Add(watchRenewalWindow))
+	if err != nil {
+		s.logger.Error("Failed to find expiring watch channels", zap.Error(err))
+		return
+	}
+
+	for _, calendar := range calendars {
+		if err := s.renewWatchChannel(calendar); err != nil {
+			s.logger.Warn("Failed to renew watch channel",
+				zap.Error(err),
+				zap.Uint64("calendar_id", calendar.ID))
+		}
+	}
+}
+
+// renewWatchChannel opens a new channel for a calendar and stops the one it replaces. The new
+// channel is opened first so no changes go unnoticed in between.
+func (s *CalendarService) renewWatchChannel(calendar *model.Calendar) error {
+	credentials, err := s.google.RefreshCredentials(context.Background(), calendar)
+	if err != nil {
+		return err
+	}
+
+	previous := *calendar
+	if err := s.startWatchChannel(credentials.AccessToken, calendar); err != nil {
+		return err
+	}
+
+	if err := s.stopGoogleWatchChannel(credentials.AccessToken, &previous); err != nil {
+		// The old channel runs out on its own, notifications it still sends are rejected
+		s.logger.Warn("Failed to stop replaced watch channel",
+			zap.Error(err),
+			zap.Uint64("calendar_id", calendar.ID))
+	}
+
+	return nil
+}
+
+// startWatchChannel opens a push notification channel for the events of a Google calendar and
+// stores it with the calendar
+func (s *CalendarService) startWatchChannel(accessToken string, calendar *model.Calendar) error {
+	token, err := utils.GenerateSecretToken()
+	if err != nil {
+		return err
+	}
+
+	request := &model.GoogleWatchChannel{
+		ID:      strconv.FormatUint(utils.GenerateID(), 10),
+		Type:    "web_hook",
+		Address: s.google.oauthConfig.GoogleWebhookURL,
+		Token:   token,
+		Params: map[string]string{
+			"ttl": strconv.FormatInt(int64(watchChannelTTL/time.Second), 10),
+		},
+	}
+
+	endpoint := fmt.Sprintf("%s/calendars/%s/events/watch", s.google.baseURL, url.PathEscape(*calendar.SourceID))
+	var channel model.GoogleWatchChannel
+	if err := s.google.post(context.Background(), accessToken, endpoint, request, &channel); err != nil {
+		return fmt.Errorf("failed to open watch channel: %w", err)
+	}
+
+	expiresAt := time.Now().Add(watchChannelTTL)
+	if channel.Expiration != "" {
+		milliseconds, err := strconv.ParseInt(channel.Expiration, 10, 64)
+		if err != nil {
+			return fmt.Errorf("failed to parse watch channel expiration: %w", err)
+		}
+		expiresAt = time.UnixMilli(milliseconds)
+	}
+
+	tokenHash := utils.HashToken(token)
+	if err := s.calendarRepo.UpdateWatchChannel(calendar.ID, &request.ID, &channel.ResourceID, &tokenHash, &expiresAt); err != nil {
+		return fmt.Errorf("failed to store watch channel: %w", err)
+	}
+
+	calendar.WatchChannelID = &request.ID
+	calendar.WatchResourceID = &channel.ResourceID
+	calendar.WatchTokenHash = &tokenHash
+	calendar.WatchExpiresAt = &expiresAt
+
+	s.logger.Info("Opened watch channel",
+		zap.Uint64("calendar_id", calendar.ID),
+		zap.Time("expires_at", expiresAt))
+
+	return nil
+}
+
+// stopWatchChannel stops the push notification channel of a calendar, if it has one, and clears it
+func (s *CalendarService) stopWatchChannel(accessToken string, calendar *model.Calendar) error {
+	if calendar.WatchChannelID == nil {
+		return nil
+	}
+
+	if err := s.stopGoogleWatchChannel(accessToken, calendar); err != nil {
+		return err
+	}
+
+	calendar.WatchChannelID = nil
+	calendar.WatchResourceID = nil
+	calendar.WatchTokenHash = nil
+	calendar.WatchExpiresAt = nil
+
+	return s.calendarRepo.UpdateWatchChannel(calendar.ID, nil, nil, nil, nil)
+}
+
+// stopGoogleWatchChannel asks Google to stop sending notifications for a channel. Channels Google
+// no longer knows about count as stopped.
+func (s *CalendarService) stopGoogleWatchChannel(accessToken string, calendar *model.Calendar) error {
+	if calendar.WatchChannelID == nil || calendar.WatchResourceID == nil {
+		return nil
+	}
+
+	request := &model.GoogleWatchChannel{
+		ID:         *calendar.WatchChannelID,
+		ResourceID: *calendar.WatchResourceID,
+	}
+
+	err := s.google.post(context.Background(), accessToken, s.google.baseURL+"/channels/stop", request, nil)
+	if err != nil && !isGoogleNotFoundError(err) {
+		return fmt.Errorf("failed to stop watch channel: %w", err)
+	}
+
+	return nil
+}
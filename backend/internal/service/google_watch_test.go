@@ -0,0 +1,118 @@
+package service
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/NathanWasTaken/timely/backend/internal/model"
+)
+
+// dueCalendar returns a calendar that must be due for a background sync
+func dueCalendar(t *testing.T, calendarService *CalendarService, calendarID uint64) *model.Calendar {
+	t.Helper()
+
+	calendars, err := calendarService.calendarRepo.FindCalendarsDueForSync(calendarService.scheduledSources(), time.Now(), 10)
+	if err != nil {
+		t.Fatalf("FindCalendarsDueForSync failed: %v", err)
+	}
+	for _, calendar := range calendars {
+		if calendar.ID == calendarID {
+			return calendar
+		}
+	}
+	t.Fatalf("Expected calendar %d to be due for a sync", calendarID)
+	return nil
+}
+
+func TestGoogleWatchChannelLifecycle(t *testing.T) {
+	calendarService, calendarRepo := newTestCalendarService(t)
+	google, server := newGoogleStandIn(t)
+
+	calendar := newGoogleTestCalendar(t, calendarService, server.URL)
+	calendarService.google.oauthConfig.GoogleWebhookURL = "https://timely.example.com/api/webhooks/google"
+
+	// Calendars without a channel get one
+	calendarService.renewExpiringWatchChannels()
+
+	stored, err := calendarRepo.FindByID(fmt.Sprint(calendar.ID))
+	if err != nil {
+		t.Fatalf("Failed to find calendar: %v", err)
+	}
+	if stored.WatchChannelID == nil || stored.WatchResourceID == nil || stored.WatchExpiresAt == nil {
+		t.Fatal("Expected watch channel to be stored")
+	}
+	channelID := *stored.WatchChannelID
+	token := google.token(channelID)
+	if token == "" || *stored.WatchTokenHash == token {
+		t.Fatal("Expected channel token to be stored hashed")
+	}
+
+	// Notifications must carry the channel token
+	err = calendarService.HandleGoogleNotification(&GoogleNotification{
+		ChannelID: channelID, ChannelToken: "guessed", ResourceID: *stored.WatchResourceID, ResourceState: "exists",
+	})
+	if err == nil || err.Error() != "invalid watch channel token" {
+		t.Errorf("Expected invalid token error, got %v", err)
+	}
+	err = calendarService.HandleGoogleNotification(&GoogleNotification{
+		ChannelID: "unknown", ChannelToken: token, ResourceID: *stored.WatchResourceID, ResourceState: "exists",
+	})
+	if err == nil || err.Error() != "watch channel not found" {
+		t.Errorf("Expected channel not found error, got %v", err)
+	}
+
+	// A valid change notification schedules the calendar for a sync
+	notify := func() {
+		t.Helper()
+		err := calendarService.HandleGoogleNotification(&GoogleNotification{
+			ChannelID: channelID, ChannelToken: token, ResourceID: *stored.WatchResourceID, ResourceState: "exists",
+		})
+		if err != nil {
+			t.Fatalf("HandleGoogleNotification failed: %v", err)
+		}
+	}
+	tomorrow := time.Now().Add(24 * time.Hour).UTC().Truncate(time.Hour)
+	google.add("standup", "Standup", tomorrow.Format(time.RFC3339), "confirmed")
+	notify()
+
+	// A change notified while the scheduler syncs the calendar is picked up by the next sync
+	calendarService.runScheduledSync(dueCalendar(t, calendarService, calendar.ID), DefaultSyncInterval, func(calendar *model.Calendar) error {
+		err := calendarService.syncScheduledCalendar(calendar)
+		google.add("review", "Review", tomorrow.Add(2*time.Hour).Format(time.RFC3339), "confirmed")
+		notify()
+		return err
+	})
+	if titles := eventTitles(t, calendarService, calendar.ID); len(titles) != 1 || titles["standup"] != "Standup" {
+		t.Fatalf("Expected the notified change to be synced, got %v", titles)
+	}
+	calendarService.runScheduledSync(dueCalendar(t, calendarService, calendar.ID), DefaultSyncInterval, calendarService.syncScheduledCalendar)
+	if titles := eventTitles(t, calendarService, calendar.ID); len(titles) != 2 || titles["review"] != "Review" {
+		t.Fatalf("Expected the change notified during the sync to be synced, got %v", titles)
+	}
+
+	// Renewing opens a new channel and stops the old one
+	if err := calendarService.renewWatchChannel(stored); err != nil {
+		t.Fatalf("renewWatchChannel failed: %v", err)
+	}
+	if *stored.WatchChannelID == channelID {
+		t.Error("Expected a new channel after renewal")
+	}
+	if stopped := google.stoppedChannels(); len(stopped) != 1 || stopped[0] != channelID {
+		t.Errorf("Expected old channel to be stopped, got %v", stopped)
+	}
+	err = calendarService.HandleGoogleNotification(&GoogleNotification{
+		ChannelID: channelID, ChannelToken: token, ResourceID: "resource-1", ResourceState: "exists",
+	})
+	if err == nil {
+		t.Error("Expected notifications of the replaced channel to be rejected")
+	}
+
+	// Deleting the calendar stops its channel
+	if err := calendarService.DeleteCalendar(calendar.UserID, fmt.Sprint(calendar.ID)); err != nil {
+		t.Fatalf("DeleteCalendar failed: %v", err)
+	}
+	if stopped := google.stoppedChannels(); len(stopped) != 2 || stopped[1] != *stored.WatchChannelID {
+		t.Errorf("Expected channel to be stopped on delete, got %v", stopped)
+	}
+}
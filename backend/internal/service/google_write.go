@@ -0,0 +1,519 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/NathanWasTaken/timely/backend/internal/config"
+	"github.com/NathanWasTaken/timely/backend/internal/model"
+	"github.com/NathanWasTaken/timely/backend/pkg/utils"
+)
+
+const (
+	// eventWriteRetryDelay is the delay before retrying a failed write, doubled with every further
+	// attempt up to eventWriteMaxRetryDelay
+	eventWriteRetryDelay    = time.Minute
+	eventWriteMaxRetryDelay = time.Hour
+	// eventWriteMaxAttempts bounds how often a write is attempted before it is given up
+	eventWriteMaxAttempts = 10
+	// googleCalendarScope is the Google scope granting full access to calendars
+	googleCalendarScope = "https://www.googleapis.com/auth/calendar"
+)
+
+// errWriteBackConsentRequired is returned when write-back is enabled for a Google account that has
+// not granted permission to edit events
+var errWriteBackConsentRequired = errors.New("invalid calendar: write-back needs permission to edit Google Calendar events, sign in with Google again to grant it")
+
+// checkWriteBackScope verifies that the user granted Timely permission to edit the events of their
+// Google calendars. Accounts that signed in before it was requested, or that declined it on the
+// consent screen, have to sign in with Google again before write-back can be enabled.
+func (s *CalendarService) checkWriteBackScope(userID uint64) error {
+	account, err := s.userRepo.FindGoogleAccountByUserID(userID)
+	if err != nil {
+		return fmt.Errorf("failed to find Google account: %w", err)
+	}
+
+	if account.Scopes != nil {
+		for _, scope := range strings.Fields(*account.Scopes) {
+			// The full calendar scope includes editing events
+			if scope == config.GoogleCalendarEventsScope || scope == googleCalendarScope {
+				return nil
+			}
+		}
+	}
+	return errWriteBackConsentRequired
+}
+
+// queueEventWrite queues a change made in Timely to an event of a write-back calendar and moves
+// the next sync of the calendar forward, which pushes it to Google. A change to an event that
+// already has a queued write is folded into it. base holds the fields of an updated event before
+// the change, changes made on Google in the meantime are merged against them.
+func (s *CalendarService) queueEventWrite(calendar *model.Calendar, event *model.CalendarEvent, operation model.EventWriteOperation, base *model.EventWriteFields) error {
+	write, err := s.calendarRepo.FindEventWrite(calendar.ID, event.SourceID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("failed to find event write: %w", err)
+	}
+
+	switch {
+	case write == nil:
+		write = &model.EventWrite{
+			ID:         utils.GenerateID(),
+			CalendarID: calendar.ID,
+			SourceID:   event.SourceID,
+			EventID:    event.ID,
+			Operation:  operation,
+			Base:       base,
+		}
+	case write.Operation == model.EventWriteCreate && operation == model.EventWriteDelete:
+		// The event never reached Google, so there is nothing left to push
+		if err := s.calendarRepo.DeleteEventWrite(write); err != nil {
+			return fmt.Errorf("failed to delete event write: %w", err)
+		}
+		return nil
+	case write.Operation == model.EventWriteCreate:
+		// Creating the event pushes its latest version
+	default:
+		// An update folded into a queued update keeps the base of the first, the version last synced
+		write.Operation = operation
+	}
+
+	if operation == model.EventWriteDelete {
+		write.ETag = event.SourceETag
+	}
+	write.Version++
+	write.Attempts = 0
+	write.NextAttemptAt = time.Now()
+	write.LastError = nil
+
+	if err := s.calendarRepo.SaveEventWrite(write); err != nil {
+		return fmt.Errorf("failed to queue event write: %w", err)
+	}
+
+	if err := s.calendarRepo.ScheduleSync(calendar.ID, write.NextAttemptAt); err != nil {
+		// The write is still pushed by the next regular sync
+		s.logger.Warn("Failed to schedule sync for event write", zap.Error(err), zap.Uint64("calendar_id", calendar.ID))
+	}
+
+	return nil
+}
+
+// pushEventWrites pushes the queued writes of a write-back calendar that are due to Google. Writes
+// rejected because the event changed on Google since it was last synced are returned without being
+// rescheduled, so they can be retried once the sync has fetched the current ETag of the event.
+func (s *CalendarService) pushEventWrites(ctx context.Context, accessToken string, calendar *model.Calendar) []*model.EventWrite {
+	writes, err := s.calendarRepo.FindDueEventWrites(calendar.ID, time.Now())
+	if err != nil {
+		s.logger.Error("Failed to find event writes", zap.Error(err), zap.Uint64("calendar_id", calendar.ID))
+		return nil
+	}
+
+	var conflicts []*model.EventWrite
+	for _, write := range writes {
+		err := s.pushEventWrite(ctx, accessToken, calendar, write, false)
+		if isGooglePreconditionFailedError(err) {
+			conflicts = append(conflicts, write)
+			continue
+		}
+		s.finishEventWrite(write, err)
+	}
+
+	return conflicts
+}
+
+// retryConflictingEventWrites pushes writes rejected by pushEventWrites again, now that the sync
+// merged them with the change made on Google and updated the ETags they are conditional on. Writes
+// that could not be merged wait for the conflict to be resolved, writes rejected again are requeued.
+func (s *CalendarService) retryConflictingEventWrites(ctx context.Context, accessToken string, calendar *model.Calendar, conflicts []*model.EventWrite) {
+	for _, conflict := range conflicts {
+		// The sync may have changed the write, or dropped it because the event was deleted on Google
+		write, err := s.calendarRepo.FindEventWrite(calendar.ID, conflict.SourceID)
+		if err != nil || write.Conflict {
+			continue
+		}
+
+		s.finishEventWrite(write, s.pushEventWrite(ctx, accessToken, calendar, write, false))
+	}
+}
+
+// pushEventWrite pushes a queued write to Google. Events that no longer exist on one side or the
+// other are considered pushed, the sync reconciles them. Forced writes are pushed unconditionally,
+// overwriting whatever changed on Google.
+func (s *CalendarService) pushEventWrite(ctx context.Context, accessToken string, calendar *model.Calendar, write *model.EventWrite, force bool) error {
+	if write.Operation == model.EventWriteDelete {
+		etag := write.ETag
+		if force {
+			etag = ""
+		}
+		err := s.google.deleteEvent(ctx, accessToken, calendar, write.SourceID, etag)
+		if isGoogleNotFoundError(err) || isGoogleGoneError(err) {
+			return nil
+		}
+		return err
+	}
+
+	event, err := s.calendarRepo.FindEventByID(strconv.FormatUint(write.EventID, 10))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to find event: %w", err)
+	}
+
+	if force {
+		event.SourceETag = ""
+	}
+
+	var etag string
+	if write.Operation == model.EventWriteCreate {
+		etag, err = s.google.insertEvent(ctx, accessToken, calendar, event)
+		if isGoogleConflictError(err) {
+			// An earlier attempt created the event but its response got lost
+			etag, err = s.google.patchEvent(ctx, accessToken, calendar, event)
+		}
+	} else {
+		etag, err = s.google.patchEvent(ctx, accessToken, calendar, event)
+	}
+	if isGoogleNotFoundError(err) || isGoogleGoneError(err) {
+		s.logger.Warn("Event was deleted on Google before its change was pushed",
+			zap.Uint64("calendar_id", calendar.ID),
+			zap.Uint64("event_id", event.ID))
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	// Storing the new ETag keeps the sync from taking the pushed change for a change made on Google
+	if err := s.calendarRepo.UpdateEventSourceETag(event.ID, etag); err != nil {
+		return fmt.Errorf("failed to update event ETag: %w", err)
+	}
+	return nil
+}
+
+// finishEventWrite removes a pushed write from the queue, or requeues it after a failed push with
+// an increasing delay until it is given up. A change folded into the write while it was pushed
+// keeps it queued as it is, so the change is pushed next.
+func (s *CalendarService) finishEventWrite(write *model.EventWrite, pushErr error) {
+	if pushErr == nil {
+		if err := s.calendarRepo.DeleteEventWrite(write); err != nil {
+			s.logger.Error("Failed to delete event write", zap.Error(err), zap.Uint64("event_write_id", write.ID))
+		}
+		return
+	}
+
+	write.Attempts++
+	if write.Attempts >= eventWriteMaxAttempts {
+		s.logger.Error("Giving up on pushing event change to Google",
+			zap.Error(pushErr),
+			zap.Uint64("calendar_id", write.CalendarID),
+			zap.String("source_id", write.SourceID),
+			zap.String("operation", string(write.Operation)))
+		if err := s.calendarRepo.DeleteEventWrite(write); err != nil {
+			s.logger.Error("Failed to delete event write", zap.Error(err), zap.Uint64("event_write_id", write.ID))
+		}
+		return
+	}
+
+	lastError := pushErr.Error()
+	if isGooglePreconditionFailedError(pushErr) {
+		lastError = "event changed on Google since it was last synced"
+	}
+	write.LastError = &lastError
+	write.NextAttemptAt = time.Now().Add(eventWriteBackoff(write.Attempts))
+
+	s.logger.Warn("Failed to push event change to Google, requeued",
+		zap.Error(pushErr),
+		zap.Uint64("calendar_id", write.CalendarID),
+		zap.String("source_id", write.SourceID),
+		zap.Int("attempts", write.Attempts),
+		zap.Time("next_attempt_at", write.NextAttemptAt))
+
+	if _, err := s.calendarRepo.UpdateEventWrite(write); err != nil {
+		s.logger.Error("Failed to requeue event write", zap.Error(err), zap.Uint64("event_write_id", write.ID))
+	}
+}
+
+// ResolveEventWriteConflict resolves the conflict between a change made in Timely to an event of a
+// write-back calendar and a change made on Google, keeping either version. Keeping Timely's version
+// pushes it over the change made on Google, keeping Google's drops the change made in Timely.
+// Returns the event as kept, nil if it is deleted.
+func (s *CalendarService) ResolveEventWriteConflict(userID uint64, calendarID, eventID string, resolveRequest *model.EventConflictResolveRequest) (*model.CalendarEvent, error) {
+	calendar, err := s.findEditableCalendar(userID, calendarID)
+	if err != nil {
+		return nil, err
+	}
+
+	keep := resolveRequest.Keep
+	if keep != model.EventConflictKeepTimely && keep != model.EventConflictKeepGoogle {
+		return nil, fmt.Errorf("invalid event: unknown conflict resolution %q", keep)
+	}
+
+	// The write is looked up by event ID, since events deleted in Timely are already gone
+	id, err := strconv.ParseUint(eventID, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("event not found")
+	}
+	write, err := s.calendarRepo.FindEventWriteByEventID(calendar.ID, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && !write.Conflict) {
+		return nil, fmt.Errorf("invalid event: event has no conflicting change")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find event write: %w", err)
+	}
+
+	// Holding the sync lease keeps a sync from merging the event meanwhile
+	lease, err := s.claimSyncLease(calendar.ID, s.calendarRepo.ClaimSyncLease)
+	if err != nil {
+		return nil, err
+	}
+	// The background schedule stays as it is
+	defer s.releaseSyncLease(lease, nil)
+
+	ctx := context.Background()
+	credentials, err := s.google.RefreshCredentials(ctx, calendar)
+	if err != nil {
+		return nil, err
+	}
+
+	if keep == model.EventConflictKeepTimely {
+		if err := s.pushEventWrite(ctx, credentials.AccessToken, calendar, write, true); err != nil {
+			return nil, fmt.Errorf("failed to push event change to Google: %w", err)
+		}
+		s.finishEventWrite(write, nil)
+	} else if err := s.discardEventWrite(ctx, credentials.AccessToken, calendar, write); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Resolved event write conflict",
+		zap.Uint64("user_id", userID),
+		zap.Uint64("calendar_id", calendar.ID),
+		zap.Uint64("event_id", id),
+		zap.String("keep", string(keep)))
+
+	event, err := s.calendarRepo.FindEventByCalendarIDAndSourceID(calendar.ID, write.SourceID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find event: %w", err)
+	}
+	return event, nil
+}
+
+// discardEventWrite drops a queued write and brings its event back to the version on Google
+func (s *CalendarService) discardEventWrite(ctx context.Context, accessToken string, calendar *model.Calendar, write *model.EventWrite) error {
+	fetched := &ProviderEvents{}
+	googleEvent, err := s.google.getEvent(ctx, accessToken, calendar, write.SourceID)
+	switch {
+	case isGoogleNotFoundError(err) || isGoogleGoneError(err):
+		fetched.Deleted = []string{write.SourceID}
+	case err != nil:
+		return fmt.Errorf("failed to fetch event from Google: %w", err)
+	default:
+		eventColors := s.google.eventColors(ctx, accessToken, calendar.UserID)
+		fetched.Events, fetched.Deleted = s.google.convertGoogleEvents([]*model.GoogleCalendarEvent{googleEvent}, calendar.ID, eventColors)
+	}
+
+	if err := s.calendarRepo.DeleteEventWrite(write); err != nil {
+		return fmt.Errorf("failed to delete event write: %w", err)
+	}
+	if _, err := s.mergeEvents(calendar, fetched); err != nil {
+		return fmt.Errorf("failed to merge event: %w", err)
+	}
+	return nil
+}
+
+// markWriteConflicts flags the events shown to their owner whose change conflicts with a change
+// made on Google
+func (s *CalendarService) markWriteConflicts(events []*model.CalendarEvent) {
+	var calendarIDs []uint64
+	seen := make(map[uint64]bool)
+	for _, event := range events {
+		if !seen[event.CalendarID] {
+			seen[event.CalendarID] = true
+			calendarIDs = append(calendarIDs, event.CalendarID)
+		}
+	}
+	if len(calendarIDs) == 0 {
+		return
+	}
+
+	writes, err := s.calendarRepo.FindConflictingEventWrites(calendarIDs)
+	if err != nil {
+		// The events are still shown, just without their conflicts
+		s.logger.Warn("Failed to find conflicting event writes", zap.Error(err), zap.Int("event_count", len(events)))
+		return
+	}
+	conflicting := make(map[uint64]bool, len(writes))
+	for _, write := range writes {
+		conflicting[write.EventID] = true
+	}
+	for _, event := range events {
+		event.WriteConflict = conflicting[event.ID]
+	}
+}
+
+// refreshEventWrite reconciles a queued write with the current version of its event on Google. A
+// change made on Google since the event was last synced is merged with the change made in Timely
+// field by field, the merged event is returned to be stored and then pushed against the new ETag.
+// Changes that cannot be merged mark the write as conflicting, neither side is overwritten until
+// the owner resolves the conflict. Returns nil if there is nothing to store.
+func (s *CalendarService) refreshEventWrite(write *model.EventWrite, existing, incoming *model.CalendarEvent) (*model.CalendarEvent, error) {
+	switch {
+	case write.Operation == model.EventWriteDelete:
+		if incoming.SourceETag == write.ETag {
+			return nil, nil
+		}
+		// Changed on Google after it was deleted in Timely
+		return nil, s.markEventWriteConflict(write)
+	case existing == nil:
+		return nil, nil
+	case write.Operation == model.EventWriteCreate:
+		// An earlier attempt created the event but its response got lost, so Google has the
+		// version Timely created
+		write.Operation = model.EventWriteUpdate
+		write.Base = eventWriteFields(incoming)
+		if updated, err := s.calendarRepo.UpdateEventWrite(write); err != nil || !updated {
+			return nil, err
+		}
+		return nil, s.calendarRepo.UpdateEventSourceETag(existing.ID, incoming.SourceETag)
+	case incoming.SourceETag == existing.SourceETag:
+		return nil, nil
+	}
+
+	// Writes queued without a base can't tell which side changed a field
+	if write.Base == nil {
+		return nil, s.markEventWriteConflict(write)
+	}
+	merged, ok := mergeEventWrite(write.Base, existing, incoming)
+	if !ok {
+		return nil, s.markEventWriteConflict(write)
+	}
+
+	// The version on Google is the base of the merged change from now on
+	write.Base = eventWriteFields(incoming)
+	write.Conflict = false
+	write.LastError = nil
+	if updated, err := s.calendarRepo.UpdateEventWrite(write); err != nil || !updated {
+		// Changed in Timely meanwhile, the newer change is reconciled when it is pushed
+		return nil, err
+	}
+
+	merged.ID = existing.ID
+	merged.CreatedAt = existing.CreatedAt
+	keepEventSharingSettings(existing, merged)
+	return merged, nil
+}
+
+// markEventWriteConflict keeps a write queued without pushing it, until the owner resolves its
+// conflict with the change made on Google
+func (s *CalendarService) markEventWriteConflict(write *model.EventWrite) error {
+	lastError := "event changed on Google in a way that conflicts with the change made in Timely"
+	write.Conflict = true
+	write.LastError = &lastError
+
+	s.logger.Warn("Event change conflicts with a change made on Google",
+		zap.Uint64("calendar_id", write.CalendarID),
+		zap.String("source_id", write.SourceID),
+		zap.String("operation", string(write.Operation)))
+
+	_, err := s.calendarRepo.UpdateEventWrite(write)
+	return err
+}
+
+// eventWriteFields returns the fields of an event Timely writes to Google
+func eventWriteFields(event *model.CalendarEvent) *model.EventWriteFields {
+	return &model.EventWriteFields{
+		Title:        event.Title,
+		Description:  event.Description,
+		Location:     event.Location,
+		Start:        event.Start.UTC(),
+		End:          event.End.UTC(),
+		AllDay:       event.AllDay,
+		Status:       event.Status,
+		Transparency: event.Transparency,
+	}
+}
+
+// eventWriteTime is the time of an event as a whole, moving an event changes its start and end
+// together so they are merged as one field
+type eventWriteTime struct {
+	start, end int64
+	allDay     bool
+}
+
+func newEventWriteTime(fields *model.EventWriteFields) eventWriteTime {
+	return eventWriteTime{start: fields.Start.Unix(), end: fields.End.Unix(), allDay: fields.AllDay}
+}
+
+// mergeEventWrite merges local, the event with the change made in Timely, and remote, the event as
+// changed on Google, against base. Fields only one side changed take its value, the fields Timely
+// does not write and the ETag come from remote. Returns false if both changed a field differently.
+func mergeEventWrite(base *model.EventWriteFields, local, remote *model.CalendarEvent) (*model.CalendarEvent, bool) {
+	localFields, remoteFields := eventWriteFields(local), eventWriteFields(remote)
+	ok := true
+
+	merged := *remote
+	merged.Title = mergeEventField(base.Title, localFields.Title, remoteFields.Title, &ok)
+	merged.Description = mergeEventField(base.Description, localFields.Description, remoteFields.Description, &ok)
+	merged.Location = mergeEventField(base.Location, localFields.Location, remoteFields.Location, &ok)
+	merged.Status = mergeEventField(base.Status, localFields.Status, remoteFields.Status, &ok)
+	merged.Transparency = mergeEventField(base.Transparency, localFields.Transparency, remoteFields.Transparency, &ok)
+
+	localTime := newEventWriteTime(localFields)
+	if mergeEventField(newEventWriteTime(base), localTime, newEventWriteTime(remoteFields), &ok) == localTime {
+		merged.Start, merged.End, merged.AllDay = local.Start, local.End, local.AllDay
+	}
+
+	return &merged, ok
+}
+
+// mergeEventField merges a field against its base value. If both sides changed it differently ok
+// is cleared and the local value returned.
+func mergeEventField[T comparable](base, local, remote T, ok *bool) T {
+	switch {
+	case local == remote, local == base:
+		return remote
+	case remote == base:
+		return local
+	}
+	*ok = false
+	return local
+}
+
+// nextEventWriteAttempt returns when the next queued write of a calendar is due, nil if there is none.
+// Conflicting writes are not due until they are resolved.
+func (s *CalendarService) nextEventWriteAttempt(calendarID uint64) *time.Time {
+	writes, err := s.calendarRepo.FindEventWritesByCalendarID(calendarID)
+	if err != nil {
+		s.logger.Error("Failed to find event writes", zap.Error(err), zap.Uint64("calendar_id", calendarID))
+		return nil
+	}
+
+	var next *time.Time
+	for _, write := range writes {
+		if write.Conflict {
+			continue
+		}
+		if next == nil || write.NextAttemptAt.Before(*next) {
+			next = &write.NextAttemptAt
+		}
+	}
+	return next
+}
+
+// eventWriteBackoff returns the delay before the next attempt of a write that failed attempts times
+func eventWriteBackoff(attempts int) time.Duration {
+	delay := eventWriteRetryDelay
+	for i := 1; i < attempts && delay < eventWriteMaxRetryDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, eventWriteMaxRetryDelay)
+}
@@ -0,0 +1,306 @@
+package service
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2"
+
+	"github.com/NathanWasTaken/timely/backend/internal/config"
+	"github.com/NathanWasTaken/timely/backend/internal/model"
+)
+
+// newGoogleWriteTestCalendar creates a Google calendar with write-back enabled, served by the stand-in at serverURL
+func newGoogleWriteTestCalendar(t *testing.T, calendarService *CalendarService, serverURL string) *model.Calendar {
+	t.Helper()
+
+	calendar := newGoogleTestCalendar(t, calendarService, serverURL)
+	userID := calendar.UserID
+
+	// Write-back is opt-in
+	_, err := calendarService.CreateEvent(userID, fmt.Sprint(calendar.ID), &model.CalendarEventRequest{
+		Title: "Nope",
+		Start: "2024-07-01T10:00:00Z",
+		End:   "2024-07-01T11:00:00Z",
+	})
+	if err == nil || err.Error() != "calendar write-back is not enabled" {
+		t.Errorf("Expected editing a read-only Google calendar to fail, got %v", err)
+	}
+
+	// Write-back needs permission to edit events, which accounts signed in before it was requested lack
+	writeBack := true
+	_, err = calendarService.UpdateCalendar(userID, fmt.Sprint(calendar.ID), &model.CalendarUpdateRequest{WriteBack: &writeBack})
+	if err != errWriteBackConsentRequired {
+		t.Errorf("Expected enabling write-back without permission to fail, got %v", err)
+	}
+	token := (&oauth2.Token{AccessToken: "access", RefreshToken: "refresh", Expiry: time.Now().Add(time.Hour)}).
+		WithExtra(map[string]interface{}{"scope": "openid " + config.GoogleCalendarEventsScope})
+	if err := NewUserService(calendarService.userRepo).UpdateGoogleAccountTokens(userID, token); err != nil {
+		t.Fatalf("Failed to store Google token: %v", err)
+	}
+
+	calendar, err = calendarService.UpdateCalendar(userID, fmt.Sprint(calendar.ID), &model.CalendarUpdateRequest{WriteBack: &writeBack})
+	if err != nil || !calendar.WriteBack {
+		t.Fatalf("Failed to enable write-back: %v", err)
+	}
+	return calendar
+}
+
+func TestGoogleWriteBack(t *testing.T) {
+	calendarService, calendarRepo := newTestCalendarService(t)
+	google, server := newGoogleStandIn(t)
+	calendar := newGoogleWriteTestCalendar(t, calendarService, server.URL)
+	calendarID := fmt.Sprint(calendar.ID)
+
+	google.edit("standup", "Standup")
+	syncGoogleTestCalendar(t, calendarService, calendar)
+	if titles := eventTitles(t, calendarService, calendar.ID); titles["standup"] != "Standup" {
+		t.Fatalf("Expected the Google event to be synced, got %v", titles)
+	}
+	stored, _ := calendarRepo.FindEventsByCalendarID(calendar.ID)
+	eventID := fmt.Sprint(stored[0].ID)
+
+	// Updates are pushed conditionally and not synced back as a remote change
+	request := &model.CalendarEventRequest{Title: "Daily standup", Start: "2024-07-01T09:00:00Z", End: "2024-07-01T09:30:00Z"}
+	if _, err := calendarService.UpdateEvent(calendar.UserID, calendarID, eventID, request); err != nil {
+		t.Fatalf("UpdateEvent failed: %v", err)
+	}
+	result := syncGoogleTestCalendar(t, calendarService, calendar)
+	if got := google.event("standup").Summary; got != "Daily standup" {
+		t.Errorf("Expected the update to be pushed, got %q", got)
+	}
+	if ifMatch := google.takeIfMatch(); len(ifMatch) != 1 || ifMatch[0] != stored[0].SourceETag {
+		t.Errorf("Expected the update to be conditional on the synced ETag %s, got %v", stored[0].SourceETag, ifMatch)
+	}
+	if result.Updated != 0 {
+		t.Errorf("Expected the pushed change not to come back as a remote change, got %+v", result)
+	}
+
+	// A change made on Google to another field in between is merged, the update is retried on top of it
+	google.relocate("standup", "Room 4")
+	request.Title = "Team standup"
+	if _, err := calendarService.UpdateEvent(calendar.UserID, calendarID, eventID, request); err != nil {
+		t.Fatalf("UpdateEvent failed: %v", err)
+	}
+	syncGoogleTestCalendar(t, calendarService, calendar)
+	if got := google.event("standup"); got.Summary != "Team standup" || got.Location != "Room 4" {
+		t.Errorf("Expected the update to be merged with the change made on Google, got %q in %q", got.Summary, got.Location)
+	}
+	if ifMatch := google.takeIfMatch(); len(ifMatch) != 2 || ifMatch[0] == ifMatch[1] {
+		t.Errorf("Expected the retry to use the refreshed ETag, got %v", ifMatch)
+	}
+	if event, _ := calendarRepo.FindEventByID(eventID); event.Title != "Team standup" || event.Location != "Room 4" {
+		t.Errorf("Expected both changes to be kept locally, got %q in %q", event.Title, event.Location)
+	}
+
+	// Changes to the same field conflict, neither side is overwritten until the conflict is resolved
+	google.edit("standup", "Standup (moved)")
+	request.Title = "Standup with everyone"
+	if _, err := calendarService.UpdateEvent(calendar.UserID, calendarID, eventID, request); err != nil {
+		t.Fatalf("UpdateEvent failed: %v", err)
+	}
+	syncGoogleTestCalendar(t, calendarService, calendar)
+	syncGoogleTestCalendar(t, calendarService, calendar)
+	if got := google.event("standup").Summary; got != "Standup (moved)" {
+		t.Errorf("Expected the change made on Google to be kept, got %q", got)
+	}
+	if ifMatch := google.takeIfMatch(); len(ifMatch) != 1 {
+		t.Errorf("Expected the conflicting update not to be pushed again, got %v", ifMatch)
+	}
+	writes, _ := calendarRepo.FindEventWritesByCalendarID(calendar.ID)
+	if len(writes) != 1 || !writes[0].Conflict || writes[0].LastError == nil {
+		t.Fatalf("Expected the update to be kept as a conflict, got %+v", writes)
+	}
+	if events := ownerEvents(t, calendarService, calendar.UserID, calendar.ID); events["standup"].Title != "Standup with everyone" || !events["standup"].WriteConflict {
+		t.Errorf("Expected the owner to see the conflicting change, got %+v", events["standup"])
+	}
+	if health, err := calendarService.calendarHealth(calendar.ID); err != nil || health.WriteConflicts != 1 {
+		t.Errorf("Expected the conflict to be counted in the calendar health, got %+v, %v", health, err)
+	}
+
+	// Keeping Timely's version pushes it over the change made on Google
+	if _, err := calendarService.ResolveEventWriteConflict(calendar.UserID, calendarID, eventID, &model.EventConflictResolveRequest{Keep: "mine"}); err == nil || err.Error() != `invalid event: unknown conflict resolution "mine"` {
+		t.Errorf("Expected an unknown resolution to be rejected, got %v", err)
+	}
+	resolved, err := calendarService.ResolveEventWriteConflict(calendar.UserID, calendarID, eventID, &model.EventConflictResolveRequest{Keep: model.EventConflictKeepTimely})
+	if err != nil || resolved.Title != "Standup with everyone" {
+		t.Fatalf("ResolveEventWriteConflict failed: %+v, %v", resolved, err)
+	}
+	if got := google.event("standup").Summary; got != "Standup with everyone" {
+		t.Errorf("Expected Timely's version to be pushed, got %q", got)
+	}
+	if ifMatch := google.takeIfMatch(); len(ifMatch) != 1 || ifMatch[0] != "" {
+		t.Errorf("Expected the resolved update to be pushed unconditionally, got %v", ifMatch)
+	}
+	if writes, _ := calendarRepo.FindEventWritesByCalendarID(calendar.ID); len(writes) != 0 {
+		t.Errorf("Expected no write to be left, got %+v", writes)
+	}
+	if _, err := calendarService.ResolveEventWriteConflict(calendar.UserID, calendarID, eventID, &model.EventConflictResolveRequest{Keep: model.EventConflictKeepTimely}); err == nil || err.Error() != "invalid event: event has no conflicting change" {
+		t.Errorf("Expected resolving without a conflict to fail, got %v", err)
+	}
+	syncGoogleTestCalendar(t, calendarService, calendar)
+
+	// Deleting an event changed on Google conflicts too, keeping Google's version brings it back
+	google.edit("standup", "Standup (cancelled?)")
+	if err := calendarService.DeleteEvent(calendar.UserID, calendarID, eventID); err != nil {
+		t.Fatalf("DeleteEvent failed: %v", err)
+	}
+	syncGoogleTestCalendar(t, calendarService, calendar)
+	if got := google.event("standup").Status; got == "cancelled" {
+		t.Error("Expected the event changed on Google not to be deleted")
+	}
+	google.takeIfMatch()
+	resolved, err = calendarService.ResolveEventWriteConflict(calendar.UserID, calendarID, eventID, &model.EventConflictResolveRequest{Keep: model.EventConflictKeepGoogle})
+	if err != nil || resolved == nil || resolved.Title != "Standup (cancelled?)" {
+		t.Fatalf("ResolveEventWriteConflict failed: %+v, %v", resolved, err)
+	}
+	if titles := eventTitles(t, calendarService, calendar.ID); titles["standup"] != "Standup (cancelled?)" {
+		t.Errorf("Expected Google's version to be restored, got %v", titles)
+	}
+	syncGoogleTestCalendar(t, calendarService, calendar)
+	if ifMatch := google.takeIfMatch(); len(ifMatch) != 0 {
+		t.Errorf("Expected the dropped delete not to be pushed, got %v", ifMatch)
+	}
+	eventID = fmt.Sprint(resolved.ID)
+
+	// Conflicts that persist are requeued
+	google.mu.Lock()
+	google.conflict = true
+	google.mu.Unlock()
+	request.Title = "Standup with everyone"
+	if _, err := calendarService.UpdateEvent(calendar.UserID, calendarID, eventID, request); err != nil {
+		t.Fatalf("UpdateEvent failed: %v", err)
+	}
+	syncGoogleTestCalendar(t, calendarService, calendar)
+	writes, _ = calendarRepo.FindEventWritesByCalendarID(calendar.ID)
+	if len(writes) != 1 || writes[0].Attempts != 1 || writes[0].LastError == nil || !writes[0].NextAttemptAt.After(time.Now()) {
+		t.Fatalf("Expected the conflicting update to be requeued, got %+v", writes)
+	}
+	if titles := eventTitles(t, calendarService, calendar.ID); titles["standup"] != "Standup with everyone" {
+		t.Errorf("Expected the queued change not to be overwritten by sync, got %v", titles)
+	}
+	google.mu.Lock()
+	google.conflict = false
+	google.mu.Unlock()
+	if err := calendarRepo.DeleteEventWrite(writes[0]); err != nil {
+		t.Fatalf("Failed to delete event write: %v", err)
+	}
+
+	// Created events keep their source ID on Google
+	created, err := calendarService.CreateEvent(calendar.UserID, calendarID, &model.CalendarEventRequest{
+		Title: "Retro",
+		Start: "2024-07-05T15:00:00Z",
+		End:   "2024-07-05T16:00:00Z",
+	})
+	if err != nil {
+		t.Fatalf("CreateEvent failed: %v", err)
+	}
+	result = syncGoogleTestCalendar(t, calendarService, calendar)
+	if got := google.event(created.SourceID).Summary; got != "Retro" {
+		t.Errorf("Expected the event to be created on Google, got %q", got)
+	}
+	if result.Added != 0 {
+		t.Errorf("Expected the created event not to be synced as a new event, got %+v", result)
+	}
+
+	// Deletes are conditional as well
+	if err := calendarService.DeleteEvent(calendar.UserID, calendarID, fmt.Sprint(created.ID)); err != nil {
+		t.Fatalf("DeleteEvent failed: %v", err)
+	}
+	google.takeIfMatch()
+	syncGoogleTestCalendar(t, calendarService, calendar)
+	if got := google.event(created.SourceID).Status; got != "cancelled" {
+		t.Errorf("Expected the event to be deleted on Google, got status %q", got)
+	}
+	if ifMatch := google.takeIfMatch(); len(ifMatch) != 1 || ifMatch[0] == "" {
+		t.Errorf("Expected the delete to be conditional, got %v", ifMatch)
+	}
+	if titles := eventTitles(t, calendarService, calendar.ID); len(titles) != 1 {
+		t.Errorf("Expected only the first event to be left, got %v", titles)
+	}
+	if writes, _ := calendarRepo.FindEventWritesByCalendarID(calendar.ID); len(writes) != 0 {
+		t.Errorf("Expected every write to be pushed, got %+v", writes)
+	}
+}
+
+func TestEventWriteDuringPush(t *testing.T) {
+	calendarService, calendarRepo := newTestCalendarService(t)
+	google, server := newGoogleStandIn(t)
+	calendar := newGoogleWriteTestCalendar(t, calendarService, server.URL)
+	calendarID := fmt.Sprint(calendar.ID)
+
+	google.edit("standup", "Standup")
+	syncGoogleTestCalendar(t, calendarService, calendar)
+	stored, _ := calendarRepo.FindEventsByCalendarID(calendar.ID)
+	eventID := fmt.Sprint(stored[0].ID)
+
+	request := &model.CalendarEventRequest{Title: "Daily standup", Start: "2024-07-01T09:00:00Z", End: "2024-07-01T09:30:00Z"}
+	if _, err := calendarService.UpdateEvent(calendar.UserID, calendarID, eventID, request); err != nil {
+		t.Fatalf("UpdateEvent failed: %v", err)
+	}
+
+	// The event is changed again while the first change is pushed
+	google.mu.Lock()
+	google.onUpdate = func() {
+		request := &model.CalendarEventRequest{Title: "Team standup", Start: "2024-07-01T09:00:00Z", End: "2024-07-01T09:30:00Z"}
+		if _, err := calendarService.UpdateEvent(calendar.UserID, calendarID, eventID, request); err != nil {
+			t.Errorf("UpdateEvent failed: %v", err)
+		}
+	}
+	google.mu.Unlock()
+	syncGoogleTestCalendar(t, calendarService, calendar)
+	if got := google.event("standup").Summary; got != "Daily standup" {
+		t.Errorf("Expected the first change to be pushed, got %q", got)
+	}
+	if writes, _ := calendarRepo.FindEventWritesByCalendarID(calendar.ID); len(writes) != 1 || writes[0].LastError != nil {
+		t.Fatalf("Expected the second change to stay queued, got %+v", writes)
+	}
+
+	syncGoogleTestCalendar(t, calendarService, calendar)
+	if got := google.event("standup").Summary; got != "Team standup" {
+		t.Errorf("Expected the second change to be pushed, got %q", got)
+	}
+	if writes, _ := calendarRepo.FindEventWritesByCalendarID(calendar.ID); len(writes) != 0 {
+		t.Errorf("Expected no write to be left, got %+v", writes)
+	}
+	if titles := eventTitles(t, calendarService, calendar.ID); titles["standup"] != "Team standup" {
+		t.Errorf("Expected the second change to be kept locally, got %v", titles)
+	}
+}
+
+func TestEventWriteFolding(t *testing.T) {
+	calendarService, calendarRepo := newTestCalendarService(t)
+	_, server := newGoogleStandIn(t)
+	calendar := newGoogleWriteTestCalendar(t, calendarService, server.URL)
+	calendarID := fmt.Sprint(calendar.ID)
+
+	event, err := calendarService.CreateEvent(calendar.UserID, calendarID, &model.CalendarEventRequest{
+		Title: "Draft",
+		Start: "2024-07-05T15:00:00Z",
+		End:   "2024-07-05T16:00:00Z",
+	})
+	if err != nil {
+		t.Fatalf("CreateEvent failed: %v", err)
+	}
+	if _, err := calendarService.UpdateEvent(calendar.UserID, calendarID, fmt.Sprint(event.ID), &model.CalendarEventRequest{
+		Title: "Final",
+		Start: "2024-07-05T15:00:00Z",
+		End:   "2024-07-05T16:00:00Z",
+	}); err != nil {
+		t.Fatalf("UpdateEvent failed: %v", err)
+	}
+
+	writes, _ := calendarRepo.FindEventWritesByCalendarID(calendar.ID)
+	if len(writes) != 1 || writes[0].Operation != model.EventWriteCreate {
+		t.Fatalf("Expected the update to be folded into the create, got %+v", writes)
+	}
+
+	// An event deleted before it was pushed is never pushed
+	if err := calendarService.DeleteEvent(calendar.UserID, calendarID, fmt.Sprint(event.ID)); err != nil {
+		t.Fatalf("DeleteEvent failed: %v", err)
+	}
+	if writes, _ := calendarRepo.FindEventWritesByCalendarID(calendar.ID); len(writes) != 0 {
+		t.Errorf("Expected no write to be left, got %+v", writes)
+	}
+}
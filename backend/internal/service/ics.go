@@ -0,0 +1,559 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	ics "github.com/arran4/golang-ical"
+	"go.uber.org/zap"
+
+	"github.com/NathanWasTaken/timely/backend/internal/model"
+	"github.com/NathanWasTaken/timely/backend/pkg/ical"
+	"github.com/NathanWasTaken/timely/backend/pkg/utils"
+)
+
+const (
+	// icsRecurrenceHorizon bounds the expansion of open-ended recurrence rules
+	icsRecurrenceHorizon = 2 * 365 * 24 * time.Hour
+	// icsRecurrenceLookback bounds how far back occurrences of recurring events are stored, like
+	// the history synced from Google
+	icsRecurrenceLookback = 365 * 24 * time.Hour
+	// maxICSOccurrences caps the number of occurrences stored for a single recurring event. It is
+	// filled with the occurrences from the lookback on first, earlier ones only take the room left.
+	maxICSOccurrences = 10000
+)
+
+// newICSCalendar builds a private ICS calendar for a user, defaulting to UTC if timeZone is empty
+func (s *CalendarService) newICSCalendar(userID uint64, calendarName, timeZone string) *model.Calendar {
+	if timeZone == "" {
+		timeZone = defaultICSTimeZone
+	}
+
+	now := time.Now()
+	return &model.Calendar{
+		ID:           utils.GenerateID(),
+		UserID:       userID,
+		Source:       model.SourceICS,
+		Summary:      calendarName,
+		TimeZone:     timeZone,
+		Visibility:   model.CalendarVisibilityPrivate,
+		SyncedAt:     now,
+		SyncStatus:   model.CalendarSyncStatusFullSyncComplete, // ICS imports are considered complete
+		SyncToken:    nil,                                      // ICS calendars don't use sync tokens
+		LastFullSync: &now,
+	}
+}
+
+// createICSCalendar saves a new ICS calendar together with its converted events and returns the
+// number of events stored
+func (s *CalendarService) createICSCalendar(calendar *model.Calendar, zones *ical.TimeZones, icsEvents []*ics.VEvent) (int, error) {
+	// Save calendar to database
+	if err := s.calendarRepo.Create(calendar); err != nil {
+		return 0, fmt.Errorf("failed to create calendar: %w", err)
+	}
+
+	// Convert and import events, expanding recurring events into their occurrences
+	calendarEvents := uniqueSourceEvents(s.ics.convertICSEvents(icsEvents, calendar.ID, zones))
+	s.markSelfAttendees(calendar, calendarEvents)
+
+	// Batch create events
+	if len(calendarEvents) > 0 {
+		if err := s.calendarRepo.CreateEvents(calendarEvents); err != nil {
+			s.logger.Error("Failed to create events", zap.Error(err))
+			return 0, fmt.Errorf("failed to create events: %w", err)
+		}
+	}
+
+	return len(calendarEvents), nil
+}
+
+// uniqueSourceEvents drops events repeating the source ID of an earlier one, like mergeEvents does,
+// since a calendar stores each source ID once
+func uniqueSourceEvents(events []*model.CalendarEvent) []*model.CalendarEvent {
+	seen := make(map[string]bool, len(events))
+	unique := events[:0]
+	for _, event := range events {
+		if seen[event.SourceID] {
+			continue
+		}
+		seen[event.SourceID] = true
+		unique = append(unique, event)
+	}
+	return unique
+}
+
+// ReimportICSCalendar merges ICS events into an existing ICS calendar, upserting events by UID and
+// RECURRENCE-ID and removing events missing from the file, while keeping the calendar's settings.
+// The calendar is renamed only if calendarName is not empty, and floating times are interpreted in
+// the calendar's time zone.
+func (s *CalendarService) ReimportICSCalendar(userID uint64, calendarID, calendarName string, icsCalendar *ics.Calendar, icsEvents []*ics.VEvent) (*model.Calendar, int, *SyncResult, error) {
+	s.logger.Info("Re-importing ICS calendar",
+		zap.Uint64("user_id", userID),
+		zap.String("calendar_id", calendarID),
+		zap.Int("events_count", len(icsEvents)))
+
+	// Find the calendar and verify ownership
+	calendar, err := s.calendarRepo.FindByID(calendarID)
+	if err != nil {
+		return nil, 0, nil, fmt.Errorf("failed to find calendar: %w", err)
+	}
+
+	if calendar.UserID != userID {
+		return nil, 0, nil, fmt.Errorf("calendar not found or access denied")
+	}
+
+	if calendar.Source != model.SourceICS {
+		return nil, 0, nil, fmt.Errorf("calendar is not an ICS calendar")
+	}
+
+	// Subscribed calendars would be overwritten by the next refresh
+	if calendar.SubscriptionURL != nil {
+		return nil, 0, nil, fmt.Errorf("calendar is subscribed to a remote feed")
+	}
+
+	// Event source IDs combine the UID with the original start of each occurrence, so merging by
+	// source ID upserts by UID and RECURRENCE-ID
+	calendarEvents := s.ics.convertICSEvents(icsEvents, calendar.ID, s.ics.timeZones(icsCalendar, calendar.TimeZone))
+	result, err := s.mergeEvents(calendar, &ProviderEvents{Events: calendarEvents, Complete: true})
+	if err != nil {
+		return nil, 0, nil, err
+	}
+
+	now := time.Now()
+	calendar.SyncedAt = now
+	calendar.LastFullSync = &now
+	if calendarName != "" {
+		calendar.Summary = calendarName
+	}
+
+	if err := s.calendarRepo.Update(calendar); err != nil {
+		return nil, 0, nil, fmt.Errorf("failed to update calendar: %w", err)
+	}
+
+	eventsCount, err := s.calendarRepo.CountEventsByCalendarID(calendar.ID)
+	if err != nil {
+		return nil, 0, nil, fmt.Errorf("failed to count events: %w", err)
+	}
+
+	s.logger.Info("Successfully re-imported ICS calendar",
+		zap.Uint64("user_id", userID),
+		zap.Uint64("calendar_id", calendar.ID),
+		zap.Int("added_events", result.Added),
+		zap.Int("updated_events", result.Updated),
+		zap.Int("removed_events", result.Removed))
+
+	return calendar, int(eventsCount), result, nil
+}
+
+// icsCalendarColor returns the color of an ICS calendar from its RFC 7986 COLOR property or the
+// X-APPLE-CALENDAR-COLOR property set by Apple Calendar, nil if it has none
+func icsCalendarColor(cal *ics.Calendar) *string {
+	if cal == nil {
+		return nil
+	}
+
+	for _, token := range []string{string(ics.PropertyColor), "X-APPLE-CALENDAR-COLOR"} {
+		for _, prop := range cal.CalendarProperties {
+			if prop.IANAToken == token {
+				if color := optionalColor(prop.Value); color != nil {
+					return color
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// ExtractICSCalendarName extracts the calendar name from ICS properties, falling back to "Untitled Calendar"
+func ExtractICSCalendarName(cal *ics.Calendar) string {
+	// Try to get X-WR-CALNAME property (common non-standard property for calendar name)
+	for _, prop := range cal.CalendarProperties {
+		if prop.IANAToken == "X-WR-CALNAME" && prop.Value != "" {
+			return prop.Value
+		}
+	}
+
+	// Try to get other calendar-level properties
+	for _, prop := range cal.CalendarProperties {
+		// Try NAME property
+		if prop.IANAToken == "NAME" && prop.Value != "" {
+			return prop.Value
+		}
+		// Try SUMMARY property
+		if prop.IANAToken == "SUMMARY" && prop.Value != "" {
+			return prop.Value
+		}
+	}
+
+	// Try to extract from PRODID as last resort (clean it up)
+	for _, prop := range cal.CalendarProperties {
+		if prop.IANAToken == "PRODID" && prop.Value != "" {
+			prodId := strings.TrimSpace(prop.Value)
+			// Clean up common PRODID patterns
+			if !strings.HasPrefix(prodId, "-//") && !strings.Contains(prodId, "//") {
+				return prodId
+			}
+		}
+	}
+
+	// Default fallback
+	return "Untitled Calendar"
+}
+
+// convertICSEvents converts ICS events to CalendarEvents, expanding recurring events into one
+// event per occurrence and applying RECURRENCE-ID overrides to the matching occurrences
+func (p *icsProvider) convertICSEvents(icsEvents []*ics.VEvent, calendarID uint64, zones *ical.TimeZones) []*model.CalendarEvent {
+	var masters []*ics.VEvent
+	overrides := make(map[string][]*ics.VEvent)
+
+	for _, icsEvent := range icsEvents {
+		if icsEvent.GetProperty(ics.ComponentPropertyRecurrenceId) != nil {
+			overrides[icsEvent.Id()] = append(overrides[icsEvent.Id()], icsEvent)
+			continue
+		}
+		masters = append(masters, icsEvent)
+	}
+
+	var events []*model.CalendarEvent
+	for _, master := range masters {
+		occurrences, err := p.expandICSEvent(master, calendarID, zones)
+		if err != nil {
+			p.logger.Error("Failed to convert ICS event",
+				zap.Error(err),
+				zap.String("event_id", master.Id()))
+			continue
+		}
+
+		events = append(events, p.applyICSOverrides(occurrences, overrides[master.Id()], calendarID, zones)...)
+		delete(overrides, master.Id())
+	}
+
+	// Overrides without a master (e.g. a single forwarded occurrence) are imported on their own
+	for uid, orphans := range overrides {
+		events = append(events, p.applyICSOverrides(nil, orphans, calendarID, zones)...)
+		p.logger.Debug("Imported ICS overrides without a recurring master",
+			zap.String("event_id", uid),
+			zap.Int("override_count", len(orphans)))
+	}
+
+	return events
+}
+
+// expandICSEvent converts an ICS event and, if it has RRULE or RDATE properties, expands it into
+// its occurrences minus the EXDATE instances
+func (p *icsProvider) expandICSEvent(icsEvent *ics.VEvent, calendarID uint64, zones *ical.TimeZones) ([]*model.CalendarEvent, error) {
+	event, err := p.convertICSEventToCalendarEvent(icsEvent, calendarID, zones)
+	if err != nil {
+		return nil, err
+	}
+
+	rrules := icsEvent.GetProperties(ics.ComponentPropertyRrule)
+	rdates := icsEvent.GetProperties(ics.ComponentPropertyRdate)
+	if len(rrules) == 0 && len(rdates) == 0 {
+		return []*model.CalendarEvent{event}, nil
+	}
+
+	var rules []*ical.RecurrenceRule
+	for _, rrule := range rrules {
+		rule, err := ical.ParseRecurrenceRule(rrule.Value, event.Start.Location())
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse recurrence rule: %w", err)
+		}
+		rules = append(rules, rule)
+	}
+
+	var rdateTimes []time.Time
+	for _, rdate := range rdates {
+		rdateTimes = append(rdateTimes, p.parseICSDateList(rdate, event.Start, zones)...)
+	}
+
+	var exdateTimes []time.Time
+	for _, exdate := range icsEvent.GetProperties(ics.ComponentPropertyExdate) {
+		exdateTimes = append(exdateTimes, p.parseICSDateList(exdate, event.Start, zones)...)
+	}
+
+	now := time.Now()
+	starts := ical.Expand(event.Start, rules, rdateTimes, exdateTimes, now.Add(-icsRecurrenceLookback), now.Add(icsRecurrenceHorizon), maxICSOccurrences)
+
+	occurrences := make([]*model.CalendarEvent, 0, len(starts))
+	for _, start := range starts {
+		occurrences = append(occurrences, p.newICSOccurrence(event, start))
+	}
+
+	p.logger.Debug("Expanded recurring ICS event",
+		zap.String("event_id", event.SourceID),
+		zap.Int("occurrence_count", len(occurrences)))
+
+	return occurrences, nil
+}
+
+// applyICSOverrides replaces the occurrences whose start matches an override's RECURRENCE-ID
+// with the override; overrides that match no occurrence are added as extra occurrences
+func (p *icsProvider) applyICSOverrides(occurrences []*model.CalendarEvent, overrides []*ics.VEvent, calendarID uint64, zones *ical.TimeZones) []*model.CalendarEvent {
+	byOriginalStart := make(map[int64]int, len(occurrences))
+	for i, occurrence := range occurrences {
+		if occurrence.OriginalStartTime != nil {
+			byOriginalStart[occurrence.OriginalStartTime.Unix()] = i
+		}
+	}
+
+	for _, override := range overrides {
+		recurrenceID := override.GetProperty(ics.ComponentPropertyRecurrenceId)
+		originalStart, err := p.parseICSDateTime(recurrenceID.Value, recurrenceID.ICalParameters, zones)
+		if err != nil {
+			p.logger.Error("Failed to parse ICS recurrence ID",
+				zap.Error(err),
+				zap.String("event_id", override.Id()))
+			continue
+		}
+
+		event, err := p.convertICSEventToCalendarEvent(override, calendarID, zones)
+		if err != nil {
+			p.logger.Error("Failed to convert ICS override",
+				zap.Error(err),
+				zap.String("event_id", override.Id()))
+			continue
+		}
+
+		allDay := isICSDateValue(recurrenceID.ICalParameters)
+		event.SourceID = icsOccurrenceSourceID(override.Id(), originalStart, allDay)
+		event.RecurringEventID = override.Id()
+		event.OriginalStartTime = &originalStart
+
+		if i, exists := byOriginalStart[originalStart.Unix()]; exists {
+			occurrences[i] = event
+		} else {
+			occurrences = append(occurrences, event)
+		}
+	}
+
+	return occurrences
+}
+
+// newICSOccurrence creates the occurrence of a recurring event starting at start
+func (p *icsProvider) newICSOccurrence(master *model.CalendarEvent, start time.Time) *model.CalendarEvent {
+	occurrence := *master
+	occurrence.ID = utils.GenerateID()
+	occurrence.SourceID = icsOccurrenceSourceID(master.SourceID, start, master.AllDay)
+	occurrence.RecurringEventID = master.SourceID
+	occurrence.Start = start
+	occurrence.Attendees = copyEventAttendees(master.Attendees)
+
+	if master.AllDay {
+		// Keep all-day occurrences aligned to whole days
+		days := int(master.End.Sub(master.Start).Hours() / 24)
+		occurrence.End = start.AddDate(0, 0, days)
+	} else {
+		occurrence.End = start.Add(master.End.Sub(master.Start))
+	}
+
+	originalStart := start
+	occurrence.OriginalStartTime = &originalStart
+
+	return &occurrence
+}
+
+// convertICSAttendees converts the ATTENDEE and ORGANIZER properties of an ICS event. An organizer
+// that is also listed as an attendee is marked as the organizer of that attendee.
+func convertICSAttendees(icsEvent *ics.VEvent) []*model.EventAttendee {
+	var attendees []*model.EventAttendee
+	byEmail := make(map[string]*model.EventAttendee)
+	for _, property := range icsEvent.GetProperties(ics.ComponentPropertyAttendee) {
+		email := icsCalendarAddress(property.Value)
+		if email == "" || byEmail[strings.ToLower(email)] != nil {
+			continue
+		}
+
+		attendee := &model.EventAttendee{
+			ID:          utils.GenerateID(),
+			Email:       email,
+			DisplayName: icsParameter(property.ICalParameters, ics.ParameterCn),
+			Role:        icsAttendeeRole(icsParameter(property.ICalParameters, ics.ParameterRole)),
+			Status:      icsAttendeeStatus(icsParameter(property.ICalParameters, ics.ParameterParticipationStatus)),
+		}
+		byEmail[strings.ToLower(email)] = attendee
+		attendees = append(attendees, attendee)
+	}
+
+	property := icsEvent.GetProperty(ics.ComponentPropertyOrganizer)
+	if property == nil {
+		return attendees
+	}
+	email := icsCalendarAddress(property.Value)
+	if email == "" {
+		return attendees
+	}
+	if attendee, ok := byEmail[strings.ToLower(email)]; ok {
+		attendee.Organizer = true
+		return attendees
+	}
+
+	return append([]*model.EventAttendee{{
+		ID:          utils.GenerateID(),
+		Email:       email,
+		DisplayName: icsParameter(property.ICalParameters, ics.ParameterCn),
+		Role:        model.EventAttendeeRoleChair,
+		Status:      model.EventAttendeeStatusAccepted,
+		Organizer:   true,
+	}}, attendees...)
+}
+
+// icsAttendeeRole converts the ROLE parameter of an ICS attendee, REQ-PARTICIPANT being the default
+func icsAttendeeRole(role string) model.EventAttendeeRole {
+	switch strings.ToUpper(role) {
+	case "CHAIR":
+		return model.EventAttendeeRoleChair
+	case "OPT-PARTICIPANT":
+		return model.EventAttendeeRoleOptional
+	case "NON-PARTICIPANT":
+		return model.EventAttendeeRoleNonParticipant
+	default:
+		return model.EventAttendeeRoleRequired
+	}
+}
+
+// icsAttendeeStatus converts the PARTSTAT parameter of an ICS attendee, NEEDS-ACTION being the default
+func icsAttendeeStatus(partstat string) model.EventAttendeeStatus {
+	switch strings.ToUpper(partstat) {
+	case "ACCEPTED":
+		return model.EventAttendeeStatusAccepted
+	case "DECLINED":
+		return model.EventAttendeeStatusDeclined
+	case "TENTATIVE":
+		return model.EventAttendeeStatusTentative
+	case "DELEGATED":
+		return model.EventAttendeeStatusDelegated
+	default:
+		return model.EventAttendeeStatusNeedsAction
+	}
+}
+
+// icsEventStatus converts the STATUS property of an ICS event, events without one are confirmed
+func icsEventStatus(icsEvent *ics.VEvent) model.CalendarEventStatus {
+	status := icsEvent.GetProperty(ics.ComponentPropertyStatus)
+	if status == nil {
+		return model.CalendarEventStatusConfirmed
+	}
+	switch strings.ToUpper(strings.TrimSpace(status.Value)) {
+	case string(ics.ObjectStatusTentative):
+		return model.CalendarEventStatusTentative
+	case string(ics.ObjectStatusCancelled):
+		return model.CalendarEventStatusCancelled
+	default:
+		return model.CalendarEventStatusConfirmed
+	}
+}
+
+// icsObjectStatus converts the status of an event to the STATUS of an ICS event, empty for events
+// without a status
+func icsObjectStatus(status model.CalendarEventStatus) ics.ObjectStatus {
+	switch status {
+	case model.CalendarEventStatusConfirmed:
+		return ics.ObjectStatusConfirmed
+	case model.CalendarEventStatusTentative:
+		return ics.ObjectStatusTentative
+	case model.CalendarEventStatusCancelled:
+		return ics.ObjectStatusCancelled
+	default:
+		return ""
+	}
+}
+
+// icsEventTransparency converts the TRANSP property of an ICS event, OPAQUE being the default
+func icsEventTransparency(icsEvent *ics.VEvent) model.CalendarEventTransparency {
+	transp := icsEvent.GetProperty(ics.ComponentPropertyTransp)
+	if transp != nil && strings.EqualFold(strings.TrimSpace(transp.Value), string(ics.TransparencyTransparent)) {
+		return model.CalendarEventTransparencyTransparent
+	}
+	return model.CalendarEventTransparencyOpaque
+}
+
+// icsCalendarAddress returns the email address of an ATTENDEE or ORGANIZER value, a mailto: URI
+func icsCalendarAddress(value string) string {
+	value = strings.TrimSpace(value)
+	if len(value) >= len("mailto:") && strings.EqualFold(value[:len("mailto:")], "mailto:") {
+		value = value[len("mailto:"):]
+	}
+	return value
+}
+
+// icsParameter returns the first value of a property parameter, empty if it is missing
+func icsParameter(params map[string][]string, parameter ics.Parameter) string {
+	if values := params[string(parameter)]; len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// parseICSEndTime determines the end of an ICS event from DTEND or DURATION, defaulting to one day
+// for all-day events and to the start time otherwise (RFC 5545 section 3.6.1)
+func (p *icsProvider) parseICSEndTime(icsEvent *ics.VEvent, startTime time.Time, allDay bool, zones *ical.TimeZones) (time.Time, error) {
+	if dtEnd := icsEvent.GetProperty(ics.ComponentPropertyDtEnd); dtEnd != nil {
+		endTime, err := p.parseICSDateTime(dtEnd.Value, dtEnd.ICalParameters, zones)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("failed to parse end time: %w", err)
+		}
+		return endTime, nil
+	}
+
+	if durationProp := icsEvent.GetProperty(ics.ComponentPropertyDuration); durationProp != nil {
+		duration, err := ical.ParseDuration(durationProp.Value)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("failed to parse duration: %w", err)
+		}
+		return duration.AddTo(startTime), nil
+	}
+
+	if allDay {
+		return startTime.AddDate(0, 0, 1), nil
+	}
+	return startTime, nil
+}
+
+// parseICSDateList parses a comma separated RDATE or EXDATE property. DATE values are moved to the
+// time of day of reference so they match timed occurrences, and PERIOD values use their start.
+func (p *icsProvider) parseICSDateList(prop *ics.IANAProperty, reference time.Time, zones *ical.TimeZones) []time.Time {
+	isDate := isICSDateValue(prop.ICalParameters)
+
+	var times []time.Time
+	for _, value := range strings.Split(prop.Value, ",") {
+		value, _, _ = strings.Cut(strings.TrimSpace(value), "/")
+		if value == "" {
+			continue
+		}
+
+		parsed, err := p.parseICSDateTime(value, prop.ICalParameters, zones)
+		if err != nil {
+			p.logger.Warn("Skipping invalid ICS date",
+				zap.String("property", prop.IANAToken),
+				zap.String("value", value),
+				zap.Error(err))
+			continue
+		}
+
+		if isDate {
+			parsed = time.Date(parsed.Year(), parsed.Month(), parsed.Day(),
+				reference.Hour(), reference.Minute(), reference.Second(), 0, reference.Location())
+		}
+
+		times = append(times, parsed)
+	}
+
+	return times
+}
+
+// isICSDateValue checks whether a property carries a DATE rather than a DATE-TIME value
+func isICSDateValue(params map[string][]string) bool {
+	valueParams, exists := params["VALUE"]
+	return exists && len(valueParams) > 0 && valueParams[0] == "DATE"
+}
+
+// icsOccurrenceSourceID builds the source ID of an occurrence from its UID and original start,
+// following the "<id>_<start>" convention Google uses for recurring event instances
+func icsOccurrenceSourceID(uid string, originalStart time.Time, allDay bool) string {
+	if allDay {
+		return uid + "_" + originalStart.Format("20060102")
+	}
+	return uid + "_" + originalStart.UTC().Format("20060102T150405Z")
+}
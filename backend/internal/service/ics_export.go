@@ -0,0 +1,155 @@
+package service
+
+import (
+	"fmt"
+	"time"
+
+	ics "github.com/arran4/golang-ical"
+
+	"github.com/NathanWasTaken/timely/backend/internal/model"
+)
+
+const (
+	// icsFeedPastDays and icsFeedFutureDays define the rolling window of events published in ICS
+	// feeds; together they stay inside the 6 month limit of event queries
+	icsFeedPastDays   = 30
+	icsFeedFutureDays = 150
+	// ICSFeedRefreshInterval is how often subscribed clients are asked to re-fetch a published feed
+	ICSFeedRefreshInterval = 15 * time.Minute
+)
+
+// GetPublicUserCalendarICS renders a user's public events inside the rolling feed window as an ICS
+// feed, applying the same visibility and redaction rules as GetPublicUserCalendarEvents
+func (s *CalendarService) GetPublicUserCalendarICS(user *model.User) (string, error) {
+	startTime, endTime := icsFeedWindow(time.Now())
+
+	calendarsWithEvents, err := s.GetPublicUserCalendarEvents(user.ID, startTime, endTime)
+	if err != nil {
+		return "", err
+	}
+
+	return renderICSFeed(icsFeedName(user), calendarsWithEvents), nil
+}
+
+// GetUserCalendarICS renders every calendar a user owns, including private events and without
+// redaction, as an ICS feed for the owner. If calendarIDs is not empty only those calendars are included.
+func (s *CalendarService) GetUserCalendarICS(user *model.User, calendarIDs []uint64) (string, error) {
+	calendars, err := s.calendarRepo.FindByUserID(user.ID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get user calendars: %w", err)
+	}
+
+	if len(calendarIDs) > 0 {
+		selected := make(map[uint64]bool, len(calendarIDs))
+		for _, id := range calendarIDs {
+			selected[id] = true
+		}
+
+		var filtered []*model.Calendar
+		for _, calendar := range calendars {
+			if selected[calendar.ID] {
+				filtered = append(filtered, calendar)
+			}
+		}
+		if len(filtered) != len(selected) {
+			return "", fmt.Errorf("calendar not found or access denied")
+		}
+		calendars = filtered
+	}
+
+	var calendarsWithEvents []*model.CalendarWithEvents
+	if len(calendars) > 0 {
+		var ids []uint64
+		for _, calendar := range calendars {
+			ids = append(ids, calendar.ID)
+		}
+
+		startTime, endTime := icsFeedWindow(time.Now())
+		events, err := s.calendarRepo.FindEventsByCalendarIDsAndTimeRange(ids, startTime, endTime)
+		if err != nil {
+			return "", fmt.Errorf("failed to get calendar events: %w", err)
+		}
+		events = withoutHiddenDeclined(calendars, events)
+
+		eventsByCalendar := make(map[uint64][]*model.CalendarEvent)
+		for _, event := range events {
+			eventsByCalendar[event.CalendarID] = append(eventsByCalendar[event.CalendarID], event)
+		}
+
+		for _, calendar := range calendars {
+			calendarsWithEvents = append(calendarsWithEvents, &model.CalendarWithEvents{
+				Calendar: calendar,
+				Events:   eventsByCalendar[calendar.ID],
+			})
+		}
+	}
+
+	return renderICSFeed(icsFeedName(user), calendarsWithEvents), nil
+}
+
+// icsFeedWindow returns the rolling time window of events published in ICS feeds
+func icsFeedWindow(now time.Time) (time.Time, time.Time) {
+	return now.AddDate(0, 0, -icsFeedPastDays), now.AddDate(0, 0, icsFeedFutureDays)
+}
+
+// icsFeedName returns the calendar name used for a user's published feeds
+func icsFeedName(user *model.User) string {
+	if user.DisplayName != "" {
+		return user.DisplayName
+	}
+	return user.Username
+}
+
+// renderICSFeed serializes calendars and their events into a single VCALENDAR. Every event is
+// emitted as its own VEVENT with a UID derived from its Timely ID, so recurring occurrences stay
+// distinct and a feed renders identically until its events change.
+func renderICSFeed(name string, calendarsWithEvents []*model.CalendarWithEvents) string {
+	cal := ics.NewCalendar()
+	cal.SetProductId("-//Timely//Timely Calendar//EN")
+	cal.SetCalscale("GREGORIAN")
+	cal.SetMethod(ics.MethodPublish)
+	cal.SetName(name)
+	cal.SetXWRCalName(name)
+
+	refreshInterval := fmt.Sprintf("PT%dM", int(ICSFeedRefreshInterval.Minutes()))
+	cal.SetRefreshInterval(refreshInterval, ics.WithValue("DURATION"))
+	cal.SetXPublishedTTL(refreshInterval)
+
+	for _, calendarWithEvents := range calendarsWithEvents {
+		for _, event := range calendarWithEvents.Events {
+			vevent := cal.AddEvent(fmt.Sprintf("%d@timely", event.ID))
+			// Busy blocks carry no timestamps, stamp them with their start so the feed stays stable
+			dtStamp := event.UpdatedAt
+			if dtStamp.IsZero() {
+				dtStamp = event.Start
+			}
+			vevent.SetDtStampTime(dtStamp)
+			if event.Title != "" {
+				vevent.SetSummary(event.Title)
+			}
+
+			if event.AllDay {
+				vevent.SetAllDayStartAt(event.Start)
+				vevent.SetAllDayEndAt(event.End)
+			} else {
+				vevent.SetStartAt(event.Start)
+				vevent.SetEndAt(event.End)
+			}
+
+			if event.Location != "" {
+				vevent.SetLocation(event.Location)
+			}
+			if event.Description != "" {
+				vevent.SetDescription(event.Description)
+			}
+			if status := icsObjectStatus(event.Status); status != "" {
+				vevent.SetStatus(status)
+			}
+			if event.Transparency == model.CalendarEventTransparencyTransparent {
+				vevent.SetTimeTransparency(ics.TransparencyTransparent)
+			}
+		}
+	}
+
+	return cal.Serialize(ics.WithNewLine("\r\n"))
+}
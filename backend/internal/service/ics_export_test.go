@@ -0,0 +1,75 @@
+package service
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	ics "github.com/arran4/golang-ical"
+
+	"github.com/NathanWasTaken/timely/backend/internal/model"
+)
+
+func TestRenderICSFeedRoundTrip(t *testing.T) {
+	updatedAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	calendarsWithEvents := []*model.CalendarWithEvents{{
+		Calendar: &model.Calendar{ID: 1, Summary: "Work"},
+		Events: []*model.CalendarEvent{
+			{
+				ID:          10,
+				Title:       "Planning, part 1",
+				Start:       time.Date(2024, 1, 2, 9, 0, 0, 0, time.UTC),
+				End:         time.Date(2024, 1, 2, 10, 0, 0, 0, time.UTC),
+				Location:    "Room A",
+				Description: "Line one\nLine two",
+				UpdatedAt:   updatedAt,
+			},
+			{
+				ID:        11,
+				Title:     "Offsite",
+				Start:     time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC),
+				End:       time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC),
+				AllDay:    true,
+				UpdatedAt: updatedAt,
+			},
+		},
+	}}
+
+	feed := renderICSFeed("Jane Doe", calendarsWithEvents)
+	if feed != renderICSFeed("Jane Doe", calendarsWithEvents) {
+		t.Error("Expected rendering to be deterministic")
+	}
+	if !strings.Contains(feed, "\r\n") {
+		t.Error("Expected CRLF line endings")
+	}
+
+	parsed, err := ics.ParseCalendar(strings.NewReader(feed))
+	if err != nil {
+		t.Fatalf("Failed to parse rendered feed: %v", err)
+	}
+
+	events := parsed.Events()
+	if len(events) != 2 {
+		t.Fatalf("Expected 2 events, got %d", len(events))
+	}
+
+	timed := events[0]
+	if timed.Id() != "10@timely" {
+		t.Errorf("Expected UID 10@timely, got %q", timed.Id())
+	}
+	if summary := timed.GetProperty(ics.ComponentPropertySummary).Value; summary != "Planning, part 1" {
+		t.Errorf("Unexpected summary %q", summary)
+	}
+	if start := timed.GetProperty(ics.ComponentPropertyDtStart).Value; start != "20240102T090000Z" {
+		t.Errorf("Unexpected start %q", start)
+	}
+
+	allDay := events[1]
+	start := allDay.GetProperty(ics.ComponentPropertyDtStart)
+	if start.Value != "20240103" || !isICSDateValue(start.ICalParameters) {
+		t.Errorf("Expected an all-day DATE start, got %q %v", start.Value, start.ICalParameters)
+	}
+	if end := allDay.GetProperty(ics.ComponentPropertyDtEnd).Value; end != "20240105" {
+		t.Errorf("Unexpected all-day end %q", end)
+	}
+}
@@ -0,0 +1,213 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/NathanWasTaken/timely/backend/internal/model"
+	"github.com/NathanWasTaken/timely/backend/pkg/utils"
+)
+
+const (
+	// DefaultICSRefreshInterval is how often subscribed ICS feeds are re-fetched by default
+	DefaultICSRefreshInterval = time.Hour
+	// icsRefreshCheckInterval is how often the refresher looks for subscriptions that are due
+	icsRefreshCheckInterval = 5 * time.Minute
+	// icsFetchTimeout bounds a single request for a remote ICS feed
+	icsFetchTimeout = 30 * time.Second
+	// maxICSFeedSize caps the size of a remote ICS feed, matching the upload limit
+	maxICSFeedSize = 10 << 20
+)
+
+// SubscribeICSCalendar creates an ICS calendar backed by a remote feed URL and imports its events
+func (s *CalendarService) SubscribeICSCalendar(userID uint64, subscriptionURL, calendarName string) (*model.Calendar, int, error) {
+	feedURL, err := normalizeICSSubscriptionURL(subscriptionURL)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	s.logger.Info("Subscribing to ICS feed",
+		zap.Uint64("user_id", userID),
+		zap.String("url", feedURL))
+
+	feed, err := s.ics.fetchFeed(context.Background(), feedURL, nil, nil)
+	if errors.Is(err, utils.ErrNonPublicAddress) {
+		return nil, 0, fmt.Errorf("invalid subscription URL: %w", err)
+	}
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to fetch ICS feed: %w", err)
+	}
+
+	// Provided name takes priority, then ICS properties, then fallback
+	if calendarName == "" {
+		calendarName = ExtractICSCalendarName(feed.Calendar)
+	}
+
+	calendar := s.newICSCalendar(userID, calendarName, icsCalendarTimeZone(feed.Calendar))
+	calendar.BackgroundColor = icsCalendarColor(feed.Calendar)
+	calendar.SubscriptionURL = &feedURL
+	calendar.SubscriptionETag = optionalString(feed.ETag)
+	calendar.SubscriptionLastModified = optionalString(feed.LastModified)
+	calendar.LastFetchedAt = calendar.LastFullSync
+
+	eventsCount, err := s.createICSCalendar(calendar, s.ics.timeZones(feed.Calendar, calendar.TimeZone), feed.Calendar.Events())
+	if err != nil {
+		return nil, 0, err
+	}
+
+	s.logger.Info("Successfully subscribed to ICS feed",
+		zap.Uint64("user_id", userID),
+		zap.Uint64("calendar_id", calendar.ID),
+		zap.Int("imported_events", eventsCount))
+
+	return calendar, eventsCount, nil
+}
+
+// RefreshICSSubscription re-fetches the remote feed of a subscribed ICS calendar and merges its events
+func (s *CalendarService) RefreshICSSubscription(userID uint64, calendarID string) (*model.Calendar, *SyncResult, error) {
+	// Find the calendar and verify ownership
+	calendar, err := s.calendarRepo.FindByID(calendarID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to find calendar: %w", err)
+	}
+
+	if calendar.UserID != userID {
+		return nil, nil, fmt.Errorf("calendar not found or access denied")
+	}
+
+	if calendar.Source != model.SourceICS || calendar.SubscriptionURL == nil {
+		return nil, nil, fmt.Errorf("calendar is not an ICS subscription")
+	}
+
+	result, err := s.refreshICSCalendar(calendar)
+	if err != nil {
+		return calendar, nil, err
+	}
+
+	return calendar, result, nil
+}
+
+// RunICSSubscriptionRefresher refreshes subscribed ICS calendars that have not been fetched within
+// interval until ctx is cancelled
+func (s *CalendarService) RunICSSubscriptionRefresher(ctx context.Context, interval time.Duration) {
+	s.logger.Info("Starting ICS subscription refresher", zap.Duration("interval", interval))
+
+	checkInterval := icsRefreshCheckInterval
+	if interval < checkInterval {
+		checkInterval = interval
+	}
+
+	ticker := time.NewTicker(checkInterval)
+	defer ticker.Stop()
+
+	for {
+		s.refreshDueICSSubscriptions(interval)
+
+		select {
+		case <-ctx.Done():
+			s.logger.Info("Stopping ICS subscription refresher")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// refreshDueICSSubscriptions refreshes every subscribed ICS calendar last fetched more than interval ago
+func (s *CalendarService) refreshDueICSSubscriptions(interval time.Duration) {
+	calendars, err := s.calendarRepo.FindICSSubscriptionsFetchedBefore(time.Now().Add(-interval))
+	if err != nil {
+		s.logger.Error("Failed to find ICS subscriptions to refresh", zap.Error(err))
+		return
+	}
+
+	for _, calendar := range calendars {
+		_, err := s.refreshICSCalendar(calendar)
+		if errors.Is(err, errCalendarBusy) {
+			s.logger.Debug("ICS subscription is already being refreshed", zap.Uint64("calendar_id", calendar.ID))
+			continue
+		}
+		if err != nil {
+			s.logger.Warn("Failed to refresh ICS subscription",
+				zap.Error(err),
+				zap.Uint64("calendar_id", calendar.ID))
+		}
+	}
+}
+
+// refreshICSCalendar fetches the remote feed of a subscribed calendar with a conditional request,
+// merges any changes and records the outcome of the fetch on the calendar. The sync lease is held
+// meanwhile, errCalendarBusy is returned while another refresh of the calendar runs.
+func (s *CalendarService) refreshICSCalendar(calendar *model.Calendar) (*SyncResult, error) {
+	lease, err := s.claimSyncLease(calendar.ID, s.calendarRepo.ClaimSyncLease)
+	if err != nil {
+		return nil, err
+	}
+	defer s.releaseSyncLease(lease, nil)
+
+	now := time.Now()
+	calendar.LastFetchedAt = &now
+
+	result, err := s.SyncCalendar(calendar, true)
+	if err != nil {
+		message := err.Error()
+		calendar.LastFetchError = &message
+	} else {
+		calendar.LastFetchError = nil
+	}
+
+	if updateErr := s.calendarRepo.UpdateFetchResult(calendar.ID, now, calendar.LastFetchError); updateErr != nil {
+		s.logger.Error("Failed to record ICS fetch result",
+			zap.Error(updateErr),
+			zap.Uint64("calendar_id", calendar.ID))
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Refreshed ICS subscription",
+		zap.Uint64("calendar_id", calendar.ID),
+		zap.Bool("not_modified", result.NotModified),
+		zap.Int("added_events", result.Added),
+		zap.Int("updated_events", result.Updated),
+		zap.Int("removed_events", result.Removed))
+
+	return result, nil
+}
+
+// normalizeICSSubscriptionURL validates a subscription URL, rewriting webcal:// URLs to https://
+func normalizeICSSubscriptionURL(rawURL string) (string, error) {
+	parsed, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return "", fmt.Errorf("invalid subscription URL: %w", err)
+	}
+
+	switch strings.ToLower(parsed.Scheme) {
+	case "webcal", "webcals":
+		parsed.Scheme = "https"
+	case "http", "https":
+		parsed.Scheme = strings.ToLower(parsed.Scheme)
+	default:
+		return "", fmt.Errorf("invalid subscription URL: unsupported scheme %q", parsed.Scheme)
+	}
+
+	if parsed.Host == "" {
+		return "", fmt.Errorf("invalid subscription URL: missing host")
+	}
+
+	return parsed.String(), nil
+}
+
+// optionalString returns a pointer to value, or nil if value is empty
+func optionalString(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return &value
+}
@@ -0,0 +1,337 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/NathanWasTaken/timely/backend/internal/migrations"
+	"github.com/NathanWasTaken/timely/backend/internal/model"
+	"github.com/NathanWasTaken/timely/backend/internal/repository"
+	"github.com/NathanWasTaken/timely/backend/pkg/utils"
+)
+
+// newTestDB opens an in-memory SQLite database with the schema migrated
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	utils.InitSnowflake(1)
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("Failed to get database handle: %v", err)
+	}
+	sqlDB.SetMaxOpenConns(1) // Every connection to ":memory:" opens a separate database
+
+	if err := db.AutoMigrate(&model.User{}, &model.Account{}, &model.Calendar{}, &model.CalendarEvent{}, &model.FeedToken{},
+		&model.BookingType{}, &model.Booking{}, &model.CalDAVAccount{}, &model.AppPassword{}, &model.EventWrite{}, &model.BackfillJob{}, &model.SyncRun{}, &model.EventAttendee{}, &model.DAVObject{}); err != nil {
+		t.Fatalf("Failed to migrate database: %v", err)
+	}
+	// Indexes AutoMigrate can't express are created by their migrations
+	if err := migrations.EventSourceIndex.Migrate(db); err != nil {
+		t.Fatalf("Failed to create the event source index: %v", err)
+	}
+	if err := migrations.EventSourceURLIndex.Migrate(db); err != nil {
+		t.Fatalf("Failed to create the event source URL index: %v", err)
+	}
+
+	return db
+}
+
+// newTestCalendarService creates a CalendarService backed by an in-memory SQLite database
+func newTestCalendarService(t *testing.T) (*CalendarService, *repository.CalendarRepository) {
+	t.Helper()
+
+	db := newTestDB(t)
+	calendarRepo := repository.NewCalendarRepository(db)
+	calendarService := NewCalendarService(repository.NewUserRepository(db), calendarRepo, nil)
+
+	// Test servers listen on loopback, which the clients for user-supplied URLs refuse to reach
+	calendarService.ics.httpClient = &http.Client{Timeout: icsFetchTimeout}
+	calendarService.caldav.httpClient = &http.Client{Timeout: caldavRequestTimeout}
+
+	return calendarService, calendarRepo
+}
+
+// icsFeedServer serves an ICS feed that honours If-None-Match
+type icsFeedServer struct {
+	mu      sync.Mutex
+	body    string
+	version int
+	status  int
+}
+
+func (f *icsFeedServer) setFeed(body string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.body = body
+	f.version++
+}
+
+func (f *icsFeedServer) setStatus(status int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.status = status
+}
+
+func (f *icsFeedServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.status != 0 {
+		w.WriteHeader(f.status)
+		return
+	}
+
+	etag := fmt.Sprintf(`"v%d"`, f.version)
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/calendar")
+	w.Header().Set("ETag", etag)
+	w.Write([]byte(f.body))
+}
+
+func buildICSFeed(events ...string) string {
+	return "BEGIN:VCALENDAR\r\nVERSION:2.0\r\nPRODID:-//Test//Test//EN\r\nX-WR-CALNAME:Team\r\n" +
+		strings.Join(events, "") + "END:VCALENDAR\r\n"
+}
+
+func buildICSEvent(uid, summary, start string) string {
+	return "BEGIN:VEVENT\r\nUID:" + uid + "\r\nSUMMARY:" + summary +
+		"\r\nDTSTART:" + start + "\r\nDURATION:PT1H\r\nEND:VEVENT\r\n"
+}
+
+func TestICSSubscriptionRefresh(t *testing.T) {
+	calendarService, calendarRepo := newTestCalendarService(t)
+
+	feed := &icsFeedServer{}
+	feed.setFeed(buildICSFeed(
+		buildICSEvent("standup", "Standup", "20240101T090000Z"),
+		buildICSEvent("review", "Review", "20240102T090000Z"),
+	))
+	server := httptest.NewServer(feed)
+	defer server.Close()
+
+	const userID = 42
+	calendar, eventsCount, err := calendarService.SubscribeICSCalendar(userID, server.URL, "")
+	if err != nil {
+		t.Fatalf("Failed to subscribe: %v", err)
+	}
+	if eventsCount != 2 {
+		t.Errorf("Expected 2 imported events, got %d", eventsCount)
+	}
+	if calendar.Summary != "Team" {
+		t.Errorf("Expected calendar name from X-WR-CALNAME, got %q", calendar.Summary)
+	}
+	calendarID := fmt.Sprintf("%d", calendar.ID)
+
+	// An unchanged feed is answered with 304 and leaves the events alone
+	_, changes, err := calendarService.RefreshICSSubscription(userID, calendarID)
+	if err != nil {
+		t.Fatalf("Failed to refresh: %v", err)
+	}
+	if !changes.NotModified {
+		t.Error("Expected the unchanged feed to be reported as not modified")
+	}
+
+	// Changes are merged by UID
+	feed.setFeed(buildICSFeed(
+		buildICSEvent("standup", "Daily standup", "20240101T090000Z"),
+		buildICSEvent("retro", "Retro", "20240103T090000Z"),
+	))
+	_, changes, err = calendarService.RefreshICSSubscription(userID, calendarID)
+	if err != nil {
+		t.Fatalf("Failed to refresh: %v", err)
+	}
+	if changes.NotModified || changes.Added != 1 || changes.Updated != 1 || changes.Removed != 1 {
+		t.Errorf("Expected 1 added, 1 updated and 1 removed, got %+v", changes)
+	}
+
+	events, err := calendarRepo.FindEventsByCalendarID(calendar.ID)
+	if err != nil {
+		t.Fatalf("Failed to load events: %v", err)
+	}
+	titles := map[string]string{}
+	for _, event := range events {
+		titles[event.SourceID] = event.Title
+	}
+	if len(titles) != 2 || titles["standup"] != "Daily standup" || titles["retro"] != "Retro" {
+		t.Errorf("Unexpected events after merge: %v", titles)
+	}
+
+	// A failed fetch is recorded on the calendar and cleared by the next successful fetch
+	feed.setStatus(http.StatusInternalServerError)
+	if _, _, err := calendarService.RefreshICSSubscription(userID, calendarID); err == nil {
+		t.Fatal("Expected the refresh to fail")
+	}
+	stored, err := calendarRepo.FindByID(calendarID)
+	if err != nil {
+		t.Fatalf("Failed to load calendar: %v", err)
+	}
+	if stored.LastFetchError == nil || !strings.Contains(*stored.LastFetchError, "500") {
+		t.Errorf("Expected the fetch error to be recorded, got %v", stored.LastFetchError)
+	}
+
+	feed.setStatus(0)
+	if _, _, err := calendarService.RefreshICSSubscription(userID, calendarID); err != nil {
+		t.Fatalf("Failed to refresh: %v", err)
+	}
+	stored, err = calendarRepo.FindByID(calendarID)
+	if err != nil {
+		t.Fatalf("Failed to load calendar: %v", err)
+	}
+	if stored.LastFetchError != nil {
+		t.Errorf("Expected the fetch error to be cleared, got %q", *stored.LastFetchError)
+	}
+
+	// A refresh already running holds the sync lease
+	now := time.Now()
+	if claimed, err := calendarRepo.ClaimSyncLease(calendar.ID, "refresher", now, now.Add(syncLeaseDuration)); err != nil || !claimed {
+		t.Fatalf("Failed to claim lease: claimed=%v err=%v", claimed, err)
+	}
+	if _, _, err := calendarService.RefreshICSSubscription(userID, calendarID); err != errCalendarBusy {
+		t.Errorf("Expected the refresh to wait for the running one, got %v", err)
+	}
+	if err := calendarRepo.ReleaseSyncLease(calendar.ID, "refresher", nil); err != nil {
+		t.Fatalf("Failed to release lease: %v", err)
+	}
+
+	// Other users cannot refresh the calendar
+	if _, _, err := calendarService.RefreshICSSubscription(userID+1, calendarID); err == nil {
+		t.Error("Expected refreshing another user's calendar to fail")
+	}
+}
+
+func TestNormalizeICSSubscriptionURL(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{input: "webcal://example.com/cal.ics", want: "https://example.com/cal.ics"},
+		{input: " https://example.com/cal.ics ", want: "https://example.com/cal.ics"},
+		{input: "http://example.com/cal.ics", want: "http://example.com/cal.ics"},
+		{input: "ftp://example.com/cal.ics", wantErr: true},
+		{input: "file:///etc/passwd", wantErr: true},
+		{input: "https://", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := normalizeICSSubscriptionURL(tt.input)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("Expected an error for %q", tt.input)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Unexpected error for %q: %v", tt.input, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Expected %q for %q, got %q", tt.want, tt.input, got)
+		}
+	}
+}
+
+func TestSubscribeICSCalendarRefusesInternalAddresses(t *testing.T) {
+	// The service's own client, which doesn't reach loopback like the test services do
+	db := newTestDB(t)
+	calendarService := NewCalendarService(repository.NewUserRepository(db), repository.NewCalendarRepository(db), nil)
+
+	feed := &icsFeedServer{}
+	feed.setFeed(buildICSFeed(buildICSEvent("standup", "Standup", "20240101T090000Z")))
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		feed.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	for _, feedURL := range []string{server.URL, "http://169.254.169.254/latest/meta-data/", "webcal://[::1]/calendar.ics"} {
+		_, _, err := calendarService.SubscribeICSCalendar(71, feedURL, "Internal")
+		if err == nil || !strings.HasPrefix(err.Error(), "invalid subscription URL") {
+			t.Errorf("Expected subscribing to %s to be refused, got %v", feedURL, err)
+		}
+	}
+	if requests != 0 {
+		t.Errorf("Expected no request to reach the loopback feed, got %d", requests)
+	}
+
+	calendars, err := calendarService.calendarRepo.FindByUserID(71)
+	if err != nil || len(calendars) != 0 {
+		t.Errorf("Expected no calendar to be created, got %d (%v)", len(calendars), err)
+	}
+}
+
+func TestPublicEventsHideSubscriptionState(t *testing.T) {
+	calendarService, calendarRepo := newTestCalendarService(t)
+
+	const userID = 43
+	if err := calendarService.userRepo.Create(&model.User{ID: userID, Username: "kim"}); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	feed := &icsFeedServer{}
+	feed.setFeed(buildICSFeed(buildICSEvent("standup", "Standup", "20240101T090000Z")))
+	server := httptest.NewServer(feed)
+	defer server.Close()
+
+	calendar, _, err := calendarService.SubscribeICSCalendar(userID, server.URL+"/feed.ics?token=feed-secret", "")
+	if err != nil {
+		t.Fatalf("Failed to subscribe: %v", err)
+	}
+	public := model.CalendarVisibilityPublic
+	if _, err := calendarService.UpdateCalendar(userID, fmt.Sprint(calendar.ID), &model.CalendarUpdateRequest{Visibility: &public}); err != nil {
+		t.Fatalf("Failed to update calendar: %v", err)
+	}
+
+	feed.setStatus(http.StatusInternalServerError)
+	if _, _, err := calendarService.RefreshICSSubscription(userID, fmt.Sprint(calendar.ID)); err == nil {
+		t.Fatal("Expected the refresh to fail")
+	}
+	stored, err := calendarRepo.FindByID(fmt.Sprint(calendar.ID))
+	if err != nil {
+		t.Fatalf("Failed to load calendar: %v", err)
+	}
+	accountID := uint64(7)
+	stored.CalDAVAccountID = &accountID
+	if err := calendarRepo.Update(stored); err != nil {
+		t.Fatalf("Failed to update calendar: %v", err)
+	}
+
+	calendarsWithEvents, err := calendarService.GetPublicUserCalendarEvents(userID,
+		time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC))
+	if err != nil || len(calendarsWithEvents) != 1 || len(calendarsWithEvents[0].Events) != 1 {
+		t.Fatalf("Expected the public calendar with its event, got %+v, %v", calendarsWithEvents, err)
+	}
+	payload, err := json.Marshal(calendarsWithEvents)
+	if err != nil {
+		t.Fatalf("Failed to encode public events: %v", err)
+	}
+	for _, secret := range []string{"feed-secret", "subscription_url", "last_fetch_error", "last_fetched_at", "caldav_account_id", "watch_expires_at", "health", "sync_token"} {
+		if strings.Contains(string(payload), secret) {
+			t.Errorf("Expected the public events not to contain %q, got %s", secret, payload)
+		}
+	}
+	if calendarsWithEvents[0].Summary != calendar.Summary {
+		t.Errorf("Expected the public calendar to keep its name, got %q", calendarsWithEvents[0].Summary)
+	}
+}
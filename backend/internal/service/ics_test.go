@@ -0,0 +1,176 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	ics "github.com/arran4/golang-ical"
+
+	"github.com/NathanWasTaken/timely/backend/internal/model"
+)
+
+func parseTestICS(t *testing.T, data string) []*ics.VEvent {
+	t.Helper()
+	cal, err := ics.ParseCalendar(strings.NewReader(data))
+	if err != nil {
+		t.Fatalf("Failed to parse ICS data: %v", err)
+	}
+	return cal.Events()
+}
+
+func TestReimportICSCalendarMergesByUIDAndRecurrenceID(t *testing.T) {
+	calendarService, calendarRepo := newTestCalendarService(t)
+
+	const userID = 7
+	weekly := "BEGIN:VEVENT\r\nUID:class\r\nSUMMARY:Class\r\nDTSTART:20240101T100000Z\r\nDTEND:20240101T110000Z\r\n" +
+		"RRULE:FREQ=WEEKLY;COUNT=3\r\nEND:VEVENT\r\n"
+	single := buildICSEvent("exam", "Exam", "20240120T090000Z")
+
+	calendar, eventsCount, err := calendarService.ImportICSCalendar(userID, "Classes", nil, parseTestICS(t, buildICSFeed(weekly, single)))
+	if err != nil {
+		t.Fatalf("Failed to import: %v", err)
+	}
+	if eventsCount != 4 {
+		t.Fatalf("Expected 3 occurrences and 1 single event, got %d events", eventsCount)
+	}
+
+	// Settings made after the first import must survive the re-import
+	calendar.Visibility = model.CalendarVisibilityPublic
+	if err := calendarRepo.Update(calendar); err != nil {
+		t.Fatalf("Failed to update calendar: %v", err)
+	}
+
+	// Move the second class, cancel the third and drop the exam
+	override := "BEGIN:VEVENT\r\nUID:class\r\nRECURRENCE-ID:20240108T100000Z\r\nSUMMARY:Class (moved)\r\n" +
+		"DTSTART:20240109T100000Z\r\nDTEND:20240109T110000Z\r\nEND:VEVENT\r\n"
+	weeklyWithExdate := strings.Replace(weekly, "END:VEVENT", "EXDATE:20240115T100000Z\r\nEND:VEVENT", 1)
+
+	calendarID := fmt.Sprintf("%d", calendar.ID)
+	updated, eventsCount, changes, err := calendarService.ReimportICSCalendar(userID, calendarID, "", nil,
+		parseTestICS(t, buildICSFeed(weeklyWithExdate, override)))
+	if err != nil {
+		t.Fatalf("Failed to re-import: %v", err)
+	}
+
+	if changes.Added != 0 || changes.Updated != 1 || changes.Removed != 2 {
+		t.Errorf("Expected 0 added, 1 updated and 2 removed, got %+v", changes)
+	}
+	if eventsCount != 2 {
+		t.Errorf("Expected 2 events after re-import, got %d", eventsCount)
+	}
+	if updated.Visibility != model.CalendarVisibilityPublic || updated.Summary != "Classes" {
+		t.Errorf("Expected calendar settings to be kept, got visibility %q and summary %q", updated.Visibility, updated.Summary)
+	}
+
+	// Other users cannot target the calendar
+	if _, _, _, err := calendarService.ReimportICSCalendar(userID+1, calendarID, "", nil, nil); err == nil {
+		t.Error("Expected re-importing into another user's calendar to fail")
+	}
+}
+
+func TestImportICSCalendarResolvesTimeZones(t *testing.T) {
+	calendarService, calendarRepo := newTestCalendarService(t)
+
+	data := "BEGIN:VCALENDAR\r\nVERSION:2.0\r\nPRODID:-//Test//Test//EN\r\nX-WR-TIMEZONE:Europe/Berlin\r\n" +
+		"BEGIN:VTIMEZONE\r\nTZID:Custom Eastern\r\n" +
+		"BEGIN:STANDARD\r\nDTSTART:16010101T020000\r\nTZOFFSETFROM:-0400\r\nTZOFFSETTO:-0500\r\n" +
+		"RRULE:FREQ=YEARLY;BYDAY=1SU;BYMONTH=11\r\nEND:STANDARD\r\n" +
+		"BEGIN:DAYLIGHT\r\nDTSTART:16010101T020000\r\nTZOFFSETFROM:-0500\r\nTZOFFSETTO:-0400\r\n" +
+		"RRULE:FREQ=YEARLY;BYDAY=2SU;BYMONTH=3\r\nEND:DAYLIGHT\r\nEND:VTIMEZONE\r\n" +
+		"BEGIN:VEVENT\r\nUID:windows\r\nSUMMARY:Windows\r\nDTSTART;TZID=Pacific Standard Time:20240701T090000\r\nDURATION:PT1H\r\nEND:VEVENT\r\n" +
+		"BEGIN:VEVENT\r\nUID:custom\r\nSUMMARY:Custom\r\nDTSTART;TZID=Custom Eastern:20240701T090000\r\nDURATION:PT1H\r\nEND:VEVENT\r\n" +
+		"BEGIN:VEVENT\r\nUID:floating\r\nSUMMARY:Floating\r\nDTSTART:20240701T090000\r\nDURATION:PT1H\r\nEND:VEVENT\r\n" +
+		"END:VCALENDAR\r\n"
+
+	icsCalendar, err := ics.ParseCalendar(strings.NewReader(data))
+	if err != nil {
+		t.Fatalf("Failed to parse ICS data: %v", err)
+	}
+
+	calendar, _, err := calendarService.ImportICSCalendar(3, "Zones", icsCalendar, icsCalendar.Events())
+	if err != nil {
+		t.Fatalf("Failed to import: %v", err)
+	}
+	if calendar.TimeZone != "Europe/Berlin" {
+		t.Errorf("Expected the calendar time zone from X-WR-TIMEZONE, got %q", calendar.TimeZone)
+	}
+
+	events, err := calendarRepo.FindEventsByCalendarID(calendar.ID)
+	if err != nil {
+		t.Fatalf("Failed to load events: %v", err)
+	}
+
+	want := map[string]string{
+		"windows":  "2024-07-01T16:00:00Z", // PDT is UTC-7
+		"custom":   "2024-07-01T13:00:00Z", // EDT is UTC-4
+		"floating": "2024-07-01T07:00:00Z", // CEST is UTC+2
+	}
+	for _, event := range events {
+		if got := event.Start.UTC().Format(time.RFC3339); got != want[event.SourceID] {
+			t.Errorf("Event %s: expected start %s, got %s", event.SourceID, want[event.SourceID], got)
+		}
+	}
+	if len(events) != len(want) {
+		t.Errorf("Expected %d events, got %d", len(want), len(events))
+	}
+}
+
+func TestImportICSCalendarExpandsOldRecurringEvents(t *testing.T) {
+	calendarService, calendarRepo := newTestCalendarService(t)
+
+	// Both rules have more occurrences since DTSTART than maxICSOccurrences
+	hourly := "BEGIN:VEVENT\r\nUID:hourly\r\nSUMMARY:Hourly\r\nDTSTART:20150101T000000Z\r\nDURATION:PT15M\r\n" +
+		"RRULE:FREQ=HOURLY\r\nEND:VEVENT\r\n"
+	daily := "BEGIN:VEVENT\r\nUID:daily\r\nSUMMARY:Daily\r\nDTSTART:19900101T090000Z\r\nDURATION:PT1H\r\n" +
+		"RRULE:FREQ=DAILY\r\nEND:VEVENT\r\n"
+
+	now := time.Now()
+	calendar, _, err := calendarService.ImportICSCalendar(5, "Old", nil, parseTestICS(t, buildICSFeed(hourly, daily)))
+	if err != nil {
+		t.Fatalf("Failed to import: %v", err)
+	}
+
+	events, err := calendarRepo.FindEventsByCalendarID(calendar.ID)
+	if err != nil {
+		t.Fatalf("Failed to load events: %v", err)
+	}
+
+	first := map[string]time.Time{}
+	last := map[string]time.Time{}
+	counts := map[string]int{}
+	for _, event := range events {
+		uid := strings.SplitN(event.SourceID, "_", 2)[0]
+		counts[uid]++
+		if first[uid].IsZero() || event.Start.Before(first[uid]) {
+			first[uid] = event.Start
+		}
+		if event.Start.After(last[uid]) {
+			last[uid] = event.Start
+		}
+	}
+
+	for _, uid := range []string{"hourly", "daily"} {
+		if counts[uid] != maxICSOccurrences {
+			t.Errorf("Expected %d occurrences of %s, got %d", maxICSOccurrences, uid, counts[uid])
+		}
+	}
+
+	// The hourly occurrences fill the cap from the lookback on
+	lookback := now.Add(-icsRecurrenceLookback)
+	if first["hourly"].Before(lookback.Add(-time.Hour)) || first["hourly"].After(lookback.Add(time.Hour)) {
+		t.Errorf("Expected the first hourly occurrence at the lookback %v, got %v", lookback, first["hourly"])
+	}
+	if !last["hourly"].After(now) {
+		t.Errorf("Expected hourly occurrences after now, the last one is %v", last["hourly"])
+	}
+
+	// The daily occurrences reach the horizon and take the room left with earlier ones
+	if horizon := now.Add(icsRecurrenceHorizon); last["daily"].Before(horizon.Add(-24 * time.Hour)) {
+		t.Errorf("Expected daily occurrences up to the horizon %v, the last one is %v", horizon, last["daily"])
+	}
+	if !first["daily"].Before(lookback) {
+		t.Errorf("Expected daily occurrences before the lookback, the first one is %v", first["daily"])
+	}
+}
@@ -0,0 +1,154 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	ics "github.com/arran4/golang-ical"
+	"go.uber.org/zap"
+
+	"github.com/NathanWasTaken/timely/backend/pkg/ical"
+)
+
+// defaultICSTimeZone is used for ICS calendars that do not declare a known X-WR-TIMEZONE
+const defaultICSTimeZone = "UTC"
+
+// icsCalendarTimeZone returns the IANA name of the X-WR-TIMEZONE of an ICS calendar, or an empty
+// string if it has none or the time zone is unknown
+func icsCalendarTimeZone(cal *ics.Calendar) string {
+	if cal == nil {
+		return ""
+	}
+
+	for _, prop := range cal.CalendarProperties {
+		if prop.IANAToken == string(ics.PropertyXWRTimezone) && prop.Value != "" {
+			loc, err := ical.LoadLocation(prop.Value)
+			if err != nil {
+				return ""
+			}
+			return loc.String()
+		}
+	}
+
+	return ""
+}
+
+// timeZones builds the resolver for the TZIDs of an ICS calendar from its VTIMEZONE components.
+// Floating times are interpreted in timeZone, the time zone of the Timely calendar.
+func (p *icsProvider) timeZones(cal *ics.Calendar, timeZone string) *ical.TimeZones {
+	floating, err := ical.LoadLocation(timeZone)
+	if err != nil {
+		floating = time.UTC
+	}
+
+	zones := ical.NewTimeZones(floating)
+	if cal == nil {
+		return zones
+	}
+
+	for _, vtimezone := range cal.Timezones() {
+		tzidProp := vtimezone.GetProperty(ics.ComponentPropertyTzid)
+		if tzidProp == nil || tzidProp.Value == "" {
+			continue
+		}
+
+		loc, err := parseVTimezone(tzidProp.Value, vtimezone)
+		if err != nil {
+			p.logger.Warn("Skipping invalid VTIMEZONE",
+				zap.String("tzid", tzidProp.Value),
+				zap.Error(err))
+			continue
+		}
+		zones.Define(tzidProp.Value, loc)
+	}
+
+	return zones
+}
+
+// parseVTimezone builds a location from the STANDARD and DAYLIGHT components of a VTIMEZONE
+func parseVTimezone(tzid string, vtimezone *ics.VTimezone) (*time.Location, error) {
+	var observances []ical.Observance
+	for _, component := range vtimezone.Components {
+		switch c := component.(type) {
+		case *ics.Standard:
+			observance, err := parseICSObservance(&c.ComponentBase, false)
+			if err != nil {
+				return nil, err
+			}
+			observances = append(observances, observance)
+		case *ics.Daylight:
+			observance, err := parseICSObservance(&c.ComponentBase, true)
+			if err != nil {
+				return nil, err
+			}
+			observances = append(observances, observance)
+		}
+	}
+
+	return ical.NewVTimezoneLocation(tzid, observances)
+}
+
+// parseICSObservance parses a STANDARD or DAYLIGHT component. Its local times are kept as wall
+// clock times expressed in UTC, as ical.Observance expects.
+func parseICSObservance(component *ics.ComponentBase, dst bool) (ical.Observance, error) {
+	observance := ical.Observance{DST: dst}
+
+	dtStart := component.GetProperty(ics.ComponentPropertyDtStart)
+	if dtStart == nil {
+		return observance, fmt.Errorf("observance missing DTSTART")
+	}
+	start, err := parseICSWallTime(dtStart.Value)
+	if err != nil {
+		return observance, fmt.Errorf("failed to parse observance start: %w", err)
+	}
+	observance.Start = start
+
+	for _, offset := range []struct {
+		property ics.Property
+		target   *int
+	}{
+		{property: ics.PropertyTzoffsetfrom, target: &observance.OffsetFrom},
+		{property: ics.PropertyTzoffsetto, target: &observance.OffsetTo},
+	} {
+		prop := component.GetProperty(ics.ComponentProperty(offset.property))
+		if prop == nil {
+			return observance, fmt.Errorf("observance missing %s", offset.property)
+		}
+		value, err := ical.ParseUTCOffset(strings.TrimSpace(prop.Value))
+		if err != nil {
+			return observance, err
+		}
+		*offset.target = value
+	}
+
+	if tzname := component.GetProperty(ics.ComponentProperty(ics.PropertyTzname)); tzname != nil {
+		observance.Name = tzname.Value
+	}
+
+	for _, rrule := range component.GetProperties(ics.ComponentPropertyRrule) {
+		rule, err := ical.ParseRecurrenceRule(rrule.Value, time.UTC)
+		if err != nil {
+			return observance, fmt.Errorf("failed to parse observance rule: %w", err)
+		}
+		observance.Rules = append(observance.Rules, rule)
+	}
+
+	for _, rdate := range component.GetProperties(ics.ComponentPropertyRdate) {
+		for _, value := range strings.Split(rdate.Value, ",") {
+			value, _, _ = strings.Cut(strings.TrimSpace(value), "/")
+			parsed, err := parseICSWallTime(value)
+			if err != nil {
+				return observance, fmt.Errorf("failed to parse observance date: %w", err)
+			}
+			observance.RDates = append(observance.RDates, parsed)
+		}
+	}
+
+	return observance, nil
+}
+
+// parseICSWallTime parses a local DATE-TIME value of a VTIMEZONE observance
+func parseICSWallTime(value string) (time.Time, error) {
+	return time.Parse("20060102T150405", strings.TrimSuffix(strings.TrimSpace(value), "Z"))
+}
@@ -121,3 +121,10 @@ func (s *OAuthService) FindOrCreateUserFromGoogleWithToken(googleUser *model.Goo
 func (s *OAuthService) GetUserGoogleAccount(userID uint64) (*model.Account, error) {
 	return s.userService.GetGoogleAccountByUserID(userID)
 }
+
+// grantedScopes returns the space-separated scopes granted to a token, empty if the token response
+// does not list them
+func grantedScopes(token *oauth2.Token) string {
+	scopes, _ := token.Extra("scope").(string)
+	return scopes
+}
@@ -0,0 +1,341 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/NathanWasTaken/timely/backend/internal/model"
+	"github.com/NathanWasTaken/timely/backend/pkg/utils"
+)
+
+// errCalendarListingUnsupported is returned by providers whose source cannot list calendars, such
+// as ICS feeds that are a single calendar
+var errCalendarListingUnsupported = errors.New("calendar source does not support listing calendars")
+
+// CalendarProvider connects Timely to a remote calendar source. Providers only talk to the remote
+// service, storing calendars and merging events is left to the CalendarService, so a new source is
+// added by implementing this interface and registering it with RegisterProvider.
+type CalendarProvider interface {
+	// Source returns the calendar source the provider handles
+	Source() model.CalendarSource
+	// RefreshCredentials returns credentials valid for the next calls about a calendar, refreshing
+	// them if needed
+	RefreshCredentials(ctx context.Context, calendar *model.Calendar) (*ProviderCredentials, error)
+	// ListCalendars lists the remote calendars the user can import
+	ListCalendars(ctx context.Context, credentials *ProviderCredentials) ([]*RemoteCalendar, error)
+	// FetchEvents fetches the events of a calendar. With a sync token only the changes since the
+	// token was issued are returned, without one every event is fetched.
+	FetchEvents(ctx context.Context, credentials *ProviderCredentials, calendar *model.Calendar, syncToken string) (*ProviderEvents, error)
+}
+
+// ProviderCredentials holds what a provider needs to access the remote calendars of a user
+type ProviderCredentials struct {
+	AccessToken string // OAuth access token
+	// Basic auth credentials and calendar home of CalDAV accounts
+	Username string
+	Password string
+	HomeURL  string
+}
+
+// RemoteCalendar represents a calendar of a remote source that can be imported
+type RemoteCalendar struct {
+	ID          string
+	Summary     string
+	Description string
+	TimeZone    string
+	Color       string
+}
+
+// ProviderEvents represents the result of fetching the events of a calendar
+type ProviderEvents struct {
+	Events      []*model.CalendarEvent // Events that are new or may have changed
+	Deleted     []string               // Source IDs of events deleted remotely
+	FullSync    bool                   // Fetched without a sync token, because none was given or the provider rejected it
+	Complete    bool                   // Events holds every event of the calendar, stored events missing from it are removed
+	SyncToken   string                 // Token for the next incremental fetch, empty if the source has none
+	NotModified bool                   // The calendar is unchanged since the last fetch
+	// HTTP validators of feeds fetched with conditional requests
+	ETag         string
+	LastModified string
+}
+
+// SyncResult summarizes the changes applied when merging fetched events into a calendar
+type SyncResult struct {
+	Added       int  `json:"added"`
+	Updated     int  `json:"updated"`
+	Removed     int  `json:"removed"`
+	NotModified bool `json:"not_modified"` // True if the remote calendar was unchanged since the last fetch
+}
+
+// RegisterProvider registers the provider of a calendar source, replacing any provider registered
+// for it before
+func (s *CalendarService) RegisterProvider(provider CalendarProvider) {
+	s.providers[provider.Source()] = provider
+}
+
+// provider returns the provider of a calendar source
+func (s *CalendarService) provider(source model.CalendarSource) (CalendarProvider, error) {
+	provider, ok := s.providers[source]
+	if !ok {
+		return nil, fmt.Errorf("no provider for calendar source %q", source)
+	}
+	return provider, nil
+}
+
+// scheduledSources returns the sources synced by the background scheduler. ICS subscriptions are
+// refreshed on their own interval by RunICSSubscriptionRefresher.
+func (s *CalendarService) scheduledSources() []model.CalendarSource {
+	var sources []model.CalendarSource
+	for source := range s.providers {
+		if source != model.SourceICS {
+			sources = append(sources, source)
+		}
+	}
+	return sources
+}
+
+// isScheduledSource reports whether calendars of a source are synced by the background scheduler
+func (s *CalendarService) isScheduledSource(source model.CalendarSource) bool {
+	_, ok := s.providers[source]
+	return ok && source != model.SourceICS
+}
+
+// SyncCalendar fetches the events of a calendar from its provider and merges them into the stored
+// events. Changes are fetched incrementally when the calendar has a valid sync token, unless
+// forceFullSync is set. Every sync is recorded in the sync history of the calendar.
+func (s *CalendarService) SyncCalendar(calendar *model.Calendar, forceFullSync bool) (*SyncResult, error) {
+	run := &model.SyncRun{
+		ID:         utils.GenerateID(),
+		CalendarID: calendar.ID,
+		StartedAt:  time.Now(),
+		Mode:       model.SyncRunModeFull,
+	}
+
+	result, err := s.syncCalendar(calendar, forceFullSync, run)
+	s.recordSyncRun(run, result, err)
+	return result, err
+}
+
+// syncCalendar performs a sync for SyncCalendar, noting how the events were fetched in run
+func (s *CalendarService) syncCalendar(calendar *model.Calendar, forceFullSync bool, run *model.SyncRun) (*SyncResult, error) {
+	provider, err := s.provider(calendar.Source)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	credentials, err := provider.RefreshCredentials(ctx, calendar)
+	if err != nil {
+		return nil, err
+	}
+
+	// Changes made in Timely are pushed first, the fetch below then brings back their new ETags
+	var conflicts []*model.EventWrite
+	writeBack := calendar.WriteBack && calendar.Source == model.SourceGoogle
+	if writeBack {
+		conflicts = s.pushEventWrites(ctx, credentials.AccessToken, calendar)
+	}
+
+	syncToken := ""
+	if !s.syncTokenManager.ShouldPerformFullSync(calendar, forceFullSync) {
+		syncToken = *calendar.SyncToken
+		run.Mode = model.SyncRunModeIncremental
+	}
+
+	fetched, err := provider.FetchEvents(ctx, credentials, calendar, syncToken)
+	if err != nil {
+		return nil, err
+	}
+	if fetched.FullSync {
+		// The provider may have rejected the sync token
+		run.Mode = model.SyncRunModeFull
+	}
+
+	if fetched.NotModified {
+		return &SyncResult{NotModified: true}, nil
+	}
+
+	result, err := s.mergeEvents(calendar, fetched)
+	if err != nil {
+		return nil, err
+	}
+
+	if writeBack && len(conflicts) > 0 {
+		s.retryConflictingEventWrites(ctx, credentials.AccessToken, calendar, conflicts)
+	}
+
+	// Only store the new validators once the feed has been merged, so a failed merge is retried
+	if calendar.SubscriptionURL != nil {
+		calendar.SubscriptionETag = optionalString(fetched.ETag)
+		calendar.SubscriptionLastModified = optionalString(fetched.LastModified)
+		if err := s.calendarRepo.UpdateSubscriptionValidators(calendar.ID, calendar.SubscriptionETag, calendar.SubscriptionLastModified); err != nil {
+			return nil, fmt.Errorf("failed to update subscription validators: %w", err)
+		}
+	}
+
+	if err := s.syncTokenManager.UpdateSyncMetadata(calendar.ID, fetched.SyncToken, fetched.FullSync); err != nil {
+		// Don't fail the sync for metadata update failures, the next sync is a full sync at worst
+		s.logger.Error("Failed to update sync metadata", zap.Error(err), zap.Uint64("calendar_id", calendar.ID))
+	}
+
+	now := time.Now()
+	calendar.SyncedAt = now
+	if fetched.SyncToken != "" {
+		calendar.SyncToken = &fetched.SyncToken
+	}
+	if fetched.FullSync {
+		calendar.SyncStatus = model.CalendarSyncStatusFullSyncComplete
+		calendar.LastFullSync = &now
+	} else {
+		calendar.SyncStatus = model.CalendarSyncStatusIncrementalSync
+	}
+
+	s.logger.Info("Successfully synced calendar events",
+		zap.Uint64("user_id", calendar.UserID),
+		zap.Uint64("calendar_id", calendar.ID),
+		zap.String("source", string(calendar.Source)),
+		zap.Bool("is_full_sync", fetched.FullSync),
+		zap.Int("created_events", result.Added),
+		zap.Int("updated_events", result.Updated),
+		zap.Int("deleted_events", result.Removed))
+
+	return result, nil
+}
+
+// mergeEvents diffs fetched events against the stored events of a calendar by source ID, creating
+// new events, updating changed ones and removing deleted ones. Events with a change made in Timely
+// that has not been pushed yet keep that change.
+func (s *CalendarService) mergeEvents(calendar *model.Calendar, fetched *ProviderEvents) (*SyncResult, error) {
+	calendarID := calendar.ID
+	existingEvents, err := s.calendarRepo.FindEventsByCalendarID(calendarID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get existing events: %w", err)
+	}
+	if err := s.calendarRepo.LoadEventAttendees(existingEvents); err != nil {
+		return nil, fmt.Errorf("failed to get existing attendees: %w", err)
+	}
+	s.markSelfAttendees(calendar, fetched.Events)
+	for _, event := range fetched.Events {
+		// Events are stored as confirmed and opaque when their source does not say otherwise
+		if event.Status == "" {
+			event.Status = model.CalendarEventStatusConfirmed
+		}
+		if event.Transparency == "" {
+			event.Transparency = model.CalendarEventTransparencyOpaque
+		}
+	}
+
+	writes, err := s.calendarRepo.FindEventWritesByCalendarID(calendarID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get event writes: %w", err)
+	}
+	pendingWrites := make(map[string]*model.EventWrite, len(writes))
+	for _, write := range writes {
+		pendingWrites[write.SourceID] = write
+	}
+
+	existingEventMap := make(map[string]*model.CalendarEvent, len(existingEvents))
+	for _, event := range existingEvents {
+		existingEventMap[event.SourceID] = event
+	}
+
+	changes := &SyncEventChanges{
+		ToCreate: []*model.CalendarEvent{},
+		ToUpdate: []*model.CalendarEvent{},
+		ToDelete: []string{},
+	}
+
+	seen := make(map[string]bool, len(fetched.Events))
+	for _, event := range fetched.Events {
+		// Sources occasionally repeat an event; keep the first occurrence
+		if seen[event.SourceID] {
+			continue
+		}
+		seen[event.SourceID] = true
+
+		existingEvent, exists := existingEventMap[event.SourceID]
+		if write, ok := pendingWrites[event.SourceID]; ok {
+			merged, err := s.refreshEventWrite(write, existingEvent, event)
+			if err != nil {
+				return nil, fmt.Errorf("failed to update event write: %w", err)
+			}
+			if merged != nil {
+				changes.ToUpdate = append(changes.ToUpdate, merged)
+			}
+			continue
+		}
+
+		if !exists {
+			changes.ToCreate = append(changes.ToCreate, event)
+			continue
+		}
+
+		if eventChanged(existingEvent, event) {
+			event.ID = existingEvent.ID // Preserve local ID
+			event.CreatedAt = existingEvent.CreatedAt
+			keepEventSharingSettings(existingEvent, event)
+			changes.ToUpdate = append(changes.ToUpdate, event)
+		}
+	}
+
+	deleted := make(map[string]bool, len(fetched.Deleted))
+	for _, sourceID := range fetched.Deleted {
+		deleted[sourceID] = true
+	}
+
+	for sourceID := range existingEventMap {
+		if !seen[sourceID] && (fetched.Complete || deleted[sourceID]) {
+			changes.ToDelete = append(changes.ToDelete, sourceID)
+		}
+	}
+
+	// Changes to events deleted remotely have nothing left to change
+	for sourceID, write := range pendingWrites {
+		if deleted[sourceID] && write.Operation != model.EventWriteCreate {
+			if err := s.calendarRepo.DeleteEventWrite(write); err != nil {
+				return nil, fmt.Errorf("failed to delete event write: %w", err)
+			}
+		}
+	}
+
+	if err := s.applySyncChanges(changes, calendarID); err != nil {
+		return nil, err
+	}
+
+	return &SyncResult{
+		Added:   len(changes.ToCreate),
+		Updated: len(changes.ToUpdate),
+		Removed: len(changes.ToDelete),
+	}, nil
+}
+
+// eventChanged checks whether a fetched event differs from the stored event
+func eventChanged(existing, incoming *model.CalendarEvent) bool {
+	if existing.Title != incoming.Title ||
+		!existing.Start.Equal(incoming.Start) ||
+		!existing.End.Equal(incoming.End) ||
+		existing.AllDay != incoming.AllDay ||
+		existing.Location != incoming.Location ||
+		existing.Description != incoming.Description ||
+		existing.EventColor != incoming.EventColor ||
+		existing.RecurringEventID != incoming.RecurringEventID ||
+		existing.SourceURL != incoming.SourceURL ||
+		existing.SourceETag != incoming.SourceETag ||
+		existing.ResponseStatus != incoming.ResponseStatus ||
+		existing.Status != incoming.Status ||
+		existing.Transparency != incoming.Transparency ||
+		existing.ConferenceURL != incoming.ConferenceURL ||
+		existing.ConferenceProvider != incoming.ConferenceProvider ||
+		attendeesChanged(existing.Attendees, incoming.Attendees) {
+		return true
+	}
+
+	if (existing.OriginalStartTime == nil) != (incoming.OriginalStartTime == nil) {
+		return true
+	}
+	return existing.OriginalStartTime != nil && !existing.OriginalStartTime.Equal(*incoming.OriginalStartTime)
+}
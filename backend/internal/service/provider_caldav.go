@@ -0,0 +1,310 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	ics "github.com/arran4/golang-ical"
+	"go.uber.org/zap"
+
+	"github.com/NathanWasTaken/timely/backend/internal/config"
+	"github.com/NathanWasTaken/timely/backend/internal/model"
+	"github.com/NathanWasTaken/timely/backend/internal/repository"
+	"github.com/NathanWasTaken/timely/backend/pkg/caldav"
+	"github.com/NathanWasTaken/timely/backend/pkg/encrypt"
+	"github.com/NathanWasTaken/timely/backend/pkg/utils"
+)
+
+const (
+	// caldavRequestTimeout bounds a single request to a CalDAV server
+	caldavRequestTimeout = time.Minute
+	// caldavMultiGetBatchSize caps the number of resources fetched with one calendar-multiget REPORT
+	caldavMultiGetBatchSize = 100
+	// caldavCTagPrefix marks sync tokens holding the ctag of a collection, stored for servers that
+	// do not support sync-collection
+	caldavCTagPrefix = "ctag:"
+)
+
+// errCalDAVDisabled is returned when CalDAV credentials cannot be encrypted or decrypted
+var errCalDAVDisabled = errors.New("CalDAV accounts are disabled, CREDENTIALS_SECRET is not set")
+
+// caldavProvider syncs calendars of CalDAV servers. Changes are fetched with sync-collection
+// (RFC 6578) where the server supports it, otherwise the ctag of the collection tells whether
+// anything changed and the ETags of its resources tell what.
+type caldavProvider struct {
+	calendarRepo *repository.CalendarRepository
+	ics          *icsProvider
+	cipher       *encrypt.Cipher // Nil when CREDENTIALS_SECRET is not set
+	httpClient   *http.Client    // Refuses internal addresses, server URLs are user-supplied
+	logger       *zap.Logger
+}
+
+func newCalDAVProvider(calendarRepo *repository.CalendarRepository, icsProvider *icsProvider) *caldavProvider {
+	cipher, err := config.NewCredentialsCipher()
+	if err != nil {
+		zap.L().Debug("CalDAV accounts are disabled", zap.Error(err))
+	}
+
+	return &caldavProvider{
+		calendarRepo: calendarRepo,
+		ics:          icsProvider,
+		cipher:       cipher,
+		httpClient:   utils.NewPublicHTTPClient(caldavRequestTimeout),
+		logger:       zap.L(),
+	}
+}
+
+// Source returns the CalDAV calendar source
+func (p *caldavProvider) Source() model.CalendarSource {
+	return model.SourceCalDAV
+}
+
+// RefreshCredentials returns the decrypted credentials of the CalDAV account of a calendar
+func (p *caldavProvider) RefreshCredentials(ctx context.Context, calendar *model.Calendar) (*ProviderCredentials, error) {
+	if calendar.CalDAVAccountID == nil {
+		return nil, fmt.Errorf("calendar has no CalDAV account")
+	}
+
+	account, err := p.calendarRepo.FindCalDAVAccountByID(*calendar.CalDAVAccountID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get CalDAV account: %w", err)
+	}
+
+	return p.accountCredentials(account)
+}
+
+// accountCredentials decrypts the credentials of a CalDAV account
+func (p *caldavProvider) accountCredentials(account *model.CalDAVAccount) (*ProviderCredentials, error) {
+	if p.cipher == nil {
+		return nil, errCalDAVDisabled
+	}
+
+	password, err := p.cipher.Decrypt(account.EncryptedPassword)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt CalDAV password: %w", err)
+	}
+
+	return &ProviderCredentials{
+		Username: account.Username,
+		Password: password,
+		HomeURL:  account.CalendarHomeURL,
+	}, nil
+}
+
+// ListCalendars lists the calendars in the calendar home of a CalDAV account
+func (p *caldavProvider) ListCalendars(ctx context.Context, credentials *ProviderCredentials) ([]*RemoteCalendar, error) {
+	found, err := p.client(credentials).FindCalendars(ctx, credentials.HomeURL)
+	if err != nil {
+		return nil, err
+	}
+
+	calendars := make([]*RemoteCalendar, 0, len(found))
+	for _, calendar := range found {
+		summary := calendar.DisplayName
+		if summary == "" {
+			summary = calendarNameFromURL(calendar.URL)
+		}
+
+		calendars = append(calendars, &RemoteCalendar{
+			ID:          calendar.URL,
+			Summary:     summary,
+			Description: calendar.Description,
+			TimeZone:    calendar.TimeZone,
+			Color:       calendar.Color,
+		})
+	}
+
+	return calendars, nil
+}
+
+// FetchEvents fetches the events of a CalDAV calendar. A sync token the server no longer accepts
+// falls back to a full fetch.
+func (p *caldavProvider) FetchEvents(ctx context.Context, credentials *ProviderCredentials, calendar *model.Calendar, syncToken string) (*ProviderEvents, error) {
+	if calendar.SourceID == nil {
+		return nil, fmt.Errorf("calendar has no CalDAV calendar URL")
+	}
+	client := p.client(credentials)
+
+	switch {
+	case syncToken == "":
+		return p.fetchAll(ctx, client, calendar)
+	case strings.HasPrefix(syncToken, caldavCTagPrefix):
+		return p.fetchChangedResources(ctx, client, calendar, strings.TrimPrefix(syncToken, caldavCTagPrefix))
+	}
+
+	fetched, err := p.fetchSyncCollection(ctx, client, calendar, syncToken)
+	if errors.Is(err, caldav.ErrInvalidSyncToken) {
+		p.logger.Warn("Sync token invalid, performing full sync",
+			zap.Uint64("calendar_id", calendar.ID))
+		return p.fetchAll(ctx, client, calendar)
+	}
+	return fetched, err
+}
+
+// fetchAll fetches every event of a calendar, together with the token of the next sync
+func (p *caldavProvider) fetchAll(ctx context.Context, client *caldav.Client, calendar *model.Calendar) (*ProviderEvents, error) {
+	// The state is read first, so changes made while the events are fetched show up in the next sync
+	state, err := client.State(ctx, *calendar.SourceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch events from CalDAV server (full sync): %w", err)
+	}
+
+	objects, err := client.QueryEvents(ctx, *calendar.SourceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch events from CalDAV server (full sync): %w", err)
+	}
+
+	syncToken := state.SyncToken
+	if syncToken == "" && state.CTag != "" {
+		syncToken = caldavCTagPrefix + state.CTag
+	}
+
+	return &ProviderEvents{
+		Events:    p.convertObjects(objects, calendar),
+		FullSync:  true,
+		Complete:  true,
+		SyncToken: syncToken,
+	}, nil
+}
+
+// fetchSyncCollection fetches the resources changed since a sync-collection token
+func (p *caldavProvider) fetchSyncCollection(ctx context.Context, client *caldav.Client, calendar *model.Calendar, syncToken string) (*ProviderEvents, error) {
+	changes, err := client.SyncCollection(ctx, *calendar.SourceID, syncToken)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch events from CalDAV server (incremental sync): %w", err)
+	}
+
+	stored, err := p.storedResources(calendar.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	changed := make([]string, 0, len(changes.Changed))
+	for _, resource := range changes.Changed {
+		changed = append(changed, resource.URL)
+	}
+
+	return p.fetchResources(ctx, client, calendar, &ProviderEvents{SyncToken: changes.SyncToken}, stored, changed, changes.Deleted)
+}
+
+// fetchChangedResources compares the ETags of the resources of a calendar with the stored ones,
+// once the ctag shows the calendar changed
+func (p *caldavProvider) fetchChangedResources(ctx context.Context, client *caldav.Client, calendar *model.Calendar, ctag string) (*ProviderEvents, error) {
+	state, err := client.State(ctx, *calendar.SourceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch events from CalDAV server (incremental sync): %w", err)
+	}
+
+	fetched := &ProviderEvents{SyncToken: caldavCTagPrefix + state.CTag}
+	if state.CTag == ctag {
+		return fetched, nil
+	}
+
+	resources, err := client.ListResources(ctx, *calendar.SourceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch events from CalDAV server (incremental sync): %w", err)
+	}
+
+	stored, err := p.storedResources(calendar.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	listed := make(map[string]bool, len(resources))
+	var changed []string
+	for _, resource := range resources {
+		listed[resource.URL] = true
+		events := stored[resource.URL]
+		if len(events) == 0 || resource.ETag == "" || events[0].SourceETag != resource.ETag {
+			changed = append(changed, resource.URL)
+		}
+	}
+
+	// Stored resources the server no longer lists were deleted
+	var deleted []string
+	for resourceURL := range stored {
+		if !listed[resourceURL] {
+			deleted = append(deleted, resourceURL)
+		}
+	}
+
+	return p.fetchResources(ctx, client, calendar, fetched, stored, changed, deleted)
+}
+
+// storedResources returns the stored events of a calendar by the URL of the resource they came from
+func (p *caldavProvider) storedResources(calendarID uint64) (map[string][]*model.CalendarEvent, error) {
+	events, err := p.calendarRepo.FindEventsByCalendarID(calendarID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get existing events: %w", err)
+	}
+
+	resources := make(map[string][]*model.CalendarEvent)
+	for _, event := range events {
+		if event.SourceURL != "" {
+			resources[event.SourceURL] = append(resources[event.SourceURL], event)
+		}
+	}
+	return resources, nil
+}
+
+// fetchResources fetches changed resources into fetched. The events stored for changed and deleted
+// resources are marked deleted, so occurrences a changed resource no longer holds are removed too.
+func (p *caldavProvider) fetchResources(ctx context.Context, client *caldav.Client, calendar *model.Calendar, fetched *ProviderEvents, stored map[string][]*model.CalendarEvent, changed, deleted []string) (*ProviderEvents, error) {
+	for _, resourceURL := range append(append([]string{}, changed...), deleted...) {
+		for _, event := range stored[resourceURL] {
+			fetched.Deleted = append(fetched.Deleted, event.SourceID)
+		}
+	}
+
+	for start := 0; start < len(changed); start += caldavMultiGetBatchSize {
+		batch := changed[start:min(start+caldavMultiGetBatchSize, len(changed))]
+		objects, err := client.MultiGet(ctx, *calendar.SourceID, batch)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch events from CalDAV server (incremental sync): %w", err)
+		}
+		fetched.Events = append(fetched.Events, p.convertObjects(objects, calendar)...)
+	}
+
+	return fetched, nil
+}
+
+// convertObjects converts calendar objects to events, remembering the resource each event came from
+func (p *caldavProvider) convertObjects(objects []*caldav.Object, calendar *model.Calendar) []*model.CalendarEvent {
+	var events []*model.CalendarEvent
+	for _, object := range objects {
+		cal, err := ics.ParseCalendar(strings.NewReader(object.Data))
+		if err != nil {
+			p.logger.Warn("Failed to parse CalDAV resource",
+				zap.Error(err),
+				zap.String("url", object.URL))
+			continue
+		}
+
+		for _, event := range p.ics.convertICSEvents(cal.Events(), calendar.ID, p.ics.timeZones(cal, calendar.TimeZone)) {
+			event.SourceURL = object.URL
+			event.SourceETag = object.ETag
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
+func (p *caldavProvider) client(credentials *ProviderCredentials) *caldav.Client {
+	return caldav.NewClient(p.httpClient, credentials.Username, credentials.Password)
+}
+
+// calendarNameFromURL derives a name for calendars without a display name from the last segment
+// of their URL
+func calendarNameFromURL(calendarURL string) string {
+	parsed, err := url.Parse(calendarURL)
+	if err != nil {
+		return calendarURL
+	}
+	return path.Base(strings.TrimSuffix(parsed.Path, "/"))
+}
@@ -0,0 +1,807 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"golang.org/x/oauth2"
+
+	"github.com/NathanWasTaken/timely/backend/internal/config"
+	"github.com/NathanWasTaken/timely/backend/internal/model"
+	"github.com/NathanWasTaken/timely/backend/internal/repository"
+	"github.com/NathanWasTaken/timely/backend/pkg/ical"
+	"github.com/NathanWasTaken/timely/backend/pkg/utils"
+)
+
+const (
+	// googleCalendarAPIURL is the base URL of the Google Calendar API
+	googleCalendarAPIURL = "https://www.googleapis.com/calendar/v3"
+	// googleColorsCacheDuration is how long the color palette of an account is used before it is fetched again
+	googleColorsCacheDuration = 24 * time.Hour
+)
+
+// googleDefaultEventColors is the event palette of the Google Calendar API by colorId, used when the
+// palette of an account cannot be fetched
+var googleDefaultEventColors = map[string]string{
+	"1":  "#a4bdfc",
+	"2":  "#7ae7bf",
+	"3":  "#dbadff",
+	"4":  "#ff887c",
+	"5":  "#fbd75b",
+	"6":  "#ffb878",
+	"7":  "#46d6db",
+	"8":  "#e1e1e1",
+	"9":  "#5484ed",
+	"10": "#51b749",
+	"11": "#dc2127",
+}
+
+// googleProvider implements CalendarProvider for Google Calendar
+type googleProvider struct {
+	userRepo    *repository.UserRepository
+	oauthConfig *config.OAuthConfig
+	baseURL     string
+	logger      *zap.Logger
+
+	colorsMu sync.Mutex
+	colors   map[uint64]*googleEventColors // Event palettes by the ID of the user whose Google account they belong to
+}
+
+// googleEventColors is the event palette of a Google account, hex colors by colorId
+type googleEventColors struct {
+	colors    map[string]string
+	fetchedAt time.Time
+}
+
+func newGoogleProvider(userRepo *repository.UserRepository, oauthConfig *config.OAuthConfig) *googleProvider {
+	return &googleProvider{
+		userRepo:    userRepo,
+		oauthConfig: oauthConfig,
+		baseURL:     googleCalendarAPIURL,
+		logger:      zap.L(),
+		colors:      make(map[uint64]*googleEventColors),
+	}
+}
+
+// Source returns the Google calendar source
+func (p *googleProvider) Source() model.CalendarSource {
+	return model.SourceGoogle
+}
+
+// RefreshCredentials returns a valid access token of the Google account of the calendar's owner
+func (p *googleProvider) RefreshCredentials(ctx context.Context, calendar *model.Calendar) (*ProviderCredentials, error) {
+	return p.userCredentials(ctx, calendar.UserID)
+}
+
+// userCredentials returns a valid access token of the user's Google account
+func (p *googleProvider) userCredentials(ctx context.Context, userID uint64) (*ProviderCredentials, error) {
+	account, err := p.userRepo.FindGoogleAccountByUserID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get Google account: %w", err)
+	}
+
+	// Check if account has tokens
+	if account.AccessToken == nil || account.RefreshToken == nil {
+		return nil, fmt.Errorf("google account not properly configured with OAuth tokens")
+	}
+
+	// Check if token needs refresh
+	if err := p.refreshTokenIfNeeded(ctx, account); err != nil {
+		return nil, fmt.Errorf("failed to refresh token: %w", err)
+	}
+
+	return &ProviderCredentials{AccessToken: *account.AccessToken}, nil
+}
+
+// ListCalendars lists the calendars of the user's Google account
+func (p *googleProvider) ListCalendars(ctx context.Context, credentials *ProviderCredentials) ([]*RemoteCalendar, error) {
+	googleCalendars, err := p.fetchCalendarList(ctx, credentials.AccessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	calendars := make([]*RemoteCalendar, 0, len(googleCalendars))
+	for _, googleCalendar := range googleCalendars {
+		calendars = append(calendars, &RemoteCalendar{
+			ID:          googleCalendar.ID,
+			Summary:     googleCalendar.Summary,
+			Description: googleCalendar.Description,
+			TimeZone:    googleCalendar.TimeZone,
+			Color:       googleCalendar.BackgroundColor,
+		})
+	}
+
+	return calendars, nil
+}
+
+// FetchEvents fetches the events of a Google calendar. A sync token Google no longer accepts (410 Gone)
+// falls back to a full fetch.
+func (p *googleProvider) FetchEvents(ctx context.Context, credentials *ProviderCredentials, calendar *model.Calendar, syncToken string) (*ProviderEvents, error) {
+	if calendar.SourceID == nil {
+		return nil, fmt.Errorf("calendar has no Google calendar ID")
+	}
+	calendarID := *calendar.SourceID
+
+	fullSync := syncToken == ""
+	response, err := p.fetchAllEvents(ctx, credentials.AccessToken, calendarID, syncToken)
+	if err != nil && !fullSync && isSyncTokenInvalidError(err) {
+		p.logger.Warn("Sync token invalid, performing full sync",
+			zap.Error(err),
+			zap.Uint64("calendar_id", calendar.ID))
+
+		fullSync = true
+		response, err = p.fetchAllEvents(ctx, credentials.AccessToken, calendarID, "")
+	}
+	if err != nil {
+		if fullSync {
+			return nil, fmt.Errorf("failed to fetch events from Google (full sync): %w", err)
+		}
+		return nil, fmt.Errorf("failed to fetch events from Google (incremental sync): %w", err)
+	}
+
+	fetched := &ProviderEvents{
+		FullSync:  fullSync,
+		SyncToken: response.NextSyncToken,
+		// Full syncs only cover a window around today, so events outside of it are kept
+		Complete: false,
+	}
+	eventColors := p.eventColors(ctx, credentials.AccessToken, calendar.UserID)
+	fetched.Events, fetched.Deleted = p.convertGoogleEvents(response.Items, calendar.ID, eventColors)
+
+	return fetched, nil
+}
+
+// convertGoogleEvents converts the events of a Google events response, separating the source IDs of
+// deleted events. Event colors are resolved with eventColors, the event palette of the account.
+func (p *googleProvider) convertGoogleEvents(googleEvents []*model.GoogleCalendarEvent, calendarID uint64, eventColors map[string]string) ([]*model.CalendarEvent, []string) {
+	var events []*model.CalendarEvent
+	var deleted []string
+
+	for _, googleEvent := range googleEvents {
+		// Google Calendar API returns deleted events with status "cancelled"
+		if googleEvent.Status == "cancelled" {
+			deleted = append(deleted, googleEvent.ID)
+			continue
+		}
+
+		// Skip events without summary (some system events)
+		if googleEvent.Summary == "" {
+			continue
+		}
+
+		event, err := convertGoogleEventToCalendarEvent(googleEvent, calendarID, eventColors)
+		if err != nil {
+			p.logger.Error("Failed to convert Google event",
+				zap.Error(err),
+				zap.String("event_id", googleEvent.ID))
+			continue
+		}
+		events = append(events, event)
+	}
+
+	return events, deleted
+}
+
+// refreshTokenIfNeeded checks if the token is expired and refreshes it if necessary
+func (p *googleProvider) refreshTokenIfNeeded(ctx context.Context, account *model.Account) error {
+	// Always try to refresh if token is expired or close to expiring
+	needsRefresh := false
+
+	if account.Expiry == nil {
+		p.logger.Warn("Token has no expiry time, forcing refresh", zap.Uint64("user_id", account.UserID))
+		needsRefresh = true
+	} else if time.Now().Add(5 * time.Minute).After(*account.Expiry) {
+		p.logger.Info("Token is expired or expiring soon, refreshing",
+			zap.Uint64("user_id", account.UserID),
+			zap.Time("expiry", *account.Expiry))
+		needsRefresh = true
+	}
+
+	if !needsRefresh {
+		p.logger.Debug("Token is still valid", zap.Uint64("user_id", account.UserID))
+		return nil
+	}
+
+	p.logger.Info("Refreshing Google OAuth token", zap.Uint64("user_id", account.UserID))
+
+	// Validate we have refresh token
+	if account.RefreshToken == nil || *account.RefreshToken == "" {
+		return fmt.Errorf("no refresh token available for user %d", account.UserID)
+	}
+
+	// Create oauth2.Token for refresh
+	oauthToken := &oauth2.Token{
+		RefreshToken: *account.RefreshToken,
+	}
+
+	// Set access token if we have one (even if expired)
+	if account.AccessToken != nil {
+		oauthToken.AccessToken = *account.AccessToken
+	}
+
+	// Set expiry if we have one
+	if account.Expiry != nil {
+		oauthToken.Expiry = *account.Expiry
+	}
+
+	// Refresh the token using the OAuth config
+	tokenSource := p.oauthConfig.Google.TokenSource(ctx, oauthToken)
+
+	newToken, err := tokenSource.Token()
+	if err != nil {
+		p.logger.Error("Failed to refresh OAuth token",
+			zap.Error(err),
+			zap.Uint64("user_id", account.UserID))
+		return fmt.Errorf("failed to refresh token for user %d: %w", account.UserID, err)
+	}
+
+	// Validate new token
+	if newToken.AccessToken == "" {
+		return fmt.Errorf("received empty access token for user %d", account.UserID)
+	}
+
+	// Update the token in database
+	refreshToken := newToken.RefreshToken
+	if refreshToken == "" && account.RefreshToken != nil {
+		// Keep existing refresh token if new one is empty
+		refreshToken = *account.RefreshToken
+	}
+
+	scopes := grantedScopes(newToken)
+	if err := p.userRepo.UpdateGoogleAccountTokens(account.UserID, newToken.AccessToken, refreshToken, &newToken.Expiry, scopes); err != nil {
+		return fmt.Errorf("failed to update token in database: %w", err)
+	}
+
+	// Update the account object with new tokens for immediate use
+	account.AccessToken = &newToken.AccessToken
+	account.RefreshToken = &refreshToken
+	account.Expiry = &newToken.Expiry
+	if scopes != "" {
+		account.Scopes = &scopes
+	}
+
+	p.logger.Info("Successfully refreshed Google token",
+		zap.Uint64("user_id", account.UserID),
+		zap.Time("new_expiry", newToken.Expiry))
+
+	return nil
+}
+
+// client returns an HTTP client authenticating requests with an access token
+func (p *googleProvider) client(ctx context.Context, accessToken string) *http.Client {
+	return p.oauthConfig.Google.Client(ctx, &oauth2.Token{AccessToken: accessToken})
+}
+
+// fetchCalendarList calls the Google Calendar API to get the user's calendars
+func (p *googleProvider) fetchCalendarList(ctx context.Context, accessToken string) ([]*model.GoogleCalendar, error) {
+	resp, err := p.client(ctx, accessToken).Get(p.baseURL + "/users/me/calendarList")
+	if err != nil {
+		return nil, fmt.Errorf("failed to call Google Calendar API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("google Calendar API error: status %d, body: %s", resp.StatusCode, string(body))
+	}
+
+	// Parse response
+	var calendarList struct {
+		Items []*model.GoogleCalendar `json:"items"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&calendarList); err != nil {
+		return nil, fmt.Errorf("failed to decode Google Calendar API response: %w", err)
+	}
+
+	return calendarList.Items, nil
+}
+
+// eventColors returns the event palette of a user's Google account, fetching it at most once per
+// googleColorsCacheDuration. The default palette is used if it cannot be fetched.
+func (p *googleProvider) eventColors(ctx context.Context, accessToken string, userID uint64) map[string]string {
+	p.colorsMu.Lock()
+	cached, ok := p.colors[userID]
+	p.colorsMu.Unlock()
+	if ok && time.Since(cached.fetchedAt) < googleColorsCacheDuration {
+		return cached.colors
+	}
+
+	palette, err := p.fetchColors(ctx, accessToken)
+	if err != nil {
+		p.logger.Warn("Failed to fetch Google colors, using the default palette",
+			zap.Error(err),
+			zap.Uint64("user_id", userID))
+		return googleDefaultEventColors
+	}
+
+	colors := make(map[string]string, len(palette.Event))
+	for colorID, definition := range palette.Event {
+		if color, err := ical.ParseColor(definition.Background); err == nil {
+			colors[colorID] = color
+		}
+	}
+
+	p.colorsMu.Lock()
+	p.colors[userID] = &googleEventColors{colors: colors, fetchedAt: time.Now()}
+	p.colorsMu.Unlock()
+
+	return colors
+}
+
+// fetchColors calls the Google Calendar API to get the color palettes of the user's account
+func (p *googleProvider) fetchColors(ctx context.Context, accessToken string) (*model.GoogleColors, error) {
+	resp, err := p.client(ctx, accessToken).Get(p.baseURL + "/colors")
+	if err != nil {
+		return nil, fmt.Errorf("failed to call Google Calendar API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("google Calendar API error: status %d, body: %s", resp.StatusCode, string(body))
+	}
+
+	var colors model.GoogleColors
+	if err := json.NewDecoder(resp.Body).Decode(&colors); err != nil {
+		return nil, fmt.Errorf("failed to decode Google Calendar API response: %w", err)
+	}
+
+	return &colors, nil
+}
+
+// fetchCalendar finds a specific calendar in the user's calendar list, which unlike the calendar
+// itself includes its color
+func (p *googleProvider) fetchCalendar(ctx context.Context, accessToken, calendarID string) (*model.GoogleCalendar, error) {
+	calendars, err := p.fetchCalendarList(ctx, accessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, calendar := range calendars {
+		if calendar.ID == calendarID {
+			return calendar, nil
+		}
+	}
+
+	return nil, fmt.Errorf("calendar not found in list")
+}
+
+// fetchAllEvents fetches every page of events of a calendar. With a sync token only the changes since
+// the token was issued are fetched, otherwise the events of the year before and after today.
+func (p *googleProvider) fetchAllEvents(ctx context.Context, accessToken, calendarID, syncToken string) (*model.GoogleCalendarEventsResponse, error) {
+	var allEvents []*model.GoogleCalendarEvent
+	var nextSyncToken string
+	pageToken := ""
+
+	for {
+		response, err := p.fetchEventsPage(ctx, accessToken, calendarID, syncToken, pageToken)
+		if err != nil {
+			return nil, err
+		}
+
+		// Accumulate events
+		allEvents = append(allEvents, response.Items...)
+
+		// Store the sync token (only present on the last page)
+		if response.NextSyncToken != "" {
+			nextSyncToken = response.NextSyncToken
+		}
+
+		// Check if there are more pages
+		if response.NextPageToken == "" {
+			break
+		}
+
+		pageToken = response.NextPageToken
+	}
+
+	// Return consolidated response
+	return &model.GoogleCalendarEventsResponse{
+		Kind:          "calendar#events",
+		Items:         allEvents,
+		NextSyncToken: nextSyncToken,
+	}, nil
+}
+
+// fetchEventsPage fetches a single page of events
+func (p *googleProvider) fetchEventsPage(ctx context.Context, accessToken, calendarID, syncToken, pageToken string) (*model.GoogleCalendarEventsResponse, error) {
+	q := url.Values{}
+
+	if syncToken != "" {
+		// For incremental sync, use sync token
+		q.Add("syncToken", syncToken)
+	} else {
+		// For full sync, use time range
+		now := time.Now()
+		addEventsWindow(q, now.AddDate(-1, 0, 0), now.AddDate(1, 0, 0)) // 1 year of historical data, 1 year forward
+	}
+
+	return p.getEventsPage(ctx, accessToken, calendarID, pageToken, q)
+}
+
+// fetchEventsWindowPage fetches a single page of the events between timeMin and timeMax, with
+// recurring events expanded into their instances like a full sync
+func (p *googleProvider) fetchEventsWindowPage(ctx context.Context, accessToken, calendarID string, timeMin, timeMax time.Time, pageToken string) (*model.GoogleCalendarEventsResponse, error) {
+	q := url.Values{}
+	addEventsWindow(q, timeMin, timeMax)
+	return p.getEventsPage(ctx, accessToken, calendarID, pageToken, q)
+}
+
+// addEventsWindow adds the query parameters limiting an events request to a time range
+func addEventsWindow(q url.Values, timeMin, timeMax time.Time) {
+	q.Add("timeMin", timeMin.Format(time.RFC3339))
+	q.Add("timeMax", timeMax.Format(time.RFC3339))
+	q.Add("singleEvents", "true")
+	q.Add("orderBy", "startTime")
+}
+
+// getEventsPage requests a page of events with the given query parameters
+func (p *googleProvider) getEventsPage(ctx context.Context, accessToken, calendarID, pageToken string, q url.Values) (*model.GoogleCalendarEventsResponse, error) {
+	endpoint := fmt.Sprintf("%s/calendars/%s/events", p.baseURL, calendarID)
+	req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	if pageToken != "" {
+		q.Add("pageToken", pageToken)
+	}
+
+	// CRITICAL: Include deleted events in response for proper sync
+	q.Add("showDeleted", "true")
+	q.Add("maxResults", "2500") // Maximum allowed by Google
+	req.URL.RawQuery = q.Encode()
+
+	resp, err := p.client(ctx, accessToken).Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call Google Calendar API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("google Calendar API error: status %d, body: %s", resp.StatusCode, string(body))
+	}
+
+	// Parse response
+	var eventsResponse model.GoogleCalendarEventsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&eventsResponse); err != nil {
+		return nil, fmt.Errorf("failed to decode Google Calendar API response: %w", err)
+	}
+
+	return &eventsResponse, nil
+}
+
+// post sends a JSON request to the Google Calendar API and decodes the response into response,
+// unless it is nil
+func (p *googleProvider) post(ctx context.Context, accessToken, endpoint string, request, response interface{}) error {
+	return p.send(ctx, accessToken, http.MethodPost, endpoint, "", request, response)
+}
+
+// send sends a request to the Google Calendar API, with a JSON body unless request is nil, and
+// decodes the response into response, unless it is nil. A non-empty ifMatch makes the request
+// conditional on the ETag of the resource.
+func (p *googleProvider) send(ctx context.Context, accessToken, method, endpoint, ifMatch string, request, response interface{}) error {
+	var body io.Reader
+	if request != nil {
+		encoded, err := json.Marshal(request)
+		if err != nil {
+			return fmt.Errorf("failed to encode request: %w", err)
+		}
+		body = bytes.NewReader(encoded)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, body)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	if request != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if ifMatch != "" {
+		req.Header.Set("If-Match", ifMatch)
+	}
+
+	resp, err := p.client(ctx, accessToken).Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call Google Calendar API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("google Calendar API error: status %d, body: %s", resp.StatusCode, string(body))
+	}
+
+	if response == nil {
+		return nil
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(response); err != nil {
+		return fmt.Errorf("failed to decode Google Calendar API response: %w", err)
+	}
+
+	return nil
+}
+
+// insertEvent creates an event in a Google calendar under the event's source ID and returns the
+// ETag of the created event
+func (p *googleProvider) insertEvent(ctx context.Context, accessToken string, calendar *model.Calendar, event *model.CalendarEvent) (string, error) {
+	request := convertCalendarEventToGoogleEvent(event)
+	request.ID = event.SourceID
+
+	var created model.GoogleCalendarEvent
+	if err := p.send(ctx, accessToken, http.MethodPost, p.eventsURL(calendar), "", request, &created); err != nil {
+		return "", err
+	}
+	return created.ETag, nil
+}
+
+// patchEvent writes the fields Timely manages onto an event of a Google calendar, leaving the
+// fields it does not know about, such as attendees and reminders, as they are. The change is only
+// applied if the event still has the ETag stored with it. Returns the new ETag of the event.
+func (p *googleProvider) patchEvent(ctx context.Context, accessToken string, calendar *model.Calendar, event *model.CalendarEvent) (string, error) {
+	var patched model.GoogleCalendarEvent
+	endpoint := p.eventsURL(calendar) + "/" + url.PathEscape(event.SourceID)
+	if err := p.send(ctx, accessToken, http.MethodPatch, endpoint, event.SourceETag, convertCalendarEventToGoogleEvent(event), &patched); err != nil {
+		return "", err
+	}
+	return patched.ETag, nil
+}
+
+// getEvent fetches a single event of a Google calendar
+func (p *googleProvider) getEvent(ctx context.Context, accessToken string, calendar *model.Calendar, sourceID string) (*model.GoogleCalendarEvent, error) {
+	var event model.GoogleCalendarEvent
+	endpoint := p.eventsURL(calendar) + "/" + url.PathEscape(sourceID)
+	if err := p.send(ctx, accessToken, http.MethodGet, endpoint, "", nil, &event); err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+// deleteEvent deletes an event of a Google calendar, if it still has the given ETag
+func (p *googleProvider) deleteEvent(ctx context.Context, accessToken string, calendar *model.Calendar, sourceID, etag string) error {
+	endpoint := p.eventsURL(calendar) + "/" + url.PathEscape(sourceID)
+	return p.send(ctx, accessToken, http.MethodDelete, endpoint, etag, nil, nil)
+}
+
+// eventsURL returns the URL of the events collection of a Google calendar
+func (p *googleProvider) eventsURL(calendar *model.Calendar) string {
+	sourceID := ""
+	if calendar.SourceID != nil {
+		sourceID = *calendar.SourceID
+	}
+	return p.baseURL + "/calendars/" + url.PathEscape(sourceID) + "/events"
+}
+
+// googleEventWrite is the body of requests writing an event to Google. Times are pointers, so
+// switching between timed and all-day events clears the other kind of time with null.
+type googleEventWrite struct {
+	ID          string                `json:"id,omitempty"`
+	Summary     string                `json:"summary"`
+	Description string                `json:"description"`
+	Location    string                `json:"location"`
+	Start       *googleEventWriteTime `json:"start"`
+	End         *googleEventWriteTime `json:"end"`
+	// Status and transparency, Timely's values are those of the Google Calendar API
+	Status       string `json:"status,omitempty"`
+	Transparency string `json:"transparency,omitempty"`
+}
+
+type googleEventWriteTime struct {
+	Date     *string `json:"date"`
+	DateTime *string `json:"dateTime"`
+}
+
+// convertCalendarEventToGoogleEvent converts the fields of an event Timely manages to the body of
+// a Google Calendar API request
+func convertCalendarEventToGoogleEvent(event *model.CalendarEvent) *googleEventWrite {
+	return &googleEventWrite{
+		Summary:      event.Title,
+		Description:  event.Description,
+		Location:     event.Location,
+		Start:        googleEventTime(event.Start, event.AllDay),
+		End:          googleEventTime(event.End, event.AllDay),
+		Status:       string(event.Status),
+		Transparency: string(event.Transparency),
+	}
+}
+
+func googleEventTime(t time.Time, allDay bool) *googleEventWriteTime {
+	if allDay {
+		date := t.Format("2006-01-02")
+		return &googleEventWriteTime{Date: &date}
+	}
+	dateTime := t.Format(time.RFC3339)
+	return &googleEventWriteTime{DateTime: &dateTime}
+}
+
+// convertGoogleEventToCalendarEvent converts a Google Calendar event to our CalendarEvent model,
+// resolving its colorId with eventColors. Events without a color are left without one, they are
+// shown in the color of their calendar.
+func convertGoogleEventToCalendarEvent(googleEvent *model.GoogleCalendarEvent, calendarID uint64, eventColors map[string]string) (*model.CalendarEvent, error) {
+	if googleEvent.Start == nil || googleEvent.End == nil {
+		return nil, fmt.Errorf("event has no start or end time")
+	}
+
+	// Parse start time
+	var startTime time.Time
+	var allDay bool
+
+	if googleEvent.Start.DateTime != "" {
+		// Timed event
+		parsedTime, err := time.Parse(time.RFC3339, googleEvent.Start.DateTime)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse start time: %w", err)
+		}
+		startTime = parsedTime
+		allDay = false
+	} else if googleEvent.Start.Date != "" {
+		// All-day event
+		parsedDate, err := time.Parse("2006-01-02", googleEvent.Start.Date)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse start date: %w", err)
+		}
+		startTime = parsedDate
+		allDay = true
+	} else {
+		return nil, fmt.Errorf("event has no start time")
+	}
+
+	// Parse end time
+	var endTime time.Time
+	if googleEvent.End.DateTime != "" {
+		// Timed event
+		parsedTime, err := time.Parse(time.RFC3339, googleEvent.End.DateTime)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse end time: %w", err)
+		}
+		endTime = parsedTime
+	} else if googleEvent.End.Date != "" {
+		// All-day event
+		parsedDate, err := time.Parse("2006-01-02", googleEvent.End.Date)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse end date: %w", err)
+		}
+		endTime = parsedDate
+	} else {
+		return nil, fmt.Errorf("event has no end time")
+	}
+
+	// Create calendar event
+	event := &model.CalendarEvent{
+		ID:          utils.GenerateID(),
+		SourceID:    googleEvent.ID,
+		SourceETag:  googleEvent.ETag,
+		CalendarID:  calendarID,
+		Title:       googleEvent.Summary,
+		Start:       startTime,
+		End:         endTime,
+		AllDay:      allDay,
+		EventColor:  eventColors[googleEvent.ColorID],
+		Location:    googleEvent.Location,
+		Description: googleEvent.Description,
+		Visibility:  model.CalendarEventVisibilityInherited,
+		Attendees:   convertGoogleAttendees(googleEvent),
+		// Cancelled events are deleted, so events are either confirmed or tentative
+		Status:       googleEventStatus(googleEvent.Status),
+		Transparency: googleEventTransparency(googleEvent.Transparency),
+	}
+	setConferenceLink(event, googleConferenceLinks(googleEvent), googleEvent.Location, googleEvent.Description)
+
+	return event, nil
+}
+
+// convertGoogleAttendees converts the guests and organizer of a Google event. Google lists the
+// calendar itself as the organizer of events without guests, it is left out then.
+func convertGoogleAttendees(googleEvent *model.GoogleCalendarEvent) []*model.EventAttendee {
+	var attendees []*model.EventAttendee
+	organizerListed := false
+	for _, guest := range googleEvent.Attendees {
+		if guest == nil || guest.Email == "" {
+			continue
+		}
+
+		role := model.EventAttendeeRoleRequired
+		if guest.Optional {
+			role = model.EventAttendeeRoleOptional
+		}
+		attendees = append(attendees, &model.EventAttendee{
+			ID:          utils.GenerateID(),
+			Email:       guest.Email,
+			DisplayName: guest.DisplayName,
+			Role:        role,
+			Status:      googleAttendeeStatus(guest.ResponseStatus),
+			Organizer:   guest.Organizer,
+			Self:        guest.Self,
+		})
+		organizerListed = organizerListed || guest.Organizer
+	}
+
+	organizer := googleEvent.Organizer
+	if organizer == nil || organizer.Email == "" || organizerListed || (len(attendees) == 0 && organizer.Self) {
+		return attendees
+	}
+
+	return append([]*model.EventAttendee{{
+		ID:          utils.GenerateID(),
+		Email:       organizer.Email,
+		DisplayName: organizer.DisplayName,
+		Role:        model.EventAttendeeRoleRequired,
+		Status:      model.EventAttendeeStatusAccepted,
+		Organizer:   true,
+		Self:        organizer.Self,
+	}}, attendees...)
+}
+
+// googleAttendeeStatus converts the responseStatus of a Google attendee
+func googleAttendeeStatus(responseStatus string) model.EventAttendeeStatus {
+	switch responseStatus {
+	case "accepted":
+		return model.EventAttendeeStatusAccepted
+	case "declined":
+		return model.EventAttendeeStatusDeclined
+	case "tentative":
+		return model.EventAttendeeStatusTentative
+	default:
+		return model.EventAttendeeStatusNeedsAction
+	}
+}
+
+// googleEventStatus converts the status of a Google event that is not cancelled
+func googleEventStatus(status string) model.CalendarEventStatus {
+	if status == "tentative" {
+		return model.CalendarEventStatusTentative
+	}
+	return model.CalendarEventStatusConfirmed
+}
+
+// googleEventTransparency converts the transparency of a Google event, opaque being the default
+func googleEventTransparency(transparency string) model.CalendarEventTransparency {
+	if transparency == "transparent" {
+		return model.CalendarEventTransparencyTransparent
+	}
+	return model.CalendarEventTransparencyOpaque
+}
+
+// isSyncTokenInvalidError checks if an error indicates sync token invalidation (410 Gone)
+func isSyncTokenInvalidError(err error) bool {
+	if err == nil {
+		return false
+	}
+	errStr := err.Error()
+	return strings.Contains(errStr, "status 410") || strings.Contains(errStr, "Gone")
+}
+
+// isGoogleNotFoundError checks if an error is a 404 response of the Google Calendar API
+func isGoogleNotFoundError(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "status 404")
+}
+
+// isGoogleGoneError checks if an error is a 410 response of the Google Calendar API, returned for
+// events that were already deleted
+func isGoogleGoneError(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "status 410")
+}
+
+// isGooglePreconditionFailedError checks if an error is a 412 response of the Google Calendar API,
+// returned when an event changed since the ETag sent with If-Match was fetched
+func isGooglePreconditionFailedError(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "status 412")
+}
+
+// isGoogleConflictError checks if an error is a 409 response of the Google Calendar API, returned
+// when an event is created with an ID that already exists
+func isGoogleConflictError(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "status 409")
+}
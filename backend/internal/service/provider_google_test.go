@@ -0,0 +1,358 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2"
+
+	"github.com/NathanWasTaken/timely/backend/internal/config"
+	"github.com/NathanWasTaken/timely/backend/internal/model"
+	"github.com/NathanWasTaken/timely/backend/pkg/utils"
+)
+
+// googleStandIn serves the parts of the Google Calendar API Timely uses for a single calendar:
+// listing and writing events, watch channels and the color palette. Writes honour If-Match.
+type googleStandIn struct {
+	mu      sync.Mutex
+	events  map[string]*model.GoogleCalendarEvent
+	order   []string
+	version int
+	colors  map[string]string // Event palette served by /colors, which fails without one
+
+	// Listings
+	requests  int         // Event listings received
+	pageSize  int         // Events per page of a listing, all of them if zero
+	window    bool        // Only list the events within the requested timeMin and timeMax
+	failFirst bool        // Fail the first listing with 500
+	onRequest func(n int) // Called with the number of the listing before it is answered
+
+	// Writes
+	ifMatch  []string // If-Match headers of the writes received, in order
+	conflict bool     // Reject every conditional write with 412
+	onUpdate func()   // Called once before the next update is answered
+
+	// Watch channels
+	tokens   map[string]string // Channel ID to token
+	stopped  []string
+	channels int
+}
+
+func newGoogleStandIn(t *testing.T) (*googleStandIn, *httptest.Server) {
+	t.Helper()
+
+	g := &googleStandIn{events: map[string]*model.GoogleCalendarEvent{}, tokens: map[string]string{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /calendars/{id}/events", func(w http.ResponseWriter, r *http.Request) {
+		g.mu.Lock()
+		g.requests++
+		n, onRequest, fail := g.requests, g.onRequest, g.failFirst && g.requests == 1
+		g.mu.Unlock()
+
+		if onRequest != nil {
+			onRequest(n)
+		}
+		if fail {
+			http.Error(w, "backend error", http.StatusInternalServerError)
+			return
+		}
+
+		query := r.URL.Query()
+		timeMin, _ := time.Parse(time.RFC3339, query.Get("timeMin"))
+		timeMax, _ := time.Parse(time.RFC3339, query.Get("timeMax"))
+		offset, _ := strconv.Atoi(query.Get("pageToken"))
+
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		var matching []*model.GoogleCalendarEvent
+		for _, id := range g.order {
+			event := g.events[id]
+			if g.window {
+				start, _ := time.Parse(time.RFC3339, event.Start.DateTime)
+				end, _ := time.Parse(time.RFC3339, event.End.DateTime)
+				if !start.Before(timeMax) || !end.After(timeMin) {
+					continue
+				}
+			}
+			matching = append(matching, event)
+		}
+
+		response := &model.GoogleCalendarEventsResponse{Kind: "calendar#events"}
+		pageEnd := len(matching)
+		if g.pageSize > 0 {
+			pageEnd = min(offset+g.pageSize, len(matching))
+		}
+		response.Items = matching[min(offset, pageEnd):pageEnd]
+		if pageEnd < len(matching) {
+			response.NextPageToken = strconv.Itoa(pageEnd)
+		} else {
+			response.NextSyncToken = fmt.Sprintf("sync-%d", g.version)
+		}
+		json.NewEncoder(w).Encode(response)
+	})
+	mux.HandleFunc("GET /calendars/{id}/events/{eventId}", func(w http.ResponseWriter, r *http.Request) {
+		g.mu.Lock()
+		defer g.mu.Unlock()
+
+		event, ok := g.events[r.PathValue("eventId")]
+		if !ok {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(event)
+	})
+	mux.HandleFunc("GET /colors", func(w http.ResponseWriter, r *http.Request) {
+		g.mu.Lock()
+		defer g.mu.Unlock()
+
+		if g.colors == nil {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		response := &model.GoogleColors{Kind: "calendar#colors", Event: map[string]*model.GoogleColorDefinition{}}
+		for id, background := range g.colors {
+			response.Event[id] = &model.GoogleColorDefinition{Background: background, Foreground: "#1d1d1d"}
+		}
+		json.NewEncoder(w).Encode(response)
+	})
+	mux.HandleFunc("POST /calendars/{id}/events", func(w http.ResponseWriter, r *http.Request) {
+		var request googleEventWrite
+		json.NewDecoder(r.Body).Decode(&request)
+
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		if _, ok := g.events[request.ID]; ok {
+			http.Error(w, "duplicate", http.StatusConflict)
+			return
+		}
+		event := &model.GoogleCalendarEvent{ID: request.ID, Status: "confirmed"}
+		g.order = append(g.order, request.ID)
+		g.events[request.ID] = event
+		g.apply(event, &request)
+		json.NewEncoder(w).Encode(event)
+	})
+	mux.HandleFunc("PATCH /calendars/{id}/events/{eventId}", func(w http.ResponseWriter, r *http.Request) {
+		var request googleEventWrite
+		json.NewDecoder(r.Body).Decode(&request)
+
+		g.mu.Lock()
+		onUpdate := g.onUpdate
+		g.onUpdate = nil
+		g.mu.Unlock()
+		if onUpdate != nil {
+			onUpdate()
+		}
+
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		event, ok := g.write(w, r)
+		if !ok {
+			return
+		}
+		g.apply(event, &request)
+		json.NewEncoder(w).Encode(event)
+	})
+	mux.HandleFunc("DELETE /calendars/{id}/events/{eventId}", func(w http.ResponseWriter, r *http.Request) {
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		event, ok := g.write(w, r)
+		if !ok {
+			return
+		}
+		g.version++
+		event.Status = "cancelled"
+		event.ETag = fmt.Sprintf(`"%d"`, g.version)
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("POST /calendars/{id}/events/watch", func(w http.ResponseWriter, r *http.Request) {
+		var channel model.GoogleWatchChannel
+		if err := json.NewDecoder(r.Body).Decode(&channel); err != nil || channel.Type != "web_hook" || channel.Address == "" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+
+		g.mu.Lock()
+		g.tokens[channel.ID] = channel.Token
+		g.channels++
+		resourceID := fmt.Sprintf("resource-%d", g.channels)
+		g.mu.Unlock()
+
+		json.NewEncoder(w).Encode(&model.GoogleWatchChannel{
+			ID:         channel.ID,
+			ResourceID: resourceID,
+			Expiration: strconv.FormatInt(time.Now().Add(watchChannelTTL).UnixMilli(), 10),
+		})
+	})
+	mux.HandleFunc("POST /channels/stop", func(w http.ResponseWriter, r *http.Request) {
+		var channel model.GoogleWatchChannel
+		json.NewDecoder(r.Body).Decode(&channel)
+
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		g.stopped = append(g.stopped, channel.ID)
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return g, server
+}
+
+// write checks the preconditions of a write to an existing event
+func (g *googleStandIn) write(w http.ResponseWriter, r *http.Request) (*model.GoogleCalendarEvent, bool) {
+	g.ifMatch = append(g.ifMatch, r.Header.Get("If-Match"))
+
+	event, ok := g.events[r.PathValue("eventId")]
+	if !ok || event.Status == "cancelled" {
+		http.Error(w, "not found", http.StatusNotFound)
+		return nil, false
+	}
+	if ifMatch := r.Header.Get("If-Match"); ifMatch != "" && (g.conflict || ifMatch != event.ETag) {
+		http.Error(w, "precondition failed", http.StatusPreconditionFailed)
+		return nil, false
+	}
+	return event, true
+}
+
+func (g *googleStandIn) apply(event *model.GoogleCalendarEvent, request *googleEventWrite) {
+	g.version++
+	event.ETag = fmt.Sprintf(`"%d"`, g.version)
+	event.Summary = request.Summary
+	event.Location = request.Location
+	event.Start = &model.GoogleCalendarEventTime{DateTime: *request.Start.DateTime}
+	event.End = &model.GoogleCalendarEventTime{DateTime: *request.End.DateTime}
+}
+
+// put stores an event created in Google Calendar, g.mu must be held
+func (g *googleStandIn) put(event *model.GoogleCalendarEvent) {
+	g.version++
+	event.ETag = fmt.Sprintf(`"%d"`, g.version)
+	if _, ok := g.events[event.ID]; !ok {
+		g.order = append(g.order, event.ID)
+	}
+	g.events[event.ID] = event
+}
+
+// add creates an hour long event as if it was created in Google Calendar
+func (g *googleStandIn) add(id, summary, start, status string) {
+	startTime, _ := time.Parse(time.RFC3339, start)
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.put(&model.GoogleCalendarEvent{
+		ID:      id,
+		Summary: summary,
+		Status:  status,
+		Start:   &model.GoogleCalendarEventTime{DateTime: start},
+		End:     &model.GoogleCalendarEventTime{DateTime: startTime.Add(time.Hour).Format(time.RFC3339)},
+	})
+}
+
+// edit changes the title of an event as if it was edited in Google Calendar, creating it if needed
+func (g *googleStandIn) edit(id, summary string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	event, ok := g.events[id]
+	if !ok {
+		event = &model.GoogleCalendarEvent{
+			ID:     id,
+			Status: "confirmed",
+			Start:  &model.GoogleCalendarEventTime{DateTime: "2024-07-01T09:00:00Z"},
+			End:    &model.GoogleCalendarEventTime{DateTime: "2024-07-01T09:30:00Z"},
+		}
+	}
+	event.Summary = summary
+	g.put(event)
+}
+
+// relocate changes the location of an event as if it was edited in Google Calendar
+func (g *googleStandIn) relocate(id, location string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.events[id].Location = location
+	g.put(g.events[id])
+}
+
+func (g *googleStandIn) event(id string) model.GoogleCalendarEvent {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if event, ok := g.events[id]; ok {
+		return *event
+	}
+	return model.GoogleCalendarEvent{}
+}
+
+func (g *googleStandIn) takeIfMatch() []string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	ifMatch := g.ifMatch
+	g.ifMatch = nil
+	return ifMatch
+}
+
+func (g *googleStandIn) token(channelID string) string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.tokens[channelID]
+}
+
+func (g *googleStandIn) stoppedChannels() []string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return append([]string(nil), g.stopped...)
+}
+
+// newGoogleTestCalendar creates a Google calendar of a user with a connected Google account, served
+// by the stand-in at serverURL
+func newGoogleTestCalendar(t *testing.T, calendarService *CalendarService, serverURL string) *model.Calendar {
+	t.Helper()
+
+	calendarService.google.baseURL = serverURL
+	calendarService.google.oauthConfig = &config.OAuthConfig{Google: &oauth2.Config{}}
+
+	userID := utils.GenerateID()
+	accessToken, refreshToken := "access", "refresh"
+	expiry := time.Now().Add(time.Hour)
+	if err := calendarService.userRepo.Create(&model.User{ID: userID, Username: "robin"}); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	if err := calendarService.userRepo.CreateAccount(&model.Account{
+		ID:           utils.GenerateID(),
+		UserID:       userID,
+		Provider:     "google",
+		ProviderID:   "robin",
+		AccessToken:  &accessToken,
+		RefreshToken: &refreshToken,
+		Expiry:       &expiry,
+	}); err != nil {
+		t.Fatalf("Failed to create account: %v", err)
+	}
+
+	sourceID := "robin@example.com"
+	calendar := &model.Calendar{ID: utils.GenerateID(), UserID: userID, SourceID: &sourceID, Source: model.SourceGoogle, Summary: "Work", TimeZone: "UTC"}
+	if err := calendarService.calendarRepo.Create(calendar); err != nil {
+		t.Fatalf("Failed to create calendar: %v", err)
+	}
+	return calendar
+}
+
+// syncGoogleTestCalendar syncs the calendar and returns the result
+func syncGoogleTestCalendar(t *testing.T, calendarService *CalendarService, calendar *model.Calendar) *SyncResult {
+	t.Helper()
+
+	stored, err := calendarService.calendarRepo.FindByID(fmt.Sprint(calendar.ID))
+	if err != nil {
+		t.Fatalf("Failed to find calendar: %v", err)
+	}
+	result, err := calendarService.SyncCalendar(stored, false)
+	if err != nil {
+		t.Fatalf("SyncCalendar failed: %v", err)
+	}
+	return result
+}
@@ -0,0 +1,129 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+
+	ics "github.com/arran4/golang-ical"
+	"go.uber.org/zap"
+
+	"github.com/NathanWasTaken/timely/backend/internal/model"
+	"github.com/NathanWasTaken/timely/backend/pkg/utils"
+)
+
+// icsProvider fetches the events of calendars subscribed to a remote ICS feed. Uploaded ICS files
+// are parsed with the same conversion.
+type icsProvider struct {
+	httpClient *http.Client
+	logger     *zap.Logger
+}
+
+// icsFeed represents a fetched remote ICS feed
+type icsFeed struct {
+	Calendar     *ics.Calendar
+	ETag         string
+	LastModified string
+	NotModified  bool
+}
+
+func newICSProvider() *icsProvider {
+	return &icsProvider{
+		// Feed URLs are user-supplied, so they must not reach internal addresses
+		httpClient: utils.NewPublicHTTPClient(icsFetchTimeout),
+		logger:     zap.L(),
+	}
+}
+
+// Source returns the ICS calendar source
+func (p *icsProvider) Source() model.CalendarSource {
+	return model.SourceICS
+}
+
+// RefreshCredentials returns empty credentials, feeds are fetched anonymously
+func (p *icsProvider) RefreshCredentials(ctx context.Context, calendar *model.Calendar) (*ProviderCredentials, error) {
+	return &ProviderCredentials{}, nil
+}
+
+// ListCalendars is not supported, a feed is a single calendar
+func (p *icsProvider) ListCalendars(ctx context.Context, credentials *ProviderCredentials) ([]*RemoteCalendar, error) {
+	return nil, errCalendarListingUnsupported
+}
+
+// FetchEvents fetches the subscription feed of a calendar. Feeds have no sync tokens, so every
+// fetch returns the complete calendar unless the feed is unchanged since the last fetch.
+func (p *icsProvider) FetchEvents(ctx context.Context, credentials *ProviderCredentials, calendar *model.Calendar, syncToken string) (*ProviderEvents, error) {
+	if calendar.SubscriptionURL == nil {
+		return nil, fmt.Errorf("calendar is not an ICS subscription")
+	}
+
+	feed, err := p.fetchFeed(ctx, *calendar.SubscriptionURL, calendar.SubscriptionETag, calendar.SubscriptionLastModified)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch ICS feed: %w", err)
+	}
+
+	if feed.NotModified {
+		return &ProviderEvents{NotModified: true}, nil
+	}
+
+	zones := p.timeZones(feed.Calendar, calendar.TimeZone)
+	return &ProviderEvents{
+		Events:       p.convertICSEvents(feed.Calendar.Events(), calendar.ID, zones),
+		FullSync:     true,
+		Complete:     true,
+		ETag:         feed.ETag,
+		LastModified: feed.LastModified,
+	}, nil
+}
+
+// fetchFeed requests a remote ICS feed, sending the stored validators so an unchanged feed is
+// answered with 304 Not Modified
+func (p *icsProvider) fetchFeed(ctx context.Context, feedURL string, etag, lastModified *string) (*icsFeed, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", feedURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Accept", "text/calendar")
+	if etag != nil && *etag != "" {
+		req.Header.Set("If-None-Match", *etag)
+	}
+	if lastModified != nil && *lastModified != "" {
+		req.Header.Set("If-Modified-Since", *lastModified)
+	}
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified {
+		return &icsFeed{NotModified: true}, nil
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxICSFeedSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if len(data) > maxICSFeedSize {
+		return nil, fmt.Errorf("feed exceeds %d bytes", maxICSFeedSize)
+	}
+
+	cal, err := ics.ParseCalendar(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse ICS data: %w", err)
+	}
+
+	return &icsFeed{
+		Calendar:     cal,
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+	}, nil
+}
@@ -0,0 +1,196 @@
+package service
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/NathanWasTaken/timely/backend/internal/model"
+	"github.com/NathanWasTaken/timely/backend/pkg/utils"
+)
+
+const sourceFake model.CalendarSource = "fake"
+
+// fakeProvider serves calendars from memory, issuing a sync token per change like Google does
+type fakeProvider struct {
+	mu         sync.Mutex
+	titles     map[string]string // Event source ID to title
+	changes    []string          // Source IDs in the order they changed, the sync token is an index into it
+	syncTokens []string          // Sync tokens FetchEvents was called with
+}
+
+func newFakeProvider() *fakeProvider {
+	return &fakeProvider{titles: map[string]string{}}
+}
+
+func (p *fakeProvider) Source() model.CalendarSource {
+	return sourceFake
+}
+
+func (p *fakeProvider) RefreshCredentials(ctx context.Context, calendar *model.Calendar) (*ProviderCredentials, error) {
+	return &ProviderCredentials{AccessToken: "fake"}, nil
+}
+
+func (p *fakeProvider) ListCalendars(ctx context.Context, credentials *ProviderCredentials) ([]*RemoteCalendar, error) {
+	return []*RemoteCalendar{{ID: "fake-calendar", Summary: "Fake"}}, nil
+}
+
+func (p *fakeProvider) FetchEvents(ctx context.Context, credentials *ProviderCredentials, calendar *model.Calendar, syncToken string) (*ProviderEvents, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.syncTokens = append(p.syncTokens, syncToken)
+
+	fetched := &ProviderEvents{SyncToken: strconv.Itoa(len(p.changes))}
+	changed := map[string]bool{}
+	if syncToken == "" {
+		fetched.FullSync = true
+		fetched.Complete = true
+		for sourceID := range p.titles {
+			changed[sourceID] = true
+		}
+	} else {
+		since, err := strconv.Atoi(syncToken)
+		if err != nil {
+			return nil, err
+		}
+		for _, sourceID := range p.changes[since:] {
+			changed[sourceID] = true
+		}
+	}
+
+	start := time.Date(2024, 7, 1, 10, 0, 0, 0, time.UTC)
+	for sourceID := range changed {
+		title, ok := p.titles[sourceID]
+		if !ok {
+			fetched.Deleted = append(fetched.Deleted, sourceID)
+			continue
+		}
+		fetched.Events = append(fetched.Events, &model.CalendarEvent{
+			ID:         utils.GenerateID(),
+			SourceID:   sourceID,
+			CalendarID: calendar.ID,
+			Title:      title,
+			Start:      start,
+			End:        start.Add(time.Hour),
+		})
+	}
+
+	return fetched, nil
+}
+
+// set creates or renames an event, an empty title deletes it
+func (p *fakeProvider) set(sourceID, title string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if title == "" {
+		delete(p.titles, sourceID)
+	} else {
+		p.titles[sourceID] = title
+	}
+	p.changes = append(p.changes, sourceID)
+}
+
+// eventTitles returns the titles of the stored events of a calendar by source ID
+func eventTitles(t *testing.T, calendarService *CalendarService, calendarID uint64) map[string]string {
+	t.Helper()
+
+	events, err := calendarService.calendarRepo.FindEventsByCalendarID(calendarID)
+	if err != nil {
+		t.Fatalf("Failed to find events: %v", err)
+	}
+	titles := map[string]string{}
+	for _, event := range events {
+		titles[event.SourceID] = event.Title
+	}
+	return titles
+}
+
+func TestSyncCalendarWithProvider(t *testing.T) {
+	calendarService, calendarRepo := newTestCalendarService(t)
+	fake := newFakeProvider()
+	calendarService.RegisterProvider(fake)
+
+	if !calendarService.isScheduledSource(sourceFake) {
+		t.Error("Expected registered provider to be synced by the scheduler")
+	}
+
+	sourceID := "fake-calendar"
+	calendar := &model.Calendar{ID: utils.GenerateID(), UserID: 51, SourceID: &sourceID, Source: sourceFake, Summary: "Fake"}
+	if err := calendarRepo.Create(calendar); err != nil {
+		t.Fatalf("Failed to create calendar: %v", err)
+	}
+
+	fake.set("standup", "Standup")
+	fake.set("review", "Review")
+
+	// The first sync fetches every event
+	result, err := calendarService.SyncCalendar(calendar, false)
+	if err != nil {
+		t.Fatalf("SyncCalendar failed: %v", err)
+	}
+	if result.Added != 2 || result.Updated != 0 || result.Removed != 0 {
+		t.Errorf("Expected 2 added events, got %+v", result)
+	}
+
+	// Later syncs only fetch the changes since the stored sync token
+	fake.set("standup", "Daily standup")
+	fake.set("review", "")
+	fake.set("retro", "Retro")
+
+	result, err = calendarService.SyncCalendar(calendar, false)
+	if err != nil {
+		t.Fatalf("SyncCalendar failed: %v", err)
+	}
+	if result.Added != 1 || result.Updated != 1 || result.Removed != 1 {
+		t.Errorf("Expected 1 added, 1 updated and 1 removed event, got %+v", result)
+	}
+	if fake.syncTokens[1] != "2" {
+		t.Errorf("Expected incremental sync with token 2, got %q", fake.syncTokens[1])
+	}
+
+	titles := eventTitles(t, calendarService, calendar.ID)
+	if len(titles) != 2 || titles["standup"] != "Daily standup" || titles["retro"] != "Retro" {
+		t.Errorf("Unexpected events after incremental sync: %v", titles)
+	}
+
+	stored, err := calendarRepo.FindByUserIDAndSourceID(calendar.UserID, sourceID)
+	if err != nil {
+		t.Fatalf("Failed to find calendar: %v", err)
+	}
+	if stored.SyncToken == nil || *stored.SyncToken != "5" || stored.SyncStatus != model.CalendarSyncStatusIncrementalSync {
+		t.Errorf("Expected sync token 5 after incremental sync, got %v (%s)", stored.SyncToken, stored.SyncStatus)
+	}
+
+	// A complete fetch removes stored events the provider no longer has, even without a deletion
+	if err := calendarRepo.CreateEvents([]*model.CalendarEvent{{
+		ID: utils.GenerateID(), SourceID: "stale", CalendarID: calendar.ID, Title: "Stale",
+		Start: time.Now(), End: time.Now().Add(time.Hour),
+	}}); err != nil {
+		t.Fatalf("Failed to create event: %v", err)
+	}
+
+	result, err = calendarService.SyncCalendar(stored, true)
+	if err != nil {
+		t.Fatalf("SyncCalendar failed: %v", err)
+	}
+	if fake.syncTokens[2] != "" {
+		t.Errorf("Expected forced sync without a token, got %q", fake.syncTokens[2])
+	}
+	if result.Added != 0 || result.Updated != 0 || result.Removed != 1 {
+		t.Errorf("Expected the stale event to be removed, got %+v", result)
+	}
+	if titles := eventTitles(t, calendarService, calendar.ID); len(titles) != 2 || titles["stale"] != "" {
+		t.Errorf("Unexpected events after full sync: %v", titles)
+	}
+}
+
+func TestSyncCalendarWithoutProvider(t *testing.T) {
+	calendarService, _ := newTestCalendarService(t)
+
+	_, err := calendarService.SyncCalendar(&model.Calendar{ID: 1, Source: sourceFake}, false)
+	if err == nil || err.Error() != `no provider for calendar source "fake"` {
+		t.Errorf("Expected missing provider error, got %v", err)
+	}
+}
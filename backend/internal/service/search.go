@@ -0,0 +1,124 @@
+package service
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"go.uber.org/zap"
+
+	"github.com/NathanWasTaken/timely/backend/internal/model"
+)
+
+const (
+	// DefaultEventSearchLimit and MaxEventSearchLimit bound the number of events a search returns at once
+	DefaultEventSearchLimit = 50
+	MaxEventSearchLimit     = 200
+	// maxEventSearchTerms is the number of words of a search query that are searched for
+	maxEventSearchTerms = 10
+)
+
+// SearchEvents finds the events of the user's calendars whose title, location or description
+// contain every word of a query. The search is limited to calendarIDs unless it is empty, and to
+// events between startTime and endTime unless those are zero. Returns up to limit events from offset
+// in order of their start, and whether more events match.
+func (s *CalendarService) SearchEvents(userID uint64, query string, calendarIDs []string, startTime, endTime time.Time, limit, offset int) ([]*model.CalendarEvent, bool, error) {
+	terms := eventSearchTerms(query)
+	if len(terms) == 0 {
+		return nil, false, fmt.Errorf("invalid search: query must contain a word")
+	}
+	if !startTime.IsZero() && !endTime.IsZero() && !endTime.After(startTime) {
+		return nil, false, fmt.Errorf("invalid search: end must be after start")
+	}
+	if offset < 0 {
+		return nil, false, fmt.Errorf("invalid search: offset must not be negative")
+	}
+	if limit <= 0 {
+		limit = DefaultEventSearchLimit
+	}
+	limit = min(limit, MaxEventSearchLimit)
+
+	calendars, err := s.calendarRepo.FindByUserID(userID)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get user calendars: %w", err)
+	}
+
+	if len(calendarIDs) > 0 {
+		selected := make(map[string]bool, len(calendarIDs))
+		for _, id := range calendarIDs {
+			selected[id] = true
+		}
+
+		var filtered []*model.Calendar
+		for _, calendar := range calendars {
+			if selected[strconv.FormatUint(calendar.ID, 10)] {
+				filtered = append(filtered, calendar)
+			}
+		}
+		if len(filtered) != len(selected) {
+			return nil, false, fmt.Errorf("calendar not found or access denied")
+		}
+		calendars = filtered
+	}
+
+	if len(calendars) == 0 {
+		return []*model.CalendarEvent{}, false, nil
+	}
+
+	ids := make([]uint64, 0, len(calendars))
+	calendarMap := make(map[uint64]*model.Calendar, len(calendars))
+	for _, calendar := range calendars {
+		ids = append(ids, calendar.ID)
+		calendarMap[calendar.ID] = calendar
+	}
+
+	// One more event than asked for tells whether there are more
+	events, err := s.calendarRepo.SearchEvents(ids, terms, startTime, endTime, limit+1, offset)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to search events: %w", err)
+	}
+	more := len(events) > limit
+	if more {
+		events = events[:limit]
+	}
+
+	events = withoutHiddenDeclined(calendars, events)
+	s.loadEventAttendees(events)
+	s.markWriteConflicts(events)
+	for _, event := range events {
+		// Events without a color of their own are shown in the calendar's color
+		applyCalendarColor([]*model.CalendarEvent{event}, calendarMap[event.CalendarID])
+	}
+
+	s.logger.Info("Searched events",
+		zap.Uint64("user_id", userID),
+		zap.Int("term_count", len(terms)),
+		zap.Int("calendar_count", len(calendars)),
+		zap.Int("event_count", len(events)))
+
+	return events, more, nil
+}
+
+// eventSearchTerms splits a search query into its words, lowercased and without duplicates.
+// Anything but letters and digits separates words.
+func eventSearchTerms(query string) []string {
+	words := strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	var terms []string
+	seen := make(map[string]bool, len(words))
+	for _, word := range words {
+		if seen[word] {
+			continue
+		}
+		seen[word] = true
+		terms = append(terms, word)
+		if len(terms) == maxEventSearchTerms {
+			break
+		}
+	}
+	return terms
+}
@@ -0,0 +1,185 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	ics "github.com/arran4/golang-ical"
+
+	"github.com/NathanWasTaken/timely/backend/internal/migrations"
+	"github.com/NathanWasTaken/timely/backend/internal/model"
+	"github.com/NathanWasTaken/timely/backend/internal/repository"
+)
+
+func TestSearchEvents(t *testing.T) {
+	// The search index is created at startup, SQLite without FTS5 searches without it
+	db := newTestDB(t)
+	if err := migrations.EnsureEventSearchIndex(db); err != nil {
+		t.Fatalf("Failed to create the search index: %v", err)
+	}
+	calendarService := NewCalendarService(repository.NewUserRepository(db), repository.NewCalendarRepository(db), nil)
+
+	withProperties := func(event, properties string) string {
+		return strings.Replace(event, "END:VEVENT", properties+"END:VEVENT", 1)
+	}
+	importCalendar := func(userID uint64, name string, events ...string) *model.Calendar {
+		t.Helper()
+		icsCalendar, err := ics.ParseCalendar(strings.NewReader(buildICSFeed(events...)))
+		if err != nil {
+			t.Fatalf("Failed to parse ICS data: %v", err)
+		}
+		calendar, _, err := calendarService.ImportICSCalendar(userID, name, icsCalendar, icsCalendar.Events())
+		if err != nil {
+			t.Fatalf("ImportICSCalendar failed: %v", err)
+		}
+		return calendar
+	}
+	search := func(userID uint64, query string, calendarIDs []string, startTime, endTime time.Time, limit, offset int) ([]string, bool) {
+		t.Helper()
+		events, more, err := calendarService.SearchEvents(userID, query, calendarIDs, startTime, endTime, limit, offset)
+		if err != nil {
+			t.Fatalf("SearchEvents(%q) failed: %v", query, err)
+		}
+		var ids []string
+		for _, event := range events {
+			ids = append(ids, event.SourceID)
+		}
+		return ids, more
+	}
+
+	const userID = 61
+	personal := importCalendar(userID, "Personal",
+		buildICSEvent("dentist", "Dentist", "20240701T090000Z"),
+		withProperties(buildICSEvent("checkup", "Checkup", "20240801T090000Z"), "LOCATION:Dentistry on Main Street\r\n"),
+		withProperties(buildICSEvent("call", "Call", "20240901T090000Z"), "DESCRIPTION:Reschedule the dentist appointment\r\n"),
+		buildICSEvent("gym", "Gym", "20240701T180000Z"),
+	)
+	work := importCalendar(userID, "Work", buildICSEvent("planning", "Planning with the dentist team", "20240702T090000Z"))
+	importCalendar(userID+1, "Other", buildICSEvent("other", "Dentist", "20240701T090000Z"))
+
+	// Title, location and description match, only in the user's calendars and in order of start
+	if ids, more := search(userID, "DENT", nil, time.Time{}, time.Time{}, 0, 0); strings.Join(ids, ",") != "dentist,planning,checkup,call" || more {
+		t.Errorf("Expected every dentist event of the user, got %v (more: %v)", ids, more)
+	}
+	if ids, _ := search(userID, "dentist appointment", nil, time.Time{}, time.Time{}, 0, 0); strings.Join(ids, ",") != "call" {
+		t.Errorf("Expected events matching every word, got %v", ids)
+	}
+
+	// Calendars, dates and pages narrow the results down
+	if ids, _ := search(userID, "dentist", []string{fmt.Sprint(work.ID)}, time.Time{}, time.Time{}, 0, 0); strings.Join(ids, ",") != "planning" {
+		t.Errorf("Expected the events of the work calendar, got %v", ids)
+	}
+	august := time.Date(2024, 8, 1, 0, 0, 0, 0, time.UTC)
+	if ids, _ := search(userID, "dentist", nil, august, august.AddDate(0, 1, 0), 0, 0); strings.Join(ids, ",") != "checkup" {
+		t.Errorf("Expected the events of August, got %v", ids)
+	}
+	if ids, more := search(userID, "dentist", nil, time.Time{}, time.Time{}, 2, 0); strings.Join(ids, ",") != "dentist,planning" || !more {
+		t.Errorf("Expected the first page with more to come, got %v (more: %v)", ids, more)
+	}
+	if ids, more := search(userID, "dentist", nil, time.Time{}, time.Time{}, 2, 2); strings.Join(ids, ",") != "checkup,call" || more {
+		t.Errorf("Expected the last page, got %v (more: %v)", ids, more)
+	}
+
+	// Reimported events are searched by their new text and deleted ones are gone
+	icsCalendar, err := ics.ParseCalendar(strings.NewReader(buildICSFeed(
+		buildICSEvent("dentist", "Orthodontist", "20240701T090000Z"),
+		buildICSEvent("gym", "Gym", "20240701T180000Z"),
+	)))
+	if err != nil {
+		t.Fatalf("Failed to parse ICS data: %v", err)
+	}
+	if _, _, _, err := calendarService.ReimportICSCalendar(userID, fmt.Sprint(personal.ID), "Personal", icsCalendar, icsCalendar.Events()); err != nil {
+		t.Fatalf("ReimportICSCalendar failed: %v", err)
+	}
+	if ids, _ := search(userID, "dentist", nil, time.Time{}, time.Time{}, 0, 0); strings.Join(ids, ",") != "planning" {
+		t.Errorf("Expected only the work event after the reimport, got %v", ids)
+	}
+	if ids, _ := search(userID, "orthodontist", nil, time.Time{}, time.Time{}, 0, 0); strings.Join(ids, ",") != "dentist" {
+		t.Errorf("Expected the renamed event, got %v", ids)
+	}
+
+	if _, _, err := calendarService.SearchEvents(userID, " -- ", nil, time.Time{}, time.Time{}, 0, 0); err == nil || !strings.HasPrefix(err.Error(), "invalid search") {
+		t.Errorf("Expected a query without words to be rejected, got %v", err)
+	}
+	if _, _, err := calendarService.SearchEvents(userID+1, "dentist", []string{fmt.Sprint(work.ID)}, time.Time{}, time.Time{}, 0, 0); err == nil || err.Error() != "calendar not found or access denied" {
+		t.Errorf("Expected the calendar of another user to be rejected, got %v", err)
+	}
+}
+
+func TestEnsureEventSearchIndex(t *testing.T) {
+	db := newTestDB(t)
+	calendarService := NewCalendarService(repository.NewUserRepository(db), repository.NewCalendarRepository(db), nil)
+	importCalendar := func(name string, events ...string) {
+		t.Helper()
+		icsCalendar, err := ics.ParseCalendar(strings.NewReader(buildICSFeed(events...)))
+		if err != nil {
+			t.Fatalf("Failed to parse ICS data: %v", err)
+		}
+		if _, _, err := calendarService.ImportICSCalendar(62, name, icsCalendar, icsCalendar.Events()); err != nil {
+			t.Fatalf("ImportICSCalendar failed: %v", err)
+		}
+	}
+	search := func(query string) string {
+		t.Helper()
+		events, _, err := calendarService.SearchEvents(62, query, nil, time.Time{}, time.Time{}, 0, 0)
+		if err != nil {
+			t.Fatalf("SearchEvents(%q) failed: %v", query, err)
+		}
+		var ids []string
+		for _, event := range events {
+			ids = append(ids, event.SourceID)
+		}
+		return strings.Join(ids, ",")
+	}
+
+	// Which path is covered depends on the build, go test -tags sqlite_fts5 covers the index
+	var fts5 bool
+	if err := db.Raw("SELECT sqlite_compileoption_used('ENABLE_FTS5')").Scan(&fts5).Error; err != nil {
+		t.Fatalf("Failed to check for FTS5: %v", err)
+	}
+
+	if !fts5 {
+		// Triggers left by a build with FTS5 would fail every write of events
+		trigger := `CREATE TRIGGER calendar_events_fts_insert AFTER INSERT ON calendar_events BEGIN
+			INSERT INTO calendar_events_fts (rowid, title) VALUES (new.id, new.title);
+		END`
+		if err := db.Exec(trigger).Error; err != nil {
+			t.Fatalf("Failed to create trigger: %v", err)
+		}
+		if err := migrations.EnsureEventSearchIndex(db); err != nil {
+			t.Fatalf("EnsureEventSearchIndex failed: %v", err)
+		}
+		importCalendar("Work", buildICSEvent("standup", "Standup", "20240701T090000Z"))
+		if ids := search("tand"); ids != "standup" {
+			t.Errorf("Expected the events to be scanned, got %q", ids)
+		}
+		return
+	}
+
+	// Events stored before the index existed are indexed when it is created
+	importCalendar("Work", buildICSEvent("standup", "Standup", "20240701T090000Z"))
+	if err := migrations.EnsureEventSearchIndex(db); err != nil {
+		t.Fatalf("EnsureEventSearchIndex failed: %v", err)
+	}
+	if !db.Migrator().HasTable("calendar_events_fts") {
+		t.Fatal("Expected the FTS5 table to be created")
+	}
+	if ids := search("stand"); ids != "standup" {
+		t.Errorf("Expected the existing event to be indexed, got %q", ids)
+	}
+	// The index matches the start of words, unlike the scan without it
+	if ids := search("tand"); ids != "" {
+		t.Errorf("Expected the index to be searched, got %q", ids)
+	}
+
+	// The index is kept up to date, ensuring it again leaves it as it is
+	importCalendar("Home", buildICSEvent("desk", "Standing desk delivery", "20240702T090000Z"))
+	if err := migrations.EnsureEventSearchIndex(db); err != nil {
+		t.Fatalf("EnsureEventSearchIndex failed: %v", err)
+	}
+	if ids := search("stand"); ids != "standup,desk" {
+		t.Errorf("Expected new events to be indexed, got %q", ids)
+	}
+}
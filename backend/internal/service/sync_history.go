@@ -0,0 +1,208 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"golang.org/x/oauth2"
+	"gorm.io/gorm"
+
+	"github.com/NathanWasTaken/timely/backend/internal/model"
+	"github.com/NathanWasTaken/timely/backend/pkg/caldav"
+)
+
+const (
+	// syncRunRetention is how long the sync runs of a calendar are kept
+	syncRunRetention = 30 * 24 * time.Hour
+	// DefaultSyncRunLimit and MaxSyncRunLimit bound the number of sync runs returned at once
+	DefaultSyncRunLimit = 50
+	MaxSyncRunLimit     = 200
+)
+
+// syncErrorStatusPattern finds the HTTP status in the errors of the Google, ICS and CalDAV clients
+var syncErrorStatusPattern = regexp.MustCompile(`status (\d{3})`)
+
+// GetCalendarSyncRuns returns the most recent sync runs of one of the user's calendars, the most
+// recent first, with the sync health derived from them
+func (s *CalendarService) GetCalendarSyncRuns(userID uint64, calendarID string, limit int) ([]*model.SyncRun, *model.CalendarHealth, error) {
+	calendar, err := s.calendarRepo.FindByID(calendarID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to find calendar: %w", err)
+	}
+
+	if calendar.UserID != userID {
+		return nil, nil, fmt.Errorf("calendar not found or access denied")
+	}
+
+	if limit <= 0 {
+		limit = DefaultSyncRunLimit
+	}
+	limit = min(limit, MaxSyncRunLimit)
+
+	runs, err := s.calendarRepo.FindSyncRunsByCalendarID(calendar.ID, limit)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get sync runs: %w", err)
+	}
+
+	health, err := s.calendarHealth(calendar.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return runs, health, nil
+}
+
+// setCalendarHealth fills in the sync health of the calendars synced with a remote source
+func (s *CalendarService) setCalendarHealth(calendars []*model.Calendar) {
+	for _, calendar := range calendars {
+		if !hasSyncHistory(calendar) {
+			continue
+		}
+
+		health, err := s.calendarHealth(calendar.ID)
+		if err != nil {
+			// The calendars are still listed, just without their health
+			s.logger.Warn("Failed to get calendar health", zap.Error(err), zap.Uint64("calendar_id", calendar.ID))
+			continue
+		}
+		calendar.Health = health
+	}
+}
+
+// calendarHealth derives the sync health of a calendar from its sync runs. A calendar is healthy
+// until a sync fails, and needs reauthentication if the last sync failed on its credentials.
+// Changes made in Timely waiting for a conflict with Google to be resolved are counted as well.
+func (s *CalendarService) calendarHealth(calendarID uint64) (*model.CalendarHealth, error) {
+	health := &model.CalendarHealth{Status: model.CalendarHealthHealthy}
+
+	// Conflicting changes wait for the owner whether or not syncs succeed
+	conflicts, err := s.calendarRepo.CountConflictingEventWrites(calendarID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count conflicting event writes: %w", err)
+	}
+	health.WriteConflicts = int(conflicts)
+
+	runs, err := s.calendarRepo.FindSyncRunsByCalendarID(calendarID, 1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sync runs: %w", err)
+	}
+	if len(runs) == 0 {
+		return health, nil
+	}
+
+	last := runs[0]
+	health.LastSyncAt = &last.StartedAt
+	if last.ErrorClass == nil {
+		health.LastSuccessAt = &last.StartedAt
+		return health, nil
+	}
+
+	health.Status = model.CalendarHealthDegraded
+	if *last.ErrorClass == model.SyncErrorClassAuth {
+		health.Status = model.CalendarHealthNeedsReauthentication
+	}
+	health.ErrorClass = last.ErrorClass
+	health.ErrorMessage = last.ErrorMessage
+
+	var since time.Time
+	success, err := s.calendarRepo.FindLastSuccessfulSyncRun(calendarID)
+	switch {
+	case err == nil:
+		health.LastSuccessAt = &success.StartedAt
+		since = success.StartedAt
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		return nil, fmt.Errorf("failed to get last successful sync run: %w", err)
+	}
+
+	failures, err := s.calendarRepo.CountFailedSyncRunsSince(calendarID, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count failed sync runs: %w", err)
+	}
+	health.ConsecutiveFailures = int(failures)
+
+	return health, nil
+}
+
+// recordSyncRun completes a sync run with the outcome of the sync and stores it, dropping the runs
+// of the calendar that are older than syncRunRetention
+func (s *CalendarService) recordSyncRun(run *model.SyncRun, result *SyncResult, syncErr error) {
+	run.FinishedAt = time.Now()
+	if result != nil {
+		run.NotModified = result.NotModified
+		run.Added = result.Added
+		run.Updated = result.Updated
+		run.Deleted = result.Removed
+	}
+	if syncErr != nil {
+		errorClass := classifySyncError(syncErr)
+		errorMessage := syncErr.Error()
+		run.ErrorClass = &errorClass
+		run.ErrorMessage = &errorMessage
+	}
+
+	if err := s.calendarRepo.CreateSyncRun(run); err != nil {
+		s.logger.Error("Failed to record sync run", zap.Error(err), zap.Uint64("calendar_id", run.CalendarID))
+		return
+	}
+
+	if err := s.calendarRepo.DeleteSyncRunsBefore(run.CalendarID, run.StartedAt.Add(-syncRunRetention)); err != nil {
+		s.logger.Warn("Failed to delete old sync runs", zap.Error(err), zap.Uint64("calendar_id", run.CalendarID))
+	}
+}
+
+// classifySyncError groups a sync error by what it takes to fix it
+func classifySyncError(err error) model.SyncErrorClass {
+	message := err.Error()
+
+	var retrieveErr *oauth2.RetrieveError
+	switch {
+	case errors.Is(err, caldav.ErrUnauthorized),
+		errors.As(err, &retrieveErr),
+		strings.HasPrefix(message, "failed to get Google account"),
+		strings.Contains(message, "not properly configured with OAuth tokens"),
+		strings.Contains(message, "no refresh token available"):
+		return model.SyncErrorClassAuth
+	}
+
+	status := 0
+	if match := syncErrorStatusPattern.FindStringSubmatch(message); match != nil {
+		status, _ = strconv.Atoi(match[1])
+	}
+
+	var netErr net.Error
+	switch {
+	case status == 401:
+		return model.SyncErrorClassAuth
+	case status == 403 && (strings.Contains(message, "insufficientPermissions") || strings.Contains(message, "ACCESS_TOKEN_SCOPE_INSUFFICIENT")):
+		// Granted before Timely asked for more scopes
+		return model.SyncErrorClassAuth
+	case status == 429,
+		status == 403 && (strings.Contains(message, "rateLimitExceeded") || strings.Contains(message, "quotaExceeded")):
+		return model.SyncErrorClassRateLimited
+	case status == 403, status == 404, status == 410:
+		return model.SyncErrorClassNotFound
+	case status >= 500, errors.As(err, &netErr):
+		return model.SyncErrorClassRemote
+	default:
+		return model.SyncErrorClassOther
+	}
+}
+
+// hasSyncHistory reports whether the events of a calendar are synced with a remote source
+func hasSyncHistory(calendar *model.Calendar) bool {
+	switch calendar.Source {
+	case model.SourceTimely:
+		return false
+	case model.SourceICS:
+		// Imported files are never fetched again
+		return calendar.SubscriptionURL != nil
+	default:
+		return true
+	}
+}
@@ -0,0 +1,125 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"golang.org/x/oauth2"
+
+	"github.com/NathanWasTaken/timely/backend/internal/model"
+	"github.com/NathanWasTaken/timely/backend/pkg/caldav"
+)
+
+func TestSyncHistory(t *testing.T) {
+	calendarService, _ := newTestCalendarService(t)
+	google, server := newGoogleStandIn(t)
+	calendar := newGoogleTestCalendar(t, calendarService, server.URL)
+	calendarID := fmt.Sprint(calendar.ID)
+
+	timely, err := calendarService.CreateCalendar(calendar.UserID, &model.CalendarCreateRequest{Summary: "Personal"})
+	if err != nil {
+		t.Fatalf("CreateCalendar failed: %v", err)
+	}
+
+	google.edit("standup", "Standup")
+	syncGoogleTestCalendar(t, calendarService, calendar)
+	google.edit("retro", "Retro")
+	syncGoogleTestCalendar(t, calendarService, calendar)
+
+	runs, health, err := calendarService.GetCalendarSyncRuns(calendar.UserID, calendarID, 0)
+	if err != nil {
+		t.Fatalf("GetCalendarSyncRuns failed: %v", err)
+	}
+	if len(runs) != 2 {
+		t.Fatalf("Expected 2 sync runs, got %d", len(runs))
+	}
+	if runs[0].Mode != model.SyncRunModeIncremental || runs[0].Added != 1 || runs[0].ErrorClass != nil {
+		t.Errorf("Expected the latest run to be a successful incremental sync, got %+v", runs[0])
+	}
+	if runs[1].Mode != model.SyncRunModeFull || runs[1].Added != 1 {
+		t.Errorf("Expected the first run to be a full sync, got %+v", runs[1])
+	}
+	if health.Status != model.CalendarHealthHealthy || health.LastSuccessAt == nil || health.ConsecutiveFailures != 0 {
+		t.Errorf("Expected the calendar to be healthy, got %+v", health)
+	}
+
+	// Server errors degrade the calendar
+	var failing atomic.Int32
+	failing.Store(http.StatusServiceUnavailable)
+	failingServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "failing", int(failing.Load()))
+	}))
+	t.Cleanup(failingServer.Close)
+	calendarService.google.baseURL = failingServer.URL
+
+	for range 2 {
+		stored, _ := calendarService.calendarRepo.FindByID(calendarID)
+		if _, err := calendarService.SyncCalendar(stored, false); err == nil {
+			t.Fatal("Expected the sync to fail")
+		}
+	}
+
+	_, health, _ = calendarService.GetCalendarSyncRuns(calendar.UserID, calendarID, 0)
+	if health.Status != model.CalendarHealthDegraded || health.ConsecutiveFailures != 2 ||
+		health.ErrorClass == nil || *health.ErrorClass != model.SyncErrorClassRemote || health.LastSuccessAt == nil {
+		t.Errorf("Expected the calendar to be degraded, got %+v", health)
+	}
+
+	// Rejected credentials have to be fixed by the user
+	failing.Store(http.StatusUnauthorized)
+	stored, _ := calendarService.calendarRepo.FindByID(calendarID)
+	calendarService.SyncCalendar(stored, false)
+
+	calendars, err := calendarService.GetImportedCalendars(calendar.UserID)
+	if err != nil {
+		t.Fatalf("GetImportedCalendars failed: %v", err)
+	}
+	for _, listed := range calendars {
+		switch listed.ID {
+		case calendar.ID:
+			if listed.Health == nil || listed.Health.Status != model.CalendarHealthNeedsReauthentication || listed.Health.ConsecutiveFailures != 3 {
+				t.Errorf("Expected the calendar to need reauthentication, got %+v", listed.Health)
+			}
+		case timely.ID:
+			if listed.Health != nil {
+				t.Errorf("Expected no health for a Timely calendar, got %+v", listed.Health)
+			}
+		}
+	}
+
+	if runs, _, _ := calendarService.GetCalendarSyncRuns(calendar.UserID, calendarID, 2); len(runs) != 2 {
+		t.Errorf("Expected the limit to apply, got %d runs", len(runs))
+	}
+	if _, _, err := calendarService.GetCalendarSyncRuns(calendar.UserID+1, calendarID, 0); err == nil || err.Error() != "calendar not found or access denied" {
+		t.Errorf("Expected the history to be private, got %v", err)
+	}
+}
+
+func TestClassifySyncError(t *testing.T) {
+	tests := []struct {
+		err      error
+		expected model.SyncErrorClass
+	}{
+		{fmt.Errorf("failed to refresh token: %w", &oauth2.RetrieveError{ErrorCode: "invalid_grant"}), model.SyncErrorClassAuth},
+		{fmt.Errorf("failed to get Google account: record not found"), model.SyncErrorClassAuth},
+		{fmt.Errorf("failed to fetch events from CalDAV server (full sync): %w", caldav.ErrUnauthorized), model.SyncErrorClassAuth},
+		{fmt.Errorf("google Calendar API error: status 401, body: {}"), model.SyncErrorClassAuth},
+		{fmt.Errorf("google Calendar API error: status 403, body: {\"reason\": \"insufficientPermissions\"}"), model.SyncErrorClassAuth},
+		{fmt.Errorf("google Calendar API error: status 403, body: {\"reason\": \"rateLimitExceeded\"}"), model.SyncErrorClassRateLimited},
+		{fmt.Errorf("google Calendar API error: status 429, body: {}"), model.SyncErrorClassRateLimited},
+		{fmt.Errorf("google Calendar API error: status 404, body: {}"), model.SyncErrorClassNotFound},
+		{fmt.Errorf("failed to fetch ICS feed: unexpected status 410"), model.SyncErrorClassNotFound},
+		{fmt.Errorf("google Calendar API error: status 502, body: {}"), model.SyncErrorClassRemote},
+		{errors.New("failed to get existing events: database is locked"), model.SyncErrorClassOther},
+	}
+
+	for _, test := range tests {
+		if got := classifySyncError(test.err); got != test.expected {
+			t.Errorf("classifySyncError(%q) = %s, expected %s", test.err, got, test.expected)
+		}
+	}
+}
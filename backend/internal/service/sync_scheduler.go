@@ -0,0 +1,267 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/NathanWasTaken/timely/backend/internal/model"
+	"github.com/NathanWasTaken/timely/backend/pkg/utils"
+)
+
+const (
+	// DefaultSyncInterval is the time between background syncs of calendars without their own interval
+	DefaultSyncInterval = 15 * time.Minute
+	// DefaultSyncWorkers is the default number of calendars synced at the same time
+	DefaultSyncWorkers = 4
+	// syncSchedulerCheckInterval is how often the scheduler looks for calendars due for a sync
+	syncSchedulerCheckInterval = 30 * time.Second
+	// syncLeaseDuration bounds how long a calendar stays claimed by a sync that never finished, for
+	// example because the server was stopped while it ran
+	syncLeaseDuration = 10 * time.Minute
+	// syncJitterFraction is the largest share of the interval added to each sync, so calendars
+	// imported together do not keep syncing at the same moment
+	syncJitterFraction = 0.1
+	// minSyncIntervalMinutes and maxSyncIntervalMinutes bound the sync interval of a calendar
+	minSyncIntervalMinutes = 5
+	maxSyncIntervalMinutes = 24 * 60
+)
+
+// syncLeaseRenewInterval is how often a running sync extends its lease, well before it runs out
+var syncLeaseRenewInterval = syncLeaseDuration / 3
+
+// SyncSchedulerConfig configures the background sync of Google calendars
+type SyncSchedulerConfig struct {
+	Interval time.Duration // Interval of calendars without their own
+	Workers  int           // Maximum number of calendars synced at the same time
+}
+
+// RunSyncScheduler syncs Google calendars in the background whenever their next sync is due, until
+// ctx is cancelled. The schedule is stored with each calendar so it survives restarts, and every sync
+// holds a lease on its calendar so a calendar is never synced twice at the same time.
+func (s *CalendarService) RunSyncScheduler(ctx context.Context, config SyncSchedulerConfig) {
+	s.runSyncScheduler(ctx, config, s.syncScheduledCalendar)
+}
+
+// runSyncScheduler runs the scheduler with syncCalendar performing the sync of a single calendar
+func (s *CalendarService) runSyncScheduler(ctx context.Context, config SyncSchedulerConfig, syncCalendar func(*model.Calendar) error) {
+	if config.Interval <= 0 {
+		config.Interval = DefaultSyncInterval
+	}
+	if config.Workers <= 0 {
+		config.Workers = DefaultSyncWorkers
+	}
+
+	s.logger.Info("Starting sync scheduler",
+		zap.Duration("interval", config.Interval),
+		zap.Int("workers", config.Workers))
+
+	jobs := make(chan *model.Calendar)
+	var wg sync.WaitGroup
+	for i := 0; i < config.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for calendar := range jobs {
+				s.runScheduledSync(calendar, config.Interval, syncCalendar)
+			}
+		}()
+	}
+
+	ticker := time.NewTicker(syncSchedulerCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		s.dispatchDueSyncs(ctx, jobs, config.Workers)
+
+		select {
+		case <-ctx.Done():
+			close(jobs)
+			wg.Wait()
+			s.logger.Info("Stopping sync scheduler")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// dispatchDueSyncs hands the calendars due for a sync to the workers, blocking while all of them are busy
+func (s *CalendarService) dispatchDueSyncs(ctx context.Context, jobs chan<- *model.Calendar, workers int) {
+	for {
+		calendars, err := s.calendarRepo.FindCalendarsDueForSync(s.scheduledSources(), time.Now(), workers)
+		if err != nil {
+			s.logger.Error("Failed to find calendars due for sync", zap.Error(err))
+			return
+		}
+
+		for _, calendar := range calendars {
+			select {
+			case jobs <- calendar:
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		// A full batch means more calendars may be waiting
+		if len(calendars) < workers {
+			return
+		}
+	}
+}
+
+// runScheduledSync syncs a calendar unless another sync holds its lease or already synced it, then
+// schedules its next sync.
+// Failed syncs are retried at the next regular interval.
+func (s *CalendarService) runScheduledSync(calendar *model.Calendar, defaultInterval time.Duration, syncCalendar func(*model.Calendar) error) {
+	lease, err := s.claimSyncLease(calendar.ID, s.calendarRepo.ClaimDueSyncLease)
+	if errors.Is(err, errCalendarBusy) {
+		s.logger.Debug("Calendar is already being synced", zap.Uint64("calendar_id", calendar.ID))
+		return
+	}
+	if err != nil {
+		s.logger.Error("Failed to claim sync lease", zap.Error(err), zap.Uint64("calendar_id", calendar.ID))
+		return
+	}
+
+	err = syncCalendar(calendar)
+	if err != nil {
+		s.logger.Warn("Scheduled calendar sync failed",
+			zap.Error(err),
+			zap.Uint64("calendar_id", calendar.ID),
+			zap.Uint64("user_id", calendar.UserID))
+	}
+
+	nextSyncAt := time.Now().Add(withSyncJitter(calendarSyncInterval(calendar, defaultInterval)))
+	// Changes made in Timely while the sync ran, or waiting for a retry, are not left until the next interval
+	if err == nil && calendar.WriteBack {
+		if nextWrite := s.nextEventWriteAttempt(calendar.ID); nextWrite != nil && nextWrite.Before(nextSyncAt) {
+			nextSyncAt = *nextWrite
+		}
+	}
+	s.releaseSyncLease(lease, &nextSyncAt)
+}
+
+// syncLease is the sync lease of a calendar held by a running sync. It is renewed in the background
+// until it is released, so a long sync does not lose it to another one.
+type syncLease struct {
+	calendarID uint64
+	token      string
+	stop       chan struct{}
+	stopped    chan struct{}
+}
+
+// claimSyncLease claims the sync lease of a calendar with claim, usually
+// CalendarRepository.ClaimSyncLease, and keeps renewing it until it is released. Returns
+// errCalendarBusy if another sync holds the lease.
+func (s *CalendarService) claimSyncLease(calendarID uint64, claim func(calendarID uint64, token string, now, until time.Time) (bool, error)) (*syncLease, error) {
+	token := utils.GenerateIDString()
+	now := time.Now()
+	claimed, err := claim(calendarID, token, now, now.Add(syncLeaseDuration))
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim sync lease: %w", err)
+	}
+	if !claimed {
+		return nil, errCalendarBusy
+	}
+	return s.holdSyncLease(calendarID, token), nil
+}
+
+// holdSyncLease renews the claimed sync lease of a calendar until it is released
+func (s *CalendarService) holdSyncLease(calendarID uint64, token string) *syncLease {
+	lease := &syncLease{calendarID: calendarID, token: token, stop: make(chan struct{}), stopped: make(chan struct{})}
+
+	go func() {
+		defer close(lease.stopped)
+
+		ticker := time.NewTicker(syncLeaseRenewInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-lease.stop:
+				return
+			case <-ticker.C:
+			}
+
+			renewed, err := s.calendarRepo.RenewSyncLease(calendarID, token, time.Now().Add(syncLeaseDuration))
+			if err != nil {
+				s.logger.Error("Failed to renew sync lease", zap.Error(err), zap.Uint64("calendar_id", calendarID))
+				continue
+			}
+			if !renewed {
+				s.logger.Warn("Sync lease expired and was claimed by another sync", zap.Uint64("calendar_id", calendarID))
+				return
+			}
+		}
+	}()
+
+	return lease
+}
+
+// releaseSyncLease stops renewing a sync lease and releases it. If nextSyncAt is not nil, the next
+// background sync of the calendar is scheduled at it.
+func (s *CalendarService) releaseSyncLease(lease *syncLease, nextSyncAt *time.Time) {
+	close(lease.stop)
+	<-lease.stopped
+
+	if err := s.calendarRepo.ReleaseSyncLease(lease.calendarID, lease.token, nextSyncAt); err != nil {
+		s.logger.Error("Failed to release sync lease", zap.Error(err), zap.Uint64("calendar_id", lease.calendarID))
+	}
+}
+
+// initialSyncLease sets the sync lease an imported calendar is created with, held by its initial
+// sync once the calendar is stored
+func initialSyncLease(calendar *model.Calendar) {
+	until := time.Now().Add(syncLeaseDuration)
+	token := utils.GenerateIDString()
+	calendar.SyncLockedUntil = &until
+	calendar.SyncLeaseToken = &token
+}
+
+// finishInitialSync releases the sync lease held by the initial sync of an imported calendar and
+// schedules its first background sync. A failed initial sync is retried by the scheduler right away.
+func (s *CalendarService) finishInitialSync(calendar *model.Calendar, lease *syncLease, syncErr error) {
+	nextSyncAt := time.Now()
+	if syncErr == nil {
+		nextSyncAt = nextSyncAt.Add(withSyncJitter(calendarSyncInterval(calendar, DefaultSyncInterval)))
+	}
+
+	s.releaseSyncLease(lease, &nextSyncAt)
+	calendar.SyncLockedUntil = nil
+	calendar.SyncLeaseToken = nil
+	calendar.NextSyncAt = &nextSyncAt
+}
+
+// syncScheduledCalendar syncs the events of a calendar from its provider
+func (s *CalendarService) syncScheduledCalendar(calendar *model.Calendar) error {
+	_, err := s.SyncCalendar(calendar, false)
+	return err
+}
+
+// calendarSyncInterval returns the background sync interval of a calendar. Calendars with an open
+// push notification channel are synced when they change, so polling them is only a fallback.
+func calendarSyncInterval(calendar *model.Calendar, defaultInterval time.Duration) time.Duration {
+	interval := defaultInterval
+	if calendar.SyncIntervalMinutes > 0 {
+		interval = time.Duration(calendar.SyncIntervalMinutes) * time.Minute
+	}
+
+	if calendar.WatchExpiresAt != nil && calendar.WatchExpiresAt.After(time.Now()) && interval < watchedSyncInterval {
+		return watchedSyncInterval
+	}
+	return interval
+}
+
+// withSyncJitter adds a random delay of up to syncJitterFraction of interval
+func withSyncJitter(interval time.Duration) time.Duration {
+	jitter := time.Duration(float64(interval) * syncJitterFraction)
+	if jitter <= 0 {
+		return interval
+	}
+	return interval + rand.N(jitter)
+}
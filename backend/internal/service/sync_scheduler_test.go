@@ -0,0 +1,240 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/NathanWasTaken/timely/backend/internal/model"
+	"github.com/NathanWasTaken/timely/backend/internal/repository"
+	"github.com/NathanWasTaken/timely/backend/pkg/utils"
+)
+
+// createSyncTestCalendar creates a calendar of the given source due for a sync at nextSyncAt
+func createSyncTestCalendar(t *testing.T, calendarRepo *repository.CalendarRepository, source model.CalendarSource, nextSyncAt *time.Time) *model.Calendar {
+	t.Helper()
+
+	id := utils.GenerateID()
+	sourceID := fmt.Sprintf("calendar-%d@group.calendar.google.com", id)
+	calendar := &model.Calendar{
+		ID:         id,
+		UserID:     31,
+		SourceID:   &sourceID,
+		Source:     source,
+		Summary:    "Work",
+		NextSyncAt: nextSyncAt,
+	}
+	if err := calendarRepo.Create(calendar); err != nil {
+		t.Fatalf("Failed to create calendar: %v", err)
+	}
+	return calendar
+}
+
+func TestFindCalendarsDueForSync(t *testing.T) {
+	calendarService, calendarRepo := newTestCalendarService(t)
+
+	now := time.Now()
+	past := now.Add(-time.Minute)
+	future := now.Add(time.Hour)
+
+	neverSynced := createSyncTestCalendar(t, calendarRepo, model.SourceGoogle, nil)
+	due := createSyncTestCalendar(t, calendarRepo, model.SourceGoogle, &past)
+	createSyncTestCalendar(t, calendarRepo, model.SourceGoogle, &future)
+	createSyncTestCalendar(t, calendarRepo, model.SourceICS, &past)
+	locked := createSyncTestCalendar(t, calendarRepo, model.SourceGoogle, &past)
+	if claimed, err := calendarRepo.ClaimSyncLease(locked.ID, "other", now, now.Add(syncLeaseDuration)); err != nil || !claimed {
+		t.Fatalf("Failed to claim lease: claimed=%v err=%v", claimed, err)
+	}
+
+	calendars, err := calendarRepo.FindCalendarsDueForSync(calendarService.scheduledSources(), now, 10)
+	if err != nil {
+		t.Fatalf("FindCalendarsDueForSync failed: %v", err)
+	}
+
+	found := map[uint64]bool{}
+	for _, calendar := range calendars {
+		found[calendar.ID] = true
+	}
+	if len(calendars) != 2 || !found[neverSynced.ID] || !found[due.ID] {
+		t.Errorf("Expected only the never synced and due Google calendars, got %d calendars", len(calendars))
+	}
+}
+
+func TestSyncLeasePreventsConcurrentSyncs(t *testing.T) {
+	_, calendarRepo := newTestCalendarService(t)
+
+	calendar := createSyncTestCalendar(t, calendarRepo, model.SourceGoogle, nil)
+	now := time.Now()
+
+	claimed, err := calendarRepo.ClaimSyncLease(calendar.ID, "first", now, now.Add(syncLeaseDuration))
+	if err != nil || !claimed {
+		t.Fatalf("Expected first claim to succeed, claimed=%v err=%v", claimed, err)
+	}
+
+	claimed, err = calendarRepo.ClaimSyncLease(calendar.ID, "second", now.Add(time.Minute), now.Add(time.Minute+syncLeaseDuration))
+	if err != nil || claimed {
+		t.Fatalf("Expected claim of a held lease to fail, claimed=%v err=%v", claimed, err)
+	}
+
+	// A lease left behind by a sync that never finished expires
+	expired := now.Add(syncLeaseDuration + time.Second)
+	claimed, err = calendarRepo.ClaimSyncLease(calendar.ID, "second", expired, expired.Add(syncLeaseDuration))
+	if err != nil || !claimed {
+		t.Fatalf("Expected claim of an expired lease to succeed, claimed=%v err=%v", claimed, err)
+	}
+
+	// The sync that lost its lease can neither renew nor release the lease of the new holder
+	if renewed, err := calendarRepo.RenewSyncLease(calendar.ID, "first", expired.Add(syncLeaseDuration)); err != nil || renewed {
+		t.Errorf("Expected renewing a lost lease to fail, renewed=%v err=%v", renewed, err)
+	}
+	if err := calendarRepo.ReleaseSyncLease(calendar.ID, "first", nil); err != nil {
+		t.Fatalf("ReleaseSyncLease failed: %v", err)
+	}
+	claimed, err = calendarRepo.ClaimSyncLease(calendar.ID, "third", expired.Add(time.Minute), expired.Add(time.Minute+syncLeaseDuration))
+	if err != nil || claimed {
+		t.Errorf("Expected the lease of the new holder to be kept, claimed=%v err=%v", claimed, err)
+	}
+
+	if renewed, err := calendarRepo.RenewSyncLease(calendar.ID, "second", expired.Add(2*syncLeaseDuration)); err != nil || !renewed {
+		t.Errorf("Expected the holder to renew its lease, renewed=%v err=%v", renewed, err)
+	}
+	if err := calendarRepo.ReleaseSyncLease(calendar.ID, "second", nil); err != nil {
+		t.Fatalf("ReleaseSyncLease failed: %v", err)
+	}
+	if stored, _ := calendarRepo.FindByID(fmt.Sprint(calendar.ID)); stored.SyncLockedUntil != nil || stored.SyncLeaseToken != nil {
+		t.Errorf("Expected the holder to release its lease, got %v", stored.SyncLockedUntil)
+	}
+}
+
+func TestLongSyncRenewsLease(t *testing.T) {
+	calendarService, calendarRepo := newTestCalendarService(t)
+
+	renewInterval := syncLeaseRenewInterval
+	syncLeaseRenewInterval = 10 * time.Millisecond
+	t.Cleanup(func() { syncLeaseRenewInterval = renewInterval })
+
+	calendar := createSyncTestCalendar(t, calendarRepo, model.SourceGoogle, nil)
+	calendarService.runScheduledSync(calendar, DefaultSyncInterval, func(*model.Calendar) error {
+		claimed, err := calendarRepo.ClaimSyncLease(calendar.ID, "other", time.Now(), time.Now().Add(syncLeaseDuration))
+		if err != nil || claimed {
+			t.Errorf("Expected the lease to be held during the sync, claimed=%v err=%v", claimed, err)
+		}
+
+		// The lease is extended while the sync runs
+		stored, _ := calendarRepo.FindByID(fmt.Sprint(calendar.ID))
+		claimedUntil := *stored.SyncLockedUntil
+		time.Sleep(5 * syncLeaseRenewInterval)
+		stored, _ = calendarRepo.FindByID(fmt.Sprint(calendar.ID))
+		if !stored.SyncLockedUntil.After(claimedUntil) {
+			t.Errorf("Expected the lease to be renewed past %v, got %v", claimedUntil, stored.SyncLockedUntil)
+		}
+		return nil
+	})
+
+	if stored, _ := calendarRepo.FindByID(fmt.Sprint(calendar.ID)); stored.SyncLockedUntil != nil {
+		t.Errorf("Expected the lease to be released, got %v", stored.SyncLockedUntil)
+	}
+}
+
+func TestRunScheduledSyncSchedulesNextSync(t *testing.T) {
+	calendarService, calendarRepo := newTestCalendarService(t)
+
+	calendar := createSyncTestCalendar(t, calendarRepo, model.SourceGoogle, nil)
+	calendar.SyncIntervalMinutes = 30
+	if err := calendarRepo.Update(calendar); err != nil {
+		t.Fatalf("Failed to update calendar: %v", err)
+	}
+
+	var syncs int
+	before := time.Now()
+	calendarService.runScheduledSync(calendar, DefaultSyncInterval, func(*model.Calendar) error {
+		syncs++
+		return fmt.Errorf("google unavailable")
+	})
+
+	if syncs != 1 {
+		t.Fatalf("Expected 1 sync, got %d", syncs)
+	}
+
+	stored, err := calendarRepo.FindByID(fmt.Sprint(calendar.ID))
+	if err != nil {
+		t.Fatalf("Failed to find calendar: %v", err)
+	}
+	if stored.SyncLockedUntil != nil {
+		t.Error("Expected lease to be released")
+	}
+	if stored.NextSyncAt == nil {
+		t.Fatal("Expected next sync to be scheduled after a failed sync")
+	}
+
+	interval := 30 * time.Minute
+	earliest := before.Add(interval)
+	latest := time.Now().Add(interval + time.Duration(float64(interval)*syncJitterFraction))
+	if stored.NextSyncAt.Before(earliest) || stored.NextSyncAt.After(latest) {
+		t.Errorf("Expected next sync between %v and %v, got %v", earliest, latest, stored.NextSyncAt)
+	}
+}
+
+func TestRunSyncSchedulerSyncsEachDueCalendarOnce(t *testing.T) {
+	calendarService, calendarRepo := newTestCalendarService(t)
+
+	const count = 6
+	for i := 0; i < count; i++ {
+		createSyncTestCalendar(t, calendarRepo, model.SourceGoogle, nil)
+	}
+
+	var mu sync.Mutex
+	syncs := map[uint64]int{}
+	var running, maxRunning atomic.Int32
+	done := make(chan struct{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go func() {
+		calendarService.runSyncScheduler(ctx, SyncSchedulerConfig{Interval: time.Hour, Workers: 2}, func(calendar *model.Calendar) error {
+			current := running.Add(1)
+			defer running.Add(-1)
+			for {
+				previous := maxRunning.Load()
+				if current <= previous || maxRunning.CompareAndSwap(previous, current) {
+					break
+				}
+			}
+			time.Sleep(10 * time.Millisecond)
+
+			mu.Lock()
+			defer mu.Unlock()
+			syncs[calendar.ID]++
+			if len(syncs) == count {
+				select {
+				case <-done:
+				default:
+					close(done)
+				}
+			}
+			return nil
+		})
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for calendars to be synced")
+	}
+	cancel()
+
+	mu.Lock()
+	defer mu.Unlock()
+	for id, n := range syncs {
+		if n != 1 {
+			t.Errorf("Expected calendar %d to be synced once, got %d", id, n)
+		}
+	}
+	if maxRunning.Load() > 2 {
+		t.Errorf("Expected at most 2 concurrent syncs, got %d", maxRunning.Load())
+	}
+}
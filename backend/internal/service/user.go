@@ -323,7 +323,7 @@ func (s *UserService) generateRandomUsername() string {
 
 // UpdateGoogleAccountTokens updates the OAuth tokens for a Google account
 func (s *UserService) UpdateGoogleAccountTokens(userID uint64, token *oauth2.Token) error {
-	return s.userRepo.UpdateGoogleAccountTokens(userID, token.AccessToken, token.RefreshToken, &token.Expiry)
+	return s.userRepo.UpdateGoogleAccountTokens(userID, token.AccessToken, token.RefreshToken, &token.Expiry, grantedScopes(token))
 }
 
 // GetGoogleAccountByUserID retrieves a Google account by user ID
@@ -0,0 +1,430 @@
+package caldav
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const (
+	// maxRedirects bounds the redirects followed for a single request, servers commonly redirect
+	// /.well-known/caldav to the actual endpoint
+	maxRedirects = 5
+	// maxResponseSize caps the size of a response, calendars with years of events stay far below it
+	maxResponseSize = 50 << 20
+)
+
+var (
+	// ErrUnauthorized is returned when the server rejects the credentials
+	ErrUnauthorized = errors.New("invalid credentials")
+	// ErrInvalidSyncToken is returned when the server no longer accepts a sync token, the
+	// collection has to be fetched in full again
+	ErrInvalidSyncToken = errors.New("invalid sync token")
+	// ErrNoCalendarHome is returned when discovery finds no calendar home for the user
+	ErrNoCalendarHome = errors.New("no CalDAV calendar home found")
+)
+
+// Calendar represents a calendar collection of a CalDAV server
+type Calendar struct {
+	URL         string
+	DisplayName string
+	Description string
+	Color       string
+	TimeZone    string // IANA name of the calendar-timezone, if it has one
+}
+
+// CollectionState holds the properties that change whenever a collection changes
+type CollectionState struct {
+	SyncToken string // RFC 6578 sync token, empty if the server does not support sync-collection
+	CTag      string // getctag of the CalendarServer extension, empty if the server does not support it
+}
+
+// Resource identifies a version of a calendar object resource
+type Resource struct {
+	URL  string
+	ETag string
+}
+
+// Object represents a calendar object resource with its iCalendar data
+type Object struct {
+	URL  string
+	ETag string
+	Data string
+}
+
+// SyncChanges represents the changes of a collection since a sync token
+type SyncChanges struct {
+	Changed   []Resource // Resources created or modified
+	Deleted   []string   // URLs of deleted resources
+	SyncToken string     // Token for the next sync
+}
+
+// Client talks to a CalDAV server (RFC 4791) with basic authentication
+type Client struct {
+	httpClient *http.Client
+	username   string
+	password   string
+}
+
+// NewClient creates a client that sends requests through httpClient. Clients for user-supplied
+// server URLs should be given one that refuses internal addresses, see utils.NewPublicHTTPClient.
+func NewClient(httpClient *http.Client, username, password string) *Client {
+	// Redirects are followed by the client itself, so PROPFIND and REPORT keep their method and body
+	noRedirects := *httpClient
+	noRedirects.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	return &Client{
+		httpClient: &noRedirects,
+		username:   username,
+		password:   password,
+	}
+}
+
+// Discover finds the calendar home of the user, starting at serverURL. The server URL is tried
+// first and /.well-known/caldav (RFC 6764) second.
+func (c *Client) Discover(ctx context.Context, serverURL string) (string, error) {
+	principal, err := c.findPrincipal(ctx, serverURL)
+	if errors.Is(err, ErrUnauthorized) {
+		return "", err
+	}
+	if err != nil || principal == "" {
+		wellKnown, parseErr := resolve(serverURL, "/.well-known/caldav")
+		if parseErr != nil {
+			return "", parseErr
+		}
+		principal, err = c.findPrincipal(ctx, wellKnown)
+		if err != nil {
+			return "", err
+		}
+	}
+	if principal == "" {
+		return "", ErrNoCalendarHome
+	}
+
+	status, err := c.propfind(ctx, principal, "0", `<d:prop><c:calendar-home-set/></d:prop>`)
+	if err != nil {
+		return "", err
+	}
+	for _, resp := range status.Responses {
+		if home := resp.props().CalendarHomeSet; home != nil && home.Href != "" {
+			return resolve(principal, home.Href)
+		}
+	}
+
+	return "", ErrNoCalendarHome
+}
+
+// findPrincipal returns the URL of the current user principal, or an empty string if the server
+// did not report one at rawURL
+func (c *Client) findPrincipal(ctx context.Context, rawURL string) (string, error) {
+	status, err := c.propfind(ctx, rawURL, "0", `<d:prop><d:current-user-principal/></d:prop>`)
+	if err != nil {
+		return "", err
+	}
+	for _, resp := range status.Responses {
+		if principal := resp.props().CurrentUserPrincipal; principal != nil && principal.Href != "" {
+			return resolve(rawURL, principal.Href)
+		}
+	}
+	return "", nil
+}
+
+// FindCalendars lists the calendar collections of a calendar home that can hold events
+func (c *Client) FindCalendars(ctx context.Context, homeURL string) ([]*Calendar, error) {
+	status, err := c.propfind(ctx, homeURL, "1", `<d:prop><d:resourcetype/><d:displayname/>`+
+		`<c:calendar-description/><c:calendar-timezone/><c:supported-calendar-component-set/>`+
+		`<a:calendar-color/></d:prop>`)
+	if err != nil {
+		return nil, err
+	}
+
+	var calendars []*Calendar
+	for _, resp := range status.Responses {
+		props := resp.props()
+		if props.ResourceType == nil || props.ResourceType.Calendar == nil || !supportsEvents(props.SupportedComponents) {
+			continue
+		}
+
+		calendarURL, err := resolve(homeURL, resp.Href)
+		if err != nil {
+			continue
+		}
+
+		calendars = append(calendars, &Calendar{
+			URL:         calendarURL,
+			DisplayName: props.DisplayName,
+			Description: props.CalendarDescription,
+			Color:       normalizeColor(props.CalendarColor),
+			TimeZone:    timeZoneID(props.CalendarTimeZone),
+		})
+	}
+
+	return calendars, nil
+}
+
+// State fetches the sync token and ctag of a collection
+func (c *Client) State(ctx context.Context, calendarURL string) (*CollectionState, error) {
+	status, err := c.propfind(ctx, calendarURL, "0", `<d:prop><d:sync-token/><cs:getctag/></d:prop>`)
+	if err != nil {
+		return nil, err
+	}
+
+	state := &CollectionState{}
+	for _, resp := range status.Responses {
+		props := resp.props()
+		state.SyncToken = firstNonEmpty(state.SyncToken, props.SyncToken)
+		state.CTag = firstNonEmpty(state.CTag, props.CTag)
+	}
+	return state, nil
+}
+
+// ListResources lists the URL and ETag of every resource in a collection
+func (c *Client) ListResources(ctx context.Context, calendarURL string) ([]Resource, error) {
+	status, err := c.propfind(ctx, calendarURL, "1", `<d:prop><d:resourcetype/><d:getetag/></d:prop>`)
+	if err != nil {
+		return nil, err
+	}
+
+	var resources []Resource
+	for _, resp := range status.Responses {
+		props := resp.props()
+		if props.ResourceType != nil && props.ResourceType.Collection != nil {
+			continue
+		}
+
+		resourceURL, err := resolve(calendarURL, resp.Href)
+		if err != nil || sameURL(resourceURL, calendarURL) {
+			continue
+		}
+		resources = append(resources, Resource{URL: resourceURL, ETag: props.ETag})
+	}
+	return resources, nil
+}
+
+// QueryEvents fetches every event of a collection with a calendar-query REPORT
+func (c *Client) QueryEvents(ctx context.Context, calendarURL string) ([]*Object, error) {
+	body := `<c:calendar-query ` + namespaces + `><d:prop><d:getetag/><c:calendar-data/></d:prop>` +
+		`<c:filter><c:comp-filter name="VCALENDAR"><c:comp-filter name="VEVENT"/></c:comp-filter></c:filter>` +
+		`</c:calendar-query>`
+
+	status, err := c.report(ctx, calendarURL, "1", body)
+	if err != nil {
+		return nil, err
+	}
+	return objects(calendarURL, status), nil
+}
+
+// MultiGet fetches the given resources of a collection with a calendar-multiget REPORT
+func (c *Client) MultiGet(ctx context.Context, calendarURL string, resourceURLs []string) ([]*Object, error) {
+	if len(resourceURLs) == 0 {
+		return nil, nil
+	}
+
+	var body strings.Builder
+	body.WriteString(`<c:calendar-multiget ` + namespaces + `><d:prop><d:getetag/><c:calendar-data/></d:prop>`)
+	for _, resourceURL := range resourceURLs {
+		parsed, err := url.Parse(resourceURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid resource URL %q: %w", resourceURL, err)
+		}
+		body.WriteString(`<d:href>` + escape(parsed.EscapedPath()) + `</d:href>`)
+	}
+	body.WriteString(`</c:calendar-multiget>`)
+
+	status, err := c.report(ctx, calendarURL, "1", body.String())
+	if err != nil {
+		return nil, err
+	}
+	return objects(calendarURL, status), nil
+}
+
+// SyncCollection fetches the changes of a collection since syncToken with a sync-collection
+// REPORT (RFC 6578). It returns ErrInvalidSyncToken if the server no longer accepts the token.
+func (c *Client) SyncCollection(ctx context.Context, calendarURL, syncToken string) (*SyncChanges, error) {
+	body := `<d:sync-collection ` + namespaces + `><d:sync-token>` + escape(syncToken) + `</d:sync-token>` +
+		`<d:sync-level>1</d:sync-level><d:prop><d:getetag/></d:prop></d:sync-collection>`
+
+	status, err := c.report(ctx, calendarURL, "0", body)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := &SyncChanges{SyncToken: status.SyncToken}
+	for _, resp := range status.Responses {
+		resourceURL, err := resolve(calendarURL, resp.Href)
+		if err != nil || sameURL(resourceURL, calendarURL) {
+			continue
+		}
+
+		if statusCode(resp.Status) == http.StatusNotFound {
+			changes.Deleted = append(changes.Deleted, resourceURL)
+			continue
+		}
+		changes.Changed = append(changes.Changed, Resource{URL: resourceURL, ETag: resp.props().ETag})
+	}
+
+	return changes, nil
+}
+
+// namespaces declares the prefixes used in request bodies
+const namespaces = `xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav" ` +
+	`xmlns:cs="http://calendarserver.org/ns/" xmlns:a="http://apple.com/ns/ical/"`
+
+// propfind sends a PROPFIND request with the given prop element
+func (c *Client) propfind(ctx context.Context, rawURL, depth, props string) (*multistatus, error) {
+	return c.multistatus(ctx, "PROPFIND", rawURL, depth, `<d:propfind `+namespaces+`>`+props+`</d:propfind>`)
+}
+
+// report sends a REPORT request
+func (c *Client) report(ctx context.Context, rawURL, depth, body string) (*multistatus, error) {
+	return c.multistatus(ctx, "REPORT", rawURL, depth, body)
+}
+
+// multistatus sends a request and decodes its multistatus response
+func (c *Client) multistatus(ctx context.Context, method, rawURL, depth, body string) (*multistatus, error) {
+	resp, err := c.do(ctx, method, rawURL, depth, `<?xml version="1.0" encoding="utf-8"?>`+body)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if len(data) > maxResponseSize {
+		return nil, fmt.Errorf("response exceeds %d bytes", maxResponseSize)
+	}
+
+	switch resp.StatusCode {
+	case http.StatusMultiStatus:
+	case http.StatusUnauthorized:
+		return nil, ErrUnauthorized
+	case http.StatusForbidden, http.StatusConflict:
+		var precondition errorBody
+		if xml.Unmarshal(data, &precondition) == nil && precondition.ValidSyncToken != nil {
+			return nil, ErrInvalidSyncToken
+		}
+		return nil, fmt.Errorf("%s %s: unexpected status %d", method, rawURL, resp.StatusCode)
+	default:
+		return nil, fmt.Errorf("%s %s: unexpected status %d", method, rawURL, resp.StatusCode)
+	}
+
+	var status multistatus
+	if err := xml.Unmarshal(data, &status); err != nil {
+		return nil, fmt.Errorf("failed to parse multistatus response: %w", err)
+	}
+	return &status, nil
+}
+
+// do sends a request, following redirects with the same method and body
+func (c *Client) do(ctx context.Context, method, rawURL, depth, body string) (*http.Response, error) {
+	for redirects := 0; ; redirects++ {
+		req, err := http.NewRequestWithContext(ctx, method, rawURL, strings.NewReader(body))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+		req.SetBasicAuth(c.username, c.password)
+		req.Header.Set("Content-Type", "application/xml; charset=utf-8")
+		if depth != "" {
+			req.Header.Set("Depth", depth)
+		}
+
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+
+		location := resp.Header.Get("Location")
+		switch resp.StatusCode {
+		case http.StatusMovedPermanently, http.StatusFound, http.StatusSeeOther,
+			http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		default:
+			return resp, nil
+		}
+		resp.Body.Close()
+
+		if location == "" || redirects >= maxRedirects {
+			return nil, fmt.Errorf("%s %s: too many redirects", method, rawURL)
+		}
+		if rawURL, err = resolve(rawURL, location); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// objects extracts the calendar objects of a REPORT response
+func objects(calendarURL string, status *multistatus) []*Object {
+	var objects []*Object
+	for _, resp := range status.Responses {
+		props := resp.props()
+		if props.CalendarData == "" {
+			continue
+		}
+
+		objectURL, err := resolve(calendarURL, resp.Href)
+		if err != nil {
+			continue
+		}
+		objects = append(objects, &Object{URL: objectURL, ETag: props.ETag, Data: props.CalendarData})
+	}
+	return objects
+}
+
+// supportsEvents reports whether a calendar accepts VEVENT components. Calendars that do not
+// restrict their components accept all of them.
+func supportsEvents(supported *supportedComponent) bool {
+	if supported == nil || len(supported.Components) == 0 {
+		return true
+	}
+	for _, component := range supported.Components {
+		if strings.EqualFold(component.Name, "VEVENT") {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeColor turns the #RRGGBBAA colors of Apple clients into #RRGGBB
+func normalizeColor(color string) string {
+	color = strings.TrimSpace(color)
+	if len(color) == 9 && strings.HasPrefix(color, "#") {
+		return color[:7]
+	}
+	return color
+}
+
+// timeZoneID extracts the TZID of the VTIMEZONE in a calendar-timezone property
+func timeZoneID(vtimezone string) string {
+	for _, line := range strings.Split(vtimezone, "\n") {
+		if value, ok := strings.CutPrefix(strings.TrimSpace(line), "TZID:"); ok {
+			return value
+		}
+	}
+	return ""
+}
+
+// resolve resolves href against base and returns an absolute URL
+func resolve(base, href string) (string, error) {
+	baseURL, err := url.Parse(base)
+	if err != nil {
+		return "", fmt.Errorf("invalid URL %q: %w", base, err)
+	}
+	ref, err := url.Parse(strings.TrimSpace(href))
+	if err != nil {
+		return "", fmt.Errorf("invalid href %q: %w", href, err)
+	}
+	return baseURL.ResolveReference(ref).String(), nil
+}
+
+// sameURL reports whether two URLs point to the same resource, ignoring a trailing slash
+func sameURL(a, b string) bool {
+	return strings.TrimSuffix(a, "/") == strings.TrimSuffix(b, "/")
+}
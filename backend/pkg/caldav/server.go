@@ -0,0 +1,239 @@
+package caldav
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Report types a server can be asked for (RFC 4791 section 7)
+const (
+	ReportCalendarQuery    = "calendar-query"
+	ReportCalendarMultiGet = "calendar-multiget"
+)
+
+// Properties maps property names to their inner XML, as produced by Text and Href
+type Properties map[xml.Name]string
+
+// PropFind is a parsed PROPFIND request body
+type PropFind struct {
+	AllProp bool       // Every property was asked for, also when the body is empty
+	Props   []xml.Name // Properties asked for unless AllProp is set
+}
+
+// Report is a parsed REPORT request body
+type Report struct {
+	Type  string     // Local name of the report, such as ReportCalendarQuery
+	Props []xml.Name // Properties asked for, empty for every property
+	Hrefs []string   // Resources of a calendar-multiget
+	Start time.Time  // Start of the time-range filter of a calendar-query, zero without one
+	End   time.Time  // End of the time-range filter of a calendar-query, zero without one
+}
+
+// ParsePropFind parses the body of a PROPFIND request. An empty body asks for every property
+// (RFC 4918 section 9.1).
+func ParsePropFind(body io.Reader) (*PropFind, error) {
+	decoder := xml.NewDecoder(body)
+	propFind := &PropFind{}
+	depth := 0
+	inProp := false
+
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid PROPFIND body: %w", err)
+		}
+
+		switch element := token.(type) {
+		case xml.StartElement:
+			depth++
+			switch {
+			case depth == 2 && element.Name == xml.Name{Space: NamespaceDAV, Local: "allprop"}:
+				propFind.AllProp = true
+			case depth == 2 && element.Name == xml.Name{Space: NamespaceDAV, Local: "prop"}:
+				inProp = true
+			case depth == 3 && inProp:
+				propFind.Props = append(propFind.Props, element.Name)
+			}
+		case xml.EndElement:
+			if depth == 2 {
+				inProp = false
+			}
+			depth--
+		}
+	}
+
+	if len(propFind.Props) == 0 {
+		propFind.AllProp = true
+	}
+	return propFind, nil
+}
+
+// ParseReport parses the body of a REPORT request
+func ParseReport(body io.Reader) (*Report, error) {
+	decoder := xml.NewDecoder(body)
+	report := &Report{}
+	var path []xml.Name
+
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid REPORT body: %w", err)
+		}
+
+		switch element := token.(type) {
+		case xml.StartElement:
+			path = append(path, element.Name)
+			switch {
+			case len(path) == 1:
+				report.Type = element.Name.Local
+			case len(path) == 3 && path[1] == xml.Name{Space: NamespaceDAV, Local: "prop"}:
+				report.Props = append(report.Props, element.Name)
+			case element.Name == xml.Name{Space: NamespaceCalDAV, Local: "time-range"}:
+				for _, attr := range element.Attr {
+					value, err := time.Parse("20060102T150405Z", attr.Value)
+					if err != nil {
+						return nil, fmt.Errorf("invalid time-range %s: %w", attr.Name.Local, err)
+					}
+					switch attr.Name.Local {
+					case "start":
+						report.Start = value
+					case "end":
+						report.End = value
+					}
+				}
+			}
+		case xml.CharData:
+			if len(path) == 2 && path[1] == (xml.Name{Space: NamespaceDAV, Local: "href"}) {
+				if href := strings.TrimSpace(string(element)); href != "" {
+					report.Hrefs = append(report.Hrefs, href)
+				}
+			}
+		case xml.EndElement:
+			path = path[:len(path)-1]
+		}
+	}
+
+	if report.Type == "" {
+		return nil, fmt.Errorf("invalid REPORT body: missing report element")
+	}
+	return report, nil
+}
+
+// MultiStatus builds the body of a 207 Multi-Status response
+type MultiStatus struct {
+	body strings.Builder
+}
+
+// AddProps adds a resource with the requested properties. Requested properties the resource does
+// not have are reported as 404 Not Found, without requested properties every property is added.
+func (m *MultiStatus) AddProps(href string, props Properties, requested []xml.Name) {
+	if len(requested) == 0 {
+		for name := range props {
+			requested = append(requested, name)
+		}
+		sort.Slice(requested, func(i, j int) bool {
+			if requested[i].Space != requested[j].Space {
+				return requested[i].Space < requested[j].Space
+			}
+			return requested[i].Local < requested[j].Local
+		})
+	}
+
+	var found, missing strings.Builder
+	for _, name := range requested {
+		value, ok := props[name]
+		if !ok {
+			missing.WriteString(element(name, ""))
+			continue
+		}
+		found.WriteString(element(name, value))
+	}
+
+	m.body.WriteString(`<d:response><d:href>` + escape(href) + `</d:href>`)
+	if found.Len() > 0 {
+		m.body.WriteString(`<d:propstat><d:prop>` + found.String() + `</d:prop>` + status(http.StatusOK) + `</d:propstat>`)
+	}
+	if missing.Len() > 0 {
+		m.body.WriteString(`<d:propstat><d:prop>` + missing.String() + `</d:prop>` + status(http.StatusNotFound) + `</d:propstat>`)
+	}
+	m.body.WriteString(`</d:response>`)
+}
+
+// AddStatus adds a resource with a status instead of properties, such as 404 for a resource
+// of a calendar-multiget that does not exist
+func (m *MultiStatus) AddStatus(href string, statusCode int) {
+	m.body.WriteString(`<d:response><d:href>` + escape(href) + `</d:href>` + status(statusCode) + `</d:response>`)
+}
+
+// WriteTo writes the response
+func (m *MultiStatus) WriteTo(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.WriteHeader(http.StatusMultiStatus)
+	io.WriteString(w, `<?xml version="1.0" encoding="utf-8"?><d:multistatus `+namespaces+`>`+m.body.String()+`</d:multistatus>`)
+}
+
+// Text returns the inner XML of a property holding text
+func Text(value string) string {
+	return escape(value)
+}
+
+// Href returns the inner XML of a property holding an href, such as current-user-principal
+func Href(href string) string {
+	return `<d:href>` + escape(href) + `</d:href>`
+}
+
+// Elements returns the inner XML of a property holding empty elements, such as resourcetype
+func Elements(names ...xml.Name) string {
+	var elements strings.Builder
+	for _, name := range names {
+		elements.WriteString(element(name, ""))
+	}
+	return elements.String()
+}
+
+// WriteError writes an error response carrying a precondition (RFC 4918 section 16), such as
+// CalDAV's valid-calendar-data
+func WriteError(w http.ResponseWriter, statusCode int, precondition xml.Name) {
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.WriteHeader(statusCode)
+	io.WriteString(w, `<?xml version="1.0" encoding="utf-8"?><d:error `+namespaces+`>`+element(precondition, "")+`</d:error>`)
+}
+
+// prefixes are the prefixes declared by namespaces
+var prefixes = map[string]string{
+	NamespaceDAV:            "d",
+	NamespaceCalDAV:         "c",
+	NamespaceCalendarServer: "cs",
+	NamespaceApple:          "a",
+}
+
+// element renders an element, declaring its namespace unless it is one of the common ones
+func element(name xml.Name, inner string) string {
+	tag, declaration := name.Local, ""
+	if prefix, ok := prefixes[name.Space]; ok {
+		tag = prefix + ":" + name.Local
+	} else if name.Space != "" {
+		tag = "x:" + name.Local
+		declaration = ` xmlns:x="` + escape(name.Space) + `"`
+	}
+
+	if inner == "" {
+		return `<` + tag + declaration + `/>`
+	}
+	return `<` + tag + declaration + `>` + inner + `</` + tag + `>`
+}
+
+func status(statusCode int) string {
+	return fmt.Sprintf(`<d:status>HTTP/1.1 %d %s</d:status>`, statusCode, http.StatusText(statusCode))
+}
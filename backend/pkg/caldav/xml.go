@@ -0,0 +1,129 @@
+package caldav
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strconv"
+	"strings"
+)
+
+// XML namespaces of the properties used by CalDAV clients and servers
+const (
+	NamespaceDAV            = "DAV:"
+	NamespaceCalDAV         = "urn:ietf:params:xml:ns:caldav"
+	NamespaceCalendarServer = "http://calendarserver.org/ns/"
+	NamespaceApple          = "http://apple.com/ns/ical/"
+)
+
+// multistatus is the body of a 207 Multi-Status response (RFC 4918 section 13)
+type multistatus struct {
+	XMLName   xml.Name   `xml:"DAV: multistatus"`
+	Responses []response `xml:"DAV: response"`
+	SyncToken string     `xml:"DAV: sync-token"`
+}
+
+// response describes one resource of a multistatus response
+type response struct {
+	Href      string     `xml:"DAV: href"`
+	Status    string     `xml:"DAV: status"`
+	Propstats []propstat `xml:"DAV: propstat"`
+}
+
+// propstat groups the properties of a resource that share a status
+type propstat struct {
+	Prop   prop   `xml:"DAV: prop"`
+	Status string `xml:"DAV: status"`
+}
+
+// prop holds every property the client asks for, properties missing from a response stay empty
+type prop struct {
+	CurrentUserPrincipal *hrefProp           `xml:"DAV: current-user-principal"`
+	CalendarHomeSet      *hrefProp           `xml:"urn:ietf:params:xml:ns:caldav calendar-home-set"`
+	ResourceType         *resourceType       `xml:"DAV: resourcetype"`
+	DisplayName          string              `xml:"DAV: displayname"`
+	CalendarDescription  string              `xml:"urn:ietf:params:xml:ns:caldav calendar-description"`
+	CalendarTimeZone     string              `xml:"urn:ietf:params:xml:ns:caldav calendar-timezone"`
+	CalendarColor        string              `xml:"http://apple.com/ns/ical/ calendar-color"`
+	SupportedComponents  *supportedComponent `xml:"urn:ietf:params:xml:ns:caldav supported-calendar-component-set"`
+	SyncToken            string              `xml:"DAV: sync-token"`
+	CTag                 string              `xml:"http://calendarserver.org/ns/ getctag"`
+	ETag                 string              `xml:"DAV: getetag"`
+	CalendarData         string              `xml:"urn:ietf:params:xml:ns:caldav calendar-data"`
+}
+
+type hrefProp struct {
+	Href string `xml:"DAV: href"`
+}
+
+type resourceType struct {
+	Collection *struct{} `xml:"DAV: collection"`
+	Calendar   *struct{} `xml:"urn:ietf:params:xml:ns:caldav calendar"`
+}
+
+type supportedComponent struct {
+	Components []struct {
+		Name string `xml:"name,attr"`
+	} `xml:"urn:ietf:params:xml:ns:caldav comp"`
+}
+
+// errorBody is the body of an error response carrying a precondition (RFC 4918 section 16)
+type errorBody struct {
+	XMLName        xml.Name  `xml:"DAV: error"`
+	ValidSyncToken *struct{} `xml:"DAV: valid-sync-token"`
+}
+
+// props merges the properties of every successful propstat of a response
+func (r *response) props() *prop {
+	merged := &prop{}
+	for _, ps := range r.Propstats {
+		if ps.Status != "" && statusCode(ps.Status) != 200 {
+			continue
+		}
+		p := ps.Prop
+		if p.CurrentUserPrincipal != nil {
+			merged.CurrentUserPrincipal = p.CurrentUserPrincipal
+		}
+		if p.CalendarHomeSet != nil {
+			merged.CalendarHomeSet = p.CalendarHomeSet
+		}
+		if p.ResourceType != nil {
+			merged.ResourceType = p.ResourceType
+		}
+		if p.SupportedComponents != nil {
+			merged.SupportedComponents = p.SupportedComponents
+		}
+		merged.DisplayName = firstNonEmpty(merged.DisplayName, p.DisplayName)
+		merged.CalendarDescription = firstNonEmpty(merged.CalendarDescription, p.CalendarDescription)
+		merged.CalendarTimeZone = firstNonEmpty(merged.CalendarTimeZone, p.CalendarTimeZone)
+		merged.CalendarColor = firstNonEmpty(merged.CalendarColor, p.CalendarColor)
+		merged.SyncToken = firstNonEmpty(merged.SyncToken, p.SyncToken)
+		merged.CTag = firstNonEmpty(merged.CTag, p.CTag)
+		merged.ETag = firstNonEmpty(merged.ETag, p.ETag)
+		merged.CalendarData = firstNonEmpty(merged.CalendarData, p.CalendarData)
+	}
+	return merged
+}
+
+// statusCode extracts the code of a status line such as "HTTP/1.1 404 Not Found"
+func statusCode(status string) int {
+	fields := strings.Fields(status)
+	if len(fields) < 2 {
+		return 0
+	}
+	code, _ := strconv.Atoi(fields[1])
+	return code
+}
+
+func firstNonEmpty(a, b string) string {
+	if a != "" {
+		return a
+	}
+	return b
+}
+
+// escape escapes text for use inside an XML element
+func escape(text string) string {
+	var buf bytes.Buffer
+	xml.EscapeText(&buf, []byte(text))
+	return buf.String()
+}
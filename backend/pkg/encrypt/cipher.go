@@ -0,0 +1,67 @@
+package encrypt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+)
+
+// Cipher encrypts secrets that have to be read back, such as passwords of remote accounts, with
+// AES-256-GCM
+type Cipher struct {
+	aead cipher.AEAD
+}
+
+// NewCipher creates a cipher with a key derived from secret
+func NewCipher(secret string) (*Cipher, error) {
+	if secret == "" {
+		return nil, fmt.Errorf("secret is required")
+	}
+
+	key := sha256.Sum256([]byte(secret))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+
+	return &Cipher{aead: aead}, nil
+}
+
+// Encrypt encrypts plaintext with a random nonce and returns the nonce and ciphertext base64 encoded
+func (c *Cipher) Encrypt(plaintext string) (string, error) {
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	sealed := c.aead.Seal(nonce, nonce, []byte(plaintext), nil)
+	return base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt decrypts a value returned by Encrypt
+func (c *Cipher) Decrypt(encoded string) (string, error) {
+	sealed, err := base64.RawStdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", fmt.Errorf("invalid ciphertext: %w", err)
+	}
+
+	nonceSize := c.aead.NonceSize()
+	if len(sealed) < nonceSize {
+		return "", fmt.Errorf("invalid ciphertext: too short")
+	}
+
+	plaintext, err := c.aead.Open(nil, sealed[:nonceSize], sealed[nonceSize:], nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt: %w", err)
+	}
+
+	return string(plaintext), nil
+}
@@ -0,0 +1,57 @@
+package encrypt
+
+import (
+	"testing"
+)
+
+func TestCipherRoundTrip(t *testing.T) {
+	c, err := NewCipher("test-secret")
+	if err != nil {
+		t.Fatalf("Failed to create cipher: %v", err)
+	}
+
+	encrypted, err := c.Encrypt("app-password")
+	if err != nil {
+		t.Fatalf("Failed to encrypt: %v", err)
+	}
+	if encrypted == "app-password" {
+		t.Error("Encrypted value should differ from the plaintext")
+	}
+
+	// Nonces are random, so the same plaintext never encrypts to the same value
+	again, err := c.Encrypt("app-password")
+	if err != nil {
+		t.Fatalf("Failed to encrypt: %v", err)
+	}
+	if again == encrypted {
+		t.Error("Encrypting twice should give different values")
+	}
+
+	decrypted, err := c.Decrypt(encrypted)
+	if err != nil {
+		t.Fatalf("Failed to decrypt: %v", err)
+	}
+	if decrypted != "app-password" {
+		t.Errorf("Expected app-password, got %q", decrypted)
+	}
+}
+
+func TestCipherRejectsWrongKey(t *testing.T) {
+	c, _ := NewCipher("test-secret")
+	other, _ := NewCipher("other-secret")
+
+	encrypted, err := c.Encrypt("app-password")
+	if err != nil {
+		t.Fatalf("Failed to encrypt: %v", err)
+	}
+
+	if _, err := other.Decrypt(encrypted); err == nil {
+		t.Error("Expected decrypting with another key to fail")
+	}
+	if _, err := c.Decrypt("bm90IGVuY3J5cHRlZA"); err == nil {
+		t.Error("Expected decrypting garbage to fail")
+	}
+	if _, err := NewCipher(""); err == nil {
+		t.Error("Expected an empty secret to be rejected")
+	}
+}
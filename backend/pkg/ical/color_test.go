@@ -0,0 +1,40 @@
+package ical
+
+import "testing"
+
+func TestParseColor(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{value: "turquoise", want: "#40e0d0"},
+		{value: " DarkSlateGrey ", want: "#2f4f4f"},
+		{value: "#FF5722", want: "#ff5722"},
+		{value: "#0082C9FF", want: "#0082c9"},
+		{value: "#f80", want: "#ff8800"},
+		{value: "#f80c", want: "#ff8800"},
+		{value: "ff5722", wantErr: true},
+		{value: "#ff572", wantErr: true},
+		{value: "#gg5722", wantErr: true},
+		{value: "rebeccapurple", wantErr: true},
+		{value: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseColor(tt.value)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("Expected an error for %q", tt.value)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Unexpected error for %q: %v", tt.value, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Expected %s for %q, got %s", tt.want, tt.value, got)
+		}
+	}
+}
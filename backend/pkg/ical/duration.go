@@ -0,0 +1,53 @@
+package ical
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// durationPattern matches RFC 5545 DURATION values such as "PT1H30M", "P1D" or "-P1W"
+var durationPattern = regexp.MustCompile(`^([+-])?P(?:(\d+)W)?(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+)S)?)?$`)
+
+// Duration represents an RFC 5545 DURATION split into its nominal (days) and exact (clock) parts,
+// so that a "P1D" duration stays one calendar day long across DST changes
+type Duration struct {
+	Days  int
+	Clock time.Duration
+}
+
+// ParseDuration parses an RFC 5545 DURATION value
+func ParseDuration(value string) (Duration, error) {
+	matches := durationPattern.FindStringSubmatch(value)
+	if matches == nil || value == "P" || value == "PT" {
+		return Duration{}, fmt.Errorf("invalid duration %q", value)
+	}
+
+	number := func(s string) int {
+		if s == "" {
+			return 0
+		}
+		n, _ := strconv.Atoi(s)
+		return n
+	}
+
+	duration := Duration{
+		Days: number(matches[2])*7 + number(matches[3]),
+		Clock: time.Duration(number(matches[4]))*time.Hour +
+			time.Duration(number(matches[5]))*time.Minute +
+			time.Duration(number(matches[6]))*time.Second,
+	}
+
+	if matches[1] == "-" {
+		duration.Days = -duration.Days
+		duration.Clock = -duration.Clock
+	}
+
+	return duration, nil
+}
+
+// AddTo returns t shifted by the duration
+func (d Duration) AddTo(t time.Time) time.Time {
+	return t.AddDate(0, 0, d.Days).Add(d.Clock)
+}
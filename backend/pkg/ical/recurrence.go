@@ -0,0 +1,761 @@
+package ical
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Frequency represents the FREQ rule part of an RRULE
+type Frequency int
+
+const (
+	Secondly Frequency = iota
+	Minutely
+	Hourly
+	Daily
+	Weekly
+	Monthly
+	Yearly
+)
+
+// maxEmptyPeriods stops the expansion of rules that can never produce an occurrence
+// (e.g. FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=30)
+const maxEmptyPeriods = 10000
+
+// maxSkippedOccurrences stops the expansion of rules with too many occurrences before the
+// requested window to step through them
+const maxSkippedOccurrences = 1000000
+
+var frequencies = map[string]Frequency{
+	"SECONDLY": Secondly,
+	"MINUTELY": Minutely,
+	"HOURLY":   Hourly,
+	"DAILY":    Daily,
+	"WEEKLY":   Weekly,
+	"MONTHLY":  Monthly,
+	"YEARLY":   Yearly,
+}
+
+var weekdays = map[string]time.Weekday{
+	"SU": time.Sunday,
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+}
+
+// WeekdayNum represents a BYDAY entry such as "MO", "2TU" or "-1FR"
+type WeekdayNum struct {
+	Weekday time.Weekday
+	N       int // Ordinal within the month or year, 0 means every matching weekday
+}
+
+// RecurrenceRule represents a parsed RFC 5545 RRULE
+type RecurrenceRule struct {
+	Freq       Frequency
+	Interval   int
+	Count      int        // 0 means unbounded
+	Until      *time.Time // Inclusive upper bound, nil means unbounded
+	BySecond   []int
+	ByMinute   []int
+	ByHour     []int
+	ByDay      []WeekdayNum
+	ByMonthDay []int
+	ByYearDay  []int
+	ByWeekNo   []int
+	ByMonth    []int
+	BySetPos   []int
+	WeekStart  time.Weekday
+}
+
+// ParseRecurrenceRule parses an RRULE value such as "FREQ=WEEKLY;BYDAY=MO,WE;COUNT=10"
+// Floating and date-only UNTIL values are interpreted in loc
+func ParseRecurrenceRule(value string, loc *time.Location) (*RecurrenceRule, error) {
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	rule := &RecurrenceRule{
+		Interval:  1,
+		WeekStart: time.Monday,
+	}
+	hasFreq := false
+
+	for _, part := range strings.Split(strings.TrimPrefix(strings.TrimSpace(value), "RRULE:"), ";") {
+		if part == "" {
+			continue
+		}
+
+		key, val, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid rule part %q", part)
+		}
+
+		var err error
+		switch strings.ToUpper(key) {
+		case "FREQ":
+			freq, exists := frequencies[strings.ToUpper(val)]
+			if !exists {
+				return nil, fmt.Errorf("unsupported frequency %q", val)
+			}
+			rule.Freq = freq
+			hasFreq = true
+		case "INTERVAL":
+			rule.Interval, err = strconv.Atoi(val)
+			if err == nil && rule.Interval < 1 {
+				err = fmt.Errorf("interval must be positive")
+			}
+		case "COUNT":
+			rule.Count, err = strconv.Atoi(val)
+			if err == nil && rule.Count < 1 {
+				err = fmt.Errorf("count must be positive")
+			}
+		case "UNTIL":
+			var until time.Time
+			until, err = parseUntil(val, loc)
+			rule.Until = &until
+		case "BYSECOND":
+			rule.BySecond, err = parseIntList(val, 0, 60, false)
+		case "BYMINUTE":
+			rule.ByMinute, err = parseIntList(val, 0, 59, false)
+		case "BYHOUR":
+			rule.ByHour, err = parseIntList(val, 0, 23, false)
+		case "BYDAY":
+			rule.ByDay, err = parseWeekdayList(val)
+		case "BYMONTHDAY":
+			rule.ByMonthDay, err = parseIntList(val, 1, 31, true)
+		case "BYYEARDAY":
+			rule.ByYearDay, err = parseIntList(val, 1, 366, true)
+		case "BYWEEKNO":
+			rule.ByWeekNo, err = parseIntList(val, 1, 53, true)
+		case "BYMONTH":
+			rule.ByMonth, err = parseIntList(val, 1, 12, false)
+		case "BYSETPOS":
+			rule.BySetPos, err = parseIntList(val, 1, 366, true)
+		case "WKST":
+			weekday, exists := weekdays[strings.ToUpper(val)]
+			if !exists {
+				err = fmt.Errorf("invalid weekday %q", val)
+			}
+			rule.WeekStart = weekday
+		default:
+			// Ignore unknown rule parts (e.g. X- extensions, RSCALE)
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", strings.ToUpper(key), err)
+		}
+	}
+
+	if !hasFreq {
+		return nil, fmt.Errorf("rule is missing FREQ")
+	}
+
+	if rule.Count > 0 && rule.Until != nil {
+		return nil, fmt.Errorf("COUNT and UNTIL must not both be set")
+	}
+
+	return rule, nil
+}
+
+// Occurrences returns the start times generated by the rule for the given DTSTART in ascending order
+// DTSTART always counts as the first occurrence. Expansion stops once an occurrence would start
+// after end. At most limit occurrences are returned (0 means no limit): those from start on come
+// first, earlier ones only fill the room they leave, the latest first.
+func (r *RecurrenceRule) Occurrences(dtstart, start, end time.Time, limit int) []time.Time {
+	if dtstart.After(end) {
+		return nil
+	}
+	if r.Count > 0 || limit <= 0 {
+		return r.occurrencesFrom(dtstart, 0, start, end, limit)
+	}
+
+	// Without COUNT the periods long before start don't need to be stepped through. Stepping back
+	// limit periods is enough unless some of them are empty, then it goes back further.
+	for back := limit; ; back *= 2 {
+		firstPeriod := max(r.periodBefore(dtstart, start)-back, 0)
+		occurrences := r.occurrencesFrom(dtstart, firstPeriod, start, end, limit)
+		if firstPeriod == 0 || len(occurrences) >= limit || back >= maxSkippedOccurrences {
+			return occurrences
+		}
+	}
+}
+
+// occurrencesFrom returns the occurrences of the rule starting with the given period, see Occurrences.
+// DTSTART is left out when periods are skipped and it is before start.
+func (r *RecurrenceRule) occurrencesFrom(dtstart time.Time, firstPeriod int, start, end time.Time, limit int) []time.Time {
+	var before, occurrences []time.Time
+	generated, skipped := 0, 0
+	add := func(occurrence time.Time) {
+		generated++
+		if !occurrence.Before(start) {
+			occurrences = append(occurrences, occurrence)
+			return
+		}
+
+		skipped++
+		before = append(before, occurrence)
+		// Only the latest limit occurrences before start can be returned
+		if limit > 0 && len(before) >= 2*limit {
+			before = append(before[:0], before[len(before)-limit:]...)
+		}
+	}
+
+	done := func() bool {
+		if r.Count > 0 && generated >= r.Count {
+			return true
+		}
+		if limit > 0 && len(occurrences) >= limit {
+			return true
+		}
+		return skipped > maxSkippedOccurrences
+	}
+
+	if firstPeriod == 0 || !dtstart.Before(start) {
+		add(dtstart)
+	}
+
+	emptyPeriods := 0
+	for period := firstPeriod; !done(); period++ {
+		candidates, periodStart := r.expandPeriod(dtstart, period)
+
+		// Nothing in later periods can be inside the window once the period itself starts after it
+		if periodStart.After(end) || (r.Until != nil && periodStart.After(*r.Until)) {
+			break
+		}
+
+		if len(candidates) == 0 {
+			emptyPeriods++
+			if emptyPeriods > maxEmptyPeriods {
+				break
+			}
+			continue
+		}
+		emptyPeriods = 0
+
+		for _, candidate := range candidates {
+			if !candidate.After(dtstart) {
+				continue
+			}
+			if candidate.After(end) || (r.Until != nil && candidate.After(*r.Until)) {
+				return keepNearest(append(before, occurrences...), start, limit)
+			}
+
+			add(candidate)
+			if done() {
+				break
+			}
+		}
+	}
+
+	return keepNearest(append(before, occurrences...), start, limit)
+}
+
+// periodBefore returns the index of a period starting before start, every earlier period only
+// holds instants before start
+func (r *RecurrenceRule) periodBefore(dtstart, start time.Time) int {
+	if !start.After(dtstart) {
+		return 0
+	}
+
+	var units int
+	switch r.Freq {
+	case Yearly:
+		units = start.Year() - dtstart.Year()
+	case Monthly:
+		units = (start.Year()-dtstart.Year())*12 + int(start.Month()) - int(dtstart.Month())
+	case Weekly:
+		units = int(start.Sub(dtstart).Hours()/24) / 7
+	case Daily:
+		units = int(start.Sub(dtstart).Hours() / 24)
+	default:
+		units = int(start.Sub(dtstart) / r.subDailyUnit())
+	}
+
+	// One period of margin for time zone offset changes and weeks starting before DTSTART
+	return max(units/r.Interval-1, 0)
+}
+
+// expandPeriod returns the sorted candidate instants for the n-th period after DTSTART and the
+// earliest instant the period can contain
+func (r *RecurrenceRule) expandPeriod(dtstart time.Time, n int) ([]time.Time, time.Time) {
+	loc := dtstart.Location()
+	step := n * r.Interval
+	y, m, d := dtstart.Date()
+
+	var days []time.Time
+	var periodStart time.Time
+
+	switch r.Freq {
+	case Yearly:
+		year := y + step
+		days = r.yearDays(dtstart, year)
+		periodStart = time.Date(year, time.January, 1, 0, 0, 0, 0, loc)
+		if len(r.ByWeekNo) > 0 {
+			// Week one of the year can start in the previous December
+			periodStart = periodStart.AddDate(0, 0, -6)
+		}
+	case Monthly:
+		first := time.Date(y, m+time.Month(step), 1, 0, 0, 0, 0, time.UTC)
+		days = r.monthDays(dtstart, first.Year(), first.Month())
+		periodStart = time.Date(first.Year(), first.Month(), 1, 0, 0, 0, 0, loc)
+	case Weekly:
+		offset := (int(dtstart.Weekday()) - int(r.WeekStart) + 7) % 7
+		weekStart := time.Date(y, m, d-offset+7*step, 0, 0, 0, 0, time.UTC)
+		days = r.weekDays(dtstart, weekStart)
+		periodStart = time.Date(weekStart.Year(), weekStart.Month(), weekStart.Day(), 0, 0, 0, 0, loc)
+	case Daily:
+		day := time.Date(y, m, d+step, 0, 0, 0, 0, time.UTC)
+		if r.matchesDayLimits(day) {
+			days = []time.Time{day}
+		}
+		periodStart = time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, loc)
+	default:
+		base := dtstart.Add(time.Duration(step) * r.subDailyUnit())
+		return r.subDailyInstants(base), base.Truncate(r.subDailyUnit())
+	}
+
+	var instants []time.Time
+	for _, day := range days {
+		for _, hour := range orDefault(r.ByHour, dtstart.Hour()) {
+			for _, minute := range orDefault(r.ByMinute, dtstart.Minute()) {
+				for _, second := range orDefault(r.BySecond, dtstart.Second()) {
+					instants = append(instants, time.Date(day.Year(), day.Month(), day.Day(), hour, minute, second, 0, loc))
+				}
+			}
+		}
+	}
+
+	sort.Slice(instants, func(i, j int) bool { return instants[i].Before(instants[j]) })
+	return r.applySetPos(instants), periodStart
+}
+
+// yearDays returns the candidate days of a YEARLY period
+func (r *RecurrenceRule) yearDays(dtstart time.Time, year int) []time.Time {
+	if len(r.ByDay) == 0 && len(r.ByMonthDay) == 0 && len(r.ByYearDay) == 0 && len(r.ByWeekNo) == 0 {
+		// Only BYMONTH (or nothing) given: repeat the DTSTART day of month
+		var days []time.Time
+		for _, month := range orDefault(r.ByMonth, int(dtstart.Month())) {
+			if day, ok := validDate(year, time.Month(month), dtstart.Day()); ok {
+				days = append(days, day)
+			}
+		}
+		return days
+	}
+
+	first := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+	last := time.Date(year, time.December, 31, 0, 0, 0, 0, time.UTC)
+	if len(r.ByWeekNo) > 0 {
+		first = weekOneStart(year, r.WeekStart)
+		last = weekOneStart(year+1, r.WeekStart).AddDate(0, 0, -1)
+	}
+
+	byDay := r.ByDay
+	if len(r.ByWeekNo) > 0 {
+		// Ordinals have no meaning inside a week, and a lone BYWEEKNO repeats the DTSTART weekday
+		byDay = stripOrdinals(byDay)
+		if len(byDay) == 0 && len(r.ByMonthDay) == 0 && len(r.ByYearDay) == 0 {
+			byDay = []WeekdayNum{{Weekday: dtstart.Weekday()}}
+		}
+	}
+
+	var days []time.Time
+	for day := first; !day.After(last); day = day.AddDate(0, 0, 1) {
+		if len(r.ByMonth) > 0 && !contains(r.ByMonth, int(day.Month())) {
+			continue
+		}
+		if len(r.ByWeekNo) > 0 && !r.matchesWeekNo(day, year) {
+			continue
+		}
+		if len(r.ByYearDay) > 0 && !matchesYearDay(r.ByYearDay, day) {
+			continue
+		}
+		if len(r.ByMonthDay) > 0 && !matchesMonthDay(r.ByMonthDay, day) {
+			continue
+		}
+		if len(byDay) > 0 {
+			// Ordinals are relative to the month when BYMONTH is given, otherwise to the year
+			if !matchesWeekday(byDay, day, len(r.ByMonth) > 0) {
+				continue
+			}
+		}
+		days = append(days, day)
+	}
+	return days
+}
+
+// monthDays returns the candidate days of a MONTHLY period
+func (r *RecurrenceRule) monthDays(dtstart time.Time, year int, month time.Month) []time.Time {
+	if len(r.ByMonth) > 0 && !contains(r.ByMonth, int(month)) {
+		return nil
+	}
+
+	if len(r.ByDay) == 0 && len(r.ByMonthDay) == 0 {
+		if day, ok := validDate(year, month, dtstart.Day()); ok {
+			return []time.Time{day}
+		}
+		return nil
+	}
+
+	var days []time.Time
+	for day := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC); day.Month() == month; day = day.AddDate(0, 0, 1) {
+		if len(r.ByMonthDay) > 0 && !matchesMonthDay(r.ByMonthDay, day) {
+			continue
+		}
+		if len(r.ByDay) > 0 && !matchesWeekday(r.ByDay, day, true) {
+			continue
+		}
+		days = append(days, day)
+	}
+	return days
+}
+
+// weekDays returns the candidate days of a WEEKLY period
+func (r *RecurrenceRule) weekDays(dtstart, weekStart time.Time) []time.Time {
+	byDay := r.ByDay
+	if len(byDay) == 0 {
+		byDay = []WeekdayNum{{Weekday: dtstart.Weekday()}}
+	}
+
+	var days []time.Time
+	for i := 0; i < 7; i++ {
+		day := weekStart.AddDate(0, 0, i)
+		if len(r.ByMonth) > 0 && !contains(r.ByMonth, int(day.Month())) {
+			continue
+		}
+		if matchesWeekday(stripOrdinals(byDay), day, false) {
+			days = append(days, day)
+		}
+	}
+	return days
+}
+
+// matchesDayLimits checks the BYMONTH, BYMONTHDAY and BYDAY limits used by DAILY and finer rules
+func (r *RecurrenceRule) matchesDayLimits(day time.Time) bool {
+	if len(r.ByMonth) > 0 && !contains(r.ByMonth, int(day.Month())) {
+		return false
+	}
+	if len(r.ByMonthDay) > 0 && !matchesMonthDay(r.ByMonthDay, day) {
+		return false
+	}
+	if len(r.ByYearDay) > 0 && !matchesYearDay(r.ByYearDay, day) {
+		return false
+	}
+	if len(r.ByDay) > 0 && !matchesWeekday(stripOrdinals(r.ByDay), day, false) {
+		return false
+	}
+	return true
+}
+
+// subDailyUnit returns the step size of HOURLY, MINUTELY and SECONDLY rules
+func (r *RecurrenceRule) subDailyUnit() time.Duration {
+	switch r.Freq {
+	case Hourly:
+		return time.Hour
+	case Minutely:
+		return time.Minute
+	default:
+		return time.Second
+	}
+}
+
+// subDailyInstants expands and limits a single HOURLY, MINUTELY or SECONDLY period
+func (r *RecurrenceRule) subDailyInstants(base time.Time) []time.Time {
+	day := time.Date(base.Year(), base.Month(), base.Day(), 0, 0, 0, 0, time.UTC)
+	if !r.matchesDayLimits(day) {
+		return nil
+	}
+	if len(r.ByHour) > 0 && !contains(r.ByHour, base.Hour()) {
+		return nil
+	}
+
+	minutes := orDefault(r.ByMinute, base.Minute())
+	seconds := orDefault(r.BySecond, base.Second())
+	if r.Freq != Hourly {
+		if len(r.ByMinute) > 0 && !contains(r.ByMinute, base.Minute()) {
+			return nil
+		}
+		minutes = []int{base.Minute()}
+	}
+	if r.Freq == Secondly {
+		if len(r.BySecond) > 0 && !contains(r.BySecond, base.Second()) {
+			return nil
+		}
+		seconds = []int{base.Second()}
+	}
+
+	var instants []time.Time
+	for _, minute := range minutes {
+		for _, second := range seconds {
+			instants = append(instants, time.Date(base.Year(), base.Month(), base.Day(), base.Hour(), minute, second, 0, base.Location()))
+		}
+	}
+
+	sort.Slice(instants, func(i, j int) bool { return instants[i].Before(instants[j]) })
+	return r.applySetPos(instants)
+}
+
+// applySetPos filters the sorted instants of a period by BYSETPOS
+func (r *RecurrenceRule) applySetPos(instants []time.Time) []time.Time {
+	if len(r.BySetPos) == 0 || len(instants) == 0 {
+		return instants
+	}
+
+	var selected []time.Time
+	for _, pos := range r.BySetPos {
+		index := pos - 1
+		if pos < 0 {
+			index = len(instants) + pos
+		}
+		if index >= 0 && index < len(instants) {
+			selected = append(selected, instants[index])
+		}
+	}
+
+	sort.Slice(selected, func(i, j int) bool { return selected[i].Before(selected[j]) })
+	return dedupe(selected)
+}
+
+// matchesWeekNo checks whether day falls into one of the BYWEEKNO weeks of year
+func (r *RecurrenceRule) matchesWeekNo(day time.Time, year int) bool {
+	start := weekOneStart(year, r.WeekStart)
+	weeksInYear := int(weekOneStart(year+1, r.WeekStart).Sub(start).Hours()/24) / 7
+	week := int(day.Sub(start).Hours()/24)/7 + 1
+
+	for _, n := range r.ByWeekNo {
+		if n == week || (n < 0 && weeksInYear+n+1 == week) {
+			return true
+		}
+	}
+	return false
+}
+
+// Expand returns the full recurrence set of an event: DTSTART, the RRULE and RDATE occurrences,
+// minus the EXDATE instants. The result is sorted, deduplicated and bounded by end and limit, which
+// is filled with the occurrences from start on before earlier ones.
+func Expand(dtstart time.Time, rules []*RecurrenceRule, rdates, exdates []time.Time, start, end time.Time, limit int) []time.Time {
+	excluded := make(map[int64]bool, len(exdates))
+	for _, exdate := range exdates {
+		excluded[exdate.Unix()] = true
+	}
+
+	var all []time.Time
+	if len(rules) == 0 {
+		all = append(all, dtstart)
+	}
+	for _, rule := range rules {
+		all = append(all, rule.Occurrences(dtstart, start, end, limit)...)
+	}
+	for _, rdate := range rdates {
+		if !rdate.After(end) {
+			all = append(all, rdate)
+		}
+	}
+
+	sort.Slice(all, func(i, j int) bool { return all[i].Before(all[j]) })
+
+	var occurrences []time.Time
+	for _, occurrence := range dedupe(all) {
+		if !excluded[occurrence.Unix()] {
+			occurrences = append(occurrences, occurrence)
+		}
+	}
+	return keepNearest(occurrences, start, limit)
+}
+
+// keepNearest bounds sorted occurrences to limit, keeping those from start on first and filling the
+// room they leave with the latest ones before start
+func keepNearest(occurrences []time.Time, start time.Time, limit int) []time.Time {
+	if limit <= 0 || len(occurrences) <= limit {
+		return occurrences
+	}
+
+	first := sort.Search(len(occurrences), func(i int) bool { return !occurrences[i].Before(start) })
+	if len(occurrences)-first >= limit {
+		return occurrences[first : first+limit]
+	}
+	return occurrences[len(occurrences)-limit:]
+}
+
+// parseUntil parses an UNTIL value, treating date-only values as the end of that day
+func parseUntil(value string, loc *time.Location) (time.Time, error) {
+	switch {
+	case len(value) == 8:
+		date, err := time.ParseInLocation("20060102", value, loc)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return date.AddDate(0, 0, 1).Add(-time.Second), nil
+	case strings.HasSuffix(value, "Z"):
+		return time.Parse("20060102T150405Z", value)
+	default:
+		return time.ParseInLocation("20060102T150405", value, loc)
+	}
+}
+
+// parseIntList parses a comma separated list of integers within [min, max] (or [-max, -min] when negative is allowed)
+func parseIntList(value string, min, max int, allowNegative bool) ([]int, error) {
+	var values []int
+	for _, item := range strings.Split(value, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(item))
+		if err != nil {
+			return nil, err
+		}
+
+		abs := n
+		if n < 0 {
+			if !allowNegative {
+				return nil, fmt.Errorf("value %d must not be negative", n)
+			}
+			abs = -n
+		}
+		if abs < min || abs > max || (n == 0 && min > 0) {
+			return nil, fmt.Errorf("value %d out of range", n)
+		}
+
+		values = append(values, n)
+	}
+	return values, nil
+}
+
+// parseWeekdayList parses a BYDAY list such as "MO,WE" or "1MO,-1FR"
+func parseWeekdayList(value string) ([]WeekdayNum, error) {
+	var days []WeekdayNum
+	for _, item := range strings.Split(value, ",") {
+		item = strings.ToUpper(strings.TrimSpace(item))
+		if len(item) < 2 {
+			return nil, fmt.Errorf("invalid weekday %q", item)
+		}
+
+		weekday, exists := weekdays[item[len(item)-2:]]
+		if !exists {
+			return nil, fmt.Errorf("invalid weekday %q", item)
+		}
+
+		n := 0
+		if prefix := item[:len(item)-2]; prefix != "" {
+			var err error
+			n, err = strconv.Atoi(prefix)
+			if err != nil || n == 0 || n > 53 || n < -53 {
+				return nil, fmt.Errorf("invalid weekday ordinal %q", item)
+			}
+		}
+
+		days = append(days, WeekdayNum{Weekday: weekday, N: n})
+	}
+	return days, nil
+}
+
+// weekOneStart returns the first day of week one of year, the first week with at least four days in that year
+func weekOneStart(year int, weekStart time.Weekday) time.Time {
+	jan1 := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+	offset := (int(jan1.Weekday()) - int(weekStart) + 7) % 7
+	start := jan1.AddDate(0, 0, -offset)
+	if 7-offset < 4 {
+		start = start.AddDate(0, 0, 7)
+	}
+	return start
+}
+
+// matchesWeekday checks a day against BYDAY entries, evaluating ordinals within the month or the year
+func matchesWeekday(byDay []WeekdayNum, day time.Time, relativeToMonth bool) bool {
+	for _, entry := range byDay {
+		if entry.Weekday != day.Weekday() {
+			continue
+		}
+		if entry.N == 0 {
+			return true
+		}
+
+		index, total := day.YearDay(), daysInYear(day.Year())
+		if relativeToMonth {
+			index, total = day.Day(), daysInMonth(day.Year(), day.Month())
+		}
+
+		fromStart := (index-1)/7 + 1
+		fromEnd := -((total-index)/7 + 1)
+		if entry.N == fromStart || entry.N == fromEnd {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesMonthDay checks a day against BYMONTHDAY entries, including negative offsets from the month end
+func matchesMonthDay(byMonthDay []int, day time.Time) bool {
+	negative := day.Day() - daysInMonth(day.Year(), day.Month()) - 1
+	for _, n := range byMonthDay {
+		if n == day.Day() || n == negative {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesYearDay checks a day against BYYEARDAY entries, including negative offsets from the year end
+func matchesYearDay(byYearDay []int, day time.Time) bool {
+	negative := day.YearDay() - daysInYear(day.Year()) - 1
+	for _, n := range byYearDay {
+		if n == day.YearDay() || n == negative {
+			return true
+		}
+	}
+	return false
+}
+
+// validDate returns the date and true if the day exists in the given month
+func validDate(year int, month time.Month, day int) (time.Time, bool) {
+	date := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	return date, date.Month() == month
+}
+
+func daysInMonth(year int, month time.Month) int {
+	return time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
+func daysInYear(year int) int {
+	return time.Date(year, time.December, 31, 0, 0, 0, 0, time.UTC).YearDay()
+}
+
+func stripOrdinals(byDay []WeekdayNum) []WeekdayNum {
+	stripped := make([]WeekdayNum, len(byDay))
+	for i, entry := range byDay {
+		stripped[i] = WeekdayNum{Weekday: entry.Weekday}
+	}
+	return stripped
+}
+
+func orDefault(values []int, fallback int) []int {
+	if len(values) == 0 {
+		return []int{fallback}
+	}
+	sorted := append([]int(nil), values...)
+	sort.Ints(sorted)
+	return sorted
+}
+
+func contains(values []int, value int) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// dedupe removes consecutive duplicate instants from a sorted slice
+func dedupe(instants []time.Time) []time.Time {
+	var unique []time.Time
+	for i, instant := range instants {
+		if i > 0 && instant.Equal(instants[i-1]) {
+			continue
+		}
+		unique = append(unique, instant)
+	}
+	return unique
+}
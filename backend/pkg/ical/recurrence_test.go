@@ -0,0 +1,233 @@
+package ical
+
+import (
+	"testing"
+	"time"
+)
+
+func mustParseRule(t *testing.T, value string, loc *time.Location) *RecurrenceRule {
+	t.Helper()
+	rule, err := ParseRecurrenceRule(value, loc)
+	if err != nil {
+		t.Fatalf("Failed to parse rule %q: %v", value, err)
+	}
+	return rule
+}
+
+func assertDates(t *testing.T, got []time.Time, want ...string) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("Expected %d occurrences, got %d: %v", len(want), len(got), got)
+	}
+	for i := range want {
+		if got[i].Format("2006-01-02 15:04") != want[i] {
+			t.Errorf("Occurrence %d: expected %s, got %s", i, want[i], got[i].Format("2006-01-02 15:04"))
+		}
+	}
+}
+
+func TestRecurrenceOccurrences(t *testing.T) {
+	far := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		rule    string
+		dtstart time.Time
+		want    []string
+	}{
+		{
+			name:    "Weekly on two weekdays",
+			rule:    "FREQ=WEEKLY;BYDAY=MO,WE;COUNT=4",
+			dtstart: time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC),
+			want:    []string{"2024-01-01 10:00", "2024-01-03 10:00", "2024-01-08 10:00", "2024-01-10 10:00"},
+		},
+		{
+			name:    "Biweekly",
+			rule:    "FREQ=WEEKLY;INTERVAL=2;COUNT=3",
+			dtstart: time.Date(2024, 1, 2, 9, 0, 0, 0, time.UTC),
+			want:    []string{"2024-01-02 09:00", "2024-01-16 09:00", "2024-01-30 09:00"},
+		},
+		{
+			name:    "Daily until inclusive",
+			rule:    "FREQ=DAILY;UNTIL=20240103T090000Z",
+			dtstart: time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC),
+			want:    []string{"2024-01-01 09:00", "2024-01-02 09:00", "2024-01-03 09:00"},
+		},
+		{
+			name:    "Monthly on the last Friday",
+			rule:    "FREQ=MONTHLY;BYDAY=-1FR;COUNT=3",
+			dtstart: time.Date(2024, 1, 26, 15, 0, 0, 0, time.UTC),
+			want:    []string{"2024-01-26 15:00", "2024-02-23 15:00", "2024-03-29 15:00"},
+		},
+		{
+			name:    "Monthly on the 31st skips short months",
+			rule:    "FREQ=MONTHLY;COUNT=3",
+			dtstart: time.Date(2024, 1, 31, 8, 0, 0, 0, time.UTC),
+			want:    []string{"2024-01-31 08:00", "2024-03-31 08:00", "2024-05-31 08:00"},
+		},
+		{
+			name:    "Last weekday of the month",
+			rule:    "FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-1;COUNT=3",
+			dtstart: time.Date(2024, 1, 31, 17, 0, 0, 0, time.UTC),
+			want:    []string{"2024-01-31 17:00", "2024-02-29 17:00", "2024-03-29 17:00"},
+		},
+		{
+			name:    "Yearly on the fourth Thursday of November",
+			rule:    "FREQ=YEARLY;BYMONTH=11;BYDAY=4TH;COUNT=3",
+			dtstart: time.Date(2023, 11, 23, 12, 0, 0, 0, time.UTC),
+			want:    []string{"2023-11-23 12:00", "2024-11-28 12:00", "2025-11-27 12:00"},
+		},
+		{
+			name:    "Yearly on leap day",
+			rule:    "FREQ=YEARLY;COUNT=2",
+			dtstart: time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC),
+			want:    []string{"2024-02-29 00:00", "2028-02-29 00:00"},
+		},
+		{
+			name:    "Impossible rule terminates",
+			rule:    "FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=30",
+			dtstart: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+			want:    []string{"2024-01-01 00:00"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule := mustParseRule(t, tt.rule, time.UTC)
+			assertDates(t, rule.Occurrences(tt.dtstart, time.Time{}, far, 0), tt.want...)
+		})
+	}
+}
+
+func TestRecurrenceKeepsWallClockAcrossDST(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("Time zone data not available: %v", err)
+	}
+
+	rule := mustParseRule(t, "FREQ=WEEKLY;COUNT=3", loc)
+	dtstart := time.Date(2024, 3, 3, 10, 0, 0, 0, loc)
+	occurrences := rule.Occurrences(dtstart, time.Time{}, dtstart.AddDate(1, 0, 0), 0)
+
+	assertDates(t, occurrences, "2024-03-03 10:00", "2024-03-10 10:00", "2024-03-17 10:00")
+	if occurrences[0].Sub(occurrences[1]) == -7*24*time.Hour {
+		t.Error("Expected the DST transition to shorten the interval between occurrences")
+	}
+}
+
+func TestRecurrenceWindowAndLimit(t *testing.T) {
+	rule := mustParseRule(t, "FREQ=DAILY", time.UTC)
+	dtstart := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+
+	occurrences := rule.Occurrences(dtstart, time.Time{}, time.Date(2024, 1, 10, 0, 0, 0, 0, time.UTC), 0)
+	if len(occurrences) != 9 {
+		t.Errorf("Expected 9 occurrences inside the window, got %d", len(occurrences))
+	}
+
+	occurrences = rule.Occurrences(dtstart, time.Time{}, time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC), 5)
+	if len(occurrences) != 5 {
+		t.Errorf("Expected the limit to cap occurrences at 5, got %d", len(occurrences))
+	}
+}
+
+func TestRecurrenceSkipsOccurrencesBeforeStart(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(1, 0, 0)
+
+	// Old rules reach the window instead of using up the limit on past occurrences
+	daily := mustParseRule(t, "FREQ=DAILY", time.UTC)
+	assertDates(t, daily.Occurrences(time.Date(1990, 6, 1, 9, 0, 0, 0, time.UTC), start, end, 2), "2026-01-01 09:00", "2026-01-02 09:00")
+	hourly := mustParseRule(t, "FREQ=HOURLY;INTERVAL=5", time.UTC)
+	assertDates(t, hourly.Occurrences(time.Date(2010, 1, 1, 0, 30, 0, 0, time.UTC), start, end, 2), "2026-01-01 04:30", "2026-01-01 09:30")
+
+	// Occurrences before the window still count towards COUNT, and fill the room the window leaves
+	counted := mustParseRule(t, "FREQ=DAILY;COUNT=10", time.UTC)
+	occurrences := Expand(time.Date(2025, 12, 25, 9, 0, 0, 0, time.UTC), []*RecurrenceRule{counted}, nil, nil, start, end, 5)
+	assertDates(t, occurrences, "2025-12-30 09:00", "2025-12-31 09:00", "2026-01-01 09:00", "2026-01-02 09:00", "2026-01-03 09:00")
+
+	// Rules that ended before the window keep their latest occurrences
+	past := mustParseRule(t, "FREQ=WEEKLY;COUNT=3", time.UTC)
+	occurrences = Expand(time.Date(2024, 7, 1, 9, 0, 0, 0, time.UTC), []*RecurrenceRule{past}, nil, nil, start, end, 2)
+	assertDates(t, occurrences, "2024-07-08 09:00", "2024-07-15 09:00")
+
+	// The window is filled first, then the latest occurrences before it
+	dtstart := time.Date(2020, 2, 29, 9, 0, 0, 0, time.UTC)
+	for _, value := range []string{"FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,SU;WKST=SU", "FREQ=MONTHLY;BYMONTHDAY=-1", "FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=29", "FREQ=DAILY;INTERVAL=3;BYHOUR=8,20"} {
+		rule := mustParseRule(t, value, time.UTC)
+		all := rule.Occurrences(dtstart, time.Time{}, end, 0)
+		for _, limit := range []int{3, 40, 300} {
+			var want []string
+			for _, occurrence := range all {
+				if !occurrence.Before(start) && len(want) < limit {
+					want = append(want, occurrence.Format("2006-01-02 15:04"))
+				}
+			}
+			for i := len(all) - 1; i >= 0 && len(want) < limit; i-- {
+				if all[i].Before(start) {
+					want = append([]string{all[i].Format("2006-01-02 15:04")}, want...)
+				}
+			}
+			assertDates(t, rule.Occurrences(dtstart, start, end, limit), want...)
+		}
+	}
+}
+
+func TestExpandWithRDateAndExDate(t *testing.T) {
+	rule := mustParseRule(t, "FREQ=WEEKLY;COUNT=4", time.UTC)
+	dtstart := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	occurrences := Expand(dtstart,
+		[]*RecurrenceRule{rule},
+		[]time.Time{time.Date(2024, 1, 5, 14, 0, 0, 0, time.UTC)},
+		[]time.Time{time.Date(2024, 1, 8, 10, 0, 0, 0, time.UTC)},
+		time.Time{}, time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC), 0)
+
+	assertDates(t, occurrences, "2024-01-01 10:00", "2024-01-05 14:00", "2024-01-15 10:00", "2024-01-22 10:00")
+}
+
+func TestParseRecurrenceRuleErrors(t *testing.T) {
+	invalid := []string{
+		"",
+		"BYDAY=MO",
+		"FREQ=FORTNIGHTLY",
+		"FREQ=DAILY;COUNT=0",
+		"FREQ=DAILY;COUNT=2;UNTIL=20240101T000000Z",
+		"FREQ=MONTHLY;BYMONTHDAY=32",
+		"FREQ=WEEKLY;BYDAY=XX",
+	}
+
+	for _, value := range invalid {
+		if _, err := ParseRecurrenceRule(value, time.UTC); err == nil {
+			t.Errorf("Expected an error for rule %q", value)
+		}
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		value string
+		days  int
+		clock time.Duration
+	}{
+		{"PT1H30M", 0, 90 * time.Minute},
+		{"P1D", 1, 0},
+		{"P2W", 14, 0},
+		{"P1DT12H", 1, 12 * time.Hour},
+		{"-PT15M", 0, -15 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		duration, err := ParseDuration(tt.value)
+		if err != nil {
+			t.Errorf("Failed to parse duration %q: %v", tt.value, err)
+			continue
+		}
+		if duration.Days != tt.days || duration.Clock != tt.clock {
+			t.Errorf("Duration %q: expected %d days and %v, got %d days and %v", tt.value, tt.days, tt.clock, duration.Days, duration.Clock)
+		}
+	}
+
+	if _, err := ParseDuration("1H"); err == nil {
+		t.Error("Expected an error for an invalid duration")
+	}
+}
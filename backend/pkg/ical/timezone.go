@@ -0,0 +1,287 @@
+package ical
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxObservanceOnsets caps the number of onsets computed for a single VTIMEZONE observance
+const maxObservanceOnsets = 1000
+
+// observanceHorizon bounds the expansion of open-ended VTIMEZONE rules. Times after the last
+// computed transition keep the offset of that transition.
+var observanceHorizon = time.Date(2100, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// Observance is a STANDARD or DAYLIGHT sub-component of a VTIMEZONE. Start, the rules and RDates
+// are local wall clock times of the offset in effect before the onset, expressed as UTC times.
+type Observance struct {
+	Name       string // TZNAME, optional
+	DST        bool
+	Start      time.Time
+	OffsetFrom int // TZOFFSETFROM in seconds east of UTC
+	OffsetTo   int // TZOFFSETTO in seconds east of UTC
+	Rules      []*RecurrenceRule
+	RDates     []time.Time
+}
+
+// ParseUTCOffset parses an RFC 5545 UTC-OFFSET value such as "-0800" or "+053000" into seconds
+// east of UTC
+func ParseUTCOffset(value string) (int, error) {
+	if len(value) != 5 && len(value) != 7 {
+		return 0, fmt.Errorf("invalid UTC offset %q", value)
+	}
+
+	sign := 1
+	switch value[0] {
+	case '+':
+	case '-':
+		sign = -1
+	default:
+		return 0, fmt.Errorf("invalid UTC offset %q", value)
+	}
+
+	var parts [3]int
+	for i := 0; 1+i*2 < len(value); i++ {
+		n, err := strconv.Atoi(value[1+i*2 : 3+i*2])
+		if err != nil || n < 0 || n > 59 {
+			return 0, fmt.Errorf("invalid UTC offset %q", value)
+		}
+		parts[i] = n
+	}
+
+	return sign * (parts[0]*3600 + parts[1]*60 + parts[2]), nil
+}
+
+// NewVTimezoneLocation builds a Location named tzid from the observances of a VTIMEZONE, so that
+// times in custom time zones follow the DST transitions the file defines
+func NewVTimezoneLocation(tzid string, observances []Observance) (*time.Location, error) {
+	if len(observances) == 0 {
+		return nil, fmt.Errorf("time zone %q has no observances", tzid)
+	}
+
+	type transition struct {
+		when int64
+		zone int
+	}
+
+	// The first zone applies before the first transition, so it must be one no transition uses
+	zones := []tzifZone{{offset: observances[0].OffsetFrom, name: offsetAbbreviation(observances[0].OffsetFrom)}}
+	zoneIndex := func(zone tzifZone) int {
+		for i := 1; i < len(zones); i++ {
+			if zones[i] == zone {
+				return i
+			}
+		}
+		zones = append(zones, zone)
+		return len(zones) - 1
+	}
+
+	var transitions []transition
+	for _, observance := range observances {
+		zone := zoneIndex(tzifZone{
+			offset: observance.OffsetTo,
+			dst:    observance.DST,
+			name:   observanceAbbreviation(observance),
+		})
+
+		// UNTIL is a UTC instant while the onsets are wall clock times
+		rules := make([]*RecurrenceRule, 0, len(observance.Rules))
+		for _, rule := range observance.Rules {
+			if rule.Until != nil {
+				shifted := *rule
+				until := rule.Until.Add(time.Duration(observance.OffsetFrom) * time.Second)
+				shifted.Until = &until
+				rule = &shifted
+			}
+			rules = append(rules, rule)
+		}
+
+		onsets := Expand(observance.Start, rules, observance.RDates, nil, time.Time{}, observanceHorizon, maxObservanceOnsets)
+		for _, onset := range onsets {
+			transitions = append(transitions, transition{
+				when: onset.Unix() - int64(observance.OffsetFrom),
+				zone: zone,
+			})
+		}
+	}
+
+	if len(zones) > 256 {
+		return nil, fmt.Errorf("time zone %q has too many observances", tzid)
+	}
+
+	sort.SliceStable(transitions, func(i, j int) bool { return transitions[i].when < transitions[j].when })
+
+	var whens []int64
+	var indexes []uint8
+	for _, t := range transitions {
+		if n := len(whens); n > 0 && whens[n-1] == t.when {
+			indexes[n-1] = uint8(t.zone)
+			continue
+		}
+		whens = append(whens, t.when)
+		indexes = append(indexes, uint8(t.zone))
+	}
+
+	loc, err := time.LoadLocationFromTZData(tzid, encodeTZif(zones, whens, indexes))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build time zone %q: %w", tzid, err)
+	}
+	return loc, nil
+}
+
+// tzifZone is a local time type of a TZif file
+type tzifZone struct {
+	offset int
+	dst    bool
+	name   string
+}
+
+// encodeTZif encodes zones and transitions as version 2 TZif data (RFC 8536). The version 1 block
+// is left empty since only its 64-bit counterpart is read.
+func encodeTZif(zones []tzifZone, whens []int64, indexes []uint8) []byte {
+	var abbreviations bytes.Buffer
+	abbreviationIndex := make(map[string]int)
+	for _, zone := range zones {
+		if _, exists := abbreviationIndex[zone.name]; !exists {
+			abbreviationIndex[zone.name] = abbreviations.Len()
+			abbreviations.WriteString(zone.name)
+			abbreviations.WriteByte(0)
+		}
+	}
+
+	var buf bytes.Buffer
+	header := func(timeCount, zoneCount, charCount int) {
+		buf.WriteString("TZif2")
+		buf.Write(make([]byte, 15))
+		// UT/local indicators, standard/wall indicators, leap seconds, transitions, zones, characters
+		for _, count := range []int{0, 0, 0, timeCount, zoneCount, charCount} {
+			binary.Write(&buf, binary.BigEndian, uint32(count))
+		}
+	}
+
+	header(0, 0, 0)
+	header(len(whens), len(zones), abbreviations.Len())
+	for _, when := range whens {
+		binary.Write(&buf, binary.BigEndian, when)
+	}
+	buf.Write(indexes)
+	for _, zone := range zones {
+		binary.Write(&buf, binary.BigEndian, int32(zone.offset))
+		if zone.dst {
+			buf.WriteByte(1)
+		} else {
+			buf.WriteByte(0)
+		}
+		buf.WriteByte(uint8(abbreviationIndex[zone.name]))
+	}
+	buf.Write(abbreviations.Bytes())
+	buf.WriteString("\n\n") // Empty footer, times after the last transition keep its zone
+
+	return buf.Bytes()
+}
+
+// observanceAbbreviation returns the TZNAME of an observance, or its offset if it has none
+func observanceAbbreviation(observance Observance) string {
+	if observance.Name != "" {
+		return observance.Name
+	}
+	return offsetAbbreviation(observance.OffsetTo)
+}
+
+// offsetAbbreviation formats an offset the way unnamed zones are abbreviated, e.g. "-08" or "+0530"
+func offsetAbbreviation(offset int) string {
+	sign := '+'
+	if offset < 0 {
+		sign = '-'
+		offset = -offset
+	}
+	hours, minutes := offset/3600, offset%3600/60
+	if minutes == 0 {
+		return fmt.Sprintf("%c%02d", sign, hours)
+	}
+	return fmt.Sprintf("%c%02d%02d", sign, hours, minutes)
+}
+
+// LoadLocation loads a time zone by its IANA or Windows name. Names prefixed with a vendor path,
+// such as "/mozilla.org/20050126_1/America/New_York", are resolved by their IANA suffix.
+func LoadLocation(name string) (*time.Location, error) {
+	name = strings.Trim(strings.TrimSpace(name), `"`)
+	if name == "" || name == "Local" {
+		return nil, fmt.Errorf("unknown time zone %q", name)
+	}
+
+	if iana, exists := windowsZones[name]; exists {
+		return time.LoadLocation(iana)
+	}
+
+	if loc, err := time.LoadLocation(name); err == nil {
+		return loc, nil
+	}
+
+	// Try the trailing "Area/City" or "Area/Region/City" part of vendor prefixed names
+	parts := strings.Split(strings.Trim(name, "/"), "/")
+	for i := 1; i < len(parts) && len(parts)-i >= 2; i++ {
+		if loc, err := time.LoadLocation(strings.Join(parts[i:], "/")); err == nil {
+			return loc, nil
+		}
+	}
+
+	return nil, fmt.Errorf("unknown time zone %q", name)
+}
+
+// TimeZones resolves the TZID parameters of an iCalendar object. Known IANA and Windows names are
+// preferred over VTIMEZONE definitions since they carry the complete transition history.
+type TimeZones struct {
+	floating *time.Location
+	defined  map[string]*time.Location
+
+	mu       sync.Mutex
+	resolved map[string]*time.Location
+}
+
+// NewTimeZones creates a resolver that interprets floating times in floating
+func NewTimeZones(floating *time.Location) *TimeZones {
+	if floating == nil {
+		floating = time.UTC
+	}
+	return &TimeZones{
+		floating: floating,
+		defined:  make(map[string]*time.Location),
+		resolved: make(map[string]*time.Location),
+	}
+}
+
+// Define registers the location built from the VTIMEZONE with the given TZID
+func (z *TimeZones) Define(tzid string, loc *time.Location) {
+	z.defined[tzid] = loc
+}
+
+// Floating returns the location of times without a time zone
+func (z *TimeZones) Floating() *time.Location {
+	return z.floating
+}
+
+// Resolve returns the location of a TZID, or false if it is neither a known time zone name nor
+// defined by a VTIMEZONE
+func (z *TimeZones) Resolve(tzid string) (*time.Location, bool) {
+	z.mu.Lock()
+	defer z.mu.Unlock()
+
+	if loc, exists := z.resolved[tzid]; exists {
+		return loc, loc != nil
+	}
+
+	loc, err := LoadLocation(tzid)
+	if err != nil {
+		loc = z.defined[tzid]
+	}
+
+	z.resolved[tzid] = loc
+	return loc, loc != nil
+}
@@ -0,0 +1,191 @@
+package ical
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseUTCOffset(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    int
+		wantErr bool
+	}{
+		{value: "-0800", want: -8 * 3600},
+		{value: "+0530", want: 5*3600 + 30*60},
+		{value: "+000000", want: 0},
+		{value: "-003015", want: -(30*60 + 15)},
+		{value: "0800", wantErr: true},
+		{value: "+08", wantErr: true},
+		{value: "+0875", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseUTCOffset(tt.value)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("Expected an error for %q", tt.value)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Unexpected error for %q: %v", tt.value, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Expected %d for %q, got %d", tt.want, tt.value, got)
+		}
+	}
+}
+
+func TestNewVTimezoneLocation(t *testing.T) {
+	// The US Eastern definition Outlook exports, with a custom TZID
+	wall := func(value string) time.Time {
+		parsed, err := time.Parse("20060102T150405", value)
+		if err != nil {
+			t.Fatalf("Failed to parse %q: %v", value, err)
+		}
+		return parsed
+	}
+
+	loc, err := NewVTimezoneLocation("Eastern (custom)", []Observance{
+		{
+			Name:       "EST",
+			Start:      wall("16010101T020000"),
+			OffsetFrom: -4 * 3600,
+			OffsetTo:   -5 * 3600,
+			Rules:      []*RecurrenceRule{mustParseRule(t, "FREQ=YEARLY;BYDAY=1SU;BYMONTH=11", time.UTC)},
+		},
+		{
+			Name:       "EDT",
+			DST:        true,
+			Start:      wall("16010101T020000"),
+			OffsetFrom: -5 * 3600,
+			OffsetTo:   -4 * 3600,
+			Rules:      []*RecurrenceRule{mustParseRule(t, "FREQ=YEARLY;BYDAY=2SU;BYMONTH=3", time.UTC)},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to build location: %v", err)
+	}
+
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("Time zone database not available: %v", err)
+	}
+
+	for _, value := range []string{
+		"20240110T090000", // Winter
+		"20240310T013000", // Just before the spring transition
+		"20240310T030000", // Just after the spring transition
+		"20240704T120000", // Summer
+		"20241103T003000", // Just before the autumn transition
+		"20241104T090000", // After the autumn transition
+		"20350615T090000", // Far future
+	} {
+		got, err := time.ParseInLocation("20060102T150405", value, loc)
+		if err != nil {
+			t.Fatalf("Failed to parse %q: %v", value, err)
+		}
+		want, _ := time.ParseInLocation("20060102T150405", value, newYork)
+		if !got.Equal(want) {
+			t.Errorf("%s: expected %s, got %s", value, want.UTC(), got.UTC())
+		}
+	}
+
+	if name, _ := time.Date(2024, 7, 4, 12, 0, 0, 0, loc).Zone(); name != "EDT" {
+		t.Errorf("Expected the EDT abbreviation in summer, got %q", name)
+	}
+}
+
+func TestNewVTimezoneLocationUntil(t *testing.T) {
+	// A zone that stopped observing DST after 2010, with UNTIL given in UTC
+	start := time.Date(2000, 3, 26, 2, 0, 0, 0, time.UTC)
+	loc, err := NewVTimezoneLocation("Custom", []Observance{
+		{
+			Start:      time.Date(2000, 10, 29, 3, 0, 0, 0, time.UTC),
+			OffsetFrom: 2 * 3600,
+			OffsetTo:   3600,
+			Rules:      []*RecurrenceRule{mustParseRule(t, "FREQ=YEARLY;BYMONTH=10;BYDAY=-1SU;UNTIL=20101031T010000Z", time.UTC)},
+		},
+		{
+			DST:        true,
+			Start:      start,
+			OffsetFrom: 3600,
+			OffsetTo:   2 * 3600,
+			Rules:      []*RecurrenceRule{mustParseRule(t, "FREQ=YEARLY;BYMONTH=3;BYDAY=-1SU;UNTIL=20100328T010000Z", time.UTC)},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to build location: %v", err)
+	}
+
+	tests := []struct {
+		when time.Time
+		want int
+	}{
+		{when: time.Date(2010, 7, 1, 12, 0, 0, 0, time.UTC), want: 2 * 3600}, // Last DST period, UNTIL is inclusive
+		{when: time.Date(2010, 12, 1, 12, 0, 0, 0, time.UTC), want: 3600},
+		{when: time.Date(2011, 7, 1, 12, 0, 0, 0, time.UTC), want: 3600}, // No more DST
+	}
+	for _, tt := range tests {
+		if _, offset := tt.when.In(loc).Zone(); offset != tt.want {
+			t.Errorf("%s: expected offset %d, got %d", tt.when, tt.want, offset)
+		}
+	}
+}
+
+func TestLoadLocation(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    string
+		wantErr bool
+	}{
+		{name: "Europe/Berlin", want: "Europe/Berlin"},
+		{name: "Pacific Standard Time", want: "America/Los_Angeles"},
+		{name: "W. Europe Standard Time", want: "Europe/Berlin"},
+		{name: `"Tokyo Standard Time"`, want: "Asia/Tokyo"},
+		{name: "/mozilla.org/20050126_1/America/New_York", want: "America/New_York"},
+		{name: "/softwarestudio.org/Olson_20011030_5/America/Argentina/Buenos_Aires", want: "America/Argentina/Buenos_Aires"},
+		{name: "Eastern (custom)", wantErr: true},
+		{name: "Local", wantErr: true},
+		{name: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		loc, err := LoadLocation(tt.name)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("Expected an error for %q", tt.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Unexpected error for %q: %v", tt.name, err)
+			continue
+		}
+		if loc.String() != tt.want {
+			t.Errorf("Expected %q for %q, got %q", tt.want, tt.name, loc.String())
+		}
+	}
+}
+
+func TestTimeZonesResolve(t *testing.T) {
+	custom := time.FixedZone("Custom", 3*3600)
+	zones := NewTimeZones(nil)
+	zones.Define("Custom", custom)
+	zones.Define("Europe/Paris", custom)
+
+	if loc, ok := zones.Resolve("Custom"); !ok || loc != custom {
+		t.Errorf("Expected the VTIMEZONE definition, got %v", loc)
+	}
+	if loc, ok := zones.Resolve("Europe/Paris"); !ok || loc.String() != "Europe/Paris" {
+		t.Errorf("Expected the IANA zone to take precedence, got %v", loc)
+	}
+	if _, ok := zones.Resolve("Unknown"); ok {
+		t.Error("Expected an unknown TZID not to resolve")
+	}
+	if zones.Floating() != time.UTC {
+		t.Errorf("Expected floating times to default to UTC, got %v", zones.Floating())
+	}
+}
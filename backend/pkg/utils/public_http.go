@@ -0,0 +1,128 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// maxPublicRedirects bounds the redirects a public HTTP client follows
+const maxPublicRedirects = 10
+
+// ErrNonPublicAddress is returned when a request to a user-supplied URL would reach an address
+// that isn't on the public internet, such as loopback, private networks or cloud metadata services
+var ErrNonPublicAddress = errors.New("address is not public")
+
+// nonPublicPrefixes are the special-purpose ranges not covered by the checks of netip.Addr
+var nonPublicPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),      // "This" network
+	netip.MustParsePrefix("100.64.0.0/10"),  // Carrier-grade NAT
+	netip.MustParsePrefix("192.0.0.0/24"),   // IETF protocol assignments
+	netip.MustParsePrefix("198.18.0.0/15"),  // Benchmarking
+	netip.MustParsePrefix("240.0.0.0/4"),    // Reserved, including broadcast
+	netip.MustParsePrefix("64:ff9b::/96"),   // NAT64, can reach any IPv4 address
+	netip.MustParsePrefix("64:ff9b:1::/48"), // Local-use NAT64
+	netip.MustParsePrefix("2002::/16"),      // 6to4, can reach any IPv4 address
+	netip.MustParsePrefix("2001::/32"),      // Teredo
+	netip.MustParsePrefix("fec0::/10"),      // Deprecated site-local
+}
+
+// IsPublicAddress checks whether addr is a public unicast address. Loopback, private, link-local
+// (which includes the 169.254.169.254 metadata address), unspecified, multicast and other
+// special-purpose addresses are not public.
+func IsPublicAddress(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsValid() || addr.IsLoopback() || addr.IsPrivate() || addr.IsUnspecified() ||
+		addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() || addr.IsInterfaceLocalMulticast() || addr.IsMulticast() {
+		return false
+	}
+	for _, prefix := range nonPublicPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// NewPublicHTTPClient creates an HTTP client for fetching user-supplied URLs that only connects to
+// public addresses. Addresses are checked by the dialer after the host is resolved, so neither
+// redirects nor DNS rebinding reach internal hosts. Proxies from the environment are not used,
+// since the dialer would only see the address of the proxy.
+func NewPublicHTTPClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control:   publicAddressControl,
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &http.Client{
+		Timeout:       timeout,
+		Transport:     transport,
+		CheckRedirect: checkPublicRedirect,
+	}
+}
+
+// publicAddressControl rejects connections to addresses that aren't public. It runs for every
+// connection attempt with the resolved address, before anything is sent.
+func publicAddressControl(network, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrNonPublicAddress, address)
+	}
+	if !IsPublicAddress(addrPort.Addr()) {
+		return fmt.Errorf("%w: %s", ErrNonPublicAddress, addrPort.Addr())
+	}
+	return nil
+}
+
+// checkPublicRedirect only follows redirects to http(s) URLs whose host isn't obviously internal.
+// Hosts that resolve to internal addresses are rejected by the dialer when connecting.
+func checkPublicRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= maxPublicRedirects {
+		return fmt.Errorf("stopped after %d redirects", maxPublicRedirects)
+	}
+	if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+		return fmt.Errorf("redirect to unsupported scheme %q", req.URL.Scheme)
+	}
+	return checkPublicHost(req.Context(), req.URL.Hostname())
+}
+
+// checkPublicHost resolves host and checks that every address it resolves to is public. The dialer
+// checks again when connecting, since the host may resolve differently by then.
+func checkPublicHost(ctx context.Context, host string) error {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if host == "" {
+		return fmt.Errorf("%w: missing host", ErrNonPublicAddress)
+	}
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return fmt.Errorf("%w: %s", ErrNonPublicAddress, host)
+	}
+
+	if addr, err := netip.ParseAddr(host); err == nil {
+		if !IsPublicAddress(addr) {
+			return fmt.Errorf("%w: %s", ErrNonPublicAddress, addr)
+		}
+		return nil
+	}
+
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return fmt.Errorf("failed to resolve %s: %w", host, err)
+	}
+	for _, addr := range addrs {
+		if !IsPublicAddress(addr) {
+			return fmt.Errorf("%w: %s resolves to %s", ErrNonPublicAddress, host, addr)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+)
+
+func TestIsPublicAddress(t *testing.T) {
+	tests := []struct {
+		addr   string
+		public bool
+	}{
+		{"93.184.216.34", true},
+		{"2606:2800:220:1:248:1893:25c8:1946", true},
+		{"127.0.0.1", false},
+		{"::1", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false},
+		{"fe80::1", false},
+		{"fd00::1", false},
+		{"0.0.0.0", false},
+		{"::", false},
+		{"100.64.0.1", false},
+		{"224.0.0.1", false},
+		{"255.255.255.255", false},
+		{"::ffff:127.0.0.1", false},
+		{"64:ff9b::a9fe:a9fe", false},
+	}
+
+	for _, tt := range tests {
+		if got := IsPublicAddress(netip.MustParseAddr(tt.addr)); got != tt.public {
+			t.Errorf("IsPublicAddress(%s) = %v, expected %v", tt.addr, got, tt.public)
+		}
+	}
+}
+
+func TestPublicHTTPClient(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+	}))
+	defer server.Close()
+
+	client := NewPublicHTTPClient(5 * time.Second)
+	for _, url := range []string{server.URL, "http://169.254.169.254/latest/meta-data/"} {
+		_, err := client.Get(url)
+		if !errors.Is(err, ErrNonPublicAddress) {
+			t.Errorf("Expected %s to be refused, got %v", url, err)
+		}
+	}
+	if requests != 0 {
+		t.Errorf("Expected no request to reach the loopback server, got %d", requests)
+	}
+
+	// Redirects are checked before they are followed
+	redirect := func(url string) error {
+		req := httptest.NewRequest("GET", url, nil).WithContext(context.Background())
+		return checkPublicRedirect(req, []*http.Request{req})
+	}
+	for _, url := range []string{"http://localhost/", "http://[::1]/", "http://10.0.0.1/admin"} {
+		if err := redirect(url); !errors.Is(err, ErrNonPublicAddress) {
+			t.Errorf("Expected the redirect to %s to be refused, got %v", url, err)
+		}
+	}
+	if err := redirect("file:///etc/passwd"); err == nil {
+		t.Error("Expected a redirect to a file URL to be refused")
+	}
+	if err := redirect("http://93.184.216.34/calendar.ics"); err != nil {
+		t.Errorf("Expected a redirect to a public address to be followed, got %v", err)
+	}
+}
@@ -0,0 +1,27 @@
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+)
+
+// secretTokenBytes is the amount of randomness in a secret token (256 bits)
+const secretTokenBytes = 32
+
+// GenerateSecretToken generates an unguessable URL-safe token
+func GenerateSecretToken() (string, error) {
+	buf := make([]byte, secretTokenBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate secret token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// HashToken returns the hex encoded SHA-256 hash of a secret token, suitable for storage and lookup
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
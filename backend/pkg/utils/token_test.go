@@ -0,0 +1,39 @@
+package utils
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestGenerateSecretToken(t *testing.T) {
+	token1, err := GenerateSecretToken()
+	if err != nil {
+		t.Fatalf("Failed to generate token: %v", err)
+	}
+	token2, err := GenerateSecretToken()
+	if err != nil {
+		t.Fatalf("Failed to generate token: %v", err)
+	}
+
+	if token1 == token2 {
+		t.Error("Generated tokens should be different")
+	}
+
+	// 32 random bytes encode to 43 URL-safe base64 characters
+	if !regexp.MustCompile(`^[A-Za-z0-9_-]{43}$`).MatchString(token1) {
+		t.Errorf("Token %q is not a 43 character URL-safe string", token1)
+	}
+}
+
+func TestHashToken(t *testing.T) {
+	hash := HashToken("secret")
+	if hash != HashToken("secret") {
+		t.Error("Hashing the same token should give the same hash")
+	}
+	if hash == HashToken("Secret") {
+		t.Error("Hashing different tokens should give different hashes")
+	}
+	if len(hash) != 64 {
+		t.Errorf("Expected a 64 character hex hash, got %d characters", len(hash))
+	}
+}